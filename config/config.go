@@ -1,10 +1,18 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/NYTimes/gizmo/config"
 	"github.com/NYTimes/gizmo/server"
 	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
 	"github.com/fsouza/gizmo-stackdriver-logging"
+	"github.com/kelseyhightower/envconfig"
 )
 
 // Config is a struct to contain all the needed configuration for the
@@ -13,6 +21,7 @@ type Config struct {
 	Server                 *server.Config
 	SwaggerManifest        string `envconfig:"SWAGGER_MANIFEST_PATH"`
 	DefaultSegmentDuration uint   `envconfig:"DEFAULT_SEGMENT_DURATION" default:"5"`
+	MaxRequestBodySize     int64  `envconfig:"MAX_REQUEST_BODY_SIZE" default:"1048576"`
 	Redis                  *storage.Config
 	EncodingCom            *EncodingCom
 	ElasticTranscoder      *ElasticTranscoder
@@ -21,6 +30,270 @@ type Config struct {
 	Zencoder               *Zencoder
 	Bitmovin               *Bitmovin
 	Log                    *logging.Config
+
+	// User-Agent and static headers sent in all requests to providers
+	ProviderUserAgent string            `envconfig:"PROVIDER_USER_AGENT"`
+	ProviderHeaders   map[string]string `envconfig:"PROVIDER_HEADERS"`
+
+	// bearer token required by the admin endpoints that modify stored
+	// data. These endpoints are disabled when it's not set.
+	AdminToken string `envconfig:"ADMIN_TOKEN"`
+
+	// interval, in seconds, between status checks for clients subscribed
+	// to job events, and the maximum number of concurrent subscriptions
+	EventsPollInterval   uint `envconfig:"JOB_EVENTS_POLL_INTERVAL" default:"5"`
+	EventsMaxSubscribers int  `envconfig:"JOB_EVENTS_MAX_SUBSCRIPTIONS" default:"100"`
+
+	// configuration of each Elemental Conductor cluster listed in
+	// ELEMENTALCONDUCTOR_CLUSTERS, loaded from environment variables
+	// prefixed with the name of the cluster (e.g.
+	// EAST_ELEMENTALCONDUCTOR_HOST)
+	ElementalConductorClusters map[string]*ElementalConductor `ignored:"true"`
+
+	// default maximum duration of jobs, in seconds, after which they're
+	// canceled. 0 means no timeout.
+	JobMaxDuration uint `envconfig:"JOB_MAX_DURATION"`
+
+	// named sets of presets that may be used in place of the list of
+	// outputs when creating jobs
+	JobProfiles JobProfiles `envconfig:"JOB_PROFILES"`
+
+	// maximum number of retries jobs may ask for, through their
+	// maxRetries, when they fail in the provider
+	JobMaxRetries uint `envconfig:"JOB_MAX_RETRIES" default:"3"`
+
+	// maximum number of outputs of each job, including the outputs of
+	// job profiles. 0 means no limit.
+	JobMaxOutputs uint `envconfig:"JOB_MAX_OUTPUTS" default:"100"`
+
+	// maximum number of jobs each client may create per minute, along
+	// with overrides for specific API keys, in the format key:limit.
+	// Clients are identified by the API key sent in the given header,
+	// falling back to their address. 0 means no limit.
+	JobRateLimit          uint            `envconfig:"JOB_RATE_LIMIT"`
+	JobRateLimitOverrides map[string]uint `envconfig:"JOB_RATE_LIMIT_OVERRIDES"`
+	JobRateLimitHeader    string          `envconfig:"JOB_RATE_LIMIT_HEADER" default:"X-Api-Key"`
+
+	// time, in seconds, after which queued jobs that opted in for priority
+	// escalation have their priority raised to EscalatedJobPriority. 0
+	// means no escalation.
+	JobPriorityEscalationThreshold uint `envconfig:"JOB_PRIORITY_ESCALATION_THRESHOLD"`
+	EscalatedJobPriority           int  `envconfig:"ESCALATED_JOB_PRIORITY" default:"100"`
+
+	// time, in seconds, test jobs are kept before being removed, and the
+	// provider test jobs are routed to instead of the one in the request.
+	// A retention of 0 keeps test jobs like any other job.
+	TestJobRetention uint   `envconfig:"TEST_JOB_RETENTION" default:"86400"`
+	TestJobProvider  string `envconfig:"TEST_JOB_PROVIDER"`
+
+	// minimum size, in bytes, of the responses compressed with gzip for
+	// clients that accept it
+	GzipMinSize uint `envconfig:"GZIP_MIN_SIZE" default:"1400"`
+
+	// time, in seconds, clients are asked to wait before retrying job
+	// submissions rejected while the datastore is unavailable
+	DatastoreRetryAfter uint `envconfig:"DATASTORE_RETRY_AFTER" default:"5"`
+
+	// default presets of each provider used for the outputs whose preset
+	// maps lack a mapping for the provider. Jobs with such outputs are
+	// rejected when no fallback is set
+	PresetFallbacks PresetFallbacks `envconfig:"PRESET_FALLBACKS"`
+
+	// server used for emailing the notifications of jobs that ask for
+	// them. Notifications are disabled when it's not set
+	SMTP *SMTP
+
+	// time, in seconds, after which requests are canceled and answered
+	// with 504 (Gateway Timeout), along with overrides for specific
+	// routes. Subscriptions to job events are never timed out. 0 means no
+	// timeout.
+	RequestTimeout uint          `envconfig:"REQUEST_TIMEOUT"`
+	RouteTimeouts  RouteTimeouts `envconfig:"ROUTE_TIMEOUTS"`
+
+	// number of jobs running concurrently in providers for each of their
+	// active nodes, for providers able to report them. The node count is
+	// refreshed after the given interval, in seconds, which also bounds
+	// how often the status of the running jobs is refreshed once they
+	// reach the limit. The concurrency limit applies to other providers
+	// and to providers whose nodes are unknown. 0 means no limit.
+	ProviderConcurrencyMultiplier   uint `envconfig:"PROVIDER_CONCURRENCY_MULTIPLIER"`
+	ProviderCapacityRefreshInterval uint `envconfig:"PROVIDER_CAPACITY_REFRESH_INTERVAL" default:"60"`
+	ProviderConcurrencyLimit        uint `envconfig:"PROVIDER_CONCURRENCY_LIMIT"`
+
+	// allows job submissions to ask, through the X-Debug-Timing header,
+	// for the duration of each of their steps, reported in the
+	// Server-Timing response header
+	DebugTiming bool `envconfig:"DEBUG_TIMING"`
+}
+
+// JobProfiles maps the name of each job profile to the names of the presets
+// it expands to. It's loaded from a comma-separated list of profiles in the
+// format name:preset1|preset2 (e.g.
+// "web-standard:mp4_1080p|mp4_720p,mobile:mp4_360p").
+type JobProfiles map[string][]string
+
+// Decode parses the value of the JOB_PROFILES environment variable.
+func (p *JobProfiles) Decode(value string) error {
+	profiles := make(JobProfiles)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return fmt.Errorf("invalid job profile %q, must be in the format name:preset1|preset2", item)
+		}
+		if _, ok := profiles[name]; ok {
+			return fmt.Errorf("duplicate job profile %q", name)
+		}
+		var presets []string
+		for _, preset := range strings.Split(parts[1], "|") {
+			if preset = strings.TrimSpace(preset); preset != "" {
+				presets = append(presets, preset)
+			}
+		}
+		if len(presets) == 0 {
+			return fmt.Errorf("job profile %q has no presets", name)
+		}
+		profiles[name] = presets
+	}
+	*p = profiles
+	return nil
+}
+
+// PresetFallbacks maps the name of each provider to the ids of the presets
+// in the provider used in place of the preset maps without a mapping for it,
+// by name of the preset map. It's loaded from a comma-separated list of
+// fallbacks in the format provider:presetmap=id (e.g.
+// "zencoder:mp4_720p=generic-720p,zencoder:mp4_480p=generic-480p").
+type PresetFallbacks map[string]map[string]string
+
+// Decode parses the value of the PRESET_FALLBACKS environment variable.
+func (f *PresetFallbacks) Decode(value string) error {
+	fallbacks := make(PresetFallbacks)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid preset fallback %q, must be in the format provider:presetmap=id", item)
+		}
+		mapping := strings.SplitN(parts[1], "=", 2)
+		if len(mapping) != 2 {
+			return fmt.Errorf("invalid preset fallback %q, must be in the format provider:presetmap=id", item)
+		}
+		providerName := strings.TrimSpace(parts[0])
+		name := strings.TrimSpace(mapping[0])
+		id := strings.TrimSpace(mapping[1])
+		if providerName == "" || name == "" || id == "" {
+			return fmt.Errorf("invalid preset fallback %q, must be in the format provider:presetmap=id", item)
+		}
+		if _, ok := fallbacks[providerName][name]; ok {
+			return fmt.Errorf("duplicate preset fallback for %q in provider %q", name, providerName)
+		}
+		if fallbacks[providerName] == nil {
+			fallbacks[providerName] = make(map[string]string)
+		}
+		fallbacks[providerName][name] = id
+	}
+	*f = fallbacks
+	return nil
+}
+
+// RouteTimeouts maps routes, identified by their method and path (e.g. "GET
+// /jobs/:jobId"), to the time after which their requests time out. It's
+// loaded from a comma-separated list of timeouts, in seconds, in the format
+// "METHOD path=seconds" (e.g. "GET /jobs/:jobId=5,POST /jobs=60").
+type RouteTimeouts map[string]time.Duration
+
+// Decode parses the value of the ROUTE_TIMEOUTS environment variable.
+func (t *RouteTimeouts) Decode(value string) error {
+	timeouts := make(RouteTimeouts)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		route := strings.Fields(parts[0])
+		if len(parts) != 2 || len(route) != 2 {
+			return fmt.Errorf("invalid route timeout %q, must be in the format METHOD path=seconds", item)
+		}
+		seconds, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid route timeout %q, must be in the format METHOD path=seconds", item)
+		}
+		key := strings.ToUpper(route[0]) + " " + route[1]
+		if _, ok := timeouts[key]; ok {
+			return fmt.Errorf("duplicate route timeout for %q", key)
+		}
+		timeouts[key] = time.Duration(seconds) * time.Second
+	}
+	*t = timeouts
+	return nil
+}
+
+// Categories of the errors reported by providers: transient errors may not
+// happen again when the job is submitted again, unlike permanent errors,
+// which are caused by the job itself.
+const (
+	TransientErrorCategory = "transient"
+	PermanentErrorCategory = "permanent"
+)
+
+// ErrorRule classifies the errors whose messages match its pattern.
+type ErrorRule struct {
+	Category string
+	Pattern  *regexp.Regexp
+}
+
+// ErrorRules lists the rules for classifying the errors reported by a
+// provider, consulted in order. It's loaded from a semicolon-separated list of
+// rules in the format category:regexp (e.g.
+// "permanent:(?i)unsupported codec;transient:license server"), as regular
+// expressions often include commas.
+type ErrorRules []ErrorRule
+
+// Decode parses the value of the environment variables of error rules.
+func (r *ErrorRules) Decode(value string) error {
+	var rules ErrorRules
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("invalid error rule %q, must be in the format category:regexp", item)
+		}
+		category := strings.TrimSpace(parts[0])
+		if category != TransientErrorCategory && category != PermanentErrorCategory {
+			return fmt.Errorf("invalid error rule %q, category must be %s or %s", item, TransientErrorCategory, PermanentErrorCategory)
+		}
+		pattern, err := regexp.Compile(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid error rule %q: %s", item, err)
+		}
+		rules = append(rules, ErrorRule{Category: category, Pattern: pattern})
+	}
+	*r = rules
+	return nil
+}
+
+// SMTP represents the set of configurations for sending email
+// notifications of jobs. Notifications are disabled unless both the host and
+// the sender are set, and the credentials are only used when the username is
+// set.
+type SMTP struct {
+	Host     string `envconfig:"SMTP_HOST"`
+	Port     int    `envconfig:"SMTP_PORT" default:"587"`
+	Username string `envconfig:"SMTP_USERNAME"`
+	Password string `envconfig:"SMTP_PASSWORD"`
+	From     string `envconfig:"SMTP_FROM"`
 }
 
 // EncodingCom represents the set of configurations for the Encoding.com
@@ -31,6 +304,9 @@ type EncodingCom struct {
 	Destination    string `envconfig:"ENCODINGCOM_DESTINATION"`
 	Region         string `envconfig:"ENCODINGCOM_REGION"`
 	StatusEndpoint string `envconfig:"ENCODINGCOM_STATUS_ENDPOINT" default:"http://status.encoding.com"`
+
+	// disabled providers are not listed and refuse new jobs
+	Disabled bool `envconfig:"ENCODINGCOM_DISABLED"`
 }
 
 // Zencoder represents the set of configurations for the Zencoder
@@ -38,6 +314,9 @@ type EncodingCom struct {
 type Zencoder struct {
 	APIKey      string `envconfig:"ZENCODER_API_KEY"`
 	Destination string `envconfig:"ZENCODER_DESTINATION"`
+
+	// disabled providers are not listed and refuse new jobs
+	Disabled bool `envconfig:"ZENCODER_DISABLED"`
 }
 
 // ElasticTranscoder represents the set of configurations for the Elastic
@@ -47,6 +326,9 @@ type ElasticTranscoder struct {
 	SecretAccessKey string `envconfig:"AWS_SECRET_ACCESS_KEY"`
 	Region          string `envconfig:"AWS_REGION"`
 	PipelineID      string `envconfig:"ELASTICTRANSCODER_PIPELINE_ID"`
+
+	// disabled providers are not listed and refuse new jobs
+	Disabled bool `envconfig:"ELASTICTRANSCODER_DISABLED"`
 }
 
 // ElementalConductor represents the set of configurations for the Elemental
@@ -59,6 +341,46 @@ type ElementalConductor struct {
 	AccessKeyID     string `envconfig:"ELEMENTALCONDUCTOR_AWS_ACCESS_KEY_ID"`
 	SecretAccessKey string `envconfig:"ELEMENTALCONDUCTOR_AWS_SECRET_ACCESS_KEY"`
 	Destination     string `envconfig:"ELEMENTALCONDUCTOR_DESTINATION"`
+
+	// names of the clusters used for balancing jobs and the strategy for
+	// picking a cluster: round-robin or least-loaded
+	Clusters        []string `envconfig:"ELEMENTALCONDUCTOR_CLUSTERS"`
+	ClusterStrategy string   `envconfig:"ELEMENTALCONDUCTOR_CLUSTER_STRATEGY" default:"round-robin"`
+
+	// service account key used for gs:// locations, either as the path
+	// to the JSON key file or as the JSON key itself
+	GCSCredentialsFile string `envconfig:"ELEMENTALCONDUCTOR_GCS_CREDENTIALS_FILE"`
+	GCSCredentials     string `envconfig:"ELEMENTALCONDUCTOR_GCS_CREDENTIALS"`
+
+	// location used by the nodes as scratch space while processing jobs,
+	// either an absolute path or a URI. Empty for the default location of
+	// the nodes
+	WorkingDirectory string `envconfig:"ELEMENTALCONDUCTOR_WORKING_DIRECTORY"`
+
+	// amount of the input, in milliseconds, prefetched by the nodes of
+	// jobs that don't set the inputBufferMsec option. Unset for the
+	// buffer of the nodes
+	InputBufferMsec *uint `envconfig:"ELEMENTALCONDUCTOR_INPUT_BUFFER_MSEC"`
+
+	// subpaths, within the destination of each job, where outputs are
+	// written, keyed by their container (e.g. mp4) or adaptive streaming
+	// type (hls, smooth or cmaf). Subpaths may include the {source} and
+	// {container} placeholders
+	OutputSubpaths map[string]string `envconfig:"ELEMENTALCONDUCTOR_OUTPUT_SUBPATHS"`
+
+	// ARN of the IAM role assumed by the nodes for reading s3:// sources,
+	// instead of the static credentials, e.g. for sources stored in another
+	// AWS account
+	SourceRoleARN string `envconfig:"ELEMENTALCONDUCTOR_SOURCE_ROLE_ARN"`
+
+	// rules for classifying the errors reported by Elemental Conductor,
+	// consulted before the default classification by error code, e.g.
+	// for telling whether failed jobs are retried
+	ErrorRules ErrorRules `envconfig:"ELEMENTALCONDUCTOR_ERROR_RULES"`
+
+	// disabled providers are not listed and refuse new jobs. Each cluster
+	// may also be disabled on its own, using its prefixed variable
+	Disabled bool `envconfig:"ELEMENTALCONDUCTOR_DISABLED"`
 }
 
 // Bitmovin represents the set of configurations for the Bitmovin
@@ -73,6 +395,9 @@ type Bitmovin struct {
 	AWSStorageRegion string `envconfig:"BITMOVIN_AWS_STORAGE_REGION" default:"US_EAST_1"`
 	EncodingRegion   string `envconfig:"BITMOVIN_ENCODING_REGION" default:"AWS_US_EAST_1"`
 	EncodingVersion  string `envconfig:"BITMOVIN_ENCODING_VERSION" default:"STABLE"`
+
+	// disabled providers are not listed and refuse new jobs
+	Disabled bool `envconfig:"BITMOVIN_DISABLED"`
 }
 
 // Hybrik represents the set of configurations for the Hybrik
@@ -86,11 +411,27 @@ type Hybrik struct {
 	AuthSecret     string `envconfig:"HYBRIK_AUTH_SECRET"`
 	Destination    string `envconfig:"HYBRIK_DESTINATION"`
 	PresetPath     string `envconfig:"HYBRIK_PRESET_PATH" default:"transcoding-api-presets"`
+
+	// disabled providers are not listed and refuse new jobs
+	Disabled bool `envconfig:"HYBRIK_DISABLED"`
 }
 
 // LoadConfig loads the configuration of the API using environment variables.
 func LoadConfig() *Config {
 	var cfg Config
 	config.LoadEnvConfig(&cfg)
+	if cfg.ElementalConductor != nil && len(cfg.ElementalConductor.Clusters) > 0 {
+		cfg.ElementalConductorClusters = make(map[string]*ElementalConductor, len(cfg.ElementalConductor.Clusters))
+		for _, cluster := range cfg.ElementalConductor.Clusters {
+			var clusterCfg ElementalConductor
+			err := envconfig.Process(strings.ToUpper(cluster), &clusterCfg)
+			if err != nil {
+				log.Fatalf("unable to load configuration for Elemental Conductor cluster %q: %s", cluster, err)
+			}
+			clusterCfg.Clusters = nil
+			clusterCfg.ClusterStrategy = ""
+			cfg.ElementalConductorClusters[cluster] = &clusterCfg
+		}
+	}
 	return &cfg
 }
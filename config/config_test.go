@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/NYTimes/gizmo/server"
 	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
@@ -50,12 +51,81 @@ func TestLoadConfigFromEnv(t *testing.T) {
 		"HTTP_ACCESS_LOG":                          accessLog,
 		"HTTP_PORT":                                "8080",
 		"DEFAULT_SEGMENT_DURATION":                 "3",
+		"MAX_REQUEST_BODY_SIZE":                    "2097152",
+		"PROVIDER_USER_AGENT":                      "video-transcoding-api-staging",
+		"PROVIDER_HEADERS":                         "X-Environment:staging,X-Team:media",
+		"ADMIN_TOKEN":                              "admin-secret",
+		"JOB_EVENTS_POLL_INTERVAL":                 "2",
+		"JOB_EVENTS_MAX_SUBSCRIPTIONS":             "20",
+		"JOB_PROFILES":                             "web-standard:mp4_1080p|mp4_720p, mobile:mp4_360p",
+		"JOB_RATE_LIMIT":                           "30",
+		"JOB_RATE_LIMIT_OVERRIDES":                 "batch-key:600,ui-key:60",
+		"JOB_PRIORITY_ESCALATION_THRESHOLD":        "900",
+		"ESCALATED_JOB_PRIORITY":                   "80",
+		"TEST_JOB_RETENTION":                       "3600",
+		"JOB_MAX_OUTPUTS":                          "20",
+		"JOB_MAX_RETRIES":                          "5",
+		"TEST_JOB_PROVIDER":                        "zencoder",
+		"GZIP_MIN_SIZE":                            "4096",
+		"DATASTORE_RETRY_AFTER":                    "30",
+		"PRESET_FALLBACKS":                         "zencoder:mp4_720p=generic-720p, zencoder:mp4_480p=generic-480p,elementalconductor:mp4_720p=42",
+		"SMTP_HOST":                                "smtp.example.com",
+		"SMTP_PORT":                                "2525",
+		"SMTP_USERNAME":                            "transcoding",
+		"SMTP_PASSWORD":                            "smtp-secret",
+		"SMTP_FROM":                                "transcoding@example.com",
+		"REQUEST_TIMEOUT":                          "10",
+		"ROUTE_TIMEOUTS":                           "GET /jobs/:jobId=2, post /jobs=60",
+		"PROVIDER_CONCURRENCY_MULTIPLIER":          "4",
+		"PROVIDER_CAPACITY_REFRESH_INTERVAL":       "30",
+		"PROVIDER_CONCURRENCY_LIMIT":               "10",
 		"LOGGING_LEVEL":                            "debug",
 	})
 	cfg := LoadConfig()
 	expectedCfg := Config{
 		SwaggerManifest:        "/opt/video-transcoding-api-swagger.json",
 		DefaultSegmentDuration: 3,
+		MaxRequestBodySize:     2097152,
+		ProviderUserAgent:      "video-transcoding-api-staging",
+		ProviderHeaders:        map[string]string{"X-Environment": "staging", "X-Team": "media"},
+		AdminToken:             "admin-secret",
+		EventsPollInterval:     2,
+		EventsMaxSubscribers:   20,
+		JobProfiles: JobProfiles{
+			"web-standard": {"mp4_1080p", "mp4_720p"},
+			"mobile":       {"mp4_360p"},
+		},
+		JobRateLimit:          30,
+		JobRateLimitOverrides: map[string]uint{"batch-key": 600, "ui-key": 60},
+		JobRateLimitHeader:    "X-Api-Key",
+
+		JobPriorityEscalationThreshold: 900,
+		EscalatedJobPriority:           80,
+		TestJobRetention:               3600,
+		JobMaxOutputs:                  20,
+		JobMaxRetries:                  5,
+		TestJobProvider:                "zencoder",
+		GzipMinSize:                    4096,
+		DatastoreRetryAfter:            30,
+		PresetFallbacks: PresetFallbacks{
+			"zencoder":           {"mp4_720p": "generic-720p", "mp4_480p": "generic-480p"},
+			"elementalconductor": {"mp4_720p": "42"},
+		},
+		SMTP: &SMTP{
+			Host:     "smtp.example.com",
+			Port:     2525,
+			Username: "transcoding",
+			Password: "smtp-secret",
+			From:     "transcoding@example.com",
+		},
+		RequestTimeout: 10,
+		RouteTimeouts: RouteTimeouts{
+			"GET /jobs/:jobId": 2 * time.Second,
+			"POST /jobs":       time.Minute,
+		},
+		ProviderConcurrencyMultiplier:   4,
+		ProviderCapacityRefreshInterval: 30,
+		ProviderConcurrencyLimit:        10,
 		Redis: &storage.Config{
 			SentinelAddrs:      "10.10.10.10:26379,10.10.10.11:26379,10.10.10.12:26379",
 			SentinelMasterName: "super-master",
@@ -90,6 +160,7 @@ func TestLoadConfigFromEnv(t *testing.T) {
 			AccessKeyID:     "AKIANOTREALLY",
 			SecretAccessKey: "secret-key",
 			Destination:     "https://safe-stuff",
+			ClusterStrategy: "round-robin",
 		},
 		Bitmovin: &Bitmovin{
 			APIKey:           "secret-key",
@@ -155,6 +226,19 @@ func TestLoadConfigFromEnvWithDefaults(t *testing.T) {
 	expectedCfg := Config{
 		SwaggerManifest:        "/opt/video-transcoding-api-swagger.json",
 		DefaultSegmentDuration: 5,
+		MaxRequestBodySize:     1048576,
+		EventsPollInterval:     5,
+		EventsMaxSubscribers:   100,
+		JobRateLimitHeader:     "X-Api-Key",
+		EscalatedJobPriority:   100,
+		TestJobRetention:       86400,
+		JobMaxOutputs:          100,
+		JobMaxRetries:          3,
+		GzipMinSize:            1400,
+		DatastoreRetryAfter:    5,
+		SMTP:                   &SMTP{Port: 587},
+
+		ProviderCapacityRefreshInterval: 60,
 		Redis: &storage.Config{
 			SentinelAddrs:      "10.10.10.10:26379,10.10.10.11:26379,10.10.10.12:26379",
 			SentinelMasterName: "super-master",
@@ -185,6 +269,7 @@ func TestLoadConfigFromEnvWithDefaults(t *testing.T) {
 			AccessKeyID:     "AKIANOTREALLY",
 			SecretAccessKey: "secret-key",
 			Destination:     "https://safe-stuff",
+			ClusterStrategy: "round-robin",
 		},
 		Hybrik: &Hybrik{
 			ComplianceDate: "20170601",
@@ -218,6 +303,159 @@ func TestLoadConfigFromEnvWithDefaults(t *testing.T) {
 	}
 }
 
+func TestLoadConfigElementalConductorClusters(t *testing.T) {
+	os.Clearenv()
+	setEnvs(map[string]string{
+		"ELEMENTALCONDUCTOR_USER_LOGIN":            "myuser",
+		"ELEMENTALCONDUCTOR_API_KEY":               "secret-key",
+		"ELEMENTALCONDUCTOR_CLUSTERS":              "east,west",
+		"ELEMENTALCONDUCTOR_CLUSTER_STRATEGY":      "least-loaded",
+		"EAST_ELEMENTALCONDUCTOR_HOST":             "elemental-east",
+		"WEST_ELEMENTALCONDUCTOR_HOST":             "elemental-west",
+		"WEST_ELEMENTALCONDUCTOR_API_KEY":          "west-secret-key",
+		"WEST_ELEMENTALCONDUCTOR_DESTINATION":      "s3://west-bucket",
+		"ELEMENTALCONDUCTOR_AWS_SECRET_ACCESS_KEY": "secret-key",
+	})
+	cfg := LoadConfig()
+	if cfg.ElementalConductor.ClusterStrategy != "least-loaded" {
+		t.Errorf("wrong cluster strategy. Want %q. Got %q", "least-loaded", cfg.ElementalConductor.ClusterStrategy)
+	}
+	expected := map[string]*ElementalConductor{
+		"east": {
+			Host:            "elemental-east",
+			UserLogin:       "myuser",
+			APIKey:          "secret-key",
+			SecretAccessKey: "secret-key",
+		},
+		"west": {
+			Host:            "elemental-west",
+			UserLogin:       "myuser",
+			APIKey:          "west-secret-key",
+			SecretAccessKey: "secret-key",
+			Destination:     "s3://west-bucket",
+		},
+	}
+	if diff := cmp.Diff(cfg.ElementalConductorClusters, expected); diff != "" {
+		t.Errorf("LoadConfig(): wrong cluster configuration\nDiff: %v", diff)
+	}
+}
+
+func TestJobProfilesDecodeErrors(t *testing.T) {
+	var tests = []struct {
+		value   string
+		wantErr string
+	}{
+		{"mp4_1080p", `invalid job profile "mp4_1080p", must be in the format name:preset1|preset2`},
+		{":mp4_1080p", `invalid job profile ":mp4_1080p", must be in the format name:preset1|preset2`},
+		{"web:mp4_1080p,web:mp4_720p", `duplicate job profile "web"`},
+		{"web:|", `job profile "web" has no presets`},
+	}
+	for _, test := range tests {
+		var profiles JobProfiles
+		err := profiles.Decode(test.value)
+		if err == nil {
+			t.Errorf("Decode(%q): unexpected <nil> error", test.value)
+			continue
+		}
+		if err.Error() != test.wantErr {
+			t.Errorf("Decode(%q): wrong error message\nwant %q\ngot  %q", test.value, test.wantErr, err.Error())
+		}
+	}
+}
+
+func TestPresetFallbacksDecodeErrors(t *testing.T) {
+	var tests = []struct {
+		value   string
+		wantErr string
+	}{
+		{"zencoder", `invalid preset fallback "zencoder", must be in the format provider:presetmap=id`},
+		{"zencoder:mp4_720p", `invalid preset fallback "zencoder:mp4_720p", must be in the format provider:presetmap=id`},
+		{":mp4_720p=generic-720p", `invalid preset fallback ":mp4_720p=generic-720p", must be in the format provider:presetmap=id`},
+		{"zencoder:mp4_720p=", `invalid preset fallback "zencoder:mp4_720p=", must be in the format provider:presetmap=id`},
+		{"zencoder:mp4_720p=generic-720p,zencoder:mp4_720p=other-720p", `duplicate preset fallback for "mp4_720p" in provider "zencoder"`},
+	}
+	for _, test := range tests {
+		var fallbacks PresetFallbacks
+		err := fallbacks.Decode(test.value)
+		if err == nil {
+			t.Errorf("Decode(%q): unexpected <nil> error", test.value)
+			continue
+		}
+		if err.Error() != test.wantErr {
+			t.Errorf("Decode(%q): wrong error message\nwant %q\ngot  %q", test.value, test.wantErr, err.Error())
+		}
+	}
+}
+
+func TestRouteTimeoutsDecodeErrors(t *testing.T) {
+	var tests = []struct {
+		value   string
+		wantErr string
+	}{
+		{"/jobs=60", `invalid route timeout "/jobs=60", must be in the format METHOD path=seconds`},
+		{"POST /jobs", `invalid route timeout "POST /jobs", must be in the format METHOD path=seconds`},
+		{"POST /jobs=1m", `invalid route timeout "POST /jobs=1m", must be in the format METHOD path=seconds`},
+		{"POST /jobs=60,post /jobs=30", `duplicate route timeout for "POST /jobs"`},
+	}
+	for _, test := range tests {
+		var timeouts RouteTimeouts
+		err := timeouts.Decode(test.value)
+		if err == nil {
+			t.Errorf("Decode(%q): unexpected <nil> error", test.value)
+			continue
+		}
+		if err.Error() != test.wantErr {
+			t.Errorf("Decode(%q): wrong error message\nwant %q\ngot  %q", test.value, test.wantErr, err.Error())
+		}
+	}
+}
+
+func TestErrorRulesDecode(t *testing.T) {
+	var rules ErrorRules
+	err := rules.Decode("permanent:(?i)unsupported codec; transient:license server (timed out|unavailable);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		category string
+		pattern  string
+	}{
+		{"permanent", "(?i)unsupported codec"},
+		{"transient", "license server (timed out|unavailable)"},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("wrong number of rules. Want %d. Got %d: %#v", len(want), len(rules), rules)
+	}
+	for i, rule := range rules {
+		if rule.Category != want[i].category || rule.Pattern.String() != want[i].pattern {
+			t.Errorf("wrong rule %d. Want %s:%s. Got %s:%s", i, want[i].category, want[i].pattern, rule.Category, rule.Pattern)
+		}
+	}
+}
+
+func TestErrorRulesDecodeErrors(t *testing.T) {
+	var tests = []struct {
+		value   string
+		wantErr string
+	}{
+		{"unsupported codec", `invalid error rule "unsupported codec", must be in the format category:regexp`},
+		{"permanent:", `invalid error rule "permanent:", must be in the format category:regexp`},
+		{"fatal:unsupported codec", `invalid error rule "fatal:unsupported codec", category must be transient or permanent`},
+		{"permanent:unsupported (codec", "invalid error rule \"permanent:unsupported (codec\": error parsing regexp: missing closing ): `unsupported (codec`"},
+	}
+	for _, test := range tests {
+		var rules ErrorRules
+		err := rules.Decode(test.value)
+		if err == nil {
+			t.Errorf("Decode(%q): unexpected <nil> error", test.value)
+			continue
+		}
+		if err.Error() != test.wantErr {
+			t.Errorf("Decode(%q): wrong error message\nwant %q\ngot  %q", test.value, test.wantErr, err.Error())
+		}
+	}
+}
+
 func setEnvs(envs map[string]string) {
 	for k, v := range envs {
 		os.Setenv(k, v)
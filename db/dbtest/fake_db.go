@@ -12,6 +12,7 @@ type fakeRepository struct {
 	presetmaps   map[string]*db.PresetMap
 	localpresets map[string]*db.LocalPreset
 	jobs         []*db.Job
+	transitions  map[string]bool
 }
 
 // NewFakeRepository creates a new instance of the fake repository
@@ -22,6 +23,7 @@ func NewFakeRepository(triggerError bool) db.Repository {
 		triggerError: triggerError,
 		presetmaps:   make(map[string]*db.PresetMap),
 		localpresets: make(map[string]*db.LocalPreset),
+		transitions:  make(map[string]bool),
 	}
 }
 
@@ -36,6 +38,18 @@ func (d *fakeRepository) CreateJob(job *db.Job) error {
 	return nil
 }
 
+func (d *fakeRepository) UpdateJob(job *db.Job) error {
+	if d.triggerError {
+		return errors.New("database error")
+	}
+	index, err := d.findJob(job.ID)
+	if err != nil {
+		return err
+	}
+	d.jobs[index] = job
+	return nil
+}
+
 func (d *fakeRepository) DeleteJob(job *db.Job) error {
 	if d.triggerError {
 		return errors.New("database error")
@@ -95,6 +109,71 @@ func (d *fakeRepository) ListJobs(filter db.JobFilter) ([]db.Job, error) {
 	return jobs, nil
 }
 
+func (d *fakeRepository) CountJobs(since time.Time) (*db.JobCounts, error) {
+	if d.triggerError {
+		return nil, errors.New("database error")
+	}
+	counts := db.JobCounts{ByProvider: make(map[string]map[string]int)}
+	for _, job := range d.jobs {
+		if job.CreationTime.Before(since) {
+			continue
+		}
+		if counts.ByProvider[job.ProviderName] == nil {
+			counts.ByProvider[job.ProviderName] = make(map[string]int)
+		}
+		counts.ByProvider[job.ProviderName][job.Status]++
+		if job.Test {
+			counts.TestJobs++
+		}
+		if job.Status == "finished" && job.ProcessingTime > 0 {
+			counts.Processed++
+			counts.ProcessingTime += job.ProcessingTime
+		}
+	}
+	return &counts, nil
+}
+
+func (d *fakeRepository) CountRunningJobs(providerName string) (int, error) {
+	jobs, err := d.ListRunningJobs(providerName)
+	return len(jobs), err
+}
+
+func (d *fakeRepository) ListRunningJobs(providerName string) ([]db.Job, error) {
+	if d.triggerError {
+		return nil, errors.New("database error")
+	}
+	var jobs []db.Job
+	for _, job := range d.jobs {
+		if job.ProviderName == providerName && job.ProviderJobID != "" && (job.Status == "queued" || job.Status == "started") {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (d *fakeRepository) ClaimJobTransition(id, transition string) (bool, error) {
+	if d.triggerError {
+		return false, errors.New("database error")
+	}
+	if _, err := d.findJob(id); err != nil {
+		return false, err
+	}
+	key := id + ":" + transition
+	if d.transitions[key] {
+		return false, nil
+	}
+	d.transitions[key] = true
+	return true, nil
+}
+
+func (d *fakeRepository) ReleaseJobTransition(id, transition string) error {
+	if d.triggerError {
+		return errors.New("database error")
+	}
+	delete(d.transitions, id+":"+transition)
+	return nil
+}
+
 func (d *fakeRepository) CreatePresetMap(presetmap *db.PresetMap) error {
 	if d.triggerError {
 		return errors.New("database error")
@@ -130,6 +209,19 @@ func (d *fakeRepository) GetPresetMap(name string) (*db.PresetMap, error) {
 	return nil, db.ErrPresetMapNotFound
 }
 
+func (d *fakeRepository) GetPresetMaps(names []string) (map[string]*db.PresetMap, error) {
+	if d.triggerError {
+		return nil, errors.New("database error")
+	}
+	presetmaps := make(map[string]*db.PresetMap, len(names))
+	for _, name := range names {
+		if presetmap, ok := d.presetmaps[name]; ok {
+			presetmaps[name] = presetmap
+		}
+	}
+	return presetmaps, nil
+}
+
 func (d *fakeRepository) DeletePresetMap(presetmap *db.PresetMap) error {
 	if d.triggerError {
 		return errors.New("database error")
@@ -216,6 +216,55 @@ func TestListJobsDBError(t *testing.T) {
 	}
 }
 
+func TestCountJobs(t *testing.T) {
+	now := time.Now().UTC()
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", Status: "finished", ProcessingTime: 60, CreationTime: now.Add(-2 * time.Hour)},
+		{ID: "job-2", ProviderName: "encodingcom", Status: "finished", ProcessingTime: 30, CreationTime: now.Add(-time.Hour)},
+		{ID: "job-3", ProviderName: "encodingcom", ProviderJobID: "3", Status: "started", CreationTime: now.Add(-30 * time.Minute)},
+		{ID: "job-4", ProviderName: "zencoder", Status: "queued", Test: true, CreationTime: now.Add(-10 * time.Minute)},
+	}
+	repo := NewFakeRepository(false)
+	for i := range jobs {
+		err := repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts, err := repo.CountJobs(now.Add(-90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := db.JobCounts{
+		ByProvider: map[string]map[string]int{
+			"encodingcom": {"finished": 1, "started": 1},
+			"zencoder":    {"queued": 1},
+		},
+		TestJobs:       1,
+		Processed:      1,
+		ProcessingTime: 30,
+	}
+	if !reflect.DeepEqual(*counts, expected) {
+		t.Errorf("CountJobs: wrong counts returned. Want %#v. Got %#v", expected, *counts)
+	}
+	for providerName, want := range map[string]int{"encodingcom": 1, "zencoder": 0} {
+		running, err := repo.CountRunningJobs(providerName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if running != want {
+			t.Errorf("CountRunningJobs(%q): wrong count. Want %d. Got %d", providerName, want, running)
+		}
+		runningJobs, err := repo.ListRunningJobs(providerName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runningJobs) != want {
+			t.Errorf("ListRunningJobs(%q): wrong jobs. Want %d. Got %#v", providerName, want, runningJobs)
+		}
+	}
+}
+
 func TestCreatePresetMap(t *testing.T) {
 	repo := NewFakeRepository(false)
 	preset := db.PresetMap{Name: "mypreset"}
@@ -12,11 +12,25 @@ import (
 
 const jobsSetKey = "jobs"
 
+var claimTransitionScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HSETNX", KEYS[1], ARGV[1], ARGV[2])
+`)
+
 func (r *redisRepository) CreateJob(job *db.Job) error {
 	if job.ID == "" {
 		return errors.New("job id is required")
 	}
 	job.CreationTime = time.Now().UTC().Truncate(time.Millisecond)
+	return checkAvailability(r.saveJob(job))
+}
+
+func (r *redisRepository) UpdateJob(job *db.Job) error {
+	if _, err := r.GetJob(job.ID); err != nil {
+		return err
+	}
 	return r.saveJob(job)
 }
 
@@ -27,23 +41,45 @@ func (r *redisRepository) saveJob(job *db.Job) error {
 	}
 	jobKey := r.jobKey(job.ID)
 	return r.storage.RedisClient().Watch(func(tx *redis.Tx) error {
-		err := tx.HMSet(jobKey, fields).Err()
+		previous, err := r.indexedJob(tx, jobKey)
 		if err != nil {
 			return err
 		}
-		return tx.ZAddNX(jobsSetKey, redis.Z{Member: job.ID, Score: float64(job.CreationTime.UnixNano())}).Err()
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			if previous != nil {
+				unindexJob(pipe, previous)
+			}
+			pipe.HMSet(jobKey, fields)
+			if job.Test && r.config.TestJobRetention > 0 {
+				retention := time.Duration(r.config.TestJobRetention) * time.Second
+				pipe.ExpireAt(jobKey, job.CreationTime.Add(retention))
+			}
+			pipe.ZAddNX(jobsSetKey, redis.Z{Member: job.ID, Score: float64(job.CreationTime.UnixNano())})
+			indexJob(pipe, job)
+			return nil
+		})
+		return err
 	}, jobKey)
 }
 
 func (r *redisRepository) DeleteJob(job *db.Job) error {
-	err := r.storage.Delete(r.jobKey(job.ID))
-	if err != nil {
-		if err == storage.ErrNotFound {
+	jobKey := r.jobKey(job.ID)
+	return r.storage.RedisClient().Watch(func(tx *redis.Tx) error {
+		previous, err := r.indexedJob(tx, jobKey)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
 			return db.ErrJobNotFound
 		}
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Del(jobKey)
+			pipe.ZRem(jobsSetKey, job.ID)
+			unindexJob(pipe, previous)
+			return nil
+		})
 		return err
-	}
-	return r.storage.RedisClient().ZRem(jobsSetKey, job.ID).Err()
+	}, jobKey)
 }
 
 func (r *redisRepository) GetJob(id string) (*db.Job, error) {
@@ -72,16 +108,43 @@ func (r *redisRepository) ListJobs(filter db.JobFilter) ([]db.Job, error) {
 	jobs := make([]db.Job, 0, len(jobIDs))
 	for _, id := range jobIDs {
 		job, err := r.GetJob(id)
-		if err != nil && err != db.ErrJobNotFound {
-			return nil, err
+		if err == db.ErrJobNotFound {
+			// expired test jobs leave their ids behind in the set
+			err = r.storage.RedisClient().ZRem(jobsSetKey, id).Err()
+			if err != nil {
+				return nil, err
+			}
+			continue
 		}
-		if job != nil {
-			jobs = append(jobs, *job)
+		if err != nil {
+			return nil, err
 		}
+		jobs = append(jobs, *job)
 	}
 	return jobs, nil
 }
 
+func (r *redisRepository) ClaimJobTransition(id, transition string) (bool, error) {
+	// claims are kept in the hash of the job, so they're removed along
+	// with it, and never recreate the hash of a deleted job.
+	claimed, err := claimTransitionScript.Run(r.storage.RedisClient(), []string{r.jobKey(id)}, transitionField(transition), time.Now().UTC().Format(time.RFC3339Nano)).Int()
+	if err != nil {
+		return false, checkAvailability(err)
+	}
+	if claimed < 0 {
+		return false, db.ErrJobNotFound
+	}
+	return claimed == 1, nil
+}
+
+func (r *redisRepository) ReleaseJobTransition(id, transition string) error {
+	return checkAvailability(r.storage.RedisClient().HDel(r.jobKey(id), transitionField(transition)).Err())
+}
+
+func transitionField(transition string) string {
+	return "transition:" + transition
+}
+
 func (r *redisRepository) jobKey(id string) string {
 	return "job:" + id
 }
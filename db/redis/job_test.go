@@ -62,6 +62,7 @@ func TestCreateJob(t *testing.T) {
 		"streamingparams_protocol":         "hls",
 		"streamingparams_playlistFileName": "hls/playlist.m3u8",
 		"creationTime":                     creationTime.Format(time.RFC3339Nano),
+		"outputs":                          `[{"presetmap":{"name":"preset-1","providerMapping":null,"output":{"extension":""}},"filename":"output1.m3u8"},{"presetmap":{"name":"preset-2","providerMapping":null,"output":{"extension":""}},"filename":"output2.m3u8"}]`,
 	}
 	if !reflect.DeepEqual(items, expected) {
 		pretty.Fdiff(os.Stderr, expected, items)
@@ -126,6 +127,143 @@ func TestCreateJobNoID(t *testing.T) {
 	}
 }
 
+func TestUpdateJob(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := db.Job{ID: "myjob", ProviderName: "elementalconductor", MaxDuration: 3600}
+	err = repo.CreateJob(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.TimedOut = true
+	err = repo.UpdateJob(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJob, err := repo.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*gotJob, job) {
+		t.Errorf("Wrong job. Want %#v. Got %#v.", job, *gotJob)
+	}
+	client := repo.(*redisRepository).storage.RedisClient()
+	defer client.Close()
+	setEntries, err := client.ZRange(jobsSetKey, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{job.ID}; !reflect.DeepEqual(setEntries, expected) {
+		t.Errorf("Wrong job set returned from Redis. Want %#v. Got %#v.", expected, setEntries)
+	}
+}
+
+func TestUpdateJobNotFound(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.UpdateJob(&db.Job{ID: "myjob"})
+	if err != db.ErrJobNotFound {
+		t.Errorf("Wrong error returned by UpdateJob. Want ErrJobNotFound. Got %#v.", err)
+	}
+}
+
+func TestClaimJobTransition(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := db.Job{ID: "myjob", ProviderJobID: "provider-job-1", Status: "started"}
+	err = repo.CreateJob(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var claims int
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := repo.ClaimJobTransition(job.ID, "provider-job-1:failed")
+			if err != nil {
+				t.Error(err)
+			}
+			if claimed {
+				mtx.Lock()
+				claims++
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if claims != 1 {
+		t.Errorf("wrong number of claims of the transition. Want 1. Got %d", claims)
+	}
+	claimed, err := repo.ClaimJobTransition(job.ID, "provider-job-1:retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Error("unexpected claim of another transition")
+	}
+	err = repo.ReleaseJobTransition(job.ID, "provider-job-1:failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed, err = repo.ClaimJobTransition(job.ID, "provider-job-1:failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Error("released transition not claimed again")
+	}
+	gotJob, err := repo.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*gotJob, job) {
+		t.Errorf("wrong job after claiming transitions\nwant %#v\ngot  %#v", job, *gotJob)
+	}
+}
+
+func TestClaimJobTransitionJobNotFound(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := repo.ClaimJobTransition("myjob", "provider-job-1:failed")
+	if err != db.ErrJobNotFound {
+		t.Errorf("wrong error. Want ErrJobNotFound. Got %#v", err)
+	}
+	if claimed {
+		t.Error("unexpected claim of the transition of a missing job")
+	}
+	client := repo.(*redisRepository).storage.RedisClient()
+	if n := client.Exists("job:myjob").Val(); n != 0 {
+		t.Error("claiming the transition of a missing job created it")
+	}
+}
+
 func TestDeleteJob(t *testing.T) {
 	err := cleanRedis()
 	if err != nil {
@@ -193,6 +331,59 @@ func TestGetJob(t *testing.T) {
 	}
 }
 
+func TestGetJobSpec(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := db.Job{
+		ID:           "myjob",
+		ProviderName: "elementalconductor",
+		Outputs: []db.TranscodeOutput{
+			{
+				Preset: db.PresetMap{
+					Name:            "mp4_1080p",
+					ProviderMapping: map[string]string{"elementalconductor": "15"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+				FileName:      "video_1080p.mp4",
+				AudioSelector: "english",
+			},
+			{
+				Preset: db.PresetMap{
+					Name:            "hls_360p",
+					ProviderMapping: map[string]string{"elementalconductor": "16"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+				FileName: "video_360p.m3u8",
+			},
+		},
+		SourceSegments: []db.SourceSegment{
+			{URI: "s3://bucket/intro.mov", Out: "00:00:10:00"},
+			{URI: "s3://bucket/video.mov", In: "00:00:05:00"},
+		},
+		AudioSelectors:  []db.AudioSelector{{Name: "english", Language: "eng"}},
+		ProviderOptions: map[string]interface{}{"skipExistingOutputs": true, "priority": float64(75)},
+		Metadata:        map[string]string{"team": "video"},
+	}
+	err = repo.CreateJob(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJob, err := repo.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*gotJob, job) {
+		pretty.Fdiff(os.Stderr, job, *gotJob)
+		t.Errorf("Wrong job. Want %#v. Got %#v.", job, *gotJob)
+	}
+}
+
 func TestGetJobNotFound(t *testing.T) {
 	err := cleanRedis()
 	if err != nil {
@@ -485,3 +676,88 @@ func TestListJobsFilteringAndLimit(t *testing.T) {
 		t.Errorf("ListJobs({}): wrong list returned. Want %#v. Got %#v", expectedJobs, gotJobs)
 	}
 }
+
+func TestCreateTestJobRetention(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config.Config
+	cfg.Redis = new(storage.Config)
+	cfg.TestJobRetention = 3600
+	repo, err := NewRepository(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", ProviderJobID: "1", Test: true},
+		{ID: "job-2", ProviderName: "encodingcom", ProviderJobID: "2"},
+	}
+	for i := range jobs {
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := repo.(*redisRepository).storage.RedisClient()
+	defer client.Close()
+	ttl, err := client.TTL("job:job-1").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("wrong TTL for test job. Want up to %s. Got %s", time.Hour, ttl)
+	}
+	ttl, err = client.TTL("job:job-2").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl >= 0 {
+		t.Errorf("unexpected TTL for regular job: %s", ttl)
+	}
+}
+
+func TestListJobsRemovesExpiredJobs(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config.Config
+	cfg.Redis = new(storage.Config)
+	cfg.TestJobRetention = 3600
+	repo, err := NewRepository(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", ProviderJobID: "1", Test: true},
+		{ID: "job-2", ProviderName: "encodingcom", ProviderJobID: "2"},
+	}
+	for i := range jobs {
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := repo.(*redisRepository).storage.RedisClient()
+	defer client.Close()
+	// simulates the expiration of the test job
+	err = client.Del("job:job-1").Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJobs, err := repo.ListJobs(db.JobFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotJobs, jobs[1:]) {
+		t.Errorf("ListJobs({}): wrong list returned. Want %#v. Got %#v", jobs[1:], gotJobs)
+	}
+	ids, err := client.ZRange(jobsSetKey, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"job-2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("wrong ids in the set of jobs. Want %#v. Got %#v", want, ids)
+	}
+}
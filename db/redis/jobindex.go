@@ -0,0 +1,296 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
+	"github.com/go-redis/redis"
+)
+
+// Jobs are indexed in sorted sets scored by their creation time, so they can
+// be counted without being loaded: one for each provider and status, one for
+// each provider with the jobs running in it, and one with the test jobs. The
+// keys of the indexes of providers are kept in a set, so they can be listed.
+const (
+	jobIndexesSetKey   = "jobs:indexes"
+	testJobsSetKey     = "jobs:test"
+	jobsIndexedKey     = "jobs:indexed"
+	jobStatusKeyPrefix = "jobs:status:"
+	runningJobsPrefix  = "jobs:running:"
+)
+
+// statuses of jobs, as stored by the service.
+const (
+	statusQueued   = "queued"
+	statusStarted  = "started"
+	statusFinished = "finished"
+)
+
+// jobIndexFields are the fields of the hash of jobs their indexes are derived
+// from.
+var jobIndexFields = []string{"jobID", "providerName", "providerJobID", "status", "test", "creationTime"}
+
+// processingTimeScript sums the processing time of the jobs in the given
+// index created since the given time, returning the number of jobs with a
+// processing time along with the sum.
+var processingTimeScript = redis.NewScript(`
+local count, total = 0, 0
+for _, id in ipairs(redis.call("ZRANGEBYSCORE", KEYS[1], ARGV[1], "+inf")) do
+	local t = tonumber(redis.call("HGET", "job:" .. id, "processingtime"))
+	if t and t > 0 then
+		count = count + 1
+		total = total + t
+	end
+end
+return {count, total}
+`)
+
+func (r *redisRepository) CountJobs(since time.Time) (*db.JobCounts, error) {
+	err := r.prepareJobIndexes()
+	if err != nil {
+		return nil, err
+	}
+	client := r.storage.RedisClient()
+	keys, err := client.SMembers(jobIndexesSetKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	min := strconv.FormatInt(since.UnixNano(), 10)
+	statusCounts := make(map[string]*redis.IntCmd)
+	var testJobs *redis.IntCmd
+	_, err = client.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			if strings.HasPrefix(key, jobStatusKeyPrefix) {
+				statusCounts[key] = pipe.ZCount(key, min, "+inf")
+			}
+		}
+		testJobs = pipe.ZCount(testJobsSetKey, min, "+inf")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := db.JobCounts{
+		ByProvider: make(map[string]map[string]int),
+		TestJobs:   int(testJobs.Val()),
+	}
+	for key, cmd := range statusCounts {
+		if cmd.Val() == 0 {
+			continue
+		}
+		providerName, status := splitJobStatusKey(key)
+		if counts.ByProvider[providerName] == nil {
+			counts.ByProvider[providerName] = make(map[string]int)
+		}
+		counts.ByProvider[providerName][status] = int(cmd.Val())
+		if status != statusFinished {
+			continue
+		}
+		result, err := processingTimeScript.Run(client, []string{key}, min).Result()
+		if err != nil {
+			return nil, err
+		}
+		if values, ok := result.([]interface{}); ok && len(values) == 2 {
+			processed, _ := values[0].(int64)
+			processingTime, _ := values[1].(int64)
+			counts.Processed += int(processed)
+			counts.ProcessingTime += uint(processingTime)
+		}
+	}
+	return &counts, nil
+}
+
+func (r *redisRepository) CountRunningJobs(providerName string) (int, error) {
+	err := r.prepareJobIndexes()
+	if err != nil {
+		return 0, err
+	}
+	count, err := r.storage.RedisClient().ZCard(runningJobsKey(providerName)).Result()
+	return int(count), checkAvailability(err)
+}
+
+func (r *redisRepository) ListRunningJobs(providerName string) ([]db.Job, error) {
+	err := r.prepareJobIndexes()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := r.storage.RedisClient().ZRange(runningJobsKey(providerName), 0, -1).Result()
+	if err != nil {
+		return nil, checkAvailability(err)
+	}
+	jobs := make([]db.Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := r.GetJob(id)
+		if err == db.ErrJobNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, checkAvailability(err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// prepareJobIndexes indexes the jobs stored before the indexes were
+// introduced, once, and removes the test jobs expired by redis from the
+// indexes.
+func (r *redisRepository) prepareJobIndexes() error {
+	err := r.indexStoredJobs()
+	if err != nil {
+		return checkAvailability(err)
+	}
+	return checkAvailability(r.removeExpiredTestJobs())
+}
+
+func (r *redisRepository) indexStoredJobs() error {
+	r.indexMtx.Lock()
+	defer r.indexMtx.Unlock()
+	if r.indexed {
+		return nil
+	}
+	client := r.storage.RedisClient()
+	indexed, err := client.Exists(jobsIndexedKey).Result()
+	if err != nil {
+		return err
+	}
+	if indexed == 0 {
+		ids, err := client.ZRange(jobsSetKey, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			jobKey := r.jobKey(id)
+			err = client.Watch(func(tx *redis.Tx) error {
+				job, err := r.indexedJob(tx, jobKey)
+				if err != nil || job == nil {
+					return err
+				}
+				_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+					indexJob(pipe, job)
+					return nil
+				})
+				return err
+			}, jobKey)
+			if err != nil {
+				return err
+			}
+		}
+		err = client.Set(jobsIndexedKey, time.Now().UTC().Format(time.RFC3339Nano), 0).Err()
+		if err != nil {
+			return err
+		}
+	}
+	r.indexed = true
+	return nil
+}
+
+// removeExpiredTestJobs removes the test jobs whose hashes were expired by
+// redis from the indexes and from the set of jobs.
+func (r *redisRepository) removeExpiredTestJobs() error {
+	if r.config.TestJobRetention == 0 {
+		return nil
+	}
+	client := r.storage.RedisClient()
+	retention := time.Duration(r.config.TestJobRetention) * time.Second
+	ids, err := client.ZRangeByScore(testJobsSetKey, redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Add(-retention).UnixNano(), 10),
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	exists := make([]*redis.IntCmd, len(ids))
+	_, err = client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			exists[i] = pipe.Exists(r.jobKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var expired []interface{}
+	for i, id := range ids {
+		if exists[i].Val() == 0 {
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	keys, err := client.SMembers(jobIndexesSetKey).Result()
+	if err != nil {
+		return err
+	}
+	_, err = client.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, key := range append(keys, testJobsSetKey, jobsSetKey) {
+			pipe.ZRem(key, expired...)
+		}
+		return nil
+	})
+	return err
+}
+
+// indexedJob loads the fields of the job stored in the given key its indexes
+// are derived from, returning nil when the job doesn't exist.
+func (r *redisRepository) indexedJob(tx *redis.Tx, jobKey string) (*db.Job, error) {
+	values, err := tx.HMGet(jobKey, jobIndexFields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	hash := make(map[string]string, len(values))
+	for i, value := range values {
+		if value, ok := value.(string); ok {
+			hash[jobIndexFields[i]] = value
+		}
+	}
+	var job db.Job
+	err = r.storage.Decode(hash, &job)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	return &job, err
+}
+
+// indexJob adds the given job to its indexes.
+func indexJob(pipe redis.Pipeliner, job *db.Job) {
+	member := redis.Z{Member: job.ID, Score: float64(job.CreationTime.UnixNano())}
+	keys := []string{jobStatusKey(job.ProviderName, job.Status)}
+	if job.ProviderJobID != "" && (job.Status == statusQueued || job.Status == statusStarted) {
+		keys = append(keys, runningJobsKey(job.ProviderName))
+	}
+	for _, key := range keys {
+		pipe.ZAdd(key, member)
+		pipe.SAdd(jobIndexesSetKey, key)
+	}
+	if job.Test {
+		pipe.ZAdd(testJobsSetKey, member)
+	}
+}
+
+// unindexJob removes the given job from its indexes.
+func unindexJob(pipe redis.Pipeliner, job *db.Job) {
+	pipe.ZRem(jobStatusKey(job.ProviderName, job.Status), job.ID)
+	pipe.ZRem(runningJobsKey(job.ProviderName), job.ID)
+	pipe.ZRem(testJobsSetKey, job.ID)
+}
+
+func jobStatusKey(providerName, status string) string {
+	return jobStatusKeyPrefix + providerName + ":" + status
+}
+
+func splitJobStatusKey(key string) (providerName, status string) {
+	parts := strings.SplitN(strings.TrimPrefix(key, jobStatusKeyPrefix), ":", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func runningJobsKey(providerName string) string {
+	return runningJobsPrefix + providerName
+}
@@ -0,0 +1,263 @@
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
+	"github.com/go-redis/redis"
+)
+
+func TestCountJobs(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", ProviderJobID: "1", Status: "queued"},
+		{ID: "job-2", ProviderName: "encodingcom", ProviderJobID: "2", Status: "started"},
+		{ID: "job-3", ProviderName: "encodingcom", ProviderJobID: "3", Status: "started", Test: true},
+		{ID: "job-4", ProviderName: "zencoder", ProviderJobID: "4", Status: "queued"},
+		{ID: "job-5", ProviderName: "zencoder", Status: "queued"},
+	}
+	for i := range jobs {
+		if i == 3 {
+			// creation times are stored in milliseconds
+			time.Sleep(2 * time.Millisecond)
+		}
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs[0].Status = "finished"
+	jobs[0].ProcessingTime = 60
+	jobs[1].Status = "finished"
+	jobs[1].ProcessingTime = 30
+	jobs[3].Status = "failed"
+	for _, i := range []int{0, 1, 3} {
+		err = repo.UpdateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = repo.DeleteJob(&jobs[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := repo.CountJobs(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := db.JobCounts{
+		ByProvider: map[string]map[string]int{
+			"encodingcom": {"finished": 2},
+			"zencoder":    {"failed": 1, "queued": 1},
+		},
+		Processed:      2,
+		ProcessingTime: 90,
+	}
+	if !reflect.DeepEqual(*counts, expected) {
+		t.Errorf("CountJobs: wrong counts. Want %#v. Got %#v", expected, *counts)
+	}
+	counts, err = repo.CountJobs(jobs[3].CreationTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = db.JobCounts{
+		ByProvider: map[string]map[string]int{
+			"zencoder": {"failed": 1, "queued": 1},
+		},
+	}
+	if !reflect.DeepEqual(*counts, expected) {
+		t.Errorf("CountJobs: wrong counts in window. Want %#v. Got %#v", expected, *counts)
+	}
+}
+
+func TestCountRunningJobs(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", ProviderJobID: "1", Status: "queued"},
+		{ID: "job-2", ProviderName: "encodingcom", ProviderJobID: "2", Status: "started"},
+		{ID: "job-3", ProviderName: "encodingcom", ProviderJobID: "3", Status: "finished"},
+		{ID: "job-4", ProviderName: "encodingcom", Status: "queued"},
+		{ID: "job-5", ProviderName: "zencoder", ProviderJobID: "5", Status: "started"},
+	}
+	for i := range jobs {
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var tests = []struct {
+		givenTestCase string
+		givenUpdate   func()
+
+		wantRunning map[string]int
+	}{
+		{
+			"jobs sent to the provider",
+			func() {},
+			map[string]int{"encodingcom": 2, "zencoder": 1, "elementalconductor": 0},
+		},
+		{
+			"held job sent to the provider",
+			func() { jobs[3].ProviderJobID = "4" },
+			map[string]int{"encodingcom": 3, "zencoder": 1},
+		},
+		{
+			"jobs completed",
+			func() { jobs[0].Status = "finished"; jobs[4].Status = "canceled" },
+			map[string]int{"encodingcom": 2, "zencoder": 0},
+		},
+	}
+	for _, test := range tests {
+		test.givenUpdate()
+		for i := range jobs {
+			err = repo.UpdateJob(&jobs[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for providerName, want := range test.wantRunning {
+			running, err := repo.CountRunningJobs(providerName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if running != want {
+				t.Errorf("%s: wrong number of running jobs in %q. Want %d. Got %d", test.givenTestCase, providerName, want, running)
+			}
+			runningJobs, err := repo.ListRunningJobs(providerName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(runningJobs) != want {
+				t.Errorf("%s: wrong running jobs in %q. Want %d. Got %#v", test.givenTestCase, providerName, want, runningJobs)
+			}
+			for _, job := range runningJobs {
+				if job.ProviderName != providerName || (job.Status != "queued" && job.Status != "started") {
+					t.Errorf("%s: unexpected job listed as running in %q: %#v", test.givenTestCase, providerName, job)
+				}
+			}
+		}
+	}
+}
+
+func TestCountJobsIndexesStoredJobs(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", ProviderJobID: "1", Status: "started"},
+		{ID: "job-2", ProviderName: "encodingcom", ProviderJobID: "2", Status: "finished", ProcessingTime: 10, Test: true},
+	}
+	for i := range jobs {
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := repo.(*redisRepository).storage.RedisClient()
+	defer client.Close()
+	// simulates jobs stored before the indexes were introduced
+	err = deleteKeys("jobs:*", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := repo.CountJobs(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := db.JobCounts{
+		ByProvider:     map[string]map[string]int{"encodingcom": {"started": 1, "finished": 1}},
+		TestJobs:       1,
+		Processed:      1,
+		ProcessingTime: 10,
+	}
+	if !reflect.DeepEqual(*counts, expected) {
+		t.Errorf("CountJobs: wrong counts. Want %#v. Got %#v", expected, *counts)
+	}
+	running, err := repo.CountRunningJobs("encodingcom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running != 1 {
+		t.Errorf("wrong number of running jobs. Want 1. Got %d", running)
+	}
+}
+
+func TestCountJobsRemovesExpiredJobs(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config.Config
+	cfg.Redis = new(storage.Config)
+	cfg.TestJobRetention = 3600
+	repo, err := NewRepository(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []db.Job{
+		{ID: "job-1", ProviderName: "encodingcom", ProviderJobID: "1", Status: "started", Test: true},
+		{ID: "job-2", ProviderName: "encodingcom", ProviderJobID: "2", Status: "started"},
+	}
+	for i := range jobs {
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := repo.(*redisRepository).storage.RedisClient()
+	defer client.Close()
+	// simulates the expiration of the test job
+	err = client.Del("job:job-1").Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.ZAdd(testJobsSetKey, redis.Z{Member: "job-1", Score: float64(time.Now().Add(-2 * time.Hour).UnixNano())}).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := repo.CountJobs(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := db.JobCounts{ByProvider: map[string]map[string]int{"encodingcom": {"started": 1}}}
+	if !reflect.DeepEqual(*counts, expected) {
+		t.Errorf("CountJobs: wrong counts. Want %#v. Got %#v", expected, *counts)
+	}
+	running, err := repo.CountRunningJobs("encodingcom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running != 1 {
+		t.Errorf("wrong number of running jobs. Want 1. Got %d", running)
+	}
+	ids, err := client.ZRange(jobsSetKey, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"job-2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("wrong ids in the set of jobs. Want %#v. Got %#v", want, ids)
+	}
+}
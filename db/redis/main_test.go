@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// testLockKey is the key of the lock held by the tests of the packages using
+// the redis server, as they run in parallel and clean the keys of each other.
+const testLockKey = "video-transcoding-api:tests:lock"
+
+func TestMain(m *testing.M) {
+	unlock, err := lockRedis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to lock the redis server: %s\n", err)
+		unlock = func() {}
+	}
+	code := m.Run()
+	unlock()
+	os.Exit(code)
+}
+
+// lockRedis waits for the lock of the tests using the redis server, returning
+// the function that releases it.
+func lockRedis() (func(), error) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	token := strconv.FormatInt(time.Now().UnixNano(), 10)
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		ok, err := client.SetNX(testLockKey, token, 2*time.Minute).Result()
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			client.Close()
+			return nil, fmt.Errorf("timed out waiting for %s", testLockKey)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return func() {
+		defer client.Close()
+		if value, _ := client.Get(testLockKey).Result(); value == token {
+			client.Del(testLockKey)
+		}
+	}, nil
+}
@@ -58,6 +58,34 @@ func (r *redisRepository) GetPresetMap(name string) (*db.PresetMap, error) {
 	return &presetMap, err
 }
 
+func (r *redisRepository) GetPresetMaps(names []string) (map[string]*db.PresetMap, error) {
+	cmds := make(map[string]*redis.StringStringMapCmd, len(names))
+	_, err := r.storage.RedisClient().Pipelined(func(pipe redis.Pipeliner) error {
+		for _, name := range names {
+			if _, ok := cmds[name]; !ok {
+				cmds[name] = pipe.HGetAll(r.presetMapKey(name))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, checkAvailability(err)
+	}
+	presetMaps := make(map[string]*db.PresetMap, len(cmds))
+	for name, cmd := range cmds {
+		presetMap := db.PresetMap{Name: name, ProviderMapping: make(map[string]string)}
+		err = r.storage.Decode(cmd.Val(), &presetMap)
+		if err == storage.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		presetMaps[name] = &presetMap
+	}
+	return presetMaps, nil
+}
+
 func (r *redisRepository) ListPresetMaps() ([]db.PresetMap, error) {
 	presetMapNames, err := r.storage.RedisClient().SMembers(presetmapsSetKey).Result()
 	if err != nil {
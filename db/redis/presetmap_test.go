@@ -41,6 +41,7 @@ func TestCreatePresetMap(t *testing.T) {
 	expectedItems := map[string]string{
 		"pmapping_elementalconductor": "abc123",
 		"pmapping_elastictranscoder":  "1281742-93939",
+		"output_audiodescription":     "false",
 		"output_extension":            "ts",
 		"presetmap_name":              "mypreset",
 	}
@@ -104,6 +105,7 @@ func TestUpdatePresetMap(t *testing.T) {
 	expectedItems := map[string]string{
 		"pmapping_elemental":         "abc1234",
 		"pmapping_elastictranscoder": "def123",
+		"output_audiodescription":    "false",
 		"output_extension":           "mp4",
 		"presetmap_name":             "mypresetmap",
 	}
@@ -216,6 +218,46 @@ func TestGetPresetMapNotFound(t *testing.T) {
 	}
 }
 
+func TestGetPresetMaps(t *testing.T) {
+	err := cleanRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepository(&config.Config{Redis: new(storage.Config)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetmaps := []db.PresetMap{
+		{
+			Name:            "presetmap-1",
+			ProviderMapping: map[string]string{"elementalconductor": "abc123"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		},
+		{
+			Name:            "presetmap-2",
+			ProviderMapping: map[string]string{"elementalconductor": "abc124"},
+			OutputOpts:      db.OutputOptions{Extension: "webm"},
+		},
+	}
+	for i := range presetmaps {
+		err = repo.CreatePresetMap(&presetmaps[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	gotPresetMaps, err := repo.GetPresetMaps([]string{"presetmap-2", "presetmap-3", "presetmap-1", "presetmap-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]*db.PresetMap{
+		"presetmap-1": &presetmaps[0],
+		"presetmap-2": &presetmaps[1],
+	}
+	if !reflect.DeepEqual(gotPresetMaps, expected) {
+		t.Errorf("Wrong presetmaps. Want %#v. Got %#v.", expected, gotPresetMaps)
+	}
+}
+
 func TestListPresetMaps(t *testing.T) {
 	err := cleanRedis()
 	if err != nil {
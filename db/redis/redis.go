@@ -1,6 +1,10 @@
 package redis
 
 import (
+	"io"
+	"net"
+	"sync"
+
 	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/db"
 	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
@@ -18,4 +22,34 @@ func NewRepository(cfg *config.Config) (db.Repository, error) {
 type redisRepository struct {
 	config  *config.Config
 	storage *storage.Storage
+
+	// whether the jobs stored before the job indexes were introduced
+	// are known to be indexed
+	indexMtx sync.Mutex
+	indexed  bool
+}
+
+// unreachableErrors lists the messages of the errors returned by the redis
+// client when it can't get a connection to the server.
+var unreachableErrors = []string{
+	"redis: connection pool timeout",
+	"redis: all sentinels are unreachable",
+}
+
+// checkAvailability converts errors caused by failing to reach redis into
+// db.UnavailableError, so callers can tell them apart from errors caused by
+// the operation itself.
+func checkAvailability(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(net.Error); ok || err == io.EOF {
+		return db.UnavailableError{Err: err}
+	}
+	for _, msg := range unreachableErrors {
+		if err.Error() == msg {
+			return db.UnavailableError{Err: err}
+		}
+	}
+	return err
 }
@@ -1,6 +1,13 @@
 package redis
 
-import "github.com/go-redis/redis"
+import (
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
+	"github.com/go-redis/redis"
+)
 
 func cleanRedis() error {
 	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
@@ -17,6 +24,10 @@ func cleanRedis() error {
 	if err != nil {
 		return err
 	}
+	err = deleteKeys("jobs:*", client)
+	if err != nil {
+		return err
+	}
 	err = deleteKeys(presetmapsSetKey, client)
 	if err != nil {
 		return err
@@ -39,3 +50,24 @@ func deleteKeys(pattern string, client *redis.Client) error {
 	}
 	return err
 }
+
+func TestRepositoryUnavailable(t *testing.T) {
+	var cfg config.Config
+	cfg.Redis = &storage.Config{RedisAddr: "127.0.0.1:1"}
+	repo, err := NewRepository(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.CreateJob(&db.Job{ID: "job1"})
+	if _, ok := err.(db.UnavailableError); !ok {
+		t.Errorf("CreateJob: wrong error returned. Want db.UnavailableError. Got %#v", err)
+	}
+	_, err = repo.GetPresetMaps([]string{"preset-1"})
+	if _, ok := err.(db.UnavailableError); !ok {
+		t.Errorf("GetPresetMaps: wrong error returned. Want db.UnavailableError. Got %#v", err)
+	}
+	err = repo.CreateJob(&db.Job{})
+	if _, ok := err.(db.UnavailableError); ok {
+		t.Error("CreateJob: invalid jobs shouldn't be reported as datastore errors")
+	}
+}
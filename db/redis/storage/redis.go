@@ -2,6 +2,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -88,6 +89,13 @@ func (s *Storage) Save(key string, hash interface{}) error {
 
 // FieldMap extract the map of fields from the given type (which can be a
 // struct, a map[string]string or pointer to those).
+//
+// Struct fields tagged with the json option (e.g. `redis-hash:"outputs,json"`)
+// are stored JSON-encoded in a single field, for values the hash can't
+// represent, like slices of structs. Fields tagged with the omitzero option
+// aren't stored when they hold the zero value of their type, like false or 0,
+// so it should be used only in fields that never go back to zero, as
+// updating the hash doesn't remove the fields left out.
 func (s *Storage) FieldMap(hash interface{}) (map[string]interface{}, error) {
 	if hash == nil {
 		return nil, errors.New("no fields provided")
@@ -163,6 +171,23 @@ func (s *Storage) structToFieldList(value reflect.Value, prefixes ...string) (ma
 		} else {
 			if parts[0] != "" {
 				key := strings.Join(append(prefixes, parts[0]), "_")
+				if hasOption(parts, "json") {
+					if hasOption(parts, "omitempty") && isEmpty(fieldValue) {
+						continue
+					}
+					data, err := json.Marshal(fieldValue.Interface())
+					if err != nil {
+						return nil, err
+					}
+					fields[key] = string(data)
+					continue
+				}
+				if fieldValue.Kind() == reflect.Ptr {
+					if fieldValue.IsNil() {
+						continue
+					}
+					fieldValue = fieldValue.Elem()
+				}
 				var strValue string
 				iface := fieldValue.Interface()
 				switch v := iface.(type) {
@@ -176,6 +201,9 @@ func (s *Storage) structToFieldList(value reflect.Value, prefixes ...string) (ma
 				if parts[len(parts)-1] == "omitempty" && strValue == "" {
 					continue
 				}
+				if hasOption(parts, "omitzero") && isZero(fieldValue) {
+					continue
+				}
 				fields[key] = strValue
 			}
 		}
@@ -183,6 +211,44 @@ func (s *Storage) structToFieldList(value reflect.Value, prefixes ...string) (ma
 	return fields, nil
 }
 
+// hasOption checks whether the given options of a redis-hash tag, which
+// follow the name of the field, include the given one.
+func hasOption(parts []string, option string) bool {
+	for _, part := range parts[1:] {
+		if part == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty checks whether the given value, stored as JSON, is nil or has no
+// items.
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// isZero checks whether the given value is the zero value of its type.
+func isZero(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	}
+	return false
+}
+
 // Load loads the given key in the given output. The output must be a pointer
 // to a struct or a map[string]string.
 func (s *Storage) Load(key string, out interface{}) error {
@@ -190,11 +256,22 @@ func (s *Storage) Load(key string, out interface{}) error {
 	if value.Kind() != reflect.Ptr {
 		return errors.New("please provide a pointer for getting result from the database")
 	}
-	value = value.Elem()
 	result, err := s.RedisClient().HGetAll(key).Result()
 	if err != nil {
 		return err
 	}
+	return s.Decode(result, out)
+}
+
+// Decode loads the given hash, as returned by HGETALL, in the given output,
+// for hashes fetched in batches. The output must be a pointer to a struct or
+// a map[string]string.
+func (s *Storage) Decode(result map[string]string, out interface{}) error {
+	value := reflect.ValueOf(out)
+	if value.Kind() != reflect.Ptr {
+		return errors.New("please provide a pointer for getting result from the database")
+	}
+	value = value.Elem()
 	if len(result) < 1 {
 		return ErrNotFound
 	}
@@ -260,6 +337,17 @@ func (s *Storage) loadStruct(in map[string]string, out reflect.Value, prefixes .
 		} else {
 			key := strings.Join(append(prefixes, parts[0]), "_")
 			if value, ok := in[key]; ok {
+				if hasOption(parts, "json") {
+					err := json.Unmarshal([]byte(value), fieldValue.Addr().Interface())
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				if fieldValue.Kind() == reflect.Ptr {
+					fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+					fieldValue = fieldValue.Elem()
+				}
 				switch fieldValue.Kind() {
 				case reflect.Slice:
 					values := strings.Split(value, "%%%")
@@ -315,6 +315,40 @@ func TestFieldMap(t *testing.T) {
 				"preset_audio_codec":         "aac",
 			},
 		},
+		{
+			"omitempty and omitzero with zero values",
+			Options{},
+			map[string]interface{}{
+				"attempts": "0",
+			},
+		},
+		{
+			"omitempty and omitzero with non-zero values",
+			Options{Name: "opts", Tags: []string{"a", "b"}, Attempts: 2, Retries: 3, Priority: -1, Enabled: true, Verbose: new(bool)},
+			map[string]interface{}{
+				"name":     "opts",
+				"tags":     "a%%%b",
+				"attempts": "2",
+				"retries":  "3",
+				"priority": "-1",
+				"enabled":  "true",
+				"verbose":  "false",
+			},
+		},
+		{
+			"json fields",
+			Playlist{
+				Name:     "main",
+				Segments: []Segment{{URI: "seg1.ts", Duration: 6}, {URI: "seg2.ts"}},
+				Labels:   map[string]string{"lang": "en"},
+			},
+			map[string]interface{}{
+				"name":     "main",
+				"segments": `[{"uri":"seg1.ts","duration":6},{"uri":"seg2.ts"}]`,
+				"labels":   `{"lang":"en"}`,
+				"extras":   "null",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -342,6 +376,7 @@ func TestLoadStruct(t *testing.T) {
 		"weight":            "159.332",
 		"birth":             date.Format(time.RFC3339Nano),
 		"colors":            "red%%%green%%%blue%%%black",
+		"verified":          "false",
 		"address_number":    "-2",
 		"address_main":      "true",
 		"address_city_name": "New York",
@@ -364,6 +399,7 @@ func TestLoadStruct(t *testing.T) {
 	expectedPerson.Weight = 159.332
 	expectedPerson.BirthTime = date
 	expectedPerson.PreferredColors = []string{"red", "green", "blue", "black"}
+	expectedPerson.Verified = new(bool)
 	err = storage.Load("test-key", &person)
 	if err != nil {
 		t.Fatal(err)
@@ -373,6 +409,42 @@ func TestLoadStruct(t *testing.T) {
 	}
 }
 
+func TestLoadJSON(t *testing.T) {
+	storage, err := NewStorage(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := storage.RedisClient()
+	defer client.Close()
+	expected := Playlist{
+		Name:     "main",
+		Segments: []Segment{{URI: "seg1.ts", Duration: 6}, {URI: "seg2.ts"}},
+		Labels:   map[string]string{"lang": "en"},
+		Extras:   []string{"a", "b"},
+	}
+	err = storage.Save("test-key", expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Del("test-key")
+	var playlist Playlist
+	err = storage.Load("test-key", &playlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(playlist, expected) {
+		t.Errorf("Didn't load data to struct\nwant %#v\ngot  %#v", expected, playlist)
+	}
+	err = storage.Save("test-key", map[string]string{"segments": "not json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.Load("test-key", &playlist)
+	if err == nil {
+		t.Error("Unexpected <nil> error loading invalid JSON")
+	}
+}
+
 func TestLoadMap(t *testing.T) {
 	storage, err := NewStorage(&Config{})
 	if err != nil {
@@ -10,6 +10,7 @@ type Person struct {
 	Weight           float64   `redis-hash:"weight"`
 	BirthTime        time.Time `redis-hash:"birth"`
 	PreferredColors  []string  `redis-hash:"colors"`
+	Verified         *bool     `redis-hash:"verified"`
 	NonTagged        string
 	unexported       string
 	unexportedTagged string `redis-hash:"unexported"`
@@ -89,3 +90,25 @@ type AudioPreset struct {
 	Codec   string `redis-hash:"codec,omitempty"`
 	Bitrate string `redis-hash:"bitrate,omitempty"`
 }
+
+type Options struct {
+	Name     string   `redis-hash:"name,omitempty"`
+	Tags     []string `redis-hash:"tags,omitempty"`
+	Attempts uint     `redis-hash:"attempts,omitempty"`
+	Retries  uint     `redis-hash:"retries,omitzero"`
+	Priority int      `redis-hash:"priority,omitzero"`
+	Enabled  bool     `redis-hash:"enabled,omitzero"`
+	Verbose  *bool    `redis-hash:"verbose"`
+}
+
+type Playlist struct {
+	Name     string            `redis-hash:"name"`
+	Segments []Segment         `redis-hash:"segments,json,omitempty"`
+	Labels   map[string]string `redis-hash:"labels,json,omitempty"`
+	Extras   []string          `redis-hash:"extras,json"`
+}
+
+type Segment struct {
+	URI      string `json:"uri"`
+	Duration uint   `json:"duration,omitempty"`
+}
@@ -27,6 +27,17 @@ var (
 	ErrLocalPresetAlreadyExists = errors.New("local preset already exists")
 )
 
+// UnavailableError is the error returned by repositories when the underlying
+// datastore can't be reached, as opposed to errors caused by the operation
+// itself.
+type UnavailableError struct {
+	Err error
+}
+
+func (e UnavailableError) Error() string {
+	return "datastore unavailable: " + e.Err.Error()
+}
+
 // Repository represents the repository for persisting types of the API.
 type Repository interface {
 	JobRepository
@@ -38,9 +49,33 @@ type Repository interface {
 // persistence.
 type JobRepository interface {
 	CreateJob(*Job) error
+	UpdateJob(*Job) error
 	DeleteJob(*Job) error
 	GetJob(id string) (*Job, error)
 	ListJobs(JobFilter) ([]Job, error)
+
+	// CountJobs counts the jobs created since the given time by provider
+	// and status, without loading them.
+	CountJobs(since time.Time) (*JobCounts, error)
+
+	// CountRunningJobs counts the jobs sent to the given provider whose
+	// last known status is queued or started.
+	CountRunningJobs(providerName string) (int, error)
+
+	// ListRunningJobs lists the jobs sent to the given provider whose last
+	// known status is queued or started.
+	ListRunningJobs(providerName string) ([]Job, error)
+
+	// ClaimJobTransition atomically records that the given transition of
+	// the job is being acted upon, returning false when it was already
+	// claimed. It guards the side effects of the changes in the status of
+	// jobs, such as retries and notifications, so they happen once even
+	// when concurrent requests observe the same change.
+	ClaimJobTransition(id, transition string) (bool, error)
+
+	// ReleaseJobTransition releases a claimed transition of the job whose
+	// side effects failed, so it can be claimed again.
+	ReleaseJobTransition(id, transition string) error
 }
 
 // JobFilter contains a set of parameters for filtering the list of jobs in
@@ -53,6 +88,21 @@ type JobFilter struct {
 	Limit uint
 }
 
+// JobCounts is the number of jobs created in a time window, returned by
+// CountJobs.
+type JobCounts struct {
+	// number of jobs by provider, then by their last known status
+	ByProvider map[string]map[string]int
+
+	// number of test jobs, also counted in ByProvider
+	TestJobs int
+
+	// number of finished jobs with a known processing time, along with the
+	// sum of their processing times, in seconds
+	Processed      int
+	ProcessingTime uint
+}
+
 // PresetMapRepository is the interface that defines the set of methods for
 // managing PresetMap persistence.
 type PresetMapRepository interface {
@@ -60,6 +110,11 @@ type PresetMapRepository interface {
 	UpdatePresetMap(*PresetMap) error
 	DeletePresetMap(*PresetMap) error
 	GetPresetMap(name string) (*PresetMap, error)
+
+	// GetPresetMaps loads the preset maps with the given names at once,
+	// keyed by name. Preset maps that don't exist are left out of the
+	// result.
+	GetPresetMaps(names []string) (map[string]*PresetMap, error)
 	ListPresetMaps() ([]PresetMap, error)
 }
 
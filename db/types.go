@@ -2,6 +2,10 @@ package db
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -45,9 +49,222 @@ type Job struct {
 	// Output list of the given job
 	//
 	// required: true
-	Outputs []TranscodeOutput `redis-hash:"-" json:"outputs"`
+	Outputs []TranscodeOutput `redis-hash:"outputs,json,omitempty" json:"outputs"`
+
+	// list of node tags used by the provider for placing the job on
+	// specific nodes. Only supported by Elemental Conductor.
+	//
+	// required: false
+	NodeTags []string `redis-hash:"nodetags,omitempty" json:"nodeTags,omitempty"`
+
+	// access control applied to the output files written to the
+	// storage, either private or public-read. Defaults to private.
+	//
+	// required: false
+	OutputACL string `redis-hash:"outputacl,omitempty" json:"outputACL,omitempty"`
+
+	// list of timecodes (HH:MM:SS:FF) of the frames that should be
+	// captured as images
+	//
+	// required: false
+	FrameCaptures []string `redis-hash:"framecaptures,omitempty" json:"frameCaptures,omitempty"`
+
+	// cluster of the provider chosen for running the job, when jobs are
+	// balanced among multiple clusters
+	//
+	// required: false
+	Cluster string `redis-hash:"cluster,omitempty" json:"cluster,omitempty"`
+
+	// maximum duration of the job, in seconds, measured from the moment
+	// the provider starts running it. Jobs still running after that are
+	// canceled and reported as failed. Defaults to the JOB_MAX_DURATION
+	// setting; 0 means no timeout.
+	//
+	// required: false
+	MaxDuration uint `redis-hash:"maxduration,omitzero" json:"maxDuration,omitempty"`
+
+	// last status of the job reported by the provider, updated whenever
+	// the status of the job is retrieved
+	//
+	// required: false
+	Status string `redis-hash:"status,omitempty" json:"status,omitempty"`
+
+	// time, in seconds, the provider took for processing the job, stored
+	// along with the status of the job once it completes
+	//
+	// required: false
+	ProcessingTime uint `redis-hash:"processingtime,omitzero" json:"processingTime,omitempty"`
+
+	// duration, in seconds, of the source media as reported by the
+	// provider, stored along with the status of the job
+	//
+	// required: false
+	SourceDuration uint `redis-hash:"sourceduration,omitzero" json:"sourceDuration,omitempty"`
+
+	// whether the job was canceled for exceeding its maximum duration
+	//
+	// required: false
+	TimedOut bool `redis-hash:"timedout,omitzero" json:"timedOut,omitempty"`
+
+	// whether the priority of the job should be raised if it stays queued
+	// for longer than the JOB_PRIORITY_ESCALATION_THRESHOLD setting
+	//
+	// required: false
+	EscalatePriority bool `redis-hash:"escalatepriority,omitzero" json:"escalatePriority,omitempty"`
+
+	// whether the priority of the job has been raised
+	//
+	// required: false
+	PriorityEscalated bool `redis-hash:"priorityescalated,omitzero" json:"priorityEscalated,omitempty"`
+
+	// provider-specific settings not covered by the job, applied by the
+	// providers that support them and ignored by the others
+	//
+	// required: false
+	ProviderOptions map[string]interface{} `redis-hash:"provideroptions,json,omitempty" json:"providerOptions,omitempty"`
+
+	// list of segments stitched together as the input of the job, in
+	// place of a single source media. Only supported by Elemental
+	// Conductor.
+	//
+	// required: false
+	SourceSegments []SourceSegment `redis-hash:"sourcesegments,json,omitempty" json:"sourceSegments,omitempty"`
+
+	// list of audio tracks of the source that outputs may use as their
+	// audio, picked by track number or language. Only supported by
+	// Elemental Conductor.
+	//
+	// required: false
+	AudioSelectors []AudioSelector `redis-hash:"audioselectors,json,omitempty" json:"audioSelectors,omitempty"`
+
+	// arbitrary metadata of the job, kept by the API for its clients
+	//
+	// required: false
+	Metadata map[string]string `redis-hash:"metadata,json,omitempty" json:"metadata,omitempty"`
+
+	// keys of the metadata attached as tags to the job in the provider,
+	// for cost allocation in its billing reports. Only supported by
+	// Elemental Conductor.
+	//
+	// required: false
+	ProviderTags []string `redis-hash:"providertags,omitempty" json:"providerTags,omitempty"`
+
+	// id of the job whose failed outputs were resubmitted in this job
+	//
+	// required: false
+	ResubmittedFrom string `redis-hash:"resubmittedfrom,omitempty" json:"resubmittedFrom,omitempty"`
+
+	// maximum number of times the job is submitted again after failing in
+	// the provider for transient reasons, bounded by the JOB_MAX_RETRIES
+	// setting. 0 means no retries.
+	//
+	// required: false
+	MaxRetries uint `redis-hash:"maxretries,omitzero" json:"maxRetries,omitempty"`
+
+	// ids of the provider jobs of the previous attempts, which failed
+	//
+	// required: false
+	FailedAttempts []string `redis-hash:"failedattempts,omitempty" json:"failedAttempts,omitempty"`
+
+	// whether this is a test job, removed after the TEST_JOB_RETENTION
+	// setting instead of being kept like other jobs
+	//
+	// required: false
+	Test bool `redis-hash:"test,omitzero" json:"test,omitempty"`
+
+	// spec of the job as submitted to the provider, with credentials
+	// redacted, and its content type. Served by /jobs/{jobId}/spec instead
+	// of being part of the job.
+	ProviderSpec            string `redis-hash:"providerspec,omitempty" json:"-"`
+	ProviderSpecContentType string `redis-hash:"providerspeccontenttype,omitempty" json:"-"`
+
+	// human readable name of the job, set on the job in the provider for
+	// identifying it in the interface of the provider
+	//
+	// required: false
+	Name string `redis-hash:"name,omitempty" json:"name,omitempty"`
+
+	// container the provider should read the source as, for sources it
+	// can't detect (e.g. files without an extension). Empty means
+	// auto-detection
+	//
+	// required: false
+	InputFormat string `redis-hash:"inputFormat,omitempty" json:"inputFormat,omitempty"`
+
+	// email address notified when the job finishes, fails or is canceled
+	//
+	// required: false
+	NotificationEmail string `redis-hash:"notificationEmail,omitempty" json:"notificationEmail,omitempty"`
+
+	// whether a JSON manifest listing the outputs of the job should be
+	// written to its destination once it finishes
+	//
+	// required: false
+	WriteManifest bool `redis-hash:"writemanifest,omitzero" json:"writeManifest,omitempty"`
+
+	// location of the manifest of the outputs of the job, once written
+	//
+	// required: false
+	Manifest string `redis-hash:"manifest,omitempty" json:"manifest,omitempty"`
+
+	// id of the job that must finish before this job is sent to the
+	// provider. The job fails if that job fails or is canceled.
+	//
+	// required: false
+	DependsOn string `redis-hash:"dependson,omitempty" json:"dependsOn,omitempty"`
+
+	// ids of the jobs waiting for this job to finish
+	//
+	// required: false
+	Dependents []string `redis-hash:"dependents,omitempty" json:"dependents,omitempty"`
+}
+
+// SourceSegment represents a clip of a media file used as part of the input
+// of a job.
+type SourceSegment struct {
+	// location of the media file
+	//
+	// required: true
+	URI string `json:"uri"`
+
+	// timecode (HH:MM:SS:FF), relative to the beginning of the file, of
+	// the first frame of the clip. Defaults to the beginning of the file.
+	//
+	// required: false
+	In string `json:"in,omitempty"`
+
+	// timecode (HH:MM:SS:FF), relative to the beginning of the file, of
+	// the last frame of the clip. Defaults to the end of the file.
+	//
+	// required: false
+	Out string `json:"out,omitempty"`
+}
+
+// AudioSelector picks an audio track of the source of a job, either by its
+// number or by its language.
+type AudioSelector struct {
+	// name used by the outputs for referencing the selector
+	//
+	// required: true
+	Name string `json:"name"`
+
+	// number of the audio track, starting at 1
+	//
+	// required: false
+	Track uint `json:"track,omitempty"`
+
+	// ISO 639-2 code of the language of the audio track (e.g. eng)
+	//
+	// required: false
+	Language string `json:"language,omitempty"`
 }
 
+// Access control lists supported for the output files of a job.
+const (
+	OutputACLPrivate    = "private"
+	OutputACLPublicRead = "public-read"
+)
+
 // TranscodeOutput represents a transcoding output. It's a combination of the
 // preset and the output file name.
 type TranscodeOutput struct {
@@ -60,6 +277,18 @@ type TranscodeOutput struct {
 	//
 	// required: true
 	FileName string `redis-hash:"filename" json:"filename"`
+
+	// name of the audio selector of the job used as the audio of the
+	// output. Defaults to the first audio track of the source
+	//
+	// required: false
+	AudioSelector string `redis-hash:"audioselector,omitempty" json:"audioSelector,omitempty"`
+
+	// BCP 47 tag of the language of the audio of the output (e.g. en or
+	// pt-BR), used for labeling the rendition in HLS and DASH manifests
+	//
+	// required: false
+	AudioLanguage string `redis-hash:"audiolanguage,omitempty" json:"audioLanguage,omitempty"`
 }
 
 // StreamingParams represents the params necessary to create Adaptive Streaming jobs
@@ -71,7 +300,8 @@ type StreamingParams struct {
 	// required: true
 	SegmentDuration uint `redis-hash:"segmentDuration" json:"segmentDuration"`
 
-	// the protocol name (hls or dash)
+	// the protocol name (hls, dash or cmaf). CMAF jobs write both HLS and
+	// DASH manifests referencing the same fragmented MP4 segments
 	//
 	// required: true
 	Protocol string `redis-hash:"protocol" json:"protocol"`
@@ -79,8 +309,70 @@ type StreamingParams struct {
 	// the playlist file name
 	// required: true
 	PlaylistFileName string `redis-hash:"playlistFileName" json:"playlistFileName,omitempty"`
+
+	// how the segments of CMAF jobs are written: single-file, with
+	// byte-range segments, or segmented, with one file per segment.
+	// Defaults to single-file
+	//
+	// required: false
+	FragmentType string `redis-hash:"fragmentType,omitempty" json:"fragmentType,omitempty"`
+
+	// how the audio of HLS jobs is laid out: muxed, with the audio in
+	// each variant, or demuxed, with the audio in a rendition group
+	// referenced by the variants. Defaults to muxed
+	//
+	// required: false
+	HLSLayout string `redis-hash:"hlsLayout,omitempty" json:"hlsLayout,omitempty"`
+
+	// absolute http or https URL prepended to the variant playlists and
+	// segments referenced by the manifests of HLS jobs (e.g. the CDN
+	// location of the playlist). Defaults to relative URLs
+	//
+	// required: false
+	BaseURL string `redis-hash:"baseURL,omitempty" json:"baseURL,omitempty"`
+
+	// template of the file names of the segments of HLS jobs, ending with
+	// the {index} placeholder and optionally including the {bitrate}
+	// placeholder (e.g. seg_{bitrate}_{index}). Defaults to the naming of
+	// the provider
+	//
+	// required: false
+	SegmentNameTemplate string `redis-hash:"segmentNameTemplate,omitempty" json:"segmentNameTemplate,omitempty"`
+
+	// order of the variants in the master playlist of HLS jobs, sorted by
+	// the bandwidth of their presets: bandwidth-asc, listing the lowest
+	// bandwidth first, or bandwidth-desc. Defaults to the order of the
+	// outputs
+	//
+	// required: false
+	VariantOrder string `redis-hash:"variantOrder,omitempty" json:"variantOrder,omitempty"`
 }
 
+// Fragment types supported for the segments of CMAF jobs.
+const (
+	FragmentTypeSingleFile = "single-file"
+	FragmentTypeSegmented  = "segmented"
+)
+
+// Layouts supported for the audio of HLS jobs.
+const (
+	HLSLayoutMuxed   = "muxed"
+	HLSLayoutDemuxed = "demuxed"
+)
+
+// Orders of the variants in the master playlist of HLS jobs.
+const (
+	VariantOrderBandwidthAsc  = "bandwidth-asc"
+	VariantOrderBandwidthDesc = "bandwidth-desc"
+)
+
+// Placeholders of the segment name templates of HLS jobs, replaced with the
+// number of the segment and the bitrate of the output.
+const (
+	SegmentNameIndex   = "{index}"
+	SegmentNameBitrate = "{bitrate}"
+)
+
 // LocalPreset is a struct to persist encoding configurations. Some providers don't have
 // the ability to store presets on it's side so we persist locally.
 //
@@ -106,12 +398,24 @@ type Preset struct {
 	TwoPass     bool        `json:"twoPass" redis-hash:"twopass"`
 	Video       VideoPreset `json:"video" redis-hash:"video,expand"`
 	Audio       AudioPreset `json:"audio" redis-hash:"audio,expand"`
+
+	// whether the outputs of the preset include video and audio. Both
+	// default to true, and at least one of them must be enabled
+	IncludeVideo *bool `json:"includeVideo,omitempty" redis-hash:"includevideo"`
+	IncludeAudio *bool `json:"includeAudio,omitempty" redis-hash:"includeaudio"`
+
+	// provider-specific settings not covered by the preset, applied by
+	// the providers that support them and ignored by the others
+	ProviderOptions map[string]interface{} `json:"providerOptions,omitempty" redis-hash:"-"`
 }
 
 // VideoPreset defines the set of parameters for video on a given preset
 type VideoPreset struct {
-	Profile       string `json:"profile,omitempty" redis-hash:"profile,omitempty"`
-	ProfileLevel  string `json:"profileLevel,omitempty" redis-hash:"profilelevel,omitempty"`
+	// profile and level of the codec. H.264 supports the baseline, main
+	// and high profiles, and H.265 the main and main10 profiles
+	Profile      string `json:"profile,omitempty" redis-hash:"profile,omitempty"`
+	ProfileLevel string `json:"profileLevel,omitempty" redis-hash:"profilelevel,omitempty"`
+
 	Width         string `json:"width,omitempty" redis-hash:"width,omitempty"`
 	Height        string `json:"height,omitempty" redis-hash:"height,omitempty"`
 	Codec         string `json:"codec,omitempty" redis-hash:"codec,omitempty"`
@@ -119,12 +423,205 @@ type VideoPreset struct {
 	GopSize       string `json:"gopSize,omitempty" redis-hash:"gopsize,omitempty"`
 	GopMode       string `json:"gopMode,omitempty" redis-hash:"gopmode,omitempty"`
 	InterlaceMode string `json:"interlaceMode,omitempty" redis-hash:"interlacemode,omitempty"`
+
+	// how the video is converted to the target aspect ratio: crop, pad or
+	// stretch
+	AspectRatioMode string `json:"aspectRatioMode,omitempty" redis-hash:"aspectratiomode,omitempty"`
+
+	// the target aspect ratio, in the format "width:height" (e.g. 4:3)
+	AspectRatio string `json:"aspectRatio,omitempty" redis-hash:"aspectratio,omitempty"`
+
+	// color of the bars added when the aspect ratio mode is pad, in the
+	// hexadecimal format #RRGGBB. Defaults to black
+	PadColor string `json:"padColor,omitempty" redis-hash:"padcolor,omitempty"`
+
+	// field order of interlaced output (interlaceMode interlaced): top
+	// for top field first or bottom for bottom field first
+	FieldOrder string `json:"fieldOrder,omitempty" redis-hash:"fieldorder,omitempty"`
+
+	// telecine applied to the output: none, soft or hard
+	Telecine string `json:"telecine,omitempty" redis-hash:"telecine,omitempty"`
+
+	// whether the source video should be deinterlaced. Can't be combined
+	// with interlaced output
+	Deinterlace bool `json:"deinterlace,omitempty" redis-hash:"deinterlace"`
+
+	// maximum frame rate of the output, either in frames per second (e.g.
+	// 30) or as a fraction (e.g. 30000/1001). Sources with higher frame
+	// rates are converted down to it, while the others keep their frame
+	// rate
+	MaxFrameRate string `json:"maxFrameRate,omitempty" redis-hash:"maxframerate,omitempty"`
+
+	// speed of the encoder, trading encoding time for quality: ultrafast,
+	// superfast, veryfast, faster, fast, medium, slow, slower or veryslow.
+	// Defaults to the speed of the provider
+	EncoderSpeed string `json:"encoderSpeed,omitempty" redis-hash:"encoderspeed,omitempty"`
+
+	// chroma subsampling of the output: 4:2:0, 4:2:2 or 4:4:4. Defaults to
+	// 4:2:0, or to the format of the codec when it doesn't support it
+	ChromaSubsampling string `json:"chromaSubsampling,omitempty" redis-hash:"chromasubsampling,omitempty"`
+
+	// bit depth of the output: 8, 10 or 12. Defaults to 8, or to the
+	// format of the codec when it doesn't support it
+	BitDepth string `json:"bitDepth,omitempty" redis-hash:"bitdepth,omitempty"`
+
+	// maximum number of consecutive B-frames between reference frames.
+	// Defaults to the number of the provider
+	BFrames string `json:"bFrames,omitempty" redis-hash:"bframes,omitempty"`
+
+	// number of frames that can be referenced by each predicted frame.
+	// Defaults to the number of the provider
+	ReferenceFrames string `json:"referenceFrames,omitempty" redis-hash:"referenceframes,omitempty"`
+
+	// whether GOPs are closed, so that no frame references frames of
+	// other GOPs, as required for splicing and ad insertion. Defaults to
+	// the behavior of the provider
+	ClosedGOP *bool `json:"closedGOP,omitempty" redis-hash:"closedgop"`
+
+	// whether keyframes are inserted at scene changes, in addition to the
+	// ones starting each GOP. Can't be combined with closed GOPs, as the
+	// extra keyframes break the cadence splicing relies on. Defaults to
+	// the behavior of the provider
+	SceneChangeDetection *bool `json:"sceneChangeDetection,omitempty" redis-hash:"scenechangedetection"`
 }
 
+// Aspect ratio conversion modes supported in VideoPreset.
+const (
+	AspectRatioModeCrop    = "crop"
+	AspectRatioModePad     = "pad"
+	AspectRatioModeStretch = "stretch"
+)
+
+// Interlace modes and field orders supported in VideoPreset.
+const (
+	InterlaceModeProgressive = "progressive"
+	InterlaceModeInterlaced  = "interlaced"
+
+	FieldOrderTop    = "top"
+	FieldOrderBottom = "bottom"
+)
+
+// Telecine modes supported in VideoPreset.
+const (
+	TelecineNone = "none"
+	TelecineSoft = "soft"
+	TelecineHard = "hard"
+)
+
+// EncoderSpeeds lists the encoder speeds supported in VideoPreset, from the
+// fastest to the slowest.
+var EncoderSpeeds = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+// Chroma subsamplings and bit depths supported in VideoPreset.
+var (
+	ChromaSubsamplings = []string{"4:2:0", "4:2:2", "4:4:4"}
+	BitDepths          = []string{"8", "10", "12"}
+)
+
 // AudioPreset defines the set of parameters for audio on a given preset
 type AudioPreset struct {
 	Codec   string `json:"codec,omitempty" redis-hash:"codec,omitempty"`
 	Bitrate string `json:"bitrate,omitempty" redis-hash:"bitrate,omitempty"`
+
+	// target integrated loudness for loudness normalization, in LKFS
+	// (e.g. -24 for ATSC A/85 or -23 for EBU R128)
+	LoudnessTarget string `json:"loudnessTarget,omitempty" redis-hash:"loudnesstarget,omitempty"`
+
+	// maximum true peak level allowed after loudness normalization, in
+	// dBTP
+	TruePeakLimit string `json:"truePeakLimit,omitempty" redis-hash:"truepeaklimit,omitempty"`
+
+	// bitrate mode of the audio: cbr for constant bitrate, using the
+	// bitrate, or vbr for variable bitrate, using the quality
+	BitrateMode string `json:"bitrateMode,omitempty" redis-hash:"bitratemode,omitempty"`
+
+	// quality of variable bitrate audio: low, medium-low, medium-high or
+	// high
+	Quality string `json:"quality,omitempty" redis-hash:"quality,omitempty"`
+
+	// number of channels of the output: 1 (mono), 2 (stereo) or 6 (5.1).
+	// Defaults to the channels of the source
+	Channels string `json:"channels,omitempty" redis-hash:"channels,omitempty"`
+
+	// number of channels of the source remixed into the channels of the
+	// output: 1, 2, 6 (5.1) or 8 (7.1). Only downmixes and remaps are
+	// supported, so it can't be lower than audio.channels
+	SourceChannels string `json:"sourceChannels,omitempty" redis-hash:"sourcechannels,omitempty"`
+
+	// gains, in dB, of the source channels in each output channel, as
+	// one comma-separated list per output channel (e.g. "0,-60,-3" mixes
+	// the first and third source channels). -60 mutes the channel.
+	// Defaults to the standard mapping of the channels, when there's one
+	ChannelMapping []string `json:"channelMapping,omitempty" redis-hash:"channelmapping,omitempty"`
+}
+
+// Audio bitrate modes and variable bitrate qualities supported in
+// AudioPreset.
+const (
+	AudioBitrateModeCBR = "cbr"
+	AudioBitrateModeVBR = "vbr"
+
+	AudioQualityLow        = "low"
+	AudioQualityMediumLow  = "medium-low"
+	AudioQualityMediumHigh = "medium-high"
+	AudioQualityHigh       = "high"
+)
+
+// Channel counts supported in the output and the source of AudioPreset.
+var (
+	AudioChannels       = []string{"1", "2", "6"}
+	AudioSourceChannels = []string{"1", "2", "6", "8"}
+)
+
+// defaultChannelMappings lists the standard mappings of the channels of the
+// source into the channels of the output, keyed by the number of source and
+// output channels, for the remixes that don't need an explicit mapping. The
+// 5.1 downmix follows ITU-R BS.775, dropping the LFE channel, with 5.1
+// channels in the L, R, C, LFE, Ls, Rs order.
+var defaultChannelMappings = map[string][]string{
+	"2:1": {"-6,-6"},
+	"6:2": {"0,-60,-3,-60,-3,-60", "-60,0,-3,-60,-60,-3"},
+}
+
+// ChannelGains returns the gains, in dB, of each source channel in each
+// output channel of the remix of the audio, using the default mapping of
+// the channels when the preset doesn't set one. It returns nil when the
+// preset doesn't remix the audio.
+func (a *AudioPreset) ChannelGains() [][]string {
+	if a.SourceChannels == "" {
+		return nil
+	}
+	mapping := a.ChannelMapping
+	if len(mapping) == 0 {
+		mapping = defaultChannelMapping(a.SourceChannels, a.Channels)
+	}
+	gains := make([][]string, len(mapping))
+	for i, channel := range mapping {
+		for _, gain := range strings.Split(channel, ",") {
+			gains[i] = append(gains[i], strings.TrimSpace(gain))
+		}
+	}
+	return gains
+}
+
+// defaultChannelMapping returns the standard mapping between the given
+// channels, or nil if there isn't one. Remixes keeping the number of
+// channels map each source channel to the same output channel.
+func defaultChannelMapping(sourceChannels, channels string) []string {
+	if sourceChannels != channels {
+		return defaultChannelMappings[sourceChannels+":"+channels]
+	}
+	n, _ := strconv.Atoi(channels)
+	mapping := make([]string, n)
+	for i := range mapping {
+		gains := make([]string, n)
+		for j := range gains {
+			gains[j] = "-60"
+		}
+		gains[i] = "0"
+		mapping[i] = strings.Join(gains, ",")
+	}
+	return mapping
 }
 
 // PresetMap represents the preset that is persisted in the repository of the
@@ -167,6 +664,29 @@ type OutputOptions struct {
 	//
 	// required: true
 	Extension string `redis-hash:"extension" json:"extension"`
+
+	// container of the output file, used for encoding it. Defaults to
+	// the extension, and may differ from it when the extension is an
+	// alternative one for the container (for example, m4v for mp4).
+	//
+	// required: false
+	Container string `redis-hash:"container,omitempty" json:"container,omitempty"`
+
+	// whether the output is an audio description of the video, for
+	// accessibility. Audio descriptions are audio-only renditions of
+	// adaptive streaming jobs, flagged in HLS manifests with the
+	// public.accessibility.describes-video characteristic.
+	//
+	// required: false
+	AudioDescription bool `redis-hash:"audiodescription" json:"audioDescription,omitempty"`
+}
+
+// containerExtensions lists the alternative extensions accepted for
+// containers. Other containers only accept extensions matching their names.
+var containerExtensions = map[string][]string{
+	"mp4": {"m4v"},
+	"mov": {"qt"},
+	"ts":  {"m2ts", "mts"},
 }
 
 // Validate checks that the OutputOptions object is properly defined.
@@ -174,5 +694,485 @@ func (o *OutputOptions) Validate() error {
 	if o.Extension == "" {
 		return errors.New("extension is required")
 	}
+	container := o.OutputContainer()
+	if o.Extension == container {
+		return nil
+	}
+	for _, extension := range containerExtensions[container] {
+		if o.Extension == extension {
+			return nil
+		}
+	}
+	return fmt.Errorf("extension %q is not valid for the container %q", o.Extension, container)
+}
+
+// OutputContainer returns the container of the output, which defaults to
+// the extension.
+func (o *OutputOptions) OutputContainer() string {
+	if o.Container != "" {
+		return o.Container
+	}
+	return o.Extension
+}
+
+// Validate checks that the settings in the Preset are consistent and within
+// the supported ranges.
+func (p *Preset) Validate() error {
+	if errs := p.ValidationErrors(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidationErrors checks the settings in the Preset like Validate, but
+// returns all problems found instead of only the first one. Each group of
+// related settings reports at most one problem.
+func (p *Preset) ValidationErrors() []error {
+	var errs []error
+	for _, validate := range []func() error{
+		p.validateStreams,
+		p.validateCodecs,
+		p.validateProfile,
+		p.validateColorFormat,
+		p.validateFrameReferences,
+		p.Video.validateBounds,
+		p.Video.validateInterlacing,
+		p.Video.validateAspectRatio,
+		p.Video.validateFrameRate,
+		p.Video.validateEncoderSpeed,
+		p.Video.validateGOPStructure,
+		p.Audio.validate,
+		p.Audio.validateBitrateMode,
+		p.Audio.validateChannels,
+	} {
+		if err := validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// containerCodecs lists the video and audio codecs supported by containers
+// that can't hold any codec.
+var containerCodecs = map[string]struct{ video, audio []string }{
+	"webm": {video: []string{"vp8", "vp9"}, audio: []string{"vorbis", "opus"}},
+	"cmaf": {video: []string{"h264", "h265"}, audio: []string{"aac"}},
+	"mxf":  {video: []string{"mpeg2", "prores"}, audio: []string{"pcm"}},
+}
+
+// HasVideo returns whether the outputs of the preset include video.
+func (p *Preset) HasVideo() bool {
+	return p.IncludeVideo == nil || *p.IncludeVideo
+}
+
+// HasAudio returns whether the outputs of the preset include audio.
+func (p *Preset) HasAudio() bool {
+	return p.IncludeAudio == nil || *p.IncludeAudio
+}
+
+func (p *Preset) validateStreams() error {
+	if !p.HasVideo() && !p.HasAudio() {
+		return errors.New("at least one of includeVideo and includeAudio must be enabled")
+	}
+	return nil
+}
+
+func (p *Preset) validateCodecs() error {
+	codecs, ok := containerCodecs[p.Container]
+	if !ok {
+		return nil
+	}
+	if p.Video.Codec != "" && !containsString(codecs.video, p.Video.Codec) {
+		return fmt.Errorf("video.codec: container %s doesn't support the codec %q, must be one of %s", p.Container, p.Video.Codec, strings.Join(codecs.video, " or "))
+	}
+	if p.Audio.Codec != "" && !containsString(codecs.audio, p.Audio.Codec) {
+		return fmt.Errorf("audio.codec: container %s doesn't support the codec %q, must be one of %s", p.Container, p.Audio.Codec, strings.Join(codecs.audio, " or "))
+	}
+	return nil
+}
+
+// codecProfiles lists the profiles and levels supported by the video codecs
+// whose profile and level can be pinned in presets.
+var codecProfiles = map[string]struct{ profiles, levels []string }{
+	"h264": {
+		profiles: []string{"baseline", "main", "high"},
+		levels:   []string{"1", "1b", "1.1", "1.2", "1.3", "2", "2.1", "2.2", "3", "3.1", "3.2", "4", "4.1", "4.2", "5", "5.1", "5.2"},
+	},
+	"h265": {
+		profiles: []string{"main", "main10"},
+		levels:   []string{"1", "2", "2.1", "3", "3.1", "4", "4.1", "5", "5.1", "5.2", "6", "6.1", "6.2"},
+	},
+}
+
+// validateProfile checks the profile and level of H.264 and H.265 presets.
+// H.264 is the codec used when the preset doesn't specify one, unless the
+// container doesn't support it. Other codecs are left to the providers.
+func (p *Preset) validateProfile() error {
+	if p.Video.Profile == "" && p.Video.ProfileLevel == "" {
+		return nil
+	}
+	codec := p.videoCodec()
+	profiles, ok := codecProfiles[codec]
+	if !ok {
+		return nil
+	}
+	if p.Video.Profile != "" && !containsString(profiles.profiles, p.Video.Profile) {
+		return fmt.Errorf("video.profile: codec %s doesn't support the profile %q, must be one of %s", codec, p.Video.Profile, strings.Join(profiles.profiles, ", "))
+	}
+	if p.Video.ProfileLevel != "" && !containsString(profiles.levels, p.Video.ProfileLevel) {
+		return fmt.Errorf("video.profileLevel: codec %s doesn't support the level %q, must be one of %s", codec, p.Video.ProfileLevel, strings.Join(profiles.levels, ", "))
+	}
+	// level 1b is signaled through constraint flags only defined for the
+	// baseline and main profiles
+	if strings.EqualFold(p.Video.ProfileLevel, "1b") && p.Video.Profile != "" && !containsString([]string{"baseline", "main"}, p.Video.Profile) {
+		return fmt.Errorf("video.profileLevel 1b is only supported by the baseline and main profiles, got %q", p.Video.Profile)
+	}
+	return nil
+}
+
+// videoCodec returns the lowercase video codec of the preset, which
+// defaults to H.264 unless the container doesn't support it. It's empty when
+// the codec is left to the providers.
+func (p *Preset) videoCodec() string {
+	codec := strings.ToLower(p.Video.Codec)
+	if codec == "" {
+		if codecs, ok := containerCodecs[p.Container]; !ok || containsString(codecs.video, "h264") {
+			codec = "h264"
+		}
+	}
+	return codec
+}
+
+// codecColorFormats lists the chroma subsamplings and bit depths supported
+// by the video codecs.
+var codecColorFormats = map[string]struct{ subsamplings, bitDepths []string }{
+	"h264":   {subsamplings: []string{"4:2:0", "4:2:2"}, bitDepths: []string{"8", "10"}},
+	"h265":   {subsamplings: []string{"4:2:0", "4:2:2", "4:4:4"}, bitDepths: []string{"8", "10", "12"}},
+	"mpeg2":  {subsamplings: []string{"4:2:0", "4:2:2"}, bitDepths: []string{"8"}},
+	"prores": {subsamplings: []string{"4:2:2", "4:4:4"}, bitDepths: []string{"10", "12"}},
+	"vp8":    {subsamplings: []string{"4:2:0"}, bitDepths: []string{"8"}},
+	"vp9":    {subsamplings: []string{"4:2:0", "4:2:2", "4:4:4"}, bitDepths: []string{"8", "10", "12"}},
+}
+
+// validateColorFormat checks the chroma subsampling and bit depth of the
+// preset against the ones supported by its codec. The profiles that can be
+// pinned in presets are limited to 4:2:0, and only main10 goes beyond 8
+// bits, so other formats require leaving the profile to the provider.
+func (p *Preset) validateColorFormat() error {
+	v := p.Video
+	if v.ChromaSubsampling == "" && v.BitDepth == "" {
+		return nil
+	}
+	if v.ChromaSubsampling != "" && !containsString(ChromaSubsamplings, v.ChromaSubsampling) {
+		return fmt.Errorf("video.chromaSubsampling: invalid chroma subsampling %q, must be one of %s", v.ChromaSubsampling, strings.Join(ChromaSubsamplings, ", "))
+	}
+	if v.BitDepth != "" && !containsString(BitDepths, v.BitDepth) {
+		return fmt.Errorf("video.bitDepth: invalid bit depth %q, must be one of %s", v.BitDepth, strings.Join(BitDepths, ", "))
+	}
+	codec := p.videoCodec()
+	formats, ok := codecColorFormats[codec]
+	if !ok {
+		return nil
+	}
+	if v.ChromaSubsampling != "" && !containsString(formats.subsamplings, v.ChromaSubsampling) {
+		return fmt.Errorf("video.chromaSubsampling: codec %s doesn't support the chroma subsampling %q, must be one of %s", codec, v.ChromaSubsampling, strings.Join(formats.subsamplings, ", "))
+	}
+	if v.BitDepth != "" && !containsString(formats.bitDepths, v.BitDepth) {
+		return fmt.Errorf("video.bitDepth: codec %s doesn't support the bit depth %q, must be one of %s", codec, v.BitDepth, strings.Join(formats.bitDepths, ", "))
+	}
+	if _, ok := codecProfiles[codec]; ok && v.Profile != "" {
+		maxBitDepth := 8
+		if strings.EqualFold(v.Profile, "main10") {
+			maxBitDepth = 10
+		}
+		bitDepth, _ := strconv.Atoi(v.BitDepth)
+		if (v.ChromaSubsampling != "" && v.ChromaSubsampling != "4:2:0") || bitDepth > maxBitDepth {
+			return fmt.Errorf("video.profile %q only supports 4:2:0 up to %d bits, leave it empty to use the profile matching the chroma subsampling and bit depth", v.Profile, maxBitDepth)
+		}
+	}
+	return nil
+}
+
+// codecFrameReferences lists the maximum number of consecutive B-frames and
+// of reference frames supported by the video codecs that allow tuning them.
+var codecFrameReferences = map[string]struct{ bFrames, referenceFrames int }{
+	"h264": {bFrames: 16, referenceFrames: 16},
+	"h265": {bFrames: 16, referenceFrames: 16},
+}
+
+// validateFrameReferences checks the number of B-frames and reference
+// frames of the preset against the limits of its codec. The baseline
+// profile of H.264 doesn't support B-frames, and codecs unknown to the API
+// are left to the provider.
+func (p *Preset) validateFrameReferences() error {
+	v := p.Video
+	if v.BFrames == "" && v.ReferenceFrames == "" {
+		return nil
+	}
+	codec := p.videoCodec()
+	limits, ok := codecFrameReferences[codec]
+	if !ok {
+		if _, known := codecColorFormats[codec]; !known {
+			return nil
+		}
+		if v.BFrames != "" {
+			return fmt.Errorf("video.bFrames: codec %s doesn't support setting the number of B-frames", codec)
+		}
+		return fmt.Errorf("video.referenceFrames: codec %s doesn't support setting the number of reference frames", codec)
+	}
+	if v.BFrames != "" {
+		bFrames, err := strconv.Atoi(v.BFrames)
+		if err != nil {
+			return fmt.Errorf("video.bFrames: invalid number %q", v.BFrames)
+		}
+		if bFrames < 0 || bFrames > limits.bFrames {
+			return fmt.Errorf("video.bFrames must be between 0 and %d for codec %s, got %d", limits.bFrames, codec, bFrames)
+		}
+		if bFrames > 0 && strings.EqualFold(v.Profile, "baseline") {
+			return errors.New("video.bFrames: profile baseline doesn't support B-frames")
+		}
+	}
+	if v.ReferenceFrames != "" {
+		referenceFrames, err := strconv.Atoi(v.ReferenceFrames)
+		if err != nil {
+			return fmt.Errorf("video.referenceFrames: invalid number %q", v.ReferenceFrames)
+		}
+		if referenceFrames < 1 || referenceFrames > limits.referenceFrames {
+			return fmt.Errorf("video.referenceFrames must be between 1 and %d for codec %s, got %d", limits.referenceFrames, codec, referenceFrames)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	aspectRatioRegexp = regexp.MustCompile(`^[1-9][0-9]*:[1-9][0-9]*$`)
+	padColorRegexp    = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// maxVideoDimension is the largest width or height accepted in presets.
+const maxVideoDimension = 8192
+
+func (v *VideoPreset) validateBounds() error {
+	if v.Width != "" {
+		if err := validateRange("video.width", v.Width, 0, maxVideoDimension); err != nil {
+			return err
+		}
+	}
+	if v.Height != "" {
+		if err := validateRange("video.height", v.Height, 0, maxVideoDimension); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *VideoPreset) validateInterlacing() error {
+	switch v.FieldOrder {
+	case "":
+		if v.InterlaceMode == InterlaceModeInterlaced {
+			return errors.New("video.interlaceMode interlaced requires video.fieldOrder")
+		}
+	case FieldOrderTop, FieldOrderBottom:
+		if v.InterlaceMode != InterlaceModeInterlaced {
+			return errors.New("video.fieldOrder requires video.interlaceMode interlaced")
+		}
+	default:
+		return fmt.Errorf("video.fieldOrder: invalid field order %q, must be one of top or bottom", v.FieldOrder)
+	}
+	switch v.Telecine {
+	case "", TelecineNone:
+	case TelecineSoft, TelecineHard:
+		if v.InterlaceMode == InterlaceModeInterlaced {
+			return errors.New("video.telecine can't be combined with interlaced output")
+		}
+	default:
+		return fmt.Errorf("video.telecine: invalid mode %q, must be one of none, soft or hard", v.Telecine)
+	}
+	if v.Deinterlace && v.InterlaceMode == InterlaceModeInterlaced {
+		return errors.New("video.deinterlace can't be combined with interlaced output")
+	}
+	return nil
+}
+
+func (v *VideoPreset) validateEncoderSpeed() error {
+	if v.EncoderSpeed != "" && !containsString(EncoderSpeeds, v.EncoderSpeed) {
+		return fmt.Errorf("video.encoderSpeed: invalid speed %q, must be one of %s", v.EncoderSpeed, strings.Join(EncoderSpeeds, ", "))
+	}
+	return nil
+}
+
+func (v *VideoPreset) validateGOPStructure() error {
+	if v.ClosedGOP != nil && *v.ClosedGOP && v.SceneChangeDetection != nil && *v.SceneChangeDetection {
+		return errors.New("video.closedGOP can't be combined with video.sceneChangeDetection")
+	}
+	return nil
+}
+
+func (v *VideoPreset) validateAspectRatio() error {
+	if v.AspectRatioMode == "" && v.AspectRatio == "" {
+		if v.PadColor != "" {
+			return errors.New("video.padColor requires video.aspectRatioMode pad")
+		}
+		return nil
+	}
+	if v.AspectRatioMode == "" || v.AspectRatio == "" {
+		return errors.New("video.aspectRatioMode and video.aspectRatio must be provided together")
+	}
+	switch v.AspectRatioMode {
+	case AspectRatioModeCrop, AspectRatioModePad, AspectRatioModeStretch:
+	default:
+		return fmt.Errorf("video.aspectRatioMode: invalid mode %q, must be one of crop, pad or stretch", v.AspectRatioMode)
+	}
+	if !aspectRatioRegexp.MatchString(v.AspectRatio) {
+		return fmt.Errorf("video.aspectRatio: invalid aspect ratio %q, must be in the format width:height", v.AspectRatio)
+	}
+	if v.PadColor != "" {
+		if v.AspectRatioMode != AspectRatioModePad {
+			return errors.New("video.padColor requires video.aspectRatioMode pad")
+		}
+		if !padColorRegexp.MatchString(v.PadColor) {
+			return fmt.Errorf("video.padColor: invalid color %q, must be in the format #RRGGBB", v.PadColor)
+		}
+	}
+	return nil
+}
+
+// maxFrameRate is the largest frame rate accepted in presets.
+const maxFrameRate = 120
+
+func (v *VideoPreset) validateFrameRate() error {
+	if v.MaxFrameRate == "" {
+		return nil
+	}
+	numerator, denominator, err := ParseFrameRate(v.MaxFrameRate)
+	if err != nil {
+		return fmt.Errorf("video.maxFrameRate: %s", err)
+	}
+	if rate := float64(numerator) / float64(denominator); rate > maxFrameRate {
+		return fmt.Errorf("video.maxFrameRate must be at most %d, got %g", maxFrameRate, rate)
+	}
+	return nil
+}
+
+// ParseFrameRate parses a frame rate in frames per second (e.g. 30) or in
+// the format numerator/denominator (e.g. 30000/1001), returning its
+// numerator and denominator.
+func ParseFrameRate(value string) (numerator, denominator int, err error) {
+	parts := strings.SplitN(value, "/", 2)
+	denominator = 1
+	numerator, err = strconv.Atoi(parts[0])
+	if err == nil && len(parts) == 2 {
+		denominator, err = strconv.Atoi(parts[1])
+	}
+	if err != nil || numerator <= 0 || denominator <= 0 {
+		return 0, 0, fmt.Errorf("invalid frame rate %q, must be a positive number of frames per second or a fraction such as 30000/1001", value)
+	}
+	return numerator, denominator, nil
+}
+
+func (a *AudioPreset) validate() error {
+	if a.LoudnessTarget != "" {
+		if err := validateRange("audio.loudnessTarget", a.LoudnessTarget, -59, 0); err != nil {
+			return err
+		}
+	}
+	if a.TruePeakLimit != "" {
+		if a.LoudnessTarget == "" {
+			return errors.New("audio.truePeakLimit requires audio.loudnessTarget")
+		}
+		if err := validateRange("audio.truePeakLimit", a.TruePeakLimit, -20, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AudioPreset) validateBitrateMode() error {
+	switch a.BitrateMode {
+	case "", AudioBitrateModeCBR:
+		if a.Quality != "" {
+			return errors.New("audio.quality requires audio.bitrateMode vbr")
+		}
+	case AudioBitrateModeVBR:
+		if a.Bitrate != "" {
+			return errors.New("audio.bitrateMode vbr can't be combined with audio.bitrate, use audio.quality instead")
+		}
+		switch a.Quality {
+		case AudioQualityLow, AudioQualityMediumLow, AudioQualityMediumHigh, AudioQualityHigh:
+		case "":
+			return errors.New("audio.bitrateMode vbr requires audio.quality")
+		default:
+			return fmt.Errorf("audio.quality: invalid quality %q, must be one of low, medium-low, medium-high or high", a.Quality)
+		}
+	default:
+		return fmt.Errorf("audio.bitrateMode: invalid mode %q, must be one of cbr or vbr", a.BitrateMode)
+	}
+	return nil
+}
+
+// validateChannels checks the output channels of the audio and the remix of
+// the source channels into them. Upmixes aren't supported, and remixes
+// without a standard mapping require an explicit one.
+func (a *AudioPreset) validateChannels() error {
+	if a.Channels != "" && !containsString(AudioChannels, a.Channels) {
+		return fmt.Errorf("audio.channels: invalid number of channels %q, must be one of %s", a.Channels, strings.Join(AudioChannels, ", "))
+	}
+	if a.SourceChannels == "" {
+		if len(a.ChannelMapping) > 0 {
+			return errors.New("audio.channelMapping requires audio.sourceChannels")
+		}
+		return nil
+	}
+	if !containsString(AudioSourceChannels, a.SourceChannels) {
+		return fmt.Errorf("audio.sourceChannels: invalid number of channels %q, must be one of %s", a.SourceChannels, strings.Join(AudioSourceChannels, ", "))
+	}
+	if a.Channels == "" {
+		return errors.New("audio.sourceChannels requires audio.channels")
+	}
+	sourceChannels, _ := strconv.Atoi(a.SourceChannels)
+	channels, _ := strconv.Atoi(a.Channels)
+	if channels > sourceChannels {
+		return fmt.Errorf("audio.channels: can't remix %d source channels into %d channels, only downmixes and remaps are supported", sourceChannels, channels)
+	}
+	if len(a.ChannelMapping) == 0 {
+		if defaultChannelMapping(a.SourceChannels, a.Channels) == nil {
+			return fmt.Errorf("audio.channelMapping is required for remixing %d source channels into %d channels", sourceChannels, channels)
+		}
+		return nil
+	}
+	if len(a.ChannelMapping) != channels {
+		return fmt.Errorf("audio.channelMapping must have one entry per output channel, got %d entries for %d channels", len(a.ChannelMapping), channels)
+	}
+	for i, channel := range a.ChannelMapping {
+		gains := strings.Split(channel, ",")
+		if len(gains) != sourceChannels {
+			return fmt.Errorf("audio.channelMapping[%d] must have one gain per source channel, got %d gains for %d channels", i, len(gains), sourceChannels)
+		}
+		for _, gain := range gains {
+			if err := validateRange(fmt.Sprintf("audio.channelMapping[%d]", i), strings.TrimSpace(gain), -60, 6); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateRange(field, value string, min, max float64) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid number %q", field, value)
+	}
+	if v < min || v > max {
+		return fmt.Errorf("%s must be between %g and %g, got %g", field, min, max, v)
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package db
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -21,6 +22,21 @@ func TestOutputOptionsValidation(t *testing.T) {
 			OutputOptions{Extension: ""},
 			"extension is required",
 		},
+		{
+			"alternative extension for the container",
+			OutputOptions{Extension: "m4v", Container: "mp4"},
+			"",
+		},
+		{
+			"extension matching the container",
+			OutputOptions{Extension: "webm", Container: "webm"},
+			"",
+		},
+		{
+			"extension not valid for the container",
+			OutputOptions{Extension: "m4v", Container: "webm"},
+			`extension "m4v" is not valid for the container "webm"`,
+		},
 	}
 	for _, test := range tests {
 		err := test.opts.Validate()
@@ -32,3 +48,674 @@ func TestOutputOptionsValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestPresetValidation(t *testing.T) {
+	var tests = []struct {
+		testCase string
+		video    VideoPreset
+		audio    AudioPreset
+		errMsg   string
+	}{
+		{
+			"pad aspect ratio conversion",
+			VideoPreset{AspectRatioMode: "pad", AspectRatio: "4:3", PadColor: "#1a1a1a"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"crop aspect ratio conversion",
+			VideoPreset{AspectRatioMode: "crop", AspectRatio: "16:9"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"aspect ratio mode without aspect ratio",
+			VideoPreset{AspectRatioMode: "crop"},
+			AudioPreset{},
+			"video.aspectRatioMode and video.aspectRatio must be provided together",
+		},
+		{
+			"aspect ratio without mode",
+			VideoPreset{AspectRatio: "4:3"},
+			AudioPreset{},
+			"video.aspectRatioMode and video.aspectRatio must be provided together",
+		},
+		{
+			"invalid aspect ratio mode",
+			VideoPreset{AspectRatioMode: "zoom", AspectRatio: "4:3"},
+			AudioPreset{},
+			`video.aspectRatioMode: invalid mode "zoom", must be one of crop, pad or stretch`,
+		},
+		{
+			"invalid aspect ratio",
+			VideoPreset{AspectRatioMode: "pad", AspectRatio: "4/3"},
+			AudioPreset{},
+			`video.aspectRatio: invalid aspect ratio "4/3", must be in the format width:height`,
+		},
+		{
+			"pad color without pad mode",
+			VideoPreset{AspectRatioMode: "crop", AspectRatio: "4:3", PadColor: "#000000"},
+			AudioPreset{},
+			"video.padColor requires video.aspectRatioMode pad",
+		},
+		{
+			"invalid pad color",
+			VideoPreset{AspectRatioMode: "pad", AspectRatio: "4:3", PadColor: "black"},
+			AudioPreset{},
+			`video.padColor: invalid color "black", must be in the format #RRGGBB`,
+		},
+		{
+			"interlaced output with field order",
+			VideoPreset{InterlaceMode: "interlaced", FieldOrder: "top"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"interlaced output without field order",
+			VideoPreset{InterlaceMode: "interlaced"},
+			AudioPreset{},
+			"video.interlaceMode interlaced requires video.fieldOrder",
+		},
+		{
+			"field order on progressive output",
+			VideoPreset{InterlaceMode: "progressive", FieldOrder: "bottom"},
+			AudioPreset{},
+			"video.fieldOrder requires video.interlaceMode interlaced",
+		},
+		{
+			"invalid field order",
+			VideoPreset{InterlaceMode: "interlaced", FieldOrder: "left"},
+			AudioPreset{},
+			`video.fieldOrder: invalid field order "left", must be one of top or bottom`,
+		},
+		{
+			"deinterlaced progressive output",
+			VideoPreset{InterlaceMode: "progressive", Deinterlace: true},
+			AudioPreset{},
+			"",
+		},
+		{
+			"deinterlaced interlaced output",
+			VideoPreset{InterlaceMode: "interlaced", FieldOrder: "top", Deinterlace: true},
+			AudioPreset{},
+			"video.deinterlace can't be combined with interlaced output",
+		},
+		{
+			"soft telecine",
+			VideoPreset{InterlaceMode: "progressive", Telecine: "soft"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"telecine on interlaced output",
+			VideoPreset{InterlaceMode: "interlaced", FieldOrder: "bottom", Telecine: "hard"},
+			AudioPreset{},
+			"video.telecine can't be combined with interlaced output",
+		},
+		{
+			"invalid telecine",
+			VideoPreset{Telecine: "pulldown"},
+			AudioPreset{},
+			`video.telecine: invalid mode "pulldown", must be one of none, soft or hard`,
+		},
+		{
+			"encoder speed",
+			VideoPreset{EncoderSpeed: "veryslow"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"invalid encoder speed",
+			VideoPreset{EncoderSpeed: "placebo"},
+			AudioPreset{},
+			`video.encoderSpeed: invalid speed "placebo", must be one of ultrafast, superfast, veryfast, faster, fast, medium, slow, slower, veryslow`,
+		},
+		{
+			"max frame rate",
+			VideoPreset{MaxFrameRate: "30"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"fractional max frame rate",
+			VideoPreset{MaxFrameRate: "30000/1001"},
+			AudioPreset{},
+			"",
+		},
+		{
+			"zero max frame rate",
+			VideoPreset{MaxFrameRate: "0"},
+			AudioPreset{},
+			`video.maxFrameRate: invalid frame rate "0", must be a positive number of frames per second or a fraction such as 30000/1001`,
+		},
+		{
+			"invalid max frame rate",
+			VideoPreset{MaxFrameRate: "30fps"},
+			AudioPreset{},
+			`video.maxFrameRate: invalid frame rate "30fps", must be a positive number of frames per second or a fraction such as 30000/1001`,
+		},
+		{
+			"max frame rate too high",
+			VideoPreset{MaxFrameRate: "240"},
+			AudioPreset{},
+			"video.maxFrameRate must be at most 120, got 240",
+		},
+		{
+			"constant bitrate audio",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", Bitrate: "128000", BitrateMode: "cbr"},
+			"",
+		},
+		{
+			"variable bitrate audio",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", BitrateMode: "vbr", Quality: "medium-high"},
+			"",
+		},
+		{
+			"variable bitrate audio with bitrate",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", Bitrate: "128000", BitrateMode: "vbr", Quality: "high"},
+			"audio.bitrateMode vbr can't be combined with audio.bitrate, use audio.quality instead",
+		},
+		{
+			"variable bitrate audio without quality",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", BitrateMode: "vbr"},
+			"audio.bitrateMode vbr requires audio.quality",
+		},
+		{
+			"invalid audio quality",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", BitrateMode: "vbr", Quality: "best"},
+			`audio.quality: invalid quality "best", must be one of low, medium-low, medium-high or high`,
+		},
+		{
+			"audio quality with constant bitrate",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", Bitrate: "128000", Quality: "high"},
+			"audio.quality requires audio.bitrateMode vbr",
+		},
+		{
+			"invalid audio bitrate mode",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", BitrateMode: "abr"},
+			`audio.bitrateMode: invalid mode "abr", must be one of cbr or vbr`,
+		},
+		{
+			"no loudness settings",
+			VideoPreset{},
+			AudioPreset{Codec: "aac", Bitrate: "64000"},
+			"",
+		},
+		{
+			"valid loudness settings",
+			VideoPreset{},
+			AudioPreset{LoudnessTarget: "-24", TruePeakLimit: "-2"},
+			"",
+		},
+		{
+			"loudness target without true peak limit",
+			VideoPreset{},
+			AudioPreset{LoudnessTarget: "-23"},
+			"",
+		},
+		{
+			"loudness target out of range",
+			VideoPreset{},
+			AudioPreset{LoudnessTarget: "-60"},
+			"audio.loudnessTarget must be between -59 and 0, got -60",
+		},
+		{
+			"positive loudness target",
+			VideoPreset{},
+			AudioPreset{LoudnessTarget: "1.5"},
+			"audio.loudnessTarget must be between -59 and 0, got 1.5",
+		},
+		{
+			"invalid loudness target",
+			VideoPreset{},
+			AudioPreset{LoudnessTarget: "loud"},
+			`audio.loudnessTarget: invalid number "loud"`,
+		},
+		{
+			"true peak limit out of range",
+			VideoPreset{},
+			AudioPreset{LoudnessTarget: "-24", TruePeakLimit: "-21"},
+			"audio.truePeakLimit must be between -20 and 0, got -21",
+		},
+		{
+			"true peak limit without loudness target",
+			VideoPreset{},
+			AudioPreset{TruePeakLimit: "-2"},
+			"audio.truePeakLimit requires audio.loudnessTarget",
+		},
+	}
+	for _, test := range tests {
+		preset := Preset{Name: "mp4_1080p", Video: test.video, Audio: test.audio}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestPresetValidationContainerCodecs(t *testing.T) {
+	var tests = []struct {
+		testCase  string
+		container string
+		video     VideoPreset
+		audio     AudioPreset
+		errMsg    string
+	}{
+		{"webm with default codecs", "webm", VideoPreset{}, AudioPreset{}, ""},
+		{"webm with vp9 and opus", "webm", VideoPreset{Codec: "vp9"}, AudioPreset{Codec: "opus"}, ""},
+		{"webm with vp8 and vorbis", "webm", VideoPreset{Codec: "VP8"}, AudioPreset{Codec: "vorbis"}, ""},
+		{
+			"webm with h264",
+			"webm",
+			VideoPreset{Codec: "h264"},
+			AudioPreset{Codec: "opus"},
+			`video.codec: container webm doesn't support the codec "h264", must be one of vp8 or vp9`,
+		},
+		{
+			"webm with aac",
+			"webm",
+			VideoPreset{Codec: "vp9"},
+			AudioPreset{Codec: "aac"},
+			`audio.codec: container webm doesn't support the codec "aac", must be one of vorbis or opus`,
+		},
+		{"mp4 with h264 and aac", "mp4", VideoPreset{Codec: "h264"}, AudioPreset{Codec: "aac"}, ""},
+		{"cmaf with h265 and aac", "cmaf", VideoPreset{Codec: "h265"}, AudioPreset{Codec: "aac"}, ""},
+		{
+			"cmaf with vp9",
+			"cmaf",
+			VideoPreset{Codec: "vp9"},
+			AudioPreset{Codec: "aac"},
+			`video.codec: container cmaf doesn't support the codec "vp9", must be one of h264 or h265`,
+		},
+		{"mxf with default codecs", "mxf", VideoPreset{}, AudioPreset{}, ""},
+		{"mxf with prores and pcm", "mxf", VideoPreset{Codec: "ProRes"}, AudioPreset{Codec: "pcm"}, ""},
+		{
+			"mxf with h264",
+			"mxf",
+			VideoPreset{Codec: "h264"},
+			AudioPreset{Codec: "pcm"},
+			`video.codec: container mxf doesn't support the codec "h264", must be one of mpeg2 or prores`,
+		},
+		{
+			"mxf with aac",
+			"mxf",
+			VideoPreset{Codec: "mpeg2"},
+			AudioPreset{Codec: "aac"},
+			`audio.codec: container mxf doesn't support the codec "aac", must be one of pcm`,
+		},
+	}
+	for _, test := range tests {
+		preset := Preset{Name: "some_preset", Container: test.container, Video: test.video, Audio: test.audio}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestPresetValidationProfile(t *testing.T) {
+	var tests = []struct {
+		testCase  string
+		container string
+		video     VideoPreset
+		errMsg    string
+	}{
+		{"h264 main 4.0", "mp4", VideoPreset{Codec: "h264", Profile: "main", ProfileLevel: "4"}, ""},
+		{"h264 high 4.1", "mp4", VideoPreset{Codec: "h264", Profile: "High", ProfileLevel: "4.1"}, ""},
+		{"h264 baseline 1b", "mp4", VideoPreset{Codec: "h264", Profile: "baseline", ProfileLevel: "1b"}, ""},
+		{"h265 main10 5.1", "mp4", VideoPreset{Codec: "h265", Profile: "main10", ProfileLevel: "5.1"}, ""},
+		{"default codec", "mp4", VideoPreset{Profile: "Main", ProfileLevel: "3.1"}, ""},
+		{"webm with default codec", "webm", VideoPreset{Profile: "0"}, ""},
+		{"mxf with default codec", "mxf", VideoPreset{Profile: "422"}, ""},
+		{
+			"h264 main10",
+			"mp4",
+			VideoPreset{Codec: "h264", Profile: "main10", ProfileLevel: "4.1"},
+			`video.profile: codec h264 doesn't support the profile "main10", must be one of baseline, main, high`,
+		},
+		{
+			"h265 level 4.2",
+			"mp4",
+			VideoPreset{Codec: "h265", Profile: "main", ProfileLevel: "4.2"},
+			`video.profileLevel: codec h265 doesn't support the level "4.2", must be one of 1, 2, 2.1, 3, 3.1, 4, 4.1, 5, 5.1, 5.2, 6, 6.1, 6.2`,
+		},
+		{
+			"h264 high 1b",
+			"mp4",
+			VideoPreset{Codec: "h264", Profile: "high", ProfileLevel: "1b"},
+			`video.profileLevel 1b is only supported by the baseline and main profiles, got "high"`,
+		},
+	}
+	for _, test := range tests {
+		preset := Preset{Name: "some_preset", Container: test.container, Video: test.video}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestPresetValidationColorFormat(t *testing.T) {
+	var tests = []struct {
+		testCase  string
+		container string
+		video     VideoPreset
+		errMsg    string
+	}{
+		{"h264 4:2:2 10-bit", "mp4", VideoPreset{Codec: "h264", ChromaSubsampling: "4:2:2", BitDepth: "10"}, ""},
+		{"default codec 10-bit", "mp4", VideoPreset{BitDepth: "10"}, ""},
+		{"h265 main10 10-bit", "mp4", VideoPreset{Codec: "h265", Profile: "main10", BitDepth: "10"}, ""},
+		{"h265 4:4:4 12-bit", "mp4", VideoPreset{Codec: "h265", ChromaSubsampling: "4:4:4", BitDepth: "12"}, ""},
+		{"mxf with default codec", "mxf", VideoPreset{ChromaSubsampling: "4:2:2"}, ""},
+		{"codec left to the provider", "mov", VideoPreset{Codec: "dnxhd", ChromaSubsampling: "4:4:4", BitDepth: "12"}, ""},
+		{
+			"invalid chroma subsampling",
+			"mp4",
+			VideoPreset{ChromaSubsampling: "4:1:1"},
+			`video.chromaSubsampling: invalid chroma subsampling "4:1:1", must be one of 4:2:0, 4:2:2, 4:4:4`,
+		},
+		{
+			"invalid bit depth",
+			"mp4",
+			VideoPreset{BitDepth: "16"},
+			`video.bitDepth: invalid bit depth "16", must be one of 8, 10, 12`,
+		},
+		{
+			"h264 4:4:4",
+			"mp4",
+			VideoPreset{Codec: "h264", ChromaSubsampling: "4:4:4"},
+			`video.chromaSubsampling: codec h264 doesn't support the chroma subsampling "4:4:4", must be one of 4:2:0, 4:2:2`,
+		},
+		{
+			"vp8 4:2:2",
+			"webm",
+			VideoPreset{Codec: "vp8", ChromaSubsampling: "4:2:2"},
+			`video.chromaSubsampling: codec vp8 doesn't support the chroma subsampling "4:2:2", must be one of 4:2:0`,
+		},
+		{
+			"mpeg2 10-bit",
+			"mxf",
+			VideoPreset{Codec: "mpeg2", ChromaSubsampling: "4:2:2", BitDepth: "10"},
+			`video.bitDepth: codec mpeg2 doesn't support the bit depth "10", must be one of 8`,
+		},
+		{
+			"h264 high 4:2:2",
+			"mp4",
+			VideoPreset{Codec: "h264", Profile: "high", ChromaSubsampling: "4:2:2"},
+			`video.profile "high" only supports 4:2:0 up to 8 bits, leave it empty to use the profile matching the chroma subsampling and bit depth`,
+		},
+		{
+			"h265 main10 12-bit",
+			"mp4",
+			VideoPreset{Codec: "h265", Profile: "main10", BitDepth: "12"},
+			`video.profile "main10" only supports 4:2:0 up to 10 bits, leave it empty to use the profile matching the chroma subsampling and bit depth`,
+		},
+	}
+	for _, test := range tests {
+		preset := Preset{Name: "some_preset", Container: test.container, Video: test.video}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestPresetValidationFrameReferences(t *testing.T) {
+	var tests = []struct {
+		testCase  string
+		container string
+		video     VideoPreset
+		errMsg    string
+	}{
+		{"h264 defaults", "mp4", VideoPreset{}, ""},
+		{"default codec", "mp4", VideoPreset{BFrames: "3", ReferenceFrames: "4"}, ""},
+		{"h264 without B-frames", "mp4", VideoPreset{Codec: "h264", BFrames: "0"}, ""},
+		{"h265 limits", "mp4", VideoPreset{Codec: "h265", BFrames: "16", ReferenceFrames: "16"}, ""},
+		{"baseline without B-frames", "mp4", VideoPreset{Profile: "baseline", BFrames: "0", ReferenceFrames: "1"}, ""},
+		{"codec left to the provider", "mov", VideoPreset{Codec: "dnxhd", BFrames: "2"}, ""},
+		{
+			"invalid B-frames",
+			"mp4",
+			VideoPreset{BFrames: "two"},
+			`video.bFrames: invalid number "two"`,
+		},
+		{
+			"too many B-frames",
+			"mp4",
+			VideoPreset{Codec: "h264", BFrames: "17"},
+			"video.bFrames must be between 0 and 16 for codec h264, got 17",
+		},
+		{
+			"negative B-frames",
+			"mp4",
+			VideoPreset{Codec: "h265", BFrames: "-1"},
+			"video.bFrames must be between 0 and 16 for codec h265, got -1",
+		},
+		{
+			"baseline with B-frames",
+			"mp4",
+			VideoPreset{Profile: "Baseline", BFrames: "2"},
+			"video.bFrames: profile baseline doesn't support B-frames",
+		},
+		{
+			"invalid reference frames",
+			"mp4",
+			VideoPreset{ReferenceFrames: "1.5"},
+			`video.referenceFrames: invalid number "1.5"`,
+		},
+		{
+			"no reference frames",
+			"mp4",
+			VideoPreset{ReferenceFrames: "0"},
+			"video.referenceFrames must be between 1 and 16 for codec h264, got 0",
+		},
+		{
+			"too many reference frames",
+			"mp4",
+			VideoPreset{Codec: "h265", ReferenceFrames: "17"},
+			"video.referenceFrames must be between 1 and 16 for codec h265, got 17",
+		},
+		{
+			"vp8 B-frames",
+			"webm",
+			VideoPreset{Codec: "vp8", BFrames: "1"},
+			"video.bFrames: codec vp8 doesn't support setting the number of B-frames",
+		},
+		{
+			"prores reference frames",
+			"mov",
+			VideoPreset{Codec: "prores", ReferenceFrames: "2"},
+			"video.referenceFrames: codec prores doesn't support setting the number of reference frames",
+		},
+	}
+	for _, test := range tests {
+		preset := Preset{Name: "some_preset", Container: test.container, Video: test.video}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestPresetValidationGOPStructure(t *testing.T) {
+	enabled, disabled := true, false
+	var tests = []struct {
+		testCase             string
+		closedGOP            *bool
+		sceneChangeDetection *bool
+		errMsg               string
+	}{
+		{"defaults", nil, nil, ""},
+		{"closed GOP", &enabled, nil, ""},
+		{"closed GOP without scene change detection", &enabled, &disabled, ""},
+		{"open GOP with scene change detection", &disabled, &enabled, ""},
+		{"scene change detection", nil, &enabled, ""},
+		{"closed GOP with scene change detection", &enabled, &enabled, "video.closedGOP can't be combined with video.sceneChangeDetection"},
+	}
+	for _, test := range tests {
+		preset := Preset{
+			Name:      "some_preset",
+			Container: "mp4",
+			Video:     VideoPreset{ClosedGOP: test.closedGOP, SceneChangeDetection: test.sceneChangeDetection},
+		}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestPresetValidationAudioChannels(t *testing.T) {
+	var tests = []struct {
+		testCase string
+		audio    AudioPreset
+		errMsg   string
+	}{
+		{"stereo", AudioPreset{Channels: "2"}, ""},
+		{"5.1 downmix", AudioPreset{SourceChannels: "6", Channels: "2"}, ""},
+		{"stereo to mono", AudioPreset{SourceChannels: "2", Channels: "1"}, ""},
+		{"stereo remap", AudioPreset{SourceChannels: "2", Channels: "2", ChannelMapping: []string{"-60,0", "0,-60"}}, ""},
+		{"7.1 downmix", AudioPreset{SourceChannels: "8", Channels: "2", ChannelMapping: []string{"0,-60,-3,-60,-3,-60,-3,-60", "-60,0,-3,-60,-60,-3,-60,-3"}}, ""},
+		{
+			"invalid channels",
+			AudioPreset{Channels: "3"},
+			`audio.channels: invalid number of channels "3", must be one of 1, 2, 6`,
+		},
+		{
+			"invalid source channels",
+			AudioPreset{SourceChannels: "5", Channels: "2"},
+			`audio.sourceChannels: invalid number of channels "5", must be one of 1, 2, 6, 8`,
+		},
+		{
+			"source channels without channels",
+			AudioPreset{SourceChannels: "6"},
+			"audio.sourceChannels requires audio.channels",
+		},
+		{
+			"channel mapping without source channels",
+			AudioPreset{Channels: "1", ChannelMapping: []string{"0,0"}},
+			"audio.channelMapping requires audio.sourceChannels",
+		},
+		{
+			"upmix",
+			AudioPreset{SourceChannels: "2", Channels: "6"},
+			"audio.channels: can't remix 2 source channels into 6 channels, only downmixes and remaps are supported",
+		},
+		{
+			"downmix without standard mapping",
+			AudioPreset{SourceChannels: "8", Channels: "2"},
+			"audio.channelMapping is required for remixing 8 source channels into 2 channels",
+		},
+		{
+			"missing output channel",
+			AudioPreset{SourceChannels: "6", Channels: "2", ChannelMapping: []string{"0,-60,-3,-60,-3,-60"}},
+			"audio.channelMapping must have one entry per output channel, got 1 entries for 2 channels",
+		},
+		{
+			"missing source channel",
+			AudioPreset{SourceChannels: "6", Channels: "2", ChannelMapping: []string{"0,-60,-3,-60,-3,-60", "-60,0,-3"}},
+			"audio.channelMapping[1] must have one gain per source channel, got 3 gains for 6 channels",
+		},
+		{
+			"gain out of range",
+			AudioPreset{SourceChannels: "2", Channels: "1", ChannelMapping: []string{"0,12"}},
+			"audio.channelMapping[0] must be between -60 and 6, got 12",
+		},
+		{
+			"invalid gain",
+			AudioPreset{SourceChannels: "2", Channels: "1", ChannelMapping: []string{"0,loud"}},
+			`audio.channelMapping[0]: invalid number "loud"`,
+		},
+	}
+	for _, test := range tests {
+		preset := Preset{Name: "mp4_audio", Container: "mp4", Audio: test.audio}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
+
+func TestAudioPresetChannelGains(t *testing.T) {
+	var tests = []struct {
+		testCase string
+		audio    AudioPreset
+		want     [][]string
+	}{
+		{"no remix", AudioPreset{Channels: "2"}, nil},
+		{
+			"5.1 downmix",
+			AudioPreset{SourceChannels: "6", Channels: "2"},
+			[][]string{{"0", "-60", "-3", "-60", "-3", "-60"}, {"-60", "0", "-3", "-60", "-60", "-3"}},
+		},
+		{
+			"same channels",
+			AudioPreset{SourceChannels: "2", Channels: "2"},
+			[][]string{{"0", "-60"}, {"-60", "0"}},
+		},
+		{
+			"custom mapping",
+			AudioPreset{SourceChannels: "2", Channels: "1", ChannelMapping: []string{"-3, -9"}},
+			[][]string{{"-3", "-9"}},
+		},
+	}
+	for _, test := range tests {
+		got := test.audio.ChannelGains()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: wrong channel gains\nwant %#v\ngot  %#v", test.testCase, test.want, got)
+		}
+	}
+}
+
+func TestPresetValidationIncludeStreams(t *testing.T) {
+	enabled, disabled := true, false
+	var tests = []struct {
+		testCase     string
+		includeVideo *bool
+		includeAudio *bool
+		errMsg       string
+	}{
+		{"defaults", nil, nil, ""},
+		{"video only", &enabled, &disabled, ""},
+		{"audio only", &disabled, nil, ""},
+		{"neither video nor audio", &disabled, &disabled, "at least one of includeVideo and includeAudio must be enabled"},
+	}
+	for _, test := range tests {
+		preset := Preset{IncludeVideo: test.includeVideo, IncludeAudio: test.includeAudio}
+		err := preset.Validate()
+		if err == nil {
+			err = errors.New("")
+		}
+		if err.Error() != test.errMsg {
+			t.Errorf("%s: wrong error message\nWant %q\nGot  %q", test.testCase, test.errMsg, err.Error())
+		}
+	}
+}
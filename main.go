@@ -8,7 +8,7 @@ import (
 	"github.com/NYTimes/video-transcoding-api/config"
 	_ "github.com/NYTimes/video-transcoding-api/provider/bitmovin"
 	_ "github.com/NYTimes/video-transcoding-api/provider/elastictranscoder"
-	_ "github.com/NYTimes/video-transcoding-api/provider/elementalconductor"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor"
 	_ "github.com/NYTimes/video-transcoding-api/provider/encodingcom"
 	_ "github.com/NYTimes/video-transcoding-api/provider/hybrik"
 	_ "github.com/NYTimes/video-transcoding-api/provider/zencoder"
@@ -31,6 +31,11 @@ func main() {
 		log.Fatal(err)
 	}
 
+	err = elementalconductor.RegisterClusters(cfg)
+	if err != nil {
+		logger.Fatal("unable to register Elemental Conductor clusters: ", err)
+	}
+
 	service, err := service.NewTranscodingService(cfg, logger)
 	if err != nil {
 		logger.Fatal("unable to initialize service: ", err)
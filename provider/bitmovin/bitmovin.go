@@ -1,6 +1,7 @@
 package bitmovin
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -475,7 +476,7 @@ func (p *bitmovinProvider) GetPreset(presetID string) (interface{}, error) {
 	return nil, errors.New("No Audio configuration found for Video Preset")
 }
 
-func (p *bitmovinProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+func (p *bitmovinProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	aclEntry := models.ACLItem{
 		Permission: bitmovintypes.ACLPermissionPublicRead,
 	}
@@ -532,7 +533,7 @@ func (p *bitmovinProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
 
 	//create the master manifest if needed so we can add it to the customData of the encoding response
 	for _, output := range job.Outputs {
-		if output.Preset.OutputOpts.Extension != "webm" {
+		if output.Preset.OutputOpts.OutputContainer() != "webm" {
 			videoPresetID := output.Preset.ProviderMapping[Name]
 			customDataResp, cdErr := h264S.RetrieveCustomData(videoPresetID)
 
@@ -898,7 +899,7 @@ func (p *bitmovinProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
 	return jobStatus, nil
 }
 
-func (p *bitmovinProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (p *bitmovinProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	encodingS := services.NewEncodingService(p.client)
 	statusResp, err := encodingS.RetrieveStatus(job.ProviderJobID)
 	if err != nil {
@@ -1188,7 +1189,7 @@ func (p *bitmovinProvider) mapStatus(status string) provider.Status {
 	}
 }
 
-func (p *bitmovinProvider) CancelJob(jobID string) error {
+func (p *bitmovinProvider) CancelJob(ctx context.Context, jobID string) error {
 	// stop the job
 	encodingS := services.NewEncodingService(p.client)
 	resp, err := encodingS.Stop(jobID)
@@ -1201,7 +1202,7 @@ func (p *bitmovinProvider) CancelJob(jobID string) error {
 	return nil
 }
 
-func (p *bitmovinProvider) Healthcheck() error {
+func (p *bitmovinProvider) Healthcheck(ctx context.Context) error {
 	// Just going to call list encodings, and if it errors, then clearly it is unhealthy
 	encodingS := services.NewEncodingService(p.client)
 	resp, err := encodingS.List(int64(0), int64(1))
@@ -1219,10 +1220,15 @@ func (p *bitmovinProvider) Capabilities() provider.Capabilities {
 		InputFormats:  []string{"prores", "h264"},
 		OutputFormats: []string{"mp4", "mov", "hls", "webm"},
 		Destinations:  []string{"s3"},
+		VideoCodecs:   []string{"h264", "vp8"},
+		AudioCodecs:   []string{"aac", "vorbis"},
 	}
 }
 
 func bitmovinFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	if cfg.Bitmovin.Disabled {
+		return nil, provider.ErrProviderDisabled
+	}
 	if cfg.Bitmovin.APIKey == "" {
 		return nil, errBitmovinInvalidConfig
 	}
@@ -1233,6 +1239,7 @@ func bitmovinFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
 		return nil, errBitmovinInvalidConfig
 	}
 	client := bitmovin.NewBitmovin(cfg.Bitmovin.APIKey, cfg.Bitmovin.Endpoint, int64(cfg.Bitmovin.Timeout))
+	client.HTTPClient = provider.HTTPClient(cfg, time.Duration(cfg.Bitmovin.Timeout)*time.Second)
 	return &bitmovinProvider{client: client, config: cfg.Bitmovin}, nil
 }
 
@@ -1,6 +1,7 @@
 package bitmovin
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -625,7 +626,7 @@ func TestTranscodeWithS3Input(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.Transcode(getJob("s3://bucket/folder/filename.mp4"))
+	jobStatus, err := prov.Transcode(context.Background(), getJob("s3://bucket/folder/filename.mp4"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -823,7 +824,7 @@ func TestTranscodeWithHTTPInput(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.Transcode(getJob("http://bucket.com/folder/filename.mp4"))
+	jobStatus, err := prov.Transcode(context.Background(), getJob("http://bucket.com/folder/filename.mp4"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1021,7 +1022,7 @@ func TestTranscodeWithHTTPSInput(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.Transcode(getJob("https://bucket.com/folder/filename.mp4"))
+	jobStatus, err := prov.Transcode(context.Background(), getJob("https://bucket.com/folder/filename.mp4"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1049,7 +1050,7 @@ func TestTranscodeFailsOnAPIError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.Transcode(getJob("s3://bucket/folder/filename.mp4"))
+	jobStatus, err := prov.Transcode(context.Background(), getJob("s3://bucket/folder/filename.mp4"))
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -1069,7 +1070,7 @@ func TestTranscodeFailsOnGenericError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.Transcode(getJob("s3://bucket/folder/filename.mp4"))
+	jobStatus, err := prov.Transcode(context.Background(), getJob("s3://bucket/folder/filename.mp4"))
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -1243,7 +1244,7 @@ func TestJobStatusReturnsFinishedIfEncodeAndManifestAreFinished(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1452,7 +1453,7 @@ func TestJobStatusReturnsFinishedIfEncodeIsFinishedAndNoManifestGenerationIsNeed
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1550,7 +1551,7 @@ func TestJobStatusReturnsStartedIfEncodeIsFinishedAndManifestIsRunning(t *testin
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1622,7 +1623,7 @@ func TestJobStatusReturnsStartedIfEncodeIsFinishedAndManifestIsCreatedAndStartRe
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1669,7 +1670,7 @@ func TestJobStatusReturnsQueuedIfEncodeIsCreated(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1714,7 +1715,7 @@ func TestJobStatusReturnsStartedIfEncodeIsRunning(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1759,7 +1760,7 @@ func TestJobStatusReturnsFailedIfEncodeFailed(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1823,7 +1824,7 @@ func TestJobStatusReturnsFailedIfEncodeIsFinishedAndManifestFailed(t *testing.T)
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1864,7 +1865,7 @@ func TestJobStatusReturnsUnknownOnAPIError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1930,7 +1931,7 @@ func TestJobStatusReturnsErrorOnFailureToListStreams(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -1993,7 +1994,7 @@ func TestJobStatusReturnsErrorOnFailureToGetInputData(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -2017,7 +2018,7 @@ func TestJobStatusReturnsErrorOnGenericError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: testJobID})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: testJobID})
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -2041,7 +2042,7 @@ func TestCancelJob(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	err := prov.CancelJob(testJobID)
+	err := prov.CancelJob(context.Background(), testJobID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2063,7 +2064,7 @@ func TestCancelJobFailsOnAPIError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	err := prov.CancelJob(testJobID)
+	err := prov.CancelJob(context.Background(), testJobID)
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -2081,7 +2082,7 @@ func TestCancelJobFailsOnGenericError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	err := prov.CancelJob(testJobID)
+	err := prov.CancelJob(context.Background(), testJobID)
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -2101,7 +2102,7 @@ func TestHealthCheck(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	err := prov.Healthcheck()
+	err := prov.Healthcheck(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2121,7 +2122,7 @@ func TestHealthCheckFailsOnAPIError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	err := prov.Healthcheck()
+	err := prov.Healthcheck(context.Background())
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -2138,7 +2139,7 @@ func TestHealthCheckFailsOnGenericError(t *testing.T) {
 	}))
 	defer ts.Close()
 	prov := getBitmovinProvider(ts.URL)
-	err := prov.Healthcheck()
+	err := prov.Healthcheck(context.Background())
 	if err == nil {
 		t.Fatal("unexpected <nil> error")
 	}
@@ -2150,6 +2151,8 @@ func TestCapabilities(t *testing.T) {
 		InputFormats:  []string{"prores", "h264"},
 		OutputFormats: []string{"mp4", "mov", "hls", "webm"},
 		Destinations:  []string{"s3"},
+		VideoCodecs:   []string{"h264", "vp8"},
+		AudioCodecs:   []string{"aac", "vorbis"},
 	}
 	cap := prov.Capabilities()
 	if !reflect.DeepEqual(cap, expected) {
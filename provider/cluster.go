@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+)
+
+const (
+	// RoundRobinStrategy sends jobs to each cluster of a provider in turn.
+	RoundRobinStrategy = "round-robin"
+
+	// LeastLoadedStrategy sends jobs to the cluster reporting the lowest
+	// load.
+	LeastLoadedStrategy = "least-loaded"
+)
+
+// ErrNoClusterAvailable is the error returned when none of the clusters of a
+// provider is able to report its load.
+var ErrNoClusterAvailable = errors.New("no cluster available for the provider")
+
+// LoadReporter is implemented by providers that are able to report their
+// current load, used for picking the least loaded cluster of a provider.
+type LoadReporter interface {
+	// Load returns the current load of the provider. Lower values mean less
+	// loaded providers.
+	Load() (float64, error)
+}
+
+type clusterGroup struct {
+	clusters []string
+	strategy string
+	next     uint32
+}
+
+var clusterGroups map[string]*clusterGroup
+
+// ClusterProviderName returns the name used for registering the given
+// cluster of a provider.
+func ClusterProviderName(name, cluster string) string {
+	return name + "-" + cluster
+}
+
+// RegisterClusters registers one provider for each of the given clusters,
+// using the names returned by ClusterProviderName, and makes jobs sent to the
+// provider with the given name be balanced among the clusters according to
+// the strategy.
+func RegisterClusters(name string, clusters map[string]Factory, strategy string) error {
+	switch strategy {
+	case "":
+		strategy = RoundRobinStrategy
+	case RoundRobinStrategy, LeastLoadedStrategy:
+	default:
+		return fmt.Errorf("invalid cluster strategy %q", strategy)
+	}
+	if clusterGroups == nil {
+		clusterGroups = make(map[string]*clusterGroup)
+	}
+	if _, ok := clusterGroups[name]; ok {
+		return ErrProviderAlreadyRegistered
+	}
+	group := clusterGroup{strategy: strategy}
+	for cluster := range clusters {
+		group.clusters = append(group.clusters, cluster)
+	}
+	sort.Strings(group.clusters)
+	for _, cluster := range group.clusters {
+		if err := Register(ClusterProviderName(name, cluster), clusters[cluster]); err != nil {
+			return err
+		}
+	}
+	clusterGroups[name] = &group
+	return nil
+}
+
+// PickCluster selects the cluster that should handle a new job sent to the
+// given provider, returning the name of the provider registered for the
+// cluster along with the name of the cluster. Providers without clusters are
+// returned as is, with an empty cluster name.
+func PickCluster(name string, cfg *config.Config) (providerName, cluster string, err error) {
+	group, ok := clusterGroups[name]
+	if !ok || len(group.clusters) == 0 {
+		return name, "", nil
+	}
+	if group.strategy == LeastLoadedStrategy {
+		cluster, err = group.leastLoaded(name, cfg)
+	} else {
+		cluster, err = group.roundRobin(name, cfg)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return ClusterProviderName(name, cluster), cluster, nil
+}
+
+// roundRobin returns the next cluster in turn, skipping the ones that are
+// disabled in the configuration.
+func (g *clusterGroup) roundRobin(name string, cfg *config.Config) (string, error) {
+	for range g.clusters {
+		n := atomic.AddUint32(&g.next, 1) - 1
+		cluster := g.clusters[int(n%uint32(len(g.clusters)))]
+		factory, err := GetProviderFactory(ClusterProviderName(name, cluster))
+		if err != nil {
+			return "", err
+		}
+		if _, err := factory(cfg); err != ErrProviderDisabled {
+			return cluster, nil
+		}
+	}
+	return "", ErrProviderDisabled
+}
+
+func (g *clusterGroup) leastLoaded(name string, cfg *config.Config) (string, error) {
+	var chosen string
+	var minLoad float64
+	for _, cluster := range g.clusters {
+		factory, err := GetProviderFactory(ClusterProviderName(name, cluster))
+		if err != nil {
+			continue
+		}
+		prov, err := factory(cfg)
+		if err != nil {
+			continue
+		}
+		reporter, ok := prov.(LoadReporter)
+		if !ok {
+			continue
+		}
+		load, err := reporter.Load()
+		if err != nil {
+			continue
+		}
+		if chosen == "" || load < minLoad {
+			chosen, minLoad = cluster, load
+		}
+	}
+	if chosen == "" {
+		return "", ErrNoClusterAvailable
+	}
+	return chosen, nil
+}
@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+)
+
+type loadedProvider struct {
+	fakeProvider
+	load    float64
+	loadErr error
+}
+
+func (p *loadedProvider) Load() (float64, error) {
+	return p.load, p.loadErr
+}
+
+func loadedFactory(load float64, loadErr error) Factory {
+	return func(*config.Config) (TranscodingProvider, error) {
+		return &loadedProvider{load: load, loadErr: loadErr}, nil
+	}
+}
+
+func TestRegisterClusters(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{
+		"east": noopFactory,
+		"west": noopFactory,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"elemental-east", "elemental-west"} {
+		if _, ok := providers[name]; !ok {
+			t.Errorf("expected to get the %s factory registered. Got map %#v", name, providers)
+		}
+	}
+	if strategy := clusterGroups["elemental"].strategy; strategy != RoundRobinStrategy {
+		t.Errorf("wrong default strategy. Want %q. Got %q", RoundRobinStrategy, strategy)
+	}
+}
+
+func TestRegisterClustersInvalidStrategy(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{"east": noopFactory}, "random")
+	if err == nil || err.Error() != `invalid cluster strategy "random"` {
+		t.Errorf("wrong error returned. Got %v", err)
+	}
+}
+
+func TestRegisterClustersDuplicate(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{"east": noopFactory}, RoundRobinStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterClusters("elemental", map[string]Factory{"west": noopFactory}, RoundRobinStrategy)
+	if err != ErrProviderAlreadyRegistered {
+		t.Errorf("Got wrong error when registering clusters twice. Want %#v. Got %#v", ErrProviderAlreadyRegistered, err)
+	}
+}
+
+func TestPickClusterRoundRobin(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{
+		"west":    noopFactory,
+		"east":    noopFactory,
+		"central": noopFactory,
+	}, RoundRobinStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := 0; i < 5; i++ {
+		providerName, cluster, err := PickCluster("elemental", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if providerName != ClusterProviderName("elemental", cluster) {
+			t.Errorf("wrong provider name for cluster %q: %q", cluster, providerName)
+		}
+		got = append(got, cluster)
+	}
+	want := []string{"central", "east", "west", "central", "east"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong clusters picked\nwant %#v\ngot  %#v", want, got)
+	}
+}
+
+func TestPickClusterRoundRobinSkipsDisabledClusters(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{
+		"west":    noopFactory,
+		"east":    getFactory(ErrProviderDisabled, nil, Capabilities{}),
+		"central": noopFactory,
+	}, RoundRobinStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := 0; i < 4; i++ {
+		_, cluster, err := PickCluster("elemental", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, cluster)
+	}
+	want := []string{"central", "west", "central", "west"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong clusters picked\nwant %#v\ngot  %#v", want, got)
+	}
+}
+
+func TestPickClusterRoundRobinAllDisabled(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	disabledFactory := getFactory(ErrProviderDisabled, nil, Capabilities{})
+	err := RegisterClusters("elemental", map[string]Factory{
+		"west": disabledFactory,
+		"east": disabledFactory,
+	}, RoundRobinStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = PickCluster("elemental", nil)
+	if err != ErrProviderDisabled {
+		t.Errorf("wrong error returned. Want %#v. Got %#v", ErrProviderDisabled, err)
+	}
+}
+
+func TestPickClusterLeastLoaded(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{
+		"east":    loadedFactory(3, nil),
+		"west":    loadedFactory(1.5, nil),
+		"central": loadedFactory(0, errors.New("there are no active nodes")),
+		"south":   getFactory(nil, nil, Capabilities{}),
+	}, LeastLoadedStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		providerName, cluster, err := PickCluster("elemental", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cluster != "west" {
+			t.Errorf("wrong cluster picked. Want %q. Got %q", "west", cluster)
+		}
+		if providerName != "elemental-west" {
+			t.Errorf("wrong provider name. Want %q. Got %q", "elemental-west", providerName)
+		}
+	}
+}
+
+func TestPickClusterLeastLoadedNoClusterAvailable(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	err := RegisterClusters("elemental", map[string]Factory{
+		"east": loadedFactory(0, errors.New("there are no active nodes")),
+		"west": getFactory(errors.New("invalid config"), nil, Capabilities{}),
+	}, LeastLoadedStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = PickCluster("elemental", nil)
+	if err != ErrNoClusterAvailable {
+		t.Errorf("wrong error returned. Want %#v. Got %#v", ErrNoClusterAvailable, err)
+	}
+}
+
+func TestPickClusterWithoutClusters(t *testing.T) {
+	providers = nil
+	clusterGroups = nil
+	providerName, cluster, err := PickCluster("fake", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providerName != "fake" || cluster != "" {
+		t.Errorf("wrong result for provider without clusters. Got (%q, %q)", providerName, cluster)
+	}
+}
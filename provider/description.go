@@ -13,11 +13,57 @@ type Description struct {
 
 // Capabilities describes the available features in the provider. It specificie
 // which input and output formats the provider supports, along with
-// supported destinations.
+// supported destinations and the access control lists it's able to apply to
+// output files.
 type Capabilities struct {
 	InputFormats  []string `json:"input"`
 	OutputFormats []string `json:"output"`
 	Destinations  []string `json:"destinations"`
+	OutputACLs    []string `json:"outputACLs,omitempty"`
+
+	// whether the provider supports stitching multiple segments
+	// together as the input of a job
+	InputStitching bool `json:"inputStitching,omitempty"`
+
+	// whether the provider supports picking the audio track of the source
+	// used by each output
+	AudioSelection bool `json:"audioSelection,omitempty"`
+
+	// whether the provider supports attaching tags to jobs
+	JobTags bool `json:"jobTags,omitempty"`
+
+	// whether the provider reports the spec of jobs as submitted to it,
+	// served by /jobs/{jobId}/spec
+	JobSpecs bool `json:"jobSpecs,omitempty"`
+
+	// whether the provider supports setting a human readable name on jobs
+	JobNames bool `json:"jobNames,omitempty"`
+
+	// whether the provider supports placing the audio of HLS jobs in a
+	// rendition group referenced by the variants
+	DemuxedHLS bool `json:"demuxedHLS,omitempty"`
+
+	// whether the provider supports referencing the variant playlists and
+	// segments of HLS jobs by absolute URLs, built from a base URL
+	AbsoluteHLSURLs bool `json:"absoluteHLSURLs,omitempty"`
+
+	// whether the provider supports naming the segments of HLS jobs after
+	// a template
+	HLSSegmentNames bool `json:"hlsSegmentNames,omitempty"`
+
+	// whether the provider supports ordering the variants of HLS jobs by
+	// their bandwidth
+	HLSVariantOrder bool `json:"hlsVariantOrder,omitempty"`
+
+	// containers jobs may tell the provider to read the source as,
+	// overriding the detection of the provider. Empty when the provider
+	// doesn't support input format hints
+	InputContainers []string `json:"inputContainers,omitempty"`
+
+	// video and audio codecs supported in presets. Empty when the provider
+	// doesn't restrict the codecs
+	VideoCodecs []string `json:"videoCodecs,omitempty"`
+	AudioCodecs []string `json:"audioCodecs,omitempty"`
 }
 
 // Health describes the current health status of the provider. If indicates
@@ -16,6 +16,7 @@
 package elastictranscoder
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -57,7 +58,7 @@ type awsProvider struct {
 	config *config.ElasticTranscoder
 }
 
-func (p *awsProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+func (p *awsProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	var adaptiveStreamingOutputs []db.TranscodeOutput
 	source := p.normalizeSource(job.SourceMedia)
 	params := elastictranscoder.CreateJobInput{
@@ -73,7 +74,7 @@ func (p *awsProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
 		presetQuery := &elastictranscoder.ReadPresetInput{
 			Id: aws.String(presetID),
 		}
-		presetOutput, err := p.c.ReadPreset(presetQuery)
+		presetOutput, err := p.c.ReadPresetWithContext(ctx, presetQuery)
 		if err != nil {
 			return nil, err
 		}
@@ -109,7 +110,7 @@ func (p *awsProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
 
 		params.Playlists = []*elastictranscoder.CreateJobPlaylist{&jobPlaylist}
 	}
-	resp, err := p.c.CreateJob(&params)
+	resp, err := p.c.CreateJobWithContext(ctx, &params)
 	if err != nil {
 		return nil, err
 	}
@@ -252,9 +253,9 @@ func (p *awsProvider) DeletePreset(presetID string) error {
 	return err
 }
 
-func (p *awsProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (p *awsProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	id := job.ProviderJobID
-	resp, err := p.c.ReadJob(&elastictranscoder.ReadJobInput{Id: aws.String(id)})
+	resp, err := p.c.ReadJobWithContext(ctx, &elastictranscoder.ReadJobInput{Id: aws.String(id)})
 	if err != nil {
 		return nil, err
 	}
@@ -269,11 +270,11 @@ func (p *awsProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
 		}
 		outputs[aws.StringValue(output.Key)] = aws.StringValue(output.StatusDetail)
 	}
-	outputDestination, err := p.getOutputDestination(job, resp.Job)
+	outputDestination, err := p.getOutputDestination(ctx, job, resp.Job)
 	if err != nil {
 		outputDestination = err.Error()
 	}
-	outputFiles, err := p.getOutputFiles(resp.Job)
+	outputFiles, err := p.getOutputFiles(ctx, resp.Job)
 	if err != nil {
 		return nil, err
 	}
@@ -307,8 +308,8 @@ func (p *awsProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
 	}, nil
 }
 
-func (p *awsProvider) getOutputDestination(job *db.Job, awsJob *elastictranscoder.Job) (string, error) {
-	readPipelineOutput, err := p.c.ReadPipeline(&elastictranscoder.ReadPipelineInput{
+func (p *awsProvider) getOutputDestination(ctx context.Context, job *db.Job, awsJob *elastictranscoder.Job) (string, error) {
+	readPipelineOutput, err := p.c.ReadPipelineWithContext(ctx, &elastictranscoder.ReadPipelineInput{
 		Id: awsJob.PipelineId,
 	})
 	if err != nil {
@@ -320,8 +321,8 @@ func (p *awsProvider) getOutputDestination(job *db.Job, awsJob *elastictranscode
 	), nil
 }
 
-func (p *awsProvider) getOutputFiles(job *elastictranscoder.Job) ([]provider.OutputFile, error) {
-	pipeline, err := p.c.ReadPipeline(&elastictranscoder.ReadPipelineInput{
+func (p *awsProvider) getOutputFiles(ctx context.Context, job *elastictranscoder.Job) ([]provider.OutputFile, error) {
+	pipeline, err := p.c.ReadPipelineWithContext(ctx, &elastictranscoder.ReadPipelineInput{
 		Id: job.PipelineId,
 	})
 	if err != nil {
@@ -329,7 +330,7 @@ func (p *awsProvider) getOutputFiles(job *elastictranscoder.Job) ([]provider.Out
 	}
 	files := make([]provider.OutputFile, 0, len(job.Outputs)+len(job.Playlists))
 	for _, output := range job.Outputs {
-		preset, err := p.c.ReadPreset(&elastictranscoder.ReadPresetInput{
+		preset, err := p.c.ReadPresetWithContext(ctx, &elastictranscoder.ReadPresetInput{
 			Id: output.PresetId,
 		})
 		if err != nil {
@@ -379,13 +380,13 @@ func (p *awsProvider) statusMap(awsStatus string) provider.Status {
 	}
 }
 
-func (p *awsProvider) CancelJob(id string) error {
-	_, err := p.c.CancelJob(&elastictranscoder.CancelJobInput{Id: aws.String(id)})
+func (p *awsProvider) CancelJob(ctx context.Context, id string) error {
+	_, err := p.c.CancelJobWithContext(ctx, &elastictranscoder.CancelJobInput{Id: aws.String(id)})
 	return err
 }
 
-func (p *awsProvider) Healthcheck() error {
-	_, err := p.c.ReadPipeline(&elastictranscoder.ReadPipelineInput{
+func (p *awsProvider) Healthcheck(ctx context.Context) error {
+	_, err := p.c.ReadPipelineWithContext(ctx, &elastictranscoder.ReadPipelineInput{
 		Id: aws.String(p.config.PipelineID),
 	})
 	return err
@@ -400,6 +401,9 @@ func (p *awsProvider) Capabilities() provider.Capabilities {
 }
 
 func elasticTranscoderFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	if cfg.ElasticTranscoder.Disabled {
+		return nil, provider.ErrProviderDisabled
+	}
 	if cfg.ElasticTranscoder.AccessKeyID == "" || cfg.ElasticTranscoder.SecretAccessKey == "" || cfg.ElasticTranscoder.PipelineID == "" {
 		return nil, errAWSInvalidConfig
 	}
@@ -412,6 +416,9 @@ func elasticTranscoderFactory(cfg *config.Config) (provider.TranscodingProvider,
 	if err != nil {
 		return nil, err
 	}
+	httpClient := *awsSession.Config.HTTPClient
+	httpClient.Transport = provider.HTTPTransport(cfg, httpClient.Transport)
+	awsSession.Config.HTTPClient = &httpClient
 	return &awsProvider{
 		c:      elastictranscoder.New(awsSession),
 		config: cfg.ElasticTranscoder,
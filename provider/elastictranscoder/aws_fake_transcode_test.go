@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/elastictranscoder"
 )
 
@@ -147,6 +148,41 @@ func (c *fakeElasticTranscoder) CancelJob(input *elastictranscoder.CancelJobInpu
 	return &elastictranscoder.CancelJobOutput{}, nil
 }
 
+func (c *fakeElasticTranscoder) CreateJobWithContext(ctx aws.Context, input *elastictranscoder.CreateJobInput, _ ...request.Option) (*elastictranscoder.CreateJobResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.CreateJob(input)
+}
+
+func (c *fakeElasticTranscoder) ReadPresetWithContext(ctx aws.Context, input *elastictranscoder.ReadPresetInput, _ ...request.Option) (*elastictranscoder.ReadPresetOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.ReadPreset(input)
+}
+
+func (c *fakeElasticTranscoder) ReadJobWithContext(ctx aws.Context, input *elastictranscoder.ReadJobInput, _ ...request.Option) (*elastictranscoder.ReadJobOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.ReadJob(input)
+}
+
+func (c *fakeElasticTranscoder) ReadPipelineWithContext(ctx aws.Context, input *elastictranscoder.ReadPipelineInput, _ ...request.Option) (*elastictranscoder.ReadPipelineOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.ReadPipeline(input)
+}
+
+func (c *fakeElasticTranscoder) CancelJobWithContext(ctx aws.Context, input *elastictranscoder.CancelJobInput, _ ...request.Option) (*elastictranscoder.CancelJobOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.CancelJob(input)
+}
+
 func (c *fakeElasticTranscoder) prepareFailure(op string, err error) {
 	c.failures <- failure{op: op, err: err}
 }
@@ -1,7 +1,10 @@
 package elastictranscoder
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"regexp"
@@ -32,6 +35,7 @@ func TestElasticTranscoderProvider(t *testing.T) {
 			PipelineID:      "mypipeline",
 			Region:          "sa-east-1",
 		},
+		ProviderUserAgent: "video-transcoding-api-staging",
 	}
 	provider, err := elasticTranscoderFactory(&cfg)
 	if err != nil {
@@ -57,6 +61,20 @@ func TestElasticTranscoderProvider(t *testing.T) {
 	if region != cfg.ElasticTranscoder.Region {
 		t.Errorf("ElasticTranscoderProvider: wrong region. Want %q. Got %q.", cfg.ElasticTranscoder.Region, region)
 	}
+
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+	}))
+	defer server.Close()
+	resp, err := elasticProvider.c.(*elastictranscoder.ElasticTranscoder).Config.HTTPClient.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if userAgent != "video-transcoding-api-staging" {
+		t.Errorf("ElasticTranscoderProvider: wrong User-Agent. Want %q. Got %q.", "video-transcoding-api-staging", userAgent)
+	}
 }
 
 func TestElasticTranscoderProviderDefaultRegion(t *testing.T) {
@@ -171,7 +189,7 @@ func TestAWSTranscode(t *testing.T) {
 		},
 	}
 
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-123",
 		SourceMedia:     source,
 		Outputs:         outputs,
@@ -265,7 +283,7 @@ func TestAWSTranscodeAdaptiveStreaming(t *testing.T) {
 		},
 	}
 
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -385,7 +403,7 @@ func TestAWSTranscodeAdaptiveAndNonAdaptiveStreaming(t *testing.T) {
 		},
 	}
 
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -483,7 +501,7 @@ func TestAWSTranscodeNormalizedSource(t *testing.T) {
 			},
 		},
 	}
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-1",
 		SourceMedia:     source,
 		Outputs:         outputs,
@@ -550,7 +568,7 @@ func TestAWSTranscodePresetNotFound(t *testing.T) {
 			},
 		},
 	}
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-123",
 		SourceMedia:     source,
 		Outputs:         outputs,
@@ -578,7 +596,7 @@ func TestAWSTranscodeAWSFailureInAmazon(t *testing.T) {
 		},
 	}
 	source := "dir/file.mp4"
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-123",
 		SourceMedia:     source,
 		StreamingParams: db.StreamingParams{},
@@ -638,7 +656,7 @@ func TestAWSJobStatus(t *testing.T) {
 		},
 	}
 	source := "dir/file.mov"
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -651,7 +669,7 @@ func TestAWSJobStatus(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	jobStatus, err = prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: jobStatus.ProviderJobID})
+	jobStatus, err = prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: jobStatus.ProviderJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -736,7 +754,7 @@ func TestAWSJobStatusNoDetectedProperties(t *testing.T) {
 			},
 		},
 	}
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-123",
 		SourceMedia:     "dir/file.mov",
 		Outputs:         outputs,
@@ -746,7 +764,7 @@ func TestAWSJobStatusNoDetectedProperties(t *testing.T) {
 		t.Fatal(err)
 	}
 	fakeTranscoder.jobs[jobStatus.ProviderJobID].Input.DetectedProperties = nil
-	jobStatus, err = prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: jobStatus.ProviderJobID})
+	jobStatus, err = prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: jobStatus.ProviderJobID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1020,7 +1038,7 @@ func TestAWSJobStatusNotFound(t *testing.T) {
 			PipelineID:      "mypipeline",
 		},
 	}
-	jobStatus, err := provider.JobStatus(&db.Job{ProviderJobID: "idk"})
+	jobStatus, err := provider.JobStatus(context.Background(), &db.Job{ProviderJobID: "idk"})
 	if err == nil {
 		t.Fatal("Got unexpected <nil> error")
 	}
@@ -1046,7 +1064,7 @@ func TestAWSJobStatusInternalError(t *testing.T) {
 			PipelineID:      "mypipeline",
 		},
 	}
-	jobStatus, err := provider.JobStatus(&db.Job{ProviderJobID: "idk"})
+	jobStatus, err := provider.JobStatus(context.Background(), &db.Job{ProviderJobID: "idk"})
 	if jobStatus != nil {
 		t.Errorf("Got unexpected non-nil JobStatus: %#v", jobStatus)
 	}
@@ -1087,7 +1105,7 @@ func TestCancelJob(t *testing.T) {
 			PipelineID:      "mypipeline",
 		},
 	}
-	err := prov.CancelJob("idk")
+	err := prov.CancelJob(context.Background(), "idk")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1109,7 +1127,7 @@ func TestCancelJobInternalError(t *testing.T) {
 			PipelineID:      "mypipeline",
 		},
 	}
-	err := provider.CancelJob("idk")
+	err := provider.CancelJob(context.Background(), "idk")
 	if err != prepErr {
 		t.Errorf("wrong error returned.\nWant %#v\nGot  %#v", prepErr, err)
 	}
@@ -1126,7 +1144,7 @@ func TestHealthcheck(t *testing.T) {
 			PipelineID:      "mypipeline",
 		},
 	}
-	err := provider.Healthcheck()
+	err := provider.Healthcheck(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1145,7 +1163,7 @@ func TestHealthcheckFailure(t *testing.T) {
 			PipelineID:      "mypipeline",
 		},
 	}
-	err := provider.Healthcheck()
+	err := provider.Healthcheck(context.Background())
 	if err != prepErr {
 		t.Errorf("Wrong error returned. Want %#v.Got %#v", prepErr, err)
 	}
@@ -1,14 +1,16 @@
 package elementalconductor
 
-import "github.com/NYTimes/encoding-wrapper/elementalconductor"
+import "github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
 
 type clientInterface interface {
+	GetPresets() (*elementalconductor.PresetList, error)
 	GetPreset(presetID string) (*elementalconductor.Preset, error)
 	CreatePreset(preset *elementalconductor.Preset) (*elementalconductor.Preset, error)
 	DeletePreset(presetID string) error
 	CreateJob(job *elementalconductor.Job) (*elementalconductor.Job, error)
 	GetJob(jobID string) (*elementalconductor.Job, error)
 	CancelJob(jobID string) (*elementalconductor.Job, error)
+	UpdateJobPriority(jobID string, priority int) (*elementalconductor.Job, error)
 	GetNodes() ([]elementalconductor.Node, error)
 	GetCloudConfig() (*elementalconductor.CloudConfig, error)
 }
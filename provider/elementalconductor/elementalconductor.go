@@ -16,17 +16,25 @@
 package elementalconductor
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/NYTimes/encoding-wrapper/elementalconductor"
 	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/db"
 	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
 )
 
 // Name is the name used for registering the Elemental Conductor provider in the
@@ -35,6 +43,189 @@ const Name = "elementalconductor"
 
 const defaultJobPriority = 50
 
+// maxInputBufferMsec is the maximum amount of the input, in milliseconds,
+// prefetched by the nodes before processing it.
+const maxInputBufferMsec = 60000
+
+// inputContainers lists the containers Elemental Conductor can be told to
+// read the source as, through the container override of the inputs.
+var inputContainers = []string{"mov", "mp4", "mxf", "ts", "webm"}
+
+// loudnessAlgorithm is the ITU-R BS.1770 revision used for measuring
+// loudness when normalizing audio.
+const loudnessAlgorithm = "ITU_BS_1770_2"
+
+// redactedCredential replaces the credentials in job specs returned for
+// inspection.
+const redactedCredential = "REDACTED"
+
+// defaultPadColor is the color of the bars added when converting the aspect
+// ratio of the video using padding.
+const defaultPadColor = "#000000"
+
+// deinterlaceAlgorithm and deinterlaceMode configure the deinterlacer used
+// when the preset asks for the source to be deinterlaced.
+const (
+	deinterlaceAlgorithm = "interpolate"
+	deinterlaceMode      = "Deinterlace"
+)
+
+// aacCodingModes maps the number of channels of the audio of presets to the
+// coding modes of AAC audio in Elemental Conductor.
+var aacCodingModes = map[string]string{
+	"1": "1_0",
+	"2": "2_0",
+	"6": "5_1",
+}
+
+// audioVBRQualities maps the qualities of variable bitrate audio in presets
+// to the ones used by the AAC encoder of Elemental Conductor.
+var audioVBRQualities = map[string]string{
+	db.AudioQualityLow:        "Low",
+	db.AudioQualityMediumLow:  "Medium Low",
+	db.AudioQualityMediumHigh: "Medium High",
+	db.AudioQualityHigh:       "High",
+}
+
+// elementalProfiles maps the codec profiles of presets to the names used by
+// Elemental Conductor.
+var elementalProfiles = map[string]string{
+	"baseline": "Baseline",
+	"main":     "Main",
+	"high":     "High",
+	"main10":   "Main10",
+}
+
+// colorFormatProfiles maps the chroma subsamplings and bit depths of presets
+// to the profiles that select them in the encoders of Elemental Conductor,
+// by codec. The formats of each codec that are missing aren't supported,
+// and 4:2:0 8-bit is the default of all codecs but ProRes, which only
+// encodes 4:2:2 10-bit.
+var colorFormatProfiles = map[string]map[string]string{
+	"h264": {
+		"4:2:0 8":  "",
+		"4:2:0 10": "High10",
+		"4:2:2 8":  "High422",
+		"4:2:2 10": "High422",
+	},
+	"h265": {
+		"4:2:0 8":  "",
+		"4:2:0 10": "Main10",
+		"4:2:2 8":  "Main422_10BIT",
+		"4:2:2 10": "Main422_10BIT",
+	},
+	"mpeg2": {
+		"4:2:0 8": "",
+		"4:2:2 8": "422",
+	},
+	"prores": {
+		"4:2:2 10": "",
+	},
+}
+
+// colorFormatProfile returns the Elemental Conductor profile selecting the
+// chroma subsampling and bit depth of the given preset, which is empty when
+// the preset uses the default format of its codec.
+func colorFormatProfile(codec string, preset db.VideoPreset) (string, error) {
+	if preset.ChromaSubsampling == "" && preset.BitDepth == "" {
+		return "", nil
+	}
+	subsampling, bitDepth := preset.ChromaSubsampling, preset.BitDepth
+	if codec == "prores" {
+		if subsampling == "" {
+			subsampling = "4:2:2"
+		}
+		if bitDepth == "" {
+			bitDepth = "10"
+		}
+	}
+	if subsampling == "" {
+		subsampling = "4:2:0"
+	}
+	if bitDepth == "" {
+		bitDepth = "8"
+	}
+	profile, ok := colorFormatProfiles[codec][subsampling+" "+bitDepth]
+	if !ok {
+		return "", fmt.Errorf("elementalconductor: codec %s doesn't support %s %s-bit output", codec, subsampling, bitDepth)
+	}
+	return profile, nil
+}
+
+// Elemental Conductor supports fewer consecutive B-frames and reference
+// frames than the codecs allow.
+const (
+	maxBFrames         = 7
+	maxReferenceFrames = 6
+)
+
+func validateFrameReferences(preset db.VideoPreset) error {
+	if preset.BFrames != "" {
+		if n, err := strconv.Atoi(preset.BFrames); err != nil || n > maxBFrames {
+			return fmt.Errorf("elementalconductor: video.bFrames must be between 0 and %d, got %q", maxBFrames, preset.BFrames)
+		}
+	}
+	if preset.ReferenceFrames != "" {
+		if n, err := strconv.Atoi(preset.ReferenceFrames); err != nil || n > maxReferenceFrames {
+			return fmt.Errorf("elementalconductor: video.referenceFrames must be between 1 and %d, got %q", maxReferenceFrames, preset.ReferenceFrames)
+		}
+	}
+	return nil
+}
+
+// gopClosedCadence returns the number of GOPs between closed GOPs in
+// Elemental Conductor for the given setting of the preset, leaving it to the
+// provider when unset. Zero makes all GOPs open.
+func gopClosedCadence(closed *bool) string {
+	if closed == nil {
+		return ""
+	}
+	if *closed {
+		return "1"
+	}
+	return "0"
+}
+
+func boolString(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
+// encoderQualityLevels maps the encoder speeds of presets to the quality
+// levels of the encoders of Elemental Conductor, which trade speed for
+// quality in coarser steps.
+var encoderQualityLevels = map[string]string{
+	"ultrafast": "Low",
+	"superfast": "Low",
+	"veryfast":  "Low",
+	"faster":    "Medium",
+	"fast":      "Medium",
+	"medium":    "Medium",
+	"slow":      "High",
+	"slower":    "High",
+	"veryslow":  "High",
+}
+
+func elementalProfile(profile string) string {
+	if name, ok := elementalProfiles[strings.ToLower(profile)]; ok {
+		return name
+	}
+	return profile
+}
+
+// containerDefaultCodecs lists the codecs used in presets that don't specify
+// them, for containers that can't hold the default codecs of Elemental
+// Conductor. MXF defaults to XDCAM, which is MPEG-2 video with PCM audio.
+var containerDefaultCodecs = map[string]struct{ video, audio string }{
+	"webm": {video: "vp9", audio: "opus"},
+	"mxf":  {video: "mpeg2", audio: "pcm"},
+}
+
+// frameCaptureQuality is the JPEG quality of captured frames.
+const frameCaptureQuality = 80
+
 var errElementalConductorInvalidConfig = provider.InvalidConfigError("missing Elemental user login or api key. Please define the environment variables ELEMENTALCONDUCTOR_USER_LOGIN and ELEMENTALCONDUCTOR_API_KEY or set these values in the configuration file")
 
 func init() {
@@ -42,8 +233,10 @@ func init() {
 }
 
 type elementalConductorProvider struct {
-	config *config.ElementalConductor
-	client clientInterface
+	config  *config.ElementalConductor
+	client  clientInterface
+	gcs     *gcsCredentials
+	objects objectStore
 }
 
 func (p *elementalConductorProvider) DeletePreset(presetID string) error {
@@ -57,18 +250,131 @@ func (p *elementalConductorProvider) CreatePreset(preset db.Preset) (string, err
 	elementalConductorPreset.Name = preset.Name
 	elementalConductorPreset.Description = preset.Description
 	elementalConductorPreset.Container = preset.Container
-	elementalConductorPreset.Profile = preset.Video.Profile
-	elementalConductorPreset.ProfileLevel = preset.Video.ProfileLevel
-	elementalConductorPreset.RateControl = preset.RateControl
-	elementalConductorPreset.Width = preset.Video.Width
-	elementalConductorPreset.Height = preset.Video.Height
-	elementalConductorPreset.VideoCodec = preset.Video.Codec
-	elementalConductorPreset.VideoBitrate = preset.Video.Bitrate
-	elementalConductorPreset.GopSize = preset.Video.GopSize
-	elementalConductorPreset.GopMode = preset.Video.GopMode
-	elementalConductorPreset.InterlaceMode = preset.Video.InterlaceMode
-	elementalConductorPreset.AudioCodec = preset.Audio.Codec
-	elementalConductorPreset.AudioBitrate = preset.Audio.Bitrate
+	elementalConductorPreset.ExcludeVideo = !preset.HasVideo()
+	elementalConductorPreset.ExcludeAudio = !preset.HasAudio()
+	if preset.HasVideo() {
+		qualityLevel := encoderQualityLevels[strings.ToLower(preset.Video.EncoderSpeed)]
+		codec := strings.ToLower(preset.Video.Codec)
+		if codec == "" {
+			codec = containerDefaultCodecs[preset.Container].video
+		}
+		if codec == "" {
+			codec = "h264"
+		}
+		formatProfile, err := colorFormatProfile(codec, preset.Video)
+		if err != nil {
+			return "", err
+		}
+		err = validateFrameReferences(preset.Video)
+		if err != nil {
+			return "", err
+		}
+		if strings.EqualFold(preset.Video.Codec, "h265") {
+			elementalConductorPreset.H265Profile = elementalProfile(preset.Video.Profile)
+			elementalConductorPreset.H265Level = preset.Video.ProfileLevel
+			elementalConductorPreset.H265Quality = qualityLevel
+			elementalConductorPreset.H265NumBFrames = preset.Video.BFrames
+			elementalConductorPreset.H265NumRefFrames = preset.Video.ReferenceFrames
+			elementalConductorPreset.H265GopClosed = gopClosedCadence(preset.Video.ClosedGOP)
+			elementalConductorPreset.H265SceneChange = boolString(preset.Video.SceneChangeDetection)
+			if formatProfile != "" {
+				elementalConductorPreset.H265Profile = formatProfile
+			}
+		} else {
+			elementalConductorPreset.Profile = elementalProfile(preset.Video.Profile)
+			elementalConductorPreset.ProfileLevel = preset.Video.ProfileLevel
+			elementalConductorPreset.QualityLevel = qualityLevel
+			elementalConductorPreset.NumBFrames = preset.Video.BFrames
+			elementalConductorPreset.NumRefFrames = preset.Video.ReferenceFrames
+			elementalConductorPreset.GopClosed = gopClosedCadence(preset.Video.ClosedGOP)
+			elementalConductorPreset.SceneChange = boolString(preset.Video.SceneChangeDetection)
+			if formatProfile != "" {
+				elementalConductorPreset.Profile = formatProfile
+			}
+		}
+		elementalConductorPreset.RateControl = preset.RateControl
+		elementalConductorPreset.Width = preset.Video.Width
+		elementalConductorPreset.Height = preset.Video.Height
+		elementalConductorPreset.VideoCodec = preset.Video.Codec
+		elementalConductorPreset.VideoBitrate = preset.Video.Bitrate
+		elementalConductorPreset.GopSize = preset.Video.GopSize
+		elementalConductorPreset.GopMode = preset.Video.GopMode
+		elementalConductorPreset.InterlaceMode = interlaceMode(preset.Video)
+		if preset.Video.Telecine != "" {
+			elementalConductorPreset.Telecine = preset.Video.Telecine
+		}
+		if preset.Video.Deinterlace {
+			elementalConductorPreset.Deinterlacer = &elementalconductor.Deinterlacer{
+				Algorithm: deinterlaceAlgorithm,
+				Mode:      deinterlaceMode,
+			}
+		}
+		if preset.Video.MaxFrameRate != "" {
+			numerator, denominator, err := db.ParseFrameRate(preset.Video.MaxFrameRate)
+			if err != nil {
+				return "", err
+			}
+			elementalConductorPreset.FramerateFollowSource = "true"
+			elementalConductorPreset.FrameRateConversion = &elementalconductor.FrameRateConversion{
+				Numerator:    strconv.Itoa(numerator),
+				Denominator:  strconv.Itoa(denominator),
+				DecreaseOnly: "true",
+			}
+		}
+		switch preset.Video.AspectRatioMode {
+		case db.AspectRatioModeStretch:
+			elementalConductorPreset.StretchToOutput = "true"
+		case db.AspectRatioModeCrop, db.AspectRatioModePad:
+			elementalConductorPreset.StretchToOutput = "false"
+			elementalConductorPreset.AspectRatioConversion = &elementalconductor.AspectRatioConversion{
+				Mode:        preset.Video.AspectRatioMode,
+				AspectRatio: preset.Video.AspectRatio,
+				PadColor:    preset.Video.PadColor,
+			}
+			if preset.Video.AspectRatioMode == db.AspectRatioModePad && preset.Video.PadColor == "" {
+				elementalConductorPreset.AspectRatioConversion.PadColor = defaultPadColor
+			}
+		}
+		if elementalConductorPreset.VideoCodec == "" {
+			elementalConductorPreset.VideoCodec = containerDefaultCodecs[preset.Container].video
+		}
+	}
+	if preset.HasAudio() {
+		elementalConductorPreset.AudioCodec = preset.Audio.Codec
+		elementalConductorPreset.AudioBitrate = preset.Audio.Bitrate
+		if elementalConductorPreset.AudioCodec == "" {
+			elementalConductorPreset.AudioCodec = containerDefaultCodecs[preset.Container].audio
+		}
+		switch preset.Audio.BitrateMode {
+		case db.AudioBitrateModeCBR:
+			elementalConductorPreset.AudioRateControl = "CBR"
+		case db.AudioBitrateModeVBR:
+			elementalConductorPreset.AudioRateControl = "VBR"
+			elementalConductorPreset.AudioVBRQuality = audioVBRQualities[preset.Audio.Quality]
+		}
+		if codec := elementalConductorPreset.AudioCodec; codec == "" || strings.EqualFold(codec, "aac") {
+			elementalConductorPreset.AudioCodingMode = aacCodingModes[preset.Audio.Channels]
+		}
+		if gains := preset.Audio.ChannelGains(); gains != nil {
+			elementalConductorPreset.AudioRemix = &elementalconductor.RemixSettings{
+				ChannelsIn:     preset.Audio.SourceChannels,
+				ChannelsOut:    preset.Audio.Channels,
+				ChannelMapping: gains,
+			}
+		}
+	}
+	err := applyPresetOptions(&elementalConductorPreset, preset.ProviderOptions)
+	if err != nil {
+		return "", err
+	}
+	if preset.HasAudio() && preset.Audio.LoudnessTarget != "" {
+		elementalConductorPreset.AudioNormalization = &elementalconductor.AudioNormalizationSettings{
+			Algorithm:        loudnessAlgorithm,
+			AlgorithmControl: "correct_audio",
+			TargetLKFS:       preset.Audio.LoudnessTarget,
+			TruePeakLimit:    preset.Audio.TruePeakLimit,
+		}
+	}
 
 	result, err := p.client.CreatePreset(&elementalConductorPreset)
 	if err != nil {
@@ -78,6 +384,18 @@ func (p *elementalConductorProvider) CreatePreset(preset db.Preset) (string, err
 	return result.Name, nil
 }
 
+// interlaceMode returns the Elemental Conductor interlace mode of the given
+// preset, which combines the interlace mode and the field order.
+func interlaceMode(preset db.VideoPreset) string {
+	if preset.InterlaceMode != db.InterlaceModeInterlaced {
+		return preset.InterlaceMode
+	}
+	if preset.FieldOrder == db.FieldOrderBottom {
+		return "bottom_field"
+	}
+	return "top_field"
+}
+
 func (p *elementalConductorProvider) GetPreset(presetID string) (interface{}, error) {
 	preset, err := p.client.GetPreset(presetID)
 	if err != nil {
@@ -86,26 +404,66 @@ func (p *elementalConductorProvider) GetPreset(presetID string) (interface{}, er
 	return preset, err
 }
 
-func (p *elementalConductorProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+// withContext returns a copy of the provider whose calls to Elemental
+// Conductor are bound to the given context.
+func (p *elementalConductorProvider) withContext(ctx context.Context) *elementalConductorProvider {
+	bound := *p
+	if client, ok := p.client.(*elementalconductor.Client); ok {
+		bound.client = client.WithContext(ctx)
+	}
+	return &bound
+}
+
+func (p *elementalConductorProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
+	p = p.withContext(ctx)
 	newJob, err := p.newJob(job)
 	if err != nil {
 		return nil, err
 	}
+	if skipExistingOutputs(job) {
+		files, err := p.existingOutputFiles(ctx, newJob)
+		if err != nil {
+			return nil, err
+		}
+		if files != nil {
+			status := p.skippedJobStatus(job, files)
+			status.Spec, err = jobSpec(*newJob)
+			if err != nil {
+				return nil, err
+			}
+			return status, nil
+		}
+	}
 	resp, err := p.client.CreateJob(newJob)
 	if err != nil {
 		return nil, err
 	}
-	return &provider.JobStatus{
+	status := provider.JobStatus{
 		ProviderName:  Name,
 		ProviderJobID: resp.GetID(),
 		Status:        provider.StatusQueued,
-	}, nil
+	}
+	// the job is already in Elemental Conductor, so it's reported without
+	// its spec rather than failed when the spec can't be generated.
+	status.Spec, err = jobSpec(*newJob)
+	if err != nil {
+		log.Printf("elementalconductor: unable to generate the spec of job %q: %s", status.ProviderJobID, err)
+	}
+	return &status, nil
 }
 
-func (p *elementalConductorProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (p *elementalConductorProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
+	p = p.withContext(ctx)
+	if strings.HasPrefix(job.ProviderJobID, skippedJobPrefix) {
+		files, err := p.skippedOutputFiles(job)
+		if err != nil {
+			return nil, err
+		}
+		return p.skippedJobStatus(job, files), nil
+	}
 	resp, err := p.client.GetJob(job.ProviderJobID)
 	if err != nil {
-		return nil, err
+		return nil, checkJobNotFound(job.ProviderJobID, err)
 	}
 	providerStatus := map[string]interface{}{
 		"status":    resp.Status,
@@ -122,23 +480,26 @@ func (p *elementalConductorProvider) JobStatus(job *db.Job) (*provider.JobStatus
 	}
 	if len(resp.ErrorMessages) > 0 {
 		providerStatus["error_messages"] = resp.ErrorMessages
+		providerStatus["error_category"] = p.jobErrorsCategory(resp.ErrorMessages)
 	}
 	var duration time.Duration
 	if resp.ContentDuration != nil {
 		duration = time.Duration(resp.ContentDuration.InputDuration) * time.Second
 	}
+	completeTime := resp.CompleteTime.Time
+	if completeTime.IsZero() {
+		completeTime = resp.ErroredTime.Time
+	}
 	return &provider.JobStatus{
 		ProviderName:   Name,
 		ProviderJobID:  job.ProviderJobID,
 		Progress:       float64(resp.PercentComplete),
 		Status:         p.statusMap(resp.Status),
 		ProviderStatus: providerStatus,
-		SourceInfo: provider.SourceInfo{
-			Duration:   duration,
-			VideoCodec: resp.Input.InputInfo.Video.Format,
-			Height:     resp.Input.InputInfo.Video.GetHeight(),
-			Width:      resp.Input.InputInfo.Video.GetWidth(),
-		},
+		SubmitTime:     resp.Submitted.Time,
+		StartTime:      resp.StartTime.Time,
+		CompleteTime:   completeTime,
+		SourceInfo:     p.sourceInfo(resp, duration),
 		Output: provider.JobOutput{
 			Destination: p.getOutputDestination(job),
 			Files:       p.getOutputFiles(resp),
@@ -146,6 +507,76 @@ func (p *elementalConductorProvider) JobStatus(job *db.Job) (*provider.JobStatus
 	}, nil
 }
 
+// TransientFailure returns whether the failed job may succeed when submitted
+// again, which is the case unless Elemental Conductor reported an error caused
+// by the job itself.
+func (p *elementalConductorProvider) TransientFailure(status *provider.JobStatus) bool {
+	jobErrors, _ := status.ProviderStatus["error_messages"].([]elementalconductor.JobError)
+	return p.jobErrorsCategory(jobErrors) != config.PermanentErrorCategory
+}
+
+func (p *elementalConductorProvider) sourceInfo(job *elementalconductor.Job, duration time.Duration) provider.SourceInfo {
+	sourceInfo := provider.SourceInfo{Duration: duration}
+	if len(job.Input) > 0 && job.Input[0].InputInfo != nil {
+		video := job.Input[0].InputInfo.Video
+		sourceInfo.VideoCodec = video.Format
+		sourceInfo.Height = video.GetHeight()
+		sourceInfo.Width = video.GetWidth()
+	}
+	return sourceInfo
+}
+
+// inputs returns the inputs of the job, either its source media or the
+// clips of each of its segments, which Elemental Conductor stitches in
+// order.
+func (p *elementalConductorProvider) inputs(job *db.Job) ([]elementalconductor.Input, error) {
+	if len(job.SourceSegments) == 0 {
+		location, err := p.inputLocation(job.SourceMedia)
+		if err != nil {
+			return nil, err
+		}
+		return []elementalconductor.Input{{FileInput: location}}, nil
+	}
+	inputs := make([]elementalconductor.Input, len(job.SourceSegments))
+	for i, segment := range job.SourceSegments {
+		location, err := p.inputLocation(segment.URI)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = elementalconductor.Input{
+			FileInput:      location,
+			TimecodeSource: elementalconductor.ZeroBasedTimecodeSource,
+		}
+		if segment.In != "" || segment.Out != "" {
+			inputs[i].InputClipping = &elementalconductor.InputClipping{
+				StartTimecode: segment.In,
+				EndTimecode:   segment.Out,
+			}
+		}
+	}
+	return inputs, nil
+}
+
+// audioSelectors converts the audio selectors of the job into the audio
+// selectors of each input.
+func audioSelectors(selectors []db.AudioSelector) []elementalconductor.AudioSelector {
+	if len(selectors) == 0 {
+		return nil
+	}
+	result := make([]elementalconductor.AudioSelector, len(selectors))
+	for i, selector := range selectors {
+		result[i] = elementalconductor.AudioSelector{Name: selector.Name, Order: i + 1}
+		if selector.Track > 0 {
+			result[i].SelectorType = elementalconductor.TrackAudioSelectorType
+			result[i].Track = strconv.FormatUint(uint64(selector.Track), 10)
+		} else {
+			result[i].SelectorType = elementalconductor.LanguageAudioSelectorType
+			result[i].LanguageCode = selector.Language
+		}
+	}
+	return result
+}
+
 func (p *elementalConductorProvider) getOutputDestination(job *db.Job) string {
 	return strings.TrimRight(p.config.Destination, "/") + "/" + job.ID
 }
@@ -159,20 +590,49 @@ func (p *elementalConductorProvider) getOutputFiles(job *elementalconductor.Job)
 				Path:      outputGroup.AppleLiveGroupSettings.Destination.URI + ".m3u8",
 				Container: "m3u8",
 			})
+		} else if outputGroup.Type == elementalconductor.MSSmoothOutputGroupType {
+			files = append(files, provider.OutputFile{
+				Path:      outputGroup.MSSmoothGroupSettings.Destination.URI + ".ism",
+				Container: "ism",
+			})
+		} else if outputGroup.Type == elementalconductor.CMAFOutputGroupType {
+			files = append(files, provider.OutputFile{
+				Path:      outputGroup.CMAFGroupSettings.Destination.URI + ".m3u8",
+				Container: "m3u8",
+			}, provider.OutputFile{
+				Path:      outputGroup.CMAFGroupSettings.Destination.URI + ".mpd",
+				Container: "mpd",
+			})
 		} else {
 			for _, output := range outputGroup.Output {
+				container := string(output.Container)
+				if output.Container == elementalconductor.RawContainer && output.Extension != "" {
+					container = output.Extension
+				}
+				path := output.FullURI
+				if path == "" && outputGroup.FileGroupSettings != nil {
+					// jobs that haven't run yet don't have the full
+					// URI, which is named after the destination.
+					extension := string(output.Container)
+					if output.Extension != "" {
+						extension = output.Extension
+					}
+					path = outputGroup.FileGroupSettings.Destination.URI + "." + extension
+				}
 				streamFiles[output.StreamAssemblyName] = provider.OutputFile{
-					Path:      output.FullURI,
-					Container: string(output.Container),
+					Path:      path,
+					Container: container,
 				}
 			}
 		}
 	}
 	for _, stream := range job.StreamAssembly {
 		if file, ok := streamFiles[stream.Name]; ok {
-			file.VideoCodec = stream.VideoDescription.Codec
-			file.Width = stream.VideoDescription.GetWidth()
-			file.Height = stream.VideoDescription.GetHeight()
+			if stream.VideoDescription != nil {
+				file.VideoCodec = stream.VideoDescription.Codec
+				file.Width = stream.VideoDescription.GetWidth()
+				file.Height = stream.VideoDescription.GetHeight()
+			}
 			files = append(files, file)
 		}
 	}
@@ -198,35 +658,94 @@ func (p *elementalConductorProvider) statusMap(elementalConductorStatus string)
 
 func (p *elementalConductorProvider) buildOutputGroupAndStreamAssemblies(outputLocation elementalconductor.Location, job db.Job) ([]elementalconductor.OutputGroup, []elementalconductor.StreamAssembly, error) {
 	var streamingOutputList []elementalconductor.Output
+	var streamingAudioOnly []bool
+	var streamingAudioDescriptions []bool
+	var streamingBandwidths []int64
+	var smoothOutputList []elementalconductor.Output
+	var cmafOutputList []elementalconductor.Output
 	var streamAssemblyList []elementalconductor.StreamAssembly
 	var outputGroupList []elementalconductor.OutputGroup
 	var outputGroupOrder int
 	var streamingGroupOrder int
+	var smoothGroupOrder int
+	var cmafGroupOrder int
+	var presets *presetIndex
+	if len(job.Outputs) == 0 {
+		return nil, nil, provider.ErrNoPresets
+	}
+	requestedGroupType, err := outputGroupType(job.ProviderOptions)
+	if err != nil {
+		return nil, nil, provider.InvalidJobError(err.Error())
+	}
 	for index, output := range job.Outputs {
 		indexString := strconv.Itoa(index)
 		streamAssemblyName := "stream_" + indexString
 		out := elementalconductor.Output{StreamAssemblyName: streamAssemblyName}
-		presetID, ok := output.Preset.ProviderMapping[Name]
+		presetMapping, ok := output.Preset.ProviderMapping[Name]
 		if !ok {
 			return outputGroupList, nil, provider.ErrPresetMapNotFound
 		}
-		presetOutput, err := p.GetPreset(presetID)
+		if presets == nil {
+			var err error
+			presets, err = p.loadPresetIndex()
+			if err != nil {
+				return outputGroupList, nil, err
+			}
+		}
+		presetStruct, streamAssembly, err := p.resolvePreset(presets, presetMapping)
 		if err != nil {
 			return outputGroupList, nil, err
 		}
-		presetStruct := presetOutput.(*elementalconductor.Preset)
-		if presetStruct.Container == string(elementalconductor.AppleHTTPLiveStreaming) {
+		if presetStruct.Container == string(elementalconductor.CMAF) && job.StreamingParams.Protocol != cmafProtocol {
+			return outputGroupList, nil, provider.InvalidJobError(fmt.Sprintf("preset %q uses the cmaf container, which is only supported in jobs with the cmaf streaming protocol", output.Preset.Name))
+		}
+		groupType := requestedGroupType
+		if groupType == "" {
+			switch presetStruct.Container {
+			case string(elementalconductor.AppleHTTPLiveStreaming):
+				groupType = elementalconductor.AppleLiveOutputGroupType
+			case string(elementalconductor.CMAF):
+				groupType = elementalconductor.CMAFOutputGroupType
+			default:
+				groupType = elementalconductor.FileOutputGroupType
+			}
+		} else if !supportsContainer(groupType, presetStruct.Container) {
+			return outputGroupList, nil, provider.InvalidJobError(fmt.Sprintf("preset %q uses the container %q, which is not supported by the %q output group type", output.Preset.Name, presetStruct.Container, job.ProviderOptions[outputGroupTypeKey]))
+		}
+		if output.Preset.OutputOpts.AudioDescription && (groupType != elementalconductor.AppleLiveOutputGroupType || job.StreamingParams.HLSLayout != db.HLSLayoutDemuxed) {
+			return outputGroupList, nil, provider.InvalidJobError(fmt.Sprintf("preset %q is an audio description, which is only supported in hls outputs with the demuxed layout", output.Preset.Name))
+		}
+		switch groupType {
+		case elementalconductor.AppleLiveOutputGroupType:
 			streamingGroupOrder++
 			out.NameModifier = fmt.Sprintf("_%010d", streamingGroupOrder)
 			out.Container = elementalconductor.AppleHTTPLiveStreaming
 			out.Order = streamingGroupOrder
 			streamingOutputList = append(streamingOutputList, out)
-		} else {
+			streamingAudioOnly = append(streamingAudioOnly, isAudioOnly(presetStruct))
+			streamingAudioDescriptions = append(streamingAudioDescriptions, output.Preset.OutputOpts.AudioDescription)
+			streamingBandwidths = append(streamingBandwidths, presetBandwidth(presetStruct))
+		case elementalconductor.MSSmoothOutputGroupType:
+			smoothGroupOrder++
+			out.NameModifier = fmt.Sprintf("_%010d", smoothGroupOrder)
+			out.Container = elementalconductor.MSSmooth
+			out.Order = smoothGroupOrder
+			smoothOutputList = append(smoothOutputList, out)
+		case elementalconductor.CMAFOutputGroupType:
+			cmafGroupOrder++
+			out.NameModifier = fmt.Sprintf("_%010d", cmafGroupOrder)
+			out.Container = elementalconductor.CMAF
+			out.Order = cmafGroupOrder
+			cmafOutputList = append(cmafOutputList, out)
+		default:
 			outputGroupOrder++
-			location := outputLocation
+			container := strings.TrimLeft(output.Preset.OutputOpts.OutputContainer(), ".")
+			location := p.withSubpath(outputLocation, container, job)
 			location.URI += "/" + output.FileName[:len(output.FileName)-len(filepath.Ext(output.FileName))]
-			ext := strings.TrimLeft(output.Preset.OutputOpts.Extension, ".")
-			out.Container = elementalconductor.Container(ext)
+			out.Container = outputContainer(container)
+			if ext := strings.TrimLeft(output.Preset.OutputOpts.Extension, "."); ext != container {
+				out.Extension = ext
+			}
 			out.Order = 1
 			outputGroupList = append(outputGroupList, elementalconductor.OutputGroup{
 				Order:  outputGroupOrder,
@@ -237,98 +756,613 @@ func (p *elementalConductorProvider) buildOutputGroupAndStreamAssemblies(outputL
 				},
 			})
 		}
-		streamAssembly := elementalconductor.StreamAssembly{
-			Name:   streamAssemblyName,
-			Preset: presetID,
+		streamAssembly.Name = streamAssemblyName
+		if output.AudioSelector != "" || output.AudioLanguage != "" {
+			streamAssembly.AudioDescription = &elementalconductor.StreamAudioDescription{
+				AudioSourceName: output.AudioSelector,
+				LanguageCode:    output.AudioLanguage,
+			}
 		}
 		streamAssemblyList = append(streamAssemblyList, streamAssembly)
 	}
+	for index, timecode := range job.FrameCaptures {
+		outputGroupOrder++
+		streamAssemblyName := "frame_capture_" + strconv.Itoa(index)
+		location := outputLocation
+		location.URI += "/frames/" + strings.Replace(timecode, ":", "-", -1)
+		outputGroupList = append(outputGroupList, elementalconductor.OutputGroup{
+			Order: outputGroupOrder,
+			Type:  elementalconductor.FileOutputGroupType,
+			Output: []elementalconductor.Output{{
+				StreamAssemblyName: streamAssemblyName,
+				Order:              1,
+				Extension:          "jpg",
+				Container:          elementalconductor.RawContainer,
+			}},
+			FileGroupSettings: &elementalconductor.FileGroupSettings{
+				Destination: &location,
+			},
+		})
+		streamAssemblyList = append(streamAssemblyList, elementalconductor.StreamAssembly{
+			Name: streamAssemblyName,
+			VideoDescription: &elementalconductor.StreamVideoDescription{
+				Codec: elementalconductor.FrameCaptureCodec,
+				FrameCaptureSettings: &elementalconductor.FrameCaptureSettings{
+					StartTimecode: timecode,
+					MaxCaptures:   1,
+					Quality:       frameCaptureQuality,
+				},
+			},
+		})
+	}
+	if len(streamingOutputList) > 0 && job.StreamingParams.HLSLayout == db.HLSLayoutDemuxed {
+		err = demuxHLSOutputs(streamingOutputList, streamingAudioOnly, streamingAudioDescriptions)
+		if err != nil {
+			return outputGroupList, nil, err
+		}
+	}
+	if modifier := segmentModifier(job.StreamingParams.SegmentNameTemplate); modifier != "" {
+		for i := range streamingOutputList {
+			if streamingOutputList[i].AppleLiveSettings == nil {
+				streamingOutputList[i].AppleLiveSettings = &elementalconductor.AppleLiveOutputSettings{}
+			}
+			streamingOutputList[i].AppleLiveSettings.SegmentModifier = modifier
+		}
+	}
+	if job.StreamingParams.VariantOrder != "" {
+		renditions := make([]bool, len(streamingOutputList))
+		if job.StreamingParams.HLSLayout == db.HLSLayoutDemuxed {
+			copy(renditions, streamingAudioOnly)
+		}
+		sortHLSVariants(streamingOutputList, streamingBandwidths, renditions, job.StreamingParams.VariantOrder)
+	}
 	if len(streamingOutputList) > 0 {
 		playlistFileName := job.StreamingParams.PlaylistFileName
-		location := outputLocation
+		location := p.withSubpath(outputLocation, hlsSubpathKey, job)
 		location.URI += "/" + strings.TrimRight(playlistFileName, filepath.Ext(playlistFileName))
 		outputGroupOrder++
+		var baseURL string
+		if job.StreamingParams.BaseURL != "" {
+			baseURL = strings.TrimRight(job.StreamingParams.BaseURL, "/") + "/"
+		}
 		streamingOutputGroup := elementalconductor.OutputGroup{
 			Order: outputGroupOrder,
 			AppleLiveGroupSettings: &elementalconductor.AppleLiveGroupSettings{
 				Destination:     &location,
 				SegmentDuration: job.StreamingParams.SegmentDuration,
 				EmitSingleFile:  true,
+				BaseURLManifest: baseURL,
+				BaseURLContent:  baseURL,
 			},
 			Type:   elementalconductor.AppleLiveOutputGroupType,
 			Output: streamingOutputList,
 		}
 		outputGroupList = append(outputGroupList, streamingOutputGroup)
 	}
+	if len(smoothOutputList) > 0 {
+		location := manifestLocation(p.withSubpath(outputLocation, smoothSubpathKey, job), job)
+		outputGroupOrder++
+		outputGroupList = append(outputGroupList, elementalconductor.OutputGroup{
+			Order: outputGroupOrder,
+			MSSmoothGroupSettings: &elementalconductor.MSSmoothGroupSettings{
+				Destination:    &location,
+				FragmentLength: job.StreamingParams.SegmentDuration,
+			},
+			Type:   elementalconductor.MSSmoothOutputGroupType,
+			Output: smoothOutputList,
+		})
+	}
+	if len(cmafOutputList) > 0 {
+		location := manifestLocation(p.withSubpath(outputLocation, cmafSubpathKey, job), job)
+		segmentControl := elementalconductor.SingleFileSegmentControl
+		if job.StreamingParams.FragmentType == db.FragmentTypeSegmented {
+			segmentControl = elementalconductor.SegmentedFilesSegmentControl
+		}
+		outputGroupOrder++
+		outputGroupList = append(outputGroupList, elementalconductor.OutputGroup{
+			Order: outputGroupOrder,
+			CMAFGroupSettings: &elementalconductor.CMAFGroupSettings{
+				Destination:       &location,
+				SegmentLength:     job.StreamingParams.SegmentDuration,
+				SegmentControl:    segmentControl,
+				WriteHLSManifest:  true,
+				WriteDASHManifest: true,
+			},
+			Type:   elementalconductor.CMAFOutputGroupType,
+			Output: cmafOutputList,
+		})
+	}
 	return outputGroupList, streamAssemblyList, nil
 }
 
+// presetBandwidth returns the bandwidth of the outputs of the given preset,
+// in bits per second, adding up the bitrates of its video and audio. Missing
+// bitrates count as zero.
+func presetBandwidth(preset *elementalconductor.Preset) int64 {
+	var bandwidth int64
+	if !preset.ExcludeVideo {
+		video, _ := strconv.ParseInt(preset.VideoBitrate, 10, 64)
+		bandwidth += video
+	}
+	if !preset.ExcludeAudio {
+		audio, _ := strconv.ParseInt(preset.AudioBitrate, 10, 64)
+		bandwidth += audio
+	}
+	return bandwidth
+}
+
+// sortHLSVariants sorts the variants of an HLS output group by their
+// bandwidth, in the given order, renumbering the outputs so the master
+// playlist lists them in that order. Renditions keep their positions, so the
+// default rendition doesn't change. Variants with the same bandwidth keep
+// the order of the job.
+func sortHLSVariants(outputs []elementalconductor.Output, bandwidths []int64, renditions []bool, order string) {
+	var positions []int
+	for i := range outputs {
+		if !renditions[i] {
+			positions = append(positions, i)
+		}
+	}
+	sorted := make([]int, len(positions))
+	copy(sorted, positions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if order == db.VariantOrderBandwidthDesc {
+			return bandwidths[sorted[i]] > bandwidths[sorted[j]]
+		}
+		return bandwidths[sorted[i]] < bandwidths[sorted[j]]
+	})
+	variants := make([]elementalconductor.Output, len(sorted))
+	for i, index := range sorted {
+		variants[i] = outputs[index]
+	}
+	for i, position := range positions {
+		outputOrder := outputs[position].Order
+		outputs[position] = variants[i]
+		outputs[position].Order = outputOrder
+	}
+}
+
+// hlsAudioGroupID is the id of the rendition group holding the audio of HLS
+// jobs with the demuxed layout.
+const hlsAudioGroupID = "audio"
+
+// isAudioOnly returns whether the given preset leaves video out of its
+// outputs. Presets returned by Elemental Conductor always name the codec of
+// their video.
+func isAudioOnly(preset *elementalconductor.Preset) bool {
+	return preset.ExcludeVideo || preset.VideoCodec == ""
+}
+
+// demuxHLSOutputs places the audio-only outputs of an HLS output group in a
+// rendition group, the first one being the default rendition, and makes the
+// other outputs reference it as their audio. Audio descriptions are flagged
+// with the accessibility characteristic and are never the default rendition.
+func demuxHLSOutputs(outputs []elementalconductor.Output, audioOnly, audioDescriptions []bool) error {
+	var renditions, variants int
+	var hasDefault bool
+	for i := range outputs {
+		if !audioOnly[i] {
+			if audioDescriptions[i] {
+				return provider.InvalidJobError("audio descriptions must be audio-only outputs")
+			}
+			variants++
+			outputs[i].AppleLiveSettings = &elementalconductor.AppleLiveOutputSettings{AudioRenditionSets: hlsAudioGroupID}
+			continue
+		}
+		renditions++
+		settings := elementalconductor.AppleLiveOutputSettings{
+			AudioGroupID:   hlsAudioGroupID,
+			AudioTrackType: elementalconductor.AlternateAudioAutoSelect,
+		}
+		if audioDescriptions[i] {
+			settings.Characteristics = elementalconductor.AudioDescriptionCharacteristic
+		} else if !hasDefault {
+			settings.AudioTrackType = elementalconductor.AlternateAudioAutoSelectDefault
+			hasDefault = true
+		}
+		outputs[i].AppleLiveSettings = &settings
+	}
+	if renditions == 0 || variants == 0 {
+		return provider.InvalidJobError("the demuxed hls layout requires both audio-only outputs and outputs with video")
+	}
+	return nil
+}
+
+// segmentModifier converts the segment name template of HLS jobs into the
+// segment modifier of the outputs. Elemental Conductor appends the number of
+// the segment to the modifier, and replaces the $rt$ identifier with the
+// bitrate of the output.
+func segmentModifier(template string) string {
+	modifier := strings.TrimSuffix(template, db.SegmentNameIndex)
+	return strings.Replace(modifier, db.SegmentNameBitrate, "$rt$", -1)
+}
+
+// cmafProtocol is the streaming protocol of jobs with CMAF outputs.
+const cmafProtocol = "cmaf"
+
+// manifestLocation returns the location of the manifests of adaptive
+// streaming output groups, named after the playlist of the job or, when it's
+// missing, after the first output.
+func manifestLocation(outputLocation elementalconductor.Location, job db.Job) elementalconductor.Location {
+	manifestFileName := job.StreamingParams.PlaylistFileName
+	if manifestFileName == "" {
+		manifestFileName = job.Outputs[0].FileName
+	}
+	location := outputLocation
+	location.URI += "/" + manifestFileName[:len(manifestFileName)-len(filepath.Ext(manifestFileName))]
+	return location
+}
+
 // newJob constructs a job spec from the given source and presets
 func (p *elementalConductorProvider) newJob(job *db.Job) (*elementalconductor.Job, error) {
-	inputLocation := elementalconductor.Location{
-		URI:      job.SourceMedia,
-		Username: p.config.AccessKeyID,
-		Password: p.config.SecretAccessKey,
+	inputs, err := p.inputs(job)
+	if err != nil {
+		return nil, err
+	}
+	selectors := audioSelectors(job.AudioSelectors)
+	for i := range inputs {
+		inputs[i].AudioSelector = selectors
 	}
 	baseLocation := strings.TrimRight(p.config.Destination, "/")
-	outputLocation := elementalconductor.Location{
-		URI:      baseLocation + "/" + job.ID,
-		Username: p.config.AccessKeyID,
-		Password: p.config.SecretAccessKey,
+	outputLocation, err := p.location(baseLocation + "/" + job.ID)
+	if err != nil {
+		return nil, err
 	}
+	outputLocation.CannedACL = job.OutputACL
 	outputGroup, streamAssemblyList, err := p.buildOutputGroupAndStreamAssemblies(outputLocation, *job)
 	if err != nil {
 		return nil, err
 	}
+	if len(job.NodeTags) > 0 {
+		err = p.validateNodeTags(job.NodeTags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tags, err := jobTags(job)
+	if err != nil {
+		return nil, err
+	}
+	err = validateJobName(job.Name)
+	if err != nil {
+		return nil, err
+	}
+	err = p.validateManifestDestination(job)
+	if err != nil {
+		return nil, err
+	}
+	for i := range inputs {
+		if bufferMsec := p.config.InputBufferMsec; bufferMsec != nil {
+			inputs[i].BufferMsec = strconv.FormatUint(uint64(*bufferMsec), 10)
+		}
+		inputs[i].ContainerOverride = job.InputFormat
+	}
 	newJob := elementalconductor.Job{
 		XMLName: xml.Name{
 			Local: "job",
 		},
-		Input: elementalconductor.Input{
-			FileInput: inputLocation,
-		},
+		Name:           job.Name,
+		Input:          inputs,
 		Priority:       defaultJobPriority,
+		NodeTags:       job.NodeTags,
+		WorkingDir:     p.config.WorkingDirectory,
+		Tags:           tags,
 		OutputGroup:    outputGroup,
 		StreamAssembly: streamAssemblyList,
 	}
+	err = applyJobOptions(&newJob, job.ProviderOptions)
+	if err != nil {
+		return nil, provider.InvalidJobError(err.Error())
+	}
 	return &newJob, nil
 }
 
-func (p *elementalConductorProvider) CancelJob(id string) error {
-	_, err := p.client.CancelJob(id)
-	return err
+// Constraints of the names and tags of jobs in Elemental Conductor.
+const (
+	maxJobNameLength     = 255
+	maxJobTagKeyLength   = 128
+	maxJobTagValueLength = 256
+)
+
+var jobTagRegexp = regexp.MustCompile(`^[\pL\pZ\pN_.:/=+\-@]*$`)
+
+// validateJobName ensures that the name of the job is accepted by Elemental
+// Conductor, which restricts names to the characters allowed in tags.
+func validateJobName(name string) error {
+	if utf8.RuneCountInString(name) > maxJobNameLength {
+		return provider.InvalidJobError(fmt.Sprintf("invalid job name %q: must have at most %d characters", name, maxJobNameLength))
+	}
+	if !jobTagRegexp.MatchString(name) {
+		return provider.InvalidJobError(fmt.Sprintf("invalid job name %q: may only contain letters, numbers, spaces and the characters _.:/=+-@", name))
+	}
+	return nil
+}
+
+// jobTags returns the tags of the job in Elemental Conductor, taken from the
+// metadata keys that should be propagated to the provider.
+func jobTags(job *db.Job) ([]elementalconductor.JobTag, error) {
+	var tags []elementalconductor.JobTag
+	for _, key := range job.ProviderTags {
+		value := job.Metadata[key]
+		if key == "" || utf8.RuneCountInString(key) > maxJobTagKeyLength {
+			return nil, provider.InvalidJobError(fmt.Sprintf("invalid tag %q: keys must have between 1 and %d characters", key, maxJobTagKeyLength))
+		}
+		if utf8.RuneCountInString(value) > maxJobTagValueLength {
+			return nil, provider.InvalidJobError(fmt.Sprintf("invalid tag %q: values must have at most %d characters", key, maxJobTagValueLength))
+		}
+		if !jobTagRegexp.MatchString(key) || !jobTagRegexp.MatchString(value) {
+			return nil, provider.InvalidJobError(fmt.Sprintf("invalid tag %q: keys and values may only contain letters, numbers, spaces and the characters _.:/=+-@", key))
+		}
+		tags = append(tags, elementalconductor.JobTag{Key: key, Value: value})
+	}
+	return tags, nil
 }
 
-func (p *elementalConductorProvider) Healthcheck() error {
+// validateNodeTags ensures that all the given tags are assigned to at least
+// one node in the Elemental Conductor cluster.
+func (p *elementalConductorProvider) validateNodeTags(tags []string) error {
 	nodes, err := p.client.GetNodes()
 	if err != nil {
 		return err
 	}
+	knownTags := make(map[string]bool)
+	for _, node := range nodes {
+		for _, tag := range node.Tags {
+			knownTags[tag] = true
+		}
+	}
+	var unknownTags []string
+	for _, tag := range tags {
+		if !knownTags[tag] {
+			unknownTags = append(unknownTags, tag)
+		}
+	}
+	if len(unknownTags) > 0 {
+		return provider.InvalidJobError(fmt.Sprintf("unknown node tags: %s", strings.Join(unknownTags, ", ")))
+	}
+	return nil
+}
+
+func (p *elementalConductorProvider) CancelJob(ctx context.Context, id string) error {
+	p = p.withContext(ctx)
+	_, err := p.client.CancelJob(id)
+	return checkJobNotFound(id, err)
+}
+
+// UpdateJobPriority changes the priority of the given job in Elemental
+// Conductor, moving it ahead of lower priority jobs in the queue.
+func (p *elementalConductorProvider) UpdateJobPriority(ctx context.Context, id string, priority int) error {
+	p = p.withContext(ctx)
+	_, err := p.client.UpdateJobPriority(id, priority)
+	return checkJobNotFound(id, err)
+}
+
+// GetJobLogs returns the log of the given job, built from the times of its
+// state changes and the errors reported by Elemental Conductor, as its API
+// doesn't expose the full log of the nodes.
+func (p *elementalConductorProvider) GetJobLogs(ctx context.Context, id string) ([]byte, error) {
+	p = p.withContext(ctx)
+	job, err := p.client.GetJob(id)
+	if err != nil {
+		return nil, checkJobNotFound(id, err)
+	}
+	var logs bytes.Buffer
+	logEntry := func(t time.Time, message string) {
+		if !t.IsZero() {
+			fmt.Fprintf(&logs, "%s %s\n", t.UTC().Format(time.RFC3339), message)
+		}
+	}
+	logEntry(job.Submitted.Time, "job submitted")
+	logEntry(job.StartTime.Time, "job started")
+	for _, jobError := range job.ErrorMessages {
+		logEntry(jobError.CreatedAt.Time, fmt.Sprintf("error %d: %s", jobError.Code, jobError.Message))
+	}
+	logEntry(job.ErroredTime.Time, "job failed")
+	logEntry(job.CompleteTime.Time, "job "+strings.ToLower(job.Status))
+	return logs.Bytes(), nil
+}
+
+// jobSpec returns the XML spec of the given job, as submitted to Elemental
+// Conductor, with the credentials used for reading the input and writing the
+// outputs redacted, along with the query strings of its URIs, which may
+// carry signatures. The given job is left untouched.
+func jobSpec(job elementalconductor.Job) (*provider.JobSpec, error) {
+	job.Input = append([]elementalconductor.Input(nil), job.Input...)
+	for i := range job.Input {
+		job.Input[i].FileInput = *redactLocation(&job.Input[i].FileInput)
+	}
+	job.OutputGroup = append([]elementalconductor.OutputGroup(nil), job.OutputGroup...)
+	for i := range job.OutputGroup {
+		group := &job.OutputGroup[i]
+		if group.FileGroupSettings != nil {
+			settings := *group.FileGroupSettings
+			settings.Destination = redactLocation(settings.Destination)
+			group.FileGroupSettings = &settings
+		}
+		if group.AppleLiveGroupSettings != nil {
+			settings := *group.AppleLiveGroupSettings
+			settings.Destination = redactLocation(settings.Destination)
+			settings.BaseURLManifest = redactURI(settings.BaseURLManifest)
+			settings.BaseURLContent = redactURI(settings.BaseURLContent)
+			group.AppleLiveGroupSettings = &settings
+		}
+		if group.MSSmoothGroupSettings != nil {
+			settings := *group.MSSmoothGroupSettings
+			settings.Destination = redactLocation(settings.Destination)
+			group.MSSmoothGroupSettings = &settings
+		}
+		if group.CMAFGroupSettings != nil {
+			settings := *group.CMAFGroupSettings
+			settings.Destination = redactLocation(settings.Destination)
+			group.CMAFGroupSettings = &settings
+		}
+		group.Output = append([]elementalconductor.Output(nil), group.Output...)
+		for j := range group.Output {
+			group.Output[j].FullURI = redactURI(group.Output[j].FullURI)
+		}
+	}
+	spec, err := xml.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &provider.JobSpec{Data: append([]byte(xml.Header), spec...), ContentType: "application/xml"}, nil
+}
+
+// PreviewOutput returns the destination and the output files of the given
+// job, named as in the job spec, without submitting it.
+func (p *elementalConductorProvider) PreviewOutput(job *db.Job) (*provider.JobOutput, error) {
+	newJob, err := p.newJob(job)
+	if err != nil {
+		return nil, err
+	}
+	return &provider.JobOutput{
+		Destination: p.getOutputDestination(job),
+		Files:       p.getOutputFiles(newJob),
+	}, nil
+}
+
+// redactLocation returns a copy of the given location with its credentials
+// and the query string of its URI redacted.
+func redactLocation(location *elementalconductor.Location) *elementalconductor.Location {
+	if location == nil {
+		return nil
+	}
+	redacted := *location
+	redacted.URI = redactURI(redacted.URI)
+	if redacted.Username != "" {
+		redacted.Username = redactedCredential
+	}
+	if redacted.Password != "" {
+		redacted.Password = redactedCredential
+	}
+	return &redacted
+}
+
+// redactURI strips the query string of the given URI, if it has one.
+func redactURI(uri string) string {
+	if !strings.Contains(uri, "?") {
+		return uri
+	}
+	return redactQuery(uri)
+}
+
+// checkJobNotFound converts 404 errors returned by the Elemental Conductor API
+// for the given job into provider.JobNotFoundError.
+func checkJobNotFound(id string, err error) error {
+	if apiErr, ok := err.(*elementalconductor.APIError); ok && apiErr.Status == http.StatusNotFound {
+		return provider.JobNotFoundError{ID: id}
+	}
+	return err
+}
+
+func (p *elementalConductorProvider) Healthcheck(ctx context.Context) error {
+	p = p.withContext(ctx)
+	// the cloud config requires valid credentials, so it's retrieved
+	// first, detecting authentication failures before the node count.
 	cloudConfig, err := p.client.GetCloudConfig()
 	if err != nil {
-		return err
+		return checkAuth(err)
+	}
+	nodes, err := p.client.GetNodes()
+	if err != nil {
+		return checkAuth(err)
+	}
+	serverCount := activeServerCount(nodes)
+	if serverCount < cloudConfig.MinNodes {
+		return fmt.Errorf("there are not enough active nodes. %d nodes required to be active, but found only %d", cloudConfig.MinNodes, serverCount)
+	}
+	return nil
+}
+
+// ActiveNodes returns the number of active server nodes of the Elemental
+// Conductor cluster.
+func (p *elementalConductorProvider) ActiveNodes(ctx context.Context) (int, error) {
+	nodes, err := p.withContext(ctx).client.GetNodes()
+	if err != nil {
+		return 0, checkAuth(err)
 	}
+	return activeServerCount(nodes), nil
+}
+
+func activeServerCount(nodes []elementalconductor.Node) int {
 	var serverCount int
 	for _, node := range nodes {
 		if node.Product == elementalconductor.ProductServer && node.Status == "active" {
 			serverCount++
 		}
 	}
-	if serverCount < cloudConfig.MinNodes {
-		return fmt.Errorf("there are not enough active nodes. %d nodes required to be active, but found only %d", cloudConfig.MinNodes, serverCount)
+	return serverCount
+}
+
+// checkAuth converts authentication errors returned by the Elemental
+// Conductor API into provider.ErrAuthFailed.
+func checkAuth(err error) error {
+	if apiErr, ok := err.(*elementalconductor.APIError); ok {
+		if apiErr.Status == http.StatusUnauthorized || apiErr.Status == http.StatusForbidden {
+			return provider.ErrAuthFailed
+		}
+	}
+	return err
+}
+
+// Load returns the average number of jobs running in the active server nodes
+// of the Elemental Conductor cluster.
+func (p *elementalConductorProvider) Load() (float64, error) {
+	nodes, err := p.client.GetNodes()
+	if err != nil {
+		return 0, err
+	}
+	var serverCount, runningCount int
+	for _, node := range nodes {
+		if node.Product == elementalconductor.ProductServer && node.Status == "active" {
+			serverCount++
+			runningCount += node.RunningCount
+		}
+	}
+	if serverCount == 0 {
+		return 0, errors.New("there are no active nodes")
+	}
+	return float64(runningCount) / float64(serverCount), nil
+}
+
+// outputContainer returns the Elemental Conductor container used for outputs
+// in the given container.
+func outputContainer(container string) elementalconductor.Container {
+	switch container {
+	case "mp4":
+		return elementalconductor.MPEG4
+	case "webm":
+		return elementalconductor.WebM
+	case "mxf":
+		return elementalconductor.MXF
+	default:
+		return elementalconductor.Container(container)
 	}
-	return nil
 }
 
 func (p *elementalConductorProvider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		InputFormats:  []string{"prores", "h264"},
-		OutputFormats: []string{"mp4", "hls"},
-		Destinations:  []string{"akamai", "s3"},
+		InputFormats:    []string{"prores", "h264"},
+		OutputFormats:   []string{"mp4", "webm", "mxf", "hls", "cmaf", "jpg"},
+		Destinations:    []string{"akamai", "s3", "gcs"},
+		OutputACLs:      []string{db.OutputACLPrivate, db.OutputACLPublicRead},
+		InputStitching:  true,
+		AudioSelection:  true,
+		JobTags:         true,
+		JobSpecs:        true,
+		JobNames:        true,
+		DemuxedHLS:      true,
+		AbsoluteHLSURLs: true,
+		HLSSegmentNames: true,
+		HLSVariantOrder: true,
+		InputContainers: inputContainers,
 	}
 }
 
 func elementalConductorFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	if cfg.ElementalConductor.Disabled {
+		return nil, provider.ErrProviderDisabled
+	}
 	if cfg.ElementalConductor.Host == "" || cfg.ElementalConductor.UserLogin == "" ||
 		cfg.ElementalConductor.APIKey == "" || cfg.ElementalConductor.AuthExpires == 0 {
 		return nil, errElementalConductorInvalidConfig
@@ -342,5 +1376,57 @@ func elementalConductorFactory(cfg *config.Config) (provider.TranscodingProvider
 		cfg.ElementalConductor.SecretAccessKey,
 		cfg.ElementalConductor.Destination,
 	)
-	return &elementalConductorProvider{client: client, config: cfg.ElementalConductor}, nil
+	client.HTTPClient = provider.HTTPClient(cfg, 0)
+	if dir := cfg.ElementalConductor.WorkingDirectory; dir != "" {
+		if err := validateWorkingDirectory(dir); err != nil {
+			return nil, provider.InvalidConfigError(fmt.Sprintf("invalid ELEMENTALCONDUCTOR_WORKING_DIRECTORY: %s", err))
+		}
+	}
+	if bufferMsec := cfg.ElementalConductor.InputBufferMsec; bufferMsec != nil && *bufferMsec > maxInputBufferMsec {
+		return nil, provider.InvalidConfigError(fmt.Sprintf("invalid ELEMENTALCONDUCTOR_INPUT_BUFFER_MSEC: must be an integer between 0 and %d", maxInputBufferMsec))
+	}
+	if err := validateOutputSubpaths(cfg.ElementalConductor.OutputSubpaths); err != nil {
+		return nil, provider.InvalidConfigError(fmt.Sprintf("invalid ELEMENTALCONDUCTOR_OUTPUT_SUBPATHS: %s", err))
+	}
+	if arn := cfg.ElementalConductor.SourceRoleARN; arn != "" {
+		if err := validateRoleARN(arn); err != nil {
+			return nil, provider.InvalidConfigError(fmt.Sprintf("invalid ELEMENTALCONDUCTOR_SOURCE_ROLE_ARN: %s", err))
+		}
+	}
+	gcs, err := loadGCSCredentials(cfg.ElementalConductor)
+	if err != nil {
+		return nil, err
+	}
+	return &elementalConductorProvider{
+		client:  client,
+		config:  cfg.ElementalConductor,
+		gcs:     gcs,
+		objects: newHTTPObjectStore(client.HTTPClient, cfg.ElementalConductor),
+	}, nil
+}
+
+// RegisterClusters registers one provider for each of the Elemental Conductor
+// clusters defined in the configuration, named elementalconductor-<cluster>.
+// Jobs sent to the elementalconductor provider are then balanced among the
+// clusters.
+func RegisterClusters(cfg *config.Config) error {
+	if len(cfg.ElementalConductorClusters) == 0 {
+		return nil
+	}
+	factories := make(map[string]provider.Factory, len(cfg.ElementalConductorClusters))
+	for name, clusterCfg := range cfg.ElementalConductorClusters {
+		factories[name] = clusterFactory(clusterCfg)
+	}
+	return provider.RegisterClusters(Name, factories, cfg.ElementalConductor.ClusterStrategy)
+}
+
+func clusterFactory(clusterCfg *config.ElementalConductor) provider.Factory {
+	return func(cfg *config.Config) (provider.TranscodingProvider, error) {
+		if cfg.ElementalConductor != nil && cfg.ElementalConductor.Disabled {
+			return nil, provider.ErrProviderDisabled
+		}
+		c := *cfg
+		c.ElementalConductor = clusterCfg
+		return elementalConductorFactory(&c)
+	}
 }
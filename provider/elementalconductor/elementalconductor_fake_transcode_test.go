@@ -1,22 +1,27 @@
 package elementalconductor
 
 import (
+	"strconv"
 	"strings"
 
-	"github.com/NYTimes/encoding-wrapper/elementalconductor"
 	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
 )
 
 type fakeElementalConductorClient struct {
 	*elementalconductor.Client
 	jobs         map[string]elementalconductor.Job
 	canceledJobs []string
+	priorities   map[string]int
+	nodes        []elementalconductor.Node
+	presets      []elementalconductor.Preset
 }
 
 func newFakeElementalConductorClient(cfg *config.ElementalConductor) *fakeElementalConductorClient {
 	return &fakeElementalConductorClient{
-		jobs: make(map[string]elementalconductor.Job),
+		jobs:       make(map[string]elementalconductor.Job),
+		priorities: make(map[string]int),
 		Client: &elementalconductor.Client{
 			Host:            cfg.Host,
 			UserLogin:       cfg.UserLogin,
@@ -34,33 +39,62 @@ func (c *fakeElementalConductorClient) GetPreset(presetID string) (*elementalcon
 	if strings.Contains(presetID, "hls") {
 		container = elementalconductor.AppleHTTPLiveStreaming
 	}
-	return &elementalconductor.Preset{
+	preset := elementalconductor.Preset{
 		Name:      presetID,
 		Container: string(container),
-	}, nil
+	}
+	if !strings.Contains(presetID, "audio") {
+		preset.VideoCodec = "h.264"
+	}
+	return &preset, nil
+}
+
+func (c *fakeElementalConductorClient) GetPresets() (*elementalconductor.PresetList, error) {
+	return &elementalconductor.PresetList{Presets: c.presets}, nil
 }
 
 func (c *fakeElementalConductorClient) CreatePreset(preset *elementalconductor.Preset) (*elementalconductor.Preset, error) {
+	c.presets = append(c.presets, *preset)
 	return &elementalconductor.Preset{
 		Name: preset.Name,
 	}, nil
 }
 
+func (c *fakeElementalConductorClient) CreateJob(job *elementalconductor.Job) (*elementalconductor.Job, error) {
+	id := strconv.Itoa(len(c.jobs) + 1)
+	job.Href = "/jobs/" + id
+	c.jobs[id] = *job
+	return job, nil
+}
+
 func (c *fakeElementalConductorClient) GetJob(jobID string) (*elementalconductor.Job, error) {
 	job := c.jobs[jobID]
 	return &job, nil
 }
 
+func (c *fakeElementalConductorClient) GetNodes() ([]elementalconductor.Node, error) {
+	return c.nodes, nil
+}
+
 func (c *fakeElementalConductorClient) CancelJob(jobID string) (*elementalconductor.Job, error) {
 	c.canceledJobs = append(c.canceledJobs, jobID)
 	return &elementalconductor.Job{}, nil
 }
 
+func (c *fakeElementalConductorClient) UpdateJobPriority(jobID string, priority int) (*elementalconductor.Job, error) {
+	c.priorities[jobID] = priority
+	return &elementalconductor.Job{}, nil
+}
+
 func fakeElementalConductorFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
 	if cfg.ElementalConductor.Host == "" || cfg.ElementalConductor.UserLogin == "" ||
 		cfg.ElementalConductor.APIKey == "" || cfg.ElementalConductor.AuthExpires == 0 {
 		return nil, errElementalConductorInvalidConfig
 	}
 	client := newFakeElementalConductorClient(cfg.ElementalConductor)
-	return &elementalConductorProvider{client: client, config: cfg.ElementalConductor}, nil
+	gcs, err := loadGCSCredentials(cfg.ElementalConductor)
+	if err != nil {
+		return nil, err
+	}
+	return &elementalConductorProvider{client: client, config: cfg.ElementalConductor, gcs: gcs}, nil
 }
@@ -1,15 +1,22 @@
 package elementalconductor
 
 import (
+	"context"
 	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/NYTimes/encoding-wrapper/elementalconductor"
 	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/db"
 	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
 )
 
 func TestFactoryIsRegistered(t *testing.T) {
@@ -42,6 +49,12 @@ func TestElementalConductorFactory(t *testing.T) {
 		APIKey:      "secret-key",
 		AuthExpires: 30,
 	}
+	if client, ok := econductorProvider.client.(*elementalconductor.Client); ok {
+		if client.HTTPClient == nil {
+			t.Error("Factory: unexpected <nil> HTTP client")
+		}
+		expected.HTTPClient = client.HTTPClient
+	}
 	if !reflect.DeepEqual(econductorProvider.client, expected) {
 		t.Errorf("Factory: wrong client returned. Want %#v. Got %#v.", expected, econductorProvider.client)
 	}
@@ -143,13 +156,13 @@ func TestElementalNewJob(t *testing.T) {
 		XMLName: xml.Name{
 			Local: "job",
 		},
-		Input: elementalconductor.Input{
+		Input: []elementalconductor.Input{{
 			FileInput: elementalconductor.Location{
 				URI:      "http://some.nice/video.mov",
 				Username: "aws-access-key",
 				Password: "aws-secret-key",
 			},
-		},
+		}},
 		Priority: 50,
 		OutputGroup: []elementalconductor.OutputGroup{
 			{
@@ -299,13 +312,13 @@ func TestElementalNewJobAdaptiveStreaming(t *testing.T) {
 		XMLName: xml.Name{
 			Local: "job",
 		},
-		Input: elementalconductor.Input{
+		Input: []elementalconductor.Input{{
 			FileInput: elementalconductor.Location{
 				URI:      "http://some.nice/video.mov",
 				Username: "aws-access-key",
 				Password: "aws-secret-key",
 			},
-		},
+		}},
 		Priority: 50,
 		OutputGroup: []elementalconductor.OutputGroup{
 			{
@@ -468,13 +481,13 @@ func TestElementalNewJobAdaptiveAndNonAdaptiveStreaming(t *testing.T) {
 		XMLName: xml.Name{
 			Local: "job",
 		},
-		Input: elementalconductor.Input{
+		Input: []elementalconductor.Input{{
 			FileInput: elementalconductor.Location{
 				URI:      "http://some.nice/video.mov",
 				Username: "aws-access-key",
 				Password: "aws-secret-key",
 			},
-		},
+		}},
 		Priority: 50,
 		OutputGroup: []elementalconductor.OutputGroup{
 			{
@@ -646,27 +659,7 @@ func TestElementalNewJobPresetNotFound(t *testing.T) {
 	}
 }
 
-func TestJobStatusOutputDestination(t *testing.T) {
-	var tests = []struct {
-		job            db.Job
-		destinationCfg string
-		expected       string
-	}{
-		{
-			db.Job{
-				ID: "job-123",
-			},
-			"s3://destination///",
-			"s3://destination/job-123",
-		},
-		{
-			db.Job{
-				ID: "job-123",
-			},
-			"s3://destination",
-			"s3://destination/job-123",
-		},
-	}
+func TestElementalNewJobNoPresets(t *testing.T) {
 	elementalConductorConfig := config.Config{
 		ElementalConductor: &config.ElementalConductor{
 			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
@@ -675,402 +668,2509 @@ func TestJobStatusOutputDestination(t *testing.T) {
 			AuthExpires:     30,
 			AccessKeyID:     "aws-access-key",
 			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
 		},
 	}
 	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
 	if err != nil {
 		t.Fatal(err)
 	}
-	provider, ok := prov.(*elementalConductorProvider)
-	if !ok {
-		t.Fatal("Could not type assert test provider to elementalConductorProvider")
-	}
-	for _, test := range tests {
-		provider.config.Destination = test.destinationCfg
-		got := provider.getOutputDestination(&test.job)
-		if got != test.expected {
-			t.Errorf("Wrong output destination. Want %q. Got %q", test.expected, got)
-		}
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{ID: "job-3", SourceMedia: "http://some.nice/video.mov"})
+	if err != provider.ErrNoPresets {
+		t.Errorf("Wrong error returned. Want %#v. Got %#v", provider.ErrNoPresets, err)
 	}
-}
-
-func TestJobStatusMap(t *testing.T) {
-	var tests = []struct {
-		elementalConductorStatus string
-		expected                 provider.Status
-	}{
-		{"pending", provider.StatusQueued},
-		{"preprocessing", provider.StatusStarted},
-		{"running", provider.StatusStarted},
-		{"postprocessing", provider.StatusStarted},
-		{"complete", provider.StatusFinished},
-		{"cancelled", provider.StatusCanceled},
-		{"error", provider.StatusFailed},
-		{"unknown", provider.StatusUnknown},
-		{"someotherstatus", provider.StatusUnknown},
+	if jobStatus != nil {
+		t.Errorf("Got unexpected non-nil job status: %#v.", jobStatus)
 	}
-	var p elementalConductorProvider
-	for _, test := range tests {
-		got := p.statusMap(test.elementalConductorStatus)
-		if got != test.expected {
-			t.Errorf("statusMap(%q): wrong value. Want %q. Got %q", test.elementalConductorStatus, test.expected, got)
-		}
+	if client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient); len(client.jobs) > 0 {
+		t.Errorf("Unexpected job sent to Elemental Conductor: %#v", client.jobs)
 	}
 }
 
-func TestJobStatus(t *testing.T) {
-	elementalConductorConfig := config.ElementalConductor{
-		Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
-		UserLogin:       "myuser",
-		APIKey:          "elemental-api-key",
-		AuthExpires:     30,
-		AccessKeyID:     "aws-access-key",
-		SecretAccessKey: "aws-secret-key",
-		Destination:     "s3://destination",
+func TestElementalNewJobAudioSelectors(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
 	}
-	submitted := elementalconductor.DateTime{Time: time.Now().UTC()}
-	client := newFakeElementalConductorClient(&elementalConductorConfig)
-	client.jobs["job-1"] = elementalconductor.Job{
-		Href: "whatever",
-		Input: elementalconductor.Input{
-			InputInfo: &elementalconductor.InputInfo{
-				Video: elementalconductor.VideoInputInfo{
-					Format: "AVC",
-					Width:  "1 920 pixels",
-					Height: "1 080 pixels",
-				},
-			},
+	newJob, err := prov.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		AudioSelectors: []db.AudioSelector{
+			{Name: "commentary", Track: 2},
+			{Name: "spanish", Language: "spa"},
 		},
-		ContentDuration: &elementalconductor.ContentDuration{
-			InputDuration: 123,
-		},
-		OutputGroup: []elementalconductor.OutputGroup{
-			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1.mp4",
-						Extension:          "mp4",
-						StreamAssemblyName: "stream_0",
-						Container:          "mp4",
-					},
-				},
-			},
+		Outputs: []db.TranscodeOutput{
 			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1.webm",
-						Extension:          "webm",
-						StreamAssemblyName: "stream_1",
-						Container:          "webm",
-					},
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
 				},
+				AudioSelector: "commentary",
 			},
 			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1.mov",
-						Extension:          "mov",
-						StreamAssemblyName: "unknown",
-						Container:          "mov",
-					},
+				FileName: "output_720p_es.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
 				},
+				AudioSelector: "spanish",
 			},
 			{
-				Type: elementalconductor.AppleLiveOutputGroupType,
-				AppleLiveGroupSettings: &elementalconductor.AppleLiveGroupSettings{
-					Destination: &elementalconductor.Location{
-						URI: "s3://somebucket/dir/video1",
-					},
-					SegmentDuration: 3,
-					EmitSingleFile:  true,
-				},
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1_720p.m3u8",
-						Extension:          "m3u8",
-						StreamAssemblyName: "stream_2",
-						Container:          "m3u8",
-					},
-					{
-						FullURI:            "s3://somebucket/dir/video1_1080p.m3u8",
-						Extension:          "m3u8",
-						StreamAssemblyName: "stream_3",
-						Container:          "m3u8",
-					},
+				FileName: "output_360p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_360p",
+					ProviderMapping: map[string]string{Name: "mp4_360p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
 				},
 			},
 		},
-		StreamAssembly: []elementalconductor.StreamAssembly{
-			{
-				ID:   "2323",
-				Name: "stream_0",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "h.264",
-					EncoderType: "gpu",
-					Height:      "1080",
-					Width:       "1920",
-				},
-			},
-			{
-				ID:   "2324",
-				Name: "stream_1",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "vp8",
-					EncoderType: "gpu",
-					Height:      "1080",
-					Width:       "1920",
-				},
-			},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSelectors := []elementalconductor.AudioSelector{
+		{Name: "commentary", Order: 1, SelectorType: "track", Track: "2"},
+		{Name: "spanish", Order: 2, SelectorType: "language_code", LanguageCode: "spa"},
+	}
+	if !reflect.DeepEqual(newJob.Input[0].AudioSelector, expectedSelectors) {
+		t.Errorf("wrong audio selectors\nwant %#v\ngot  %#v", expectedSelectors, newJob.Input[0].AudioSelector)
+	}
+	expectedSources := []string{"commentary", "spanish", ""}
+	for i, streamAssembly := range newJob.StreamAssembly {
+		var source string
+		if streamAssembly.AudioDescription != nil {
+			source = streamAssembly.AudioDescription.AudioSourceName
+		}
+		if source != expectedSources[i] {
+			t.Errorf("wrong audio source in stream assembly %d. Want %q. Got %q", i, expectedSources[i], source)
+		}
+	}
+}
+
+func TestElementalNewJobAudioLanguages(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	newJob, err := prov.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		AudioSelectors: []db.AudioSelector{
+			{Name: "english", Language: "eng"},
+			{Name: "portuguese", Language: "por"},
+		},
+		StreamingParams: db.StreamingParams{
+			Protocol:         "hls",
+			PlaylistFileName: "hls/index.m3u8",
+			SegmentDuration:  3,
+		},
+		Outputs: []db.TranscodeOutput{
 			{
-				ID:   "2325",
-				Name: "stream_2",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "h.264",
-					EncoderType: "gpu",
-					Height:      "720",
-					Width:       "1280",
+				FileName: "hls/audio_en.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_audio",
+					ProviderMapping: map[string]string{Name: "hls_audio"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
 				},
+				AudioSelector: "english",
+				AudioLanguage: "en",
 			},
 			{
-				ID:   "2326",
-				Name: "stream_3",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "h.264",
-					EncoderType: "gpu",
-					Height:      "1080",
-					Width:       "1920",
+				FileName: "hls/audio_pt.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_audio",
+					ProviderMapping: map[string]string{Name: "hls_audio"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
 				},
+				AudioSelector: "portuguese",
+				AudioLanguage: "pt-BR",
 			},
 		},
-		PercentComplete: 89,
-		Status:          "running",
-		Submitted:       submitted,
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	prov := elementalConductorProvider{client: client, config: &elementalConductorConfig}
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "super-job-1", ProviderJobID: "job-1"})
+	expectedDescriptions := []elementalconductor.StreamAudioDescription{
+		{AudioSourceName: "english", LanguageCode: "en"},
+		{AudioSourceName: "portuguese", LanguageCode: "pt-BR"},
+	}
+	if len(newJob.StreamAssembly) != len(expectedDescriptions) {
+		t.Fatalf("wrong number of stream assemblies. Want %d. Got %d", len(expectedDescriptions), len(newJob.StreamAssembly))
+	}
+	for i, streamAssembly := range newJob.StreamAssembly {
+		if streamAssembly.AudioDescription == nil {
+			t.Errorf("missing audio description in stream assembly %d", i)
+			continue
+		}
+		if *streamAssembly.AudioDescription != expectedDescriptions[i] {
+			t.Errorf("wrong audio description in stream assembly %d\nwant %#v\ngot  %#v", i, expectedDescriptions[i], *streamAssembly.AudioDescription)
+		}
+	}
+	data, err := xml.Marshal(newJob)
 	if err != nil {
 		t.Fatal(err)
 	}
-	expectedJobStatus := provider.JobStatus{
-		ProviderName:  Name,
-		ProviderJobID: "job-1",
-		Progress:      89.,
-		Status:        provider.StatusStarted,
-		Output: provider.JobOutput{
-			Destination: "s3://destination/super-job-1",
-			Files: []provider.OutputFile{
-				{
-					Path:      "s3://somebucket/dir/video1.m3u8",
-					Container: "m3u8",
-				},
-				{
-					Path:       "s3://somebucket/dir/video1.mp4",
-					Container:  "mp4",
-					VideoCodec: "h.264",
-					Width:      1920,
-					Height:     1080,
-				},
-				{
-					Path:       "s3://somebucket/dir/video1.webm",
-					Container:  "webm",
-					VideoCodec: "vp8",
-					Width:      1920,
-					Height:     1080,
-				},
-			},
+	if !strings.Contains(string(data), "<language_code>pt-BR</language_code>") {
+		t.Errorf("language label not found in the job XML: %s", data)
+	}
+}
+
+func TestElementalNewJobHLSLayout(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenLayout   string
+		wantSettings  []*elementalconductor.AppleLiveOutputSettings
+	}{
+		{
+			"default layout",
+			"",
+			[]*elementalconductor.AppleLiveOutputSettings{nil, nil, nil, nil},
 		},
-		SourceInfo: provider.SourceInfo{
-			Duration:   123 * time.Second,
-			Width:      1920,
-			Height:     1080,
-			VideoCodec: "AVC",
+		{
+			"muxed layout",
+			"muxed",
+			[]*elementalconductor.AppleLiveOutputSettings{nil, nil, nil, nil},
 		},
-		ProviderStatus: map[string]interface{}{
-			"status":    "running",
-			"submitted": submitted,
+		{
+			"demuxed layout",
+			"demuxed",
+			[]*elementalconductor.AppleLiveOutputSettings{
+				{AudioRenditionSets: "audio"},
+				{AudioGroupID: "audio", AudioTrackType: "alternate_audio_auto_select_default"},
+				{AudioRenditionSets: "audio"},
+				{AudioGroupID: "audio", AudioTrackType: "alternate_audio_auto_select"},
+			},
 		},
 	}
-	if !reflect.DeepEqual(*jobStatus, expectedJobStatus) {
-		t.Errorf("wrong job stats\nwant %#v\ngot  %#v", expectedJobStatus, *jobStatus)
+	presets := []string{"hls_1080p", "hls_audio_en", "hls_720p", "hls_audio_pt"}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{Destination: "s3://destination"},
+		}
+		job := db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			StreamingParams: db.StreamingParams{
+				Protocol:         "hls",
+				PlaylistFileName: "hls/index.m3u8",
+				SegmentDuration:  3,
+				HLSLayout:        test.givenLayout,
+			},
+		}
+		for _, preset := range presets {
+			job.Outputs = append(job.Outputs, db.TranscodeOutput{
+				FileName: "hls/" + preset + ".m3u8",
+				Preset: db.PresetMap{
+					Name:            preset,
+					ProviderMapping: map[string]string{Name: preset},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			})
+		}
+		newJob, err := prov.newJob(&job)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if len(newJob.OutputGroup) != 1 {
+			t.Fatalf("%s: wrong number of output groups. Want 1. Got %d", test.givenTestCase, len(newJob.OutputGroup))
+		}
+		outputs := newJob.OutputGroup[0].Output
+		if len(outputs) != len(test.wantSettings) {
+			t.Fatalf("%s: wrong number of outputs. Want %d. Got %d", test.givenTestCase, len(test.wantSettings), len(outputs))
+		}
+		for i, output := range outputs {
+			if !reflect.DeepEqual(output.AppleLiveSettings, test.wantSettings[i]) {
+				t.Errorf("%s: wrong settings of output %d\nwant %#v\ngot  %#v", test.givenTestCase, i, test.wantSettings[i], output.AppleLiveSettings)
+			}
+		}
 	}
 }
 
-func TestJobStatusNoDuration(t *testing.T) {
-	elementalConductorConfig := config.ElementalConductor{
-		Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
-		UserLogin:       "myuser",
+func TestElementalNewJobHLSAudioDescription(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	job := db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{
+			Protocol:         "hls",
+			PlaylistFileName: "hls/index.m3u8",
+			SegmentDuration:  3,
+			HLSLayout:        "demuxed",
+		},
+	}
+	for _, preset := range []string{"hls_1080p", "hls_audio_description", "hls_audio_en"} {
+		job.Outputs = append(job.Outputs, db.TranscodeOutput{
+			FileName: "hls/" + preset + ".m3u8",
+			Preset: db.PresetMap{
+				Name:            preset,
+				ProviderMapping: map[string]string{Name: preset},
+				OutputOpts:      db.OutputOptions{Extension: "m3u8", AudioDescription: preset == "hls_audio_description"},
+			},
+		})
+	}
+	newJob, err := prov.newJob(&job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSettings := []*elementalconductor.AppleLiveOutputSettings{
+		{AudioRenditionSets: "audio"},
+		{AudioGroupID: "audio", AudioTrackType: "alternate_audio_auto_select", Characteristics: "public.accessibility.describes-video"},
+		{AudioGroupID: "audio", AudioTrackType: "alternate_audio_auto_select_default"},
+	}
+	outputs := newJob.OutputGroup[0].Output
+	if len(outputs) != len(wantSettings) {
+		t.Fatalf("wrong number of outputs. Want %d. Got %d", len(wantSettings), len(outputs))
+	}
+	for i, output := range outputs {
+		if !reflect.DeepEqual(output.AppleLiveSettings, wantSettings[i]) {
+			t.Errorf("wrong settings of output %d\nwant %#v\ngot  %#v", i, wantSettings[i], output.AppleLiveSettings)
+		}
+	}
+	data, err := xml.Marshal(newJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<characteristics>public.accessibility.describes-video</characteristics>"; !strings.Contains(string(data), want) {
+		t.Errorf("accessibility characteristic not found in the job XML\nwant %s\nin   %s", want, data)
+	}
+}
+
+func TestElementalNewJobHLSAudioDescriptionErrors(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenLayout   string
+		givenPreset   string
+
+		wantErr error
+	}{
+		{
+			"muxed layout",
+			"muxed",
+			"hls_audio_description",
+			provider.InvalidJobError(`preset "hls_audio_description" is an audio description, which is only supported in hls outputs with the demuxed layout`),
+		},
+		{
+			"preset with video",
+			"demuxed",
+			"hls_720p",
+			provider.InvalidJobError("audio descriptions must be audio-only outputs"),
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{Destination: "s3://destination"},
+		}
+		_, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			StreamingParams: db.StreamingParams{
+				Protocol:         "hls",
+				PlaylistFileName: "hls/index.m3u8",
+				SegmentDuration:  3,
+				HLSLayout:        test.givenLayout,
+			},
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "hls/hls_1080p.m3u8",
+					Preset: db.PresetMap{
+						Name:            "hls_1080p",
+						ProviderMapping: map[string]string{Name: "hls_1080p"},
+						OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+					},
+				},
+				{
+					FileName: "hls/" + test.givenPreset + ".m3u8",
+					Preset: db.PresetMap{
+						Name:            test.givenPreset,
+						ProviderMapping: map[string]string{Name: test.givenPreset},
+						OutputOpts:      db.OutputOptions{Extension: "m3u8", AudioDescription: true},
+					},
+				},
+			},
+		})
+		if err != test.wantErr {
+			t.Errorf("%s: wrong error\nwant %#v\ngot  %#v", test.givenTestCase, test.wantErr, err)
+		}
+	}
+}
+
+func TestElementalNewJobHLSBaseURL(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenBaseURL  string
+
+		wantBaseURL string
+		wantXML     string
+	}{
+		{
+			"base url",
+			"https://cdn.example.com/videos/job-1",
+			"https://cdn.example.com/videos/job-1/",
+			"<base_url_manifest>https://cdn.example.com/videos/job-1/</base_url_manifest>" +
+				"<base_url_content>https://cdn.example.com/videos/job-1/</base_url_content>",
+		},
+		{
+			"base url with trailing slash",
+			"https://cdn.example.com/videos/job-1/",
+			"https://cdn.example.com/videos/job-1/",
+			"<base_url_manifest>https://cdn.example.com/videos/job-1/</base_url_manifest>" +
+				"<base_url_content>https://cdn.example.com/videos/job-1/</base_url_content>",
+		},
+		{
+			"relative urls",
+			"",
+			"",
+			"<emit_single_file>true</emit_single_file></apple_live_group_settings>",
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{Destination: "s3://destination"},
+		}
+		newJob, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			StreamingParams: db.StreamingParams{
+				Protocol:         "hls",
+				PlaylistFileName: "hls/index.m3u8",
+				SegmentDuration:  3,
+				BaseURL:          test.givenBaseURL,
+			},
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "hls/hls_1080p.m3u8",
+					Preset: db.PresetMap{
+						Name:            "hls_1080p",
+						ProviderMapping: map[string]string{Name: "hls_1080p"},
+						OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		settings := newJob.OutputGroup[0].AppleLiveGroupSettings
+		if settings.BaseURLManifest != test.wantBaseURL {
+			t.Errorf("%s: wrong manifest base url. Want %q. Got %q", test.givenTestCase, test.wantBaseURL, settings.BaseURLManifest)
+		}
+		if settings.BaseURLContent != test.wantBaseURL {
+			t.Errorf("%s: wrong content base url. Want %q. Got %q", test.givenTestCase, test.wantBaseURL, settings.BaseURLContent)
+		}
+		data, err := xml.Marshal(newJob)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if !strings.Contains(string(data), test.wantXML) {
+			t.Errorf("%s: apple live group settings not found in the job XML\nwant %s\nin   %s", test.givenTestCase, test.wantXML, data)
+		}
+	}
+}
+
+func TestElementalNewJobHLSSegmentNames(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenTemplate string
+		givenLayout   string
+
+		wantSettings []*elementalconductor.AppleLiveOutputSettings
+	}{
+		{
+			"template with bitrate",
+			"seg_{bitrate}_{index}",
+			"",
+			[]*elementalconductor.AppleLiveOutputSettings{
+				{SegmentModifier: "seg_$rt$_"},
+				{SegmentModifier: "seg_$rt$_"},
+			},
+		},
+		{
+			"template with demuxed layout",
+			"part-{index}",
+			"demuxed",
+			[]*elementalconductor.AppleLiveOutputSettings{
+				{AudioRenditionSets: "audio", SegmentModifier: "part-"},
+				{AudioGroupID: "audio", AudioTrackType: "alternate_audio_auto_select_default", SegmentModifier: "part-"},
+			},
+		},
+		{
+			"default naming",
+			"",
+			"",
+			[]*elementalconductor.AppleLiveOutputSettings{nil, nil},
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{Destination: "s3://destination"},
+		}
+		job := db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			StreamingParams: db.StreamingParams{
+				Protocol:            "hls",
+				PlaylistFileName:    "hls/index.m3u8",
+				SegmentDuration:     3,
+				HLSLayout:           test.givenLayout,
+				SegmentNameTemplate: test.givenTemplate,
+			},
+		}
+		for _, preset := range []string{"hls_1080p", "hls_audio_en"} {
+			job.Outputs = append(job.Outputs, db.TranscodeOutput{
+				FileName: "hls/" + preset + ".m3u8",
+				Preset: db.PresetMap{
+					Name:            preset,
+					ProviderMapping: map[string]string{Name: preset},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			})
+		}
+		newJob, err := prov.newJob(&job)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if len(newJob.OutputGroup) != 1 {
+			t.Fatalf("%s: wrong number of output groups. Want 1. Got %d", test.givenTestCase, len(newJob.OutputGroup))
+		}
+		outputs := newJob.OutputGroup[0].Output
+		if len(outputs) != len(test.wantSettings) {
+			t.Fatalf("%s: wrong number of outputs. Want %d. Got %d", test.givenTestCase, len(test.wantSettings), len(outputs))
+		}
+		for i, output := range outputs {
+			if !reflect.DeepEqual(output.AppleLiveSettings, test.wantSettings[i]) {
+				t.Errorf("%s: wrong settings of output %d\nwant %#v\ngot  %#v", test.givenTestCase, i, test.wantSettings[i], output.AppleLiveSettings)
+			}
+		}
+	}
+}
+
+func TestElementalNewJobHLSVariantOrder(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenOrder    string
+		givenLayout   string
+		givenPresets  []string
+
+		wantStreams []string
+	}{
+		{
+			"order of the outputs",
+			"",
+			"",
+			[]string{"hls_1080p", "hls_360p", "hls_720p"},
+			[]string{"stream_0", "stream_1", "stream_2"},
+		},
+		{
+			"ascending bandwidth",
+			"bandwidth-asc",
+			"",
+			[]string{"hls_1080p", "hls_360p", "hls_720p"},
+			[]string{"stream_1", "stream_2", "stream_0"},
+		},
+		{
+			"descending bandwidth",
+			"bandwidth-desc",
+			"",
+			[]string{"hls_360p", "hls_1080p", "hls_720p"},
+			[]string{"stream_1", "stream_2", "stream_0"},
+		},
+		{
+			"variants with the same bandwidth",
+			"bandwidth-asc",
+			"",
+			[]string{"hls_720p", "hls_720p_alt", "hls_360p"},
+			[]string{"stream_2", "stream_0", "stream_1"},
+		},
+		{
+			"demuxed layout keeps the position of renditions",
+			"bandwidth-asc",
+			"demuxed",
+			[]string{"hls_audio_en", "hls_1080p", "hls_audio_es", "hls_360p"},
+			[]string{"stream_0", "stream_3", "stream_2", "stream_1"},
+		},
+	}
+	presets := []elementalconductor.Preset{
+		{Name: "hls_1080p", Container: "m3u8", VideoCodec: "h.264", VideoBitrate: "5000000", AudioBitrate: "128000"},
+		{Name: "hls_720p", Container: "m3u8", VideoCodec: "h.264", VideoBitrate: "2500000", AudioBitrate: "128000"},
+		{Name: "hls_720p_alt", Container: "m3u8", VideoCodec: "h.264", VideoBitrate: "2500000", AudioBitrate: "128000"},
+		{Name: "hls_360p", Container: "m3u8", VideoCodec: "h.264", VideoBitrate: "800000", AudioBitrate: "64000"},
+		{Name: "hls_audio_en", Container: "m3u8", AudioBitrate: "128000"},
+		{Name: "hls_audio_es", Container: "m3u8", AudioBitrate: "64000"},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{presets: presets},
+			config: &config.ElementalConductor{Destination: "s3://destination"},
+		}
+		job := db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			StreamingParams: db.StreamingParams{
+				Protocol:         "hls",
+				PlaylistFileName: "hls/index.m3u8",
+				SegmentDuration:  3,
+				HLSLayout:        test.givenLayout,
+				VariantOrder:     test.givenOrder,
+			},
+		}
+		for _, preset := range test.givenPresets {
+			job.Outputs = append(job.Outputs, db.TranscodeOutput{
+				FileName: "hls/" + preset + ".m3u8",
+				Preset: db.PresetMap{
+					Name:            preset,
+					ProviderMapping: map[string]string{Name: preset},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			})
+		}
+		newJob, err := prov.newJob(&job)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if len(newJob.OutputGroup) != 1 {
+			t.Fatalf("%s: wrong number of output groups. Want 1. Got %d", test.givenTestCase, len(newJob.OutputGroup))
+		}
+		var streams []string
+		for i, output := range newJob.OutputGroup[0].Output {
+			if output.Order != i+1 {
+				t.Errorf("%s: wrong order of output %d. Want %d. Got %d", test.givenTestCase, i, i+1, output.Order)
+			}
+			streams = append(streams, output.StreamAssemblyName)
+		}
+		if !reflect.DeepEqual(streams, test.wantStreams) {
+			t.Errorf("%s: wrong variant order\nwant %q\ngot  %q", test.givenTestCase, test.wantStreams, streams)
+		}
+	}
+}
+
+func TestElementalNewJobDemuxedHLSWithoutAudioOutputs(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	_, err := prov.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{
+			Protocol:         "hls",
+			PlaylistFileName: "hls/index.m3u8",
+			SegmentDuration:  3,
+			HLSLayout:        "demuxed",
+		},
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "hls/video_1080p.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_1080p",
+					ProviderMapping: map[string]string{Name: "hls_1080p"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			},
+		},
+	})
+	expectedErr := provider.InvalidJobError("the demuxed hls layout requires both audio-only outputs and outputs with video")
+	if err != expectedErr {
+		t.Errorf("wrong error\nwant %#v\ngot  %#v", expectedErr, err)
+	}
+}
+
+func TestElementalNewJobCMAF(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	presetProvider.client.(*fakeElementalConductorClient).presets = []elementalconductor.Preset{
+		{Href: "/presets/12", Name: "cmaf_720p", Container: string(elementalconductor.CMAF)},
+		{Href: "/presets/34", Name: "cmaf_1080p", Container: string(elementalconductor.CMAF)},
+	}
+	outputs := []db.TranscodeOutput{
+		{
+			FileName: "output_720p.mp4",
+			Preset: db.PresetMap{
+				Name:            "cmaf_720p",
+				ProviderMapping: map[string]string{Name: "cmaf_720p"},
+				OutputOpts:      db.OutputOptions{Extension: "mp4", Container: "cmaf"},
+			},
+		},
+		{
+			FileName: "output_1080p.mp4",
+			Preset: db.PresetMap{
+				Name:            "cmaf_1080p",
+				ProviderMapping: map[string]string{Name: "cmaf_1080p"},
+				OutputOpts:      db.OutputOptions{Extension: "mp4", Container: "cmaf"},
+			},
+		},
+	}
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{
+			Protocol:         "cmaf",
+			SegmentDuration:  4,
+			PlaylistFileName: "cmaf/index",
+			FragmentType:     db.FragmentTypeSegmented,
+		},
+		Outputs: outputs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOutputGroup := []elementalconductor.OutputGroup{
+		{
+			Order: 1,
+			CMAFGroupSettings: &elementalconductor.CMAFGroupSettings{
+				Destination: &elementalconductor.Location{
+					URI:      "s3://destination/job-1/cmaf/index",
+					Username: "aws-access-key",
+					Password: "aws-secret-key",
+				},
+				SegmentLength:     4,
+				SegmentControl:    "segmented_files",
+				WriteHLSManifest:  true,
+				WriteDASHManifest: true,
+			},
+			Type: elementalconductor.CMAFOutputGroupType,
+			Output: []elementalconductor.Output{
+				{
+					StreamAssemblyName: "stream_0",
+					NameModifier:       "_0000000001",
+					Order:              1,
+					Container:          elementalconductor.CMAF,
+				},
+				{
+					StreamAssemblyName: "stream_1",
+					NameModifier:       "_0000000002",
+					Order:              2,
+					Container:          elementalconductor.CMAF,
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(newJob.OutputGroup, expectedOutputGroup) {
+		t.Errorf("wrong output groups\nwant %#v\ngot  %#v", expectedOutputGroup, newJob.OutputGroup)
+	}
+	files := presetProvider.getOutputFiles(newJob)
+	expectedFiles := []provider.OutputFile{
+		{Path: "s3://destination/job-1/cmaf/index.m3u8", Container: "m3u8"},
+		{Path: "s3://destination/job-1/cmaf/index.mpd", Container: "mpd"},
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("wrong output files\nwant %#v\ngot  %#v", expectedFiles, files)
+	}
+
+	_, err = presetProvider.newJob(&db.Job{
+		ID:              "job-2",
+		SourceMedia:     "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{Protocol: "hls", SegmentDuration: 4},
+		Outputs:         outputs,
+	})
+	expectedErr := `preset "cmaf_720p" uses the cmaf container, which is only supported in jobs with the cmaf streaming protocol`
+	if _, ok := err.(provider.InvalidJobError); !ok || err.Error() != expectedErr {
+		t.Errorf("wrong error for CMAF preset in HLS job. Want %q. Got %#v", expectedErr, err)
+	}
+}
+
+func TestElementalNewJobTags(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	newJob, err := prov.newJob(&db.Job{
+		ID:           "job-1",
+		SourceMedia:  "http://some.nice/video.mov",
+		Metadata:     map[string]string{"cost-center": "video 1234", "owner": "someone@example.com"},
+		ProviderTags: []string{"cost-center"},
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.Marshal(newJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<tags><tag><key>cost-center</key><value>video 1234</value></tag></tags>"; !strings.Contains(string(data), want) {
+		t.Errorf("missing job tags\nwant %s\ngot  %s", want, data)
+	}
+	if strings.Contains(string(data), "owner") {
+		t.Errorf("unexpected local metadata in the job: %s", data)
+	}
+}
+
+func TestElementalNewJobInvalidTags(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenMetadata map[string]string
+		givenTags     []string
+		wantErr       string
+	}{
+		{
+			"key too long",
+			map[string]string{strings.Repeat("k", 129): "value"},
+			[]string{strings.Repeat("k", 129)},
+			`invalid tag "` + strings.Repeat("k", 129) + `": keys must have between 1 and 128 characters`,
+		},
+		{
+			"value too long",
+			map[string]string{"cost-center": strings.Repeat("v", 257)},
+			[]string{"cost-center"},
+			`invalid tag "cost-center": values must have at most 256 characters`,
+		},
+		{
+			"invalid characters",
+			map[string]string{"cost-center": "<1234>"},
+			[]string{"cost-center"},
+			`invalid tag "cost-center": keys and values may only contain letters, numbers, spaces and the characters _.:/=+-@`,
+		},
+	}
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	for _, test := range tests {
+		_, err := prov.newJob(&db.Job{
+			ID:           "job-1",
+			SourceMedia:  "http://some.nice/video.mov",
+			Metadata:     test.givenMetadata,
+			ProviderTags: test.givenTags,
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+		})
+		if _, ok := err.(provider.InvalidJobError); !ok || err.Error() != test.wantErr {
+			t.Errorf("%s: wrong error returned\nwant %q\ngot  %#v", test.givenTestCase, test.wantErr, err)
+		}
+	}
+}
+
+func TestElementalNewJobName(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenName     string
+		wantName      string
+		wantErr       string
+	}{
+		{"name", "Big Buck Bunny: trailer 1080p", "<job><name>Big Buck Bunny: trailer 1080p</name>", ""},
+		{"no name", "", "", ""},
+		{
+			"name too long",
+			strings.Repeat("n", 256),
+			"",
+			`invalid job name "` + strings.Repeat("n", 256) + `": must have at most 255 characters`,
+		},
+		{
+			"invalid characters",
+			"<trailer>",
+			"",
+			`invalid job name "<trailer>": may only contain letters, numbers, spaces and the characters _.:/=+-@`,
+		},
+	}
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	for _, test := range tests {
+		newJob, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			Name:        test.givenName,
+			SourceMedia: "http://some.nice/video.mov",
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+		})
+		if test.wantErr != "" {
+			if _, ok := err.(provider.InvalidJobError); !ok || err.Error() != test.wantErr {
+				t.Errorf("%s: wrong error returned\nwant %q\ngot  %#v", test.givenTestCase, test.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(newJob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.wantName == "" && strings.HasPrefix(string(data), "<job><name>") {
+			t.Errorf("%s: unexpected job name: %s", test.givenTestCase, data)
+		}
+		if test.wantName != "" && !strings.HasPrefix(string(data), test.wantName) {
+			t.Errorf("%s: missing job name\nwant %s\ngot  %s", test.givenTestCase, test.wantName, data)
+		}
+	}
+}
+
+func TestElementalNewJobNodeTags(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	presetProvider.client.(*fakeElementalConductorClient).nodes = []elementalconductor.Node{
+		{Name: "node-1", Status: "active", Product: elementalconductor.ProductServer, Tags: []string{"gpu", "hevc"}},
+		{Name: "node-2", Status: "active", Product: elementalconductor.ProductServer, Tags: []string{"cpu"}},
+		{Name: "node-3", Status: "active", Product: elementalconductor.ProductServer},
+	}
+	outputs := []db.TranscodeOutput{
+		{
+			FileName: "output_720p.mp4",
+			Preset: db.PresetMap{
+				Name:            "mp4_720p",
+				ProviderMapping: map[string]string{Name: "mp4_720p"},
+				OutputOpts:      db.OutputOptions{Extension: "mp4"},
+			},
+		},
+	}
+	var tests = []struct {
+		givenTestCase string
+		givenNodeTags []string
+
+		wantNodeTags []string
+		wantErr      error
+	}{
+		{
+			"no placement hint",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"known tags",
+			[]string{"gpu", "hevc"},
+			[]string{"gpu", "hevc"},
+			nil,
+		},
+		{
+			"unknown tags",
+			[]string{"gpu", "arm", "fpga"},
+			nil,
+			provider.InvalidJobError("unknown node tags: arm, fpga"),
+		},
+	}
+	for _, test := range tests {
+		newJob, err := presetProvider.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			Outputs:     outputs,
+			NodeTags:    test.givenNodeTags,
+		})
+		if err != test.wantErr {
+			t.Errorf("%s: wrong error returned. Want %#v. Got %#v", test.givenTestCase, test.wantErr, err)
+		}
+		if test.wantErr != nil {
+			continue
+		}
+		if !reflect.DeepEqual(newJob.NodeTags, test.wantNodeTags) {
+			t.Errorf("%s: wrong node tags in the job. Want %#v. Got %#v", test.givenTestCase, test.wantNodeTags, newJob.NodeTags)
+		}
+		data, err := xml.Marshal(newJob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hasNodeTags := strings.Contains(string(data), "<node_tag>")
+		if hasNodeTags != (len(test.wantNodeTags) > 0) {
+			t.Errorf("%s: unexpected node_tag presence in the generated job: %s", test.givenTestCase, data)
+		}
+	}
+}
+
+func TestElementalNewJobOutputACL(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	var tests = []struct {
+		givenTestCase string
+		givenPreset   string
+		givenACL      string
+	}{
+		{"default ACL", "mp4_720p", ""},
+		{"public-read file output", "mp4_720p", "public-read"},
+		{"public-read HLS output", "hls_720p", "public-read"},
+	}
+	for _, test := range tests {
+		extension := "mp4"
+		if strings.HasPrefix(test.givenPreset, "hls") {
+			extension = "m3u8"
+		}
+		newJob, err := presetProvider.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			OutputACL:   test.givenACL,
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p." + extension,
+					Preset: db.PresetMap{
+						Name:            test.givenPreset,
+						ProviderMapping: map[string]string{Name: test.givenPreset},
+						OutputOpts:      db.OutputOptions{Extension: extension},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		group := newJob.OutputGroup[0]
+		var location *elementalconductor.Location
+		if group.FileGroupSettings != nil {
+			location = group.FileGroupSettings.Destination
+		} else {
+			location = group.AppleLiveGroupSettings.Destination
+		}
+		if location.CannedACL != test.givenACL {
+			t.Errorf("%s: wrong ACL in the output location. Want %q. Got %q", test.givenTestCase, test.givenACL, location.CannedACL)
+		}
+		data, err := xml.Marshal(newJob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hasACL := strings.Contains(string(data), "<canned_acl>"+test.givenACL+"</canned_acl>")
+		if hasACL != (test.givenACL != "") {
+			t.Errorf("%s: unexpected canned_acl in the generated job: %s", test.givenTestCase, data)
+		}
+	}
+}
+
+func TestElementalNewJobFrameCaptures(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+		},
+		FrameCaptures: []string{"00:00:01:00", "00:00:30:12", "01:02:03:04"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTimecodes := []string{"00:00:01:00", "00:00:30:12", "01:02:03:04"}
+	wantURIs := []string{
+		"s3://destination/job-1/frames/00-00-01-00",
+		"s3://destination/job-1/frames/00-00-30-12",
+		"s3://destination/job-1/frames/01-02-03-04",
+	}
+	if len(newJob.OutputGroup) != 4 {
+		t.Fatalf("wrong number of output groups. Want 4. Got %d", len(newJob.OutputGroup))
+	}
+	if len(newJob.StreamAssembly) != 4 {
+		t.Fatalf("wrong number of stream assemblies. Want 4. Got %d", len(newJob.StreamAssembly))
+	}
+	for i, timecode := range wantTimecodes {
+		outputGroup := newJob.OutputGroup[i+1]
+		if outputGroup.Order != i+2 {
+			t.Errorf("%s: wrong output group order. Want %d. Got %d", timecode, i+2, outputGroup.Order)
+		}
+		if uri := outputGroup.FileGroupSettings.Destination.URI; uri != wantURIs[i] {
+			t.Errorf("%s: wrong destination. Want %q. Got %q", timecode, wantURIs[i], uri)
+		}
+		output := outputGroup.Output[0]
+		if output.Container != elementalconductor.RawContainer || output.Extension != "jpg" {
+			t.Errorf("%s: wrong container/extension. Want raw/jpg. Got %s/%s", timecode, output.Container, output.Extension)
+		}
+		streamAssembly := newJob.StreamAssembly[i+1]
+		if streamAssembly.Name != output.StreamAssemblyName {
+			t.Errorf("%s: wrong stream assembly. Want %q. Got %q", timecode, output.StreamAssemblyName, streamAssembly.Name)
+		}
+		expectedVideo := &elementalconductor.StreamVideoDescription{
+			Codec: "frame_capture",
+			FrameCaptureSettings: &elementalconductor.FrameCaptureSettings{
+				StartTimecode: timecode,
+				MaxCaptures:   1,
+				Quality:       80,
+			},
+		}
+		if !reflect.DeepEqual(streamAssembly.VideoDescription, expectedVideo) {
+			t.Errorf("%s: wrong video description\nwant %#v\ngot  %#v", timecode, expectedVideo, streamAssembly.VideoDescription)
+		}
+	}
+
+	// simulate the full URIs returned by Elemental once the job finishes
+	for i := range newJob.OutputGroup[1:] {
+		outputGroup := newJob.OutputGroup[i+1]
+		outputGroup.Output[0].FullURI = outputGroup.FileGroupSettings.Destination.URI + ".jpg"
+	}
+	files := presetProvider.getOutputFiles(newJob)
+	var frameFiles []provider.OutputFile
+	for _, file := range files {
+		if file.Container == "jpg" {
+			frameFiles = append(frameFiles, file)
+		}
+	}
+	if len(frameFiles) != len(wantURIs) {
+		t.Fatalf("wrong number of frame captures in the output files. Want %d. Got %#v", len(wantURIs), files)
+	}
+	for i, file := range frameFiles {
+		if file.Path != wantURIs[i]+".jpg" {
+			t.Errorf("wrong frame capture path. Want %q. Got %q", wantURIs[i]+".jpg", file.Path)
+		}
+		if file.VideoCodec != "frame_capture" {
+			t.Errorf("wrong frame capture codec. Want %q. Got %q", "frame_capture", file.VideoCodec)
+		}
+	}
+}
+
+func TestJobStatusOutputDestination(t *testing.T) {
+	var tests = []struct {
+		job            db.Job
+		destinationCfg string
+		expected       string
+	}{
+		{
+			db.Job{
+				ID: "job-123",
+			},
+			"s3://destination///",
+			"s3://destination/job-123",
+		},
+		{
+			db.Job{
+				ID: "job-123",
+			},
+			"s3://destination",
+			"s3://destination/job-123",
+		},
+	}
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, ok := prov.(*elementalConductorProvider)
+	if !ok {
+		t.Fatal("Could not type assert test provider to elementalConductorProvider")
+	}
+	for _, test := range tests {
+		provider.config.Destination = test.destinationCfg
+		got := provider.getOutputDestination(&test.job)
+		if got != test.expected {
+			t.Errorf("Wrong output destination. Want %q. Got %q", test.expected, got)
+		}
+	}
+}
+
+func TestJobStatusMap(t *testing.T) {
+	var tests = []struct {
+		elementalConductorStatus string
+		expected                 provider.Status
+	}{
+		{"pending", provider.StatusQueued},
+		{"preprocessing", provider.StatusStarted},
+		{"running", provider.StatusStarted},
+		{"postprocessing", provider.StatusStarted},
+		{"complete", provider.StatusFinished},
+		{"cancelled", provider.StatusCanceled},
+		{"error", provider.StatusFailed},
+		{"unknown", provider.StatusUnknown},
+		{"someotherstatus", provider.StatusUnknown},
+	}
+	var p elementalConductorProvider
+	for _, test := range tests {
+		got := p.statusMap(test.elementalConductorStatus)
+		if got != test.expected {
+			t.Errorf("statusMap(%q): wrong value. Want %q. Got %q", test.elementalConductorStatus, test.expected, got)
+		}
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	elementalConductorConfig := config.ElementalConductor{
+		Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+		UserLogin:       "myuser",
+		APIKey:          "elemental-api-key",
+		AuthExpires:     30,
+		AccessKeyID:     "aws-access-key",
+		SecretAccessKey: "aws-secret-key",
+		Destination:     "s3://destination",
+	}
+	submitted := elementalconductor.DateTime{Time: time.Now().UTC()}
+	client := newFakeElementalConductorClient(&elementalConductorConfig)
+	client.jobs["job-1"] = elementalconductor.Job{
+		Href: "whatever",
+		Input: []elementalconductor.Input{{
+			InputInfo: &elementalconductor.InputInfo{
+				Video: elementalconductor.VideoInputInfo{
+					Format: "AVC",
+					Width:  "1 920 pixels",
+					Height: "1 080 pixels",
+				},
+			},
+		}},
+		ContentDuration: &elementalconductor.ContentDuration{
+			InputDuration: 123,
+		},
+		OutputGroup: []elementalconductor.OutputGroup{
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1.mp4",
+						Extension:          "mp4",
+						StreamAssemblyName: "stream_0",
+						Container:          "mp4",
+					},
+				},
+			},
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1.webm",
+						Extension:          "webm",
+						StreamAssemblyName: "stream_1",
+						Container:          "webm",
+					},
+				},
+			},
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1.mov",
+						Extension:          "mov",
+						StreamAssemblyName: "unknown",
+						Container:          "mov",
+					},
+				},
+			},
+			{
+				Type: elementalconductor.AppleLiveOutputGroupType,
+				AppleLiveGroupSettings: &elementalconductor.AppleLiveGroupSettings{
+					Destination: &elementalconductor.Location{
+						URI: "s3://somebucket/dir/video1",
+					},
+					SegmentDuration: 3,
+					EmitSingleFile:  true,
+				},
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1_720p.m3u8",
+						Extension:          "m3u8",
+						StreamAssemblyName: "stream_2",
+						Container:          "m3u8",
+					},
+					{
+						FullURI:            "s3://somebucket/dir/video1_1080p.m3u8",
+						Extension:          "m3u8",
+						StreamAssemblyName: "stream_3",
+						Container:          "m3u8",
+					},
+				},
+			},
+		},
+		StreamAssembly: []elementalconductor.StreamAssembly{
+			{
+				ID:   "2323",
+				Name: "stream_0",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "h.264",
+					EncoderType: "gpu",
+					Height:      "1080",
+					Width:       "1920",
+				},
+			},
+			{
+				ID:   "2324",
+				Name: "stream_1",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "vp8",
+					EncoderType: "gpu",
+					Height:      "1080",
+					Width:       "1920",
+				},
+			},
+			{
+				ID:   "2325",
+				Name: "stream_2",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "h.264",
+					EncoderType: "gpu",
+					Height:      "720",
+					Width:       "1280",
+				},
+			},
+			{
+				ID:   "2326",
+				Name: "stream_3",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "h.264",
+					EncoderType: "gpu",
+					Height:      "1080",
+					Width:       "1920",
+				},
+			},
+		},
+		PercentComplete: 89,
+		Status:          "running",
+		Submitted:       submitted,
+	}
+	prov := elementalConductorProvider{client: client, config: &elementalConductorConfig}
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "super-job-1", ProviderJobID: "job-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedJobStatus := provider.JobStatus{
+		ProviderName:  Name,
+		ProviderJobID: "job-1",
+		Progress:      89.,
+		Status:        provider.StatusStarted,
+		Output: provider.JobOutput{
+			Destination: "s3://destination/super-job-1",
+			Files: []provider.OutputFile{
+				{
+					Path:      "s3://somebucket/dir/video1.m3u8",
+					Container: "m3u8",
+				},
+				{
+					Path:       "s3://somebucket/dir/video1.mp4",
+					Container:  "mp4",
+					VideoCodec: "h.264",
+					Width:      1920,
+					Height:     1080,
+				},
+				{
+					Path:       "s3://somebucket/dir/video1.webm",
+					Container:  "webm",
+					VideoCodec: "vp8",
+					Width:      1920,
+					Height:     1080,
+				},
+			},
+		},
+		SourceInfo: provider.SourceInfo{
+			Duration:   123 * time.Second,
+			Width:      1920,
+			Height:     1080,
+			VideoCodec: "AVC",
+		},
+		ProviderStatus: map[string]interface{}{
+			"status":    "running",
+			"submitted": submitted,
+		},
+		SubmitTime: submitted.Time,
+	}
+	if !reflect.DeepEqual(*jobStatus, expectedJobStatus) {
+		t.Errorf("wrong job stats\nwant %#v\ngot  %#v", expectedJobStatus, *jobStatus)
+	}
+}
+
+func TestJobStatusTimestamps(t *testing.T) {
+	submitted := time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+	started := submitted.Add(time.Minute)
+	finished := started.Add(5 * time.Minute)
+	var tests = []struct {
+		givenTestCase string
+		givenJob      elementalconductor.Job
+
+		wantCompleteTime time.Time
+	}{
+		{
+			"complete job",
+			elementalconductor.Job{
+				Status:       "complete",
+				Submitted:    elementalconductor.DateTime{Time: submitted},
+				StartTime:    elementalconductor.DateTime{Time: started},
+				CompleteTime: elementalconductor.DateTime{Time: finished},
+			},
+			finished,
+		},
+		{
+			"failed job",
+			elementalconductor.Job{
+				Status:      "error",
+				Submitted:   elementalconductor.DateTime{Time: submitted},
+				StartTime:   elementalconductor.DateTime{Time: started},
+				ErroredTime: elementalconductor.DateTime{Time: finished},
+			},
+			finished,
+		},
+		{
+			"running job",
+			elementalconductor.Job{
+				Status:    "running",
+				Submitted: elementalconductor.DateTime{Time: submitted},
+				StartTime: elementalconductor.DateTime{Time: started},
+			},
+			time.Time{},
+		},
+	}
+	for _, test := range tests {
+		elementalConductorConfig := config.ElementalConductor{Destination: "s3://destination"}
+		client := newFakeElementalConductorClient(&elementalConductorConfig)
+		client.jobs["job-1"] = test.givenJob
+		prov := elementalConductorProvider{client: client, config: &elementalConductorConfig}
+		jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-1", ProviderJobID: "job-1"})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if !jobStatus.SubmitTime.Equal(submitted) {
+			t.Errorf("%s: wrong submit time. Want %s. Got %s", test.givenTestCase, submitted, jobStatus.SubmitTime)
+		}
+		if !jobStatus.StartTime.Equal(started) {
+			t.Errorf("%s: wrong start time. Want %s. Got %s", test.givenTestCase, started, jobStatus.StartTime)
+		}
+		if !jobStatus.CompleteTime.Equal(test.wantCompleteTime) {
+			t.Errorf("%s: wrong complete time. Want %s. Got %s", test.givenTestCase, test.wantCompleteTime, jobStatus.CompleteTime)
+		}
+	}
+}
+
+func TestJobStatusNoDuration(t *testing.T) {
+	elementalConductorConfig := config.ElementalConductor{
+		Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+		UserLogin:       "myuser",
 		APIKey:          "elemental-api-key",
 		AuthExpires:     30,
 		AccessKeyID:     "aws-access-key",
 		SecretAccessKey: "aws-secret-key",
 		Destination:     "s3://destination",
 	}
-	submitted := elementalconductor.DateTime{Time: time.Now().UTC()}
-	client := newFakeElementalConductorClient(&elementalConductorConfig)
-	client.jobs["job-1"] = elementalconductor.Job{
-		Href: "whatever",
-		Input: elementalconductor.Input{
-			InputInfo: &elementalconductor.InputInfo{
-				Video: elementalconductor.VideoInputInfo{
-					Format: "AVC",
-					Width:  "1 920 pixels",
-					Height: "1 080 pixels",
-				},
-			},
+	submitted := elementalconductor.DateTime{Time: time.Now().UTC()}
+	client := newFakeElementalConductorClient(&elementalConductorConfig)
+	client.jobs["job-1"] = elementalconductor.Job{
+		Href: "whatever",
+		Input: []elementalconductor.Input{{
+			InputInfo: &elementalconductor.InputInfo{
+				Video: elementalconductor.VideoInputInfo{
+					Format: "AVC",
+					Width:  "1 920 pixels",
+					Height: "1 080 pixels",
+				},
+			},
+		}},
+		OutputGroup: []elementalconductor.OutputGroup{
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1.mp4",
+						Extension:          "mp4",
+						StreamAssemblyName: "stream_0",
+						Container:          "mp4",
+					},
+				},
+			},
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1.webm",
+						Extension:          "webm",
+						StreamAssemblyName: "stream_1",
+						Container:          "webm",
+					},
+				},
+			},
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1.mov",
+						Extension:          "mov",
+						StreamAssemblyName: "unknown",
+						Container:          "mov",
+					},
+				},
+			},
+			{
+				Output: []elementalconductor.Output{
+					{
+						FullURI:            "s3://somebucket/dir/video1_720p.m3u8",
+						Extension:          "m3u8",
+						StreamAssemblyName: "stream_2",
+						Container:          "m3u8",
+					},
+					{
+						FullURI:            "s3://somebucket/dir/video1_1080p.m3u8",
+						Extension:          "m3u8",
+						StreamAssemblyName: "stream_3",
+						Container:          "m3u8",
+					},
+				},
+			},
+		},
+		StreamAssembly: []elementalconductor.StreamAssembly{
+			{
+				ID:   "2323",
+				Name: "stream_0",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "h.264",
+					EncoderType: "gpu",
+					Height:      "1080",
+					Width:       "1920",
+				},
+			},
+			{
+				ID:   "2324",
+				Name: "stream_1",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "vp8",
+					EncoderType: "gpu",
+					Height:      "1080",
+					Width:       "1920",
+				},
+			},
+			{
+				ID:   "2325",
+				Name: "stream_2",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "h.264",
+					EncoderType: "gpu",
+					Height:      "720",
+					Width:       "1280",
+				},
+			},
+			{
+				ID:   "2326",
+				Name: "stream_3",
+				VideoDescription: &elementalconductor.StreamVideoDescription{
+					Codec:       "h.264",
+					EncoderType: "gpu",
+					Height:      "1080",
+					Width:       "1920",
+				},
+			},
+		},
+		PercentComplete: 89,
+		Status:          "running",
+		Submitted:       submitted,
+	}
+	prov := elementalConductorProvider{client: client, config: &elementalConductorConfig}
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "super-job-1", ProviderJobID: "job-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedJobStatus := provider.JobStatus{
+		ProviderName:  Name,
+		ProviderJobID: "job-1",
+		Progress:      89.,
+		Status:        provider.StatusStarted,
+		Output: provider.JobOutput{
+			Destination: "s3://destination/super-job-1",
+			Files: []provider.OutputFile{
+				{
+					Path:       "s3://somebucket/dir/video1.mp4",
+					Container:  "mp4",
+					VideoCodec: "h.264",
+					Width:      1920,
+					Height:     1080,
+				},
+				{
+					Path:       "s3://somebucket/dir/video1.webm",
+					Container:  "webm",
+					VideoCodec: "vp8",
+					Width:      1920,
+					Height:     1080,
+				},
+				{
+					Path:       "s3://somebucket/dir/video1_720p.m3u8",
+					Container:  "m3u8",
+					VideoCodec: "h.264",
+					Width:      1280,
+					Height:     720,
+				},
+				{
+					Path:       "s3://somebucket/dir/video1_1080p.m3u8",
+					Container:  "m3u8",
+					VideoCodec: "h.264",
+					Width:      1920,
+					Height:     1080,
+				},
+			},
+		},
+		SourceInfo: provider.SourceInfo{
+			Width:      1920,
+			Height:     1080,
+			VideoCodec: "AVC",
+		},
+		ProviderStatus: map[string]interface{}{
+			"status":    "running",
+			"submitted": submitted,
+		},
+		SubmitTime: submitted.Time,
+	}
+	if !reflect.DeepEqual(*jobStatus, expectedJobStatus) {
+		t.Errorf("wrong job stats\nwant %#v\ngot  %#v", expectedJobStatus, *jobStatus)
+	}
+}
+
+func TestCreatePresetLoudnessNormalization(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = prov.CreatePreset(db.Preset{
+		Name:      "mp4_1080p",
+		Container: "mp4",
+		Audio: db.AudioPreset{
+			Codec:          "aac",
+			Bitrate:        "64000",
+			LoudnessTarget: "-24",
+			TruePeakLimit:  "-2",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+	if len(client.presets) != 1 {
+		t.Fatalf("wrong number of presets created. Want 1. Got %d", len(client.presets))
+	}
+	data, err := xml.Marshal(client.presets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<audio_description><codec>aac</codec><aac_settings><bitrate>64000</bitrate></aac_settings>" +
+		"<audio_normalization_settings><algorithm>ITU_BS_1770_2</algorithm><algorithm_control>correct_audio</algorithm_control>" +
+		"<target_lkfs>-24</target_lkfs><true_peak_limiter_threshold>-2</true_peak_limiter_threshold></audio_normalization_settings></audio_description>"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("wrong audio description in preset\nwant %s\ngot  %s", want, data)
+	}
+}
+
+func TestCreatePresetNoLoudnessNormalization(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = prov.CreatePreset(db.Preset{
+		Name:      "mp4_1080p",
+		Container: "mp4",
+		Audio:     db.AudioPreset{Codec: "aac", Bitrate: "64000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+	data, err := xml.Marshal(client.presets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "audio_normalization_settings") {
+		t.Errorf("unexpected audio normalization settings in preset: %s", data)
+	}
+}
+
+func TestCreatePresetAspectRatioConversion(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantXML       string
+	}{
+		{
+			"pad mode",
+			db.VideoPreset{Width: "640", Height: "480", AspectRatioMode: "pad", AspectRatio: "4:3", PadColor: "#FFFFFF"},
+			"<stretch_to_output>false</stretch_to_output><video_preprocessors><aspect_ratio_conversion>" +
+				"<mode>pad</mode><aspect_ratio>4:3</aspect_ratio><pad_color>#FFFFFF</pad_color></aspect_ratio_conversion></video_preprocessors>",
+		},
+		{
+			"pad mode with default color",
+			db.VideoPreset{Width: "1280", Height: "720", AspectRatioMode: "pad", AspectRatio: "16:9"},
+			"<stretch_to_output>false</stretch_to_output><video_preprocessors><aspect_ratio_conversion>" +
+				"<mode>pad</mode><aspect_ratio>16:9</aspect_ratio><pad_color>#000000</pad_color></aspect_ratio_conversion></video_preprocessors>",
+		},
+		{
+			"crop mode",
+			db.VideoPreset{Width: "640", Height: "480", AspectRatioMode: "crop", AspectRatio: "4:3"},
+			"<stretch_to_output>false</stretch_to_output><video_preprocessors><aspect_ratio_conversion>" +
+				"<mode>crop</mode><aspect_ratio>4:3</aspect_ratio></aspect_ratio_conversion></video_preprocessors>",
+		},
+		{
+			"stretch mode",
+			db.VideoPreset{Width: "640", Height: "480", AspectRatioMode: "stretch", AspectRatio: "4:3"},
+			"<stretch_to_output>true</stretch_to_output></video_description>",
+		},
+	}
+	for _, test := range tests {
+		elementalConductorConfig := config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+			},
+		}
+		prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = prov.CreatePreset(db.Preset{Name: "mp4_480p", Container: "mp4", Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+		data, err := xml.Marshal(client.presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantXML) {
+			t.Errorf("%s: wrong video description in preset\nwant %s\ngot  %s", test.givenTestCase, test.wantXML, data)
+		}
+	}
+}
+
+func TestCreatePresetInterlacing(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantXML       string
+	}{
+		{
+			"interlaced top field first",
+			db.VideoPreset{Width: "1920", Height: "1080", InterlaceMode: "interlaced", FieldOrder: "top"},
+			"<interlace_mode>top_field</interlace_mode>",
+		},
+		{
+			"interlaced bottom field first",
+			db.VideoPreset{Width: "1920", Height: "1080", InterlaceMode: "interlaced", FieldOrder: "bottom"},
+			"<interlace_mode>bottom_field</interlace_mode>",
+		},
+		{
+			"progressive with soft telecine",
+			db.VideoPreset{Width: "1920", Height: "1080", InterlaceMode: "progressive", Telecine: "soft"},
+			"<interlace_mode>progressive</interlace_mode><telecine>soft</telecine>",
+		},
+		{
+			"deinterlaced source",
+			db.VideoPreset{Width: "1920", Height: "1080", InterlaceMode: "progressive", Deinterlace: true},
+			"<video_preprocessors><deinterlacer><algorithm>interpolate</algorithm>" +
+				"<deinterlace_mode>Deinterlace</deinterlace_mode></deinterlacer></video_preprocessors>",
+		},
+	}
+	for _, test := range tests {
+		elementalConductorConfig := config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+			},
+		}
+		prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = prov.CreatePreset(db.Preset{Name: "mp4_1080i", Container: "mp4", Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+		data, err := xml.Marshal(client.presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantXML) {
+			t.Errorf("%s: wrong video description in preset\nwant %s\ngot  %s", test.givenTestCase, test.wantXML, data)
+		}
+	}
+}
+
+func TestCreatePresetMaxFrameRate(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+
+		wantFollowSource string
+		wantConversion   *elementalconductor.FrameRateConversion
+	}{
+		{
+			"30fps cap",
+			db.VideoPreset{Width: "1280", Height: "720", MaxFrameRate: "30"},
+			"true",
+			&elementalconductor.FrameRateConversion{Numerator: "30", Denominator: "1", DecreaseOnly: "true"},
+		},
+		{
+			"fractional cap",
+			db.VideoPreset{Width: "1280", Height: "720", MaxFrameRate: "30000/1001"},
+			"true",
+			&elementalconductor.FrameRateConversion{Numerator: "30000", Denominator: "1001", DecreaseOnly: "true"},
+		},
+		{
+			"no cap",
+			db.VideoPreset{Width: "1280", Height: "720"},
+			"",
+			nil,
+		},
+	}
+	for _, test := range tests {
+		elementalConductorConfig := config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+			},
+		}
+		prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = prov.CreatePreset(db.Preset{Name: "mp4_720p", Container: "mp4", Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		preset := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient).presets[0]
+		if preset.FramerateFollowSource != test.wantFollowSource {
+			t.Errorf("%s: wrong framerate_follow_source. Want %q. Got %q", test.givenTestCase, test.wantFollowSource, preset.FramerateFollowSource)
+		}
+		if !reflect.DeepEqual(preset.FrameRateConversion, test.wantConversion) {
+			t.Errorf("%s: wrong frame rate conversion\nwant %#v\ngot  %#v", test.givenTestCase, test.wantConversion, preset.FrameRateConversion)
+		}
+	}
+}
+
+func TestCreatePresetAudioBitrateMode(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenAudio    db.AudioPreset
+		wantXML       string
+	}{
+		{
+			"constant bitrate",
+			db.AudioPreset{Codec: "aac", Bitrate: "128000", BitrateMode: "cbr"},
+			"<aac_settings><bitrate>128000</bitrate><rate_control_mode>CBR</rate_control_mode></aac_settings>",
+		},
+		{
+			"variable bitrate",
+			db.AudioPreset{Codec: "aac", BitrateMode: "vbr", Quality: "medium-high"},
+			"<aac_settings><rate_control_mode>VBR</rate_control_mode><vbr_quality>Medium High</vbr_quality></aac_settings>",
+		},
+		{
+			"default bitrate mode",
+			db.AudioPreset{Codec: "aac", Bitrate: "64000"},
+			"<aac_settings><bitrate>64000</bitrate></aac_settings>",
+		},
+	}
+	for _, test := range tests {
+		elementalConductorConfig := config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+			},
+		}
+		prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = prov.CreatePreset(db.Preset{Name: "mp4_audio", Container: "mp4", Audio: test.givenAudio})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+		data, err := xml.Marshal(client.presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantXML) {
+			t.Errorf("%s: wrong audio description in preset\nwant %s\ngot  %s", test.givenTestCase, test.wantXML, data)
+		}
+	}
+}
+
+func TestCreatePresetAudioDownmix(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenAudio    db.AudioPreset
+		wantXML       []string
+	}{
+		{
+			"5.1 to stereo",
+			db.AudioPreset{Codec: "aac", Bitrate: "128000", SourceChannels: "6", Channels: "2"},
+			[]string{
+				"<aac_settings><bitrate>128000</bitrate><coding_mode>2_0</coding_mode></aac_settings>",
+				"<remix_settings><channels_in>6</channels_in><channels_out>2</channels_out><channel_mapping>" +
+					"<out_ch_0><in_ch_0>0</in_ch_0><in_ch_1>-60</in_ch_1><in_ch_2>-3</in_ch_2><in_ch_3>-60</in_ch_3><in_ch_4>-3</in_ch_4><in_ch_5>-60</in_ch_5></out_ch_0>" +
+					"<out_ch_1><in_ch_0>-60</in_ch_0><in_ch_1>0</in_ch_1><in_ch_2>-3</in_ch_2><in_ch_3>-60</in_ch_3><in_ch_4>-60</in_ch_4><in_ch_5>-3</in_ch_5></out_ch_1>" +
+					"</channel_mapping></remix_settings>",
+			},
+		},
+		{
+			"custom stereo to mono",
+			db.AudioPreset{SourceChannels: "2", Channels: "1", ChannelMapping: []string{"-3,-3"}},
+			[]string{
+				"<coding_mode>1_0</coding_mode>",
+				"<remix_settings><channels_in>2</channels_in><channels_out>1</channels_out><channel_mapping>" +
+					"<out_ch_0><in_ch_0>-3</in_ch_0><in_ch_1>-3</in_ch_1></out_ch_0>" +
+					"</channel_mapping></remix_settings>",
+			},
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{Name: "mp4_audio", Container: "mp4", Audio: test.givenAudio})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range test.wantXML {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: wrong audio description in preset\nwant %s\ngot  %s", test.givenTestCase, want, data)
+			}
+		}
+		var decoded elementalconductor.Preset
+		err = xml.Unmarshal(data, &decoded)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if want := prov.client.(*fakeElementalConductorClient).presets[0].AudioRemix; !reflect.DeepEqual(decoded.AudioRemix, want) {
+			t.Errorf("%s: wrong decoded remix settings\nwant %#v\ngot  %#v", test.givenTestCase, want, decoded.AudioRemix)
+		}
+	}
+}
+
+func TestCreatePresetNoAudioRemix(t *testing.T) {
+	prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+	_, err := prov.CreatePreset(db.Preset{Name: "mp4_audio", Container: "mp4", Audio: db.AudioPreset{Codec: "aac", Bitrate: "64000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "remix_settings") || strings.Contains(string(data), "coding_mode") {
+		t.Errorf("unexpected channel settings in the preset: %s", data)
+	}
+}
+
+func TestCreatePresetContainerDefaultCodecs(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenPreset   db.Preset
+
+		wantVideoCodec string
+		wantAudioCodec string
+	}{
+		{
+			"default codecs",
+			db.Preset{Name: "webm_720p", Container: "webm", Video: db.VideoPreset{Width: "1280", Height: "720"}},
+			"vp9",
+			"opus",
+		},
+		{
+			"codecs set in the preset",
+			db.Preset{
+				Name:      "webm_720p",
+				Container: "webm",
+				Video:     db.VideoPreset{Width: "1280", Height: "720", Codec: "vp8"},
+				Audio:     db.AudioPreset{Codec: "vorbis"},
+			},
+			"vp8",
+			"vorbis",
+		},
+		{
+			"mxf default codecs",
+			db.Preset{Name: "xdcam_1080p", Container: "mxf", Video: db.VideoPreset{Width: "1920", Height: "1080"}},
+			"mpeg2",
+			"pcm",
+		},
+		{
+			"mxf with prores",
+			db.Preset{Name: "prores_1080p", Container: "mxf", Video: db.VideoPreset{Width: "1920", Height: "1080", Codec: "prores"}},
+			"prores",
+			"pcm",
+		},
+		{
+			"mp4 preset",
+			db.Preset{Name: "mp4_720p", Container: "mp4", Video: db.VideoPreset{Width: "1280", Height: "720"}},
+			"",
+			"",
+		},
+	}
+	for _, test := range tests {
+		elementalConductorConfig := config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+			},
+		}
+		prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = prov.CreatePreset(test.givenPreset)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		preset := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient).presets[0]
+		if preset.VideoCodec != test.wantVideoCodec {
+			t.Errorf("%s: wrong video codec. Want %q. Got %q", test.givenTestCase, test.wantVideoCodec, preset.VideoCodec)
+		}
+		if preset.AudioCodec != test.wantAudioCodec {
+			t.Errorf("%s: wrong audio codec. Want %q. Got %q", test.givenTestCase, test.wantAudioCodec, preset.AudioCodec)
+		}
+	}
+}
+
+func TestCreatePresetEncoderSpeed(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantSettings  string
+	}{
+		{
+			"h264 veryfast",
+			db.VideoPreset{Codec: "h264", EncoderSpeed: "veryfast"},
+			"<h264_settings><quality_level>Low</quality_level></h264_settings>",
+		},
+		{
+			"h264 medium",
+			db.VideoPreset{EncoderSpeed: "Medium"},
+			"<h264_settings><quality_level>Medium</quality_level></h264_settings>",
+		},
+		{
+			"h265 veryslow",
+			db.VideoPreset{Codec: "h265", EncoderSpeed: "veryslow"},
+			"<h265_settings><quality_level>High</quality_level></h265_settings>",
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{Name: "mp4_1080p", Container: "mp4", Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantSettings) {
+			t.Errorf("%s: wrong encoder settings\nwant %s\ngot  %s", test.givenTestCase, test.wantSettings, data)
+		}
+	}
+}
+
+func TestCreatePresetDefaultEncoderSpeed(t *testing.T) {
+	prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+	_, err := prov.CreatePreset(db.Preset{Name: "mp4_1080p", Container: "mp4", Video: db.VideoPreset{Codec: "h264"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "quality_level") {
+		t.Errorf("unexpected quality level in preset without encoder speed: %s", data)
+	}
+}
+
+func TestCreatePresetColorFormat(t *testing.T) {
+	var tests = []struct {
+		givenTestCase  string
+		givenContainer string
+		givenVideo     db.VideoPreset
+		wantSettings   string
+	}{
+		{
+			"h264 4:2:2 10-bit",
+			"mov",
+			db.VideoPreset{Codec: "h264", ChromaSubsampling: "4:2:2", BitDepth: "10"},
+			"<h264_settings><profile>High422</profile></h264_settings>",
+		},
+		{
+			"default codec 10-bit",
+			"mp4",
+			db.VideoPreset{BitDepth: "10"},
+			"<h264_settings><profile>High10</profile></h264_settings>",
+		},
+		{
+			"h265 4:2:0 10-bit",
+			"mp4",
+			db.VideoPreset{Codec: "h265", ChromaSubsampling: "4:2:0", BitDepth: "10"},
+			"<h265_settings><profile>Main10</profile></h265_settings>",
+		},
+		{
+			"mxf 4:2:2",
+			"mxf",
+			db.VideoPreset{ChromaSubsampling: "4:2:2"},
+			"<codec>mpeg2</codec><h264_settings><profile>422</profile></h264_settings>",
+		},
+		{
+			"h264 4:2:0 8-bit",
+			"mp4",
+			db.VideoPreset{Codec: "h264", Profile: "main", ChromaSubsampling: "4:2:0", BitDepth: "8"},
+			"<h264_settings><profile>Main</profile></h264_settings>",
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{Name: "mezzanine", Container: test.givenContainer, Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantSettings) {
+			t.Errorf("%s: wrong video settings\nwant %s\ngot  %s", test.givenTestCase, test.wantSettings, data)
+		}
+	}
+}
+
+func TestCreatePresetUnsupportedColorFormat(t *testing.T) {
+	var tests = []struct {
+		givenTestCase  string
+		givenContainer string
+		givenVideo     db.VideoPreset
+		wantErrMsg     string
+	}{
+		{
+			"h265 4:4:4",
+			"mp4",
+			db.VideoPreset{Codec: "h265", ChromaSubsampling: "4:4:4"},
+			"elementalconductor: codec h265 doesn't support 4:4:4 8-bit output",
+		},
+		{
+			"vp9 4:2:2",
+			"webm",
+			db.VideoPreset{ChromaSubsampling: "4:2:2", BitDepth: "10"},
+			"elementalconductor: codec vp9 doesn't support 4:2:2 10-bit output",
+		},
+		{
+			"prores 4:4:4",
+			"mov",
+			db.VideoPreset{Codec: "prores", ChromaSubsampling: "4:4:4"},
+			"elementalconductor: codec prores doesn't support 4:4:4 10-bit output",
+		},
+	}
+	for _, test := range tests {
+		client := &fakeElementalConductorClient{}
+		prov := elementalConductorProvider{client: client}
+		_, err := prov.CreatePreset(db.Preset{Name: "mezzanine", Container: test.givenContainer, Video: test.givenVideo})
+		if err == nil || err.Error() != test.wantErrMsg {
+			t.Errorf("%s: wrong error\nwant %q\ngot  %v", test.givenTestCase, test.wantErrMsg, err)
+		}
+		if len(client.presets) > 0 {
+			t.Errorf("%s: unexpected preset created: %#v", test.givenTestCase, client.presets)
+		}
+	}
+}
+
+func TestCreatePresetFrameReferences(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantSettings  []string
+	}{
+		{
+			"h264",
+			db.VideoPreset{Codec: "h264", BFrames: "3", ReferenceFrames: "4"},
+			[]string{"<gop_num_b_frames>3</gop_num_b_frames>", "<num_ref_frames>4</num_ref_frames>"},
+		},
+		{
+			"default codec without B-frames",
+			db.VideoPreset{BFrames: "0"},
+			[]string{"<gop_num_b_frames>0</gop_num_b_frames>"},
+		},
+		{
+			"h265",
+			db.VideoPreset{Codec: "h265", BFrames: "7", ReferenceFrames: "6"},
+			[]string{"<h265_settings><gop_num_b_frames>7</gop_num_b_frames><num_ref_frames>6</num_ref_frames></h265_settings>"},
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{Name: "mezzanine", Container: "mp4", Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range test.wantSettings {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: wrong video settings\nwant %s\ngot  %s", test.givenTestCase, want, data)
+			}
+		}
+	}
+}
+
+func TestCreatePresetDefaultFrameReferences(t *testing.T) {
+	prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+	_, err := prov.CreatePreset(db.Preset{Name: "mezzanine", Container: "mp4", Video: db.VideoPreset{Codec: "h264"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "gop_num_b_frames") || strings.Contains(string(data), "num_ref_frames") {
+		t.Errorf("unexpected B-frames or reference frames in the preset: %s", data)
+	}
+}
+
+func TestCreatePresetGOPStructure(t *testing.T) {
+	enabled, disabled := true, false
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantSettings  []string
+		wantMissing   []string
+	}{
+		{
+			"closed GOP",
+			db.VideoPreset{Codec: "h264", ClosedGOP: &enabled},
+			[]string{"<gop_closed_cadence>1</gop_closed_cadence>"},
+			[]string{"scene_change_detect"},
+		},
+		{
+			"closed GOP without scene change detection",
+			db.VideoPreset{ClosedGOP: &enabled, SceneChangeDetection: &disabled},
+			[]string{"<gop_closed_cadence>1</gop_closed_cadence>", "<scene_change_detect>false</scene_change_detect>"},
+			nil,
+		},
+		{
+			"open GOP with scene change detection",
+			db.VideoPreset{Codec: "h264", ClosedGOP: &disabled, SceneChangeDetection: &enabled},
+			[]string{"<gop_closed_cadence>0</gop_closed_cadence>", "<scene_change_detect>true</scene_change_detect>"},
+			nil,
+		},
+		{
+			"h265 closed GOP",
+			db.VideoPreset{Codec: "h265", ClosedGOP: &enabled},
+			[]string{"<h265_settings><gop_closed_cadence>1</gop_closed_cadence></h265_settings>"},
+			nil,
+		},
+		{
+			"provider defaults",
+			db.VideoPreset{Codec: "h264"},
+			nil,
+			[]string{"gop_closed_cadence", "scene_change_detect"},
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{Name: "mezzanine", Container: "mp4", Video: test.givenVideo})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(prov.client.(*fakeElementalConductorClient).presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range test.wantSettings {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: wrong video settings\nwant %s\ngot  %s", test.givenTestCase, want, data)
+			}
+		}
+		for _, missing := range test.wantMissing {
+			if strings.Contains(string(data), missing) {
+				t.Errorf("%s: unexpected %s in the video settings: %s", test.givenTestCase, missing, data)
+			}
+		}
+	}
+}
+
+func TestCreatePresetUnsupportedFrameReferences(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantErrMsg    string
+	}{
+		{
+			"too many B-frames",
+			db.VideoPreset{Codec: "h264", BFrames: "8"},
+			`elementalconductor: video.bFrames must be between 0 and 7, got "8"`,
 		},
-		OutputGroup: []elementalconductor.OutputGroup{
-			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1.mp4",
-						Extension:          "mp4",
-						StreamAssemblyName: "stream_0",
-						Container:          "mp4",
-					},
-				},
-			},
-			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1.webm",
-						Extension:          "webm",
-						StreamAssemblyName: "stream_1",
-						Container:          "webm",
-					},
-				},
-			},
-			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1.mov",
-						Extension:          "mov",
-						StreamAssemblyName: "unknown",
-						Container:          "mov",
-					},
-				},
-			},
-			{
-				Output: []elementalconductor.Output{
-					{
-						FullURI:            "s3://somebucket/dir/video1_720p.m3u8",
-						Extension:          "m3u8",
-						StreamAssemblyName: "stream_2",
-						Container:          "m3u8",
-					},
-					{
-						FullURI:            "s3://somebucket/dir/video1_1080p.m3u8",
-						Extension:          "m3u8",
-						StreamAssemblyName: "stream_3",
-						Container:          "m3u8",
-					},
-				},
-			},
+		{
+			"too many reference frames",
+			db.VideoPreset{Codec: "h265", ReferenceFrames: "16"},
+			`elementalconductor: video.referenceFrames must be between 1 and 6, got "16"`,
 		},
-		StreamAssembly: []elementalconductor.StreamAssembly{
-			{
-				ID:   "2323",
-				Name: "stream_0",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "h.264",
-					EncoderType: "gpu",
-					Height:      "1080",
-					Width:       "1920",
-				},
-			},
-			{
-				ID:   "2324",
-				Name: "stream_1",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "vp8",
-					EncoderType: "gpu",
-					Height:      "1080",
-					Width:       "1920",
-				},
-			},
-			{
-				ID:   "2325",
-				Name: "stream_2",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "h.264",
-					EncoderType: "gpu",
-					Height:      "720",
-					Width:       "1280",
-				},
-			},
+	}
+	for _, test := range tests {
+		client := &fakeElementalConductorClient{}
+		prov := elementalConductorProvider{client: client}
+		_, err := prov.CreatePreset(db.Preset{Name: "mezzanine", Container: "mp4", Video: test.givenVideo})
+		if err == nil || err.Error() != test.wantErrMsg {
+			t.Errorf("%s: wrong error\nwant %q\ngot  %v", test.givenTestCase, test.wantErrMsg, err)
+		}
+		if len(client.presets) > 0 {
+			t.Errorf("%s: unexpected preset created: %#v", test.givenTestCase, client.presets)
+		}
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = prov.CancelJob(context.Background(), "idk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+	if client.canceledJobs[0] != "idk" {
+		t.Errorf("did not cancel the correct job. Want %q. Got %q", "idk", client.canceledJobs[0])
+	}
+}
+
+func TestUpdateJobPriority(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = prov.(provider.PriorityUpdater).UpdateJobPriority(context.Background(), "job-1", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+	if want := map[string]int{"job-1": 90}; !reflect.DeepEqual(client.priorities, want) {
+		t.Errorf("wrong priority updates. Want %#v. Got %#v", want, client.priorities)
+	}
+}
+
+func TestGetJobLogs(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitted := time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+	client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+	client.jobs["job-1"] = elementalconductor.Job{
+		Status:      "Error",
+		Submitted:   elementalconductor.DateTime{Time: submitted},
+		StartTime:   elementalconductor.DateTime{Time: submitted.Add(time.Minute)},
+		ErroredTime: elementalconductor.DateTime{Time: submitted.Add(3 * time.Minute)},
+		ErrorMessages: []elementalconductor.JobError{
 			{
-				ID:   "2326",
-				Name: "stream_3",
-				VideoDescription: &elementalconductor.StreamVideoDescription{
-					Codec:       "h.264",
-					EncoderType: "gpu",
-					Height:      "1080",
-					Width:       "1920",
-				},
+				Code:      1040,
+				CreatedAt: elementalconductor.JobErrorDateTime{Time: submitted.Add(2 * time.Minute)},
+				Message:   "Failed to open input file",
 			},
 		},
-		PercentComplete: 89,
-		Status:          "running",
-		Submitted:       submitted,
 	}
-	prov := elementalConductorProvider{client: client, config: &elementalConductorConfig}
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "super-job-1", ProviderJobID: "job-1"})
+	logs, err := prov.(provider.JobLogger).GetJobLogs(context.Background(), "job-1")
 	if err != nil {
 		t.Fatal(err)
 	}
-	expectedJobStatus := provider.JobStatus{
-		ProviderName:  Name,
-		ProviderJobID: "job-1",
-		Progress:      89.,
-		Status:        provider.StatusStarted,
-		Output: provider.JobOutput{
-			Destination: "s3://destination/super-job-1",
-			Files: []provider.OutputFile{
-				{
-					Path:       "s3://somebucket/dir/video1.mp4",
-					Container:  "mp4",
-					VideoCodec: "h.264",
-					Width:      1920,
-					Height:     1080,
-				},
-				{
-					Path:       "s3://somebucket/dir/video1.webm",
-					Container:  "webm",
-					VideoCodec: "vp8",
-					Width:      1920,
-					Height:     1080,
-				},
-				{
-					Path:       "s3://somebucket/dir/video1_720p.m3u8",
-					Container:  "m3u8",
-					VideoCodec: "h.264",
-					Width:      1280,
-					Height:     720,
-				},
-				{
-					Path:       "s3://somebucket/dir/video1_1080p.m3u8",
-					Container:  "m3u8",
-					VideoCodec: "h.264",
-					Width:      1920,
-					Height:     1080,
-				},
+	expected := "2016-03-10T10:00:00Z job submitted\n" +
+		"2016-03-10T10:01:00Z job started\n" +
+		"2016-03-10T10:02:00Z error 1040: Failed to open input file\n" +
+		"2016-03-10T10:03:00Z job failed\n"
+	if string(logs) != expected {
+		t.Errorf("wrong job logs\nwant %q\ngot  %q", expected, logs)
+	}
+}
+
+func TestTransientFailure(t *testing.T) {
+	const customRules = "permanent:(?i)unsupported codec;transient:(?i)input file.*(timed out|throttled)"
+	var tests = []struct {
+		givenTestCase string
+		givenRules    string
+		givenErrors   []elementalconductor.JobError
+		wantTransient bool
+	}{
+		{"failure without errors", "", nil, true},
+		{"node failure", "", []elementalconductor.JobError{{Code: 1900, Message: "Node went offline"}}, true},
+		{"unreadable input", "", []elementalconductor.JobError{{Code: 1040, Message: "Failed to open input file"}}, false},
+		{"custom permanent rule", customRules, []elementalconductor.JobError{{Code: 1900, Message: "Unsupported codec: dnxhd"}}, false},
+		{"custom transient rule", customRules, []elementalconductor.JobError{{Code: 1040, Message: "Failed to open input file: connection timed out"}}, true},
+		{"custom rules without a match", customRules, []elementalconductor.JobError{{Code: 1040, Message: "Failed to open input file"}}, false},
+		{
+			"custom rules with a permanent error among transient errors",
+			customRules,
+			[]elementalconductor.JobError{
+				{Code: 1040, Message: "Failed to open input file: request throttled"},
+				{Code: 1900, Message: "UNSUPPORTED CODEC"},
 			},
-		},
-		SourceInfo: provider.SourceInfo{
-			Width:      1920,
-			Height:     1080,
-			VideoCodec: "AVC",
-		},
-		ProviderStatus: map[string]interface{}{
-			"status":    "running",
-			"submitted": submitted,
+			false,
 		},
 	}
-	if !reflect.DeepEqual(*jobStatus, expectedJobStatus) {
-		t.Errorf("wrong job stats\nwant %#v\ngot  %#v", expectedJobStatus, *jobStatus)
+	for _, test := range tests {
+		var rules config.ErrorRules
+		err := rules.Decode(test.givenRules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		elementalConductorConfig := config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+				ErrorRules:  rules,
+			},
+		}
+		prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
+		client.jobs["job-1"] = elementalconductor.Job{Status: "Error", ErrorMessages: test.givenErrors}
+		status, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-1", ProviderJobID: "job-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Status != provider.StatusFailed {
+			t.Fatalf("%s: wrong status. Want %q. Got %q", test.givenTestCase, provider.StatusFailed, status.Status)
+		}
+		transient := prov.(provider.FailureClassifier).TransientFailure(status)
+		if transient != test.wantTransient {
+			t.Errorf("%s: wrong classification. Want transient=%v. Got %v", test.givenTestCase, test.wantTransient, transient)
+		}
+		if len(test.givenErrors) > 0 {
+			wantCategory := config.TransientErrorCategory
+			if !test.wantTransient {
+				wantCategory = config.PermanentErrorCategory
+			}
+			if category := status.ProviderStatus["error_category"]; category != wantCategory {
+				t.Errorf("%s: wrong error category. Want %q. Got %v", test.givenTestCase, wantCategory, category)
+			}
+		}
 	}
 }
 
-func TestCancelJob(t *testing.T) {
+func TestTranscodeJobSpec(t *testing.T) {
 	elementalConductorConfig := config.Config{
 		ElementalConductor: &config.ElementalConductor{
 			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
@@ -1086,13 +3186,82 @@ func TestCancelJob(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = prov.CancelJob("idk")
-	if err != nil {
-		t.Fatal(err)
+	signedAt := time.Now().UTC().Format(presignedDateFormat)
+	source := "https://source.s3.amazonaws.com/video.mov?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=AKIDEXAMPLE%2F20160310%2Fus-east-1%2Fs3%2Faws4_request&X-Amz-Date=" + signedAt + "&X-Amz-Expires=3600&X-Amz-Signature=secret-signature"
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
+		ID:          "job-1",
+		SourceMedia: source,
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+			{
+				FileName: "output_720p.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_720p",
+					ProviderMapping: map[string]string{Name: "hls_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			},
+		},
+		StreamingParams: db.StreamingParams{Protocol: "hls", SegmentDuration: 3, PlaylistFileName: "hls/playlist.m3u8"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobStatus.Spec == nil {
+		t.Fatal("spec of the job not reported")
+	}
+	if jobStatus.Spec.ContentType != "application/xml" {
+		t.Errorf("wrong content type. Want %q. Got %q", "application/xml", jobStatus.Spec.ContentType)
+	}
+	spec := jobStatus.Spec.Data
+	for _, secret := range []string{"aws-access-key", "aws-secret-key", "secret-signature", "AKIDEXAMPLE"} {
+		if strings.Contains(string(spec), secret) {
+			t.Errorf("credential %q not redacted from the spec:\n%s", secret, spec)
+		}
+	}
+	var job elementalconductor.Job
+	err = xml.Unmarshal(spec, &job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(job.Input) != 1 || job.Input[0].FileInput.URI != "https://source.s3.amazonaws.com/video.mov" {
+		t.Fatalf("wrong input in the spec: %#v", job.Input)
+	}
+	var locations []*elementalconductor.Location
+	for _, group := range job.OutputGroup {
+		if group.FileGroupSettings != nil {
+			locations = append(locations, group.FileGroupSettings.Destination)
+		}
+		if group.AppleLiveGroupSettings != nil {
+			locations = append(locations, group.AppleLiveGroupSettings.Destination)
+		}
+	}
+	if len(locations) != 2 {
+		t.Fatalf("wrong number of output locations in the spec. Want 2. Got %d", len(locations))
+	}
+	for _, location := range locations {
+		if location.Username != redactedCredential || location.Password != redactedCredential {
+			t.Errorf("credentials not redacted from location %q: %q/%q", location.URI, location.Username, location.Password)
+		}
 	}
+
+	// the job sent to Elemental Conductor keeps its credentials.
 	client := prov.(*elementalConductorProvider).client.(*fakeElementalConductorClient)
-	if client.canceledJobs[0] != "idk" {
-		t.Errorf("did not cancel the correct job. Want %q. Got %q", "idk", client.canceledJobs[0])
+	submitted := client.jobs["1"]
+	if submitted.Input[0].FileInput.URI != source {
+		t.Errorf("wrong input submitted. Want %q. Got %q", source, submitted.Input[0].FileInput.URI)
+	}
+	for _, group := range submitted.OutputGroup {
+		if group.FileGroupSettings != nil && group.FileGroupSettings.Destination.Password != "aws-secret-key" {
+			t.Errorf("credentials of the submitted job redacted: %#v", group.FileGroupSettings.Destination)
+		}
 	}
 }
 
@@ -1173,7 +3342,7 @@ func TestHealthcheck(t *testing.T) {
 	for _, test := range tests {
 		server.SetCloudConfig(&elementalconductor.CloudConfig{MinNodes: test.minNodes})
 		server.SetNodes(test.nodes)
-		err := prov.Healthcheck()
+		err := prov.Healthcheck(context.Background())
 		if test.expectedMsg != "" {
 			if got := err.Error(); got != test.expectedMsg {
 				t.Errorf("Wrong error returned. Want %q. Got %q", test.expectedMsg, got)
@@ -1184,15 +3353,534 @@ func TestHealthcheck(t *testing.T) {
 	}
 }
 
+func TestJobStatusNotFound(t *testing.T) {
+	server := NewElementalServer(nil, nil)
+	defer server.Close()
+	prov := elementalConductorProvider{
+		client: elementalconductor.NewClient(server.URL, "", "", 0, "", "", ""),
+		config: &config.ElementalConductor{},
+	}
+	_, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-1", ProviderJobID: "12345"})
+	if want := (provider.JobNotFoundError{ID: "12345"}); err != want {
+		t.Errorf("wrong error returned by JobStatus. Want %#v. Got %#v", want, err)
+	}
+	err = prov.CancelJob(context.Background(), "12345")
+	if want := (provider.JobNotFoundError{ID: "12345"}); err != want {
+		t.Errorf("wrong error returned by CancelJob. Want %#v. Got %#v", want, err)
+	}
+}
+
+func TestJobStatusProviderError(t *testing.T) {
+	server := NewElementalServer(nil, nil)
+	defer server.Close()
+	server.SetJobStatus(http.StatusInternalServerError)
+	prov := elementalConductorProvider{
+		client: elementalconductor.NewClient(server.URL, "", "", 0, "", "", ""),
+		config: &config.ElementalConductor{},
+	}
+	_, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-1", ProviderJobID: "12345"})
+	apiErr, ok := err.(*elementalconductor.APIError)
+	if !ok {
+		t.Fatalf("wrong error returned by JobStatus. Want *elementalconductor.APIError. Got %#v", err)
+	}
+	if apiErr.Status != http.StatusInternalServerError {
+		t.Errorf("wrong status in the error. Want %d. Got %d", http.StatusInternalServerError, apiErr.Status)
+	}
+}
+
+func TestHealthcheckAuthFailure(t *testing.T) {
+	server := NewElementalServer(&elementalconductor.CloudConfig{MinNodes: 1}, []elementalconductor.Node{
+		{Product: elementalconductor.ProductServer, Status: "active"},
+	})
+	defer server.Close()
+	prov := elementalConductorProvider{
+		client: elementalconductor.NewClient(server.URL, "", "", 0, "", "", ""),
+	}
+	var tests = []struct {
+		authStatus  int
+		expectedErr error
+	}{
+		{0, nil},
+		{http.StatusUnauthorized, provider.ErrAuthFailed},
+		{http.StatusForbidden, provider.ErrAuthFailed},
+	}
+	for _, test := range tests {
+		server.SetAuthStatus(test.authStatus)
+		err := prov.Healthcheck(context.Background())
+		if err != test.expectedErr {
+			t.Errorf("auth status %d: wrong error returned. Want %#v. Got %#v", test.authStatus, test.expectedErr, err)
+		}
+	}
+	server.SetAuthStatus(http.StatusInternalServerError)
+	err := prov.Healthcheck(context.Background())
+	if _, ok := err.(*elementalconductor.APIError); !ok {
+		t.Errorf("wrong error returned for server errors. Want *elementalconductor.APIError. Got %#v", err)
+	}
+}
+
+func TestHealthcheckRecordedInteractions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "elementalconductor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "healthcheck.json")
+	server := NewElementalServer(&elementalconductor.CloudConfig{MinNodes: 2}, []elementalconductor.Node{
+		{Product: elementalconductor.ProductConductorFile, Status: "active"},
+		{Product: elementalconductor.ProductServer, Status: "active"},
+		{Product: elementalconductor.ProductServer, Status: "error"},
+	})
+	healthcheck := func(mode string) error {
+		recorder, err := provider.NewRecorder(mode, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := elementalconductor.NewClient(server.URL, "myuser", "secret-key", 30, "", "", "")
+		client.HTTPClient = &http.Client{Transport: recorder}
+		prov := elementalConductorProvider{client: client}
+		return prov.Healthcheck(context.Background())
+	}
+	recordedErr := healthcheck(provider.RecordModeRecord)
+	server.Close()
+	if recordedErr == nil {
+		t.Fatal("unexpected <nil> error in the recorded healthcheck")
+	}
+	replayedErr := healthcheck(provider.RecordModeReplay)
+	if replayedErr == nil || replayedErr.Error() != recordedErr.Error() {
+		t.Errorf("wrong error in the replayed healthcheck\nwant %v\ngot  %v", recordedErr, replayedErr)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	var tests = []struct {
+		nodes        []elementalconductor.Node
+		expectedLoad float64
+		expectedMsg  string
+	}{
+		{
+			[]elementalconductor.Node{
+				{Product: elementalconductor.ProductConductorFile, Status: "active", RunningCount: 10},
+				{Product: elementalconductor.ProductServer, Status: "active", RunningCount: 3},
+				{Product: elementalconductor.ProductServer, Status: "active", RunningCount: 2},
+				{Product: elementalconductor.ProductServer, Status: "starting", RunningCount: 7},
+			},
+			2.5,
+			"",
+		},
+		{
+			[]elementalconductor.Node{
+				{Product: elementalconductor.ProductConductorFile, Status: "active"},
+				{Product: elementalconductor.ProductServer, Status: "starting"},
+			},
+			0,
+			"there are no active nodes",
+		},
+	}
+	for _, test := range tests {
+		client := &fakeElementalConductorClient{nodes: test.nodes}
+		prov := elementalConductorProvider{client: client}
+		load, err := prov.Load()
+		if test.expectedMsg != "" {
+			if err == nil || err.Error() != test.expectedMsg {
+				t.Errorf("Wrong error returned. Want %q. Got %v", test.expectedMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if load != test.expectedLoad {
+			t.Errorf("Wrong load. Want %v. Got %v", test.expectedLoad, load)
+		}
+	}
+}
+
+func TestActiveNodes(t *testing.T) {
+	client := &fakeElementalConductorClient{nodes: []elementalconductor.Node{
+		{Product: elementalconductor.ProductConductorFile, Status: "active"},
+		{Product: elementalconductor.ProductServer, Status: "active"},
+		{Product: elementalconductor.ProductServer, Status: "active"},
+		{Product: elementalconductor.ProductServer, Status: "starting"},
+	}}
+	prov := elementalConductorProvider{client: client}
+	nodes, err := prov.ActiveNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes != 2 {
+		t.Errorf("Wrong number of active nodes. Want 2. Got %d", nodes)
+	}
+}
+
+func TestRegisterClusters(t *testing.T) {
+	cfg := config.Config{
+		ElementalConductor: &config.ElementalConductor{ClusterStrategy: provider.RoundRobinStrategy},
+		ElementalConductorClusters: map[string]*config.ElementalConductor{
+			"east": {Host: "https://elemental-east", UserLogin: "myuser", APIKey: "secret-key", AuthExpires: 30},
+			"west": {Host: "https://elemental-west", UserLogin: "myuser", APIKey: "secret-key", AuthExpires: 30},
+		},
+	}
+	err := RegisterClusters(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cluster := range []string{"east", "west"} {
+		factory, err := provider.GetProviderFactory(provider.ClusterProviderName(Name, cluster))
+		if err != nil {
+			t.Fatal(err)
+		}
+		prov, err := factory(&cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		host := prov.(*elementalConductorProvider).config.Host
+		if want := "https://elemental-" + cluster; host != want {
+			t.Errorf("wrong host for cluster %q. Want %q. Got %q", cluster, want, host)
+		}
+	}
+	providerName, cluster, err := provider.PickCluster(Name, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providerName != "elementalconductor-east" || cluster != "east" {
+		t.Errorf("wrong cluster picked. Got (%q, %q)", providerName, cluster)
+	}
+}
+
+func TestClusterFactoryDisabled(t *testing.T) {
+	clusterCfg := config.ElementalConductor{Host: "https://elemental-east", UserLogin: "myuser", APIKey: "secret-key", AuthExpires: 30}
+	var tests = []struct {
+		testCase       string
+		disableAll     bool
+		disableCluster bool
+	}{
+		{"cluster disabled", false, true},
+		{"provider disabled", true, false},
+	}
+	for _, test := range tests {
+		cluster := clusterCfg
+		cluster.Disabled = test.disableCluster
+		cfg := config.Config{ElementalConductor: &config.ElementalConductor{Disabled: test.disableAll}}
+		prov, err := clusterFactory(&cluster)(&cfg)
+		if prov != nil {
+			t.Errorf("%s: unexpected non-nil provider: %#v", test.testCase, prov)
+		}
+		if err != provider.ErrProviderDisabled {
+			t.Errorf("%s: wrong error returned. Want provider.ErrProviderDisabled. Got %#v", test.testCase, err)
+		}
+	}
+}
+
+func TestElementalUserAgent(t *testing.T) {
+	server := NewElementalServer(nil, nil)
+	defer server.Close()
+	cfg := config.Config{
+		ProviderUserAgent: "video-transcoding-api-staging",
+		ProviderHeaders:   map[string]string{"X-Environment": "staging"},
+		ElementalConductor: &config.ElementalConductor{
+			Host:        server.URL,
+			UserLogin:   "myuser",
+			APIKey:      "secret-key",
+			AuthExpires: 30,
+		},
+	}
+	prov, err := elementalConductorFactory(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = prov.(*elementalConductorProvider).client.GetNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := server.header.Get("User-Agent"); got != "video-transcoding-api-staging" {
+		t.Errorf("wrong User-Agent. Want %q. Got %q", "video-transcoding-api-staging", got)
+	}
+	if got := server.header.Get("X-Environment"); got != "staging" {
+		t.Errorf("wrong X-Environment header. Want %q. Got %q", "staging", got)
+	}
+	if got := server.header.Get("X-Auth-User"); got != "myuser" {
+		t.Errorf("wrong X-Auth-User header. Want %q. Got %q", "myuser", got)
+	}
+}
+
+func TestElementalJobStatusCanceledContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	prov := elementalConductorProvider{
+		client: elementalconductor.NewClient(server.URL, "myuser", "secret-key", 30, "", "", ""),
+		config: &config.ElementalConductor{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	jobStatus, err := prov.JobStatus(ctx, &db.Job{ID: "job-1", ProviderJobID: "12345"})
+	if err == nil {
+		t.Fatalf("unexpected <nil> error, got job status: %#v", jobStatus)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("JobStatus took %s to return after the context was canceled", elapsed)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("wrong context error. Want %v. Got %v", context.Canceled, ctx.Err())
+	}
+}
+
 func TestCapabilities(t *testing.T) {
 	var prov elementalConductorProvider
 	expected := provider.Capabilities{
-		InputFormats:  []string{"prores", "h264"},
-		OutputFormats: []string{"mp4", "hls"},
-		Destinations:  []string{"akamai", "s3"},
+		InputFormats:    []string{"prores", "h264"},
+		OutputFormats:   []string{"mp4", "webm", "mxf", "hls", "cmaf", "jpg"},
+		Destinations:    []string{"akamai", "s3", "gcs"},
+		OutputACLs:      []string{"private", "public-read"},
+		InputStitching:  true,
+		AudioSelection:  true,
+		JobTags:         true,
+		JobSpecs:        true,
+		JobNames:        true,
+		DemuxedHLS:      true,
+		AbsoluteHLSURLs: true,
+		HLSSegmentNames: true,
+		HLSVariantOrder: true,
+		InputContainers: []string{"mov", "mp4", "mxf", "ts", "webm"},
 	}
 	cap := prov.Capabilities()
 	if !reflect.DeepEqual(cap, expected) {
 		t.Errorf("Capabilities: want %#v. Got %#v", expected, cap)
 	}
 }
+
+func TestElementalNewJobStitchedInput(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/intro.mov",
+		SourceSegments: []db.SourceSegment{
+			{URI: "http://some.nice/intro.mov"},
+			{URI: "http://some.nice/video.mov", In: "00:00:10:00", Out: "00:05:00:00"},
+			{URI: "http://some.nice/video.mov", In: "00:07:30:12"},
+		},
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := func(uri string) elementalconductor.Location {
+		return elementalconductor.Location{URI: uri, Username: "aws-access-key", Password: "aws-secret-key"}
+	}
+	expectedInput := []elementalconductor.Input{
+		{
+			FileInput:      location("http://some.nice/intro.mov"),
+			TimecodeSource: "zerobased",
+		},
+		{
+			FileInput:      location("http://some.nice/video.mov"),
+			InputClipping:  &elementalconductor.InputClipping{StartTimecode: "00:00:10:00", EndTimecode: "00:05:00:00"},
+			TimecodeSource: "zerobased",
+		},
+		{
+			FileInput:      location("http://some.nice/video.mov"),
+			InputClipping:  &elementalconductor.InputClipping{StartTimecode: "00:07:30:12"},
+			TimecodeSource: "zerobased",
+		},
+	}
+	if !reflect.DeepEqual(newJob.Input, expectedInput) {
+		t.Errorf("wrong inputs\nwant %#v\ngot  %#v", expectedInput, newJob.Input)
+	}
+	data, err := xml.Marshal(newJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedXML := "<input><file_input><uri>http://some.nice/video.mov</uri><username>aws-access-key</username><password>aws-secret-key</password></file_input>" +
+		"<input_clipping><start_timecode>00:00:10:00</start_timecode><end_timecode>00:05:00:00</end_timecode></input_clipping>" +
+		"<timecode_source>zerobased</timecode_source></input>"
+	if !strings.Contains(string(data), expectedXML) {
+		t.Errorf("stitched input not found in the job XML\nwant %s\nin   %s", expectedXML, data)
+	}
+}
+
+func TestElementalNewJobInputFormat(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	var tests = []struct {
+		givenTestCase    string
+		givenInputFormat string
+
+		wantXML string
+	}{
+		{
+			"input format hint",
+			"mxf",
+			"<container_override>mxf</container_override></input>",
+		},
+		{
+			"auto-detection",
+			"",
+			"</file_input></input>",
+		},
+	}
+	for _, test := range tests {
+		newJob, err := presetProvider.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/source/12345",
+			InputFormat: test.givenInputFormat,
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if got := newJob.Input[0].ContainerOverride; got != test.givenInputFormat {
+			t.Errorf("%s: wrong container override. Want %q. Got %q", test.givenTestCase, test.givenInputFormat, got)
+		}
+		data, err := xml.Marshal(newJob)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if !strings.Contains(string(data), test.wantXML) {
+			t.Errorf("%s: input not found in the job XML\nwant %s\nin   %s", test.givenTestCase, test.wantXML, data)
+		}
+	}
+}
+
+func TestElementalNewJobExtensionOverride(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.m4v",
+				Preset: db.PresetMap{
+					Name:            "m4v_720p",
+					ProviderMapping: map[string]string{Name: "m4v_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "m4v", Container: "mp4"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOutput := elementalconductor.Output{
+		StreamAssemblyName: "stream_0",
+		Order:              1,
+		Extension:          "m4v",
+		Container:          elementalconductor.MPEG4,
+	}
+	if len(newJob.OutputGroup) != 1 || len(newJob.OutputGroup[0].Output) != 1 {
+		t.Fatalf("wrong output groups: %#v", newJob.OutputGroup)
+	}
+	if output := newJob.OutputGroup[0].Output[0]; !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("wrong output\nwant %#v\ngot  %#v", expectedOutput, output)
+	}
+	if uri := newJob.OutputGroup[0].FileGroupSettings.Destination.URI; uri != "s3://destination/job-1/output_720p" {
+		t.Errorf("wrong destination. Want %q. Got %q", "s3://destination/job-1/output_720p", uri)
+	}
+}
+
+func TestElementalNewJobMXF(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	newJob, err := prov.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_1080p.mxf",
+				Preset: db.PresetMap{
+					Name:            "xdcam_1080p",
+					ProviderMapping: map[string]string{Name: "xdcam_1080p"},
+					OutputOpts:      db.OutputOptions{Extension: "mxf"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOutput := elementalconductor.Output{
+		StreamAssemblyName: "stream_0",
+		Order:              1,
+		Container:          elementalconductor.MXF,
+	}
+	if len(newJob.OutputGroup) != 1 || len(newJob.OutputGroup[0].Output) != 1 {
+		t.Fatalf("wrong output groups: %#v", newJob.OutputGroup)
+	}
+	if newJob.OutputGroup[0].Type != elementalconductor.FileOutputGroupType {
+		t.Errorf("wrong output group type. Want %q. Got %q", elementalconductor.FileOutputGroupType, newJob.OutputGroup[0].Type)
+	}
+	if output := newJob.OutputGroup[0].Output[0]; !reflect.DeepEqual(output, expectedOutput) {
+		t.Errorf("wrong output\nwant %#v\ngot  %#v", expectedOutput, output)
+	}
+}
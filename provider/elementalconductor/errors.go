@@ -0,0 +1,39 @@
+package elementalconductor
+
+import (
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+// permanentErrorCodes lists the codes of errors reported by Elemental
+// Conductor that are caused by the job itself, so submitting it again
+// wouldn't help.
+var permanentErrorCodes = map[int]bool{
+	1040: true, // failed to open input file
+}
+
+// errorCategory classifies the given error using the first configured rule
+// matching its message. Errors not matching any rule are classified by their
+// code.
+func (p *elementalConductorProvider) errorCategory(jobError elementalconductor.JobError) string {
+	for _, rule := range p.config.ErrorRules {
+		if rule.Pattern.MatchString(jobError.Message) {
+			return rule.Category
+		}
+	}
+	if permanentErrorCodes[jobError.Code] {
+		return config.PermanentErrorCategory
+	}
+	return config.TransientErrorCategory
+}
+
+// jobErrorsCategory classifies the errors of a job, which are permanent when
+// any of them is permanent.
+func (p *elementalConductorProvider) jobErrorsCategory(jobErrors []elementalconductor.JobError) string {
+	for _, jobError := range jobErrors {
+		if p.errorCategory(jobError) == config.PermanentErrorCategory {
+			return config.PermanentErrorCategory
+		}
+	}
+	return config.TransientErrorCategory
+}
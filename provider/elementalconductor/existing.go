@@ -0,0 +1,221 @@
+package elementalconductor
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	skipExistingOutputsKey = "skipExistingOutputs"
+
+	// skippedJobPrefix prefixes the id of jobs that weren't submitted to
+	// Elemental Conductor because all their outputs already existed.
+	skippedJobPrefix = "skipped-"
+
+	// defaultS3Region is the region used for the first attempt of checking
+	// objects in S3, which reports the region of buckets in other regions.
+	defaultS3Region = "us-east-1"
+)
+
+// objectStore checks whether objects exist in the destination of jobs and
+// writes objects to it.
+type objectStore interface {
+	Exists(ctx context.Context, uri string) (bool, error)
+	Put(ctx context.Context, uri string, data []byte, contentType, acl string) error
+}
+
+// skipExistingOutputs returns whether the given job should be skipped when all
+// its outputs already exist in the destination.
+func skipExistingOutputs(job *db.Job) bool {
+	skip, _ := job.ProviderOptions[skipExistingOutputsKey].(bool)
+	return skip
+}
+
+// existingOutputFiles returns the output files of the given job spec when all
+// of them already exist in the destination, or nil when any of them is
+// missing.
+func (p *elementalConductorProvider) existingOutputFiles(ctx context.Context, job *elementalconductor.Job) ([]provider.OutputFile, error) {
+	files := p.getOutputFiles(job)
+	if len(files) == 0 {
+		return nil, nil
+	}
+	for _, file := range files {
+		exists, err := p.objects.Exists(ctx, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, nil
+		}
+	}
+	return files, nil
+}
+
+// skippedOutputFiles returns the output files of a skipped job, taken from
+// the spec recorded when it was skipped rather than from a new spec, which
+// may no longer be generated (e.g. once a presigned source expires). Jobs
+// skipped without a recorded spec report no files.
+func (p *elementalConductorProvider) skippedOutputFiles(job *db.Job) ([]provider.OutputFile, error) {
+	if job.ProviderSpec == "" {
+		return nil, nil
+	}
+	var spec elementalconductor.Job
+	err := xml.Unmarshal([]byte(job.ProviderSpec), &spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec recorded for job %q: %s", job.ID, err)
+	}
+	return p.getOutputFiles(&spec), nil
+}
+
+// skippedJobStatus returns the status of a job skipped because its outputs
+// already existed, pointing to the existing files.
+func (p *elementalConductorProvider) skippedJobStatus(job *db.Job, files []provider.OutputFile) *provider.JobStatus {
+	return &provider.JobStatus{
+		ProviderName:   Name,
+		ProviderJobID:  skippedJobPrefix + job.ID,
+		Status:         provider.StatusFinished,
+		StatusMessage:  "all the outputs of the job already exist",
+		Progress:       100,
+		ProviderStatus: map[string]interface{}{"skipped": true},
+		Output: provider.JobOutput{
+			Destination: p.getOutputDestination(job),
+			Files:       files,
+		},
+	}
+}
+
+// httpObjectStore checks whether objects exist using HEAD requests and writes
+// objects using PUT requests. Requests for objects in S3 are signed with the
+// configured AWS credentials.
+type httpObjectStore struct {
+	client *http.Client
+	signer *v4.Signer
+}
+
+func newHTTPObjectStore(client *http.Client, cfg *config.ElementalConductor) *httpObjectStore {
+	store := httpObjectStore{client: client}
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		creds := credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+		store.signer = v4.NewSigner(creds, func(s *v4.Signer) {
+			s.DisableURIPathEscaping = true
+		})
+	}
+	return &store
+}
+
+func (c *httpObjectStore) Exists(ctx context.Context, uri string) (bool, error) {
+	resp, err := c.do(ctx, "HEAD", uri, nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to check whether %q exists: %s", uri, err)
+	}
+	resp.Body.Close()
+	return objectExists(uri, resp.StatusCode)
+}
+
+// Put writes the given object, setting its ACL in S3 when one is given.
+func (c *httpObjectStore) Put(ctx context.Context, uri string, data []byte, contentType, acl string) error {
+	header := http.Header{"Content-Type": {contentType}}
+	if acl != "" && strings.HasPrefix(uri, "s3://") {
+		header.Set("X-Amz-Acl", acl)
+	}
+	resp, err := c.do(ctx, "PUT", uri, header, data)
+	if err != nil {
+		return fmt.Errorf("unable to write %q: %s", uri, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unable to write %q: unexpected status %d", uri, resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *httpObjectStore) do(ctx context.Context, method, uri string, header http.Header, body []byte) (*http.Response, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "s3":
+		return c.s3Do(ctx, method, u.Host, strings.TrimLeft(u.Path, "/"), header, body)
+	case "http", "https":
+		req, err := newObjectRequest(ctx, method, uri, header, body)
+		if err != nil {
+			return nil, err
+		}
+		return c.client.Do(req)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
+
+func (c *httpObjectStore) s3Do(ctx context.Context, method, bucket, key string, header http.Header, body []byte) (*http.Response, error) {
+	region := defaultS3Region
+	for {
+		objectURL := url.URL{
+			Scheme: "https",
+			Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, region),
+			Path:   "/" + key,
+		}
+		req, err := newObjectRequest(ctx, method, objectURL.String(), header, body)
+		if err != nil {
+			return nil, err
+		}
+		if c.signer != nil {
+			_, err = c.signer.Sign(req, bytes.NewReader(body), "s3", region, time.Now())
+			if err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		bucketRegion := resp.Header.Get("X-Amz-Bucket-Region")
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound && bucketRegion != "" && bucketRegion != region {
+			resp.Body.Close()
+			region = bucketRegion
+			continue
+		}
+		return resp, nil
+	}
+}
+
+func newObjectRequest(ctx context.Context, method, uri string, header http.Header, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, uri, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return req.WithContext(ctx), nil
+}
+
+func objectExists(uri string, statusCode int) (bool, error) {
+	switch statusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unable to check whether %q exists: unexpected status %d", uri, statusCode)
+	}
+}
@@ -0,0 +1,253 @@
+package elementalconductor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+type fakeObjectStore map[string]bool
+
+func (c fakeObjectStore) Exists(ctx context.Context, uri string) (bool, error) {
+	return c[uri], nil
+}
+
+func (c fakeObjectStore) Put(ctx context.Context, uri string, data []byte, contentType, acl string) error {
+	c[uri] = true
+	return nil
+}
+
+func skipExistingJob(options map[string]interface{}) *db.Job {
+	return &db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+			{
+				FileName: "output_1080p.webm",
+				Preset: db.PresetMap{
+					Name:            "webm_1080p",
+					ProviderMapping: map[string]string{Name: "webm_1080p"},
+					OutputOpts:      db.OutputOptions{Extension: "webm"},
+				},
+			},
+		},
+		ProviderOptions: options,
+	}
+}
+
+func TestElementalTranscodeSkipExistingOutputs(t *testing.T) {
+	allOutputs := fakeObjectStore{
+		"s3://destination/job-1/output_720p.mp4":   true,
+		"s3://destination/job-1/output_1080p.webm": true,
+	}
+	var tests = []struct {
+		givenTestCase string
+		givenOptions  map[string]interface{}
+		givenObjects  fakeObjectStore
+
+		wantSkipped bool
+	}{
+		{
+			"all the outputs exist",
+			map[string]interface{}{"skipExistingOutputs": true},
+			allOutputs,
+			true,
+		},
+		{
+			"some of the outputs exist",
+			map[string]interface{}{"skipExistingOutputs": true},
+			fakeObjectStore{"s3://destination/job-1/output_720p.mp4": true},
+			false,
+		},
+		{
+			"none of the outputs exist",
+			map[string]interface{}{"skipExistingOutputs": true},
+			fakeObjectStore{},
+			false,
+		},
+		{
+			"all the outputs exist without the option",
+			nil,
+			allOutputs,
+			false,
+		},
+	}
+	for _, test := range tests {
+		prov, err := fakeElementalConductorFactory(&config.Config{
+			ElementalConductor: &config.ElementalConductor{
+				Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+				UserLogin:   "myuser",
+				APIKey:      "elemental-api-key",
+				AuthExpires: 30,
+				Destination: "s3://destination",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		presetProvider := prov.(*elementalConductorProvider)
+		presetProvider.objects = test.givenObjects
+		client := presetProvider.client.(*fakeElementalConductorClient)
+		jobStatus, err := presetProvider.Transcode(context.Background(), skipExistingJob(test.givenOptions))
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if !test.wantSkipped {
+			if len(client.jobs) != 1 {
+				t.Errorf("%s: job not sent to Elemental Conductor", test.givenTestCase)
+			}
+			if jobStatus.Status != provider.StatusQueued {
+				t.Errorf("%s: wrong job status. Want %q. Got %q", test.givenTestCase, provider.StatusQueued, jobStatus.Status)
+			}
+			continue
+		}
+		if len(client.jobs) > 0 {
+			t.Errorf("%s: unexpected job sent to Elemental Conductor: %#v", test.givenTestCase, client.jobs)
+		}
+		expectedStatus := &provider.JobStatus{
+			ProviderName:   Name,
+			ProviderJobID:  "skipped-job-1",
+			Status:         provider.StatusFinished,
+			StatusMessage:  "all the outputs of the job already exist",
+			Progress:       100,
+			ProviderStatus: map[string]interface{}{"skipped": true},
+			Output: provider.JobOutput{
+				Destination: "s3://destination/job-1",
+				Files: []provider.OutputFile{
+					{Path: "s3://destination/job-1/output_720p.mp4", Container: "mp4"},
+					{Path: "s3://destination/job-1/output_1080p.webm", Container: "webm"},
+				},
+			},
+		}
+		if jobStatus.Spec == nil {
+			t.Errorf("%s: spec of the skipped job not reported", test.givenTestCase)
+		}
+		jobStatus.Spec = nil
+		if !reflect.DeepEqual(jobStatus, expectedStatus) {
+			t.Errorf("%s: wrong job status\nwant %#v\ngot  %#v", test.givenTestCase, expectedStatus, jobStatus)
+		}
+	}
+}
+
+func TestElementalJobStatusSkippedJob(t *testing.T) {
+	prov, err := fakeElementalConductorFactory(&config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+			Destination: "s3://destination",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	presetProvider.objects = fakeObjectStore{
+		"s3://destination/job-1/output_720p.mp4":   true,
+		"s3://destination/job-1/output_1080p.webm": true,
+	}
+	job := skipExistingJob(map[string]interface{}{"skipExistingOutputs": true})
+	job.SourceMedia = presignedS3URL(time.Now(), 3600)
+	skipStatus, err := presetProvider.Transcode(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.ProviderJobID = skipStatus.ProviderJobID
+	job.ProviderSpec = string(skipStatus.Spec.Data)
+
+	// the source has expired since the job was skipped, so the job can't
+	// be generated again.
+	job.SourceMedia = presignedS3URL(time.Now().Add(-2*time.Hour), 3600)
+	jobStatus, err := presetProvider.JobStatus(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobStatus.Status != provider.StatusFinished {
+		t.Errorf("wrong job status. Want %q. Got %q", provider.StatusFinished, jobStatus.Status)
+	}
+	expectedFiles := []provider.OutputFile{
+		{Path: "s3://destination/job-1/output_720p.mp4", Container: "mp4"},
+		{Path: "s3://destination/job-1/output_1080p.webm", Container: "webm"},
+	}
+	if !reflect.DeepEqual(jobStatus.Output.Files, expectedFiles) {
+		t.Errorf("wrong output files\nwant %#v\ngot  %#v", expectedFiles, jobStatus.Output.Files)
+	}
+}
+
+func TestElementalJobStatusSkippedJobWithoutSpec(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	job := skipExistingJob(map[string]interface{}{"skipExistingOutputs": true})
+	job.ProviderJobID = "skipped-job-1"
+	jobStatus, err := prov.JobStatus(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobStatus.Status != provider.StatusFinished {
+		t.Errorf("wrong job status. Want %q. Got %q", provider.StatusFinished, jobStatus.Status)
+	}
+	if len(jobStatus.Output.Files) > 0 {
+		t.Errorf("unexpected output files: %#v", jobStatus.Output.Files)
+	}
+}
+
+func TestHTTPObjectStoreExists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("wrong method. Want HEAD. Got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/existing.mp4":
+			w.WriteHeader(http.StatusOK)
+		case "/forbidden.mp4":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	var tests = []struct {
+		givenTestCase string
+		givenURI      string
+
+		wantExists bool
+		wantErr    string
+	}{
+		{"existing object", server.URL + "/existing.mp4", true, ""},
+		{"missing object", server.URL + "/missing.mp4", false, ""},
+		{"unexpected status", server.URL + "/forbidden.mp4", false, `unable to check whether "` + server.URL + `/forbidden.mp4" exists: unexpected status 403`},
+		{"unsupported scheme", "ftp://server/video.mp4", false, `unable to check whether "ftp://server/video.mp4" exists: unsupported scheme "ftp"`},
+	}
+	store := newHTTPObjectStore(http.DefaultClient, &config.ElementalConductor{})
+	for _, test := range tests {
+		exists, err := store.Exists(context.Background(), test.givenURI)
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if errMsg != test.wantErr {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, test.wantErr, errMsg)
+		}
+		if exists != test.wantExists {
+			t.Errorf("%s: wrong result. Want %v. Got %v", test.givenTestCase, test.wantExists, exists)
+		}
+	}
+}
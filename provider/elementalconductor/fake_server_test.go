@@ -4,8 +4,9 @@ import (
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
-	"github.com/NYTimes/encoding-wrapper/elementalconductor"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
 )
 
 type nodeList struct {
@@ -17,6 +18,13 @@ type ElementalServer struct {
 	*httptest.Server
 	nodes  *nodeList
 	config *elementalconductor.CloudConfig
+	header http.Header
+
+	// when set, authenticated endpoints reply with the given status
+	authStatus int
+
+	// status returned by the job endpoints, defaulting to 404
+	jobStatus int
 }
 
 func NewElementalServer(config *elementalconductor.CloudConfig, nodes []elementalconductor.Node) *ElementalServer {
@@ -29,14 +37,26 @@ func NewElementalServer(config *elementalconductor.CloudConfig, nodes []elementa
 }
 
 func (s *ElementalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.header = r.Header
+	if s.authStatus != 0 {
+		http.Error(w, "<errors><error>Authentication failed</error></errors>", s.authStatus)
+		return
+	}
 	switch r.URL.Path {
 	case "/api/nodes":
 		w.Header().Set("Content-Type", "application/xml")
 		xml.NewEncoder(w).Encode(s.nodes)
+	case "/api/presets":
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte("<preset_list></preset_list>"))
 	case "/api/config/cloud":
 		w.Header().Set("Content-Type", "application/xml")
 		xml.NewEncoder(w).Encode(s.config)
 	default:
+		if strings.HasPrefix(r.URL.Path, "/api/jobs/") && s.jobStatus != 0 {
+			http.Error(w, "<errors><error>Internal server error</error></errors>", s.jobStatus)
+			return
+		}
 		http.Error(w, "not found", http.StatusNotFound)
 	}
 }
@@ -45,6 +65,14 @@ func (s *ElementalServer) SetCloudConfig(config *elementalconductor.CloudConfig)
 	s.config = config
 }
 
+func (s *ElementalServer) SetJobStatus(status int) {
+	s.jobStatus = status
+}
+
+func (s *ElementalServer) SetAuthStatus(status int) {
+	s.authStatus = status
+}
+
 func (s *ElementalServer) SetNodes(nodes []elementalconductor.Node) {
 	s.nodes.Nodes = nodes
 }
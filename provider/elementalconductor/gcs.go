@@ -0,0 +1,78 @@
+package elementalconductor
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+const gcsScheme = "gs://"
+
+// gcsCredentials contains the fields of a Google Cloud service account key
+// used for authenticating with Google Cloud Storage.
+type gcsCredentials struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// loadGCSCredentials loads and validates the service account key defined in
+// the configuration, returning nil when no key is configured.
+func loadGCSCredentials(cfg *config.ElementalConductor) (*gcsCredentials, error) {
+	data := []byte(cfg.GCSCredentials)
+	if cfg.GCSCredentialsFile != "" {
+		if cfg.GCSCredentials != "" {
+			return nil, provider.InvalidConfigError("only one of ELEMENTALCONDUCTOR_GCS_CREDENTIALS_FILE and ELEMENTALCONDUCTOR_GCS_CREDENTIALS may be defined")
+		}
+		var err error
+		data, err = ioutil.ReadFile(cfg.GCSCredentialsFile)
+		if err != nil {
+			return nil, provider.InvalidConfigError(fmt.Sprintf("unable to read GCS credentials: %s", err))
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var creds gcsCredentials
+	err := json.Unmarshal(data, &creds)
+	if err != nil {
+		return nil, provider.InvalidConfigError(fmt.Sprintf("invalid GCS credentials: %s", err))
+	}
+	if creds.Type != "service_account" {
+		return nil, provider.InvalidConfigError(fmt.Sprintf("invalid GCS credentials: expected a service_account key, got %q", creds.Type))
+	}
+	if creds.ClientEmail == "" {
+		return nil, provider.InvalidConfigError("invalid GCS credentials: missing client_email")
+	}
+	if block, _ := pem.Decode([]byte(creds.PrivateKey)); block == nil {
+		return nil, provider.InvalidConfigError("invalid GCS credentials: missing or malformed private_key")
+	}
+	return &creds, nil
+}
+
+// location returns the location for the given URI, including the credentials
+// matching its scheme: the service account key for gs:// URIs and the AWS
+// keys otherwise.
+func (p *elementalConductorProvider) location(uri string) (elementalconductor.Location, error) {
+	if strings.HasPrefix(uri, gcsScheme) {
+		if p.gcs == nil {
+			return elementalconductor.Location{}, provider.InvalidJobError(fmt.Sprintf("unable to use %s: GCS credentials are not configured", uri))
+		}
+		return elementalconductor.Location{
+			URI:      uri,
+			Username: p.gcs.ClientEmail,
+			Password: p.gcs.PrivateKey,
+		}, nil
+	}
+	return elementalconductor.Location{
+		URI:      uri,
+		Username: p.config.AccessKeyID,
+		Password: p.config.SecretAccessKey,
+	}, nil
+}
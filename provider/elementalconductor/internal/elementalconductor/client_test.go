@@ -0,0 +1,58 @@
+package elementalconductor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientHTTPClientAndContext(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Write([]byte(`<node_list><node href="/nodes/1"><name>node-1</name><tags><tag>fast</tag></tags></node></node_list>`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "myuser", "secret-key", 30, "", "", "")
+	client.HTTPClient = &http.Client{Transport: headerTransport{"X-Request-Source", "transcoding-api"}}
+	nodes, err := client.GetNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || len(nodes[0].Tags) != 1 || nodes[0].Tags[0] != "fast" {
+		t.Errorf("wrong nodes: %#v", nodes)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("wrong number of requests. Want 1. Got %d", len(requests))
+	}
+	if got := requests[0].Header.Get("X-Request-Source"); got != "transcoding-api" {
+		t.Errorf("request not sent through the HTTP client. Got header %q", got)
+	}
+	if got := requests[0].Header.Get("X-Auth-User"); got != "myuser" {
+		t.Errorf("wrong X-Auth-User header. Want %q. Got %q", "myuser", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.WithContext(ctx).GetNodes()
+	if err == nil {
+		t.Error("unexpected <nil> error for a request with a canceled context")
+	}
+	if len(requests) != 1 {
+		t.Errorf("request with a canceled context sent to the server")
+	}
+	_, err = client.GetNodes()
+	if err != nil {
+		t.Errorf("context leaked into the original client: %s", err)
+	}
+}
+
+type headerTransport struct {
+	name, value string
+}
+
+func (t headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.Header.Set(t.name, t.value)
+	return http.DefaultTransport.RoundTrip(r)
+}
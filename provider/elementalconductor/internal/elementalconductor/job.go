@@ -0,0 +1,422 @@
+package elementalconductor
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var nonDigitRegexp = regexp.MustCompile(`[^\d]`)
+
+// OutputGroupType is a custom type for OutputGroup type field values
+type OutputGroupType string
+
+const (
+	// FileOutputGroupType is the value for the type field on OutputGroup
+	// for jobs with a file output
+	FileOutputGroupType = OutputGroupType("file_group_settings")
+	// AppleLiveOutputGroupType is the value for the type field on OutputGroup
+	// for jobs with Apple's HTTP Live Streaming (HLS) output
+	AppleLiveOutputGroupType = OutputGroupType("apple_live_group_settings")
+	// MSSmoothOutputGroupType is the value for the type field on OutputGroup
+	// for jobs with Microsoft Smooth Streaming output
+	MSSmoothOutputGroupType = OutputGroupType("ms_smooth_group_settings")
+	// CMAFOutputGroupType is the value for the type field on OutputGroup
+	// for jobs with CMAF output, served by both HLS and DASH manifests
+	CMAFOutputGroupType = OutputGroupType("cmaf_group_settings")
+)
+
+// Container is the Video container type for a job
+type Container string
+
+const (
+	// AppleHTTPLiveStreaming is the container for HLS video files
+	AppleHTTPLiveStreaming = Container("m3u8")
+	// MPEG4 is the container for MPEG-4 video files
+	MPEG4 = Container("mp4")
+	// WebM is the container for WebM video files
+	WebM = Container("webm")
+	// MXF is the container for Material Exchange Format video files
+	MXF = Container("mxf")
+	// MSSmooth is the container for Microsoft Smooth Streaming video files
+	MSSmooth = Container("ismv")
+	// CMAF is the container for fragmented MP4 segments in CMAF outputs
+	CMAF = Container("cmaf")
+	// RawContainer is the container for outputs without a container, like
+	// frame captures
+	RawContainer = Container("raw")
+)
+
+// FrameCaptureCodec is the video codec used for capturing frames as images
+const FrameCaptureCodec = "frame_capture"
+
+// GetJobs returns a list of the user's jobs
+func (c *Client) GetJobs() (*JobList, error) {
+	var result *JobList
+	err := c.do("GET", "/jobs", nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetJob returns metadata on a single job
+func (c *Client) GetJob(jobID string) (*Job, error) {
+	var result *Job
+	err := c.do("GET", "/jobs/"+jobID, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateJob sends a single job to the current Elemental
+// Cloud deployment for processing
+func (c *Client) CreateJob(job *Job) (*Job, error) {
+	var result *Job
+	err := c.do("POST", "/jobs", *job, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CancelJob cancels the given job in the Elemental Conductor API.
+func (c *Client) CancelJob(jobID string) (*Job, error) {
+	var job *Job
+	var payload = struct {
+		XMLName xml.Name `xml:"cancel"`
+	}{}
+	err := c.do("POST", "/jobs/"+jobID+"/cancel", payload, &job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// UpdateJobPriority changes the priority of the job with the given id, moving
+// it in the queue, and returns the job
+func (c *Client) UpdateJobPriority(jobID string, priority int) (*Job, error) {
+	var job *Job
+	var payload = struct {
+		XMLName  xml.Name `xml:"priority"`
+		Priority int      `xml:",chardata"`
+	}{Priority: priority}
+	err := c.do("POST", "/jobs/"+jobID+"/priority", payload, &job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetID is a convenience function to parse the job id
+// out of the Href attribute in Job
+func (j *Job) GetID() string {
+	if j.Href != "" {
+		hrefData := strings.Split(j.Href, "/")
+		return hrefData[len(hrefData)-1]
+	}
+	return ""
+}
+
+// JobList represents the response returned by
+// a query for the list of jobs
+type JobList struct {
+	XMLName xml.Name `xml:"job_list"`
+	Empty   string   `xml:"empty,omitempty"`
+	Job     []Job    `xml:"job"`
+}
+
+// Job represents a job to be sent to Elemental Cloud
+type Job struct {
+	XMLName         xml.Name         `xml:"job"`
+	Href            string           `xml:"href,attr,omitempty"`
+	Name            string           `xml:"name,omitempty"`
+	Input           []Input          `xml:"input,omitempty"`
+	ContentDuration *ContentDuration `xml:"content_duration,omitempty"`
+	Priority        int              `xml:"priority,omitempty"`
+	NodeTags        []string         `xml:"node_tag,omitempty"`
+	WorkingDir      string           `xml:"working_dir,omitempty"`
+	Tags            []JobTag         `xml:"tags>tag,omitempty"`
+	OutputGroup     []OutputGroup    `xml:"output_group,omitempty"`
+	StreamAssembly  []StreamAssembly `xml:"stream_assembly,omitempty"`
+	Status          string           `xml:"status,omitempty"`
+	Submitted       DateTime         `xml:"submitted,omitempty"`
+	StartTime       DateTime         `xml:"start_time,omitempty"`
+	CompleteTime    DateTime         `xml:"complete_time,omitempty"`
+	ErroredTime     DateTime         `xml:"errored_time,omitempty"`
+	PercentComplete int              `xml:"pct_complete,omitempty"`
+	ErrorMessages   []JobError       `xml:"error_messages,omitempty"`
+}
+
+// JobTag represents a tag attached to a job, used for cost allocation
+type JobTag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+// JobError represents an individual error on a job
+type JobError struct {
+	Code      int              `xml:"error>code,omitempty"`
+	CreatedAt JobErrorDateTime `xml:"error>created_at,omitempty"`
+	Message   string           `xml:"error>message,omitempty"`
+}
+
+// Input represents the spec for the job's input
+type Input struct {
+	FileInput         Location           `xml:"file_input,omitempty"`
+	InputClipping     *InputClipping     `xml:"input_clipping,omitempty"`
+	TimecodeSource    string             `xml:"timecode_source,omitempty"`
+	InputLossBehavior *InputLossBehavior `xml:"input_loss_behavior,omitempty"`
+	InputInfo         *InputInfo         `xml:"input_info,omitempty"`
+	AudioSelector     []AudioSelector    `xml:"audio_selector,omitempty"`
+	BufferMsec        string             `xml:"buffer_msec,omitempty"`
+	ContainerOverride string             `xml:"container_override,omitempty"`
+}
+
+// AudioSelector picks an audio track of the input, by track number or by
+// language, so stream assemblies can reference it by name
+type AudioSelector struct {
+	Name             string `xml:"name,omitempty"`
+	Order            int    `xml:"order,omitempty"`
+	DefaultSelection bool   `xml:"default_selection,omitempty"`
+	SelectorType     string `xml:"selector_type,omitempty"`
+	Track            string `xml:"track,omitempty"`
+	LanguageCode     string `xml:"language_code,omitempty"`
+}
+
+const (
+	// TrackAudioSelectorType is the selector type of audio selectors that
+	// pick tracks by number
+	TrackAudioSelectorType = "track"
+	// LanguageAudioSelectorType is the selector type of audio selectors
+	// that pick tracks by language
+	LanguageAudioSelectorType = "language_code"
+)
+
+// InputLossBehavior represents what the encoder does when there are gaps in
+// the input: repeat the last frame, then insert black (or an image) frames,
+// failing the job after that. Durations are in milliseconds
+type InputLossBehavior struct {
+	RepeatFrameMsec     string `xml:"repeat_frame_msec,omitempty"`
+	BlackFrameMsec      string `xml:"black_frame_msec,omitempty"`
+	InputLossImageType  string `xml:"input_loss_image_type,omitempty"`
+	InputLossImageColor string `xml:"input_loss_image_color,omitempty"`
+}
+
+// InputClipping represents the portion of an input that is used in the job,
+// delimited by timecodes in the format HH:MM:SS:FF
+type InputClipping struct {
+	StartTimecode string `xml:"start_timecode,omitempty"`
+	EndTimecode   string `xml:"end_timecode,omitempty"`
+}
+
+// ZeroBasedTimecodeSource is the timecode source for inputs whose clipping
+// timecodes are relative to the beginning of the file
+const ZeroBasedTimecodeSource = "zerobased"
+
+// InputInfo contains metadata related to a job input.
+type InputInfo struct {
+	Video VideoInputInfo `xml:"video"`
+}
+
+// VideoInputInfo contains video metadata related to a job input.
+type VideoInputInfo struct {
+	Format        string `xml:"format"`
+	FormatInfo    string `xml:"format_info"`
+	FormatProfile string `xml:"format_profile"`
+	CodecID       string `xml:"codec_id"`
+	CodecIDInfo   string `xml:"codec_id_info"`
+	Bitrate       string `xml:"bit_rate"`
+	Width         string `xml:"width"`
+	Height        string `xml:"height"`
+}
+
+// GetWidth parses the underlying width returned the Elemental Conductor API
+// and converts it to int64.
+//
+// Examples:
+//  - Input: "1 920 pixels"
+//    Output: 1920
+//  - Input: "1920p"
+//    Output: 1920
+//  - Input: "1 920"
+//    Output: 1920
+func (v *VideoInputInfo) GetWidth() int64 {
+	return v.extractNumber(v.Width)
+}
+
+// GetHeight parses the underlying height returned the Elemental Conductor API
+// and converts it to int64.
+//
+// Examples:
+//  - Input: "1 080 pixels"
+//    Output: 1080
+//  - Input: "1080p"
+//    Output: 1080
+//  - Input: "1 080"
+//    Output: 1080
+func (v *VideoInputInfo) GetHeight() int64 {
+	return v.extractNumber(v.Height)
+}
+
+func (v *VideoInputInfo) extractNumber(input string) int64 {
+	input = nonDigitRegexp.ReplaceAllString(input, "")
+	n, _ := strconv.ParseInt(input, 10, 64)
+	return n
+}
+
+// ContentDuration contains information about the content of the media in the
+// job.
+type ContentDuration struct {
+	InputDuration int `xml:"input_duration"`
+}
+
+// Location defines where a file is or needs to be.
+// Username and Password are required for certain
+// protocols that require authentication, like S3
+type Location struct {
+	URI       string `xml:"uri,omitempty"`
+	Username  string `xml:"username,omitempty"`
+	Password  string `xml:"password,omitempty"`
+	CannedACL string `xml:"canned_acl,omitempty"`
+	RoleARN   string `xml:"role_arn,omitempty"`
+}
+
+// OutputGroup is a list of the indended outputs for the job
+type OutputGroup struct {
+	Order                  int                     `xml:"order,omitempty"`
+	FileGroupSettings      *FileGroupSettings      `xml:"file_group_settings,omitempty"`
+	AppleLiveGroupSettings *AppleLiveGroupSettings `xml:"apple_live_group_settings,omitempty"`
+	MSSmoothGroupSettings  *MSSmoothGroupSettings  `xml:"ms_smooth_group_settings,omitempty"`
+	CMAFGroupSettings      *CMAFGroupSettings      `xml:"cmaf_group_settings,omitempty"`
+	Type                   OutputGroupType         `xml:"type,omitempty"`
+	Output                 []Output                `xml:"output,omitempty"`
+}
+
+// FileGroupSettings define where the file job output should go
+type FileGroupSettings struct {
+	Destination *Location `xml:"destination,omitempty"`
+}
+
+// AppleLiveGroupSettings define where the HLS job output should go
+type AppleLiveGroupSettings struct {
+	Destination     *Location `xml:"destination,omitempty"`
+	SegmentDuration uint      `xml:"segment_length,omitempty"`
+	EmitSingleFile  bool      `xml:"emit_single_file,omitempty"`
+	BaseURLManifest string    `xml:"base_url_manifest,omitempty"`
+	BaseURLContent  string    `xml:"base_url_content,omitempty"`
+}
+
+// MSSmoothGroupSettings define where the Microsoft Smooth Streaming job
+// output should go
+type MSSmoothGroupSettings struct {
+	Destination    *Location `xml:"destination,omitempty"`
+	FragmentLength uint      `xml:"fragment_length,omitempty"`
+}
+
+// CMAFGroupSettings define where the CMAF job output should go and how its
+// segments are written
+type CMAFGroupSettings struct {
+	Destination       *Location `xml:"destination,omitempty"`
+	SegmentLength     uint      `xml:"segment_length,omitempty"`
+	SegmentControl    string    `xml:"segment_control,omitempty"`
+	WriteHLSManifest  bool      `xml:"write_hls_manifest,omitempty"`
+	WriteDASHManifest bool      `xml:"write_dash_manifest,omitempty"`
+}
+
+const (
+	// SingleFileSegmentControl writes the segments of CMAF outputs as
+	// byte ranges of a single file
+	SingleFileSegmentControl = "single_file"
+	// SegmentedFilesSegmentControl writes each segment of CMAF outputs to
+	// its own file
+	SegmentedFilesSegmentControl = "segmented_files"
+)
+
+// Output defines the different processing stream assemblies
+// for the job
+type Output struct {
+	FullURI            string    `xml:"full_uri,omitempty"`
+	StreamAssemblyName string    `xml:"stream_assembly_name,omitempty"`
+	NameModifier       string    `xml:"name_modifier,omitempty"`
+	Order              int       `xml:"order,omitempty"`
+	Extension          string    `xml:"extension,omitempty"`
+	Container          Container `xml:"container,omitempty"`
+
+	AppleLiveSettings *AppleLiveOutputSettings `xml:"apple_live_settings,omitempty"`
+}
+
+// AppleLiveOutputSettings place the audio of HLS outputs in rendition groups,
+// either as a member of the group or as a variant referencing it
+type AppleLiveOutputSettings struct {
+	AudioGroupID       string `xml:"audio_group_id,omitempty"`
+	AudioTrackType     string `xml:"audio_track_type,omitempty"`
+	AudioRenditionSets string `xml:"audio_rendition_sets,omitempty"`
+	SegmentModifier    string `xml:"segment_modifier,omitempty"`
+	Characteristics    string `xml:"characteristics,omitempty"`
+}
+
+// AudioDescriptionCharacteristic is the characteristic of audio renditions
+// describing the video, for accessibility
+const AudioDescriptionCharacteristic = "public.accessibility.describes-video"
+
+const (
+	// AlternateAudioAutoSelectDefault is the track type of the default
+	// member of an audio rendition group
+	AlternateAudioAutoSelectDefault = "alternate_audio_auto_select_default"
+	// AlternateAudioAutoSelect is the track type of the other members of
+	// an audio rendition group
+	AlternateAudioAutoSelect = "alternate_audio_auto_select"
+)
+
+// StreamAssembly defines how each processing stream should behave
+type StreamAssembly struct {
+	ID               string                  `xml:"id,omitempty"`
+	Name             string                  `xml:"name,omitempty"`
+	Preset           string                  `xml:"preset,omitempty"`
+	PresetID         string                  `xml:"preset_id,omitempty"`
+	VideoDescription *StreamVideoDescription `xml:"video_description"`
+	AudioDescription *StreamAudioDescription `xml:"audio_description,omitempty"`
+}
+
+// StreamAudioDescription contains information about the audio in a given
+// stream assembly.
+type StreamAudioDescription struct {
+	AudioSourceName string `xml:"audio_source_name,omitempty"`
+	LanguageCode    string `xml:"language_code,omitempty"`
+}
+
+// StreamVideoDescription contains information about the video in a given
+// stream assembly.
+type StreamVideoDescription struct {
+	Codec                string                `xml:"codec"`
+	EncoderType          string                `xml:"encoder_type,omitempty"`
+	Height               string                `xml:"height,omitempty"`
+	Width                string                `xml:"width,omitempty"`
+	FrameCaptureSettings *FrameCaptureSettings `xml:"frame_capture_settings,omitempty"`
+}
+
+// FrameCaptureSettings defines how frames are captured in stream assemblies
+// using the frame_capture codec.
+type FrameCaptureSettings struct {
+	StartTimecode string `xml:"start_timecode,omitempty"`
+	MaxCaptures   int    `xml:"max_captures,omitempty"`
+	Quality       int    `xml:"quality,omitempty"`
+}
+
+// GetWidth returns the underlying width parsed as an int64.
+func (s *StreamVideoDescription) GetWidth() int64 {
+	return s.getNumber(s.Width)
+}
+
+// GetHeight returns the underlying height parsed as an int64.
+func (s *StreamVideoDescription) GetHeight() int64 {
+	return s.getNumber(s.Height)
+}
+
+func (s *StreamVideoDescription) getNumber(input string) int64 {
+	v, _ := strconv.ParseInt(input, 10, 64)
+	return v
+}
@@ -0,0 +1,36 @@
+package elementalconductor
+
+import "github.com/NYTimes/encoding-wrapper/elementalconductor"
+
+// Types of the library that aren't extended.
+type (
+	// CloudConfig contains configuration for Elemental Cloud, including
+	// Autoscaler Settings.
+	CloudConfig = elementalconductor.CloudConfig
+
+	// DateTime is a custom struct for representing time within
+	// ElementalConductor.
+	DateTime = elementalconductor.DateTime
+
+	// JobErrorDateTime is a custom time struct to be used on Media items.
+	JobErrorDateTime = elementalconductor.JobErrorDateTime
+
+	// NodeProduct is the product that is running inside a node.
+	NodeProduct = elementalconductor.NodeProduct
+)
+
+const (
+	// ProductConductorFile is condutor file product.
+	ProductConductorFile = elementalconductor.ProductConductorFile
+
+	// ProductServer is the server product.
+	ProductServer = elementalconductor.ProductServer
+)
+
+// GetCloudConfig returns the current Elemental Cloud configuration. It
+// includes Autoscaler Settings.
+func (c *Client) GetCloudConfig() (*CloudConfig, error) {
+	var config CloudConfig
+	err := c.do("GET", "/config/cloud", nil, &config)
+	return &config, err
+}
@@ -0,0 +1,239 @@
+package elementalconductor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GetPresets returns a list of presets
+func (c *Client) GetPresets() (*PresetList, error) {
+	var result *PresetList
+	err := c.do("GET", "/presets", nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPreset return details of a given presetID
+func (c *Client) GetPreset(presetID string) (*Preset, error) {
+	var result *Preset
+	err := c.do("GET", "/presets/"+presetID, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CreatePreset creates a new preset
+func (c *Client) CreatePreset(preset *Preset) (*Preset, error) {
+	var result *Preset
+	err := c.do("POST", "/presets", preset, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeletePreset removes a preset based on its presetID
+func (c *Client) DeletePreset(presetID string) error {
+	return c.do("DELETE", "/presets/"+presetID, nil, nil)
+}
+
+// GetID is a convenience function to parse the preset id
+// out of the Href attribute in Preset
+func (p *Preset) GetID() string {
+	if p.Href != "" {
+		hrefData := strings.Split(p.Href, "/")
+		return hrefData[len(hrefData)-1]
+	}
+	return ""
+}
+
+// PresetList represents the response returned by
+// a query for the list of jobs
+type PresetList struct {
+	Presets []Preset `xml:"preset"`
+}
+
+// Preset represents a preset
+type Preset struct {
+	XMLName       xml.Name `xml:"preset"`
+	Name          string   `xml:"name"`
+	Href          string   `xml:"href,attr,omitempty"`
+	Permalink     string   `xml:"permalink,omitempty"`
+	Description   string   `xml:"description,omitempty"`
+	Container     string   `xml:"container,omitempty"`
+	Width         string   `xml:"video_description>width,omitempty"`
+	Height        string   `xml:"video_description>height,omitempty"`
+	VideoCodec    string   `xml:"video_description>codec,omitempty"`
+	VideoBitrate  string   `xml:"video_description>h264_settings>bitrate,omitempty"`
+	GopSize       string   `xml:"video_description>h264_settings>gop_size,omitempty"`
+	GopMode       string   `xml:"video_description>h264_settings>gop_mode,omitempty"`
+	Profile       string   `xml:"video_description>h264_settings>profile,omitempty"`
+	ProfileLevel  string   `xml:"video_description>h264_settings>level,omitempty"`
+	RateControl   string   `xml:"video_description>h264_settings>rate_control_mode,omitempty"`
+	InterlaceMode string   `xml:"video_description>h264_settings>interlace_mode,omitempty"`
+	NumBFrames    string   `xml:"video_description>h264_settings>gop_num_b_frames,omitempty"`
+	NumRefFrames  string   `xml:"video_description>h264_settings>num_ref_frames,omitempty"`
+	GopClosed     string   `xml:"video_description>h264_settings>gop_closed_cadence,omitempty"`
+	SceneChange   string   `xml:"video_description>h264_settings>scene_change_detect,omitempty"`
+	Slices        string   `xml:"video_description>h264_settings>slices,omitempty"`
+	Telecine      string   `xml:"video_description>h264_settings>telecine,omitempty"`
+	QualityLevel  string   `xml:"video_description>h264_settings>quality_level,omitempty"`
+	H265Profile   string   `xml:"video_description>h265_settings>profile,omitempty"`
+	H265Level     string   `xml:"video_description>h265_settings>level,omitempty"`
+	H265Quality   string   `xml:"video_description>h265_settings>quality_level,omitempty"`
+
+	H265NumBFrames   string `xml:"video_description>h265_settings>gop_num_b_frames,omitempty"`
+	H265NumRefFrames string `xml:"video_description>h265_settings>num_ref_frames,omitempty"`
+	H265GopClosed    string `xml:"video_description>h265_settings>gop_closed_cadence,omitempty"`
+	H265SceneChange  string `xml:"video_description>h265_settings>scene_change_detect,omitempty"`
+
+	FramerateFollowSource string               `xml:"video_description>h264_settings>framerate_follow_source,omitempty"`
+	FrameRateConversion   *FrameRateConversion `xml:"video_description>video_preprocessors>frame_rate_conversion,omitempty"`
+
+	StretchToOutput       string                 `xml:"video_description>stretch_to_output,omitempty"`
+	AspectRatioConversion *AspectRatioConversion `xml:"video_description>video_preprocessors>aspect_ratio_conversion,omitempty"`
+	Deinterlacer          *Deinterlacer          `xml:"video_description>video_preprocessors>deinterlacer,omitempty"`
+
+	AudioCodec       string `xml:"audio_description>codec,omitempty"`
+	AudioBitrate     string `xml:"audio_description>aac_settings>bitrate,omitempty"`
+	SampleRate       string `xml:"audio_description>aac_settings>sample_rate,omitempty"`
+	AudioRateControl string `xml:"audio_description>aac_settings>rate_control_mode,omitempty"`
+	AudioVBRQuality  string `xml:"audio_description>aac_settings>vbr_quality,omitempty"`
+	AudioCodingMode  string `xml:"audio_description>aac_settings>coding_mode,omitempty"`
+
+	AudioNormalization *AudioNormalizationSettings `xml:"audio_description>audio_normalization_settings,omitempty"`
+	AudioRemix         *RemixSettings              `xml:"audio_description>remix_settings,omitempty"`
+
+	// ExcludeVideo and ExcludeAudio leave the video or the audio
+	// description out of the preset, for audio-only or video-only outputs
+	ExcludeVideo bool `xml:"-"`
+	ExcludeAudio bool `xml:"-"`
+}
+
+// MarshalXML encodes the preset, leaving out the descriptions of the
+// excluded streams, which would otherwise be encoded as empty elements.
+func (p Preset) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type plainPreset Preset
+	data, err := xml.Marshal(plainPreset(p))
+	if err != nil {
+		return err
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if el, ok := token.(xml.StartElement); ok {
+			if (el.Name.Local == "video_description" && p.ExcludeVideo) || (el.Name.Local == "audio_description" && p.ExcludeAudio) {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
+
+// AspectRatioConversion represents the preprocessor that converts the video
+// to a different aspect ratio, either cropping or padding the image
+type AspectRatioConversion struct {
+	Mode        string `xml:"mode"`
+	AspectRatio string `xml:"aspect_ratio"`
+	PadColor    string `xml:"pad_color,omitempty"`
+}
+
+// FrameRateConversion represents the preprocessor that converts the frame
+// rate of the video
+type FrameRateConversion struct {
+	Numerator    string `xml:"framerate_numerator"`
+	Denominator  string `xml:"framerate_denominator"`
+	DecreaseOnly string `xml:"decrease_only,omitempty"`
+	Interpolated string `xml:"interpolated,omitempty"`
+}
+
+// Deinterlacer represents the preprocessor that deinterlaces the video
+type Deinterlacer struct {
+	Algorithm string `xml:"algorithm,omitempty"`
+	Mode      string `xml:"deinterlace_mode,omitempty"`
+}
+
+// AudioNormalizationSettings represents the loudness normalization settings
+// of the audio in a preset
+type AudioNormalizationSettings struct {
+	Algorithm        string `xml:"algorithm,omitempty"`
+	AlgorithmControl string `xml:"algorithm_control,omitempty"`
+	TargetLKFS       string `xml:"target_lkfs,omitempty"`
+	TruePeakLimit    string `xml:"true_peak_limiter_threshold,omitempty"`
+}
+
+// RemixSettings represents the remix of the channels of the audio in a
+// preset, e.g. for downmixing 5.1 audio to stereo
+type RemixSettings struct {
+	ChannelsIn     string         `xml:"channels_in"`
+	ChannelsOut    string         `xml:"channels_out"`
+	ChannelMapping ChannelMapping `xml:"channel_mapping"`
+}
+
+// ChannelMapping holds the gains, in dB, of each input channel in each output
+// channel of a remix, encoded as out_ch_N elements holding in_ch_N elements
+type ChannelMapping [][]string
+
+// MarshalXML encodes the channel mapping, numbering the channels.
+func (m ChannelMapping) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for i, gains := range m {
+		out := xml.StartElement{Name: xml.Name{Local: fmt.Sprintf("out_ch_%d", i)}}
+		if err := e.EncodeToken(out); err != nil {
+			return err
+		}
+		for j, gain := range gains {
+			err := e.EncodeElement(gain, xml.StartElement{Name: xml.Name{Local: fmt.Sprintf("in_ch_%d", j)}})
+			if err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(out.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes the channel mapping, in the order of the channels.
+func (m *ChannelMapping) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var mapping struct {
+		Outputs []struct {
+			Inputs []struct {
+				Gain string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	}
+	if err := d.DecodeElement(&mapping, &start); err != nil {
+		return err
+	}
+	*m = make(ChannelMapping, len(mapping.Outputs))
+	for i, output := range mapping.Outputs {
+		for _, input := range output.Inputs {
+			(*m)[i] = append((*m)[i], input.Gain)
+		}
+	}
+	return nil
+}
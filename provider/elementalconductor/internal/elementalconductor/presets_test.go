@@ -0,0 +1,53 @@
+package elementalconductor
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPresetMarshalXMLExcludedStreams(t *testing.T) {
+	preset := Preset{
+		Name:         "audio_only",
+		Container:    "mp4",
+		Width:        "1280",
+		AudioCodec:   "aac",
+		AudioBitrate: "128000",
+		ExcludeVideo: true,
+	}
+	data, err := xml.Marshal(preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "video_description") {
+		t.Errorf("excluded video description encoded: %s", data)
+	}
+	if !strings.Contains(string(data), "<audio_description><codec>aac</codec>") {
+		t.Errorf("audio description not encoded: %s", data)
+	}
+}
+
+func TestChannelMappingRoundTrip(t *testing.T) {
+	settings := RemixSettings{
+		ChannelsIn:     "2",
+		ChannelsOut:    "1",
+		ChannelMapping: ChannelMapping{{"-3", "-3"}},
+	}
+	data, err := xml.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<RemixSettings><channels_in>2</channels_in><channels_out>1</channels_out><channel_mapping><out_ch_0><in_ch_0>-3</in_ch_0><in_ch_1>-3</in_ch_1></out_ch_0></channel_mapping></RemixSettings>"
+	if string(data) != expected {
+		t.Errorf("wrong XML\nwant %s\ngot  %s", expected, data)
+	}
+	var decoded RemixSettings
+	err = xml.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, settings) {
+		t.Errorf("wrong remix settings after decoding\nwant %#v\ngot  %#v", settings, decoded)
+	}
+}
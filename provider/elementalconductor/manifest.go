@@ -0,0 +1,40 @@
+package elementalconductor
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+// manifestFileName is the name of the manifest of the outputs of jobs,
+// written to the destination of the job along with the outputs.
+const manifestFileName = "manifest.json"
+
+// WriteManifest writes the manifest of the outputs of the given job to its
+// destination, with the same ACL as the outputs.
+func (p *elementalConductorProvider) WriteManifest(ctx context.Context, job *db.Job, manifest []byte) (string, error) {
+	uri := p.getOutputDestination(job) + "/" + manifestFileName
+	err := p.objects.Put(ctx, uri, manifest, "application/json", job.OutputACL)
+	if err != nil {
+		return "", err
+	}
+	return uri, nil
+}
+
+// validateManifestDestination ensures that the manifest of the outputs of
+// jobs asking for one can be written to the destination, which must be in S3
+// or an HTTP server.
+func (p *elementalConductorProvider) validateManifestDestination(job *db.Job) error {
+	if !job.WriteManifest {
+		return nil
+	}
+	destination := p.getOutputDestination(job)
+	u, err := url.Parse(destination)
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "http" && u.Scheme != "https") {
+		return provider.InvalidJobError(fmt.Sprintf("unable to write the manifest of the outputs to %s: only s3 and http destinations are supported", destination))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package elementalconductor
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+type writtenObject struct {
+	data        string
+	contentType string
+	acl         string
+}
+
+type fakeObjectWriter struct {
+	fakeObjectStore
+	written map[string]writtenObject
+}
+
+func (w *fakeObjectWriter) Put(ctx context.Context, uri string, data []byte, contentType, acl string) error {
+	w.written[uri] = writtenObject{data: string(data), contentType: contentType, acl: acl}
+	return nil
+}
+
+func TestElementalWriteManifest(t *testing.T) {
+	store := fakeObjectWriter{written: make(map[string]writtenObject)}
+	prov := elementalConductorProvider{
+		client:  &fakeElementalConductorClient{},
+		config:  &config.ElementalConductor{Destination: "s3://destination/outputs/"},
+		objects: &store,
+	}
+	location, err := prov.WriteManifest(context.Background(), &db.Job{ID: "job-1", OutputACL: "public-read"}, []byte(`{"jobId":"job-1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLocation := "s3://destination/outputs/job-1/manifest.json"
+	if location != wantLocation {
+		t.Errorf("wrong manifest location. Want %q. Got %q", wantLocation, location)
+	}
+	want := writtenObject{data: `{"jobId":"job-1"}`, contentType: "application/json", acl: "public-read"}
+	if got := store.written[wantLocation]; got != want {
+		t.Errorf("wrong manifest written\nwant %#v\ngot  %#v", want, got)
+	}
+}
+
+func TestElementalNewJobManifestDestination(t *testing.T) {
+	var tests = []struct {
+		givenTestCase    string
+		givenDestination string
+
+		wantErrMsg string
+	}{
+		{"s3 destination", "s3://destination", ""},
+		{"http destination", "https://storage.example.com/outputs", ""},
+		{
+			"gcs destination",
+			"gs://destination",
+			"unable to write the manifest of the outputs to gs://destination/job-1: only s3 and http destinations are supported",
+		},
+	}
+	for _, test := range tests {
+		prov := presignedElementalProvider()
+		prov.config.Destination = test.givenDestination
+		prov.gcs = &gcsCredentials{ClientEmail: "transcoder@video-transcoding.iam.gserviceaccount.com", PrivateKey: fakeGCSPrivateKey}
+		job := presignedJob("s3://some-bucket/video.mov")
+		job.WriteManifest = true
+		_, err := prov.newJob(job)
+		if test.wantErrMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.givenTestCase, err)
+			}
+			continue
+		}
+		if _, ok := err.(provider.InvalidJobError); !ok || err.Error() != test.wantErrMsg {
+			t.Errorf("%s: wrong error returned\nwant provider.InvalidJobError(%q)\ngot  %#v", test.givenTestCase, test.wantErrMsg, err)
+		}
+	}
+}
+
+func TestHTTPObjectStorePut(t *testing.T) {
+	var body, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			t.Errorf("wrong method. Want PUT. Got %s", r.Method)
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		contentType = r.Header.Get("Content-Type")
+		if r.URL.Path == "/forbidden/manifest.json" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	store := newHTTPObjectStore(http.DefaultClient, &config.ElementalConductor{})
+	err := store.Put(context.Background(), server.URL+"/job-1/manifest.json", []byte(`{"jobId":"job-1"}`), "application/json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"jobId":"job-1"}` {
+		t.Errorf("wrong body. Want %q. Got %q", `{"jobId":"job-1"}`, body)
+	}
+	if contentType != "application/json" {
+		t.Errorf("wrong content type. Want %q. Got %q", "application/json", contentType)
+	}
+	err = store.Put(context.Background(), server.URL+"/forbidden/manifest.json", []byte("{}"), "application/json", "")
+	wantErr := `unable to write "` + server.URL + `/forbidden/manifest.json": unexpected status 403`
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("wrong error. Want %q. Got %v", wantErr, err)
+	}
+	body = ""
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = store.Put(ctx, server.URL+"/job-2/manifest.json", []byte(`{"jobId":"job-2"}`), "application/json", "")
+	if err == nil {
+		t.Error("unexpected <nil> error writing with a canceled context")
+	}
+	if body != "" {
+		t.Errorf("object written with a canceled context: %q", body)
+	}
+}
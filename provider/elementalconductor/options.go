@@ -0,0 +1,277 @@
+package elementalconductor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+// The provider options supported by Elemental Conductor are listed below.
+// Options not listed are ignored, and a warning is logged.
+//
+// Job options:
+//
+//   - priority (integer between 0 and 100): priority of the job in the
+//     cluster. Defaults to 50
+//   - emitSingleFile (boolean): whether HLS outputs are written as a single
+//     file with byte-range segments. Defaults to true
+//   - inputBufferMsec (integer between 0 and 60000): amount of the input, in
+//     milliseconds, that the nodes prefetch and buffer ahead of processing,
+//     absorbing stalls of unreliable origins. Zero disables the buffer.
+//     Defaults to the ELEMENTALCONDUCTOR_INPUT_BUFFER_MSEC setting, leaving
+//     the buffer of the nodes untouched when it's unset
+//   - inputGapHandling (fail, black or stretch): how gaps in the input are
+//     handled: failing the job, inserting black frames or repeating the last
+//     frame before the gap. Defaults to fail
+//   - normalizeTimecode (boolean): whether the timecode of the inputs is
+//     reset to start at zero, instead of using the timecode embedded in the
+//     source. Inputs of jobs with segments are always normalized. Defaults
+//     to false
+//   - outputGroupType (auto, file, hls or mss): type of the output group used
+//     for all the outputs of the job, instead of detecting it from the
+//     container of each preset. Presets must use a container supported by
+//     the group: m3u8 for hls, ismv for mss (Microsoft Smooth Streaming) and
+//     any other, except cmaf, for file. Defaults to auto
+//   - skipExistingOutputs (boolean): whether the job is skipped, instead of
+//     submitted, when all its outputs already exist in the destination. The
+//     job is then reported as finished, pointing to the existing files.
+//     Defaults to false
+//   - workingDirectory (absolute path or URI): location used by the nodes as
+//     scratch space while processing the job. Defaults to the location in
+//     the configuration, if any, or to the default location of the nodes
+//
+// Preset options:
+//
+//   - numBFrames (integer between 0 and 7): number of B-frames between
+//     reference frames
+//   - slices (integer between 1 and 32): number of slices per picture
+//   - audioSampleRate (integer between 8000 and 96000): sample rate of the
+//     AAC audio, in Hz
+//   - frameRateConversion (duplicate-drop or interpolate): how frames are
+//     converted when the frame rate of the source is higher than the maximum
+//     frame rate of the preset, either duplicating and dropping frames or
+//     interpolating them. Requires video.maxFrameRate. Defaults to the
+//     conversion of Elemental Conductor
+func applyJobOptions(job *elementalconductor.Job, options map[string]interface{}) error {
+	for _, key := range sortedKeys(options) {
+		value := options[key]
+		switch key {
+		case "priority":
+			priority, err := intOption(key, value, 0, 100)
+			if err != nil {
+				return err
+			}
+			job.Priority = priority
+		case "emitSingleFile":
+			emitSingleFile, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("invalid provider option %q: must be a boolean", key)
+			}
+			for _, outputGroup := range job.OutputGroup {
+				if outputGroup.AppleLiveGroupSettings != nil {
+					outputGroup.AppleLiveGroupSettings.EmitSingleFile = emitSingleFile
+				}
+			}
+		case "inputBufferMsec":
+			bufferMsec, err := intOptionString(key, value, 0, maxInputBufferMsec)
+			if err != nil {
+				return err
+			}
+			for i := range job.Input {
+				job.Input[i].BufferMsec = bufferMsec
+			}
+		case "inputGapHandling":
+			behavior, err := inputLossBehavior(key, value)
+			if err != nil {
+				return err
+			}
+			for i := range job.Input {
+				job.Input[i].InputLossBehavior = behavior
+			}
+		case "normalizeTimecode":
+			normalize, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("invalid provider option %q: must be a boolean", key)
+			}
+			if normalize {
+				for i := range job.Input {
+					job.Input[i].TimecodeSource = elementalconductor.ZeroBasedTimecodeSource
+				}
+			}
+		case outputGroupTypeKey:
+			// applied when building the output groups of the job.
+		case skipExistingOutputsKey:
+			if _, ok := value.(bool); !ok {
+				return fmt.Errorf("invalid provider option %q: must be a boolean", key)
+			}
+		case "workingDirectory":
+			dir, _ := value.(string)
+			err := validateWorkingDirectory(dir)
+			if err != nil {
+				return fmt.Errorf("invalid provider option %q: %s", key, err)
+			}
+			job.WorkingDir = dir
+		default:
+			log.Printf("elementalconductor: ignoring unknown job option %q", key)
+		}
+	}
+	return nil
+}
+
+func applyPresetOptions(preset *elementalconductor.Preset, options map[string]interface{}) error {
+	for _, key := range sortedKeys(options) {
+		value := options[key]
+		var err error
+		switch key {
+		case "numBFrames":
+			preset.NumBFrames, err = intOptionString(key, value, 0, 7)
+		case "slices":
+			preset.Slices, err = intOptionString(key, value, 1, 32)
+		case "audioSampleRate":
+			preset.SampleRate, err = intOptionString(key, value, 8000, 96000)
+		case "frameRateConversion":
+			err = applyFrameRateConversion(preset, key, value)
+		default:
+			log.Printf("elementalconductor: ignoring unknown preset option %q", key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWorkingDirectory ensures that the given working directory is either
+// an absolute path or a URI with a scheme and a host.
+func validateWorkingDirectory(dir string) error {
+	u, err := url.Parse(dir)
+	if err != nil || dir == "" {
+		return errors.New("must be an absolute path or a URI")
+	}
+	if u.Scheme == "" {
+		if !path.IsAbs(dir) {
+			return errors.New("must be an absolute path or a URI")
+		}
+		return nil
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host in URI %q", dir)
+	}
+	return nil
+}
+
+const outputGroupTypeKey = "outputGroupType"
+
+var outputGroupTypes = map[string]elementalconductor.OutputGroupType{
+	"auto": "",
+	"file": elementalconductor.FileOutputGroupType,
+	"hls":  elementalconductor.AppleLiveOutputGroupType,
+	"mss":  elementalconductor.MSSmoothOutputGroupType,
+}
+
+// outputGroupType returns the output group type requested in the given job
+// options, or an empty type when it should be detected from the container of
+// each preset.
+func outputGroupType(options map[string]interface{}) (elementalconductor.OutputGroupType, error) {
+	value, ok := options[outputGroupTypeKey]
+	if !ok {
+		return "", nil
+	}
+	name, _ := value.(string)
+	groupType, ok := outputGroupTypes[name]
+	if !ok {
+		return "", fmt.Errorf("invalid provider option %q: must be one of auto, file, hls or mss", outputGroupTypeKey)
+	}
+	return groupType, nil
+}
+
+// supportsContainer reports whether outputs of the given container may be
+// placed in output groups of the given type.
+func supportsContainer(groupType elementalconductor.OutputGroupType, container string) bool {
+	switch groupType {
+	case elementalconductor.AppleLiveOutputGroupType:
+		return container == string(elementalconductor.AppleHTTPLiveStreaming)
+	case elementalconductor.MSSmoothOutputGroupType:
+		return container == string(elementalconductor.MSSmooth)
+	}
+	switch elementalconductor.Container(container) {
+	case elementalconductor.AppleHTTPLiveStreaming, elementalconductor.MSSmooth, elementalconductor.CMAF:
+		return false
+	}
+	return true
+}
+
+// maxInputLossMsec is the longest duration, in milliseconds, that Elemental
+// Conductor is able to fill input gaps for.
+const maxInputLossMsec = "1000000"
+
+var frameRateConversionAlgorithms = map[string]string{
+	"duplicate-drop": "false",
+	"interpolate":    "true",
+}
+
+func applyFrameRateConversion(preset *elementalconductor.Preset, key string, value interface{}) error {
+	algorithm, _ := value.(string)
+	interpolated, ok := frameRateConversionAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("invalid provider option %q: must be one of duplicate-drop or interpolate", key)
+	}
+	if preset.FrameRateConversion == nil {
+		return fmt.Errorf("invalid provider option %q: requires video.maxFrameRate", key)
+	}
+	preset.FrameRateConversion.Interpolated = interpolated
+	return nil
+}
+
+func inputLossBehavior(key string, value interface{}) (*elementalconductor.InputLossBehavior, error) {
+	handling, _ := value.(string)
+	switch handling {
+	case "fail":
+		return nil, nil
+	case "black":
+		return &elementalconductor.InputLossBehavior{
+			RepeatFrameMsec:     "0",
+			BlackFrameMsec:      maxInputLossMsec,
+			InputLossImageType:  "color",
+			InputLossImageColor: "000000",
+		}, nil
+	case "stretch":
+		return &elementalconductor.InputLossBehavior{
+			RepeatFrameMsec: maxInputLossMsec,
+			BlackFrameMsec:  "0",
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid provider option %q: must be one of fail, black or stretch", key)
+}
+
+func intOption(key string, value interface{}, min, max int) (int, error) {
+	n, ok := value.(float64)
+	if !ok || n != math.Trunc(n) || n < float64(min) || n > float64(max) {
+		return 0, fmt.Errorf("invalid provider option %q: must be an integer between %d and %d", key, min, max)
+	}
+	return int(n), nil
+}
+
+func intOptionString(key string, value interface{}, min, max int) (string, error) {
+	n, err := intOption(key, value, min, max)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(n), nil
+}
+
+func sortedKeys(options map[string]interface{}) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,808 @@
+package elementalconductor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+func TestElementalNewJobProviderOptions(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{
+			Protocol:         "hls",
+			SegmentDuration:  3,
+			PlaylistFileName: "hls/index.m3u8",
+		},
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_720p",
+					ProviderMapping: map[string]string{Name: "hls_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			},
+		},
+		ProviderOptions: map[string]interface{}{
+			"priority":       float64(80),
+			"emitSingleFile": false,
+			"encoderType":    "gpu",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newJob.Priority != 80 {
+		t.Errorf("wrong job priority. Want 80. Got %d", newJob.Priority)
+	}
+	settings := newJob.OutputGroup[0].AppleLiveGroupSettings
+	if settings == nil {
+		t.Fatalf("unexpected output group: %#v", newJob.OutputGroup[0])
+	}
+	if settings.EmitSingleFile {
+		t.Error("unexpected emit single file in HLS output group")
+	}
+	if want := `ignoring unknown job option "encoderType"`; !strings.Contains(logs.String(), want) {
+		t.Errorf("missing warning for unknown option\nwant %q\ngot  %q", want, logs.String())
+	}
+}
+
+func TestElementalNewJobInvalidProviderOptions(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenOptions  map[string]interface{}
+		wantErr       string
+	}{
+		{
+			"priority as string",
+			map[string]interface{}{"priority": "high"},
+			`invalid provider option "priority": must be an integer between 0 and 100`,
+		},
+		{
+			"priority out of range",
+			map[string]interface{}{"priority": float64(101)},
+			`invalid provider option "priority": must be an integer between 0 and 100`,
+		},
+		{
+			"fractional priority",
+			map[string]interface{}{"priority": 10.5},
+			`invalid provider option "priority": must be an integer between 0 and 100`,
+		},
+		{
+			"emitSingleFile as string",
+			map[string]interface{}{"emitSingleFile": "false"},
+			`invalid provider option "emitSingleFile": must be a boolean`,
+		},
+		{
+			"unknown input gap handling",
+			map[string]interface{}{"inputGapHandling": "slate"},
+			`invalid provider option "inputGapHandling": must be one of fail, black or stretch`,
+		},
+		{
+			"normalizeTimecode as string",
+			map[string]interface{}{"normalizeTimecode": "true"},
+			`invalid provider option "normalizeTimecode": must be a boolean`,
+		},
+		{
+			"skipExistingOutputs as string",
+			map[string]interface{}{"skipExistingOutputs": "true"},
+			`invalid provider option "skipExistingOutputs": must be a boolean`,
+		},
+		{
+			"relative working directory",
+			map[string]interface{}{"workingDirectory": "scratch/jobs"},
+			`invalid provider option "workingDirectory": must be an absolute path or a URI`,
+		},
+		{
+			"working directory without host",
+			map[string]interface{}{"workingDirectory": "s3:///scratch"},
+			`invalid provider option "workingDirectory": missing host in URI "s3:///scratch"`,
+		},
+		{
+			"negative input buffer",
+			map[string]interface{}{"inputBufferMsec": float64(-1)},
+			`invalid provider option "inputBufferMsec": must be an integer between 0 and 60000`,
+		},
+		{
+			"input buffer too large",
+			map[string]interface{}{"inputBufferMsec": float64(60001)},
+			`invalid provider option "inputBufferMsec": must be an integer between 0 and 60000`,
+		},
+		{
+			"fractional input buffer",
+			map[string]interface{}{"inputBufferMsec": 1500.5},
+			`invalid provider option "inputBufferMsec": must be an integer between 0 and 60000`,
+		},
+		{
+			"working directory as number",
+			map[string]interface{}{"workingDirectory": 42},
+			`invalid provider option "workingDirectory": must be an absolute path or a URI`,
+		},
+		{
+			"unknown output group type",
+			map[string]interface{}{"outputGroupType": "dash"},
+			`invalid provider option "outputGroupType": must be one of auto, file, hls or mss`,
+		},
+		{
+			"output group type not supported by the preset",
+			map[string]interface{}{"outputGroupType": "mss"},
+			`preset "mp4_720p" uses the container "mp4", which is not supported by the "mss" output group type`,
+		},
+	}
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	for _, test := range tests {
+		_, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+			ProviderOptions: test.givenOptions,
+		})
+		if _, ok := err.(provider.InvalidJobError); !ok {
+			t.Errorf("%s: wrong error type. Want provider.InvalidJobError. Got %#v", test.givenTestCase, err)
+			continue
+		}
+		if err.Error() != test.wantErr {
+			t.Errorf("%s: wrong error message\nwant %q\ngot  %q", test.givenTestCase, test.wantErr, err.Error())
+		}
+	}
+}
+
+func TestElementalNewJobInputBuffer(t *testing.T) {
+	defaultBuffer := uint(5000)
+	var tests = []struct {
+		givenTestCase string
+		givenOptions  map[string]interface{}
+		givenDefault  *uint
+		wantBuffer    string
+	}{
+		{"configured", map[string]interface{}{"inputBufferMsec": float64(10000)}, nil, "<buffer_msec>10000</buffer_msec>"},
+		{"disabled", map[string]interface{}{"inputBufferMsec": float64(0)}, nil, "<buffer_msec>0</buffer_msec>"},
+		{"configured over the default", map[string]interface{}{"inputBufferMsec": float64(10000)}, &defaultBuffer, "<buffer_msec>10000</buffer_msec>"},
+		{"default", nil, &defaultBuffer, "<buffer_msec>5000</buffer_msec>"},
+		{"not configured", nil, nil, ""},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{Destination: "s3://destination", InputBufferMsec: test.givenDefault},
+		}
+		newJob, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+			ProviderOptions: test.givenOptions,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(newJob.Input[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.wantBuffer == "" {
+			if strings.Contains(string(data), "buffer_msec") {
+				t.Errorf("%s: unexpected input buffer in %s", test.givenTestCase, data)
+			}
+		} else if !strings.Contains(string(data), test.wantBuffer) {
+			t.Errorf("%s: input buffer not found\nwant %s\ngot  %s", test.givenTestCase, test.wantBuffer, data)
+		}
+	}
+}
+
+func TestElementalNewJobNormalizeTimecode(t *testing.T) {
+	var tests = []struct {
+		givenTestCase  string
+		givenOptions   map[string]interface{}
+		wantNormalized bool
+	}{
+		{"enabled", map[string]interface{}{"normalizeTimecode": true}, true},
+		{"disabled", map[string]interface{}{"normalizeTimecode": false}, false},
+		{"default", nil, false},
+	}
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	for _, test := range tests {
+		newJob, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+			ProviderOptions: test.givenOptions,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		data, err := xml.Marshal(newJob.Input[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		normalized := strings.Contains(string(data), "<timecode_source>zerobased</timecode_source>")
+		if normalized != test.wantNormalized {
+			t.Errorf("%s: wrong timecode source settings. Want normalized=%v. Got %s", test.givenTestCase, test.wantNormalized, data)
+		}
+	}
+}
+
+func TestElementalNewJobWorkingDirectory(t *testing.T) {
+	var tests = []struct {
+		givenTestCase  string
+		givenConfigDir string
+		givenOptions   map[string]interface{}
+		wantWorkingDir string
+	}{
+		{"default", "", nil, ""},
+		{"from the configuration", "/mnt/scratch", nil, "/mnt/scratch"},
+		{"from the job", "", map[string]interface{}{"workingDirectory": "s3://scratch-bucket/jobs"}, "s3://scratch-bucket/jobs"},
+		{"job overriding the configuration", "/mnt/scratch", map[string]interface{}{"workingDirectory": "/mnt/large-scratch"}, "/mnt/large-scratch"},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{Destination: "s3://destination", WorkingDirectory: test.givenConfigDir},
+		}
+		newJob, err := prov.newJob(&db.Job{
+			ID:          "job-1",
+			SourceMedia: "http://some.nice/video.mov",
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+			ProviderOptions: test.givenOptions,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if newJob.WorkingDir != test.wantWorkingDir {
+			t.Errorf("%s: wrong working directory. Want %q. Got %q", test.givenTestCase, test.wantWorkingDir, newJob.WorkingDir)
+		}
+	}
+}
+
+func TestElementalFactoryInvalidWorkingDirectory(t *testing.T) {
+	cfg := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:             "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:        "myuser",
+			APIKey:           "elemental-api-key",
+			AuthExpires:      30,
+			WorkingDirectory: "scratch",
+		},
+	}
+	prov, err := elementalConductorFactory(&cfg)
+	if prov != nil {
+		t.Errorf("unexpected non-nil provider: %#v", prov)
+	}
+	expectedErr := provider.InvalidConfigError("invalid ELEMENTALCONDUCTOR_WORKING_DIRECTORY: must be an absolute path or a URI")
+	if err != expectedErr {
+		t.Errorf("wrong error returned. Want %#v. Got %#v", expectedErr, err)
+	}
+}
+
+func TestElementalFactoryInvalidInputBuffer(t *testing.T) {
+	bufferMsec := uint(60001)
+	cfg := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			InputBufferMsec: &bufferMsec,
+		},
+	}
+	prov, err := elementalConductorFactory(&cfg)
+	if prov != nil {
+		t.Errorf("unexpected non-nil provider: %#v", prov)
+	}
+	expectedErr := provider.InvalidConfigError("invalid ELEMENTALCONDUCTOR_INPUT_BUFFER_MSEC: must be an integer between 0 and 60000")
+	if err != expectedErr {
+		t.Errorf("wrong error returned. Want %#v. Got %#v", expectedErr, err)
+	}
+}
+
+func TestElementalNewJobOutputGroupType(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	presetProvider.client.(*fakeElementalConductorClient).presets = []elementalconductor.Preset{
+		{Href: "/presets/12", Name: "mss_720p", Container: string(elementalconductor.MSSmooth)},
+		{Href: "/presets/34", Name: "mss_1080p", Container: string(elementalconductor.MSSmooth)},
+	}
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{
+			SegmentDuration:  2,
+			PlaylistFileName: "mss/index.ism",
+		},
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.ismv",
+				Preset: db.PresetMap{
+					Name:            "mss_720p",
+					ProviderMapping: map[string]string{Name: "mss_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "ismv"},
+				},
+			},
+			{
+				FileName: "output_1080p.ismv",
+				Preset: db.PresetMap{
+					Name:            "mss_1080p",
+					ProviderMapping: map[string]string{Name: "mss_1080p"},
+					OutputOpts:      db.OutputOptions{Extension: "ismv"},
+				},
+			},
+		},
+		ProviderOptions: map[string]interface{}{"outputGroupType": "mss"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOutputGroup := []elementalconductor.OutputGroup{
+		{
+			Order: 1,
+			MSSmoothGroupSettings: &elementalconductor.MSSmoothGroupSettings{
+				Destination: &elementalconductor.Location{
+					URI:      "s3://destination/job-1/mss/index",
+					Username: "aws-access-key",
+					Password: "aws-secret-key",
+				},
+				FragmentLength: 2,
+			},
+			Type: elementalconductor.MSSmoothOutputGroupType,
+			Output: []elementalconductor.Output{
+				{
+					StreamAssemblyName: "stream_0",
+					NameModifier:       "_0000000001",
+					Order:              1,
+					Container:          elementalconductor.MSSmooth,
+				},
+				{
+					StreamAssemblyName: "stream_1",
+					NameModifier:       "_0000000002",
+					Order:              2,
+					Container:          elementalconductor.MSSmooth,
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(newJob.OutputGroup, expectedOutputGroup) {
+		t.Errorf("wrong output groups\nwant %#v\ngot  %#v", expectedOutputGroup, newJob.OutputGroup)
+	}
+	files := presetProvider.getOutputFiles(newJob)
+	expectedFiles := []provider.OutputFile{{Path: "s3://destination/job-1/mss/index.ism", Container: "ism"}}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("wrong output files\nwant %#v\ngot  %#v", expectedFiles, files)
+	}
+}
+
+func TestElementalNewJobInputGapHandling(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenHandling interface{}
+		wantXML       string
+	}{
+		{
+			"black frames",
+			"black",
+			"<input_loss_behavior><repeat_frame_msec>0</repeat_frame_msec><black_frame_msec>1000000</black_frame_msec>" +
+				"<input_loss_image_type>color</input_loss_image_type><input_loss_image_color>000000</input_loss_image_color></input_loss_behavior>",
+		},
+		{
+			"repeated frames",
+			"stretch",
+			"<input_loss_behavior><repeat_frame_msec>1000000</repeat_frame_msec><black_frame_msec>0</black_frame_msec></input_loss_behavior>",
+		},
+		{
+			"failure",
+			"fail",
+			"",
+		},
+		{
+			"default",
+			nil,
+			"",
+		},
+	}
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{Destination: "s3://destination"},
+	}
+	for _, test := range tests {
+		options := map[string]interface{}{}
+		if test.givenHandling != nil {
+			options["inputGapHandling"] = test.givenHandling
+		}
+		newJob, err := prov.newJob(&db.Job{
+			ID: "job-1",
+			SourceSegments: []db.SourceSegment{
+				{URI: "http://some.nice/part1.mov"},
+				{URI: "http://some.nice/part2.mov"},
+			},
+			SourceMedia: "http://some.nice/part1.mov",
+			Outputs: []db.TranscodeOutput{
+				{
+					FileName: "output_720p.mp4",
+					Preset: db.PresetMap{
+						Name:            "mp4_720p",
+						ProviderMapping: map[string]string{Name: "mp4_720p"},
+						OutputOpts:      db.OutputOptions{Extension: "mp4"},
+					},
+				},
+			},
+			ProviderOptions: options,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if len(newJob.Input) != 2 {
+			t.Fatalf("%s: wrong number of inputs. Want 2. Got %d", test.givenTestCase, len(newJob.Input))
+		}
+		for i, input := range newJob.Input {
+			data, err := xml.Marshal(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wantXML == "" {
+				if strings.Contains(string(data), "input_loss_behavior") {
+					t.Errorf("%s: unexpected gap handling settings in input %d: %s", test.givenTestCase, i, data)
+				}
+			} else if !strings.Contains(string(data), test.wantXML) {
+				t.Errorf("%s: wrong gap handling settings in input %d\nwant %s\ngot  %s", test.givenTestCase, i, test.wantXML, data)
+			}
+		}
+	}
+}
+
+func TestCreatePresetProviderOptions(t *testing.T) {
+	prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	_, err := prov.CreatePreset(db.Preset{
+		Name:      "mp4_1080p",
+		Container: "mp4",
+		Video: db.VideoPreset{
+			Codec:   "h264",
+			Bitrate: "3500000",
+		},
+		Audio: db.AudioPreset{
+			Codec:   "aac",
+			Bitrate: "64000",
+		},
+		ProviderOptions: map[string]interface{}{
+			"numBFrames":      float64(3),
+			"slices":          float64(4),
+			"audioSampleRate": float64(48000),
+			"lookahead":       "high",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prov.client.(*fakeElementalConductorClient)
+	data, err := xml.Marshal(client.presets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<h264_settings><bitrate>3500000</bitrate><gop_num_b_frames>3</gop_num_b_frames><slices>4</slices></h264_settings>",
+		"<aac_settings><bitrate>64000</bitrate><sample_rate>48000</sample_rate></aac_settings>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("wrong preset generated\nwant %s\ngot  %s", want, data)
+		}
+	}
+	if want := `ignoring unknown preset option "lookahead"`; !strings.Contains(logs.String(), want) {
+		t.Errorf("missing warning for unknown option\nwant %q\ngot  %q", want, logs.String())
+	}
+}
+
+func TestCreatePresetInvalidProviderOptions(t *testing.T) {
+	prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+	_, err := prov.CreatePreset(db.Preset{
+		Name:            "mp4_1080p",
+		Container:       "mp4",
+		ProviderOptions: map[string]interface{}{"slices": float64(0)},
+	})
+	want := `invalid provider option "slices": must be an integer between 1 and 32`
+	if err == nil || err.Error() != want {
+		t.Errorf("wrong error returned\nwant %q\ngot  %v", want, err)
+	}
+	if client := prov.client.(*fakeElementalConductorClient); len(client.presets) != 0 {
+		t.Errorf("unexpected presets created: %#v", client.presets)
+	}
+}
+
+func TestCreatePresetFrameRateConversion(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenOptions  map[string]interface{}
+		wantSettings  string
+	}{
+		{
+			"interpolate",
+			map[string]interface{}{"frameRateConversion": "interpolate"},
+			"<frame_rate_conversion><framerate_numerator>24000</framerate_numerator><framerate_denominator>1001</framerate_denominator><decrease_only>true</decrease_only><interpolated>true</interpolated></frame_rate_conversion>",
+		},
+		{
+			"duplicate-drop",
+			map[string]interface{}{"frameRateConversion": "duplicate-drop"},
+			"<frame_rate_conversion><framerate_numerator>24000</framerate_numerator><framerate_denominator>1001</framerate_denominator><decrease_only>true</decrease_only><interpolated>false</interpolated></frame_rate_conversion>",
+		},
+		{
+			"default",
+			nil,
+			"<frame_rate_conversion><framerate_numerator>24000</framerate_numerator><framerate_denominator>1001</framerate_denominator><decrease_only>true</decrease_only></frame_rate_conversion>",
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{
+			Name:            "mp4_1080p",
+			Container:       "mp4",
+			Video:           db.VideoPreset{Codec: "h264", MaxFrameRate: "24000/1001"},
+			ProviderOptions: test.givenOptions,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		client := prov.client.(*fakeElementalConductorClient)
+		data, err := xml.Marshal(client.presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantSettings) {
+			t.Errorf("%s: wrong frame rate conversion\nwant %s\ngot  %s", test.givenTestCase, test.wantSettings, data)
+		}
+	}
+}
+
+func TestCreatePresetProfileLevel(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenVideo    db.VideoPreset
+		wantSettings  string
+	}{
+		{
+			"h264 baseline 3.0",
+			db.VideoPreset{Codec: "h264", Profile: "baseline", ProfileLevel: "3"},
+			"<profile>Baseline</profile><level>3</level>",
+		},
+		{
+			"h264 high 4.1",
+			db.VideoPreset{Codec: "h264", Profile: "High", ProfileLevel: "4.1"},
+			"<profile>High</profile><level>4.1</level>",
+		},
+		{
+			"h265 main10 5.1",
+			db.VideoPreset{Codec: "h265", Profile: "main10", ProfileLevel: "5.1"},
+			"<h265_settings><profile>Main10</profile><level>5.1</level></h265_settings>",
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{
+			Name:      "mp4_1080p",
+			Container: "mp4",
+			Video:     test.givenVideo,
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		client := prov.client.(*fakeElementalConductorClient)
+		data, err := xml.Marshal(client.presets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantSettings) {
+			t.Errorf("%s: wrong profile settings\nwant %s\ngot  %s", test.givenTestCase, test.wantSettings, data)
+		}
+	}
+}
+
+func TestCreatePresetInvalidFrameRateConversion(t *testing.T) {
+	var tests = []struct {
+		givenTestCase     string
+		givenMaxFrameRate string
+		givenValue        interface{}
+		wantErr           string
+	}{
+		{
+			"unknown algorithm",
+			"30",
+			"blend",
+			`invalid provider option "frameRateConversion": must be one of duplicate-drop or interpolate`,
+		},
+		{
+			"algorithm as boolean",
+			"30",
+			true,
+			`invalid provider option "frameRateConversion": must be one of duplicate-drop or interpolate`,
+		},
+		{
+			"missing max frame rate",
+			"",
+			"interpolate",
+			`invalid provider option "frameRateConversion": requires video.maxFrameRate`,
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{client: &fakeElementalConductorClient{}}
+		_, err := prov.CreatePreset(db.Preset{
+			Name:            "mp4_1080p",
+			Container:       "mp4",
+			Video:           db.VideoPreset{Codec: "h264", MaxFrameRate: test.givenMaxFrameRate},
+			ProviderOptions: map[string]interface{}{"frameRateConversion": test.givenValue},
+		})
+		if err == nil || err.Error() != test.wantErr {
+			t.Errorf("%s: wrong error returned\nwant %q\ngot  %v", test.givenTestCase, test.wantErr, err)
+		}
+	}
+}
+
+func TestCreatePresetIncludeStreams(t *testing.T) {
+	prov, err := fakeElementalConductorFactory(&config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+			Destination: "s3://destination",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	disabled := false
+	for _, preset := range []db.Preset{
+		{
+			Name:         "mp4_video_only",
+			Container:    "mp4",
+			Video:        db.VideoPreset{Codec: "h264", Bitrate: "3500000"},
+			Audio:        db.AudioPreset{Codec: "aac", Bitrate: "64000"},
+			IncludeAudio: &disabled,
+		},
+		{
+			Name:         "mp4_audio_only",
+			Container:    "mp4",
+			Video:        db.VideoPreset{Codec: "h264", Bitrate: "3500000"},
+			Audio:        db.AudioPreset{Codec: "aac", Bitrate: "64000"},
+			IncludeVideo: &disabled,
+		},
+	} {
+		_, err = presetProvider.CreatePreset(preset)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := presetProvider.client.(*fakeElementalConductorClient)
+	for i, test := range []struct {
+		wantElement   string
+		unwantElement string
+	}{
+		{"<video_description>", "<audio_description>"},
+		{"<audio_description>", "<video_description>"},
+	} {
+		data, err := xml.Marshal(client.presets[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), test.wantElement) || strings.Contains(string(data), test.unwantElement) {
+			t.Errorf("wrong preset %q: want %s without %s. Got %s", client.presets[i].Name, test.wantElement, test.unwantElement, data)
+		}
+	}
+
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "video.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_video_only",
+					ProviderMapping: map[string]string{Name: "mp4_video_only"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+			{
+				FileName: "audio.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_audio_only",
+					ProviderMapping: map[string]string{Name: "mp4_audio_only"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var presets []string
+	for _, streamAssembly := range newJob.StreamAssembly {
+		presets = append(presets, streamAssembly.Preset)
+	}
+	if want := []string{"mp4_video_only", "mp4_audio_only"}; !reflect.DeepEqual(presets, want) {
+		t.Errorf("wrong presets in the stream assemblies. Want %#v. Got %#v", want, presets)
+	}
+}
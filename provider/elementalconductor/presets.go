@@ -0,0 +1,82 @@
+package elementalconductor
+
+import (
+	"context"
+	"log"
+
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+// presetIndex indexes the presets available in Elemental Conductor by id and
+// by name, so preset mappings may reference presets using either of them.
+type presetIndex struct {
+	byID   map[string]*elementalconductor.Preset
+	byName map[string]*elementalconductor.Preset
+}
+
+func (p *elementalConductorProvider) loadPresetIndex() (*presetIndex, error) {
+	list, err := p.client.GetPresets()
+	if err != nil {
+		return nil, err
+	}
+	index := presetIndex{
+		byID:   make(map[string]*elementalconductor.Preset),
+		byName: make(map[string]*elementalconductor.Preset),
+	}
+	if list == nil {
+		return &index, nil
+	}
+	for i := range list.Presets {
+		preset := &list.Presets[i]
+		if id := preset.GetID(); id != "" {
+			index.byID[id] = preset
+		}
+		if preset.Name != "" {
+			index.byName[preset.Name] = preset
+		}
+	}
+	return &index, nil
+}
+
+// resolvePreset returns the preset referenced by the given mapping, along with
+// the stream assembly using it. Names take precedence over ids, as presets
+// created by the API are mapped by name. Mappings that look like neither are
+// logged and sent as they are, leaving it to Elemental Conductor to resolve
+// them.
+func (p *elementalConductorProvider) resolvePreset(index *presetIndex, mapping string) (*elementalconductor.Preset, elementalconductor.StreamAssembly, error) {
+	if preset, ok := index.byName[mapping]; ok {
+		return preset, elementalconductor.StreamAssembly{Preset: mapping}, nil
+	}
+	if preset, ok := index.byID[mapping]; ok {
+		return preset, elementalconductor.StreamAssembly{PresetID: mapping}, nil
+	}
+	log.Printf("elementalconductor: preset mapping %q doesn't match the id or the name of any preset", mapping)
+	preset, err := p.client.GetPreset(mapping)
+	if err != nil {
+		return nil, elementalconductor.StreamAssembly{}, err
+	}
+	return preset, elementalconductor.StreamAssembly{Preset: mapping}, nil
+}
+
+// ListPresets returns the presets defined in Elemental Conductor.
+func (p *elementalConductorProvider) ListPresets(ctx context.Context) ([]provider.PresetSummary, error) {
+	p = p.withContext(ctx)
+	list, err := p.client.GetPresets()
+	if err != nil {
+		return nil, err
+	}
+	presets := []provider.PresetSummary{}
+	if list == nil {
+		return presets, nil
+	}
+	for _, preset := range list.Presets {
+		presets = append(presets, provider.PresetSummary{
+			ID:          preset.GetID(),
+			Name:        preset.Name,
+			Description: preset.Description,
+			Container:   preset.Container,
+		})
+	}
+	return presets, nil
+}
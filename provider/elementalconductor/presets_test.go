@@ -0,0 +1,134 @@
+package elementalconductor
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+func TestElementalNewJobPresetMappingByNameOrID(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:            "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:       "myuser",
+			APIKey:          "elemental-api-key",
+			AuthExpires:     30,
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	presetProvider.client.(*fakeElementalConductorClient).presets = []elementalconductor.Preset{
+		{Href: "/presets/12", Name: "Web 720p", Container: string(elementalconductor.MPEG4)},
+		{Href: "/presets/34", Name: "HLS 1080p", Container: string(elementalconductor.AppleHTTPLiveStreaming)},
+	}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	newJob, err := presetProvider.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		StreamingParams: db.StreamingParams{
+			Protocol:         "hls",
+			SegmentDuration:  3,
+			PlaylistFileName: "hls/index.m3u8",
+		},
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "Web 720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+			{
+				FileName: "output_1080p.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_1080p",
+					ProviderMapping: map[string]string{Name: "34"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			},
+			{
+				FileName: "output_360p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_360p",
+					ProviderMapping: map[string]string{Name: "mp4_360p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStreamAssembly := []elementalconductor.StreamAssembly{
+		{Name: "stream_0", Preset: "Web 720p"},
+		{Name: "stream_1", PresetID: "34"},
+		{Name: "stream_2", Preset: "mp4_360p"},
+	}
+	if !reflect.DeepEqual(newJob.StreamAssembly, expectedStreamAssembly) {
+		t.Errorf("wrong stream assemblies\nwant %#v\ngot  %#v", expectedStreamAssembly, newJob.StreamAssembly)
+	}
+	if len(newJob.OutputGroup) != 3 {
+		t.Fatalf("wrong number of output groups. Want 3. Got %d", len(newJob.OutputGroup))
+	}
+	if newJob.OutputGroup[2].Type != elementalconductor.AppleLiveOutputGroupType {
+		t.Errorf("wrong type for the output group of the preset mapped by id. Want %q. Got %q", elementalconductor.AppleLiveOutputGroupType, newJob.OutputGroup[2].Type)
+	}
+	logged := logs.String()
+	if strings.Contains(logged, `"Web 720p"`) || strings.Contains(logged, `"34"`) {
+		t.Errorf("unexpected warning for known presets: %s", logged)
+	}
+	expectedWarning := `preset mapping "mp4_360p" doesn't match the id or the name of any preset`
+	if !strings.Contains(logged, expectedWarning) {
+		t.Errorf("missing warning for unknown preset mapping. Want %q in:\n%s", expectedWarning, logged)
+	}
+}
+
+func TestListPresets(t *testing.T) {
+	elementalConductorConfig := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:        "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:   "myuser",
+			APIKey:      "elemental-api-key",
+			AuthExpires: 30,
+			Destination: "s3://destination",
+		},
+	}
+	prov, err := fakeElementalConductorFactory(&elementalConductorConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetProvider := prov.(*elementalConductorProvider)
+	presetProvider.client.(*fakeElementalConductorClient).presets = []elementalconductor.Preset{
+		{Href: "/presets/12", Name: "Web 720p", Description: "720p for the web", Container: string(elementalconductor.MPEG4)},
+		{Href: "/presets/34", Name: "HLS 1080p", Container: string(elementalconductor.AppleHTTPLiveStreaming)},
+	}
+	presets, err := presetProvider.ListPresets(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []provider.PresetSummary{
+		{ID: "12", Name: "Web 720p", Description: "720p for the web", Container: "mp4"},
+		{ID: "34", Name: "HLS 1080p", Container: "m3u8"},
+	}
+	if !reflect.DeepEqual(presets, expected) {
+		t.Errorf("wrong presets\nwant %#v\ngot  %#v", expected, presets)
+	}
+}
@@ -0,0 +1,78 @@
+package elementalconductor
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+// presignedDateFormat is the format of the signing date of presigned URLs
+// using AWS Signature Version 4 and of Google Cloud Storage V4 signed URLs.
+const presignedDateFormat = "20060102T150405Z"
+
+// inputLocation returns the location of the given input. Presigned URLs
+// already carry their authentication in the query string, so they're used as
+// is, without credentials, as long as they haven't expired.
+func (p *elementalConductorProvider) inputLocation(uri string) (elementalconductor.Location, error) {
+	presigned, expiration := presignedURLExpiration(uri)
+	if !presigned {
+		return p.sourceLocation(uri)
+	}
+	if !expiration.IsZero() && !time.Now().Before(expiration) {
+		return elementalconductor.Location{}, provider.InvalidJobError(fmt.Sprintf("presigned url %s expired at %s", redactQuery(uri), expiration.UTC().Format(time.RFC3339)))
+	}
+	return elementalconductor.Location{URI: uri}, nil
+}
+
+// presignedURLExpiration reports whether the given URI is a presigned S3 or
+// GCS URL, along with its expiration. The expiration is the zero time when it
+// can't be determined from the query string.
+func presignedURLExpiration(uri string) (bool, time.Time) {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false, time.Time{}
+	}
+	query := u.Query()
+	switch {
+	case query.Get("X-Amz-Signature") != "":
+		return true, signedURLExpiration(query.Get("X-Amz-Date"), query.Get("X-Amz-Expires"))
+	case query.Get("X-Goog-Signature") != "":
+		return true, signedURLExpiration(query.Get("X-Goog-Date"), query.Get("X-Goog-Expires"))
+	case query.Get("Signature") != "" && (query.Get("AWSAccessKeyId") != "" || query.Get("GoogleAccessId") != ""):
+		expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+		if err != nil {
+			return true, time.Time{}
+		}
+		return true, time.Unix(expires, 0)
+	}
+	return false, time.Time{}
+}
+
+// signedURLExpiration returns the expiration of V4 signed URLs, given their
+// signing date and their lifetime, in seconds.
+func signedURLExpiration(date, expires string) time.Time {
+	signedAt, err := time.Parse(presignedDateFormat, date)
+	if err != nil {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return signedAt.Add(time.Duration(seconds) * time.Second)
+}
+
+// redactQuery strips the query string of the given URL, so signatures don't
+// leak into error messages.
+func redactQuery(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,161 @@
+package elementalconductor
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+func presignedS3URL(signedAt time.Time, expires int) string {
+	return fmt.Sprintf(
+		"https://some-bucket.s3.amazonaws.com/video.mov?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=AKIDEXAMPLE%%2F20180101%%2Fus-east-1%%2Fs3%%2Faws4_request&X-Amz-Date=%s&X-Amz-Expires=%d&X-Amz-SignedHeaders=host&X-Amz-Signature=abcdef",
+		signedAt.UTC().Format(presignedDateFormat), expires,
+	)
+}
+
+func presignedElementalProvider() *elementalConductorProvider {
+	return &elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{
+			AccessKeyID:     "aws-access-key",
+			SecretAccessKey: "aws-secret-key",
+			Destination:     "s3://destination-bucket",
+		},
+	}
+}
+
+func presignedJob(source string, segments ...db.SourceSegment) *db.Job {
+	return &db.Job{
+		ID:             "job-1",
+		SourceMedia:    source,
+		SourceSegments: segments,
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+		},
+	}
+}
+
+func TestElementalNewJobPresignedInputs(t *testing.T) {
+	validS3 := presignedS3URL(time.Now().Add(-time.Minute), 3600)
+	validS3V2 := "https://some-bucket.s3.amazonaws.com/video.mov?AWSAccessKeyId=AKIDEXAMPLE&Expires=" + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "&Signature=abc%2Bdef%3D"
+	validGCS := "https://storage.googleapis.com/some-bucket/video.mov?X-Goog-Algorithm=GOOG4-RSA-SHA256&X-Goog-Credential=transcoder&X-Goog-Date=" + time.Now().UTC().Format(presignedDateFormat) + "&X-Goog-Expires=900&X-Goog-SignedHeaders=host&X-Goog-Signature=abcdef"
+	var tests = []struct {
+		givenTestCase string
+		givenSource   string
+		wantInput     elementalconductor.Location
+	}{
+		{
+			"credentialed S3 source",
+			"s3://some-bucket/video.mov",
+			elementalconductor.Location{URI: "s3://some-bucket/video.mov", Username: "aws-access-key", Password: "aws-secret-key"},
+		},
+		{
+			"http source without signature",
+			"https://example.com/video.mov?version=2",
+			elementalconductor.Location{URI: "https://example.com/video.mov?version=2", Username: "aws-access-key", Password: "aws-secret-key"},
+		},
+		{
+			"S3 presigned URL",
+			validS3,
+			elementalconductor.Location{URI: validS3},
+		},
+		{
+			"S3 presigned URL with signature version 2",
+			validS3V2,
+			elementalconductor.Location{URI: validS3V2},
+		},
+		{
+			"GCS signed URL",
+			validGCS,
+			elementalconductor.Location{URI: validGCS},
+		},
+		{
+			"presigned URL with unknown expiration",
+			"https://some-bucket.s3.amazonaws.com/video.mov?X-Amz-Signature=abcdef",
+			elementalconductor.Location{URI: "https://some-bucket.s3.amazonaws.com/video.mov?X-Amz-Signature=abcdef"},
+		},
+	}
+	for _, test := range tests {
+		newJob, err := presignedElementalProvider().newJob(presignedJob(test.givenSource))
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if newJob.Input[0].FileInput != test.wantInput {
+			t.Errorf("%s: wrong input location\nwant %#v\ngot  %#v", test.givenTestCase, test.wantInput, newJob.Input[0].FileInput)
+		}
+		want := elementalconductor.Location{URI: "s3://destination-bucket/job-1/output_720p", Username: "aws-access-key", Password: "aws-secret-key"}
+		if output := *newJob.OutputGroup[0].FileGroupSettings.Destination; output != want {
+			t.Errorf("%s: wrong output location\nwant %#v\ngot  %#v", test.givenTestCase, want, output)
+		}
+	}
+}
+
+func TestElementalNewJobPresignedSegments(t *testing.T) {
+	presigned := presignedS3URL(time.Now(), 600)
+	newJob, err := presignedElementalProvider().newJob(presignedJob("",
+		db.SourceSegment{URI: presigned},
+		db.SourceSegment{URI: "s3://some-bucket/outro.mov"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []elementalconductor.Location{
+		{URI: presigned},
+		{URI: "s3://some-bucket/outro.mov", Username: "aws-access-key", Password: "aws-secret-key"},
+	}
+	if len(newJob.Input) != len(want) {
+		t.Fatalf("wrong number of inputs. Want %d. Got %d", len(want), len(newJob.Input))
+	}
+	for i, input := range newJob.Input {
+		if input.FileInput != want[i] {
+			t.Errorf("wrong location of input %d\nwant %#v\ngot  %#v", i, want[i], input.FileInput)
+		}
+	}
+}
+
+func TestElementalNewJobExpiredPresignedInputs(t *testing.T) {
+	signedAt := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		givenTestCase string
+		givenJob      *db.Job
+		wantErrMsg    string
+	}{
+		{
+			"expired S3 presigned URL",
+			presignedJob(presignedS3URL(signedAt, 3600)),
+			"presigned url https://some-bucket.s3.amazonaws.com/video.mov expired at 2018-01-01T11:00:00Z",
+		},
+		{
+			"expired S3 presigned URL with signature version 2",
+			presignedJob("https://some-bucket.s3.amazonaws.com/video.mov?AWSAccessKeyId=AKIDEXAMPLE&Expires=1514800800&Signature=abcdef"),
+			"presigned url https://some-bucket.s3.amazonaws.com/video.mov expired at 2018-01-01T10:00:00Z",
+		},
+		{
+			"expired segment",
+			presignedJob("",
+				db.SourceSegment{URI: "s3://some-bucket/intro.mov"},
+				db.SourceSegment{URI: presignedS3URL(signedAt, 60)},
+			),
+			"presigned url https://some-bucket.s3.amazonaws.com/video.mov expired at 2018-01-01T10:01:00Z",
+		},
+	}
+	for _, test := range tests {
+		_, err := presignedElementalProvider().newJob(test.givenJob)
+		if _, ok := err.(provider.InvalidJobError); !ok || err.Error() != test.wantErrMsg {
+			t.Errorf("%s: wrong error returned\nwant provider.InvalidJobError(%q)\ngot  %#v", test.givenTestCase, test.wantErrMsg, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package elementalconductor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+func TestElementalPreviewOutput(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenSubpaths map[string]string
+		givenJob      db.Job
+
+		wantOutput provider.JobOutput
+	}{
+		{
+			"file outputs",
+			nil,
+			db.Job{
+				ID:          "{jobId}",
+				SourceMedia: "http://some.nice/video.mov",
+				Outputs: []db.TranscodeOutput{
+					{
+						FileName: "video_1080p.mp4",
+						Preset: db.PresetMap{
+							Name:            "mp4_1080p",
+							ProviderMapping: map[string]string{Name: "mp4_1080p"},
+							OutputOpts:      db.OutputOptions{Extension: "mp4"},
+						},
+					},
+					{
+						FileName: "thumbs/video_720p.mp4",
+						Preset: db.PresetMap{
+							Name:            "mp4_720p",
+							ProviderMapping: map[string]string{Name: "mp4_720p"},
+							OutputOpts:      db.OutputOptions{Extension: "mp4"},
+						},
+					},
+				},
+			},
+			provider.JobOutput{
+				Destination: "s3://destination-bucket/{jobId}",
+				Files: []provider.OutputFile{
+					{Path: "s3://destination-bucket/{jobId}/video_1080p.mp4", Container: "mp4"},
+					{Path: "s3://destination-bucket/{jobId}/thumbs/video_720p.mp4", Container: "mp4"},
+				},
+			},
+		},
+		{
+			"hls outputs in a subpath",
+			map[string]string{"hls": "hls/{source}"},
+			db.Job{
+				ID:          "{jobId}",
+				SourceMedia: "http://some.nice/video.mov",
+				Outputs: []db.TranscodeOutput{
+					{
+						FileName: "video_hls_1080p.m3u8",
+						Preset: db.PresetMap{
+							Name:            "hls_1080p",
+							ProviderMapping: map[string]string{Name: "hls_1080p"},
+							OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+						},
+					},
+				},
+				StreamingParams: db.StreamingParams{
+					Protocol:         "hls",
+					PlaylistFileName: "index.m3u8",
+					SegmentDuration:  3,
+				},
+			},
+			provider.JobOutput{
+				Destination: "s3://destination-bucket/{jobId}",
+				Files: []provider.OutputFile{
+					{Path: "s3://destination-bucket/{jobId}/hls/video/index.m3u8", Container: "m3u8"},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		prov := elementalConductorProvider{
+			client: &fakeElementalConductorClient{},
+			config: &config.ElementalConductor{
+				AccessKeyID:     "aws-access-key",
+				SecretAccessKey: "aws-secret-key",
+				Destination:     "s3://destination-bucket/",
+				OutputSubpaths:  test.givenSubpaths,
+			},
+		}
+		output, err := prov.PreviewOutput(&test.givenJob)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if !reflect.DeepEqual(*output, test.wantOutput) {
+			t.Errorf("%s: wrong output.\nWant %#v\nGot  %#v", test.givenTestCase, test.wantOutput, *output)
+		}
+	}
+}
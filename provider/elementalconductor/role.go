@@ -0,0 +1,31 @@
+package elementalconductor
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+// roleARNRegexp matches ARNs of IAM roles, in any AWS partition, e.g.
+// arn:aws:iam::123456789012:role/transcoding/source-reader.
+var roleARNRegexp = regexp.MustCompile(`^arn:aws(-[a-z]+)*:iam::\d{12}:role/[\w+=,.@/-]{1,512}$`)
+
+// validateRoleARN ensures the given value is the ARN of an IAM role.
+func validateRoleARN(arn string) error {
+	if !roleARNRegexp.MatchString(arn) {
+		return errors.New("must be the ARN of an IAM role (arn:aws:iam::<account-id>:role/<name>)")
+	}
+	return nil
+}
+
+// sourceLocation returns the location for reading the given source. When a
+// source role is configured, s3:// sources are read by assuming the role,
+// without the static credentials.
+func (p *elementalConductorProvider) sourceLocation(uri string) (elementalconductor.Location, error) {
+	if p.config.SourceRoleARN != "" && strings.HasPrefix(uri, "s3://") {
+		return elementalconductor.Location{URI: uri, RoleARN: p.config.SourceRoleARN}, nil
+	}
+	return p.location(uri)
+}
@@ -0,0 +1,113 @@
+package elementalconductor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+func TestValidateRoleARN(t *testing.T) {
+	var tests = []struct {
+		givenARN  string
+		wantValid bool
+	}{
+		{"arn:aws:iam::123456789012:role/source-reader", true},
+		{"arn:aws:iam::123456789012:role/transcoding/source-reader", true},
+		{"arn:aws-cn:iam::123456789012:role/source-reader", true},
+		{"arn:aws-us-gov:iam::123456789012:role/source-reader", true},
+		{"arn:aws:iam::123456789012:user/source-reader", false},
+		{"arn:aws:iam::12345:role/source-reader", false},
+		{"arn:aws:s3:::some-bucket", false},
+		{"arn:aws:iam::123456789012:role/", false},
+		{"source-reader", false},
+	}
+	for _, test := range tests {
+		err := validateRoleARN(test.givenARN)
+		if valid := err == nil; valid != test.wantValid {
+			t.Errorf("%s: wrong validation result. Want valid=%v. Got %v", test.givenARN, test.wantValid, err)
+		}
+	}
+}
+
+func TestElementalNewJobSourceRole(t *testing.T) {
+	const roleARN = "arn:aws:iam::123456789012:role/source-reader"
+	var tests = []struct {
+		givenTestCase string
+		givenRoleARN  string
+		givenSource   string
+		wantInput     elementalconductor.Location
+	}{
+		{
+			"s3 source with role",
+			roleARN,
+			"s3://other-account-bucket/video.mov",
+			elementalconductor.Location{URI: "s3://other-account-bucket/video.mov", RoleARN: roleARN},
+		},
+		{
+			"s3 source without role",
+			"",
+			"s3://some-bucket/video.mov",
+			elementalconductor.Location{URI: "s3://some-bucket/video.mov", Username: "aws-access-key", Password: "aws-secret-key"},
+		},
+		{
+			"http source with role",
+			roleARN,
+			"http://some.nice/video.mov",
+			elementalconductor.Location{URI: "http://some.nice/video.mov", Username: "aws-access-key", Password: "aws-secret-key"},
+		},
+	}
+	for _, test := range tests {
+		prov := presignedElementalProvider()
+		prov.config.SourceRoleARN = test.givenRoleARN
+		newJob, err := prov.newJob(presignedJob(test.givenSource))
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if got := newJob.Input[0].FileInput; !reflect.DeepEqual(got, test.wantInput) {
+			t.Errorf("%s: wrong input.\nWant %#v\nGot  %#v", test.givenTestCase, test.wantInput, got)
+		}
+		wantOutput := elementalconductor.Location{URI: "s3://destination-bucket/job-1/output_720p", Username: "aws-access-key", Password: "aws-secret-key"}
+		if got := newJob.OutputGroup[0].FileGroupSettings.Destination; !reflect.DeepEqual(*got, wantOutput) {
+			t.Errorf("%s: wrong output destination.\nWant %#v\nGot  %#v", test.givenTestCase, wantOutput, *got)
+		}
+	}
+}
+
+func TestElementalNewJobSourceRoleSegments(t *testing.T) {
+	const roleARN = "arn:aws:iam::123456789012:role/source-reader"
+	prov := presignedElementalProvider()
+	prov.config.SourceRoleARN = roleARN
+	newJob, err := prov.newJob(presignedJob("", db.SourceSegment{URI: "s3://other-account-bucket/part1.mov"}, db.SourceSegment{URI: "s3://other-account-bucket/part2.mov"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, input := range newJob.Input {
+		if input.FileInput.RoleARN != roleARN || input.FileInput.Username != "" || input.FileInput.Password != "" {
+			t.Errorf("wrong location of segment %d: %#v", i, input.FileInput)
+		}
+	}
+}
+
+func TestElementalFactoryInvalidSourceRoleARN(t *testing.T) {
+	cfg := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:          "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:     "myuser",
+			APIKey:        "elemental-api-key",
+			AuthExpires:   30,
+			SourceRoleARN: "arn:aws:iam::123456789012:user/source-reader",
+		},
+	}
+	prov, err := elementalConductorFactory(&cfg)
+	if prov != nil {
+		t.Errorf("unexpected non-nil provider: %#v", prov)
+	}
+	expectedErr := provider.InvalidConfigError("invalid ELEMENTALCONDUCTOR_SOURCE_ROLE_ARN: must be the ARN of an IAM role (arn:aws:iam::<account-id>:role/<name>)")
+	if err != expectedErr {
+		t.Errorf("wrong error returned. Want %#v. Got %#v", expectedErr, err)
+	}
+}
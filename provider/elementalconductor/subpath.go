@@ -0,0 +1,85 @@
+package elementalconductor
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider/elementalconductor/internal/elementalconductor"
+)
+
+// Keys of the output subpaths of adaptive streaming output groups. File
+// outputs are keyed by their container (e.g. mp4).
+const (
+	hlsSubpathKey    = "hls"
+	smoothSubpathKey = "smooth"
+	cmafSubpathKey   = "cmaf"
+)
+
+var (
+	subpathKeyRegexp         = regexp.MustCompile(`^[a-z0-9]+$`)
+	subpathPlaceholderRegexp = regexp.MustCompile(`\{[^{}]*\}`)
+)
+
+// validateOutputSubpaths ensures that the given subpath templates are
+// relative paths within the destination of the job, using only the {source}
+// and {container} placeholders.
+func validateOutputSubpaths(subpaths map[string]string) error {
+	keys := make([]string, 0, len(subpaths))
+	for key := range subpaths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !subpathKeyRegexp.MatchString(key) {
+			return fmt.Errorf("invalid output type %q, must be a container or one of hls, smooth or cmaf", key)
+		}
+		if err := validateSubpathTemplate(subpaths[key]); err != nil {
+			return fmt.Errorf("invalid subpath for %s outputs: %s", key, err)
+		}
+	}
+	return nil
+}
+
+func validateSubpathTemplate(template string) error {
+	if template == "" {
+		return errors.New("must not be empty")
+	}
+	if strings.HasPrefix(template, "/") || strings.Contains(template, `\`) {
+		return fmt.Errorf("%q must be a relative path", template)
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(template, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%q must not contain empty, . or .. segments", template)
+		}
+	}
+	for _, placeholder := range subpathPlaceholderRegexp.FindAllString(template, -1) {
+		if placeholder != "{source}" && placeholder != "{container}" {
+			return fmt.Errorf("unknown placeholder %s, must be one of {source} or {container}", placeholder)
+		}
+	}
+	if strings.ContainsAny(subpathPlaceholderRegexp.ReplaceAllString(template, ""), "{}") {
+		return fmt.Errorf("%q has unbalanced braces", template)
+	}
+	return nil
+}
+
+// withSubpath appends the configured subpath of the given type of output to
+// the location, if any.
+func (p *elementalConductorProvider) withSubpath(location elementalconductor.Location, key string, job db.Job) elementalconductor.Location {
+	template, ok := p.config.OutputSubpaths[key]
+	if !ok {
+		return location
+	}
+	source := path.Base(job.SourceMedia)
+	replacer := strings.NewReplacer(
+		"{source}", strings.TrimSuffix(source, path.Ext(source)),
+		"{container}", key,
+	)
+	location.URI += "/" + strings.TrimSuffix(replacer.Replace(template), "/")
+	return location
+}
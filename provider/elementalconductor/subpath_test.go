@@ -0,0 +1,126 @@
+package elementalconductor
+
+import (
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+func TestElementalNewJobOutputSubpaths(t *testing.T) {
+	prov := elementalConductorProvider{
+		client: &fakeElementalConductorClient{},
+		config: &config.ElementalConductor{
+			Destination: "s3://destination",
+			OutputSubpaths: map[string]string{
+				"mp4": "{container}",
+				"hls": "hls/{source}/",
+			},
+		},
+	}
+	newJob, err := prov.newJob(&db.Job{
+		ID:          "job-1",
+		SourceMedia: "http://some.nice/video.mov",
+		Outputs: []db.TranscodeOutput{
+			{
+				FileName: "output_720p.mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_720p",
+					ProviderMapping: map[string]string{Name: "mp4_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			},
+			{
+				FileName: "output_720p.webm",
+				Preset: db.PresetMap{
+					Name:            "webm_720p",
+					ProviderMapping: map[string]string{Name: "webm_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "webm"},
+				},
+			},
+			{
+				FileName: "output_720p.m3u8",
+				Preset: db.PresetMap{
+					Name:            "hls_720p",
+					ProviderMapping: map[string]string{Name: "hls_720p"},
+					OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+				},
+			},
+		},
+		StreamingParams: db.StreamingParams{Protocol: "hls", SegmentDuration: 3, PlaylistFileName: "master.m3u8"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotFileURIs []string
+	var gotHLSURI string
+	for _, group := range newJob.OutputGroup {
+		if group.FileGroupSettings != nil {
+			gotFileURIs = append(gotFileURIs, group.FileGroupSettings.Destination.URI)
+		}
+		if group.AppleLiveGroupSettings != nil {
+			gotHLSURI = group.AppleLiveGroupSettings.Destination.URI
+		}
+	}
+	wantFileURIs := []string{"s3://destination/job-1/mp4/output_720p", "s3://destination/job-1/output_720p"}
+	if len(gotFileURIs) != len(wantFileURIs) {
+		t.Fatalf("wrong file outputs. Want %#v. Got %#v", wantFileURIs, gotFileURIs)
+	}
+	for i := range wantFileURIs {
+		if gotFileURIs[i] != wantFileURIs[i] {
+			t.Errorf("wrong destination of file output %d. Want %q. Got %q", i, wantFileURIs[i], gotFileURIs[i])
+		}
+	}
+	if want := "s3://destination/job-1/hls/video/master"; gotHLSURI != want {
+		t.Errorf("wrong destination of HLS outputs. Want %q. Got %q", want, gotHLSURI)
+	}
+}
+
+func TestValidateOutputSubpaths(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenSubpaths map[string]string
+		wantErr       string
+	}{
+		{"valid subpaths", map[string]string{"mp4": "files/{container}", "hls": "streaming/{source}/"}, ""},
+		{"no subpaths", nil, ""},
+		{"unknown output type", map[string]string{"HLS!": "hls"}, `invalid output type "HLS!", must be a container or one of hls, smooth or cmaf`},
+		{"empty subpath", map[string]string{"mp4": ""}, "invalid subpath for mp4 outputs: must not be empty"},
+		{"absolute subpath", map[string]string{"mp4": "/mp4"}, `invalid subpath for mp4 outputs: "/mp4" must be a relative path`},
+		{"parent directory", map[string]string{"hls": "../hls"}, `invalid subpath for hls outputs: "../hls" must not contain empty, . or .. segments`},
+		{"empty segment", map[string]string{"hls": "hls//{source}"}, `invalid subpath for hls outputs: "hls//{source}" must not contain empty, . or .. segments`},
+		{"unknown placeholder", map[string]string{"mp4": "mp4/{preset}"}, "invalid subpath for mp4 outputs: unknown placeholder {preset}, must be one of {source} or {container}"},
+		{"unbalanced braces", map[string]string{"mp4": "mp4/{source"}, `invalid subpath for mp4 outputs: "mp4/{source" has unbalanced braces`},
+	}
+	for _, test := range tests {
+		err := validateOutputSubpaths(test.givenSubpaths)
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != test.wantErr {
+			t.Errorf("%s: wrong error\nwant %q\ngot  %q", test.givenTestCase, test.wantErr, gotErr)
+		}
+	}
+}
+
+func TestElementalFactoryInvalidOutputSubpaths(t *testing.T) {
+	cfg := config.Config{
+		ElementalConductor: &config.ElementalConductor{
+			Host:           "https://mybucket.s3.amazonaws.com/destination-dir/",
+			UserLogin:      "myuser",
+			APIKey:         "elemental-api-key",
+			AuthExpires:    30,
+			OutputSubpaths: map[string]string{"mp4": "/mp4"},
+		},
+	}
+	prov, err := elementalConductorFactory(&cfg)
+	if prov != nil {
+		t.Errorf("unexpected non-nil provider: %#v", prov)
+	}
+	expectedErr := provider.InvalidConfigError(`invalid ELEMENTALCONDUCTOR_OUTPUT_SUBPATHS: invalid subpath for mp4 outputs: "/mp4" must be a relative path`)
+	if err != expectedErr {
+		t.Errorf("wrong error returned. Want %#v. Got %#v", expectedErr, err)
+	}
+}
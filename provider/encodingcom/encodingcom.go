@@ -16,6 +16,7 @@
 package encodingcom
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -53,7 +54,7 @@ type encodingComProvider struct {
 	client *encodingcom.Client
 }
 
-func (e *encodingComProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+func (e *encodingComProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	formats, err := e.presetsToFormats(job)
 	if err != nil {
 		return nil, fmt.Errorf("Error converting presets to formats on Transcode operation: %s", err.Error())
@@ -214,7 +215,7 @@ func (e *encodingComProvider) presetsToFormats(job *db.Job) ([]encodingcom.Forma
 	return formats, nil
 }
 
-func (e *encodingComProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (e *encodingComProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	resp, err := e.client.GetStatus([]string{job.ProviderJobID}, false)
 	if err != nil {
 		return nil, err
@@ -385,12 +386,14 @@ func (e *encodingComProvider) statusMap(encodingComStatus string) provider.Statu
 	}
 }
 
-func (e *encodingComProvider) CancelJob(id string) error {
+func (e *encodingComProvider) CancelJob(ctx context.Context, id string) error {
 	_, err := e.client.CancelMedia(id)
 	return err
 }
 
-func (e *encodingComProvider) Healthcheck() error {
+func (e *encodingComProvider) Healthcheck(ctx context.Context) error {
+	// the library sets up its own transport for the status endpoint, so
+	// the provider headers aren't sent in these requests.
 	status, err := encodingcom.APIStatus(e.config.EncodingCom.StatusEndpoint)
 	if err != nil {
 		return err
@@ -410,6 +413,9 @@ func (e *encodingComProvider) Capabilities() provider.Capabilities {
 }
 
 func encodingComFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	if cfg.EncodingCom.Disabled {
+		return nil, provider.ErrProviderDisabled
+	}
 	if cfg.EncodingCom.UserID == "" || cfg.EncodingCom.UserKey == "" {
 		return nil, errEncodingComInvalidConfig
 	}
@@ -417,5 +423,10 @@ func encodingComFactory(cfg *config.Config) (provider.TranscodingProvider, error
 	if err != nil {
 		return nil, err
 	}
+	// the library sends its requests through http.DefaultClient.
+	err = provider.DefaultClientHeaders(cfg, client.Endpoint)
+	if err != nil {
+		return nil, err
+	}
 	return &encodingComProvider{client: client, config: cfg}, nil
 }
@@ -1,6 +1,7 @@
 package encodingcom
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"testing"
@@ -153,7 +154,7 @@ func TestEncodingComTranscode(t *testing.T) {
 		}
 	}
 
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -216,7 +217,7 @@ func TestEncodingComTranscode(t *testing.T) {
 		t.Errorf("Wrong source. Want %v. Got %v.", []string{source}, media.Request.Source)
 	}
 
-	jobStatus, err = prov.Transcode(&db.Job{
+	jobStatus, err = prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -318,7 +319,7 @@ func TestEncodingComS3Input(t *testing.T) {
 		}
 	}
 
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -390,7 +391,7 @@ func TestEncodingComS3InputWithNoCopy(t *testing.T) {
 		}
 	}
 
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:          "job-123",
 		SourceMedia: source,
 		Outputs:     outputs,
@@ -457,7 +458,7 @@ func TestEncodingComTranscodePresetNotFound(t *testing.T) {
 			},
 		},
 	}
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-2",
 		SourceMedia:     source,
 		Outputs:         outputs,
@@ -505,7 +506,7 @@ func TestJobStatus(t *testing.T) {
 			Destination: "https://mybucket.s3.amazonaws.com/dir/",
 		},
 	}
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: "mymedia"})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: "mymedia"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -589,7 +590,7 @@ func TestJobStatusMissingDimension(t *testing.T) {
 			Destination: "https://mybucket.s3.amazonaws.com/dir/",
 		},
 	}
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: "mymedia"})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: "mymedia"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -674,7 +675,7 @@ func TestJobStatusRotatedVideo(t *testing.T) {
 			Destination: "https://mybucket.s3.amazonaws.com/dir/",
 		},
 	}
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: "mymedia"})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: "mymedia"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -756,7 +757,7 @@ func TestJobStatusNotFinished(t *testing.T) {
 			Destination: "https://mybucket.s3.amazonaws.com/dir/",
 		},
 	}
-	jobStatus, err := prov.JobStatus(&db.Job{ID: "job-123", ProviderJobID: "mymedia"})
+	jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ID: "job-123", ProviderJobID: "mymedia"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -896,7 +897,7 @@ func TestJobStatusInvalidSourceInfo(t *testing.T) {
 		},
 	}
 	for _, test := range tests {
-		jobStatus, err := prov.JobStatus(&db.Job{ProviderJobID: test.mediaID})
+		jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ProviderJobID: test.mediaID})
 		if jobStatus != nil {
 			t.Errorf("%s: got unexpected non-nil status: %#v", test.testCase, jobStatus)
 		}
@@ -915,7 +916,7 @@ func TestJobStatusMediaNotFound(t *testing.T) {
 	defer server.Close()
 	client, _ := encodingcom.NewClient(server.URL, "myuser", "secret")
 	provider := encodingComProvider{client: client}
-	jobStatus, err := provider.JobStatus(&db.Job{ProviderJobID: "non-existent-job"})
+	jobStatus, err := provider.JobStatus(context.Background(), &db.Job{ProviderJobID: "non-existent-job"})
 	if err == nil {
 		t.Errorf("JobStatus: got unexpected <nil> err.")
 	}
@@ -1360,7 +1361,7 @@ func TestCancelJob(t *testing.T) {
 		t.Fatal(err)
 	}
 	prov := encodingComProvider{client: client}
-	err = prov.CancelJob("mymedia")
+	err = prov.CancelJob(context.Background(), "mymedia")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1406,7 +1407,7 @@ func TestHealthcheck(t *testing.T) {
 	}
 	for _, test := range tests {
 		server.SetAPIStatus(&test.apiStatus)
-		err := provider.Healthcheck()
+		err := provider.Healthcheck(context.Background())
 		if test.expectedMsg != "" {
 			if got := err.Error(); got != test.expectedMsg {
 				t.Errorf("Wrong error returned. Want %q. Got %q", test.expectedMsg, got)
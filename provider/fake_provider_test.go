@@ -1,6 +1,8 @@
 package provider
 
 import (
+	"context"
+
 	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/db"
 )
@@ -10,11 +12,11 @@ type fakeProvider struct {
 	healthErr error
 }
 
-func (*fakeProvider) Transcode(*db.Job) (*JobStatus, error) {
+func (*fakeProvider) Transcode(context.Context, *db.Job) (*JobStatus, error) {
 	return nil, nil
 }
 
-func (*fakeProvider) JobStatus(*db.Job) (*JobStatus, error) {
+func (*fakeProvider) JobStatus(context.Context, *db.Job) (*JobStatus, error) {
 	return nil, nil
 }
 
@@ -30,11 +32,11 @@ func (*fakeProvider) DeletePreset(string) error {
 	return nil
 }
 
-func (*fakeProvider) CancelJob(string) error {
+func (*fakeProvider) CancelJob(context.Context, string) error {
 	return nil
 }
 
-func (f *fakeProvider) Healthcheck() error {
+func (f *fakeProvider) Healthcheck(context.Context) error {
 	return f.healthErr
 }
 
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/version"
+)
+
+// UserAgent returns the User-Agent sent in requests to the providers, either
+// the one defined in the configuration or the default
+// video-transcoding-api/<version>.
+func UserAgent(cfg *config.Config) string {
+	if cfg.ProviderUserAgent != "" {
+		return cfg.ProviderUserAgent
+	}
+	return "video-transcoding-api/" + version.Version
+}
+
+// HTTPClient returns an HTTP client to be used by providers when talking to
+// their APIs. All requests sent with the client include the User-Agent and
+// the static headers defined in the configuration.
+func HTTPClient(cfg *config.Config, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: HTTPTransport(cfg, http.DefaultTransport),
+	}
+}
+
+// HTTPTransport wraps the given transport, for providers whose libraries set
+// up their own, so requests include the User-Agent and the static headers
+// defined in the configuration. A nil base means http.DefaultTransport.
+func HTTPTransport(cfg *config.Config, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &headerTransport{
+		userAgent: UserAgent(cfg),
+		headers:   cfg.ProviderHeaders,
+		base:      base,
+	}
+}
+
+// DefaultClientHeaders makes requests sent through http.DefaultClient to the
+// host of the given URL include the User-Agent and the static headers defined
+// in the configuration, for providers whose libraries always send their
+// requests through http.DefaultClient. Requests to other hosts are left
+// untouched.
+func DefaultClientHeaders(cfg *config.Config, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host in %q", rawURL)
+	}
+	defaultClientHosts.Lock()
+	defer defaultClientHosts.Unlock()
+	if defaultClientHosts.transports == nil {
+		defaultClientHosts.transports = make(map[string]http.RoundTripper)
+		http.DefaultClient.Transport = hostTransport{base: http.DefaultClient.Transport}
+	}
+	defaultClientHosts.transports[u.Host] = HTTPTransport(cfg, nil)
+	return nil
+}
+
+var defaultClientHosts struct {
+	sync.RWMutex
+	transports map[string]http.RoundTripper
+}
+
+// hostTransport sends requests through the transport registered for their
+// host, falling back to the base transport.
+type hostTransport struct {
+	base http.RoundTripper
+}
+
+func (t hostTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	defaultClientHosts.RLock()
+	transport := defaultClientHosts.transports[r.URL.Host]
+	defaultClientHosts.RUnlock()
+	if transport != nil {
+		return transport.RoundTrip(r)
+	}
+	if t.base != nil {
+		return t.base.RoundTrip(r)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+type headerTransport struct {
+	userAgent string
+	headers   map[string]string
+	base      http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	req := new(http.Request)
+	*req = *r
+	req.Header = make(http.Header, len(r.Header)+len(t.headers)+1)
+	for name, values := range r.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
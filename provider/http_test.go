@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/version"
+)
+
+func TestHTTPClientHeaders(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenConfig   config.Config
+		wantHeaders   map[string]string
+	}{
+		{
+			"default user agent",
+			config.Config{},
+			map[string]string{"User-Agent": "video-transcoding-api/" + version.Version},
+		},
+		{
+			"custom user agent and headers",
+			config.Config{
+				ProviderUserAgent: "video-transcoding-api-staging/1.0",
+				ProviderHeaders:   map[string]string{"X-Environment": "staging"},
+			},
+			map[string]string{
+				"User-Agent":    "video-transcoding-api-staging/1.0",
+				"X-Environment": "staging",
+			},
+		},
+	}
+	for _, test := range tests {
+		var got http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header
+		}))
+		client := HTTPClient(&test.givenConfig, 0)
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header.Set("Accept", "application/xml")
+		resp, err := client.Do(req)
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		for name, value := range test.wantHeaders {
+			if got.Get(name) != value {
+				t.Errorf("%s: wrong %s header. Want %q. Got %q", test.givenTestCase, name, value, got.Get(name))
+			}
+		}
+		if got.Get("Accept") != "application/xml" {
+			t.Errorf("%s: request header not preserved. Want %q. Got %q", test.givenTestCase, "application/xml", got.Get("Accept"))
+		}
+		if len(req.Header) != 1 {
+			t.Errorf("%s: original request headers were modified: %#v", test.givenTestCase, req.Header)
+		}
+	}
+}
+
+func TestHTTPTransportBase(t *testing.T) {
+	var base recordingTransport
+	transport := HTTPTransport(&config.Config{ProviderUserAgent: "video-transcoding-api-staging/1.0"}, &base)
+	req, _ := http.NewRequest("GET", "http://provider.example.com/jobs", nil)
+	_, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(base.requests) != 1 {
+		t.Fatalf("wrong number of requests sent through the base transport. Want 1. Got %d", len(base.requests))
+	}
+	if got := base.requests[0].Header.Get("User-Agent"); got != "video-transcoding-api-staging/1.0" {
+		t.Errorf("wrong User-Agent header. Want %q. Got %q", "video-transcoding-api-staging/1.0", got)
+	}
+}
+
+func TestDefaultClientHeaders(t *testing.T) {
+	var got []http.Header
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header)
+	})
+	providerServer := httptest.NewServer(handler)
+	defer providerServer.Close()
+	otherServer := httptest.NewServer(handler)
+	defer otherServer.Close()
+	cfg := config.Config{
+		ProviderUserAgent: "video-transcoding-api-staging/1.0",
+		ProviderHeaders:   map[string]string{"X-Environment": "staging"},
+	}
+	err := DefaultClientHeaders(&cfg, providerServer.URL+"/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{providerServer.URL + "/api/jobs", otherServer.URL} {
+		resp, err := http.DefaultClient.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(got) != 2 {
+		t.Fatalf("wrong number of requests. Want 2. Got %d", len(got))
+	}
+	if ua := got[0].Get("User-Agent"); ua != "video-transcoding-api-staging/1.0" {
+		t.Errorf("wrong User-Agent header in requests to the provider. Want %q. Got %q", "video-transcoding-api-staging/1.0", ua)
+	}
+	if env := got[0].Get("X-Environment"); env != "staging" {
+		t.Errorf("wrong X-Environment header in requests to the provider. Want %q. Got %q", "staging", env)
+	}
+	if env := got[1].Get("X-Environment"); env != "" {
+		t.Errorf("unexpected X-Environment header in requests to other hosts: %q", env)
+	}
+}
+
+func TestDefaultClientHeadersInvalidURL(t *testing.T) {
+	err := DefaultClientHeaders(&config.Config{}, "manage.encoding.com")
+	if err == nil {
+		t.Error("unexpected <nil> error for URL without host")
+	}
+}
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, r)
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+}
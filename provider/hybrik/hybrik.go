@@ -1,6 +1,7 @@
 package hybrik
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"path"
@@ -55,6 +56,9 @@ func (hp hybrikProvider) String() string {
 }
 
 func hybrikTranscoderFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	if cfg.Hybrik.Disabled {
+		return nil, provider.ErrProviderDisabled
+	}
 	api, err := hwrapper.NewClient(hwrapper.Config{
 		URL:            cfg.Hybrik.URL,
 		ComplianceDate: cfg.Hybrik.ComplianceDate,
@@ -66,6 +70,11 @@ func hybrikTranscoderFactory(cfg *config.Config) (provider.TranscodingProvider,
 	if err != nil {
 		return &hybrikProvider{}, err
 	}
+	// the library sends its requests through http.DefaultClient.
+	err = provider.DefaultClientHeaders(cfg, cfg.Hybrik.URL)
+	if err != nil {
+		return &hybrikProvider{}, err
+	}
 
 	return &hybrikProvider{
 		c:      api,
@@ -73,7 +82,7 @@ func hybrikTranscoderFactory(cfg *config.Config) (provider.TranscodingProvider,
 	}, nil
 }
 
-func (hp *hybrikProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+func (hp *hybrikProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	cj, err := hp.presetsToTranscodeJob(job)
 	if err != nil {
 		return &provider.JobStatus{}, err
@@ -314,7 +323,7 @@ func (hp *hybrikProvider) presetsToTranscodeJob(job *db.Job) (string, error) {
 	return string(resp), nil
 }
 
-func (hp *hybrikProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (hp *hybrikProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	ji, err := hp.c.GetJobInfo(job.ProviderJobID)
 	if err != nil {
 		return &provider.JobStatus{}, err
@@ -344,7 +353,7 @@ func (hp *hybrikProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
 	}, nil
 }
 
-func (hp *hybrikProvider) CancelJob(id string) error {
+func (hp *hybrikProvider) CancelJob(ctx context.Context, id string) error {
 	return hp.c.StopJob(id)
 }
 
@@ -474,7 +483,7 @@ func (hp *hybrikProvider) GetPreset(presetID string) (interface{}, error) {
 // Healthcheck should return nil if the provider is currently available
 // for transcoding videos, otherwise it should return an error
 // explaining what's going on.
-func (hp *hybrikProvider) Healthcheck() error {
+func (hp *hybrikProvider) Healthcheck(ctx context.Context) error {
 	// For now, just call list jobs. If this errors, then we can consider the service unhealthy
 	_, err := hp.c.CallAPI("GET", "/jobs/info", nil, nil)
 	return err
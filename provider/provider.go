@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -19,9 +20,25 @@ var (
 	// that is not registered.
 	ErrProviderNotFound = errors.New("provider not found")
 
+	// ErrProviderDisabled is the error returned by the factory of providers
+	// that are disabled in the configuration.
+	ErrProviderDisabled = InvalidConfigError("provider is disabled in the configuration")
+
 	// ErrPresetMapNotFound is the error returned when the given preset is not
 	// found in the provider.
 	ErrPresetMapNotFound = errors.New("preset not found in provider")
+
+	// ErrNoPresets is the error returned when trying to transcode a job
+	// without any preset.
+	ErrNoPresets = InvalidJobError("the job must have at least one preset")
+
+	// ErrNotImplemented is the error returned when the provider doesn't
+	// support the requested operation.
+	ErrNotImplemented = errors.New("operation not supported by the provider")
+
+	// ErrAuthFailed is the error returned when the provider rejects the
+	// configured credentials.
+	ErrAuthFailed = errors.New("authentication with the provider failed")
 )
 
 // TranscodingProvider represents a provider of transcoding.
@@ -29,10 +46,13 @@ var (
 // It defines a basic API for transcoding a media and query the status of a
 // Job. The underlying provider should handle the profileSpec as desired (it
 // might be a JSON, or an XML, or anything else.
+//
+// Methods taking a context should abort the calls made to the provider once
+// the context is canceled or its deadline expires.
 type TranscodingProvider interface {
-	Transcode(*db.Job) (*JobStatus, error)
-	JobStatus(*db.Job) (*JobStatus, error)
-	CancelJob(id string) error
+	Transcode(context.Context, *db.Job) (*JobStatus, error)
+	JobStatus(context.Context, *db.Job) (*JobStatus, error)
+	CancelJob(ctx context.Context, id string) error
 	CreatePreset(db.Preset) (string, error)
 	DeletePreset(presetID string) error
 	GetPreset(presetID string) (interface{}, error)
@@ -40,12 +60,95 @@ type TranscodingProvider interface {
 	// Healthcheck should return nil if the provider is currently available
 	// for transcoding videos, otherwise it should return an error
 	// explaining what's going on.
-	Healthcheck() error
+	Healthcheck(context.Context) error
 
 	// Capabilities describes the capabilities of the provider.
 	Capabilities() Capabilities
 }
 
+// PresetValidator is implemented by providers that are able to check whether
+// a preset is valid without creating it.
+type PresetValidator interface {
+	// ValidatePreset returns an error describing why the provider would
+	// reject the given preset, or nil if the preset is valid.
+	ValidatePreset(db.Preset) error
+}
+
+// PriorityUpdater is implemented by providers that are able to change the
+// priority of jobs after creating them.
+type PriorityUpdater interface {
+	UpdateJobPriority(ctx context.Context, id string, priority int) error
+}
+
+// JobLogger is implemented by providers that are able to retrieve the logs
+// of jobs, for debugging purposes.
+type JobLogger interface {
+	GetJobLogs(ctx context.Context, id string) ([]byte, error)
+}
+
+// FailureClassifier is implemented by providers that are able to tell whether
+// a failed job may succeed when submitted again, as opposed to failures caused
+// by the job itself (e.g. an unreadable source).
+type FailureClassifier interface {
+	TransientFailure(status *JobStatus) bool
+}
+
+// JobSpec is the spec of a job in the format used by the provider (e.g. XML
+// for Elemental Conductor), with credentials redacted. Providers that
+// support job specs report it in the status returned by Transcode, and it's
+// kept along with the job for inspection.
+type JobSpec struct {
+	Data        []byte
+	ContentType string
+}
+
+// OutputPreviewer is implemented by providers that are able to tell the
+// destination and the output files of a job without submitting it.
+type OutputPreviewer interface {
+	PreviewOutput(job *db.Job) (*JobOutput, error)
+}
+
+// JobCoster is implemented by providers that are able to report the cost of
+// jobs after they complete. GetJobCost returns ErrNotImplemented when the
+// provider doesn't report the cost of the given job.
+type JobCoster interface {
+	GetJobCost(ctx context.Context, id string) (*JobCost, error)
+}
+
+// JobCost is the cost of a job, as billed by the provider.
+//
+// swagger:model
+type JobCost struct {
+	// duration of the job billed by the provider, in minutes
+	BilledMinutes float64 `json:"billedMinutes"`
+
+	// amount charged by the provider for the job
+	Amount float64 `json:"amount"`
+
+	// ISO 4217 code of the currency of the amount (e.g. USD)
+	Currency string `json:"currency,omitempty"`
+}
+
+// ManifestWriter is implemented by providers that are able to write the
+// manifest of the outputs of jobs to their destination. WriteManifest returns
+// the location of the manifest.
+type ManifestWriter interface {
+	WriteManifest(ctx context.Context, job *db.Job, manifest []byte) (string, error)
+}
+
+// CapacityReporter is implemented by providers that are able to report the
+// number of active nodes processing jobs, as checked by Healthcheck, which
+// bounds the number of concurrent submissions to the provider.
+type CapacityReporter interface {
+	ActiveNodes(context.Context) (int, error)
+}
+
+// PresetLister is implemented by providers that are able to list the presets
+// defined in the provider, including the ones not created by the API.
+type PresetLister interface {
+	ListPresets(context.Context) ([]PresetSummary, error)
+}
+
 // Factory is the function responsible for creating the instance of a
 // provider.
 type Factory func(cfg *config.Config) (TranscodingProvider, error)
@@ -53,6 +156,10 @@ type Factory func(cfg *config.Config) (TranscodingProvider, error)
 // InvalidConfigError is returned if a provider could not be configured properly
 type InvalidConfigError string
 
+// InvalidJobError is returned if the provider can't transcode the given job
+// because of invalid job options
+type InvalidJobError string
+
 // JobNotFoundError is returned if a job with a given id could not be found by the provider
 type JobNotFoundError struct {
 	ID string
@@ -62,6 +169,10 @@ func (err InvalidConfigError) Error() string {
 	return string(err)
 }
 
+func (err InvalidJobError) Error() string {
+	return string(err)
+}
+
 func (err JobNotFoundError) Error() string {
 	return fmt.Sprintf("could not found job with id: %s", err.ID)
 }
@@ -79,12 +190,68 @@ type JobStatus struct {
 	ProviderStatus map[string]interface{} `json:"providerStatus,omitempty"`
 	Output         JobOutput              `json:"output"`
 	SourceInfo     SourceInfo             `json:"sourceInfo,omitempty"`
+
+	// status of each output of the job, for providers that report the
+	// progress of outputs individually
+	Outputs []OutputStatus `json:"outputs,omitempty"`
+
+	// whether the job is a test job, removed sooner than other jobs
+	Test bool `json:"test,omitempty"`
+
+	// ids of the provider jobs of previous attempts that failed, for jobs
+	// retried after failing in the provider
+	FailedAttempts []string `json:"failedAttempts,omitempty"`
+
+	// time spent by the job in the queue of the provider and running,
+	// computed by ComputeDurations. Omitted when unknown
+	QueueDuration      time.Duration `json:"queueDuration,omitempty"`
+	ProcessingDuration time.Duration `json:"processingDuration,omitempty"`
+
+	// time when the job was submitted to the provider, when the provider
+	// started running it and when it completed, successfully or not. The
+	// start time is also used for enforcing the maximum duration of jobs.
+	// Zero when unknown.
+	SubmitTime   time.Time `json:"-"`
+	StartTime    time.Time `json:"-"`
+	CompleteTime time.Time `json:"-"`
+
+	// spec of the job as submitted to the provider, reported by Transcode
+	// in providers that support job specs
+	Spec *JobSpec `json:"-"`
+}
+
+// ComputeDurations fills QueueDuration and ProcessingDuration using the
+// timestamps reported by the provider. Durations depending on unknown
+// timestamps are left out.
+func (s *JobStatus) ComputeDurations() {
+	s.QueueDuration = elapsed(s.SubmitTime, s.StartTime)
+	s.ProcessingDuration = elapsed(s.StartTime, s.CompleteTime)
+}
+
+func elapsed(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
 }
 
 // JobOutput represents information about a job output.
 type JobOutput struct {
 	Destination string       `json:"destination,omitempty"`
 	Files       []OutputFile `json:"files,omitempty"`
+
+	// location of the manifest of the outputs, for jobs that asked for
+	// one, once it's written
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// OutputStatus represents the status of an individual output of a job, so
+// clients can start using outputs that finish before the others.
+type OutputStatus struct {
+	FileName string  `json:"fileName"`
+	Preset   string  `json:"preset,omitempty"`
+	Status   Status  `json:"status"`
+	Progress float64 `json:"progress"`
 }
 
 // OutputFile represents an output file in a given job.
@@ -95,6 +262,28 @@ type OutputFile struct {
 	Height     int64  `json:"height"`
 	Width      int64  `json:"width"`
 	FileSize   int64  `json:"fileSize"`
+
+	// bitrate, in bits per second, and duration of the file, for
+	// providers that report them
+	Bitrate  int64         `json:"bitrate,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// PresetSummary briefly describes a preset defined in the provider.
+//
+// swagger:model
+type PresetSummary struct {
+	// id of the preset in the provider
+	ID string `json:"id"`
+
+	// name of the preset
+	Name string `json:"name"`
+
+	// description of the preset
+	Description string `json:"description,omitempty"`
+
+	// container of the outputs generated by the preset
+	Container string `json:"container,omitempty"`
 }
 
 // SourceInfo contains information about media transcoded using the Transcoding
@@ -159,8 +348,8 @@ func GetProviderFactory(name string) (Factory, error) {
 	return factory, nil
 }
 
-// ListProviders returns the list of currently registered providers,
-// alphabetically ordered.
+// ListProviders returns the list of currently registered providers that are
+// properly configured and not disabled, alphabetically ordered.
 func ListProviders(c *config.Config) []string {
 	providerNames := make([]string, 0, len(providers))
 	for name, factory := range providers {
@@ -173,8 +362,9 @@ func ListProviders(c *config.Config) []string {
 }
 
 // DescribeProvider describes the given provider. It includes information about
-// the provider's capabilities and its current health state.
-func DescribeProvider(name string, c *config.Config) (*Description, error) {
+// the provider's capabilities and its current health state, checked within
+// the given context.
+func DescribeProvider(ctx context.Context, name string, c *config.Config) (*Description, error) {
 	factory, err := GetProviderFactory(name)
 	if err != nil {
 		return nil, err
@@ -187,7 +377,7 @@ func DescribeProvider(name string, c *config.Config) (*Description, error) {
 	description.Enabled = true
 	description.Capabilities = provider.Capabilities()
 	description.Health = Health{OK: true}
-	if err = provider.Healthcheck(); err != nil {
+	if err = provider.Healthcheck(ctx); err != nil {
 		description.Health = Health{OK: false, Message: err.Error()}
 	}
 	return &description, nil
@@ -1,9 +1,11 @@
 package provider
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/NYTimes/video-transcoding-api/config"
 )
@@ -149,7 +151,7 @@ func TestDescribeProvider(t *testing.T) {
 		},
 	}
 	for _, test := range tests {
-		description, err := DescribeProvider(test.input, &config.Config{})
+		description, err := DescribeProvider(context.Background(), test.input, &config.Config{})
 		if err != nil {
 			t.Error(err)
 		}
@@ -161,7 +163,7 @@ func TestDescribeProvider(t *testing.T) {
 
 func TestDescribeProviderNotFound(t *testing.T) {
 	providers = nil
-	description, err := DescribeProvider("anything", nil)
+	description, err := DescribeProvider(context.Background(), "anything", nil)
 	if err != ErrProviderNotFound {
 		t.Errorf("Wrong error. Want %#v. Got %#v", ErrProviderNotFound, err)
 	}
@@ -169,3 +171,35 @@ func TestDescribeProviderNotFound(t *testing.T) {
 		t.Errorf("Unexpected non-nil description: %#v", description)
 	}
 }
+
+func TestJobStatusComputeDurations(t *testing.T) {
+	submitted := time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+	started := submitted.Add(90 * time.Second)
+	completed := started.Add(10 * time.Minute)
+	var tests = []struct {
+		testCase     string
+		submitTime   time.Time
+		startTime    time.Time
+		completeTime time.Time
+
+		wantQueueDuration      time.Duration
+		wantProcessingDuration time.Duration
+	}{
+		{"all timestamps", submitted, started, completed, 90 * time.Second, 10 * time.Minute},
+		{"queued job", submitted, time.Time{}, time.Time{}, 0, 0},
+		{"running job", submitted, started, time.Time{}, 90 * time.Second, 0},
+		{"missing submit time", time.Time{}, started, completed, 0, 10 * time.Minute},
+		{"missing start time", submitted, time.Time{}, completed, 0, 0},
+		{"start before submission", started, submitted, completed, 0, 10*time.Minute + 90*time.Second},
+	}
+	for _, test := range tests {
+		status := JobStatus{SubmitTime: test.submitTime, StartTime: test.startTime, CompleteTime: test.completeTime}
+		status.ComputeDurations()
+		if status.QueueDuration != test.wantQueueDuration {
+			t.Errorf("%s: wrong queue duration. Want %s. Got %s", test.testCase, test.wantQueueDuration, status.QueueDuration)
+		}
+		if status.ProcessingDuration != test.wantProcessingDuration {
+			t.Errorf("%s: wrong processing duration. Want %s. Got %s", test.testCase, test.wantProcessingDuration, status.ProcessingDuration)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Modes of the Recorder.
+const (
+	// RecordModeRecord sends requests to the API of the provider, storing
+	// each request and its response in the file of the recorder.
+	RecordModeRecord = "record"
+
+	// RecordModeReplay serves the responses stored in the file of the
+	// recorder, without sending any request to the API of the provider.
+	RecordModeReplay = "replay"
+)
+
+// Interaction is a request sent to the API of a provider along with the
+// response it got. The file of a Recorder holds a JSON array of
+// interactions, in the order they happened.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request used for matching it when
+// replaying interactions. Headers aren't recorded, as they carry the
+// credentials and signatures of the requests.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an Interaction.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records the interactions with the
+// API of a provider to a file and replays them later, so tests can exercise
+// providers against interactions captured from their real APIs.
+//
+// In replay mode, each request gets the response of the first interaction
+// not replayed yet with the same method, URL and body, so repeated requests
+// (e.g. status checks of a running job) get their responses in the order
+// they were recorded.
+type Recorder struct {
+	mode string
+	path string
+	base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewRecorder returns a Recorder in the given mode that stores interactions
+// in the given file. Requests are sent with the given transport in record
+// mode, defaulting to http.DefaultTransport. In replay mode, the file must
+// exist.
+func NewRecorder(mode, path string, base http.RoundTripper) (*Recorder, error) {
+	r := Recorder{mode: mode, path: path, base: base}
+	switch mode {
+	case RecordModeRecord:
+		if r.base == nil {
+			r.base = http.DefaultTransport
+		}
+	case RecordModeReplay:
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &r.interactions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording %q: %s", path, err)
+		}
+		r.replayed = make([]bool, len(r.interactions))
+	default:
+		return nil, fmt.Errorf("invalid record mode %q, must be one of %s or %s", mode, RecordModeRecord, RecordModeReplay)
+	}
+	return &r, nil
+}
+
+// RoundTrip records or replays the given request, depending on the mode of
+// the recorder.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == RecordModeReplay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	if req.Body != nil {
+		req = cloneRequest(req)
+		req.Body = ioutil.NopCloser(bytes.NewBufferString(recorded.Body))
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(body),
+		},
+	})
+	return resp, r.save()
+}
+
+// save writes all the interactions recorded so far, so the file is usable
+// even if the recording is interrupted.
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, os.FileMode(0644))
+}
+
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		if r.replayed[i] || interaction.Request != recorded {
+			continue
+		}
+		r.replayed[i] = true
+		header := make(http.Header, len(interaction.Response.Header))
+		for name, values := range interaction.Response.Header {
+			header[name] = append([]string(nil), values...)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction left for %s %s in %q", recorded.Method, recorded.URL, r.path)
+}
+
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{Method: req.Method, URL: req.URL.String()}
+	if req.Body == nil {
+		return recorded, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return recorded, err
+	}
+	recorded.Body = string(body)
+	return recorded, nil
+}
+
+func cloneRequest(r *http.Request) *http.Request {
+	req := new(http.Request)
+	*req = *r
+	return req
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "job-lifecycle.json")
+	statuses := []string{"pending", "running", "complete"}
+	var checks int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("Location", "/jobs/1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`<job href="/jobs/1">` + string(body) + `</job>`))
+		case "GET":
+			w.Write([]byte(`<job href="/jobs/1"><status>` + statuses[checks] + `</status></job>`))
+			checks++
+		}
+	}))
+	type result struct {
+		status   int
+		location string
+		body     string
+	}
+	lifecycle := func(client *http.Client) ([]result, error) {
+		var results []result
+		req, _ := http.NewRequest("POST", server.URL+"/jobs", strings.NewReader("<input>video.mov</input>"))
+		req.Header.Set("X-Auth-Key", "secret-signature")
+		for i := 0; i <= len(statuses); i++ {
+			if i > 0 {
+				req, _ = http.NewRequest("GET", server.URL+"/jobs/1", nil)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return results, err
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			results = append(results, result{resp.StatusCode, resp.Header.Get("Location"), string(body)})
+		}
+		return results, nil
+	}
+
+	recorder, err := NewRecorder(RecordModeRecord, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := lifecycle(&http.Client{Transport: recorder})
+	server.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-signature") {
+		t.Errorf("request headers shouldn't be recorded:\n%s", data)
+	}
+
+	replayer, err := NewRecorder(RecordModeReplay, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: replayer}
+	replayed, err := lifecycle(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != len(recorded) {
+		t.Fatalf("wrong number of replayed responses. Want %d. Got %d", len(recorded), len(replayed))
+	}
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Errorf("wrong response %d\nwant %#v\ngot  %#v", i, recorded[i], replayed[i])
+		}
+	}
+	if want := `<job href="/jobs/1"><status>complete</status></job>`; replayed[len(replayed)-1].body != want {
+		t.Errorf("wrong last response. Want %q. Got %q", want, replayed[len(replayed)-1].body)
+	}
+	_, err = client.Get(server.URL + "/jobs/1")
+	if err == nil {
+		t.Error("unexpected <nil> error after replaying all the interactions")
+	}
+}
+
+func TestRecorderReplayUnmatchedRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.json")
+	err = ioutil.WriteFile(path, []byte(`[
+  {
+    "request": {"method": "POST", "url": "http://provider/jobs", "body": "<input>video.mov</input>"},
+    "response": {"statusCode": 201, "body": "<job/>"}
+  }
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := NewRecorder(RecordModeReplay, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder}
+	_, err = client.Post("http://provider/jobs", "application/xml", strings.NewReader("<input>other.mov</input>"))
+	if err == nil {
+		t.Error("unexpected <nil> error for a request with a different body")
+	}
+	resp, err := client.Post("http://provider/jobs", "application/xml", strings.NewReader("<input>video.mov</input>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("wrong status code. Want %d. Got %d", http.StatusCreated, resp.StatusCode)
+	}
+}
+
+func TestNewRecorderErrors(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenMode     string
+		givenPath     string
+		givenContent  string
+
+		wantErr string
+	}{
+		{"invalid mode", "rewind", "recording.json", "", `invalid record mode "rewind", must be one of record or replay`},
+		{"invalid recording", RecordModeReplay, "recording.json", "{", `invalid recording "%s": unexpected end of JSON input`},
+		{"missing recording", RecordModeReplay, "missing.json", "", ""},
+	}
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "recorder")
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, test.givenPath)
+		if test.givenContent != "" {
+			ioutil.WriteFile(path, []byte(test.givenContent), 0644)
+		}
+		_, err = NewRecorder(test.givenMode, path, nil)
+		os.RemoveAll(dir)
+		if err == nil {
+			t.Errorf("%s: unexpected <nil> error", test.givenTestCase)
+			continue
+		}
+		if want := strings.Replace(test.wantErr, "%s", path, 1); want != "" && err.Error() != want {
+			t.Errorf("%s: wrong error\nwant %q\ngot  %q", test.givenTestCase, want, err.Error())
+		}
+	}
+}
@@ -16,6 +16,7 @@
 package zencoder
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
@@ -58,7 +59,7 @@ type zencoderProvider struct {
 	db     db.Repository
 }
 
-func (z *zencoderProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+func (z *zencoderProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	outputs, err := z.buildOutputs(job)
 	if err != nil {
 		return nil, err
@@ -260,7 +261,7 @@ func (z *zencoderProvider) buildOutput(job *db.Job, preset db.Preset, filename s
 	return zencoderOutput, nil
 }
 
-func (z *zencoderProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (z *zencoderProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
 	jobID, err := strconv.ParseInt(job.ProviderJobID, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("error converting job ID (%q): %s", job.ID, err)
@@ -352,7 +353,7 @@ func (z *zencoderProvider) getJobOutputs(job *db.Job, outputMediaFiles []*zencod
 	}, nil
 }
 
-func (z *zencoderProvider) CancelJob(id string) error {
+func (z *zencoderProvider) CancelJob(ctx context.Context, id string) error {
 	jobID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return fmt.Errorf("error canceling job %s: %s", id, err)
@@ -360,7 +361,7 @@ func (z *zencoderProvider) CancelJob(id string) error {
 	return z.client.CancelJob(jobID)
 }
 
-func (z *zencoderProvider) Healthcheck() error {
+func (z *zencoderProvider) Healthcheck(ctx context.Context) error {
 	_, err := z.client.GetVodUsage(nil)
 	return err
 }
@@ -397,10 +398,14 @@ func (z *zencoderProvider) Capabilities() provider.Capabilities {
 }
 
 func zencoderFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	if cfg.Zencoder.Disabled {
+		return nil, provider.ErrProviderDisabled
+	}
 	if cfg.Zencoder.APIKey == "" {
 		return nil, errZencoderInvalidConfig
 	}
 	client := zencoder.NewZencoder(cfg.Zencoder.APIKey)
+	client.Client = provider.HTTPClient(cfg, 0)
 	dbRepo, err := redis.NewRepository(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("Error initializing zencoder wrapper: %s", err)
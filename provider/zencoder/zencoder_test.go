@@ -1,8 +1,11 @@
 package zencoder
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"testing"
@@ -39,6 +42,10 @@ func TestZencoderFactory(t *testing.T) {
 		t.Fatalf("Wrong provider returned. Want zencoderProvider instance. Got %#v.", prov)
 	}
 	expected := zencoder.NewZencoder("api-key-here")
+	expected.Client = zencoderProvider.client.(*zencoder.Zencoder).Client
+	if expected.Client == http.DefaultClient {
+		t.Error("Factory: client created without the HTTP client of the providers")
+	}
 	if !reflect.DeepEqual(zencoderProvider.client, expected) {
 		t.Errorf("Factory: wrong client returned. Want %#v. Got %#v.", expected, zencoderProvider.client)
 	}
@@ -47,6 +54,46 @@ func TestZencoderFactory(t *testing.T) {
 	}
 }
 
+func TestZencoderFactoryProviderHeaders(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	cfg := config.Config{
+		Zencoder:          &config.Zencoder{APIKey: "api-key-here"},
+		Redis:             new(storage.Config),
+		ProviderUserAgent: "video-transcoding-api-staging",
+		ProviderHeaders:   map[string]string{"X-Environment": "staging"},
+	}
+	prov, err := zencoderFactory(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prov.(*zencoderProvider).client.(*zencoder.Zencoder)
+	client.BaseUrl = server.URL
+	err = prov.CancelJob(context.Background(), "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("wrong number of requests. Want 1. Got %d", len(requests))
+	}
+	if got := requests[0].Header.Get("User-Agent"); got != "video-transcoding-api-staging" {
+		t.Errorf("wrong User-Agent. Want %q. Got %q", "video-transcoding-api-staging", got)
+	}
+	if got := requests[0].Header.Get("X-Environment"); got != "staging" {
+		t.Errorf("wrong X-Environment header. Want %q. Got %q", "staging", got)
+	}
+	if got := requests[0].Header.Get("Zencoder-Api-Key"); got != "api-key-here" {
+		t.Errorf("wrong Zencoder-Api-Key header. Want %q. Got %q", "api-key-here", got)
+	}
+	if got := client.Header.Get("User-Agent"); got != "gozencoder v1" {
+		t.Errorf("headers of the client changed by the request. Got User-Agent %q", got)
+	}
+}
+
 func TestZencoderFactoryValidation(t *testing.T) {
 	cfg := config.Config{Zencoder: &config.Zencoder{APIKey: "api-key"}}
 	prov, err := zencoderFactory(&cfg)
@@ -67,6 +114,17 @@ func TestZencoderFactoryValidation(t *testing.T) {
 	}
 }
 
+func TestZencoderFactoryDisabled(t *testing.T) {
+	cfg := config.Config{Zencoder: &config.Zencoder{APIKey: "api-key", Disabled: true}}
+	prov, err := zencoderFactory(&cfg)
+	if prov != nil {
+		t.Errorf("Unexpected non-nil provider: %#v", prov)
+	}
+	if err != provider.ErrProviderDisabled {
+		t.Errorf("Wrong error returned. Want provider.ErrProviderDisabled. Got %#v", err)
+	}
+}
+
 func TestZencoderCapabilities(t *testing.T) {
 	var prov zencoderProvider
 	expected := provider.Capabilities{
@@ -283,7 +341,7 @@ func TestZencoderTranscode(t *testing.T) {
 			},
 		},
 	}
-	jobStatus, err := prov.Transcode(&db.Job{
+	jobStatus, err := prov.Transcode(context.Background(), &db.Job{
 		ID:              "job-123",
 		SourceMedia:     "dir/file.mov",
 		Outputs:         outputs,
@@ -820,7 +878,7 @@ func TestZencoderHealthcheck(t *testing.T) {
 		db:     dbRepo,
 	}
 
-	err = prov.Healthcheck()
+	err = prov.Healthcheck(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -842,7 +900,7 @@ func TestZencoderCancelJob(t *testing.T) {
 		db:     dbRepo,
 	}
 
-	err = prov.CancelJob("123")
+	err = prov.CancelJob(context.Background(), "123")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -998,7 +1056,7 @@ func TestZencoderJobStatus(t *testing.T) {
 		},
 	}
 	for _, test := range tests {
-		jobStatus, err := prov.JobStatus(&db.Job{ProviderJobID: test.ProviderJobID})
+		jobStatus, err := prov.JobStatus(context.Background(), &db.Job{ProviderJobID: test.ProviderJobID})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+var (
+	errAdminDisabled     = errors.New("admin endpoint disabled, ADMIN_TOKEN is not set")
+	errAdminUnauthorized = errors.New("missing or invalid admin token")
+)
+
+// adminOnly guards the given handler, only calling it for requests
+// authenticated with the admin token in a bearer Authorization header.
+func (s *TranscodingService) adminOnly(h swagger.Handler) swagger.Handler {
+	return func(r *http.Request) swagger.GizmoJSONResponse {
+		if s.config.AdminToken == "" {
+			return swagger.NewErrorResponse(errAdminDisabled).WithStatus(http.StatusForbidden)
+		}
+		const prefix = "Bearer "
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, prefix) ||
+			subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(s.config.AdminToken)) != 1 {
+			return swagger.NewErrorResponse(errAdminUnauthorized).WithStatus(http.StatusUnauthorized)
+		}
+		return h(r)
+	}
+}
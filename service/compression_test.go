@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestResponseCompression(t *testing.T) {
+	largeBody := bytes.Repeat([]byte(`{"jobId":"job-123","status":"finished"},`), 100)
+	smallBody := []byte(`{"jobId":"job-123","status":"finished"}`)
+	var tests = []struct {
+		givenTestCase        string
+		givenAcceptEncoding  string
+		givenContentType     string
+		givenContentEncoding string
+		givenBody            []byte
+
+		wantContentEncoding string
+	}{
+		{
+			"large JSON response",
+			"gzip",
+			"application/json; charset=utf-8",
+			"",
+			largeBody,
+			"gzip",
+		},
+		{
+			"large XML response",
+			"gzip, deflate",
+			"application/xml",
+			"",
+			largeBody,
+			"gzip",
+		},
+		{
+			"client without gzip support",
+			"",
+			"application/json; charset=utf-8",
+			"",
+			largeBody,
+			"",
+		},
+		{
+			"small response",
+			"gzip",
+			"application/json; charset=utf-8",
+			"",
+			smallBody,
+			"",
+		},
+		{
+			"already compressed content type",
+			"gzip",
+			"application/gzip",
+			"",
+			largeBody,
+			"",
+		},
+		{
+			"already encoded response",
+			"gzip",
+			"application/json",
+			"br",
+			largeBody,
+			"br",
+		},
+	}
+	for _, test := range tests {
+		logger := logrus.New()
+		logger.Out = ioutil.Discard
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, GzipMinSize: 1024}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := service.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", test.givenContentType)
+			if test.givenContentEncoding != "" {
+				w.Header().Set("Content-Encoding", test.givenContentEncoding)
+			}
+			w.Write(test.givenBody)
+		}))
+		r, _ := http.NewRequest("GET", "/jobs/job-123", nil)
+		if test.givenAcceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", test.givenAcceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if encoding := w.Header().Get("Content-Encoding"); encoding != test.wantContentEncoding {
+			t.Errorf("%s: wrong Content-Encoding. Want %q. Got %q", test.givenTestCase, test.wantContentEncoding, encoding)
+			continue
+		}
+		body := w.Body.Bytes()
+		if test.wantContentEncoding == "gzip" {
+			reader, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("%s: %s", test.givenTestCase, err)
+			}
+			body, err = ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("%s: %s", test.givenTestCase, err)
+			}
+		}
+		if !bytes.Equal(body, test.givenBody) {
+			t.Errorf("%s: wrong body\nwant %s\ngot  %s", test.givenTestCase, test.givenBody, body)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%s: wrong Vary header. Want %q. Got %q", test.givenTestCase, "Accept-Encoding", vary)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+// providerAtCapacityError is returned when the running jobs of a provider
+// reach its concurrency limit.
+type providerAtCapacityError string
+
+func (name providerAtCapacityError) Error() string {
+	return fmt.Sprintf("provider %q is at capacity, please try again later", string(name))
+}
+
+// transcode sends the given job to the provider, within the concurrency limit
+// of the provider. The submission counts against the limit until the returned
+// function is called, which callers do once the job is stored, so it's then
+// counted among the running jobs of the provider.
+func (s *TranscodingService) transcode(ctx context.Context, job *db.Job, p provider.TranscodingProvider, providerName string) (*provider.JobStatus, func(), error) {
+	ok, err := s.providersLimiter.acquire(ctx, providerName, p, runningJobsOf{s}, s.now())
+	if err != nil {
+		s.logger.WithError(err).Error("unable to check the capacity of provider")
+	}
+	if !ok {
+		return nil, func() {}, providerAtCapacityError(providerName)
+	}
+	release := func() { s.providersLimiter.release(providerName) }
+	status, err := p.Transcode(ctx, job)
+	if err != nil {
+		release()
+		return nil, func() {}, err
+	}
+	return status, release, nil
+}
+
+// runningJobsCounter counts the jobs running in providers.
+type runningJobsCounter interface {
+	CountRunningJobs(providerName string) (int, error)
+
+	// RefreshRunningJobs retrieves the status of the jobs counted as
+	// running in the given provider, storing the ones that changed.
+	RefreshRunningJobs(ctx context.Context, providerName string, p provider.TranscodingProvider) error
+}
+
+// runningJobsOf counts the running jobs stored in the repository of the
+// service. The status of jobs is only stored when it's retrieved, so jobs
+// that completed without their status being retrieved are counted until
+// they're refreshed.
+type runningJobsOf struct {
+	s *TranscodingService
+}
+
+func (r runningJobsOf) CountRunningJobs(providerName string) (int, error) {
+	return r.s.db.CountRunningJobs(providerName)
+}
+
+// RefreshRunningJobs retrieves the status of each running job as requests
+// for their status do, so jobs that completed are stored as such, and act
+// on as well.
+func (r runningJobsOf) RefreshRunningJobs(ctx context.Context, providerName string, p provider.TranscodingProvider) error {
+	jobs, err := r.s.db.ListRunningJobs(providerName)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for i := range jobs {
+		_, err = r.s.jobStatus(ctx, &jobs[i], p)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("job %q: %s", jobs[i].ID, err))
+		}
+	}
+	return joinErrors(errs)
+}
+
+// concurrencyLimiter bounds the number of jobs running in each provider,
+// counting the jobs sent to the provider that are queued or started along
+// with the submissions in progress. The limit of providers able to report
+// their active nodes is the number of nodes times the multiplier, refreshed
+// once it's older than the refresh interval, so it follows the size of the
+// cluster. The configured limit applies to other providers, and to providers
+// whose nodes are unknown, including those reporting no nodes. 0 means no
+// limit. Once the running jobs reach the limit, their status is refreshed
+// from the provider, at most once per refresh interval, so jobs that
+// completed without their status being retrieved stop counting.
+type concurrencyLimiter struct {
+	multiplier      uint
+	refreshInterval time.Duration
+	defaultLimit    uint
+
+	mu        sync.Mutex
+	providers map[string]*providerCapacity
+}
+
+type providerCapacity struct {
+	// limit of running jobs derived from the active nodes, -1 while
+	// unknown
+	limit     int
+	refreshed time.Time
+	inFlight  int
+
+	// last refresh of the status of the running jobs
+	jobsRefreshed time.Time
+}
+
+func newConcurrencyLimiter(multiplier, refreshInterval, defaultLimit uint) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		multiplier:      multiplier,
+		refreshInterval: time.Duration(refreshInterval) * time.Second,
+		defaultLimit:    defaultLimit,
+		providers:       make(map[string]*providerCapacity),
+	}
+}
+
+// acquire takes a slot of the given provider for a submission, returning
+// false when the running jobs reach the limit. Slots taken must be given
+// back with release. Failures to refresh the number of nodes of the provider,
+// to count its running jobs or to refresh their status are returned along
+// with the result, which then uses the previous limit, counts only the
+// submissions in progress or keeps counting the jobs that weren't refreshed.
+func (l *concurrencyLimiter) acquire(ctx context.Context, name string, p provider.TranscodingProvider, jobs runningJobsCounter, now time.Time) (bool, error) {
+	if l == nil {
+		return true, nil
+	}
+	var errs []string
+	if reporter, ok := p.(provider.CapacityReporter); ok && l.multiplier > 0 && l.needsRefresh(name, now) {
+		nodes, err := reporter.ActiveNodes(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error retrieving the active nodes of provider %q: %s", name, err))
+		}
+		l.mu.Lock()
+		capacity := l.capacity(name)
+		if err == nil {
+			capacity.limit = -1
+			if nodes > 0 {
+				capacity.limit = nodes * int(l.multiplier)
+			}
+		}
+		capacity.refreshed = now
+		l.mu.Unlock()
+	}
+	limit := l.limit(name)
+	if limit < 0 {
+		return true, joinErrors(errs)
+	}
+	running, err := jobs.CountRunningJobs(name)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("error counting the running jobs of provider %q: %s", name, err))
+		running = 0
+	}
+	if running+l.inFlight(name) >= limit && running > 0 && l.claimJobsRefresh(name, now) {
+		err = jobs.RefreshRunningJobs(ctx, name, p)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error refreshing the running jobs of provider %q: %s", name, err))
+		}
+		running, err = jobs.CountRunningJobs(name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error counting the running jobs of provider %q: %s", name, err))
+			running = 0
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	capacity := l.capacity(name)
+	if running+capacity.inFlight >= limit {
+		return false, joinErrors(errs)
+	}
+	capacity.inFlight++
+	return true, joinErrors(errs)
+}
+
+// release gives back a slot taken with acquire.
+func (l *concurrencyLimiter) release(name string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if capacity := l.capacity(name); capacity.inFlight > 0 {
+		capacity.inFlight--
+	}
+}
+
+// limit returns the current limit of running jobs in the given provider, -1
+// when it isn't limited.
+func (l *concurrencyLimiter) limit(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit := l.capacity(name).limit; limit >= 0 {
+		return limit
+	}
+	if l.defaultLimit > 0 {
+		return int(l.defaultLimit)
+	}
+	return -1
+}
+
+func (l *concurrencyLimiter) inFlight(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.capacity(name).inFlight
+}
+
+// claimJobsRefresh reports whether the status of the running jobs of the
+// given provider should be refreshed, recording the refresh when it should,
+// so concurrent and nested submissions don't refresh them again.
+func (l *concurrencyLimiter) claimJobsRefresh(name string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	capacity := l.capacity(name)
+	if !capacity.jobsRefreshed.IsZero() && now.Sub(capacity.jobsRefreshed) < l.refreshInterval {
+		return false
+	}
+	capacity.jobsRefreshed = now
+	return true
+}
+
+func (l *concurrencyLimiter) needsRefresh(name string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	capacity := l.capacity(name)
+	return capacity.refreshed.IsZero() || now.Sub(capacity.refreshed) >= l.refreshInterval
+}
+
+// capacity returns the capacity of the given provider, creating it when
+// missing. Must be called with the lock held.
+func (l *concurrencyLimiter) capacity(name string) *providerCapacity {
+	capacity, ok := l.providers[name]
+	if !ok {
+		capacity = &providerCapacity{limit: -1}
+		l.providers[name] = capacity
+	}
+	return capacity
+}
+
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
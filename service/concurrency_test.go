@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/sirupsen/logrus"
+)
+
+type capacityProvider struct {
+	provider.TranscodingProvider
+	nodes int
+	err   error
+}
+
+func (p *capacityProvider) ActiveNodes(ctx context.Context) (int, error) {
+	return p.nodes, p.err
+}
+
+type runningJobs struct {
+	count int
+	err   error
+
+	// jobs counted as running that completed in the provider
+	completed  int
+	refreshes  int
+	refreshErr error
+}
+
+func (r *runningJobs) CountRunningJobs(providerName string) (int, error) {
+	return r.count, r.err
+}
+
+func (r *runningJobs) RefreshRunningJobs(ctx context.Context, providerName string, p provider.TranscodingProvider) error {
+	r.refreshes++
+	r.count -= r.completed
+	r.completed = 0
+	return r.refreshErr
+}
+
+func TestConcurrencyLimiterTracksNodes(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	prov := capacityProvider{nodes: 3}
+	var running runningJobs
+	limiter := newConcurrencyLimiter(2, 60, 0)
+	tests := []struct {
+		givenTestCase string
+		givenNodes    int
+		givenElapsed  time.Duration
+		givenRunning  int
+		givenAcquire  int
+
+		wantLimit    int
+		wantAcquired int
+	}{
+		{"initial nodes", 3, 0, 0, 7, 6, 6},
+		{"scale down before the refresh", 1, 30 * time.Second, 6, 1, 6, 0},
+		{"scale down after the refresh", 1, 30 * time.Second, 4, 1, 2, 0},
+		{"jobs finished below the new limit", 1, 0, 1, 2, 2, 1},
+		{"scale up", 4, time.Minute, 2, 8, 8, 6},
+		{"scale down to zero nodes", 0, time.Minute, 8, 3, -1, 3},
+	}
+	for _, test := range tests {
+		prov.nodes = test.givenNodes
+		running.count = test.givenRunning
+		now = now.Add(test.givenElapsed)
+		var acquired int
+		for i := 0; i < test.givenAcquire; i++ {
+			ok, err := limiter.acquire(context.Background(), "elementalconductor", &prov, &running, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				acquired++
+			}
+		}
+		if limit := limiter.limit("elementalconductor"); limit != test.wantLimit {
+			t.Errorf("%s: wrong limit. Want %d. Got %d", test.givenTestCase, test.wantLimit, limit)
+		}
+		if acquired != test.wantAcquired {
+			t.Errorf("%s: wrong number of acquired slots. Want %d. Got %d", test.givenTestCase, test.wantAcquired, acquired)
+		}
+		for i := 0; i < acquired; i++ {
+			limiter.release("elementalconductor")
+		}
+	}
+}
+
+func TestConcurrencyLimiterDefaultLimit(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		givenTestCase   string
+		givenMultiplier uint
+		givenProvider   provider.TranscodingProvider
+
+		wantLimit int
+	}{
+		{"provider without active nodes", 2, &capacityProvider{}, 4},
+		{"provider with active nodes", 2, &capacityProvider{nodes: 3}, 6},
+		{"provider unable to report its nodes", 2, &struct{ provider.TranscodingProvider }{}, 4},
+		{"nodes disabled", 0, &capacityProvider{nodes: 3}, 4},
+	}
+	for _, test := range tests {
+		limiter := newConcurrencyLimiter(test.givenMultiplier, 60, 4)
+		running := runningJobs{count: test.wantLimit - 1}
+		ok, err := limiter.acquire(context.Background(), "someprovider", test.givenProvider, &running, now)
+		if !ok || err != nil {
+			t.Errorf("%s: unexpected result acquiring slot below the limit: %v, %v", test.givenTestCase, ok, err)
+		}
+		ok, err = limiter.acquire(context.Background(), "someprovider", test.givenProvider, &running, now)
+		if ok || err != nil {
+			t.Errorf("%s: unexpected result acquiring slot at the limit: %v, %v", test.givenTestCase, ok, err)
+		}
+		if limit := limiter.limit("someprovider"); limit != test.wantLimit {
+			t.Errorf("%s: wrong limit. Want %d. Got %d", test.givenTestCase, test.wantLimit, limit)
+		}
+	}
+}
+
+func TestConcurrencyLimiterRefreshError(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	prov := capacityProvider{nodes: 1}
+	var running runningJobs
+	limiter := newConcurrencyLimiter(1, 60, 0)
+	ok, err := limiter.acquire(context.Background(), "elementalconductor", &prov, &running, now)
+	if !ok || err != nil {
+		t.Fatalf("unexpected result acquiring slot: %v, %v", ok, err)
+	}
+	limiter.release("elementalconductor")
+	prov.err = errors.New("connection refused")
+	ok, err = limiter.acquire(context.Background(), "elementalconductor", &prov, &running, now.Add(time.Minute))
+	wantErr := `error retrieving the active nodes of provider "elementalconductor": connection refused`
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("wrong error. Want %q. Got %v", wantErr, err)
+	}
+	if !ok {
+		t.Error("slot not acquired with the previous limit")
+	}
+	if limit := limiter.limit("elementalconductor"); limit != 1 {
+		t.Errorf("wrong limit. Want 1. Got %d", limit)
+	}
+}
+
+func TestConcurrencyLimiterCountError(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	running := runningJobs{count: 5, err: errors.New("database error")}
+	limiter := newConcurrencyLimiter(1, 60, 1)
+	ok, err := limiter.acquire(context.Background(), "someprovider", &struct{ provider.TranscodingProvider }{}, &running, now)
+	wantErr := `error counting the running jobs of provider "someprovider": database error`
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("wrong error. Want %q. Got %v", wantErr, err)
+	}
+	if !ok {
+		t.Error("slot not acquired with the submissions in progress")
+	}
+	ok, _ = limiter.acquire(context.Background(), "someprovider", &struct{ provider.TranscodingProvider }{}, &running, now)
+	if ok {
+		t.Error("slot acquired beyond the submissions in progress")
+	}
+}
+
+func TestConcurrencyLimiterRefreshesRunningJobs(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	prov := struct{ provider.TranscodingProvider }{}
+	running := runningJobs{count: 2}
+	limiter := newConcurrencyLimiter(0, 60, 2)
+	var tests = []struct {
+		givenTestCase  string
+		givenElapsed   time.Duration
+		givenCompleted int
+
+		wantAcquired  bool
+		wantRefreshes int
+		wantRunning   int
+	}{
+		{"jobs still running", 0, 0, false, 1, 2},
+		{"job completed before the refresh interval", 30 * time.Second, 1, false, 1, 2},
+		{"job completed after the refresh interval", 30 * time.Second, 1, true, 2, 1},
+		{"below the limit", 5 * time.Minute, 1, true, 2, 1},
+	}
+	for _, test := range tests {
+		now = now.Add(test.givenElapsed)
+		if test.givenCompleted > running.completed {
+			running.completed = test.givenCompleted
+		}
+		ok, err := limiter.acquire(context.Background(), "someprovider", &prov, &running, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != test.wantAcquired {
+			t.Errorf("%s: wrong result acquiring slot. Want %v. Got %v", test.givenTestCase, test.wantAcquired, ok)
+		}
+		if ok {
+			limiter.release("someprovider")
+		}
+		if running.refreshes != test.wantRefreshes {
+			t.Errorf("%s: wrong number of refreshes. Want %d. Got %d", test.givenTestCase, test.wantRefreshes, running.refreshes)
+		}
+		if running.count != test.wantRunning {
+			t.Errorf("%s: wrong number of running jobs. Want %d. Got %d", test.givenTestCase, test.wantRunning, running.count)
+		}
+	}
+}
+
+func TestConcurrencyLimiterRefreshRunningJobsError(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	running := runningJobs{count: 1, refreshErr: errors.New("provider error")}
+	limiter := newConcurrencyLimiter(0, 60, 1)
+	ok, err := limiter.acquire(context.Background(), "someprovider", &struct{ provider.TranscodingProvider }{}, &running, now)
+	wantErr := `error refreshing the running jobs of provider "someprovider": provider error`
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("wrong error. Want %q. Got %v", wantErr, err)
+	}
+	if ok {
+		t.Error("slot acquired with the jobs that weren't refreshed")
+	}
+}
+
+func TestConcurrencyLimiterWithoutCapacity(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		givenTestCase   string
+		givenMultiplier uint
+		givenProvider   provider.TranscodingProvider
+	}{
+		{"provider unable to report its nodes", 2, &struct{ provider.TranscodingProvider }{}},
+		{"provider without active nodes", 2, &capacityProvider{}},
+		{"limiter disabled", 0, &capacityProvider{nodes: 1}},
+	}
+	for _, test := range tests {
+		limiter := newConcurrencyLimiter(test.givenMultiplier, 60, 0)
+		running := runningJobs{count: 100}
+		for i := 0; i < 100; i++ {
+			ok, err := limiter.acquire(context.Background(), "someprovider", test.givenProvider, &running, now)
+			if !ok || err != nil {
+				t.Fatalf("%s: unexpected result acquiring slot: %v, %v", test.givenTestCase, ok, err)
+			}
+		}
+	}
+}
+
+func TestTranscodeProviderAtCapacity(t *testing.T) {
+	defer func() {
+		fprovider.jobs = nil
+		fprovider.activeNodes = 0
+	}()
+	var tests = []struct {
+		givenTestCase   string
+		givenNodes      int
+		givenMultiplier uint
+		givenLimit      uint
+		givenRunning    int
+
+		wantCode int
+	}{
+		{"provider with active nodes", 2, 4, 0, 7, http.StatusOK},
+		{"provider with active nodes at capacity", 2, 4, 0, 8, http.StatusServiceUnavailable},
+		{"provider without active nodes", 0, 4, 0, 8, http.StatusOK},
+		{"provider without active nodes at the default limit", 0, 4, 3, 3, http.StatusServiceUnavailable},
+		{"limiter disabled", 2, 0, 0, 8, http.StatusOK},
+	}
+	for _, test := range tests {
+		fprovider.jobs = nil
+		fprovider.activeNodes = test.givenNodes
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		for i := 0; i < test.givenRunning; i++ {
+			fakeDBObj.CreateJob(&db.Job{
+				ID:            fmt.Sprintf("job-%d", i),
+				ProviderName:  "fake",
+				ProviderJobID: "provider-job-running",
+				Status:        "started",
+			})
+		}
+		service, err := NewTranscodingService(&config.Config{
+			Server:                          &server.Config{},
+			ProviderConcurrencyMultiplier:   test.givenMultiplier,
+			ProviderCapacityRefreshInterval: 60,
+			ProviderConcurrencyLimit:        test.givenLimit,
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		body := `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}]}`
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+			continue
+		}
+		if test.wantCode == http.StatusOK {
+			continue
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		wantErr := `provider "fake" is at capacity, please try again later`
+		if got["error"] != wantErr {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, wantErr, got["error"])
+		}
+		if len(fprovider.jobs) > 0 {
+			t.Errorf("%s: unexpected job sent to the provider: %#v", test.givenTestCase, fprovider.jobs)
+		}
+	}
+}
+
+func TestTranscodeRefreshesCompletedRunningJobs(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	// finished in the provider, without its status being retrieved
+	fakeDBObj.CreateJob(&db.Job{
+		ID:            "job-finished",
+		ProviderName:  "fake",
+		ProviderJobID: "provider-preset-job-123",
+		Status:        "started",
+	})
+	service, err := NewTranscodingService(&config.Config{
+		Server:                          &server.Config{},
+		ProviderCapacityRefreshInterval: 60,
+		ProviderConcurrencyLimit:        1,
+	}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	body := `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}]}`
+	r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	job, err := fakeDBObj.GetJob("job-finished")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != "finished" {
+		t.Errorf("wrong status of the completed job. Want %q. Got %q", "finished", job.Status)
+	}
+	if len(fprovider.jobs) != 1 {
+		t.Errorf("wrong number of submissions to the provider. Want 1. Got %d", len(fprovider.jobs))
+	}
+}
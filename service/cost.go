@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/NYTimes/gizmo/web"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// response for the getJobCost operation.
+//
+// swagger:response jobCost
+type jobCostResponse struct {
+	// in: body
+	Payload *provider.JobCost
+
+	baseResponse
+}
+
+// error returned when the job is still in progress.
+//
+// swagger:response jobInProgress
+type jobInProgressResponse struct {
+	// in: body
+	Error *swagger.ErrorResponse
+}
+
+func (r *jobInProgressResponse) Result() (int, interface{}, error) {
+	return r.Error.Result()
+}
+
+// swagger:route GET /jobs/{jobId}/cost jobs getJobCost
+//
+// Retrieves the cost of a job, as billed by the provider. Only available
+// once the job is finished, failed or canceled.
+//
+//     Responses:
+//       200: jobCost
+//       404: jobNotFound
+//       409: jobInProgress
+//       410: jobNotFoundInTheProvider
+//       500: genericError
+//       501: genericError
+//       502: providerError
+func (s *TranscodingService) getJobCost(r *http.Request) swagger.GizmoJSONResponse {
+	var params getTranscodeJobCostInput
+	params.loadParams(web.Vars(r))
+	job, status, prov, err := s.getTranscodeJobByID(r.Context(), params.JobID)
+	if err != nil {
+		return jobCostErrorResponse(prov, err)
+	}
+	coster, ok := prov.(provider.JobCoster)
+	if !ok {
+		return swagger.NewErrorResponse(provider.ErrNotImplemented).WithStatus(http.StatusNotImplemented)
+	}
+	if !isTerminalStatus(status.Status) {
+		err = fmt.Errorf("job %q is %s, its cost is only available once it's finished, failed or canceled", job.ID, status.Status)
+		return &jobInProgressResponse{Error: swagger.NewErrorResponse(err).WithStatus(http.StatusConflict)}
+	}
+	cost, err := coster.GetJobCost(r.Context(), job.ProviderJobID)
+	if err != nil {
+		return jobCostErrorResponse(prov, err)
+	}
+	return &jobCostResponse{
+		baseResponse: baseResponse{payload: cost, status: http.StatusOK},
+	}
+}
+
+func jobCostErrorResponse(p provider.TranscodingProvider, err error) swagger.GizmoJSONResponse {
+	if err == provider.ErrNotImplemented {
+		return swagger.NewErrorResponse(err).WithStatus(http.StatusNotImplemented)
+	}
+	if err == db.ErrJobNotFound {
+		return newJobNotFoundResponse(err)
+	}
+	if _, ok := err.(provider.JobNotFoundError); ok {
+		return newJobNotFoundProviderResponse(err)
+	}
+	if p != nil {
+		return newProviderErrorResponse(err)
+	}
+	return swagger.NewErrorResponse(err)
+}
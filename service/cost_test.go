@@ -0,0 +1,98 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobCost(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenJobID    string
+
+		wantCode int
+		wantBody map[string]interface{}
+	}{
+		{
+			"finished job",
+			"job-123",
+			http.StatusOK,
+			map[string]interface{}{"billedMinutes": 3.5, "amount": 0.105, "currency": "USD"},
+		},
+		{
+			"running job",
+			"job-running",
+			http.StatusConflict,
+			map[string]interface{}{"error": `job "job-running" is started, its cost is only available once it's finished, failed or canceled`},
+		},
+		{
+			"queued job",
+			"job-queued",
+			http.StatusConflict,
+			map[string]interface{}{"error": `job "job-queued" is queued, its cost is only available once it's finished, failed or canceled`},
+		},
+		{
+			"job without cost in the provider",
+			"job-partial",
+			http.StatusNotImplemented,
+			map[string]interface{}{"error": "operation not supported by the provider"},
+		},
+		{
+			"provider error",
+			"job-crashed",
+			http.StatusBadGateway,
+			map[string]interface{}{"error": "internal server error"},
+		},
+		{
+			"job not found in the provider",
+			"job-gone",
+			http.StatusGone,
+			map[string]interface{}{"error": "could not found job with id: provider-job-gone"},
+		},
+		{
+			"job not found",
+			"job-404",
+			http.StatusNotFound,
+			map[string]interface{}{"error": "job not found"},
+		},
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-partial", ProviderName: "fake", ProviderJobID: "provider-job-partial"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-gone", ProviderName: "fake", ProviderJobID: "provider-job-gone"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/jobs/"+test.givenJobID+"/cost", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var body map[string]interface{}
+		err := json.NewDecoder(w.Body).Decode(&body)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if !reflect.DeepEqual(body, test.wantBody) {
+			t.Errorf("%s: wrong body\nwant %#v\ngot  %#v", test.givenTestCase, test.wantBody, body)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// isHeldJob reports whether the given job is held by the API, waiting for the
+// job it depends on, instead of being sent to the provider.
+func isHeldJob(job *db.Job) bool {
+	return job.DependsOn != "" && job.ProviderJobID == ""
+}
+
+// dependencyJob loads the job a new job depends on, ensuring it may still
+// finish according to its last known status.
+func (s *TranscodingService) dependencyJob(id string) (*db.Job, error) {
+	upstream, err := s.db.GetJob(id)
+	if err == db.ErrJobNotFound {
+		return nil, invalidField("dependsOn", fmt.Errorf("job %q not found", id))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status := provider.Status(upstream.Status); status == provider.StatusFailed || status == provider.StatusCanceled {
+		return nil, invalidField("dependsOn", fmt.Errorf("job %q is %s", id, status))
+	}
+	return upstream, nil
+}
+
+// holdJob stores the given job without sending it to the provider, which
+// happens once the job it depends on finishes. The job is registered in the
+// job it depends on, so it's resolved once that job completes.
+func (s *TranscodingService) holdJob(job *db.Job, upstream *db.Job, providerName string, skipped []SkippedOutput) swagger.GizmoJSONResponse {
+	var err error
+	job.ID, err = s.genID()
+	if err != nil {
+		return swagger.NewErrorResponse(err)
+	}
+	job.ProviderName = providerName
+	job.Status = string(provider.StatusQueued)
+	err = s.db.CreateJob(job)
+	if _, ok := err.(db.UnavailableError); ok {
+		s.logger.WithError(err).Error("unable to store new job")
+		return newDatastoreUnavailableResponse(s.config.DatastoreRetryAfter)
+	}
+	if err != nil {
+		return swagger.NewErrorResponse(err)
+	}
+	upstream.Dependents = append(upstream.Dependents, job.ID)
+	err = s.db.UpdateJob(upstream)
+	if err != nil {
+		// the job is still resolved whenever its status is retrieved
+		s.logger.WithError(err).WithField("jobId", job.ID).Error("unable to register job in the job it depends on")
+	}
+	return newJobResponse(job.ID, skipped)
+}
+
+// resolveDependents checks the status of the jobs waiting for the given job
+// once it completes, so they're sent to the provider or failed without
+// waiting for their status to be retrieved. Failures are logged, and the
+// jobs are resolved again whenever their status is retrieved.
+func (s *TranscodingService) resolveDependents(ctx context.Context, job *db.Job, status *provider.JobStatus) {
+	if !isTerminalStatus(status.Status) {
+		return
+	}
+	for _, id := range job.Dependents {
+		_, _, _, err := s.getTranscodeJobByID(ctx, id)
+		if err != nil && err != db.ErrJobNotFound {
+			s.logger.WithError(err).WithField("jobId", id).Error("unable to resolve the dependency of job")
+		}
+	}
+}
+
+// heldJobStatus resolves the dependency of a held job, checking the status of
+// the job it depends on. The held job is sent to the provider once that job
+// finishes, and nil is returned so its status is retrieved from the
+// provider. It fails when that job fails, is canceled or is removed. Only
+// the request that claims either change acts on it.
+func (s *TranscodingService) heldJobStatus(ctx context.Context, job *db.Job, p provider.TranscodingProvider) (*provider.JobStatus, error) {
+	if isTerminalStatus(provider.Status(job.Status)) {
+		return heldJobTerminalStatus(job), nil
+	}
+	_, upstreamStatus, _, err := s.getTranscodeJobByID(ctx, job.DependsOn)
+	if err != nil && err != db.ErrJobNotFound {
+		return nil, fmt.Errorf("error retrieving the status of job %q, which job %q depends on: %s", job.DependsOn, job.ID, err)
+	}
+	if err == db.ErrJobNotFound || upstreamStatus.Status == provider.StatusFailed || upstreamStatus.Status == provider.StatusCanceled {
+		claimed := s.claimJobTransition(job, string(provider.StatusFailed))
+		job.Status = string(provider.StatusFailed)
+		if !claimed {
+			return heldJobTerminalStatus(job), nil
+		}
+		err = s.db.UpdateJob(job)
+		if err != nil {
+			return nil, fmt.Errorf("error storing the status of job %q: %s", job.ID, err)
+		}
+		status := heldJobTerminalStatus(job)
+		s.notifyJob(job, status)
+		s.resolveDependents(ctx, job, status)
+		return status, nil
+	}
+	if upstreamStatus.Status != provider.StatusFinished {
+		return heldJobQueuedStatus(job, fmt.Sprintf("waiting for job %q to finish", job.DependsOn)), nil
+	}
+	if !s.claimJobTransition(job, "submit") {
+		return heldJobQueuedStatus(job, "being sent to the provider"), nil
+	}
+	status, release, err := s.transcode(ctx, job, p, job.ProviderName)
+	defer release()
+	if err != nil {
+		s.releaseJobTransition(job, "submit")
+	}
+	if _, ok := err.(providerAtCapacityError); ok {
+		return heldJobQueuedStatus(job, err.Error()), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error sending job %q to the provider after job %q finished: %s", job.ID, job.DependsOn, err)
+	}
+	job.ProviderJobID = status.ProviderJobID
+	keepJobSpec(job, status)
+	job.Status = string(status.Status)
+	err = s.db.UpdateJob(job)
+	if err != nil {
+		return nil, fmt.Errorf("error storing job %q after sending it to the provider: %s", job.ID, err)
+	}
+	return nil, nil
+}
+
+// cancelHeldJob cancels a job still waiting for the job it depends on, so
+// it's never sent to the provider.
+func (s *TranscodingService) cancelHeldJob(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
+	if isTerminalStatus(provider.Status(job.Status)) {
+		return heldJobTerminalStatus(job), nil
+	}
+	job.Status = string(provider.StatusCanceled)
+	err := s.db.UpdateJob(job)
+	if err != nil {
+		return nil, fmt.Errorf("error storing the status of job %q: %s", job.ID, err)
+	}
+	status := heldJobTerminalStatus(job)
+	s.notifyJob(job, status)
+	s.resolveDependents(ctx, job, status)
+	return status, nil
+}
+
+func heldJobQueuedStatus(job *db.Job, message string) *provider.JobStatus {
+	return &provider.JobStatus{
+		ProviderName:  job.ProviderName,
+		Status:        provider.StatusQueued,
+		StatusMessage: message,
+		Test:          job.Test,
+	}
+}
+
+func heldJobTerminalStatus(job *db.Job) *provider.JobStatus {
+	status := provider.JobStatus{
+		ProviderName: job.ProviderName,
+		Status:       provider.Status(job.Status),
+		Test:         job.Test,
+	}
+	if status.Status == provider.StatusCanceled {
+		status.StatusMessage = fmt.Sprintf("job canceled while waiting for job %q", job.DependsOn)
+	} else {
+		status.StatusMessage = fmt.Sprintf("job %q, which this job depends on, didn't finish", job.DependsOn)
+	}
+	return &status
+}
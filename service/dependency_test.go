@@ -0,0 +1,260 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+type dependencyTestServer struct {
+	t    *testing.T
+	srvr *server.SimpleServer
+	repo db.Repository
+}
+
+func newDependencyTestServer(t *testing.T, jobs ...db.Job) *dependencyTestServer {
+	return newDependencyTestServerWithRepository(t, dbtest.NewFakeRepository(false), jobs...)
+}
+
+func newDependencyTestServerWithRepository(t *testing.T, repo db.Repository, jobs ...db.Job) *dependencyTestServer {
+	err := repo.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range jobs {
+		err = repo.CreateJob(&jobs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+	srvr.Register(service)
+	return &dependencyTestServer{t: t, srvr: srvr, repo: repo}
+}
+
+func (s *dependencyTestServer) do(method, uri, body string) (int, map[string]interface{}) {
+	r, _ := http.NewRequest(method, uri, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.srvr.ServeHTTP(w, r)
+	var got map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		s.t.Fatalf("%s %s: %s: %s", method, uri, err, w.Body.String())
+	}
+	return w.Code, got
+}
+
+func (s *dependencyTestServer) newDependentJob(dependsOn string) string {
+	code, got := s.do("POST", "/jobs", `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}],"dependsOn":"`+dependsOn+`"}`)
+	if code != http.StatusOK {
+		s.t.Fatalf("wrong response code creating dependent job. Want %d. Got %d: %#v", http.StatusOK, code, got)
+	}
+	return got["jobId"].(string)
+}
+
+func (s *dependencyTestServer) checkStatus(jobID, wantStatus, wantMessage string) {
+	code, got := s.do("GET", "/jobs/"+jobID, "")
+	if code != http.StatusOK {
+		s.t.Fatalf("wrong response code retrieving job %q. Want %d. Got %d: %#v", jobID, http.StatusOK, code, got)
+	}
+	if got["status"] != wantStatus {
+		s.t.Errorf("wrong status of job %q. Want %q. Got %v", jobID, wantStatus, got["status"])
+	}
+	if message, _ := got["statusMessage"].(string); message != wantMessage {
+		s.t.Errorf("wrong status message of job %q. Want %q. Got %q", jobID, wantMessage, message)
+	}
+}
+
+func TestDependentJobStartsAfterUpstreamFinishes(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	s := newDependencyTestServer(t, db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "started"})
+	jobID := s.newDependentJob("job-normalize")
+	if len(fprovider.jobs) > 0 {
+		t.Fatalf("dependent job sent to the provider before the job it depends on finished: %#v", fprovider.jobs)
+	}
+	s.checkStatus(jobID, "queued", `waiting for job "job-normalize" to finish`)
+	if len(fprovider.jobs) > 0 {
+		t.Fatalf("dependent job sent to the provider while the job it depends on is running: %#v", fprovider.jobs)
+	}
+
+	// the job it depends on finishes, which releases the dependent job
+	// without waiting for its status to be retrieved.
+	upstream, err := s.repo.GetJob("job-normalize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upstream.Dependents) != 1 || upstream.Dependents[0] != jobID {
+		t.Errorf("wrong dependents. Want [%q]. Got %#v", jobID, upstream.Dependents)
+	}
+	upstream.ProviderJobID = "provider-job-123"
+	s.checkStatus("job-normalize", "finished", "The job is finished")
+	if len(fprovider.jobs) != 1 || fprovider.jobs[0].ID != jobID {
+		t.Fatalf("dependent job not sent to the provider after the job it depends on finished: %#v", fprovider.jobs)
+	}
+	job, err := s.repo.GetJob(jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ProviderJobID != "provider-preset-job-123" {
+		t.Errorf("wrong provider job id. Want %q. Got %q", "provider-preset-job-123", job.ProviderJobID)
+	}
+	if job.DependsOn != "job-normalize" {
+		t.Errorf("wrong dependency. Want %q. Got %q", "job-normalize", job.DependsOn)
+	}
+	s.checkStatus(jobID, "finished", "The job is finished")
+	if len(fprovider.jobs) != 1 {
+		t.Errorf("dependent job sent to the provider more than once: %#v", fprovider.jobs)
+	}
+}
+
+func TestDependentJobStartsAfterUpstreamFinishesRedis(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	s := newDependencyTestServerWithRepository(t, newRedisRepository(t), db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "started"})
+	jobID := s.newDependentJob("job-normalize")
+	upstream, err := s.repo.GetJob("job-normalize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.ProviderJobID = "provider-job-123"
+	err = s.repo.UpdateJob(upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.checkStatus("job-normalize", "finished", "The job is finished")
+	if len(fprovider.jobs) != 1 || fprovider.jobs[0].ID != jobID {
+		t.Fatalf("dependent job not sent to the provider after the job it depends on finished: %#v", fprovider.jobs)
+	}
+	released := fprovider.jobs[0]
+	if released.SourceMedia != "http://some.source/video.mov" {
+		t.Errorf("wrong source of the released job. Want %q. Got %q", "http://some.source/video.mov", released.SourceMedia)
+	}
+	if len(released.Outputs) != 1 || released.Outputs[0].Preset.ProviderMapping["fake"] != "18828" {
+		t.Errorf("outputs of the held job not kept: %#v", released.Outputs)
+	}
+	s.checkStatus(jobID, "finished", "The job is finished")
+}
+
+func TestDependentJobWithFinishedUpstream(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	s := newDependencyTestServer(t, db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-123", Status: "finished"})
+	jobID := s.newDependentJob("job-normalize")
+	if len(fprovider.jobs) != 1 || fprovider.jobs[0].ID != jobID {
+		t.Fatalf("dependent job not sent to the provider right away: %#v", fprovider.jobs)
+	}
+}
+
+func TestDependentJobFailsWithUpstream(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	s := newDependencyTestServer(t, db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "started"})
+	jobID := s.newDependentJob("job-normalize")
+	chainedID := s.newDependentJob(jobID)
+	upstream, err := s.repo.GetJob("job-normalize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.ProviderJobID = "provider-job-bad-source"
+	s.checkStatus("job-normalize", "failed", "")
+	for _, id := range []string{jobID, chainedID} {
+		job, err := s.repo.GetJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != "failed" {
+			t.Errorf("wrong status stored for job %q. Want %q. Got %q", id, "failed", job.Status)
+		}
+	}
+	s.checkStatus(jobID, "failed", `job "job-normalize", which this job depends on, didn't finish`)
+	s.checkStatus(chainedID, "failed", `job "`+jobID+`", which this job depends on, didn't finish`)
+	if len(fprovider.jobs) > 0 {
+		t.Errorf("jobs sent to the provider after the job they depend on failed: %#v", fprovider.jobs)
+	}
+}
+
+func TestCancelHeldJob(t *testing.T) {
+	defer func() {
+		fprovider.jobs = nil
+		fprovider.canceledJobs = nil
+	}()
+	fprovider.jobs = nil
+	fprovider.canceledJobs = nil
+	s := newDependencyTestServer(t, db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "started"})
+	jobID := s.newDependentJob("job-normalize")
+	code, got := s.do("POST", "/jobs/"+jobID+"/cancel", "")
+	if code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d: %#v", http.StatusOK, code, got)
+	}
+	if got["status"] != "canceled" {
+		t.Errorf("wrong status. Want %q. Got %v", "canceled", got["status"])
+	}
+	if len(fprovider.canceledJobs) > 0 {
+		t.Errorf("unexpected jobs canceled in the provider: %#v", fprovider.canceledJobs)
+	}
+	upstream, err := s.repo.GetJob("job-normalize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.ProviderJobID = "provider-job-123"
+	s.checkStatus("job-normalize", "finished", "The job is finished")
+	s.checkStatus(jobID, "canceled", `job canceled while waiting for job "job-normalize"`)
+	if len(fprovider.jobs) > 0 {
+		t.Errorf("canceled job sent to the provider: %#v", fprovider.jobs)
+	}
+}
+
+func TestTranscodeDependsOnValidation(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenJob      db.Job
+
+		wantError string
+	}{
+		{
+			"missing job",
+			db.Job{ID: "job-other", ProviderName: "fake", ProviderJobID: "provider-job-123"},
+			`job "job-normalize" not found`,
+		},
+		{
+			"failed job",
+			db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-bad-source", Status: "failed"},
+			`job "job-normalize" is failed`,
+		},
+		{
+			"canceled job",
+			db.Job{ID: "job-normalize", ProviderName: "fake", ProviderJobID: "provider-job-123", Status: "canceled"},
+			`job "job-normalize" is canceled`,
+		},
+	}
+	for _, test := range tests {
+		s := newDependencyTestServer(t, test.givenJob)
+		code, got := s.do("POST", "/jobs", `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}],"dependsOn":"job-normalize"}`)
+		if code != http.StatusBadRequest {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusBadRequest, code)
+			continue
+		}
+		want := invalidJobBody(ValidationError{Field: "dependsOn", Message: test.wantError})
+		if got["error"] != want["error"] {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, want["error"], got["error"])
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NYTimes/gizmo/web"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// etaHistoryWindow is the time window of the finished jobs used for
+// estimating the completion of jobs.
+const etaHistoryWindow = 30 * 24 * time.Hour
+
+// Sources of the estimates in JobETA.
+const (
+	// etaBasisHistory estimates the completion using the processing time
+	// of similar finished jobs, before the job reports any progress.
+	etaBasisHistory = "history"
+
+	// etaBasisProgress estimates the completion extrapolating the
+	// progress reported by the provider, when there are no similar
+	// finished jobs.
+	etaBasisProgress = "progress"
+
+	// etaBasisBlended combines both estimates, relying more on the
+	// progress as the job advances.
+	etaBasisBlended = "blended"
+
+	// etaBasisComplete is used for jobs that are no longer running.
+	etaBasisComplete = "complete"
+
+	// etaBasisUnavailable is used when there's no data for estimating the
+	// completion of the job.
+	etaBasisUnavailable = "unavailable"
+)
+
+// JobETA is the estimated completion of a job.
+//
+// swagger:model
+type JobETA struct {
+	// id of the job
+	JobID string `json:"jobId"`
+
+	// last status of the job reported by the provider
+	Status provider.Status `json:"status"`
+
+	// progress of the job reported by the provider, from 0 to 100
+	Progress float64 `json:"progress"`
+
+	// estimated time, in seconds, until the job completes. Omitted when
+	// the completion can't be estimated
+	RemainingTime *float64 `json:"remainingTime,omitempty"`
+
+	// estimated time of the completion of the job. Omitted when it can't
+	// be estimated
+	EstimatedCompletionTime *time.Time `json:"estimatedCompletionTime,omitempty"`
+
+	// average processing time, in seconds, per minute of source of the
+	// finished jobs used as history
+	ProcessingTimePerSourceMinute float64 `json:"processingTimePerSourceMinute,omitempty"`
+
+	// number of finished jobs used as history: jobs of the same provider
+	// with the same presets or, when there are none, any jobs of the
+	// provider
+	HistoricalJobs int `json:"historicalJobs"`
+
+	// source of the estimate: history, progress, blended, complete or
+	// unavailable
+	Basis string `json:"basis"`
+}
+
+// response for the getJobETA operation.
+//
+// swagger:response jobETA
+type jobETAResponse struct {
+	// in: body
+	Payload *JobETA
+
+	baseResponse
+}
+
+// swagger:route GET /jobs/{jobId}/eta jobs getJobETA
+//
+// Estimates the completion of a job, based on the duration of its source
+// and the processing time of similar jobs finished in the last 30 days.
+// The estimate is refined with the progress reported by the provider as the
+// job advances.
+//
+//     Responses:
+//       200: jobETA
+//       404: jobNotFound
+//       410: jobNotFoundInTheProvider
+//       500: genericError
+//       502: providerError
+func (s *TranscodingService) getJobETA(r *http.Request) swagger.GizmoJSONResponse {
+	var params getTranscodeJobETAInput
+	params.loadParams(web.Vars(r))
+	job, status, prov, err := s.getTranscodeJobByID(r.Context(), params.JobID)
+	if err != nil {
+		return s.getJobStatusResponse(job, status, prov, err)
+	}
+	history, err := s.db.ListJobs(db.JobFilter{Since: s.now().Add(-etaHistoryWindow)})
+	if err != nil {
+		return swagger.NewErrorResponse(fmt.Errorf("error listing jobs: %s", err))
+	}
+	eta := s.estimateCompletion(job, status, history)
+	return &jobETAResponse{
+		baseResponse: baseResponse{payload: eta, status: http.StatusOK},
+	}
+}
+
+// estimateCompletion estimates the completion of the given job. The
+// historical estimate multiplies the duration of the source by the average
+// processing time per second of source of similar jobs, while the progress
+// estimate extrapolates the time elapsed since the job started. When both
+// are available, they're weighted by the progress of the job.
+func (s *TranscodingService) estimateCompletion(job *db.Job, status *provider.JobStatus, history []db.Job) *JobETA {
+	now := s.now()
+	eta := JobETA{JobID: job.ID, Status: status.Status, Progress: status.Progress}
+	if isTerminalStatus(status.Status) {
+		completion := status.CompleteTime
+		if completion.IsZero() {
+			completion = now
+		}
+		eta.setRemaining(0, completion)
+		eta.Basis = etaBasisComplete
+		return &eta
+	}
+	var elapsed time.Duration
+	if !status.StartTime.IsZero() && now.After(status.StartTime) {
+		elapsed = now.Sub(status.StartTime)
+	}
+	var historicalTotal, progressTotal time.Duration
+	rate, n := processingRate(job, history)
+	eta.HistoricalJobs = n
+	if n > 0 {
+		eta.ProcessingTimePerSourceMinute = rate * 60
+		sourceDuration := status.SourceInfo.Duration
+		if sourceDuration == 0 {
+			sourceDuration = time.Duration(job.SourceDuration) * time.Second
+		}
+		historicalTotal = time.Duration(rate * float64(sourceDuration))
+	}
+	if status.Progress > 0 && elapsed > 0 {
+		progressTotal = time.Duration(float64(elapsed) * 100 / status.Progress)
+	}
+	var total time.Duration
+	switch {
+	case historicalTotal > 0 && progressTotal > 0:
+		weight := status.Progress / 100
+		total = time.Duration((1-weight)*float64(historicalTotal) + weight*float64(progressTotal))
+		eta.Basis = etaBasisBlended
+	case historicalTotal > 0:
+		total = historicalTotal
+		eta.Basis = etaBasisHistory
+	case progressTotal > 0:
+		total = progressTotal
+		eta.Basis = etaBasisProgress
+	default:
+		eta.Basis = etaBasisUnavailable
+		return &eta
+	}
+	remaining := total - elapsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	eta.setRemaining(remaining, now.Add(remaining))
+	return &eta
+}
+
+func (e *JobETA) setRemaining(remaining time.Duration, completion time.Time) {
+	seconds := remaining.Seconds()
+	completion = completion.UTC()
+	e.RemainingTime = &seconds
+	e.EstimatedCompletionTime = &completion
+}
+
+// processingRate returns the average processing time per second of source
+// of the finished jobs in the history that ran in the same provider as the
+// given job, along with the number of jobs considered. Jobs with the same
+// presets are preferred, falling back to all jobs of the provider.
+func processingRate(job *db.Job, history []db.Job) (float64, int) {
+	presets := jobPresets(job)
+	var samePresets, sameProvider []float64
+	for _, j := range history {
+		if j.ID == job.ID || j.ProviderName != job.ProviderName {
+			continue
+		}
+		if provider.Status(j.Status) != provider.StatusFinished || j.ProcessingTime == 0 || j.SourceDuration == 0 {
+			continue
+		}
+		rate := float64(j.ProcessingTime) / float64(j.SourceDuration)
+		sameProvider = append(sameProvider, rate)
+		if jobPresets(&j) == presets {
+			samePresets = append(samePresets, rate)
+		}
+	}
+	rates := samePresets
+	if len(rates) == 0 {
+		rates = sameProvider
+	}
+	if len(rates) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, rate := range rates {
+		sum += rate
+	}
+	return sum / float64(len(rates)), len(rates)
+}
+
+// jobPresets returns the sorted names of the preset maps of the outputs of
+// the given job, joined by commas.
+func jobPresets(job *db.Job) string {
+	names := make([]string, len(job.Outputs))
+	for i, output := range job.Outputs {
+		names[i] = output.Preset.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
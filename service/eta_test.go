@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobETA(t *testing.T) {
+	now := fakeJobStartTime.Add(3 * time.Minute)
+	outputs := func(presets ...string) []db.TranscodeOutput {
+		var result []db.TranscodeOutput
+		for _, preset := range presets {
+			result = append(result, db.TranscodeOutput{Preset: db.PresetMap{Name: preset}, FileName: preset + ".mp4"})
+		}
+		return result
+	}
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	jobs := []db.Job{
+		{ID: "history-1", ProviderName: "fake", Status: "finished", ProcessingTime: 300, SourceDuration: 600, Outputs: outputs("mp4_1080p", "hls_1080p")},
+		{ID: "history-2", ProviderName: "fake", Status: "finished", ProcessingTime: 900, SourceDuration: 1200, Outputs: outputs("hls_1080p", "mp4_1080p")},
+		{ID: "history-3", ProviderName: "fake", Status: "finished", ProcessingTime: 1050, SourceDuration: 600, Outputs: outputs("mp4_4k")},
+		{ID: "history-failed", ProviderName: "fake", Status: "failed", ProcessingTime: 10, SourceDuration: 600, Outputs: outputs("mp4_1080p", "hls_1080p")},
+		{ID: "history-zencoder", ProviderName: "zencoder", Status: "finished", ProcessingTime: 60, SourceDuration: 600, Outputs: outputs("mp4_1080p", "hls_1080p")},
+		{ID: "history-old", ProviderName: "fake", Status: "finished", ProcessingTime: 6000, SourceDuration: 600, Outputs: outputs("mp4_1080p", "hls_1080p"), CreationTime: now.Add(-60 * 24 * time.Hour)},
+		{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", SourceDuration: 600, Outputs: outputs("mp4_1080p", "hls_1080p")},
+		{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued", SourceDuration: 120, Outputs: outputs("mp4_720p")},
+		{ID: "job-renditions", ProviderName: "fake", ProviderJobID: "provider-job-renditions", Outputs: outputs("mp4_360p", "mp4_720p", "mp4_1080p")},
+		{ID: "job-progress", ProviderName: "fake", ProviderJobID: "provider-job-progress"},
+		{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"},
+	}
+	for i := range jobs {
+		if jobs[i].CreationTime.IsZero() {
+			jobs[i].CreationTime = now.Add(-time.Hour)
+		}
+		fakeDBObj.CreateJob(&jobs[i])
+	}
+	tests := []struct {
+		givenTestCase string
+		givenJobID    string
+
+		wantCode                 int
+		wantBasis                string
+		wantHistoricalJobs       int
+		wantTimePerSourceMinute  float64
+		wantRemainingTime        float64
+		wantWithoutRemainingTime bool
+	}{
+		{
+			"running job with similar jobs",
+			"job-running",
+			http.StatusOK,
+			"blended",
+			2,
+			37.5,
+			262.5,
+			false,
+		},
+		{
+			"queued job without similar presets",
+			"job-queued",
+			http.StatusOK,
+			"history",
+			3,
+			60,
+			120,
+			false,
+		},
+		{
+			"running job without source duration",
+			"job-renditions",
+			http.StatusOK,
+			"progress",
+			3,
+			60,
+			120,
+			false,
+		},
+		{
+			"job without progress nor history",
+			"job-progress",
+			http.StatusOK,
+			"unavailable",
+			3,
+			60,
+			0,
+			true,
+		},
+		{
+			"finished job",
+			"job-123",
+			http.StatusOK,
+			"complete",
+			0,
+			0,
+			0,
+			false,
+		},
+		{
+			"job not found",
+			"job-404",
+			http.StatusNotFound,
+			"",
+			0,
+			0,
+			0,
+			false,
+		},
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	service.clock = func() time.Time { return now }
+	srvr.Register(service)
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/jobs/"+test.givenJobID+"/eta", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+			continue
+		}
+		if test.wantCode != http.StatusOK {
+			continue
+		}
+		var eta JobETA
+		err := json.Unmarshal(w.Body.Bytes(), &eta)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if eta.JobID != test.givenJobID {
+			t.Errorf("%s: wrong job id. Want %q. Got %q", test.givenTestCase, test.givenJobID, eta.JobID)
+		}
+		if eta.Basis != test.wantBasis {
+			t.Errorf("%s: wrong basis. Want %q. Got %q", test.givenTestCase, test.wantBasis, eta.Basis)
+		}
+		if eta.HistoricalJobs != test.wantHistoricalJobs {
+			t.Errorf("%s: wrong number of historical jobs. Want %d. Got %d", test.givenTestCase, test.wantHistoricalJobs, eta.HistoricalJobs)
+		}
+		if math.Abs(eta.ProcessingTimePerSourceMinute-test.wantTimePerSourceMinute) > 1e-6 {
+			t.Errorf("%s: wrong processing time per source minute. Want %g. Got %g", test.givenTestCase, test.wantTimePerSourceMinute, eta.ProcessingTimePerSourceMinute)
+		}
+		if test.wantWithoutRemainingTime {
+			if eta.RemainingTime != nil || eta.EstimatedCompletionTime != nil {
+				t.Errorf("%s: unexpected estimate: %s", test.givenTestCase, w.Body.String())
+			}
+			continue
+		}
+		if eta.RemainingTime == nil || eta.EstimatedCompletionTime == nil {
+			t.Errorf("%s: missing estimate: %s", test.givenTestCase, w.Body.String())
+			continue
+		}
+		if math.Abs(*eta.RemainingTime-test.wantRemainingTime) > 1e-3 {
+			t.Errorf("%s: wrong remaining time. Want %g. Got %g", test.givenTestCase, test.wantRemainingTime, *eta.RemainingTime)
+		}
+		wantCompletion := now.Add(time.Duration(test.wantRemainingTime * float64(time.Second)))
+		if diff := eta.EstimatedCompletionTime.Sub(wantCompletion); diff > time.Millisecond || diff < -time.Millisecond {
+			t.Errorf("%s: wrong estimated completion time. Want %s. Got %s", test.givenTestCase, wantCompletion, eta.EstimatedCompletionTime)
+		}
+	}
+}
+
+func TestJobStatusStoresSourceDuration(t *testing.T) {
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	r, _ := http.NewRequest("GET", "/jobs/job-123", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong status code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	job, err := fakeDBObj.GetJob("job-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.SourceDuration != 183 {
+		t.Errorf("wrong source duration. Want 183. Got %d", job.SourceDuration)
+	}
+}
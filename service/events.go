@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NYTimes/gizmo/web"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+var errTooManySubscriptions = errors.New("too many subscriptions to job events, please try again later")
+
+// swagger:route GET /jobs/{jobId}/events jobs getJobEvents
+//
+// Streams the status of a job using Server-Sent Events.
+// The status is sent when the subscription starts and then every time it
+// changes, until the job reaches a terminal state or the client disconnects.
+//
+//     Produces:
+//     - text/event-stream
+//
+//     Responses:
+//       200: jobStatus
+//       404: jobNotFound
+//       410: jobNotFoundInTheProvider
+//       500: genericError
+//       503: genericError
+func (s *TranscodingService) jobEvents(w http.ResponseWriter, r *http.Request) {
+	select {
+	case s.eventSubscriptions <- struct{}{}:
+		defer func() { <-s.eventSubscriptions }()
+	default:
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(errTooManySubscriptions).WithStatus(http.StatusServiceUnavailable))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(errors.New("streaming is not supported")))
+		return
+	}
+
+	var params getTranscodeJobInput
+	params.loadParams(web.Vars(r))
+	job, status, prov, err := s.getTranscodeJobByID(r.Context(), params.JobID)
+	if err != nil {
+		s.writeJSONResponse(w, r, s.getJobStatusResponse(job, status, prov, err))
+		return
+	}
+
+	// the encoding is set so the gzip middleware doesn't buffer events.
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Content-Encoding", "identity")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	var lastETag string
+	ticker := time.NewTicker(s.eventsPollInterval)
+	defer ticker.Stop()
+	for {
+		resp := newJobStatusResponse(status)
+		etag, err := resp.etag()
+		if err != nil {
+			writeEvent(w, "error", map[string]string{"error": err.Error()})
+			flusher.Flush()
+			return
+		}
+		if etag != lastETag {
+			lastETag = etag
+			writeEvent(w, "status", status)
+			flusher.Flush()
+		}
+		if isTerminalStatus(status.Status) {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		status, err = s.jobStatus(r.Context(), job, prov)
+		if err != nil {
+			writeEvent(w, "error", map[string]string{"error": err.Error()})
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+func isTerminalStatus(status provider.Status) bool {
+	switch status {
+	case provider.StatusFinished, provider.StatusFailed, provider.StatusCanceled:
+		return true
+	}
+	return false
+}
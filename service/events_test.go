@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+type jobEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+func newEventsTestServer(t *testing.T, maxSubscribers int) (*httptest.Server, *TranscodingService) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-progress", ProviderName: "fake", ProviderJobID: "provider-job-progress"})
+	service, err := NewTranscodingService(&config.Config{
+		EventsMaxSubscribers: maxSubscribers,
+		Server:               &server.Config{},
+	}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	service.eventsPollInterval = time.Millisecond
+	srvr.Register(service)
+	return httptest.NewServer(srvr), service
+}
+
+func readEvents(t *testing.T, resp *http.Response) []jobEvent {
+	var events []jobEvent
+	var event jobEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+		case line == "":
+			events = append(events, event)
+			event = jobEvent{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestJobEvents(t *testing.T) {
+	srvr, _ := newEventsTestServer(t, 10)
+	defer srvr.Close()
+	resp, err := http.Get(srvr.URL + "/jobs/job-123/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("wrong status code. Want %d. Got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("wrong content type. Want %q. Got %q", "text/event-stream", ct)
+	}
+	events := readEvents(t, resp)
+	if len(events) != 1 {
+		t.Fatalf("wrong number of events before the stream was closed. Want 1. Got %d: %#v", len(events), events)
+	}
+	if events[0].name != "status" {
+		t.Errorf("wrong event name. Want %q. Got %q", "status", events[0].name)
+	}
+	if status := events[0].data["status"]; status != "finished" {
+		t.Errorf("wrong status in event. Want %q. Got %q", "finished", status)
+	}
+	if providerName := events[0].data["providerName"]; providerName != "fake" {
+		t.Errorf("wrong provider name in event. Want %q. Got %q", "fake", providerName)
+	}
+}
+
+func TestJobEventsStatusChanges(t *testing.T) {
+	fprovider.progressChecks = 0
+	srvr, _ := newEventsTestServer(t, 10)
+	defer srvr.Close()
+	resp, err := http.Get(srvr.URL + "/jobs/job-progress/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := readEvents(t, resp)
+	var progress []float64
+	var statuses []string
+	for _, event := range events {
+		progress = append(progress, event.data["progress"].(float64))
+		statuses = append(statuses, event.data["status"].(string))
+	}
+	if want := []float64{0, 50, 100}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("wrong progress in events. Want %v. Got %v", want, progress)
+	}
+	if want := []string{"started", "started", "finished"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("wrong statuses in events. Want %v. Got %v", want, statuses)
+	}
+}
+
+func TestJobEventsJobNotFound(t *testing.T) {
+	srvr, _ := newEventsTestServer(t, 10)
+	defer srvr.Close()
+	resp, err := http.Get(srvr.URL + "/jobs/non_existent_job/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("wrong status code. Want %d. Got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	var got map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"error": "job not found"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong body returned\nwant %#v\ngot  %#v", want, got)
+	}
+}
+
+func TestJobEventsTooManySubscriptions(t *testing.T) {
+	srvr, service := newEventsTestServer(t, 1)
+	defer srvr.Close()
+	service.eventSubscriptions <- struct{}{}
+	resp, err := http.Get(srvr.URL + "/jobs/job-123/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("wrong status code. Want %d. Got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	<-service.eventSubscriptions
+	resp, err = http.Get(srvr.URL + "/jobs/job-123/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong status code after releasing the subscription. Want %d. Got %d", http.StatusOK, resp.StatusCode)
+	}
+}
@@ -1,6 +1,10 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/db"
 	"github.com/NYTimes/video-transcoding-api/provider"
@@ -9,16 +13,58 @@ import (
 func init() {
 	provider.Register("fake", fakeProviderFactory)
 	provider.Register("zencoder", fakeProviderFactory)
+	provider.Register("disabled", disabledProviderFactory)
 }
 
 type fakeProvider struct {
-	jobs         []*db.Job
-	canceledJobs []string
+	jobs           []*db.Job
+	canceledJobs   []string
+	progressChecks int
+	priorities     map[string]int
+
+	// whether the provider stops reporting the spec of jobs
+	noJobSpecs bool
+
+	// contexts of the last calls to the optional methods of the provider,
+	// keyed by method name
+	callContexts map[string]context.Context
+
+	// receives the error of the context of status checks of slow jobs,
+	// once it's done
+	interruptedChecks chan error
+
+	// manifests written by the provider, keyed by job id
+	manifests map[string][]byte
+
+	// number of active nodes reported by the provider
+	activeNodes int
 }
 
 var fprovider fakeProvider
 
-func (p *fakeProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
+// fakeJobStartTime is the start time reported for running jobs.
+var fakeJobStartTime = time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+
+func (p *fakeProvider) isCanceled(id string) bool {
+	for _, canceled := range p.canceledJobs {
+		if canceled == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *fakeProvider) recordCall(ctx context.Context, method string) {
+	if p.callContexts == nil {
+		p.callContexts = make(map[string]context.Context)
+	}
+	p.callContexts[method] = ctx
+}
+
+func (p *fakeProvider) Transcode(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	for _, output := range job.Outputs {
 		if _, ok := output.Preset.ProviderMapping["fake"]; !ok {
 			return nil, provider.ErrPresetMapNotFound
@@ -33,6 +79,10 @@ func (p *fakeProvider) Transcode(job *db.Job) (*provider.JobStatus, error) {
 			"progress":   100.0,
 			"sourcefile": "http://some.source.file",
 		},
+		Spec: &provider.JobSpec{
+			Data:        []byte("<job><input><file_input><uri>" + job.SourceMedia + "</uri></file_input></input></job>"),
+			ContentType: "application/xml",
+		},
 	}, nil
 }
 
@@ -40,16 +90,54 @@ func (*fakeProvider) CreatePreset(preset db.Preset) (string, error) {
 	return "presetID_here", nil
 }
 
+func (*fakeProvider) ValidatePreset(preset db.Preset) error {
+	if preset.Video.Profile == "Extended" {
+		return errors.New("profile Extended is not supported")
+	}
+	return nil
+}
+
 func (*fakeProvider) GetPreset(presetID string) (interface{}, error) {
 	return struct{ presetID string }{"presetID_here"}, nil
 }
 
+func (p *fakeProvider) ListPresets(ctx context.Context) ([]provider.PresetSummary, error) {
+	p.recordCall(ctx, "ListPresets")
+	return []provider.PresetSummary{
+		{ID: "18828", Name: "mp4_1080p", Container: "mp4"},
+		{ID: "19928", Name: "hls_1080p", Description: "HLS 1080p", Container: "m3u8"},
+	}, nil
+}
+
+func (p *fakeProvider) WriteManifest(ctx context.Context, job *db.Job, manifest []byte) (string, error) {
+	p.recordCall(ctx, "WriteManifest")
+	if p.manifests == nil {
+		p.manifests = make(map[string][]byte)
+	}
+	p.manifests[job.ID] = manifest
+	return "s3://mybucket/some/dir/" + job.ID + "/manifest.json", nil
+}
+
+func (p *fakeProvider) ActiveNodes(ctx context.Context) (int, error) {
+	return p.activeNodes, nil
+}
+
 func (*fakeProvider) DeletePreset(presetID string) error {
 	return nil
 }
 
-func (p *fakeProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
+func (p *fakeProvider) JobStatus(ctx context.Context, job *db.Job) (*provider.JobStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	id := job.ProviderJobID
+	if id == "provider-job-slow" {
+		<-ctx.Done()
+		if p.interruptedChecks != nil {
+			p.interruptedChecks <- ctx.Err()
+		}
+		return nil, ctx.Err()
+	}
 	if id == "provider-job-123" {
 		status := provider.StatusFinished
 		if len(p.canceledJobs) > 0 {
@@ -60,6 +148,7 @@ func (p *fakeProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
 			Status:        status,
 			StatusMessage: "The job is finished",
 			Progress:      10.3,
+			StartTime:     fakeJobStartTime,
 			SourceInfo: provider.SourceInfo{
 				Width:      4096,
 				Height:     2160,
@@ -75,29 +164,185 @@ func (p *fakeProvider) JobStatus(job *db.Job) (*provider.JobStatus, error) {
 			},
 		}, nil
 	}
+	if id == "provider-preset-job-123" {
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        provider.StatusFinished,
+			StatusMessage: "The job is finished",
+			Progress:      100,
+		}, nil
+	}
+	if id == "provider-job-outputs" {
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        provider.StatusFinished,
+			Progress:      100,
+			SourceInfo:    provider.SourceInfo{Duration: 120 * time.Second},
+			Output: provider.JobOutput{
+				Destination: "s3://mybucket/some/dir/job-outputs",
+				Files: []provider.OutputFile{
+					{Path: "s3://mybucket/some/dir/job-outputs/video_1080p.mp4", Container: "mp4", VideoCodec: "h264", Width: 1920, Height: 1080, FileSize: 75000000},
+					{Path: "s3://mybucket/some/dir/job-outputs/video_720p.mp4", Container: "mp4", VideoCodec: "h264", Width: 1280, Height: 720, FileSize: 30000000, Bitrate: 2100000, Duration: 119500 * time.Millisecond},
+				},
+			},
+		}, nil
+	}
+	if id == "provider-job-progress" {
+		progress := []float64{0, 50, 50, 100}[p.progressChecks]
+		p.progressChecks++
+		status := provider.StatusStarted
+		if progress == 100 {
+			status = provider.StatusFinished
+		}
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        status,
+			Progress:      progress,
+		}, nil
+	}
+	if id == "provider-job-running" {
+		status := provider.StatusStarted
+		if p.isCanceled(id) {
+			status = provider.StatusCanceled
+		}
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        status,
+			Progress:      30,
+			StartTime:     fakeJobStartTime,
+		}, nil
+	}
+	if id == "provider-job-renditions" {
+		status := provider.StatusStarted
+		if p.isCanceled(id) {
+			status = provider.StatusCanceled
+		}
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        status,
+			Progress:      60,
+			StartTime:     fakeJobStartTime,
+			Outputs: []provider.OutputStatus{
+				{FileName: "video_360p.mp4", Preset: "mp4_360p", Status: provider.StatusFinished, Progress: 100},
+				{FileName: "video_720p.mp4", Preset: "mp4_720p", Status: provider.StatusStarted, Progress: 55},
+				{FileName: "video_1080p.mp4", Preset: "mp4_1080p", Status: provider.StatusStarted, Progress: 25},
+			},
+		}, nil
+	}
+	if id == "provider-job-partial" {
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        provider.StatusFailed,
+			Progress:      100,
+			Outputs: []provider.OutputStatus{
+				{FileName: "video_360p.mp4", Preset: "mp4_360p", Status: provider.StatusFinished, Progress: 100},
+				{FileName: "video_720p.mp4", Preset: "mp4_720p", Status: provider.StatusFailed, Progress: 40},
+				{FileName: "video_1080p.mp4", Preset: "mp4_1080p", Status: provider.StatusFinished, Progress: 100},
+			},
+		}, nil
+	}
+	if id == "provider-job-error" {
+		return nil, errors.New("internal server error")
+	}
+	if id == "provider-job-crashed" || id == "provider-job-bad-source" {
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        provider.StatusFailed,
+		}, nil
+	}
+	if id == "provider-job-queued" {
+		return &provider.JobStatus{
+			ProviderJobID: id,
+			Status:        provider.StatusQueued,
+		}, nil
+	}
 	return nil, provider.JobNotFoundError{ID: id}
 }
 
-func (p *fakeProvider) CancelJob(id string) error {
-	if id == "provider-job-123" {
+func (p *fakeProvider) CancelJob(ctx context.Context, id string) error {
+	p.recordCall(ctx, "CancelJob")
+	if id == "provider-job-123" || id == "provider-job-running" || id == "provider-job-renditions" || id == "provider-preset-job-123" {
 		p.canceledJobs = append(p.canceledJobs, id)
 		return nil
 	}
 	return provider.JobNotFoundError{ID: id}
 }
 
-func (p *fakeProvider) Healthcheck() error {
+func (p *fakeProvider) UpdateJobPriority(ctx context.Context, id string, priority int) error {
+	p.recordCall(ctx, "UpdateJobPriority")
+	if p.priorities == nil {
+		p.priorities = make(map[string]int)
+	}
+	p.priorities[id] = priority
+	return nil
+}
+
+func (p *fakeProvider) GetJobLogs(ctx context.Context, id string) ([]byte, error) {
+	p.recordCall(ctx, "GetJobLogs")
+	switch id {
+	case "provider-job-123":
+		return []byte("2016-03-10T10:00:00Z job submitted\n2016-03-10T10:05:00Z job complete\n"), nil
+	case "provider-job-queued":
+		return nil, provider.ErrNotImplemented
+	}
+	return nil, provider.JobNotFoundError{ID: id}
+}
+
+func (p *fakeProvider) TransientFailure(status *provider.JobStatus) bool {
+	return status.ProviderJobID != "provider-job-bad-source"
+}
+
+func (p *fakeProvider) PreviewOutput(job *db.Job) (*provider.JobOutput, error) {
+	destination := "s3://fake-destination/" + job.ID
+	output := provider.JobOutput{Destination: destination}
+	if job.StreamingParams.Protocol == "hls" {
+		output.Files = append(output.Files, provider.OutputFile{
+			Path:      destination + "/" + job.StreamingParams.PlaylistFileName,
+			Container: "m3u8",
+		})
+	}
+	for _, o := range job.Outputs {
+		if _, ok := o.Preset.ProviderMapping["fake"]; !ok {
+			return nil, provider.ErrPresetMapNotFound
+		}
+		output.Files = append(output.Files, provider.OutputFile{
+			Path:      destination + "/" + o.FileName,
+			Container: o.Preset.OutputOpts.OutputContainer(),
+		})
+	}
+	return &output, nil
+}
+
+func (p *fakeProvider) GetJobCost(ctx context.Context, id string) (*provider.JobCost, error) {
+	p.recordCall(ctx, "GetJobCost")
+	switch id {
+	case "provider-job-123":
+		return &provider.JobCost{BilledMinutes: 3.5, Amount: 0.105, Currency: "USD"}, nil
+	case "provider-job-partial":
+		return nil, provider.ErrNotImplemented
+	}
+	return nil, errors.New("internal server error")
+}
+
+func (p *fakeProvider) Healthcheck(context.Context) error {
 	return nil
 }
 
 func (p *fakeProvider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		InputFormats:  []string{"prores", "h264"},
-		OutputFormats: []string{"mp4", "webm", "hls"},
-		Destinations:  []string{"akamai", "s3"},
+		InputFormats:    []string{"prores", "h264"},
+		OutputFormats:   []string{"mp4", "webm", "hls", "jpg"},
+		Destinations:    []string{"akamai", "s3"},
+		OutputACLs:      []string{"private"},
+		InputContainers: []string{"mp4", "mxf"},
+		JobSpecs:        !p.noJobSpecs,
 	}
 }
 
 func fakeProviderFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
 	return &fprovider, nil
 }
+
+func disabledProviderFactory(cfg *config.Config) (provider.TranscodingProvider, error) {
+	return nil, provider.ErrProviderDisabled
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/NYTimes/gizmo/web"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// Plain text log of the job, as reported by the provider.
+//
+// swagger:response jobLogs
+type jobLogsResponse struct {
+	// in: body
+	Payload string
+}
+
+// swagger:route GET /jobs/{jobId}/logs jobs getJobLogs
+//
+// Retrieves the log of a job from the provider, for debugging purposes.
+//
+//     Produces:
+//     - text/plain
+//
+//     Responses:
+//       200: jobLogs
+//       404: jobNotFound
+//       410: jobNotFoundInTheProvider
+//       500: genericError
+//       501: genericError
+//       502: providerError
+func (s *TranscodingService) jobLogs(w http.ResponseWriter, r *http.Request) {
+	var params getTranscodeJobLogsInput
+	params.loadParams(web.Vars(r))
+	job, err := s.db.GetJob(params.JobID)
+	if err != nil {
+		if err == db.ErrJobNotFound {
+			s.writeJSONResponse(w, r, newJobNotFoundResponse(err))
+			return
+		}
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(err))
+		return
+	}
+	prov, err := s.providerFor(job)
+	if err != nil {
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(err))
+		return
+	}
+	logger, ok := prov.(provider.JobLogger)
+	if !ok {
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(provider.ErrNotImplemented).WithStatus(http.StatusNotImplemented))
+		return
+	}
+	logs, err := logger.GetJobLogs(r.Context(), job.ProviderJobID)
+	if err != nil {
+		s.writeJSONResponse(w, r, jobLogsErrorResponse(err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(logs)
+}
+
+func jobLogsErrorResponse(err error) swagger.GizmoJSONResponse {
+	if err == provider.ErrNotImplemented {
+		return swagger.NewErrorResponse(err).WithStatus(http.StatusNotImplemented)
+	}
+	if _, ok := err.(provider.JobNotFoundError); ok {
+		return newJobNotFoundProviderResponse(err)
+	}
+	return newProviderErrorResponse(err)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobLogs(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenJobID    string
+
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			"job with logs",
+			"job-123",
+			http.StatusOK,
+			"text/plain; charset=utf-8",
+			"2016-03-10T10:00:00Z job submitted\n2016-03-10T10:05:00Z job complete\n",
+		},
+		{
+			"job without logs in the provider",
+			"job-queued",
+			http.StatusNotImplemented,
+			"application/json; charset=UTF-8",
+			`{"error":"operation not supported by the provider"}`,
+		},
+		{
+			"job not found in the provider",
+			"job-unknown",
+			http.StatusGone,
+			"application/json; charset=UTF-8",
+			`{"error":"could not found job with id: provider-job-unknown"}`,
+		},
+		{
+			"job not found",
+			"job-404",
+			http.StatusNotFound,
+			"application/json; charset=UTF-8",
+			`{"error":"job not found"}`,
+		},
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued"})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-unknown", ProviderName: "fake", ProviderJobID: "provider-job-unknown"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/jobs/"+test.givenJobID+"/logs", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.wantContentType {
+			t.Errorf("%s: wrong content type. Want %q. Got %q", test.givenTestCase, test.wantContentType, ct)
+		}
+		if body := w.Body.String(); body != test.wantBody && body != test.wantBody+"\n" {
+			t.Errorf("%s: wrong body\nwant %q\ngot  %q", test.givenTestCase, test.wantBody, body)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+// jobManifest is the sidecar JSON manifest written to the destination of
+// finished jobs, listing their outputs.
+type jobManifest struct {
+	JobID       string           `json:"jobId"`
+	Source      string           `json:"source"`
+	Destination string           `json:"destination,omitempty"`
+	Outputs     []manifestOutput `json:"outputs"`
+}
+
+// manifestOutput describes an output in the manifest of a job. The duration
+// is in seconds and the bitrate in bits per second.
+type manifestOutput struct {
+	URL        string  `json:"url"`
+	Container  string  `json:"container,omitempty"`
+	VideoCodec string  `json:"videoCodec,omitempty"`
+	Width      int64   `json:"width,omitempty"`
+	Height     int64   `json:"height,omitempty"`
+	Bitrate    int64   `json:"bitrate,omitempty"`
+	Duration   float64 `json:"duration,omitempty"`
+	FileSize   int64   `json:"fileSize,omitempty"`
+}
+
+// writeJobManifest writes the manifest of the outputs of jobs that asked for
+// one once they finish, storing its location in the job. Failures are
+// logged instead of failing the status check, and writing the manifest is
+// retried in the next check.
+func (s *TranscodingService) writeJobManifest(ctx context.Context, job *db.Job, status *provider.JobStatus, p provider.TranscodingProvider) {
+	if !job.WriteManifest || job.Manifest != "" || status.Status != provider.StatusFinished {
+		return
+	}
+	writer, ok := p.(provider.ManifestWriter)
+	if !ok {
+		return
+	}
+	logger := s.logger.WithField("jobId", job.ID)
+	data, err := newJobManifest(job, status)
+	if err != nil {
+		logger.WithError(err).Error("failed to render the manifest of job")
+		return
+	}
+	location, err := writer.WriteManifest(ctx, job, data)
+	if err != nil {
+		logger.WithError(err).Error("failed to write the manifest of job")
+		return
+	}
+	job.Manifest = location
+	err = s.db.UpdateJob(job)
+	if err != nil {
+		logger.WithError(err).Error("failed to store the location of the manifest of job")
+	}
+}
+
+// newJobManifest renders the manifest of the given job. Outputs without a
+// duration take the duration of the source, and the bitrate is computed from
+// the size of outputs when the provider doesn't report it.
+func newJobManifest(job *db.Job, status *provider.JobStatus) ([]byte, error) {
+	manifest := jobManifest{
+		JobID:       job.ID,
+		Source:      job.SourceMedia,
+		Destination: status.Output.Destination,
+		Outputs:     make([]manifestOutput, len(status.Output.Files)),
+	}
+	for i, file := range status.Output.Files {
+		duration := file.Duration
+		if duration == 0 {
+			duration = status.SourceInfo.Duration
+		}
+		bitrate := file.Bitrate
+		if bitrate == 0 && file.FileSize > 0 && duration > 0 {
+			bitrate = int64(float64(file.FileSize*8) / duration.Seconds())
+		}
+		manifest.Outputs[i] = manifestOutput{
+			URL:        file.Path,
+			Container:  file.Container,
+			VideoCodec: file.VideoCodec,
+			Width:      file.Width,
+			Height:     file.Height,
+			Bitrate:    bitrate,
+			Duration:   duration.Seconds(),
+			FileSize:   file.FileSize,
+		}
+	}
+	return json.MarshalIndent(manifest, "", "  ")
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobManifest(t *testing.T) {
+	defer func() { fprovider.manifests = nil }()
+	fprovider.manifests = nil
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-outputs", ProviderName: "fake", ProviderJobID: "provider-job-outputs", SourceMedia: "s3://bucket/video.mov", WriteManifest: true})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	wantLocation := "s3://mybucket/some/dir/job-outputs/manifest.json"
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", "/jobs/job-outputs", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+		}
+		var status struct {
+			Output struct {
+				Manifest string `json:"manifest"`
+			} `json:"output"`
+		}
+		err = json.Unmarshal(w.Body.Bytes(), &status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Output.Manifest != wantLocation {
+			t.Errorf("wrong manifest location in the status. Want %q. Got %q", wantLocation, status.Output.Manifest)
+		}
+	}
+	if len(fprovider.manifests) != 1 {
+		t.Fatalf("wrong number of manifests written. Want 1. Got %d", len(fprovider.manifests))
+	}
+	var manifest map[string]interface{}
+	err = json.Unmarshal(fprovider.manifests["job-outputs"], &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantManifest := map[string]interface{}{
+		"jobId":       "job-outputs",
+		"source":      "s3://bucket/video.mov",
+		"destination": "s3://mybucket/some/dir/job-outputs",
+		"outputs": []interface{}{
+			map[string]interface{}{
+				"url":        "s3://mybucket/some/dir/job-outputs/video_1080p.mp4",
+				"container":  "mp4",
+				"videoCodec": "h264",
+				"width":      float64(1920),
+				"height":     float64(1080),
+				"bitrate":    float64(5000000),
+				"duration":   float64(120),
+				"fileSize":   float64(75000000),
+			},
+			map[string]interface{}{
+				"url":        "s3://mybucket/some/dir/job-outputs/video_720p.mp4",
+				"container":  "mp4",
+				"videoCodec": "h264",
+				"width":      float64(1280),
+				"height":     float64(720),
+				"bitrate":    float64(2100000),
+				"duration":   119.5,
+				"fileSize":   float64(30000000),
+			},
+		},
+	}
+	if !reflect.DeepEqual(manifest, wantManifest) {
+		t.Errorf("wrong manifest\nwant %#v\ngot  %#v", wantManifest, manifest)
+	}
+	job, err := fakeDBObj.GetJob("job-outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Manifest != wantLocation {
+		t.Errorf("wrong manifest location stored in the job. Want %q. Got %q", wantLocation, job.Manifest)
+	}
+
+	r, _ := http.NewRequest("GET", "/jobs/job-123", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	if _, ok := fprovider.manifests["job-123"]; ok {
+		t.Error("unexpected manifest written for job that didn't ask for one")
+	}
+}
+
+func TestTranscodeWriteManifest(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	body := `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}],"writeManifest":true}`
+	r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(fprovider.jobs) != 1 {
+		t.Fatalf("wrong number of jobs sent to the provider. Want 1. Got %d", len(fprovider.jobs))
+	}
+	if !fprovider.jobs[0].WriteManifest {
+		t.Error("job sent to the provider without asking for the manifest")
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+var (
+	notificationSubject = template.Must(template.New("subject").Parse(
+		`Transcoding job {{.JobID}}{{if .Name}} ({{.Name}}){{end}} {{.Status}}`,
+	))
+
+	notificationBody = template.Must(template.New("body").Parse(`Job {{.JobID}}{{if .Name}} ({{.Name}}){{end}} is {{.Status}}.
+
+Source: {{.Source}}
+{{- if .Message}}
+Message: {{.Message}}
+{{- end}}
+{{- if .Destination}}
+
+Destination: {{.Destination}}
+{{- end}}
+{{- if .Outputs}}
+
+Outputs:
+{{- range .Outputs}}
+  - {{.}}
+{{- end}}
+{{- end}}
+`))
+)
+
+// jobNotification holds the values available to the templates of the
+// notifications of jobs.
+type jobNotification struct {
+	JobID       string
+	Name        string
+	Source      string
+	Status      provider.Status
+	Message     string
+	Destination string
+	Outputs     []string
+}
+
+// emailNotificationsEnabled reports whether the SMTP settings required for
+// sending notifications of jobs are set.
+func (s *TranscodingService) emailNotificationsEnabled() bool {
+	return s.config.SMTP != nil && s.config.SMTP.Host != "" && s.config.SMTP.From != ""
+}
+
+// validateNotificationEmail ensures that notifications can be sent to the
+// given address, which must be a plain email address, without a display
+// name.
+func (s *TranscodingService) validateNotificationEmail(email string) error {
+	if !s.emailNotificationsEnabled() {
+		return errors.New("email notifications are disabled")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("invalid notificationEmail %q, must be an email address", email)
+	}
+	return nil
+}
+
+// notifyJob emails the notification address of the given job once it
+// finishes, fails or is canceled. Failures to send the email are logged.
+func (s *TranscodingService) notifyJob(job *db.Job, status *provider.JobStatus) {
+	if job.NotificationEmail == "" || !isTerminalStatus(status.Status) || !s.emailNotificationsEnabled() {
+		return
+	}
+	logger := s.logger.WithField("jobId", job.ID)
+	cfg := s.config.SMTP
+	msg, err := notificationMessage(cfg.From, job, status)
+	if err != nil {
+		logger.WithError(err).Error("failed to render the notification of job")
+		return
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	sendMail := s.sendMail
+	if sendMail == nil {
+		sendMail = smtp.SendMail
+	}
+	err = sendMail(net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), auth, cfg.From, []string{job.NotificationEmail}, msg)
+	if err != nil {
+		logger.WithError(err).Error("failed to send the notification of job")
+	}
+}
+
+// notificationMessage renders the email notifying the given status of the
+// job, with links to its outputs.
+func notificationMessage(from string, job *db.Job, status *provider.JobStatus) ([]byte, error) {
+	data := jobNotification{
+		JobID:       job.ID,
+		Name:        job.Name,
+		Source:      job.SourceMedia,
+		Status:      status.Status,
+		Message:     status.StatusMessage,
+		Destination: status.Output.Destination,
+	}
+	for _, file := range status.Output.Files {
+		data.Outputs = append(data.Outputs, file.Path)
+	}
+	var subject, body bytes.Buffer
+	err := notificationSubject.Execute(&subject, data)
+	if err != nil {
+		return nil, err
+	}
+	err = notificationBody.Execute(&body, data)
+	if err != nil {
+		return nil, err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", job.NotificationEmail)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject.String()))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(body.String(), "\n", "\r\n", -1))
+	return msg.Bytes(), nil
+}
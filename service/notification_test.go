@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/sirupsen/logrus"
+)
+
+type sentEmail struct {
+	addr string
+	from string
+	to   []string
+	msg  string
+}
+
+type fakeSMTPServer struct {
+	emails []sentEmail
+}
+
+func (s *fakeSMTPServer) sendMail(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	s.emails = append(s.emails, sentEmail{addr: addr, from: from, to: to, msg: string(msg)})
+	return nil
+}
+
+func TestJobNotificationEmail(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenJob      db.Job
+
+		wantSubject string
+		wantBody    []string
+	}{
+		{
+			"finished job",
+			db.Job{ID: "job-123", Name: "Evening news", ProviderName: "fake", ProviderJobID: "provider-job-123", SourceMedia: "s3://bucket/news.mov", NotificationEmail: "editor@example.com"},
+			"Subject: Transcoding job job-123 (Evening news) finished\r\n",
+			[]string{
+				"Job job-123 (Evening news) is finished.\r\n",
+				"Source: s3://bucket/news.mov\r\n",
+				"Message: The job is finished\r\n",
+				"Destination: s3://mybucket/some/dir/job-123\r\n",
+			},
+		},
+		{
+			"failed job",
+			db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed", SourceMedia: "s3://bucket/crash.mov", NotificationEmail: "editor@example.com"},
+			"Subject: Transcoding job job-crashed failed\r\n",
+			[]string{
+				"Job job-crashed is failed.\r\n",
+				"Source: s3://bucket/crash.mov\r\n",
+			},
+		},
+	}
+	for _, test := range tests {
+		smtpServer := fakeSMTPServer{}
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		job := test.givenJob
+		fakeDBObj.CreateJob(&job)
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		logger := logrus.New()
+		logger.Out = ioutil.Discard
+		cfg := config.Config{
+			Server: &server.Config{},
+			SMTP:   &config.SMTP{Host: "smtp.example.com", Port: 587, From: "transcoding@example.com"},
+		}
+		service, err := NewTranscodingService(&cfg, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		service.sendMail = smtpServer.sendMail
+		srvr.Register(service)
+		for i := 0; i < 2; i++ {
+			r, _ := http.NewRequest("GET", "/jobs/"+job.ID, nil)
+			w := httptest.NewRecorder()
+			srvr.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusOK, w.Code)
+			}
+		}
+		if len(smtpServer.emails) != 1 {
+			t.Fatalf("%s: wrong number of emails sent. Want 1. Got %d", test.givenTestCase, len(smtpServer.emails))
+		}
+		email := smtpServer.emails[0]
+		if email.addr != "smtp.example.com:587" {
+			t.Errorf("%s: wrong smtp server. Want %q. Got %q", test.givenTestCase, "smtp.example.com:587", email.addr)
+		}
+		if email.from != "transcoding@example.com" {
+			t.Errorf("%s: wrong sender. Want %q. Got %q", test.givenTestCase, "transcoding@example.com", email.from)
+		}
+		if !reflect.DeepEqual(email.to, []string{"editor@example.com"}) {
+			t.Errorf("%s: wrong recipients. Want %#v. Got %#v", test.givenTestCase, []string{"editor@example.com"}, email.to)
+		}
+		for _, want := range append([]string{test.wantSubject, "To: editor@example.com\r\n"}, test.wantBody...) {
+			if !strings.Contains(email.msg, want) {
+				t.Errorf("%s: missing %q in the email:\n%s", test.givenTestCase, want, email.msg)
+			}
+		}
+	}
+}
+
+func TestJobNotificationEmailConcurrentReads(t *testing.T) {
+	smtpServer := fakeSMTPServer{}
+	repo := newRedisRepository(t)
+	err := repo.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123", SourceMedia: "s3://bucket/news.mov", NotificationEmail: "editor@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	service, err := NewTranscodingService(&config.Config{
+		Server: &server.Config{},
+		SMTP:   &config.SMTP{Host: "smtp.example.com", Port: 587, From: "transcoding@example.com"},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+	service.sendMail = smtpServer.sendMail
+
+	// both requests load the job before either of them stores its status.
+	var jobs []*db.Job
+	for i := 0; i < 2; i++ {
+		job, err := repo.GetJob("job-123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+	prov, err := service.providerFor(jobs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		status, err := service.jobStatus(context.Background(), job, prov)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Status != provider.StatusFinished {
+			t.Errorf("wrong status. Want %q. Got %q", provider.StatusFinished, status.Status)
+		}
+	}
+	if len(smtpServer.emails) != 1 {
+		t.Errorf("wrong number of emails sent. Want 1. Got %d", len(smtpServer.emails))
+	}
+}
+
+func TestJobNotificationEmailOutputs(t *testing.T) {
+	job := db.Job{ID: "job-123", SourceMedia: "s3://bucket/news.mov", NotificationEmail: "editor@example.com"}
+	status := provider.JobStatus{
+		Status: provider.StatusFinished,
+		Output: provider.JobOutput{
+			Destination: "s3://bucket/output/",
+			Files: []provider.OutputFile{
+				{Path: "https://cdn.example.com/output/news_1080p.mp4"},
+				{Path: "https://cdn.example.com/output/news_720p.mp4"},
+			},
+		},
+	}
+	msg, err := notificationMessage("transcoding@example.com", &job, &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMsg := "From: transcoding@example.com\r\n" +
+		"To: editor@example.com\r\n" +
+		"Subject: Transcoding job job-123 finished\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Job job-123 is finished.\r\n" +
+		"\r\n" +
+		"Source: s3://bucket/news.mov\r\n" +
+		"\r\n" +
+		"Destination: s3://bucket/output/\r\n" +
+		"\r\n" +
+		"Outputs:\r\n" +
+		"  - https://cdn.example.com/output/news_1080p.mp4\r\n" +
+		"  - https://cdn.example.com/output/news_720p.mp4\r\n"
+	if string(msg) != wantMsg {
+		t.Errorf("wrong email\nwant %q\ngot  %q", wantMsg, msg)
+	}
+}
+
+func TestJobNotificationEmailNotSentForRunningJobs(t *testing.T) {
+	smtpServer := fakeSMTPServer{}
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", NotificationEmail: "editor@example.com"})
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	cfg := config.Config{
+		Server: &server.Config{},
+		SMTP:   &config.SMTP{Host: "smtp.example.com", Port: 587, From: "transcoding@example.com"},
+	}
+	service, err := NewTranscodingService(&cfg, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	service.sendMail = smtpServer.sendMail
+	srvr.Register(service)
+	r, _ := http.NewRequest("GET", "/jobs/job-running", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	if len(smtpServer.emails) > 0 {
+		t.Errorf("unexpected emails sent: %#v", smtpServer.emails)
+	}
+}
+
+func TestTranscodeNotificationEmail(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenEmail    string
+		givenSMTP     *config.SMTP
+
+		wantCode  int
+		wantError string
+	}{
+		{
+			"valid email",
+			"editor@example.com",
+			&config.SMTP{Host: "smtp.example.com", Port: 587, From: "transcoding@example.com"},
+			http.StatusOK,
+			"",
+		},
+		{
+			"invalid email",
+			"editor.example.com",
+			&config.SMTP{Host: "smtp.example.com", Port: 587, From: "transcoding@example.com"},
+			http.StatusBadRequest,
+			`invalid notificationEmail "editor.example.com", must be an email address`,
+		},
+		{
+			"email with display name",
+			"Editor <editor@example.com>",
+			&config.SMTP{Host: "smtp.example.com", Port: 587, From: "transcoding@example.com"},
+			http.StatusBadRequest,
+			`invalid notificationEmail "Editor <editor@example.com>", must be an email address`,
+		},
+		{
+			"smtp not configured",
+			"editor@example.com",
+			&config.SMTP{Port: 587},
+			http.StatusBadRequest,
+			"email notifications are disabled",
+		},
+	}
+	defer func() { fprovider.jobs = nil }()
+	for _, test := range tests {
+		fprovider.jobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		logger := logrus.New()
+		logger.Out = ioutil.Discard
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, SMTP: test.givenSMTP}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		body := `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}],"notificationEmail":"` + test.givenEmail + `"}`
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+			continue
+		}
+		if test.wantError != "" {
+			var got map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("%s: %s", test.givenTestCase, err)
+			}
+			if got["error"] != test.wantError {
+				t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, test.wantError, got["error"])
+			}
+			continue
+		}
+		if len(fprovider.jobs) != 1 {
+			t.Fatalf("%s: wrong number of jobs sent to the provider. Want 1. Got %d", test.givenTestCase, len(fprovider.jobs))
+		}
+		if email := fprovider.jobs[0].NotificationEmail; email != test.givenEmail {
+			t.Errorf("%s: wrong notification email. Want %q. Got %q", test.givenTestCase, test.givenEmail, email)
+		}
+	}
+}
@@ -1,10 +1,9 @@
 package service
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/NYTimes/gizmo/web"
 	"github.com/NYTimes/video-transcoding-api/db"
@@ -79,14 +78,13 @@ func (s *TranscodingService) newPreset(r *http.Request) swagger.GizmoJSONRespons
 	var providers []string
 	var shouldCreatePresetMap bool
 
-	respData, err := ioutil.ReadAll(r.Body)
+	err := decodeJSON(s.requestBody(r), &input)
 	if err != nil {
-		return swagger.NewErrorResponse(err)
+		return newInvalidPresetResponse(err)
 	}
 
-	err = json.Unmarshal(respData, &input)
-	if err != nil {
-		return swagger.NewErrorResponse(err)
+	if err = input.Preset.Validate(); err != nil {
+		return newInvalidPresetResponse(fmt.Errorf("invalid preset: %s", err))
 	}
 
 	output.Results = make(map[string]newPresetOutput)
@@ -96,8 +94,7 @@ func (s *TranscodingService) newPreset(r *http.Request) swagger.GizmoJSONRespons
 	presetMap, err = s.db.GetPresetMap(input.Preset.Name)
 	if err == db.ErrPresetMapNotFound {
 		presetMap = &db.PresetMap{Name: input.Preset.Name}
-		presetMap.OutputOpts = input.OutputOptions
-		presetMap.OutputOpts.Extension = input.Preset.Container
+		presetMap.OutputOpts = input.outputOptions()
 		presetMap.ProviderMapping = make(map[string]string)
 		if err = presetMap.OutputOpts.Validate(); err != nil {
 			return newInvalidPresetResponse(fmt.Errorf("invalid outputOptions: %s", err))
@@ -160,6 +157,94 @@ func (s *TranscodingService) newPreset(r *http.Request) swagger.GizmoJSONRespons
 	}
 }
 
+// swagger:route POST /presets/validate presets validatePreset
+//
+// Validates a preset against the given providers, without creating it.
+//
+//     Responses:
+//       200: presetValidation
+//       400: presetValidation
+func (s *TranscodingService) validatePreset(r *http.Request) swagger.GizmoJSONResponse {
+	defer r.Body.Close()
+	var input newPresetInput
+	err := decodeJSON(s.requestBody(r), &input)
+	if err != nil {
+		return newInvalidPresetResponse(err)
+	}
+	problems := []string{}
+	for _, err = range input.Preset.ValidationErrors() {
+		problems = append(problems, err.Error())
+	}
+	outputOpts := input.outputOptions()
+	if err = outputOpts.Validate(); err != nil {
+		problems = append(problems, "outputOptions: "+err.Error())
+	}
+	for _, p := range input.Providers {
+		for _, problem := range s.providerPresetProblems(p, input.Preset) {
+			problems = append(problems, p+": "+problem)
+		}
+	}
+	status := http.StatusOK
+	if len(problems) > 0 {
+		status = http.StatusBadRequest
+	}
+	return &presetValidationResponse{
+		baseResponse: baseResponse{
+			payload: presetValidation{Valid: len(problems) == 0, Problems: problems},
+			status:  status,
+		},
+	}
+}
+
+// providerPresetProblems lists the reasons why the given provider can't use
+// the preset: unsupported container or codecs, and the problems reported by
+// the provider itself when it's able to validate presets.
+func (s *TranscodingService) providerPresetProblems(name string, preset db.Preset) []string {
+	providerFactory, err := provider.GetProviderFactory(name)
+	if err != nil {
+		return []string{"getting factory: " + err.Error()}
+	}
+	providerObj, err := providerFactory(s.config)
+	if err != nil {
+		return []string{"initializing provider: " + err.Error()}
+	}
+	var problems []string
+	capabilities := providerObj.Capabilities()
+	if preset.Container != "" && !containsFold(capabilities.OutputFormats, outputFormat(preset.Container)) {
+		problems = append(problems, fmt.Sprintf("container %q is not supported", preset.Container))
+	}
+	if preset.Video.Codec != "" && len(capabilities.VideoCodecs) > 0 && !containsFold(capabilities.VideoCodecs, preset.Video.Codec) {
+		problems = append(problems, fmt.Sprintf("video codec %q is not supported", preset.Video.Codec))
+	}
+	if preset.Audio.Codec != "" && len(capabilities.AudioCodecs) > 0 && !containsFold(capabilities.AudioCodecs, preset.Audio.Codec) {
+		problems = append(problems, fmt.Sprintf("audio codec %q is not supported", preset.Audio.Codec))
+	}
+	if validator, ok := providerObj.(provider.PresetValidator); ok {
+		if err = validator.ValidatePreset(preset); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// outputFormat returns the output format, as listed in the capabilities of
+// providers, produced by presets using the given container.
+func outputFormat(container string) string {
+	if container == "m3u8" {
+		return "hls"
+	}
+	return container
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
 // getMissingProviders will check what providers already have a preset associated to it
 // and return the missing ones. This method is used when a request to create a new preset
 // is done but we already have a PresetMap stored locally.
@@ -10,6 +10,17 @@ type newPresetInput struct {
 	OutputOptions db.OutputOptions `json:"outputOptions"`
 }
 
+// outputOptions returns the output options of the preset map created along
+// with the preset, which default to the container of the preset.
+func (input *newPresetInput) outputOptions() db.OutputOptions {
+	opts := input.OutputOptions
+	opts.Container = input.Preset.Container
+	if opts.Extension == "" {
+		opts.Extension = input.Preset.Container
+	}
+	return opts
+}
+
 // list of the results of the attempt to create a preset
 // in each provider.
 //
@@ -41,3 +52,14 @@ type deletePresetOutput struct {
 	PresetID string `json:"presetId"`
 	Error    string `json:"error,omitempty"`
 }
+
+// result of the validation of a preset, listing the problems found in the
+// preset and in its support by each of the given providers.
+//
+// swagger:response presetValidation
+type presetValidation struct {
+	// in: body
+	// required: true
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems"`
+}
@@ -14,6 +14,10 @@ type deletePresetResponse struct {
 	baseResponse
 }
 
+type presetValidationResponse struct {
+	baseResponse
+}
+
 // error returned when the given preset data is not valid.
 //
 // swagger:response invalidPreset
@@ -28,16 +28,16 @@ func TestNewPreset(t *testing.T) {
 			map[string]interface{}{
 				"providers": []string{"fake", "encodingcom"},
 				"outputOptions": map[string]interface{}{
-					"extension": "mp5",
+					"extension": "m4v",
 				},
 				"preset": map[string]interface{}{
-					"name":         "nyt_test_here_2wq",
-					"description":  "testing creation from api",
-					"container":    "mp4",
-					"profile":      "Main",
-					"profileLevel": "3.1",
-					"rateControl":  "VBR",
+					"name":        "nyt_test_here_2wq",
+					"description": "testing creation from api",
+					"container":   "mp4",
+					"rateControl": "VBR",
 					"video": map[string]string{
+						"profile":       "Main",
+						"profileLevel":  "3.1",
 						"height":        "720",
 						"codec":         "h264",
 						"bitrate":       "1000",
@@ -52,7 +52,8 @@ func TestNewPreset(t *testing.T) {
 				},
 			},
 			db.OutputOptions{
-				Extension: "mp4",
+				Extension: "m4v",
+				Container: "mp4",
 			},
 			map[string]interface{}{
 				"Results": map[string]interface{}{
@@ -74,16 +75,16 @@ func TestNewPreset(t *testing.T) {
 			map[string]interface{}{
 				"providers": []string{"elastictranscoder", "encodingcom"},
 				"outputOptions": map[string]interface{}{
-					"extension": "mp5",
+					"extension": "mp4",
 				},
 				"preset": map[string]interface{}{
-					"name":         "nyt_test_here_3wq",
-					"description":  "testing creation from api",
-					"container":    "mp4",
-					"profile":      "Main",
-					"profileLevel": "3.1",
-					"rateControl":  "VBR",
+					"name":        "nyt_test_here_3wq",
+					"description": "testing creation from api",
+					"container":   "mp4",
+					"rateControl": "VBR",
 					"video": map[string]string{
+						"profile":       "Main",
+						"profileLevel":  "3.1",
 						"height":        "720",
 						"codec":         "h264",
 						"bitrate":       "1000",
@@ -113,6 +114,61 @@ func TestNewPreset(t *testing.T) {
 			},
 			http.StatusInternalServerError,
 		},
+		{
+			"Invalid loudness settings",
+			map[string]interface{}{
+				"providers": []string{"fake"},
+				"outputOptions": map[string]interface{}{
+					"extension": "mp4",
+				},
+				"preset": map[string]interface{}{
+					"name":      "nyt_test_here_4wq",
+					"container": "mp4",
+					"video": map[string]string{
+						"height":  "720",
+						"codec":   "h264",
+						"bitrate": "1000",
+					},
+					"audio": map[string]string{
+						"codec":          "aac",
+						"bitrate":        "64000",
+						"loudnessTarget": "-70",
+					},
+				},
+			},
+			db.OutputOptions{},
+			map[string]interface{}{
+				"error": "invalid preset: audio.loudnessTarget must be between -59 and 0, got -70",
+			},
+			http.StatusBadRequest,
+		},
+		{
+			"Extension not valid for the container",
+			map[string]interface{}{
+				"providers": []string{"fake"},
+				"outputOptions": map[string]interface{}{
+					"extension": "mp5",
+				},
+				"preset": map[string]interface{}{
+					"name":      "nyt_test_here_5wq",
+					"container": "mp4",
+					"video": map[string]string{
+						"height":  "720",
+						"codec":   "h264",
+						"bitrate": "1000",
+					},
+					"audio": map[string]string{
+						"codec":   "aac",
+						"bitrate": "64000",
+					},
+				},
+			},
+			db.OutputOptions{},
+			map[string]interface{}{
+				"error": `invalid outputOptions: extension "mp5" is not valid for the container "mp4"`,
+			},
+			http.StatusBadRequest,
+		},
 	}
 
 	for _, test := range tests {
@@ -153,18 +209,127 @@ func TestNewPreset(t *testing.T) {
 	}
 }
 
+func TestValidatePreset(t *testing.T) {
+	tests := []struct {
+		givenTestCase    string
+		givenRequestData map[string]interface{}
+		wantBody         map[string]interface{}
+		wantCode         int
+	}{
+		{
+			"valid preset",
+			map[string]interface{}{
+				"providers": []string{"fake"},
+				"preset": map[string]interface{}{
+					"name":      "mp4_1080i",
+					"container": "mp4",
+					"video": map[string]string{
+						"profile":       "High",
+						"width":         "1920",
+						"height":        "1080",
+						"codec":         "h264",
+						"interlaceMode": "interlaced",
+						"fieldOrder":    "top",
+					},
+					"audio": map[string]string{
+						"codec":   "aac",
+						"bitrate": "128000",
+					},
+				},
+			},
+			map[string]interface{}{
+				"valid":    true,
+				"problems": []interface{}{},
+			},
+			http.StatusOK,
+		},
+		{
+			"preset with multiple problems",
+			map[string]interface{}{
+				"providers": []string{"fake", "encodingcom"},
+				"outputOptions": map[string]interface{}{
+					"extension": "mp4",
+				},
+				"preset": map[string]interface{}{
+					"name":      "mov_4k",
+					"container": "mov",
+					"video": map[string]string{
+						"profile":         "Extended",
+						"width":           "10000",
+						"codec":           "h264",
+						"interlaceMode":   "interlaced",
+						"aspectRatioMode": "crop",
+					},
+					"audio": map[string]string{
+						"loudnessTarget": "-60",
+					},
+				},
+			},
+			map[string]interface{}{
+				"valid": false,
+				"problems": []interface{}{
+					"video.profile: codec h264 doesn't support the profile \"Extended\", must be one of baseline, main, high",
+					"video.width must be between 0 and 8192, got 10000",
+					"video.interlaceMode interlaced requires video.fieldOrder",
+					"video.aspectRatioMode and video.aspectRatio must be provided together",
+					"audio.loudnessTarget must be between -59 and 0, got -60",
+					`outputOptions: extension "mp4" is not valid for the container "mov"`,
+					`fake: container "mov" is not supported`,
+					"fake: profile Extended is not supported",
+					"encodingcom: getting factory: provider not found",
+				},
+			},
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDB := dbtest.NewFakeRepository(false)
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDB
+		srvr.Register(service)
+		body, _ := json.Marshal(test.givenRequestData)
+		r, _ := http.NewRequest("POST", "/presets/validate", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var got map[string]interface{}
+		err = json.NewDecoder(w.Body).Decode(&got)
+		if err != nil {
+			t.Errorf("%s: unable to JSON decode response body: %s", test.givenTestCase, err)
+		}
+		if !reflect.DeepEqual(got, test.wantBody) {
+			t.Errorf("%s: expected response body of\n%#v;\ngot\n%#v", test.givenTestCase, test.wantBody, got)
+		}
+		presetMaps, err := fakeDB.ListPresetMaps()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(presetMaps) > 0 {
+			t.Errorf("%s: unexpected preset maps stored: %#v", test.givenTestCase, presetMaps)
+		}
+	}
+}
+
 func TestNewPresetWithExistentPresetMap(t *testing.T) {
 	data := map[string]interface{}{
 		"providers":     []string{"zencoder"},
 		"outputOptions": map[string]interface{}{},
 		"preset": map[string]interface{}{
-			"name":         "presetID_here",
-			"description":  "testing creation from api",
-			"container":    "mp4",
-			"profile":      "Main",
-			"profileLevel": "3.1",
-			"rateControl":  "VBR",
+			"name":        "presetID_here",
+			"description": "testing creation from api",
+			"container":   "mp4",
+			"rateControl": "VBR",
 			"video": map[string]string{
+				"profile":       "Main",
+				"profileLevel":  "3.1",
 				"height":        "720",
 				"codec":         "h264",
 				"bitrate":       "1000",
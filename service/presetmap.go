@@ -20,7 +20,7 @@ import (
 func (s *TranscodingService) newPresetMap(r *http.Request) swagger.GizmoJSONResponse {
 	var input newPresetMapInput
 	defer r.Body.Close()
-	preset, err := input.PresetMap(r.Body)
+	preset, err := input.PresetMap(s.requestBody(r))
 	if err != nil {
 		return newInvalidPresetMapResponse(err)
 	}
@@ -70,7 +70,7 @@ func (s *TranscodingService) getPresetMap(r *http.Request) swagger.GizmoJSONResp
 func (s *TranscodingService) updatePresetMap(r *http.Request) swagger.GizmoJSONResponse {
 	defer r.Body.Close()
 	var input updatePresetMapInput
-	presetMap, err := input.PresetMap(web.Vars(r), r.Body)
+	presetMap, err := input.PresetMap(web.Vars(r), s.requestBody(r))
 	if err != nil {
 		return newInvalidPresetMapResponse(err)
 	}
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -134,7 +133,7 @@ func newListPresetMapsResponse(presetsMap []db.PresetMap) *listPresetMapsRespons
 // Preset loads the input from the request body, validates them and returns the
 // preset.
 func (p *newPresetMapInput) PresetMap(body io.Reader) (db.PresetMap, error) {
-	err := json.NewDecoder(body).Decode(&p.Payload)
+	err := decodeJSON(body, &p.Payload)
 	if err != nil {
 		return p.Payload, err
 	}
@@ -155,7 +154,7 @@ func (p *getPresetMapInput) loadParams(paramsMap map[string]string) {
 
 func (p *updatePresetMapInput) PresetMap(paramsMap map[string]string, body io.Reader) (db.PresetMap, error) {
 	p.Name = paramsMap["name"]
-	err := json.NewDecoder(body).Decode(&p.Payload)
+	err := decodeJSON(body, &p.Payload)
 	if err != nil {
 		return p.Payload, err
 	}
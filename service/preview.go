@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// previewJobID is the id given to previewed jobs. Jobs get their id once
+// they're created, so output paths including it have this placeholder
+// instead.
+const previewJobID = "{jobId}"
+
+// previewPath is the path for previewing jobs. The router doesn't allow it
+// along with the routes of existing jobs, e.g. /jobs/:jobId/cancel, so it's
+// one of the staticEndpoints.
+const previewPath = "/jobs/preview"
+
+// JobPreview contains the output files a new job would produce.
+//
+// swagger:model
+type JobPreview struct {
+	// destination of the outputs of the job
+	Destination string `json:"destination,omitempty"`
+
+	// output files of the job, as named by the provider. Paths may include
+	// the {jobId} placeholder, replaced by the id of the job once it's
+	// created
+	Files []provider.OutputFile `json:"files"`
+
+	// list of outputs skipped in best-effort jobs, because their presets
+	// couldn't be used in the job
+	SkippedOutputs []SkippedOutput `json:"skippedOutputs,omitempty"`
+}
+
+// response for the previewJob operation.
+//
+// swagger:response jobPreview
+type jobPreviewResponse struct {
+	// in: body
+	Payload *JobPreview
+
+	baseResponse
+}
+
+// swagger:route POST /jobs/preview jobs previewJob
+//
+// Previews the output files of a new transcoding job, as named by the
+// provider, without creating the job or sending it to the provider. Paths
+// include the {jobId} placeholder where the provider names outputs after the
+// id of the job, which is only assigned once the job is created.
+//
+//     Responses:
+//       200: jobPreview
+//       400: invalidJob
+//       429: genericError
+//       500: genericError
+//       501: genericError
+//       503: datastoreUnavailable
+func (s *TranscodingService) previewTranscodeJob(r *http.Request) swagger.GizmoJSONResponse {
+	defer r.Body.Close()
+	prepared, resp := s.prepareTranscodeJob(r)
+	if resp != nil {
+		return resp
+	}
+	previewer, ok := prepared.provider.(provider.OutputPreviewer)
+	if !ok {
+		return swagger.NewErrorResponse(provider.ErrNotImplemented).WithStatus(http.StatusNotImplemented)
+	}
+	job := prepared.job
+	job.ID = previewJobID
+	job.ProviderName = prepared.providerName
+	output, err := previewer.PreviewOutput(&job)
+	if err == provider.ErrPresetMapNotFound {
+		return newInvalidJobResponse(err)
+	}
+	if _, ok := err.(provider.InvalidJobError); ok {
+		return newInvalidJobResponse(err)
+	}
+	if err != nil {
+		return swagger.NewErrorResponse(err)
+	}
+	preview := JobPreview{
+		Destination:    output.Destination,
+		Files:          output.Files,
+		SkippedOutputs: prepared.skipped,
+	}
+	if preview.Files == nil {
+		preview.Files = []provider.OutputFile{}
+	}
+	return &jobPreviewResponse{
+		baseResponse: baseResponse{payload: &preview, status: http.StatusOK},
+	}
+}
+
+func (s *TranscodingService) previewTranscodeJobHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSONResponse(w, r, s.previewTranscodeJob(r))
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPreviewJob(t *testing.T) {
+	var tests = []struct {
+		givenTestCase    string
+		givenRequestBody string
+
+		wantCode    int
+		wantPreview JobPreview
+		wantError   string
+	}{
+		{
+			"file outputs",
+			`{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"},{"preset":"mp4_1080p","fileName":"custom/name.mp4"}]}`,
+			http.StatusOK,
+			JobPreview{
+				Destination: "s3://fake-destination/{jobId}",
+				Files: []provider.OutputFile{
+					{Path: "s3://fake-destination/{jobId}/video_mp4_1080p.mp4", Container: "mp4"},
+					{Path: "s3://fake-destination/{jobId}/custom/name.mp4", Container: "mp4"},
+				},
+			},
+			"",
+		},
+		{
+			"hls outputs",
+			`{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"hls_1080p"}],"streamingParams":{"protocol":"hls"}}`,
+			http.StatusOK,
+			JobPreview{
+				Destination: "s3://fake-destination/{jobId}",
+				Files: []provider.OutputFile{
+					{Path: "s3://fake-destination/{jobId}/hls/index.m3u8", Container: "m3u8"},
+					{Path: "s3://fake-destination/{jobId}/hls/video_hls_1080p.m3u8", Container: "m3u8"},
+				},
+			},
+			"",
+		},
+		{
+			"best-effort job with skipped outputs",
+			`{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"},{"preset":"webm_1080p"}],"bestEffort":true}`,
+			http.StatusOK,
+			JobPreview{
+				Destination: "s3://fake-destination/{jobId}",
+				Files: []provider.OutputFile{
+					{Path: "s3://fake-destination/{jobId}/video_mp4_1080p.mp4", Container: "mp4"},
+				},
+				SkippedOutputs: []SkippedOutput{{Preset: "webm_1080p", Reason: "presetmap not found"}},
+			},
+			"",
+		},
+		{
+			"invalid job",
+			`{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"webm_1080p"}]}`,
+			http.StatusBadRequest,
+			JobPreview{},
+			"presetmap not found",
+		},
+	}
+	defer func() { fprovider.jobs = nil }()
+	for _, test := range tests {
+		fprovider.jobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "hls_1080p",
+			ProviderMapping: map[string]string{"fake": "19928"},
+			OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+		})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/jobs/preview", strings.NewReader(test.givenRequestBody))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		if test.wantError != "" {
+			var got map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("%s: %s", test.givenTestCase, err)
+			}
+			if got["error"] != test.wantError {
+				t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, test.wantError, got["error"])
+			}
+		} else {
+			var got JobPreview
+			err = json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("%s: %s", test.givenTestCase, err)
+			}
+			if !reflect.DeepEqual(got, test.wantPreview) {
+				t.Errorf("%s: wrong preview.\nWant %#v\nGot  %#v", test.givenTestCase, test.wantPreview, got)
+			}
+		}
+		jobs, err := fakeDBObj.ListJobs(db.JobFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) > 0 {
+			t.Errorf("%s: unexpected jobs stored: %#v", test.givenTestCase, jobs)
+		}
+		if len(fprovider.jobs) > 0 {
+			t.Errorf("%s: unexpected jobs sent to the provider: %#v", test.givenTestCase, fprovider.jobs)
+		}
+	}
+}
+
+func TestPreviewJobUnknownRoute(t *testing.T) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = dbtest.NewFakeRepository(false)
+	srvr.Register(service)
+	r, _ := http.NewRequest("POST", "/jobs/job-123", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("wrong response code. Want %d. Got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestPreviewJobRateLimit(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	now := time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, JobRateLimit: 2}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeDB := dbtest.NewFakeRepository(false)
+	fakeDB.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	service.db = fakeDB
+	service.clock = func() time.Time { return now }
+	srvr.Register(service)
+	body := `{"source":"http://another.non.existent/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`
+	var codes []int
+	for _, path := range []string{"/jobs/preview", "/jobs", "/jobs/preview"} {
+		r, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	expectedCodes := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	if !reflect.DeepEqual(codes, expectedCodes) {
+		t.Errorf("wrong response codes. Want %v. Got %v", expectedCodes, codes)
+	}
+}
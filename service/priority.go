@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+// escalateJobPriority raises the priority of jobs that opted in for
+// escalation and have been queued for longer than the configured threshold,
+// measured from the creation of the job. The priority is raised at most once
+// per job. Failures are logged instead of failing the status check, as the
+// job keeps going with its original priority and escalation is retried in
+// the next check.
+func (s *TranscodingService) escalateJobPriority(ctx context.Context, job *db.Job, status *provider.JobStatus, p provider.TranscodingProvider) {
+	threshold := time.Duration(s.config.JobPriorityEscalationThreshold) * time.Second
+	if !job.EscalatePriority || job.PriorityEscalated || threshold == 0 || status.Status != provider.StatusQueued {
+		return
+	}
+	if s.now().Sub(job.CreationTime) < threshold {
+		return
+	}
+	updater, ok := p.(provider.PriorityUpdater)
+	if !ok {
+		return
+	}
+	logger := s.logger.WithField("jobId", job.ID)
+	err := updater.UpdateJobPriority(ctx, job.ProviderJobID, s.config.EscalatedJobPriority)
+	if err != nil {
+		logger.WithError(err).Error("failed to escalate the priority of queued job")
+		return
+	}
+	job.PriorityEscalated = true
+	err = s.db.UpdateJob(job)
+	if err != nil {
+		logger.WithError(err).Error("failed to store the escalation of the priority of job")
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobPriorityEscalation(t *testing.T) {
+	creationTime := time.Date(2016, 3, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		givenTestCase    string
+		givenJob         db.Job
+		givenElapsedTime time.Duration
+
+		wantPriorities map[string]int
+	}{
+		{
+			"queued job within the threshold",
+			db.Job{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued", EscalatePriority: true},
+			9 * time.Minute,
+			nil,
+		},
+		{
+			"queued job exceeding the threshold",
+			db.Job{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued", EscalatePriority: true},
+			10 * time.Minute,
+			map[string]int{"provider-job-queued": 90},
+		},
+		{
+			"queued job without escalation",
+			db.Job{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued"},
+			time.Hour,
+			nil,
+		},
+		{
+			"running job",
+			db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", EscalatePriority: true},
+			time.Hour,
+			nil,
+		},
+	}
+	defer func() { fprovider.priorities = nil }()
+	for _, test := range tests {
+		fprovider.priorities = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		job := test.givenJob
+		job.CreationTime = creationTime
+		fakeDBObj.CreateJob(&job)
+		service, err := NewTranscodingService(&config.Config{
+			Server:                         &server.Config{},
+			JobPriorityEscalationThreshold: 600,
+			EscalatedJobPriority:           90,
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		service.clock = func() time.Time { return creationTime.Add(test.givenElapsedTime) }
+		srvr.Register(service)
+		for i := 0; i < 2; i++ {
+			r, _ := http.NewRequest("GET", "/jobs/"+job.ID, nil)
+			w := httptest.NewRecorder()
+			srvr.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusOK, w.Code)
+			}
+		}
+		if !reflect.DeepEqual(fprovider.priorities, test.wantPriorities) {
+			t.Errorf("%s: wrong priority updates. Want %#v. Got %#v", test.givenTestCase, test.wantPriorities, fprovider.priorities)
+		}
+		dbJob, err := fakeDBObj.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantEscalated := test.wantPriorities != nil; dbJob.PriorityEscalated != wantEscalated {
+			t.Errorf("%s: wrong PriorityEscalated flag in the database. Want %v. Got %v", test.givenTestCase, wantEscalated, dbJob.PriorityEscalated)
+		}
+	}
+}
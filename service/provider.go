@@ -1,6 +1,7 @@
 package service
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/NYTimes/gizmo/web"
@@ -32,7 +33,7 @@ func (s *TranscodingService) listProviders(r *http.Request) swagger.GizmoJSONRes
 func (s *TranscodingService) getProvider(r *http.Request) swagger.GizmoJSONResponse {
 	var params getProviderInput
 	params.loadParams(web.Vars(r))
-	description, err := provider.DescribeProvider(params.Name, s.config)
+	description, err := provider.DescribeProvider(r.Context(), params.Name, s.config)
 	switch err {
 	case nil:
 		return newGetProviderResponse(description)
@@ -42,3 +43,39 @@ func (s *TranscodingService) getProvider(r *http.Request) swagger.GizmoJSONRespo
 		return swagger.NewErrorResponse(err)
 	}
 }
+
+// swagger:route GET /providers/{name}/presets providers listProviderPresets
+//
+// List the presets defined in the provider, including the ones that weren't
+// created by the API, so they can be used in preset maps.
+//
+//     Responses:
+//       200: listProviderPresets
+//       404: providerNotFound
+//       500: genericError
+//       501: genericError
+//       502: providerError
+func (s *TranscodingService) listProviderPresets(r *http.Request) swagger.GizmoJSONResponse {
+	var params getProviderInput
+	params.loadParams(web.Vars(r))
+	providerFactory, err := provider.GetProviderFactory(params.Name)
+	if err != nil {
+		return newProviderNotFoundResponse(err)
+	}
+	providerObj, err := providerFactory(s.config)
+	if err != nil {
+		return swagger.NewErrorResponse(fmt.Errorf("error initializing provider %q: %s", params.Name, err))
+	}
+	lister, ok := providerObj.(provider.PresetLister)
+	if !ok {
+		return swagger.NewErrorResponse(provider.ErrNotImplemented).WithStatus(http.StatusNotImplemented)
+	}
+	presets, err := lister.ListPresets(r.Context())
+	if err != nil {
+		if err == provider.ErrNotImplemented {
+			return swagger.NewErrorResponse(err).WithStatus(http.StatusNotImplemented)
+		}
+		return newProviderErrorResponse(err)
+	}
+	return newListProviderPresetsResponse(presets)
+}
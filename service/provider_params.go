@@ -1,6 +1,6 @@
 package service
 
-// swagger:parameters getProvider deleteProvider
+// swagger:parameters getProvider deleteProvider listProviderPresets
 type getProviderInput struct {
 	// in: path
 	// required: true
@@ -40,6 +40,22 @@ func newGetProviderResponse(p *provider.Description) *getProviderResponse {
 	}
 }
 
+// response for the listProviderPresets operation.
+//
+// swagger:response listProviderPresets
+type listProviderPresetsResponse struct {
+	// in: body
+	Presets []provider.PresetSummary
+
+	baseResponse
+}
+
+func newListProviderPresetsResponse(presets []provider.PresetSummary) *listProviderPresetsResponse {
+	return &listProviderPresetsResponse{
+		baseResponse: baseResponse{payload: presets, status: http.StatusOK},
+	}
+}
+
 // error returned when the given provider name is not found in the API.
 //
 // swagger:response providerNotFound
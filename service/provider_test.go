@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/NYTimes/gizmo/server"
 	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
 	"github.com/sirupsen/logrus"
 )
 
@@ -52,9 +55,12 @@ func TestGetProvider(t *testing.T) {
 				"name":   "fake",
 				"health": map[string]interface{}{"ok": true},
 				"capabilities": map[string]interface{}{
-					"input":        []interface{}{"prores", "h264"},
-					"output":       []interface{}{"mp4", "webm", "hls"},
-					"destinations": []interface{}{"akamai", "s3"},
+					"input":           []interface{}{"prores", "h264"},
+					"output":          []interface{}{"mp4", "webm", "hls", "jpg"},
+					"destinations":    []interface{}{"akamai", "s3"},
+					"outputACLs":      []interface{}{"private"},
+					"inputContainers": []interface{}{"mp4", "mxf"},
+					"jobSpecs":        true,
 				},
 				"enabled": true,
 			},
@@ -89,3 +95,104 @@ func TestGetProvider(t *testing.T) {
 		}
 	}
 }
+
+func TestListProviderPresets(t *testing.T) {
+	var tests = []struct {
+		testCase string
+		name     string
+
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			"list presets",
+			"fake",
+			http.StatusOK,
+			[]interface{}{
+				map[string]interface{}{"id": "18828", "name": "mp4_1080p", "container": "mp4"},
+				map[string]interface{}{"id": "19928", "name": "hls_1080p", "description": "HLS 1080p", "container": "m3u8"},
+			},
+		},
+		{
+			"provider not found",
+			"whatever",
+			http.StatusNotFound,
+			map[string]interface{}{"error": "provider not found"},
+		},
+		{
+			"disabled provider",
+			"disabled",
+			http.StatusInternalServerError,
+			map[string]interface{}{"error": `error initializing provider "disabled": provider is disabled in the configuration`},
+		},
+	}
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		srvr.Register(service)
+		r, _ := http.NewRequest("GET", "/providers/"+test.name+"/presets", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.expectedStatus {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.testCase, test.expectedStatus, w.Code)
+		}
+		var gotBody interface{}
+		err = json.NewDecoder(w.Body).Decode(&gotBody)
+		if err != nil {
+			t.Errorf("%s: %s", test.testCase, err)
+		}
+		if !reflect.DeepEqual(gotBody, test.expectedBody) {
+			t.Errorf("%s: wrong body.\nWant %#v.\nGot  %#v", test.testCase, test.expectedBody, gotBody)
+		}
+	}
+}
+
+type requestContextKey struct{}
+
+func TestProviderCallsRequestContext(t *testing.T) {
+	var tests = []struct {
+		givenMethod string
+		givenURI    string
+
+		wantCall string
+	}{
+		{"POST", "/jobs/job-123/cancel", "CancelJob"},
+		{"GET", "/jobs/job-123/cost", "GetJobCost"},
+		{"GET", "/jobs/job-123/logs", "GetJobLogs"},
+		{"GET", "/providers/fake/presets", "ListPresets"},
+	}
+	defer func() {
+		fprovider.canceledJobs = nil
+		fprovider.callContexts = nil
+	}()
+	for _, test := range tests {
+		fprovider.callContexts = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest(test.givenMethod, test.givenURI, nil)
+		r = r.WithContext(context.WithValue(r.Context(), requestContextKey{}, test.givenURI))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: wrong status code. Want %d. Got %d", test.givenMethod, test.givenURI, http.StatusOK, w.Code)
+		}
+		ctx := fprovider.callContexts[test.wantCall]
+		if ctx == nil {
+			t.Errorf("%s %s: %s not called", test.givenMethod, test.givenURI, test.wantCall)
+			continue
+		}
+		if got := ctx.Value(requestContextKey{}); got != test.givenURI {
+			t.Errorf("%s %s: %s called without the context of the request", test.givenMethod, test.givenURI, test.wantCall)
+		}
+	}
+}
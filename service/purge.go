@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// PurgeSummary describes the result of purging job records.
+//
+// swagger:model
+type PurgeSummary struct {
+	// number of jobs deleted
+	Deleted int `json:"deleted"`
+}
+
+// response for the purgeJobs operation.
+//
+// swagger:response purgeSummary
+type purgeJobsResponse struct {
+	// in: body
+	Payload *PurgeSummary
+
+	baseResponse
+}
+
+// swagger:parameters purgeJobs
+type purgeJobsInput struct {
+	// minimum age of the jobs, as a duration (e.g. 720h)
+	//
+	// in: query
+	// required: true
+	OlderThan string `json:"olderThan"`
+
+	// name of the provider of the jobs
+	//
+	// in: query
+	Provider string `json:"provider"`
+
+	// last known status of the jobs, one of finished, failed or canceled.
+	// Defaults to any of them
+	//
+	// in: query
+	Status string `json:"status"`
+}
+
+func (p *purgeJobsInput) loadParams(values url.Values) {
+	p.OlderThan = values.Get("olderThan")
+	p.Provider = values.Get("provider")
+	p.Status = values.Get("status")
+}
+
+// swagger:route DELETE /admin/jobs admin purgeJobs
+//
+// Deletes the records of jobs in a terminal state created before the given
+// age, optionally filtered by provider and status. Jobs that are still in
+// progress are never deleted. Requires the admin token.
+//
+//     Responses:
+//       200: purgeSummary
+//       400: genericError
+//       401: genericError
+//       403: genericError
+//       500: genericError
+func (s *TranscodingService) purgeJobs(r *http.Request) swagger.GizmoJSONResponse {
+	var params purgeJobsInput
+	params.loadParams(r.URL.Query())
+	olderThan, err := time.ParseDuration(params.OlderThan)
+	if err != nil || olderThan <= 0 {
+		return swagger.NewErrorResponse(fmt.Errorf("invalid olderThan %q, must be a positive duration (e.g. 720h)", params.OlderThan)).WithStatus(http.StatusBadRequest)
+	}
+	if params.Status != "" && !isTerminalStatus(provider.Status(params.Status)) {
+		return swagger.NewErrorResponse(fmt.Errorf("invalid status %q, must be one of finished, failed or canceled", params.Status)).WithStatus(http.StatusBadRequest)
+	}
+	jobs, err := s.db.ListJobs(db.JobFilter{})
+	if err != nil {
+		return swagger.NewErrorResponse(fmt.Errorf("error listing jobs: %s", err))
+	}
+	cutoff := s.now().Add(-olderThan)
+	var summary PurgeSummary
+	for i := range jobs {
+		job := &jobs[i]
+		if !isTerminalStatus(provider.Status(job.Status)) || !job.CreationTime.Before(cutoff) {
+			continue
+		}
+		if params.Provider != "" && job.ProviderName != params.Provider {
+			continue
+		}
+		if params.Status != "" && job.Status != params.Status {
+			continue
+		}
+		err = s.db.DeleteJob(job)
+		if err == db.ErrJobNotFound {
+			continue
+		}
+		if err != nil {
+			return swagger.NewErrorResponse(fmt.Errorf("error deleting job %q after deleting %d jobs: %s", job.ID, summary.Deleted, err))
+		}
+		summary.Deleted++
+	}
+	return &purgeJobsResponse{
+		baseResponse: baseResponse{payload: &summary, status: http.StatusOK},
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPurgeJobs(t *testing.T) {
+	now := time.Date(2018, 6, 30, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-60 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	var tests = []struct {
+		givenTestCase string
+		givenQuery    string
+
+		wantDeleted   int
+		wantRemaining []string
+	}{
+		{
+			"by age",
+			"?olderThan=720h",
+			3,
+			[]string{"old-queued", "old-started", "old-unknown", "recent-failed", "recent-finished"},
+		},
+		{
+			"by age and provider",
+			"?olderThan=720h&provider=fake",
+			2,
+			[]string{"old-queued", "old-started", "old-unknown", "old-zencoder-finished", "recent-failed", "recent-finished"},
+		},
+		{
+			"by age and status",
+			"?olderThan=720h&status=finished",
+			2,
+			[]string{"old-failed", "old-queued", "old-started", "old-unknown", "recent-failed", "recent-finished"},
+		},
+		{
+			"by age, provider and status",
+			"?olderThan=720h&provider=fake&status=failed",
+			1,
+			[]string{"old-finished", "old-queued", "old-started", "old-unknown", "old-zencoder-finished", "recent-failed", "recent-finished"},
+		},
+		{
+			"short age",
+			"?olderThan=30m",
+			5,
+			[]string{"old-queued", "old-started", "old-unknown"},
+		},
+		{
+			"no matches",
+			"?olderThan=720h&provider=elementalconductor",
+			0,
+			[]string{"old-failed", "old-finished", "old-queued", "old-started", "old-unknown", "old-zencoder-finished", "recent-failed", "recent-finished"},
+		},
+	}
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		jobs := []db.Job{
+			{ID: "old-finished", ProviderName: "fake", Status: "finished", CreationTime: old},
+			{ID: "old-failed", ProviderName: "fake", Status: "failed", CreationTime: old},
+			{ID: "old-zencoder-finished", ProviderName: "zencoder", Status: "finished", CreationTime: old},
+			{ID: "old-queued", ProviderName: "fake", Status: "queued", CreationTime: old},
+			{ID: "old-started", ProviderName: "fake", Status: "started", CreationTime: old},
+			{ID: "old-unknown", ProviderName: "fake", CreationTime: old},
+			{ID: "recent-finished", ProviderName: "fake", Status: "finished", CreationTime: recent},
+			{ID: "recent-failed", ProviderName: "zencoder", Status: "failed", CreationTime: recent},
+		}
+		for i := range jobs {
+			fakeDBObj.CreateJob(&jobs[i])
+		}
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, AdminToken: "admin-secret"}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		service.clock = func() time.Time { return now }
+		srvr.Register(service)
+		r, _ := http.NewRequest("DELETE", "/admin/jobs"+test.givenQuery, nil)
+		r.Header.Set("Authorization", "Bearer admin-secret")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: wrong response code. Want %d. Got %d: %s", test.givenTestCase, http.StatusOK, w.Code, w.Body.String())
+			continue
+		}
+		var summary PurgeSummary
+		err = json.Unmarshal(w.Body.Bytes(), &summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.Deleted != test.wantDeleted {
+			t.Errorf("%s: wrong number of deleted jobs. Want %d. Got %d", test.givenTestCase, test.wantDeleted, summary.Deleted)
+		}
+		remainingJobs, err := fakeDBObj.ListJobs(db.JobFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		remaining := make([]string, len(remainingJobs))
+		for i, job := range remainingJobs {
+			remaining[i] = job.ID
+		}
+		sort.Strings(remaining)
+		if !reflect.DeepEqual(remaining, test.wantRemaining) {
+			t.Errorf("%s: wrong remaining jobs\nwant %v\ngot  %v", test.givenTestCase, test.wantRemaining, remaining)
+		}
+	}
+}
+
+func TestPurgeJobsInvalidRequests(t *testing.T) {
+	var tests = []struct {
+		givenTestCase  string
+		givenToken     string
+		givenAuthToken string
+		givenQuery     string
+
+		wantCode  int
+		wantError string
+	}{
+		{
+			"admin token not configured",
+			"",
+			"Bearer admin-secret",
+			"?olderThan=720h",
+			http.StatusForbidden,
+			"admin endpoint disabled, ADMIN_TOKEN is not set",
+		},
+		{
+			"missing authorization",
+			"admin-secret",
+			"",
+			"?olderThan=720h",
+			http.StatusUnauthorized,
+			"missing or invalid admin token",
+		},
+		{
+			"wrong token",
+			"admin-secret",
+			"Bearer other-secret",
+			"?olderThan=720h",
+			http.StatusUnauthorized,
+			"missing or invalid admin token",
+		},
+		{
+			"token without the bearer scheme",
+			"admin-secret",
+			"admin-secret",
+			"?olderThan=720h",
+			http.StatusUnauthorized,
+			"missing or invalid admin token",
+		},
+		{
+			"missing age",
+			"admin-secret",
+			"Bearer admin-secret",
+			"",
+			http.StatusBadRequest,
+			`invalid olderThan "", must be a positive duration (e.g. 720h)`,
+		},
+		{
+			"invalid age",
+			"admin-secret",
+			"Bearer admin-secret",
+			"?olderThan=30d",
+			http.StatusBadRequest,
+			`invalid olderThan "30d", must be a positive duration (e.g. 720h)`,
+		},
+		{
+			"non-terminal status",
+			"admin-secret",
+			"Bearer admin-secret",
+			"?olderThan=720h&status=started",
+			http.StatusBadRequest,
+			`invalid status "started", must be one of finished, failed or canceled`,
+		},
+	}
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		job := db.Job{ID: "old-finished", ProviderName: "fake", Status: "finished", CreationTime: time.Now().Add(-60 * 24 * time.Hour)}
+		fakeDBObj.CreateJob(&job)
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, AdminToken: test.givenToken}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("DELETE", "/admin/jobs"+test.givenQuery, nil)
+		if test.givenAuthToken != "" {
+			r.Header.Set("Authorization", test.givenAuthToken)
+		}
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var body map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body["error"] != test.wantError {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, test.wantError, body["error"])
+		}
+		if _, err := fakeDBObj.GetJob(job.ID); err != nil {
+			t.Errorf("%s: job shouldn't have been deleted: %s", test.givenTestCase, err)
+		}
+	}
+}
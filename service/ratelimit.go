@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// maxRateLimitBuckets is the number of buckets kept by the rate limiter
+// before it starts discarding the ones that are full, which are
+// indistinguishable from new buckets.
+const maxRateLimitBuckets = 10000
+
+var errRateLimited = errors.New("rate limit exceeded, please try again later")
+
+// rateLimiter implements token-bucket rate limiting keyed by client. Each
+// client may perform up to limit requests per minute, in bursts of at most
+// limit requests.
+type rateLimiter struct {
+	limit     uint
+	overrides map[string]uint
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit uint, overrides map[string]uint) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		overrides: overrides,
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+func (l *rateLimiter) limitFor(key string) uint {
+	if limit, ok := l.overrides[key]; ok {
+		return limit
+	}
+	return l.limit
+}
+
+// allow takes a token from the bucket of the given client, returning false
+// and how long the client should wait before trying again when the bucket is
+// empty.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	limit := l.limitFor(key)
+	if limit == 0 {
+		return true, 0
+	}
+	capacity := float64(limit)
+	rate := capacity / 60
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.prune(now)
+		}
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[key] = bucket
+	}
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+elapsed*rate)
+		bucket.last = now
+	}
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+func (l *rateLimiter) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		capacity := float64(l.limitFor(key))
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*capacity/60 >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitKey identifies the client of the given request by its API key,
+// falling back to its address for requests without one.
+func (s *TranscodingService) rateLimitKey(r *http.Request) string {
+	if key := r.Header.Get(s.config.JobRateLimitHeader); key != "" {
+		return key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimited wraps the given handler, replying with 429 Too Many Requests
+// to clients that exceed their rate limit.
+func (s *TranscodingService) rateLimited(l *rateLimiter, h http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter := l.allow(s.rateLimitKey(r), s.now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			s.writeJSONResponse(w, r, swagger.NewErrorResponse(errRateLimited).WithStatus(http.StatusTooManyRequests))
+			return
+		}
+		h(w, r)
+	}
+}
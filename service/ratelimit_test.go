@@ -0,0 +1,107 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobCreationRateLimit(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	now := time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{
+		Server:                &server.Config{},
+		JobRateLimit:          2,
+		JobRateLimitOverrides: map[string]uint{"batch-key": 3},
+		JobRateLimitHeader:    "X-Api-Key",
+	}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeDB := dbtest.NewFakeRepository(false)
+	fakeDB.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	service.db = fakeDB
+	service.clock = func() time.Time { return now }
+	srvr.Register(service)
+	newJob := func(apiKey string) *httptest.ResponseRecorder {
+		body := `{"source":"http://another.non.existent/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Api-Key", apiKey)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		givenTestCase string
+		givenAPIKey   string
+		givenElapsed  time.Duration
+
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{"first job", "some-key", 0, http.StatusOK, ""},
+		{"second job", "some-key", 0, http.StatusOK, ""},
+		{"over the limit", "some-key", 0, http.StatusTooManyRequests, "30"},
+		{"independent bucket", "another-key", 0, http.StatusOK, ""},
+		{"still over the limit", "some-key", 10 * time.Second, http.StatusTooManyRequests, "20"},
+		{"after refilling", "some-key", 20 * time.Second, http.StatusOK, ""},
+		{"overridden limit - first job", "batch-key", 0, http.StatusOK, ""},
+		{"overridden limit - second job", "batch-key", 0, http.StatusOK, ""},
+		{"overridden limit - third job", "batch-key", 0, http.StatusOK, ""},
+		{"overridden limit - over the limit", "batch-key", 0, http.StatusTooManyRequests, "20"},
+	}
+	for _, test := range tests {
+		now = now.Add(test.givenElapsed)
+		w := newJob(test.givenAPIKey)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != test.wantRetryAfter {
+			t.Errorf("%s: wrong Retry-After header. Want %q. Got %q", test.givenTestCase, test.wantRetryAfter, retryAfter)
+		}
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	service := TranscodingService{config: &config.Config{JobRateLimitHeader: "X-Api-Key"}}
+	r, _ := http.NewRequest("POST", "/jobs", nil)
+	r.RemoteAddr = "10.0.0.12:51234"
+	if key := service.rateLimitKey(r); key != "10.0.0.12" {
+		t.Errorf("wrong key for request without API key. Want %q. Got %q", "10.0.0.12", key)
+	}
+	r.Header.Set("X-Api-Key", "some-key")
+	if key := service.rateLimitKey(r); key != "some-key" {
+		t.Errorf("wrong key for request with API key. Want %q. Got %q", "some-key", key)
+	}
+}
+
+func TestRateLimiterWithoutLimit(t *testing.T) {
+	limiter := newRateLimiter(0, map[string]uint{"limited-key": 1})
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		if ok, _ := limiter.allow("some-key", now); !ok {
+			t.Fatalf("unexpected throttling of request %d", i)
+		}
+	}
+	if ok, _ := limiter.allow("limited-key", now); !ok {
+		t.Error("unexpected throttling of first request of limited key")
+	}
+	if ok, _ := limiter.allow("limited-key", now); ok {
+		t.Error("second request of limited key should be throttled")
+	}
+}
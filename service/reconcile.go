@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// ReconcileSummary describes the result of reconciling the stored status of
+// jobs with their status in the providers.
+//
+// swagger:model
+type ReconcileSummary struct {
+	// number of jobs whose status was retrieved from the provider
+	Checked int `json:"checked"`
+
+	// number of jobs whose stored status was out of date
+	Changed int `json:"changed"`
+
+	// number of jobs skipped for being in a terminal state
+	Skipped int `json:"skipped"`
+
+	// errors retrieving the status of jobs, keyed by job id
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// response for the reconcileJobs operation.
+//
+// swagger:response reconcileSummary
+type reconcileResponse struct {
+	// in: body
+	Payload *ReconcileSummary
+
+	baseResponse
+}
+
+// swagger:route POST /admin/reconcile admin reconcileJobs
+//
+// Retrieves the status of all jobs that are not in a terminal state from
+// their providers, updating the stored status of the jobs. Meant for
+// recovering from outages. Requires the admin token.
+//
+//     Responses:
+//       200: reconcileSummary
+//       401: genericError
+//       403: genericError
+//       500: genericError
+func (s *TranscodingService) reconcileJobs(r *http.Request) swagger.GizmoJSONResponse {
+	jobs, err := s.db.ListJobs(db.JobFilter{})
+	if err != nil {
+		return swagger.NewErrorResponse(fmt.Errorf("error listing jobs: %s", err))
+	}
+	var summary ReconcileSummary
+	providers := make(map[string]provider.TranscodingProvider)
+	for i := range jobs {
+		job := &jobs[i]
+		if isTerminalStatus(provider.Status(job.Status)) {
+			summary.Skipped++
+			continue
+		}
+		summary.Checked++
+		previousStatus := job.Status
+		err = s.reconcileJob(r.Context(), job, providers)
+		if err != nil {
+			if summary.Errors == nil {
+				summary.Errors = make(map[string]string)
+			}
+			summary.Errors[job.ID] = err.Error()
+			continue
+		}
+		if job.Status != previousStatus {
+			summary.Changed++
+		}
+	}
+	return &reconcileResponse{
+		baseResponse: baseResponse{payload: &summary, status: http.StatusOK},
+	}
+}
+
+// reconcileJob retrieves the status of the given job, which stores it when
+// it changes, reusing the providers already initialized.
+func (s *TranscodingService) reconcileJob(ctx context.Context, job *db.Job, providers map[string]provider.TranscodingProvider) error {
+	p, ok := providers[job.ProviderName]
+	if !ok {
+		providerFactory, err := provider.GetProviderFactory(job.ProviderName)
+		if err != nil {
+			return fmt.Errorf("unknown provider %q", job.ProviderName)
+		}
+		p, err = providerFactory(s.config)
+		if err != nil {
+			return fmt.Errorf("error initializing provider %q: %s", job.ProviderName, err)
+		}
+		providers[job.ProviderName] = p
+	}
+	_, err := s.jobStatus(ctx, job, p)
+	return err
+}
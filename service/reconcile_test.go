@@ -0,0 +1,158 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReconcileJobs(t *testing.T) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	jobs := []db.Job{
+		{ID: "job-stale", ProviderName: "fake", ProviderJobID: "provider-job-123", Status: "started"},
+		{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "started"},
+		{ID: "job-unknown-status", ProviderName: "fake", ProviderJobID: "provider-job-queued"},
+		{ID: "job-finished", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "finished"},
+		{ID: "job-error", ProviderName: "fake", ProviderJobID: "provider-job-error", Status: "queued"},
+	}
+	for i := range jobs {
+		fakeDBObj.CreateJob(&jobs[i])
+	}
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, AdminToken: "admin-secret"}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	r, _ := http.NewRequest("POST", "/admin/reconcile", nil)
+	r.Header.Set("Authorization", "Bearer admin-secret")
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	var summary ReconcileSummary
+	err = json.Unmarshal(w.Body.Bytes(), &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSummary := ReconcileSummary{
+		Checked: 4,
+		Changed: 2,
+		Skipped: 1,
+		Errors:  map[string]string{"job-error": "internal server error"},
+	}
+	if !reflect.DeepEqual(summary, expectedSummary) {
+		t.Errorf("wrong summary\nwant %#v\ngot  %#v", expectedSummary, summary)
+	}
+	expectedStatuses := map[string]string{
+		"job-stale":          "finished",
+		"job-running":        "started",
+		"job-unknown-status": "queued",
+		"job-finished":       "finished",
+		"job-error":          "queued",
+	}
+	for id, expectedStatus := range expectedStatuses {
+		job, err := fakeDBObj.GetJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != expectedStatus {
+			t.Errorf("wrong stored status for %q. Want %q. Got %q", id, expectedStatus, job.Status)
+		}
+	}
+}
+
+func TestReconcileJobsDBError(t *testing.T) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, AdminToken: "admin-secret"}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = dbtest.NewFakeRepository(true)
+	srvr.Register(service)
+	r, _ := http.NewRequest("POST", "/admin/reconcile", nil)
+	r.Header.Set("Authorization", "Bearer admin-secret")
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("wrong response code. Want %d. Got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestReconcileJobsUnauthorized(t *testing.T) {
+	var tests = []struct {
+		givenTestCase  string
+		givenToken     string
+		givenAuthToken string
+
+		wantCode  int
+		wantError string
+	}{
+		{
+			"admin token not configured",
+			"",
+			"Bearer admin-secret",
+			http.StatusForbidden,
+			"admin endpoint disabled, ADMIN_TOKEN is not set",
+		},
+		{
+			"missing authorization",
+			"admin-secret",
+			"",
+			http.StatusUnauthorized,
+			"missing or invalid admin token",
+		},
+		{
+			"wrong token",
+			"admin-secret",
+			"Bearer other-secret",
+			http.StatusUnauthorized,
+			"missing or invalid admin token",
+		},
+	}
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreateJob(&db.Job{ID: "job-stale", ProviderName: "fake", ProviderJobID: "provider-job-123", Status: "started"})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, AdminToken: test.givenToken}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/admin/reconcile", nil)
+		if test.givenAuthToken != "" {
+			r.Header.Set("Authorization", test.givenAuthToken)
+		}
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var body map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body["error"] != test.wantError {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, test.wantError, body["error"])
+		}
+		job, err := fakeDBObj.GetJob("job-stale")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != "started" {
+			t.Errorf("%s: job shouldn't have been reconciled. Got status %q", test.givenTestCase, job.Status)
+		}
+	}
+}
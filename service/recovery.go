@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/NYTimes/video-transcoding-api/swagger"
+	"github.com/sirupsen/logrus"
+)
+
+// errUnexpected is the error sent to clients when a handler panics, leaving
+// out the details of the failure.
+var errUnexpected = errors.New("unexpected server error")
+
+// recoverPanics wraps the given handler, logging panics along with their
+// stack and the id of the request, and replying with 500 (Internal Server
+// Error) instead of dropping the connection. Once the handler has started
+// writing its response, the status can't be changed anymore, so the panic
+// is only logged and the connection is dropped.
+func (s *TranscodingService) recoverPanics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := responseWriter{ResponseWriter: w}
+		defer func() {
+			if x := recover(); x != nil {
+				s.logger.WithFields(logrus.Fields{
+					"requestId": r.Header.Get("X-Request-Id"),
+					"method":    r.Method,
+					"path":      r.URL.Path,
+					"stack":     string(debug.Stack()),
+				}).Errorf("recovered from panic: %v", x)
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+				s.writeJSONResponse(w, r, swagger.NewErrorResponse(errUnexpected))
+			}
+		}()
+		h.ServeHTTP(&rw, r)
+	})
+}
+
+// responseWriter tracks whether the header of the response was written.
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends the buffered response to the client, when supported by the
+// underlying writer, so handlers streaming their responses keep working.
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
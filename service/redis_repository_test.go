@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/redis"
+	"github.com/NYTimes/video-transcoding-api/db/redis/storage"
+	redisDriver "github.com/go-redis/redis"
+)
+
+// testLockKey is the key of the lock held by the tests of the packages using
+// the redis server, as they run in parallel and clean the keys of each other.
+const testLockKey = "video-transcoding-api:tests:lock"
+
+var (
+	redisLockOnce sync.Once
+	redisUnlock   = func() {}
+	redisLockErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	redisUnlock()
+	os.Exit(code)
+}
+
+// newRedisRepository returns an empty repository backed by the redis server
+// used by the tests of db/redis, for tests that depend on what's actually
+// persisted in jobs. The lock of the redis server is taken by the first call
+// and held until the tests of the package finish, so they don't interfere
+// with the tests of db/redis running in parallel.
+func newRedisRepository(t *testing.T) db.Repository {
+	redisLockOnce.Do(func() {
+		var unlock func()
+		unlock, redisLockErr = lockRedis()
+		if redisLockErr == nil {
+			redisUnlock = unlock
+		}
+	})
+	if redisLockErr != nil {
+		t.Fatal(redisLockErr)
+	}
+	cfg := config.Config{Redis: new(storage.Config)}
+	client := cfg.Redis.RedisClient()
+	defer client.Close()
+	for _, pattern := range []string{"job:*", "jobs", "jobs:*", "presetmap:*", "presetmaps", "localpreset:*", "localpresets"} {
+		keys, err := client.Keys(pattern).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) > 0 {
+			err = client.Del(keys...).Err()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	repo, err := redis.NewRepository(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// lockRedis waits for the lock of the tests using the redis server, returning
+// the function that releases it.
+func lockRedis() (func(), error) {
+	client := redisDriver.NewClient(&redisDriver.Options{Addr: "127.0.0.1:6379"})
+	token := strconv.FormatInt(time.Now().UnixNano(), 10)
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		ok, err := client.SetNX(testLockKey, token, 2*time.Minute).Result()
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			client.Close()
+			return nil, fmt.Errorf("timed out waiting for %s", testLockKey)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return func() {
+		defer client.Close()
+		if value, _ := client.Get(testLockKey).Result(); value == token {
+			client.Del(testLockKey)
+		}
+	}, nil
+}
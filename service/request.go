@@ -0,0 +1,88 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxRequestBodySize is the maximum size of request bodies when the
+// configuration doesn't specify one.
+const defaultMaxRequestBodySize = 1 << 20
+
+var errEmptyRequestBody = errors.New("missing request body")
+
+// requestBodyTooLargeError is returned when the request body exceeds the
+// configured maximum size.
+type requestBodyTooLargeError struct {
+	maxSize int64
+}
+
+func (e requestBodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body exceeds the maximum size of %d bytes", e.maxSize)
+}
+
+// limitedReader wraps a request body, returning requestBodyTooLargeError
+// once more than maxSize bytes have been read from it.
+type limitedReader struct {
+	r       io.Reader
+	read    int64
+	maxSize int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.maxSize {
+		return n, requestBodyTooLargeError{maxSize: l.maxSize}
+	}
+	return n, err
+}
+
+// requestBody returns the body of the given request, limited to the maximum
+// request body size defined in the configuration.
+func (s *TranscodingService) requestBody(r *http.Request) io.Reader {
+	maxSize := s.config.MaxRequestBodySize
+	if maxSize <= 0 {
+		maxSize = defaultMaxRequestBodySize
+	}
+	return &limitedReader{r: io.LimitReader(r.Body, maxSize+1), maxSize: maxSize}
+}
+
+// decodeJSON decodes a single JSON object from the given body into v,
+// rejecting unknown fields. The returned error describes what's wrong with
+// the payload and is suitable for returning to the client.
+func decodeJSON(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		if decoder.More() {
+			return errors.New("malformed JSON: request body must contain a single JSON object")
+		}
+		return nil
+	}
+	switch e := err.(type) {
+	case requestBodyTooLargeError:
+		return e
+	case *json.SyntaxError:
+		return fmt.Errorf("malformed JSON at position %d: %s", e.Offset, e.Error())
+	case *json.UnmarshalTypeError:
+		if e.Field != "" {
+			return fmt.Errorf("invalid value for field %q: expected %s, got JSON %s", e.Field, e.Type, e.Value)
+		}
+		return fmt.Errorf("invalid value at position %d: expected %s, got JSON %s", e.Offset, e.Type, e.Value)
+	}
+	switch {
+	case err == io.EOF:
+		return errEmptyRequestBody
+	case err == io.ErrUnexpectedEOF:
+		return errors.New("malformed JSON: unexpected end of request body")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s in request body", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
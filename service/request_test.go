@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type payload struct {
+		Source  string `json:"source"`
+		Outputs []struct {
+			Preset string `json:"preset"`
+		} `json:"outputs"`
+	}
+	var tests = []struct {
+		givenTestCase string
+		givenBody     string
+		givenMaxSize  int64
+
+		wantErr string
+	}{
+		{
+			"valid payload",
+			`{"source":"http://some.source/video.mp4","outputs":[{"preset":"mp4_1080p"}]}`,
+			0,
+			"",
+		},
+		{
+			"unknown field",
+			`{"source":"http://some.source/video.mp4","destination":"s3://some-bucket"}`,
+			0,
+			`unknown field "destination" in request body`,
+		},
+		{
+			"unknown nested field",
+			`{"source":"http://some.source/video.mp4","outputs":[{"preset":"mp4_1080p","bitrate":1000}]}`,
+			0,
+			`unknown field "bitrate" in request body`,
+		},
+		{
+			"syntactically broken JSON",
+			`{"source":"http://some.source/video.mp4",}`,
+			0,
+			"malformed JSON at position 42: invalid character '}' looking for beginning of object key string",
+		},
+		{
+			"truncated JSON",
+			`{"source":"http://some.source/video.mp4"`,
+			0,
+			"malformed JSON: unexpected end of request body",
+		},
+		{
+			"wrong type",
+			`{"source":42}`,
+			0,
+			`invalid value for field "source": expected string, got JSON number`,
+		},
+		{
+			"multiple objects",
+			`{"source":"http://some.source/video.mp4"}{"source":"http://some.source/video.mp4"}`,
+			0,
+			"malformed JSON: request body must contain a single JSON object",
+		},
+		{
+			"empty body",
+			"",
+			0,
+			"missing request body",
+		},
+		{
+			"body within the limit",
+			`{"source":"video.mp4"}`,
+			22,
+			"",
+		},
+		{
+			"oversized body",
+			`{"source":"http://some.source/video.mp4"}`,
+			16,
+			"request body exceeds the maximum size of 16 bytes",
+		},
+	}
+	for _, test := range tests {
+		svc := TranscodingService{config: &config.Config{MaxRequestBodySize: test.givenMaxSize}}
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(test.givenBody))
+		var p payload
+		err := decodeJSON(svc.requestBody(r), &p)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.givenTestCase, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: unexpected <nil> error", test.givenTestCase)
+			continue
+		}
+		if err.Error() != test.wantErr {
+			t.Errorf("%s: wrong error message\nwant %q\ngot  %q", test.givenTestCase, test.wantErr, err.Error())
+		}
+	}
+}
+
+func TestOversizedRequestBody(t *testing.T) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{
+		MaxRequestBodySize: 64,
+		Server:             &server.Config{},
+	}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = dbtest.NewFakeRepository(false)
+	srvr.Register(service)
+	body := `{"source":"http://another.non.existent/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`
+	r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("wrong status code. Want %d. Got %d", http.StatusBadRequest, w.Code)
+	}
+	var got map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"error": "request body exceeds the maximum size of 64 bytes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong body returned\nwant %#v\ngot  %#v", want, got)
+	}
+}
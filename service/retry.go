@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+// retryFailedJob submits jobs that opted in for retries to the provider again
+// when they fail, up to their maximum number of retries, returning the status
+// of the new attempt. The provider job of each failed attempt is recorded in
+// the job. Jobs that timed out and failures the provider classifies as
+// caused by the job itself aren't retried. Retries are triggered by the
+// requests that retrieve the status of the job, and are submitted within the
+// concurrency limit of the provider. Failures to submit the job again,
+// including the provider being at capacity, are logged, and the status of the
+// failed attempt is returned instead, so the job is retried when its status
+// is retrieved again.
+//
+// Each failed attempt is retried once, by the request that claims it. The
+// other requests get false along with the status of the failed attempt,
+// which they must not store.
+func (s *TranscodingService) retryFailedJob(ctx context.Context, job *db.Job, status *provider.JobStatus, p provider.TranscodingProvider) (*provider.JobStatus, bool) {
+	if status.Status != provider.StatusFailed || job.TimedOut || uint(len(job.FailedAttempts)) >= job.MaxRetries {
+		return status, true
+	}
+	if classifier, ok := p.(provider.FailureClassifier); ok && !classifier.TransientFailure(status) {
+		return status, true
+	}
+	if !s.claimJobTransition(job, "retry") {
+		status.StatusMessage = "job failed in the provider and is being retried"
+		return status, false
+	}
+	logger := s.logger.WithField("jobId", job.ID)
+	newStatus, release, err := s.transcode(ctx, job, p, job.ProviderName)
+	defer release()
+	if err != nil {
+		logger.WithError(err).Error("failed to retry failed job")
+		s.releaseJobTransition(job, "retry")
+		return status, true
+	}
+	job.FailedAttempts = append(job.FailedAttempts, job.ProviderJobID)
+	job.ProviderJobID = newStatus.ProviderJobID
+	keepJobSpec(job, newStatus)
+	job.Status = string(newStatus.Status)
+	err = s.db.UpdateJob(job)
+	if err != nil {
+		logger.WithError(err).Error("failed to store the retry of job")
+	}
+	newStatus.ProviderName = job.ProviderName
+	newStatus.Test = job.Test
+	newStatus.StatusMessage = fmt.Sprintf("job failed in the provider and was retried (attempt %d of %d)", len(job.FailedAttempts)+1, job.MaxRetries+1)
+	return newStatus, true
+}
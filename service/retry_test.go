@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobRetry(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenJob      db.Job
+
+		wantStatus         string
+		wantStatusMessage  string
+		wantProviderJobID  string
+		wantFailedAttempts []string
+		wantSubmissions    int
+	}{
+		{
+			"transient failure retried and succeeding",
+			db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed", MaxRetries: 2},
+			"finished",
+			"job failed in the provider and was retried (attempt 2 of 3)",
+			"provider-preset-job-123",
+			[]string{"provider-job-crashed"},
+			1,
+		},
+		{
+			"transient failure after previous retries",
+			db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed", MaxRetries: 2, FailedAttempts: []string{"provider-job-1"}},
+			"finished",
+			"job failed in the provider and was retried (attempt 3 of 3)",
+			"provider-preset-job-123",
+			[]string{"provider-job-1", "provider-job-crashed"},
+			1,
+		},
+		{
+			"retries exhausted",
+			db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed", MaxRetries: 2, FailedAttempts: []string{"provider-job-1", "provider-job-2"}},
+			"failed",
+			"",
+			"provider-job-crashed",
+			[]string{"provider-job-1", "provider-job-2"},
+			0,
+		},
+		{
+			"non-transient failure",
+			db.Job{ID: "job-bad-source", ProviderName: "fake", ProviderJobID: "provider-job-bad-source", MaxRetries: 2},
+			"failed",
+			"",
+			"provider-job-bad-source",
+			nil,
+			0,
+		},
+		{
+			"job without retries",
+			db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed"},
+			"failed",
+			"",
+			"provider-job-crashed",
+			nil,
+			0,
+		},
+	}
+	defer func() { fprovider.jobs = nil }()
+	for _, test := range tests {
+		fprovider.jobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		job := test.givenJob
+		fakeDBObj.CreateJob(&job)
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("GET", "/jobs/"+job.ID, nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusOK, w.Code)
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["status"] != test.wantStatus {
+			t.Errorf("%s: wrong status. Want %q. Got %q", test.givenTestCase, test.wantStatus, got["status"])
+		}
+		if message, _ := got["statusMessage"].(string); message != test.wantStatusMessage {
+			t.Errorf("%s: wrong status message. Want %q. Got %q", test.givenTestCase, test.wantStatusMessage, message)
+		}
+		if len(fprovider.jobs) != test.wantSubmissions {
+			t.Errorf("%s: wrong number of submissions to the provider. Want %d. Got %d", test.givenTestCase, test.wantSubmissions, len(fprovider.jobs))
+		}
+		dbJob, err := fakeDBObj.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dbJob.ProviderJobID != test.wantProviderJobID {
+			t.Errorf("%s: wrong provider job id in the database. Want %q. Got %q", test.givenTestCase, test.wantProviderJobID, dbJob.ProviderJobID)
+		}
+		if !reflect.DeepEqual(dbJob.FailedAttempts, test.wantFailedAttempts) {
+			t.Errorf("%s: wrong failed attempts in the database. Want %#v. Got %#v", test.givenTestCase, test.wantFailedAttempts, dbJob.FailedAttempts)
+		}
+		if dbJob.Status != test.wantStatus {
+			t.Errorf("%s: wrong status in the database. Want %q. Got %q", test.givenTestCase, test.wantStatus, dbJob.Status)
+		}
+	}
+}
+
+func TestJobRetryProviderAtCapacity(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	job := db.Job{ID: "job-crashed", ProviderName: "fake", ProviderJobID: "provider-job-crashed", MaxRetries: 2}
+	fakeDBObj.CreateJob(&job)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", Status: "started"})
+	service, err := NewTranscodingService(&config.Config{
+		Server:                   &server.Config{},
+		ProviderConcurrencyLimit: 1,
+	}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	r, _ := http.NewRequest("GET", "/jobs/"+job.ID, nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	var got map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["status"] != "failed" {
+		t.Errorf("wrong status. Want %q. Got %q", "failed", got["status"])
+	}
+	if len(fprovider.jobs) != 0 {
+		t.Errorf("unexpected retry sent to the provider at capacity: %#v", fprovider.jobs)
+	}
+	dbJob, err := fakeDBObj.GetJob(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dbJob.ProviderJobID != "provider-job-crashed" || len(dbJob.FailedAttempts) != 0 {
+		t.Errorf("unexpected retry stored in the database: %#v", dbJob)
+	}
+}
+
+func TestJobRetryRedis(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	repo := newRedisRepository(t)
+	outputs := []db.TranscodeOutput{
+		{
+			FileName: "video_720p.mp4",
+			Preset: db.PresetMap{
+				Name:            "mp4_720p",
+				ProviderMapping: map[string]string{"fake": "preset-720p"},
+				OutputOpts:      db.OutputOptions{Extension: "mp4"},
+			},
+		},
+	}
+	err := repo.CreateJob(&db.Job{
+		ID:              "job-crashed",
+		ProviderName:    "fake",
+		ProviderJobID:   "provider-job-crashed",
+		SourceMedia:     "http://some.nice/video.mov",
+		MaxRetries:      1,
+		Outputs:         outputs,
+		ProviderOptions: map[string]interface{}{"priority": float64(10)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+	srvr.Register(service)
+	r, _ := http.NewRequest("GET", "/jobs/job-crashed", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	if len(fprovider.jobs) != 1 {
+		t.Fatalf("wrong number of submissions to the provider. Want 1. Got %d", len(fprovider.jobs))
+	}
+	retried := fprovider.jobs[0]
+	if !reflect.DeepEqual(retried.Outputs, outputs) {
+		t.Errorf("wrong outputs retried.\nWant %#v\nGot  %#v", outputs, retried.Outputs)
+	}
+	if retried.SourceMedia != "http://some.nice/video.mov" || retried.ProviderOptions["priority"] != float64(10) {
+		t.Errorf("settings of the job not kept in the retry: %#v", retried)
+	}
+	dbJob, err := repo.GetJob("job-crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dbJob.FailedAttempts, []string{"provider-job-crashed"}) {
+		t.Errorf("wrong failed attempts in the database. Got %#v", dbJob.FailedAttempts)
+	}
+}
+
+func TestJobRetryConcurrentReads(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	repo := newRedisRepository(t)
+	err := repo.CreateJob(&db.Job{
+		ID:            "job-crashed",
+		ProviderName:  "fake",
+		ProviderJobID: "provider-job-crashed",
+		SourceMedia:   "http://some.nice/video.mov",
+		MaxRetries:    2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+
+	// both requests load the job before either of them retries it.
+	var jobs []*db.Job
+	for i := 0; i < 2; i++ {
+		job, err := repo.GetJob("job-crashed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+	prov, err := service.providerFor(jobs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, job := range jobs {
+		status, err := service.jobStatus(context.Background(), job, prov)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, status.StatusMessage)
+	}
+	if len(fprovider.jobs) != 1 {
+		t.Errorf("wrong number of submissions to the provider. Want 1. Got %d", len(fprovider.jobs))
+	}
+	expectedMessages := []string{
+		"job failed in the provider and was retried (attempt 2 of 3)",
+		"job failed in the provider and is being retried",
+	}
+	if !reflect.DeepEqual(messages, expectedMessages) {
+		t.Errorf("wrong status messages\nwant %#v\ngot  %#v", expectedMessages, messages)
+	}
+	dbJob, err := repo.GetJob("job-crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dbJob.ProviderJobID != "provider-preset-job-123" {
+		t.Errorf("wrong provider job id in the database. Want %q. Got %q", "provider-preset-job-123", dbJob.ProviderJobID)
+	}
+	if !reflect.DeepEqual(dbJob.FailedAttempts, []string{"provider-job-crashed"}) {
+		t.Errorf("wrong failed attempts in the database. Got %#v", dbJob.FailedAttempts)
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+var errRequestTimeout = errors.New("request timed out")
+
+// untimedRoutes lists the routes that are never timed out, as their
+// responses stream for as long as clients stay connected.
+var untimedRoutes = map[string]bool{
+	"GET /jobs/:jobId/events": true,
+}
+
+// routeTimeout returns the timeout of the given route, falling back to the
+// timeout of all requests. 0 means no timeout.
+func (s *TranscodingService) routeTimeout(method, path string) time.Duration {
+	route := method + " " + path
+	if untimedRoutes[route] {
+		return 0
+	}
+	if timeout, ok := s.config.RouteTimeouts[route]; ok {
+		return timeout
+	}
+	return time.Duration(s.config.RequestTimeout) * time.Second
+}
+
+// withJSONTimeouts wraps the given JSON endpoints with the timeouts of their
+// routes.
+func (s *TranscodingService) withJSONTimeouts(endpoints map[string]map[string]server.JSONEndpoint) map[string]map[string]server.JSONEndpoint {
+	for path, methods := range endpoints {
+		for method, endpoint := range methods {
+			if timeout := s.routeTimeout(method, path); timeout > 0 {
+				methods[method] = timeoutJSONEndpoint(timeout, endpoint)
+			}
+		}
+	}
+	return endpoints
+}
+
+// withTimeouts wraps the given handlers with the timeouts of their routes.
+func (s *TranscodingService) withTimeouts(endpoints map[string]map[string]http.HandlerFunc) map[string]map[string]http.HandlerFunc {
+	for path, methods := range endpoints {
+		for method, handler := range methods {
+			if timeout := s.routeTimeout(method, path); timeout > 0 {
+				methods[method] = s.timeoutHandler(timeout, handler)
+			}
+		}
+	}
+	return endpoints
+}
+
+// timeoutJSONEndpoint cancels the context of the requests to the given
+// endpoint once the timeout expires, replying with 504 (Gateway Timeout)
+// without waiting for the endpoint to return.
+func timeoutJSONEndpoint(timeout time.Duration, endpoint server.JSONEndpoint) server.JSONEndpoint {
+	return func(r *http.Request) (int, interface{}, error) {
+		var status int
+		var result interface{}
+		var err error
+		ok := runWithTimeout(r, timeout, func(r *http.Request) {
+			status, result, err = endpoint(r)
+		})
+		if !ok {
+			return swagger.NewErrorResponse(errRequestTimeout).WithStatus(http.StatusGatewayTimeout).Result()
+		}
+		return status, result, err
+	}
+}
+
+// timeoutHandler is like timeoutJSONEndpoint, for handlers writing their own
+// responses. The response is buffered, and discarded when the timeout
+// expires.
+func (s *TranscodingService) timeoutHandler(timeout time.Duration, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tw := timeoutWriter{header: make(http.Header)}
+		ok := runWithTimeout(r, timeout, func(r *http.Request) {
+			h(&tw, r)
+		})
+		if !ok {
+			tw.discard()
+			s.writeJSONResponse(w, r, swagger.NewErrorResponse(errRequestTimeout).WithStatus(http.StatusGatewayTimeout))
+			return
+		}
+		for key, values := range tw.header {
+			w.Header()[key] = values
+		}
+		if tw.status == 0 {
+			tw.status = http.StatusOK
+		}
+		w.WriteHeader(tw.status)
+		w.Write(tw.body.Bytes())
+	}
+}
+
+// runWithTimeout calls f with a copy of the request whose context is
+// canceled once the timeout expires, returning false when it expires before
+// f returns. Panics in f are propagated to the caller, so they're handled
+// like panics in any other handler.
+func runWithTimeout(r *http.Request, timeout time.Duration, f func(*http.Request)) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	done := make(chan struct{})
+	panics := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panics <- p
+				return
+			}
+			close(done)
+		}()
+		f(r.WithContext(ctx))
+	}()
+	select {
+	case <-done:
+		return true
+	case p := <-panics:
+		panic(p)
+	case <-ctx.Done():
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return false
+	}
+	// the client went away before the timeout, so the handler is left to
+	// deal with the canceled request
+	select {
+	case <-done:
+		return true
+	case p := <-panics:
+		panic(p)
+	}
+}
+
+// timeoutWriter buffers the response of handlers with a timeout. Writes
+// after the timeout expires are discarded.
+type timeoutWriter struct {
+	mu        sync.Mutex
+	header    http.Header
+	body      bytes.Buffer
+	status    int
+	discarded bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discarded || w.status != 0 {
+		return
+	}
+	w.status = status
+}
+
+func (w *timeoutWriter) discard() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.discarded = true
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRouteTimeout(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenMethod   string
+		givenPath     string
+
+		wantTimeout time.Duration
+	}{
+		{"route with timeout", "GET", "/jobs/:jobId", 2 * time.Second},
+		{"route with the default timeout", "POST", "/jobs", 10 * time.Second},
+		{"method without timeout", "DELETE", "/presetmaps/:name", 10 * time.Second},
+		{"job events", "GET", "/jobs/:jobId/events", 0},
+	}
+	service := TranscodingService{config: &config.Config{
+		RequestTimeout: 10,
+		RouteTimeouts: config.RouteTimeouts{
+			"GET /jobs/:jobId":        2 * time.Second,
+			"GET /jobs/:jobId/events": time.Second,
+		},
+	}}
+	for _, test := range tests {
+		timeout := service.routeTimeout(test.givenMethod, test.givenPath)
+		if timeout != test.wantTimeout {
+			t.Errorf("%s: wrong timeout. Want %s. Got %s", test.givenTestCase, test.wantTimeout, timeout)
+		}
+	}
+}
+
+func TestSlowRequestTimesOut(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenRoute    string
+		givenURI      string
+	}{
+		{"job status", "GET /jobs/:jobId", "/jobs/job-slow"},
+		{"job eta", "GET /jobs/:jobId/eta", "/jobs/job-slow/eta"},
+	}
+	defer func() { fprovider.interruptedChecks = nil }()
+	for _, test := range tests {
+		fprovider.interruptedChecks = make(chan error, 1)
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreateJob(&db.Job{ID: "job-slow", ProviderName: "fake", ProviderJobID: "provider-job-slow"})
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{
+			Server:        &server.Config{},
+			RouteTimeouts: config.RouteTimeouts{test.givenRoute: 20 * time.Millisecond},
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("GET", test.givenURI, nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusGatewayTimeout, w.Code)
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if got["error"] != "request timed out" {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, "request timed out", got["error"])
+		}
+		select {
+		case err := <-fprovider.interruptedChecks:
+			if err == nil {
+				t.Errorf("%s: unexpected <nil> error in the context of the provider call", test.givenTestCase)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: the provider call wasn't canceled", test.givenTestCase)
+		}
+	}
+}
+
+func TestFastRequestWithTimeout(t *testing.T) {
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, RequestTimeout: 5}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	r, _ := http.NewRequest("GET", "/jobs/job-123", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("missing ETag header in the response")
+	}
+	var got map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["providerJobId"] != "provider-job-123" {
+		t.Errorf("wrong provider job id. Want %q. Got %v", "provider-job-123", got["providerJobId"])
+	}
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"fmt"
 	"net/http"
+	"net/smtp"
+	"time"
 
 	"github.com/NYTimes/gizmo/server"
 	"github.com/NYTimes/gziphandler"
@@ -21,6 +23,23 @@ type TranscodingService struct {
 	config *config.Config
 	db     db.Repository
 	logger *logrus.Logger
+
+	// bounds the number of concurrent job event subscriptions
+	eventSubscriptions chan struct{}
+	eventsPollInterval time.Duration
+
+	// returns the current time, used for enforcing job timeouts. Defaults
+	// to time.Now
+	clock func() time.Time
+
+	// limits the rate of job creation per client
+	jobsLimiter *rateLimiter
+
+	// limits the number of concurrent job submissions to each provider
+	providersLimiter *concurrencyLimiter
+
+	// sends emails, used for job notifications. Defaults to smtp.SendMail
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
 }
 
 // NewTranscodingService will instantiate a JSONService
@@ -30,7 +49,15 @@ func NewTranscodingService(cfg *config.Config, logger *logrus.Logger) (*Transcod
 	if err != nil {
 		return nil, fmt.Errorf("Error initializing Redis client: %s", err)
 	}
-	return &TranscodingService{config: cfg, db: dbRepo, logger: logger}, nil
+	return &TranscodingService{
+		config:             cfg,
+		db:                 dbRepo,
+		logger:             logger,
+		eventSubscriptions: make(chan struct{}, cfg.EventsMaxSubscribers),
+		eventsPollInterval: time.Duration(cfg.EventsPollInterval) * time.Second,
+		jobsLimiter:        newRateLimiter(cfg.JobRateLimit, cfg.JobRateLimitOverrides),
+		providersLimiter:   newConcurrencyLimiter(cfg.ProviderConcurrencyMultiplier, cfg.ProviderCapacityRefreshInterval, cfg.ProviderConcurrencyLimit),
+	}, nil
 }
 
 // Prefix returns the string prefix used for all endpoints within
@@ -39,16 +66,55 @@ func (s *TranscodingService) Prefix() string {
 	return ""
 }
 
+// compressibleContentTypes lists the content types of the responses
+// compressed by the gzip middleware. Other responses, like the ones already
+// compressed, are sent as they are.
+var compressibleContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"text/html",
+	"text/plain",
+}
+
 // Middleware provides an http.Handler hook wrapped around all requests.
 // In this implementation, we're using a GzipHandler middleware to
-// compress our responses.
+// compress our responses and recovering from panics in handlers.
 func (s *TranscodingService) Middleware(h http.Handler) http.Handler {
 	logMiddleware := ctxlogger.ContextLogger(s.logger)
-	h = logMiddleware(h)
+	h = logMiddleware(s.recoverPanics(s.routeStaticEndpoints(h)))
 	if s.config.Server.HTTPAccessLog == nil {
 		h = handlers.LoggingHandler(s.logger.Writer(), h)
 	}
-	return gziphandler.GzipHandler(server.CORSHandler(h, ""))
+	// the options are always valid, as the minimum size can't be negative.
+	gzipHandler, _ := gziphandler.GzipHandlerWithOpts(
+		gziphandler.MinSize(int(s.config.GzipMinSize)),
+		gziphandler.ContentTypes(compressibleContentTypes),
+	)
+	return gzipHandler(server.CORSHandler(h, ""))
+}
+
+// staticEndpoints lists the endpoints whose routes the router doesn't allow
+// along with the other routes, as it doesn't take static segments where
+// other routes have parameters. They're routed by Middleware instead.
+func (s *TranscodingService) staticEndpoints() map[string]map[string]http.HandlerFunc {
+	return s.withTimeouts(map[string]map[string]http.HandlerFunc{
+		previewPath: {
+			"POST": s.rateLimited(s.jobsLimiter, s.previewTranscodeJobHandler),
+		},
+	})
+}
+
+// routeStaticEndpoints serves the requests to staticEndpoints, passing the
+// other requests to the given handler.
+func (s *TranscodingService) routeStaticEndpoints(h http.Handler) http.Handler {
+	endpoints := s.staticEndpoints()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler, ok := endpoints[r.URL.Path][r.Method]; ok {
+			handler(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
 
 // JSONMiddleware provides a JSONEndpoint hook wrapped around all requests.
@@ -64,19 +130,23 @@ func (s *TranscodingService) JSONMiddleware(j server.JSONEndpoint) server.JSONEn
 
 // JSONEndpoints is a listing of all endpoints available in the JSONService.
 func (s *TranscodingService) JSONEndpoints() map[string]map[string]server.JSONEndpoint {
-	return map[string]map[string]server.JSONEndpoint{
-		"/jobs": {
-			"POST": swagger.HandlerToJSONEndpoint(s.newTranscodeJob),
-		},
-		"/jobs/:jobId": {
-			"GET": swagger.HandlerToJSONEndpoint(s.getTranscodeJob),
-		},
+	return s.withJSONTimeouts(map[string]map[string]server.JSONEndpoint{
+		// POST /jobs/preview, see previewTranscodeJob
 		"/jobs/:jobId/cancel": {
 			"POST": swagger.HandlerToJSONEndpoint(s.cancelTranscodeJob),
 		},
+		"/jobs/:jobId/cost": {
+			"GET": swagger.HandlerToJSONEndpoint(s.getJobCost),
+		},
+		"/jobs/:jobId/eta": {
+			"GET": swagger.HandlerToJSONEndpoint(s.getJobETA),
+		},
 		"/presets": {
 			"POST": swagger.HandlerToJSONEndpoint(s.newPreset),
 		},
+		"/presets/validate": {
+			"POST": swagger.HandlerToJSONEndpoint(s.validatePreset),
+		},
 		"/presets/:name": {
 			"DELETE": swagger.HandlerToJSONEndpoint(s.deletePreset),
 		},
@@ -95,14 +165,50 @@ func (s *TranscodingService) JSONEndpoints() map[string]map[string]server.JSONEn
 		"/providers/:name": {
 			"GET": swagger.HandlerToJSONEndpoint(s.getProvider),
 		},
-	}
+		"/providers/:name/presets": {
+			"GET": swagger.HandlerToJSONEndpoint(s.listProviderPresets),
+		},
+		"/stats": {
+			"GET": swagger.HandlerToJSONEndpoint(s.getJobStats),
+		},
+		"/admin/reconcile": {
+			"POST": swagger.HandlerToJSONEndpoint(s.adminOnly(s.reconcileJobs)),
+		},
+		"/admin/jobs": {
+			"DELETE": swagger.HandlerToJSONEndpoint(s.adminOnly(s.purgeJobs)),
+		},
+		"/version": {
+			"GET": swagger.HandlerToJSONEndpoint(s.getVersion),
+		},
+	})
 }
 
 // Endpoints is a list of all non-json endpoints.
 func (s *TranscodingService) Endpoints() map[string]map[string]http.HandlerFunc {
-	return map[string]map[string]http.HandlerFunc{
+	return s.withTimeouts(map[string]map[string]http.HandlerFunc{
 		"/swagger.json": {
 			"GET": s.swaggerManifest,
 		},
-	}
+		"/openapi.json": {
+			"GET": s.swaggerManifest,
+		},
+		"/jobs": {
+			"POST": s.rateLimited(s.jobsLimiter, s.newTranscodeJobHandler),
+		},
+		"/jobs/:jobId": {
+			"GET": s.getTranscodeJobHandler,
+		},
+		"/jobs/:jobId/events": {
+			"GET": s.jobEvents,
+		},
+		"/jobs/:jobId/logs": {
+			"GET": s.jobLogs,
+		},
+		"/jobs/:jobId/spec": {
+			"GET": s.jobSpec,
+		},
+		"/jobs/:jobId/resubmit": {
+			"POST": s.rateLimited(s.jobsLimiter, s.resubmitTranscodeJobHandler),
+		},
+	})
 }
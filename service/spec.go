@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/NYTimes/gizmo/web"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// Spec of the job in the format used by the provider (e.g. XML for Elemental
+// Conductor), with credentials redacted.
+//
+// swagger:response jobSpec
+type jobSpecResponse struct {
+	// in: body
+	Payload string
+}
+
+// swagger:route GET /jobs/{jobId}/spec jobs getJobSpec
+//
+// Returns the spec of a job as submitted to the provider, for inspection.
+// Credentials are redacted from the spec.
+//
+//     Produces:
+//     - application/xml
+//
+//     Responses:
+//       200: jobSpec
+//       404: jobNotFound
+//       500: genericError
+//       501: genericError
+func (s *TranscodingService) jobSpec(w http.ResponseWriter, r *http.Request) {
+	var params getTranscodeJobSpecInput
+	params.loadParams(web.Vars(r))
+	job, err := s.db.GetJob(params.JobID)
+	if err != nil {
+		if err == db.ErrJobNotFound {
+			s.writeJSONResponse(w, r, newJobNotFoundResponse(err))
+			return
+		}
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(err))
+		return
+	}
+	prov, err := s.providerFor(job)
+	if err != nil {
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(err))
+		return
+	}
+	if !prov.Capabilities().JobSpecs {
+		s.writeJSONResponse(w, r, swagger.NewErrorResponse(provider.ErrNotImplemented).WithStatus(http.StatusNotImplemented))
+		return
+	}
+	if job.ProviderSpec == "" {
+		// held jobs and jobs submitted before specs were kept
+		s.writeJSONResponse(w, r, newJobNotFoundResponse(fmt.Errorf("spec of job %q not recorded", job.ID)))
+		return
+	}
+	w.Header().Set("Content-Type", job.ProviderSpecContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(job.ProviderSpec))
+}
+
+// keepJobSpec records in the given job the spec reported by the provider
+// when submitting it, served by jobSpec.
+func keepJobSpec(job *db.Job, status *provider.JobStatus) {
+	if status.Spec == nil {
+		return
+	}
+	job.ProviderSpec = string(status.Spec.Data)
+	job.ProviderSpecContentType = status.Spec.ContentType
+}
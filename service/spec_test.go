@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobSpec(t *testing.T) {
+	tests := []struct {
+		givenTestCase   string
+		givenJobID      string
+		givenNoJobSpecs bool
+
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			"job with spec",
+			"job-123",
+			false,
+			http.StatusOK,
+			"application/xml",
+			"<job><input><file_input><uri>http://some.source/video.mov</uri></file_input></input></job>",
+		},
+		{
+			"job without spec",
+			"job-held",
+			false,
+			http.StatusNotFound,
+			"application/json; charset=UTF-8",
+			`{"error":"spec of job \"job-held\" not recorded"}`,
+		},
+		{
+			"provider without job specs",
+			"job-123",
+			true,
+			http.StatusNotImplemented,
+			"application/json; charset=UTF-8",
+			`{"error":"operation not supported by the provider"}`,
+		},
+		{
+			"job not found",
+			"job-404",
+			false,
+			http.StatusNotFound,
+			"application/json; charset=UTF-8",
+			`{"error":"job not found"}`,
+		},
+	}
+	defer func() { fprovider.noJobSpecs = false }()
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{
+		ID:                      "job-123",
+		ProviderName:            "fake",
+		ProviderJobID:           "provider-job-123",
+		SourceMedia:             "http://some.source/video.mov",
+		ProviderSpec:            "<job><input><file_input><uri>http://some.source/video.mov</uri></file_input></input></job>",
+		ProviderSpecContentType: "application/xml",
+	})
+	fakeDBObj.CreateJob(&db.Job{ID: "job-held", ProviderName: "fake", DependsOn: "job-123", Status: "queued"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	for _, test := range tests {
+		fprovider.noJobSpecs = test.givenNoJobSpecs
+		r, _ := http.NewRequest("GET", "/jobs/"+test.givenJobID+"/spec", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.wantContentType {
+			t.Errorf("%s: wrong content type. Want %q. Got %q", test.givenTestCase, test.wantContentType, ct)
+		}
+		if body := w.Body.String(); body != test.wantBody && body != test.wantBody+"\n" {
+			t.Errorf("%s: wrong body\nwant %q\ngot  %q", test.givenTestCase, test.wantBody, body)
+		}
+	}
+}
+
+func TestJobSpecRecordedOnSubmission(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	repo := newRedisRepository(t)
+	err := repo.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+	srvr.Register(service)
+	body := `{"source":"http://some.source/video.mov","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`
+	r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong status code creating the job. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var created map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ = http.NewRequest("GET", "/jobs/"+created["jobId"].(string)+"/spec", nil)
+	w = httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong status code retrieving the spec. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Errorf("wrong content type. Want %q. Got %q", "application/xml", ct)
+	}
+	wantSpec := "<job><input><file_input><uri>http://some.source/video.mov</uri></file_input></input></job>"
+	if spec := w.Body.String(); spec != wantSpec {
+		t.Errorf("wrong spec\nwant %q\ngot  %q", wantSpec, spec)
+	}
+}
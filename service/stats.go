@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/provider"
+	"github.com/NYTimes/video-transcoding-api/swagger"
+)
+
+// defaultStatsWindow is the time window of the statistics when the request
+// doesn't specify the start of the window.
+const defaultStatsWindow = 24 * time.Hour
+
+// JobStats summarizes the jobs created in a time window.
+//
+// swagger:model
+type JobStats struct {
+	// start of the time window
+	Since time.Time `json:"since"`
+
+	// number of jobs created in the time window
+	Total int `json:"total"`
+
+	// number of jobs by their last known status
+	ByStatus map[string]int `json:"byStatus"`
+
+	// number of jobs by provider
+	ByProvider map[string]int `json:"byProvider"`
+
+	// number of test jobs, also counted in the other totals
+	TestJobs int `json:"testJobs"`
+
+	// average time, in seconds, providers took for processing the
+	// finished jobs
+	AverageProcessingTime float64 `json:"averageProcessingTime"`
+}
+
+// response for the getJobStats operation.
+//
+// swagger:response jobStats
+type jobStatsResponse struct {
+	// in: body
+	Payload *JobStats
+
+	baseResponse
+}
+
+// swagger:parameters getJobStats
+type getJobStatsInput struct {
+	// start of the time window, in RFC 3339 format. Defaults to 24 hours
+	// ago
+	//
+	// in: query
+	Since string `json:"since"`
+}
+
+func (p *getJobStatsInput) loadParams(values url.Values) {
+	p.Since = values.Get("since")
+}
+
+// swagger:route GET /stats jobs getJobStats
+//
+// Counts the jobs created since the given time by status and by provider,
+// along with the average processing time of the finished jobs.
+//
+//     Responses:
+//       200: jobStats
+//       400: genericError
+//       500: genericError
+func (s *TranscodingService) getJobStats(r *http.Request) swagger.GizmoJSONResponse {
+	var params getJobStatsInput
+	params.loadParams(r.URL.Query())
+	since := s.now().Add(-defaultStatsWindow)
+	if params.Since != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, params.Since)
+		if err != nil {
+			return swagger.NewErrorResponse(fmt.Errorf("invalid since %q, must be in RFC 3339 format", params.Since)).WithStatus(http.StatusBadRequest)
+		}
+	}
+	counts, err := s.db.CountJobs(since)
+	if err != nil {
+		return swagger.NewErrorResponse(fmt.Errorf("error counting jobs: %s", err))
+	}
+	stats := JobStats{
+		Since:      since.UTC(),
+		ByStatus:   make(map[string]int),
+		ByProvider: make(map[string]int),
+		TestJobs:   counts.TestJobs,
+	}
+	for providerName, byStatus := range counts.ByProvider {
+		for status, count := range byStatus {
+			if status == "" {
+				status = string(provider.StatusUnknown)
+			}
+			stats.Total += count
+			stats.ByStatus[status] += count
+			stats.ByProvider[providerName] += count
+		}
+	}
+	if counts.Processed > 0 {
+		stats.AverageProcessingTime = float64(counts.ProcessingTime) / float64(counts.Processed)
+	}
+	return &jobStatsResponse{
+		baseResponse: baseResponse{payload: &stats, status: http.StatusOK},
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobStats(t *testing.T) {
+	now := time.Date(2016, 3, 10, 10, 0, 0, 0, time.UTC)
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	jobs := []db.Job{
+		{ID: "job-old", ProviderName: "fake", Status: "finished", ProcessingTime: 500, CreationTime: now.Add(-48 * time.Hour)},
+		{ID: "job-1", ProviderName: "fake", Status: "finished", ProcessingTime: 60, CreationTime: now.Add(-3 * time.Hour)},
+		{ID: "job-2", ProviderName: "fake", Status: "finished", ProcessingTime: 120, CreationTime: now.Add(-2 * time.Hour)},
+		{ID: "job-3", ProviderName: "zencoder", Status: "failed", CreationTime: now.Add(-2 * time.Hour)},
+		{ID: "job-4", ProviderName: "zencoder", Status: "started", CreationTime: now.Add(-time.Hour), Test: true},
+		{ID: "job-5", ProviderName: "fake", CreationTime: now.Add(-time.Minute)},
+	}
+	for i := range jobs {
+		fakeDBObj.CreateJob(&jobs[i])
+	}
+	tests := []struct {
+		givenTestCase string
+		givenQuery    string
+
+		wantCode  int
+		wantStats JobStats
+	}{
+		{
+			"default window",
+			"",
+			http.StatusOK,
+			JobStats{
+				Since:                 now.Add(-24 * time.Hour),
+				Total:                 5,
+				ByStatus:              map[string]int{"finished": 2, "failed": 1, "started": 1, "unknown": 1},
+				ByProvider:            map[string]int{"fake": 3, "zencoder": 2},
+				TestJobs:              1,
+				AverageProcessingTime: 90,
+			},
+		},
+		{
+			"custom window",
+			"?since=2016-03-10T08:30:00Z",
+			http.StatusOK,
+			JobStats{
+				Since:      now.Add(-90 * time.Minute),
+				Total:      2,
+				ByStatus:   map[string]int{"started": 1, "unknown": 1},
+				ByProvider: map[string]int{"fake": 1, "zencoder": 1},
+				TestJobs:   1,
+			},
+		},
+		{
+			"window without jobs",
+			"?since=2016-03-11T00:00:00Z",
+			http.StatusOK,
+			JobStats{
+				Since:      now.Add(14 * time.Hour),
+				ByStatus:   map[string]int{},
+				ByProvider: map[string]int{},
+			},
+		},
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	service.clock = func() time.Time { return now }
+	srvr.Register(service)
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/stats"+test.givenQuery, nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+			continue
+		}
+		var stats JobStats
+		err = json.Unmarshal(w.Body.Bytes(), &stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stats, test.wantStats) {
+			t.Errorf("%s: wrong stats\nwant %#v\ngot  %#v", test.givenTestCase, test.wantStats, stats)
+		}
+	}
+}
+
+func TestJobStatsInvalidSince(t *testing.T) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = dbtest.NewFakeRepository(false)
+	srvr.Register(service)
+	r, _ := http.NewRequest("GET", "/stats?since=yesterday", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("wrong response code. Want %d. Got %d", http.StatusBadRequest, w.Code)
+	}
+	var resp map[string]string
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `invalid since "yesterday", must be in RFC 3339 format`; resp["error"] != want {
+		t.Errorf("wrong error message. Want %q. Got %q", want, resp["error"])
+	}
+}
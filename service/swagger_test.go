@@ -1,9 +1,12 @@
 package service
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/NYTimes/gizmo/server"
@@ -26,23 +29,106 @@ func TestSwaggerManifest(t *testing.T) {
 		},
 		logger: logrus.New(),
 	})
-	r, _ := http.NewRequest("GET", "/swagger.json", nil)
-	w := httptest.NewRecorder()
-	srvr.ServeHTTP(w, r)
-	if w.Code != http.StatusOK {
-		t.Errorf("Swagger manifest: wrong status code. Want %d. Got %d", http.StatusOK, w.Code)
+	expectedData, err := ioutil.ReadFile("testdata/swagger.json")
+	if err != nil {
+		t.Fatal(err)
 	}
-	for k, v := range expectedHeaders {
-		got := w.Header().Get(k)
-		if got != v {
-			t.Errorf("Swagger manifest: wrong header value for key=%q. Want %q. Got %q", k, v, got)
+	for _, path := range []string{"/swagger.json", "/openapi.json"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", path, http.StatusOK, w.Code)
+		}
+		for k, v := range expectedHeaders {
+			got := w.Header().Get(k)
+			if got != v {
+				t.Errorf("%s: wrong header value for key=%q. Want %q. Got %q", path, k, v, got)
+			}
+		}
+		if string(expectedData) != w.Body.String() {
+			t.Errorf("%s: wrong body\nWant: %s\nGot:  %s", path, expectedData, w.Body)
 		}
 	}
-	expectedData, err := ioutil.ReadFile("testdata/swagger.json")
+}
+
+// TestSwaggerSpec checks that the spec generated by "make swagger" is a
+// valid Swagger 2.0 document and that it documents all the routes registered
+// by the service.
+func TestSwaggerSpec(t *testing.T) {
+	data, err := ioutil.ReadFile("../swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Swagger string `json:"swagger"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths       map[string]map[string]json.RawMessage `json:"paths"`
+		Definitions map[string]json.RawMessage            `json:"definitions"`
+		Responses   map[string]json.RawMessage            `json:"responses"`
+	}
+	err = json.Unmarshal(data, &spec)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if string(expectedData) != w.Body.String() {
-		t.Errorf("Swagger manifest: wrong body\nWant: %s\nGot:  %s", expectedData, w.Body)
+	if spec.Swagger != "2.0" {
+		t.Errorf("wrong swagger version. Want %q. Got %q", "2.0", spec.Swagger)
+	}
+	if spec.Info.Title == "" || spec.Info.Version == "" {
+		t.Errorf("missing title or version in the info of the spec: %#v", spec.Info)
+	}
+	for path, operations := range spec.Paths {
+		for method, data := range operations {
+			var operation struct {
+				OperationID string                     `json:"operationId"`
+				Responses   map[string]json.RawMessage `json:"responses"`
+			}
+			err = json.Unmarshal(data, &operation)
+			if err != nil {
+				t.Fatalf("%s %s: %s", method, path, err)
+			}
+			if operation.OperationID == "" || len(operation.Responses) == 0 {
+				t.Errorf("%s %s: operations must have an id and at least one response", method, path)
+			}
+		}
+	}
+	refs := regexp.MustCompile(`"\$ref":\s*"#/(definitions|responses)/([^"]+)"`).FindAllStringSubmatch(string(data), -1)
+	for _, ref := range refs {
+		objects := spec.Definitions
+		if ref[1] == "responses" {
+			objects = spec.Responses
+		}
+		if _, ok := objects[ref[2]]; !ok {
+			t.Errorf("unresolved reference to #/%s/%s", ref[1], ref[2])
+		}
+	}
+
+	service := TranscodingService{config: &config.Config{}}
+	routes := make(map[string][]string)
+	for route, methods := range service.JSONEndpoints() {
+		for method := range methods {
+			routes[route] = append(routes[route], method)
+		}
+	}
+	for _, endpoints := range []map[string]map[string]http.HandlerFunc{service.Endpoints(), service.staticEndpoints()} {
+		for route, methods := range endpoints {
+			for method := range methods {
+				routes[route] = append(routes[route], method)
+			}
+		}
+	}
+	delete(routes, "/swagger.json")
+	delete(routes, "/openapi.json")
+	params := regexp.MustCompile(`:(\w+)`)
+	for route, methods := range routes {
+		path := params.ReplaceAllString(route, "{$1}")
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("%s %s is not documented in the swagger spec", method, path)
+			}
+		}
 	}
 }
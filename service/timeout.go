@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/provider"
+)
+
+// checkJobTimeout cancels jobs that have been running for longer than their
+// maximum duration, reporting them as failed along with the reason. The
+// duration is measured from the start time reported by the provider, so jobs
+// still queued and providers that don't report start times are not subject to
+// timeouts.
+func (s *TranscodingService) checkJobTimeout(ctx context.Context, job *db.Job, status *provider.JobStatus, p provider.TranscodingProvider) error {
+	maxDuration := time.Duration(job.MaxDuration) * time.Second
+	if !job.TimedOut {
+		if maxDuration == 0 || status.StartTime.IsZero() || isTerminalStatus(status.Status) {
+			return nil
+		}
+		if s.now().Sub(status.StartTime) < maxDuration {
+			return nil
+		}
+		err := p.CancelJob(ctx, job.ProviderJobID)
+		if err != nil {
+			return fmt.Errorf("error canceling job %q after it exceeded its maximum duration: %s", job.ID, err)
+		}
+		job.TimedOut = true
+		err = s.db.UpdateJob(job)
+		if err != nil {
+			return fmt.Errorf("error updating job %q after it exceeded its maximum duration: %s", job.ID, err)
+		}
+	}
+	status.Status = provider.StatusFailed
+	status.StatusMessage = fmt.Sprintf("job timed out after running for more than %s", maxDuration)
+	for i := range status.Outputs {
+		if !isTerminalStatus(status.Outputs[i].Status) {
+			status.Outputs[i].Status = provider.StatusFailed
+		}
+	}
+	return nil
+}
+
+func (s *TranscodingService) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobTimeout(t *testing.T) {
+	tests := []struct {
+		givenTestCase    string
+		givenJob         db.Job
+		givenElapsedTime time.Duration
+
+		wantStatus        string
+		wantStatusMessage string
+		wantCanceledJobs  []string
+	}{
+		{
+			"running job within its maximum duration",
+			db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", MaxDuration: 3600},
+			59 * time.Minute,
+			"started",
+			"",
+			nil,
+		},
+		{
+			"running job exceeding its maximum duration",
+			db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", MaxDuration: 3600},
+			time.Hour,
+			"failed",
+			"job timed out after running for more than 1h0m0s",
+			[]string{"provider-job-running"},
+		},
+		{
+			"running job without maximum duration",
+			db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running"},
+			48 * time.Hour,
+			"started",
+			"",
+			nil,
+		},
+		{
+			"queued job",
+			db.Job{ID: "job-queued", ProviderName: "fake", ProviderJobID: "provider-job-queued", MaxDuration: 1},
+			48 * time.Hour,
+			"queued",
+			"",
+			nil,
+		},
+		{
+			"finished job",
+			db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123", MaxDuration: 60},
+			48 * time.Hour,
+			"finished",
+			"The job is finished",
+			nil,
+		},
+	}
+	defer func() { fprovider.canceledJobs = nil }()
+	for _, test := range tests {
+		fprovider.canceledJobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		job := test.givenJob
+		fakeDBObj.CreateJob(&job)
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		service.clock = func() time.Time { return fakeJobStartTime.Add(test.givenElapsedTime) }
+		srvr.Register(service)
+		r, _ := http.NewRequest("GET", "/jobs/"+job.ID, nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusOK, w.Code)
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["status"] != test.wantStatus {
+			t.Errorf("%s: wrong status. Want %q. Got %q", test.givenTestCase, test.wantStatus, got["status"])
+		}
+		if message, _ := got["statusMessage"].(string); message != test.wantStatusMessage {
+			t.Errorf("%s: wrong status message. Want %q. Got %q", test.givenTestCase, test.wantStatusMessage, message)
+		}
+		if !reflect.DeepEqual(fprovider.canceledJobs, test.wantCanceledJobs) {
+			t.Errorf("%s: wrong list of canceled jobs. Want %#v. Got %#v", test.givenTestCase, test.wantCanceledJobs, fprovider.canceledJobs)
+		}
+		dbJob, err := fakeDBObj.GetJob(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantTimedOut := test.wantCanceledJobs != nil; dbJob.TimedOut != wantTimedOut {
+			t.Errorf("%s: wrong TimedOut flag in the database. Want %v. Got %v", test.givenTestCase, wantTimedOut, dbJob.TimedOut)
+		}
+	}
+}
+
+func TestJobTimeoutIsReportedAfterCancellation(t *testing.T) {
+	defer func() { fprovider.canceledJobs = nil }()
+	fprovider.canceledJobs = nil
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-running", ProviderName: "fake", ProviderJobID: "provider-job-running", MaxDuration: 600})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	now := fakeJobStartTime.Add(5 * time.Minute)
+	service.clock = func() time.Time { return now }
+	srvr.Register(service)
+	getStatus := func() string {
+		r, _ := http.NewRequest("GET", "/jobs/job-running", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		var got map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, _ := got["status"].(string)
+		return status
+	}
+	if status := getStatus(); status != "started" {
+		t.Errorf("wrong status before the timeout. Want %q. Got %q", "started", status)
+	}
+	now = now.Add(10 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if status := getStatus(); status != "failed" {
+			t.Errorf("wrong status after the timeout. Want %q. Got %q", "failed", status)
+		}
+	}
+	if want := []string{"provider-job-running"}; !reflect.DeepEqual(fprovider.canceledJobs, want) {
+		t.Errorf("wrong list of canceled jobs. Want %#v. Got %#v", want, fprovider.canceledJobs)
+	}
+}
+
+func TestJobMaxDurationDefault(t *testing.T) {
+	tests := []struct {
+		givenTestCase           string
+		givenRequestBody        string
+		givenDefaultMaxDuration uint
+
+		wantMaxDuration uint
+	}{
+		{
+			"default from configuration",
+			`{"source":"http://some.source/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`,
+			7200,
+			7200,
+		},
+		{
+			"per-job override",
+			`{"source":"http://some.source/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake","maxDuration":600}`,
+			7200,
+			600,
+		},
+		{
+			"no timeout",
+			`{"source":"http://some.source/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`,
+			0,
+			0,
+		},
+	}
+	for _, test := range tests {
+		fprovider.jobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		service, err := NewTranscodingService(&config.Config{
+			JobMaxDuration: test.givenDefaultMaxDuration,
+			Server:         &server.Config{},
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(test.givenRequestBody))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: wrong response code. Want %d. Got %d: %s", test.givenTestCase, http.StatusOK, w.Code, w.Body.String())
+		}
+		if len(fprovider.jobs) != 1 {
+			t.Fatalf("%s: wrong number of jobs sent to the provider: %d", test.givenTestCase, len(fprovider.jobs))
+		}
+		if got := fprovider.jobs[0].MaxDuration; got != test.wantMaxDuration {
+			t.Errorf("%s: wrong maximum duration. Want %d. Got %d", test.givenTestCase, test.wantMaxDuration, got)
+		}
+	}
+}
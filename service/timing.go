@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// debugTimingHeader is the request header that asks for the timing of each
+// step of job submissions, reported in the Server-Timing response header
+// when DEBUG_TIMING is enabled.
+const debugTimingHeader = "X-Debug-Timing"
+
+type stepTimingsKey struct{}
+
+// stepTiming is the duration of a single step of a request.
+type stepTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// stepTimings records the duration of the steps of a request, in the order
+// they finish.
+type stepTimings struct {
+	now   func() time.Time
+	start time.Time
+	steps []stepTiming
+}
+
+// withStepTimings returns a copy of the given request carrying a recorder
+// for the timing of its steps, if the request asks for it and debug timing
+// is enabled. Otherwise the request is returned as it is, along with a nil
+// recorder.
+func (s *TranscodingService) withStepTimings(r *http.Request) (*http.Request, *stepTimings) {
+	if !s.config.DebugTiming || r.Header.Get(debugTimingHeader) == "" {
+		return r, nil
+	}
+	timings := &stepTimings{now: s.now, start: s.now()}
+	return r.WithContext(context.WithValue(r.Context(), stepTimingsKey{}, timings)), timings
+}
+
+// timeStep starts timing the given step of the request that owns ctx,
+// returning the function that stops it. It's a no-op for requests that
+// don't carry a recorder.
+func timeStep(ctx context.Context, name string) func() {
+	timings, _ := ctx.Value(stepTimingsKey{}).(*stepTimings)
+	if timings == nil {
+		return func() {}
+	}
+	start := timings.now()
+	return func() {
+		timings.steps = append(timings.steps, stepTiming{name: name, duration: timings.now().Sub(start)})
+	}
+}
+
+// writeHeader reports the recorded steps, along with the total duration of
+// the request so far, in the Server-Timing header of the given response.
+func (t *stepTimings) writeHeader(w http.ResponseWriter) {
+	if t == nil {
+		return
+	}
+	steps := append(t.steps, stepTiming{name: "total", duration: t.now().Sub(t.start)})
+	metrics := make([]string, len(steps))
+	for i, step := range steps {
+		metrics[i] = fmt.Sprintf("%s;dur=%.3f", step.name, float64(step.duration)/float64(time.Millisecond))
+	}
+	w.Header().Set("Server-Timing", strings.Join(metrics, ", "))
+}
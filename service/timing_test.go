@@ -0,0 +1,67 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/db"
+	"github.com/NYTimes/video-transcoding-api/db/dbtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewJobDebugTiming(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	timingRegexp := regexp.MustCompile(`^prepare;dur=\d+\.\d{3}, submit;dur=\d+\.\d{3}, persist;dur=\d+\.\d{3}, total;dur=\d+\.\d{3}$`)
+	tests := []struct {
+		givenTestCase    string
+		givenDebugTiming bool
+		givenHeader      string
+
+		wantTiming bool
+	}{
+		{"enabled and requested", true, "1", true},
+		{"enabled but not requested", true, "", false},
+		{"requested but disabled", false, "1", false},
+	}
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{
+			Server:      &server.Config{},
+			DebugTiming: test.givenDebugTiming,
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakeDB := dbtest.NewFakeRepository(false)
+		fakeDB.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		service.db = fakeDB
+		srvr.Register(service)
+		body := `{"source":"http://another.non.existent/video.mp4","outputs":[{"preset":"mp4_1080p"}],"provider":"fake"}`
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if test.givenHeader != "" {
+			r.Header.Set(debugTimingHeader, test.givenHeader)
+		}
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.givenTestCase, http.StatusOK, w.Code)
+		}
+		timing := w.Header().Get("Server-Timing")
+		if test.wantTiming && !timingRegexp.MatchString(timing) {
+			t.Errorf("%s: wrong Server-Timing header. Want it to match %q. Got %q", test.givenTestCase, timingRegexp, timing)
+		}
+		if !test.wantTiming && timing != "" {
+			t.Errorf("%s: unexpected Server-Timing header %q", test.givenTestCase, timing)
+		}
+	}
+}
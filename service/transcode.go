@@ -1,17 +1,23 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/NYTimes/gizmo/server"
 	"github.com/NYTimes/gizmo/web"
 	"github.com/NYTimes/video-transcoding-api/db"
 	"github.com/NYTimes/video-transcoding-api/provider"
 	"github.com/NYTimes/video-transcoding-api/swagger"
+	"github.com/sirupsen/logrus"
 )
 
 // swagger:route POST /jobs jobs newJob
@@ -21,70 +27,498 @@ import (
 //     Responses:
 //       200: job
 //       400: invalidJob
+//       429: genericError
 //       500: genericError
+//       503: datastoreUnavailable
 func (s *TranscodingService) newTranscodeJob(r *http.Request) swagger.GizmoJSONResponse {
 	defer r.Body.Close()
+	stop := timeStep(r.Context(), "prepare")
+	prepared, resp := s.prepareTranscodeJob(r)
+	stop()
+	if resp != nil {
+		return resp
+	}
+	if prepared.upstream != nil && provider.Status(prepared.upstream.Status) != provider.StatusFinished {
+		defer timeStep(r.Context(), "persist")()
+		return s.holdJob(&prepared.job, prepared.upstream, prepared.providerName, prepared.skipped)
+	}
+	return s.submitJob(r.Context(), &prepared.job, prepared.provider, prepared.providerName, prepared.skipped)
+}
+
+// preparedJob is a new job built from the payload of a request, along with
+// the provider it's sent to.
+type preparedJob struct {
+	job          db.Job
+	provider     provider.TranscodingProvider
+	providerName string
+
+	// job the new job depends on, if any
+	upstream *db.Job
+
+	// outputs skipped in best-effort jobs
+	skipped []SkippedOutput
+}
+
+// prepareTranscodeJob validates the payload of the given request and builds
+// the new job described by it, without storing it or sending it to the
+// provider. Validation errors are returned as the response for the request.
+func (s *TranscodingService) prepareTranscodeJob(r *http.Request) (*preparedJob, swagger.GizmoJSONResponse) {
 	var input newTranscodeJobInput
-	providerFactory, err := input.ProviderFactory(r.Body)
+	err := input.loadParams(s.requestBody(r))
 	if err != nil {
-		return newInvalidJobResponse(err)
+		return nil, newInvalidJobResponse(err)
 	}
-	providerObj, err := providerFactory(s.config)
+	errs := input.validate()
+	err = input.expandProfile(s.config.JobProfiles)
 	if err != nil {
-		formattedErr := fmt.Errorf("Error initializing provider %s for new job: %v %s", input.Payload.Provider, providerObj, err)
-		if _, ok := err.(provider.InvalidConfigError); ok {
-			return newInvalidJobResponse(formattedErr)
+		errs.add("profile", err)
+	}
+	if max := s.config.JobMaxOutputs; max > 0 && uint(len(input.Payload.Outputs)) > max {
+		errs.add("outputs", fmt.Errorf("job has %d outputs, exceeding the maximum of %d", len(input.Payload.Outputs), max))
+	}
+	if input.Payload.MaxRetries > s.config.JobMaxRetries {
+		errs.add("maxRetries", fmt.Errorf("invalid maxRetries %d, must be at most %d", input.Payload.MaxRetries, s.config.JobMaxRetries))
+	}
+	if input.Payload.NotificationEmail != "" {
+		err = s.validateNotificationEmail(input.Payload.NotificationEmail)
+		if err != nil {
+			errs.add("notificationEmail", err)
+		}
+	}
+	var (
+		providerObj  provider.TranscodingProvider
+		providerName string
+		cluster      string
+	)
+	if input.Payload.Provider != "" {
+		providerObj, providerName, cluster, err = s.newJobProvider(&input.Payload)
+		if providerErrs, ok := err.(validationErrors); ok {
+			errs = append(errs, providerErrs...)
+		} else if err != nil {
+			return nil, swagger.NewErrorResponse(err)
+		}
+	}
+	if providerObj != nil {
+		for _, verr := range validateProviderCapabilities(providerObj, &input.Payload) {
+			// fields with problems aren't checked against the provider
+			if !errs.hasField(verr.Field) {
+				errs = append(errs, verr)
+			}
+		}
+	}
+	var upstream *db.Job
+	if input.Payload.DependsOn != "" {
+		upstream, err = s.dependencyJob(input.Payload.DependsOn)
+		if depErrs, ok := err.(validationErrors); ok {
+			errs = append(errs, depErrs...)
+		} else if _, ok := err.(db.UnavailableError); ok {
+			s.logger.WithError(err).Error("unable to load the job a new job depends on")
+			return nil, newDatastoreUnavailableResponse(s.config.DatastoreRetryAfter)
+		} else if err != nil {
+			return nil, swagger.NewErrorResponse(err)
 		}
-		return swagger.NewErrorResponse(formattedErr)
 	}
 	job := db.Job{
-		SourceMedia:     input.Payload.Source,
-		StreamingParams: input.Payload.StreamingParams,
+		SourceMedia:       input.Payload.Source,
+		SourceSegments:    input.Payload.Segments,
+		AudioSelectors:    input.Payload.AudioSelectors,
+		StreamingParams:   input.Payload.StreamingParams,
+		NodeTags:          input.Payload.NodeTags,
+		OutputACL:         input.Payload.OutputACL,
+		FrameCaptures:     input.Payload.FrameCaptures,
+		Cluster:           cluster,
+		MaxDuration:       input.Payload.MaxDuration,
+		EscalatePriority:  input.Payload.EscalatePriority,
+		ProviderOptions:   input.Payload.ProviderOptions,
+		Metadata:          input.Payload.Metadata,
+		ProviderTags:      input.Payload.ProviderTags,
+		MaxRetries:        input.Payload.MaxRetries,
+		Test:              input.Payload.Test,
+		Name:              input.Payload.Name,
+		InputFormat:       input.Payload.InputFormat,
+		NotificationEmail: input.Payload.NotificationEmail,
+		WriteManifest:     input.Payload.WriteManifest,
+		DependsOn:         input.Payload.DependsOn,
+	}
+	if len(job.SourceSegments) > 0 {
+		job.SourceMedia = job.SourceSegments[0].URI
 	}
-	outputs := make([]db.TranscodeOutput, len(input.Payload.Outputs))
+	if job.MaxDuration == 0 {
+		job.MaxDuration = s.config.JobMaxDuration
+	}
+	presetNames := make([]string, len(input.Payload.Outputs))
 	for i, output := range input.Payload.Outputs {
-		presetMap, presetErr := s.db.GetPresetMap(output.Preset)
-		if presetErr != nil {
-			if presetErr == db.ErrPresetMapNotFound {
-				return newInvalidJobResponse(presetErr)
-			}
-			return swagger.NewErrorResponse(presetErr)
+		presetNames[i] = output.Preset
+	}
+	presetMaps, err := s.db.GetPresetMaps(presetNames)
+	if _, ok := err.(db.UnavailableError); ok {
+		s.logger.WithError(err).Error("unable to load the preset maps of a new job")
+		return nil, newDatastoreUnavailableResponse(s.config.DatastoreRetryAfter)
+	}
+	if err != nil {
+		return nil, swagger.NewErrorResponse(err)
+	}
+	var skipped []SkippedOutput
+	outputs := make([]db.TranscodeOutput, 0, len(input.Payload.Outputs))
+	for i, output := range input.Payload.Outputs {
+		presetMap, err := s.outputPresetMap(presetMaps, output.Preset, &input.Payload)
+		if err != nil && input.Payload.BestEffort {
+			skipped = append(skipped, SkippedOutput{Preset: output.Preset, FileName: output.FileName, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			errs.add(fmt.Sprintf("outputs[%d].preset", i), err)
+			continue
 		}
 		fileName := output.FileName
 		if fileName == "" {
-			fileName = s.defaultFileName(input.Payload.Source, presetMap)
+			fileName = s.defaultFileName(job.SourceMedia, presetMap)
 		}
-		outputs[i] = db.TranscodeOutput{FileName: fileName, Preset: *presetMap}
+		outputs = append(outputs, db.TranscodeOutput{
+			FileName:      fileName,
+			Preset:        *presetMap,
+			AudioSelector: output.AudioSelector,
+			AudioLanguage: output.AudioLanguage,
+		})
 	}
-	job.Outputs = outputs
-	job.ID, err = s.genID()
-	if err != nil {
-		return swagger.NewErrorResponse(err)
+	if len(errs) > 0 {
+		return nil, newInvalidJobResponse(errs)
 	}
-	if job.StreamingParams.Protocol == "hls" {
+	if len(outputs) == 0 && len(skipped) > 0 {
+		return nil, newInvalidJobResponse(fmt.Errorf("none of the outputs of the job can be transcoded: %s", skipped[0].Reason))
+	}
+	job.Outputs = outputs
+	switch job.StreamingParams.Protocol {
+	case "hls":
 		if job.StreamingParams.PlaylistFileName == "" {
 			job.StreamingParams.PlaylistFileName = "hls/index.m3u8"
 		}
 		if job.StreamingParams.SegmentDuration == 0 {
 			job.StreamingParams.SegmentDuration = s.config.DefaultSegmentDuration
 		}
+	case "cmaf":
+		if job.StreamingParams.PlaylistFileName == "" {
+			job.StreamingParams.PlaylistFileName = "cmaf/index"
+		}
+		if job.StreamingParams.SegmentDuration == 0 {
+			job.StreamingParams.SegmentDuration = s.config.DefaultSegmentDuration
+		}
+		if job.StreamingParams.FragmentType == "" {
+			job.StreamingParams.FragmentType = db.FragmentTypeSingleFile
+		}
+	}
+	return &preparedJob{
+		job:          job,
+		provider:     providerObj,
+		providerName: providerName,
+		upstream:     upstream,
+		skipped:      skipped,
+	}, nil
+}
+
+// newJobProvider initializes the provider of a new job, routing test jobs to
+// the test provider and picking the cluster of the provider. Problems with
+// the requested provider are returned as validationErrors.
+func (s *TranscodingService) newJobProvider(payload *NewTranscodeJobInputPayload) (provider.TranscodingProvider, string, string, error) {
+	providerFactory, err := provider.GetProviderFactory(payload.Provider)
+	if err != nil {
+		return nil, "", "", invalidField("provider", err)
+	}
+	if payload.Test && s.config.TestJobProvider != "" && payload.Provider != s.config.TestJobProvider {
+		payload.Provider = s.config.TestJobProvider
+		providerFactory, err = provider.GetProviderFactory(payload.Provider)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("Error loading provider %s for test job: %s", payload.Provider, err)
+		}
+	}
+	providerName, cluster, err := provider.PickCluster(payload.Provider, s.config)
+	if err == provider.ErrProviderDisabled {
+		return nil, "", "", invalidField("provider", fmt.Errorf("provider %q is disabled", payload.Provider))
+	}
+	if err != nil {
+		return nil, "", "", fmt.Errorf("Error picking cluster of provider %s for new job: %s", payload.Provider, err)
+	}
+	if providerName != payload.Provider {
+		providerFactory, err = provider.GetProviderFactory(providerName)
+		if err != nil {
+			return nil, "", "", err
+		}
+	}
+	providerObj, err := providerFactory(s.config)
+	if err == provider.ErrProviderDisabled {
+		return nil, "", "", invalidField("provider", fmt.Errorf("provider %q is disabled", payload.Provider))
+	}
+	if err != nil {
+		formattedErr := fmt.Errorf("Error initializing provider %s for new job: %v %s", providerName, providerObj, err)
+		if _, ok := err.(provider.InvalidConfigError); ok {
+			return nil, "", "", invalidField("provider", formattedErr)
+		}
+		return nil, "", "", formattedErr
+	}
+	return providerObj, providerName, cluster, nil
+}
+
+// invalidField returns the given error as the single problem of a field.
+func invalidField(field string, err error) validationErrors {
+	var errs validationErrors
+	errs.add(field, err)
+	return errs
+}
+
+// validateProviderCapabilities checks that the provider of a new job
+// supports the features requested in the job.
+func validateProviderCapabilities(providerObj provider.TranscodingProvider, payload *NewTranscodeJobInputPayload) validationErrors {
+	var errs validationErrors
+	capabilities := providerObj.Capabilities()
+	if payload.OutputACL != "" && !supportsOutputACL(providerObj, payload.OutputACL) {
+		errs.add("outputACL", fmt.Errorf("provider %q doesn't support the output ACL %q", payload.Provider, payload.OutputACL))
+	}
+	if len(payload.FrameCaptures) > 0 && !supportsOutputFormat(providerObj, "jpg") {
+		errs.add("frameCaptures", fmt.Errorf("provider %q doesn't support frame captures", payload.Provider))
+	}
+	if len(payload.Segments) > 0 && !capabilities.InputStitching {
+		errs.add("segments", fmt.Errorf("provider %q doesn't support stitching input segments", payload.Provider))
+	}
+	if len(payload.AudioSelectors) > 0 && !capabilities.AudioSelection {
+		errs.add("audioSelectors", fmt.Errorf("provider %q doesn't support audio selectors", payload.Provider))
+	}
+	if hasAudioLanguages(payload.Outputs) && !capabilities.AudioSelection {
+		errs.add("outputs", fmt.Errorf("provider %q doesn't support audio languages", payload.Provider))
+	}
+	if len(payload.ProviderTags) > 0 && !capabilities.JobTags {
+		errs.add("providerTags", fmt.Errorf("provider %q doesn't support job tags", payload.Provider))
+	}
+	if payload.Name != "" && !capabilities.JobNames {
+		errs.add("name", fmt.Errorf("provider %q doesn't support job names", payload.Provider))
+	}
+	if payload.StreamingParams.BaseURL != "" && !capabilities.AbsoluteHLSURLs {
+		errs.add("streamingParams.baseURL", fmt.Errorf("provider %q doesn't support absolute urls in hls manifests", payload.Provider))
+	}
+	if payload.StreamingParams.SegmentNameTemplate != "" && !capabilities.HLSSegmentNames {
+		errs.add("streamingParams.segmentNameTemplate", fmt.Errorf("provider %q doesn't support segment name templates", payload.Provider))
+	}
+	if payload.StreamingParams.VariantOrder != "" && !capabilities.HLSVariantOrder {
+		errs.add("streamingParams.variantOrder", fmt.Errorf("provider %q doesn't support ordering hls variants", payload.Provider))
+	}
+	if payload.InputFormat != "" && !supportsInputContainer(providerObj, payload.InputFormat) {
+		errs.add("inputFormat", fmt.Errorf("provider %q doesn't support the input format %q", payload.Provider, payload.InputFormat))
+	}
+	if payload.StreamingParams.HLSLayout == db.HLSLayoutDemuxed && !capabilities.DemuxedHLS {
+		errs.add("streamingParams.hlsLayout", fmt.Errorf("provider %q doesn't support the demuxed hls layout", payload.Provider))
+	}
+	if _, ok := providerObj.(provider.PriorityUpdater); payload.EscalatePriority && !ok {
+		errs.add("escalatePriority", fmt.Errorf("provider %q doesn't support priority escalation", payload.Provider))
+	}
+	if _, ok := providerObj.(provider.ManifestWriter); payload.WriteManifest && !ok {
+		errs.add("writeManifest", fmt.Errorf("provider %q doesn't support output manifests", payload.Provider))
+	}
+	return errs
+}
+
+// outputPresetMap returns the preset map of an output of the given job, or
+// the reason why the preset can't be used in the job. Presets missing a
+// mapping for the provider use the fallback preset of the provider, when
+// there's one. Otherwise they're only detected here in best-effort jobs, and
+// the provider rejects the whole job in other jobs.
+func (s *TranscodingService) outputPresetMap(presetMaps map[string]*db.PresetMap, name string, payload *NewTranscodeJobInputPayload) (*db.PresetMap, error) {
+	presetMap, ok := presetMaps[name]
+	if !ok {
+		if payload.Profile != "" {
+			return nil, fmt.Errorf("preset %q of job profile %q: %s", name, payload.Profile, db.ErrPresetMapNotFound)
+		}
+		return nil, db.ErrPresetMapNotFound
+	}
+	if _, ok := presetMap.ProviderMapping[payload.Provider]; !ok {
+		if fallback, ok := s.config.PresetFallbacks[payload.Provider][name]; ok {
+			s.logger.WithFields(logrus.Fields{
+				"presetmap": name,
+				"provider":  payload.Provider,
+				"presetId":  fallback,
+			}).Info("using fallback preset for preset map without mapping for the provider")
+			presetMap = withProviderMapping(presetMap, payload.Provider, fallback)
+		}
+	}
+	if _, ok := presetMap.ProviderMapping[payload.Provider]; payload.BestEffort && !ok {
+		return nil, provider.ErrPresetMapNotFound
+	}
+	if presetMap.OutputOpts.OutputContainer() == "cmaf" && payload.StreamingParams.Protocol != "cmaf" {
+		return nil, fmt.Errorf("preset %q uses the cmaf container, which requires the cmaf streaming protocol", presetMap.Name)
+	}
+	if presetMap.OutputOpts.AudioDescription && payload.StreamingParams.Protocol == "" {
+		return nil, fmt.Errorf("preset %q is an audio description, which is only supported in adaptive streaming jobs", presetMap.Name)
+	}
+	return presetMap, nil
+}
+
+// withProviderMapping returns a copy of the given preset map, mapped to the
+// given preset id in the provider.
+func withProviderMapping(presetMap *db.PresetMap, providerName, presetID string) *db.PresetMap {
+	mapped := *presetMap
+	mapped.ProviderMapping = make(map[string]string, len(presetMap.ProviderMapping)+1)
+	for name, id := range presetMap.ProviderMapping {
+		mapped.ProviderMapping[name] = id
+	}
+	mapped.ProviderMapping[providerName] = presetID
+	return &mapped
+}
+
+// submitJob sends the given job to the provider and stores it, assigning it
+// a new id. The outputs skipped while building the job are reported in the
+// response.
+func (s *TranscodingService) submitJob(ctx context.Context, job *db.Job, providerObj provider.TranscodingProvider, providerName string, skipped []SkippedOutput) swagger.GizmoJSONResponse {
+	var err error
+	job.ID, err = s.genID()
+	if err != nil {
+		return swagger.NewErrorResponse(err)
+	}
+	stop := timeStep(ctx, "submit")
+	jobStatus, release, err := s.transcode(ctx, job, providerObj, providerName)
+	defer release()
+	stop()
+	if _, ok := err.(providerAtCapacityError); ok {
+		return swagger.NewErrorResponse(err).WithStatus(http.StatusServiceUnavailable)
 	}
-	jobStatus, err := providerObj.Transcode(&job)
 	if err == provider.ErrPresetMapNotFound {
 		return newInvalidJobResponse(err)
 	}
+	if _, ok := err.(provider.InvalidJobError); ok {
+		return newInvalidJobResponse(err)
+	}
 	if err != nil {
-		providerError := fmt.Errorf("Error with provider %q: %s", input.Payload.Provider, err)
+		providerError := fmt.Errorf("Error with provider %q: %s", providerName, err)
 		return swagger.NewErrorResponse(providerError)
 	}
-	jobStatus.ProviderName = input.Payload.Provider
+	jobStatus.ProviderName = providerName
 	job.ProviderName = jobStatus.ProviderName
 	job.ProviderJobID = jobStatus.ProviderJobID
-	err = s.db.CreateJob(&job)
+	keepJobSpec(job, jobStatus)
+	job.Status = string(jobStatus.Status)
+	stop = timeStep(ctx, "persist")
+	err = s.db.CreateJob(job)
+	stop()
+	if _, ok := err.(db.UnavailableError); ok {
+		// the job can't be tracked without its record, so it's canceled in
+		// the provider instead of being left running while the client
+		// retries the submission.
+		logger := s.logger.WithField("providerJobId", job.ProviderJobID)
+		logger.WithError(err).Error("unable to store new job")
+		if cancelErr := providerObj.CancelJob(ctx, job.ProviderJobID); cancelErr != nil {
+			logger.WithError(cancelErr).Error("unable to cancel job that couldn't be stored")
+		}
+		return newDatastoreUnavailableResponse(s.config.DatastoreRetryAfter)
+	}
 	if err != nil {
 		return swagger.NewErrorResponse(err)
 	}
-	return newJobResponse(job.ID)
+	return newJobResponse(job.ID, skipped)
+}
+
+// swagger:route POST /jobs/{jobId}/resubmit jobs resubmitJob
+//
+// Creates a new job with the outputs that failed in the given job, linked
+// to it. Only jobs of providers that report the status of each output can
+// be resubmitted.
+//
+//     Responses:
+//       200: job
+//       400: invalidJob
+//       404: jobNotFound
+//       410: jobNotFoundInTheProvider
+//       429: genericError
+//       500: genericError
+//       502: providerError
+//       503: datastoreUnavailable
+func (s *TranscodingService) resubmitTranscodeJob(r *http.Request) swagger.GizmoJSONResponse {
+	var params resubmitTranscodeJobInput
+	params.loadParams(web.Vars(r))
+	job, status, prov, err := s.getTranscodeJobByID(r.Context(), params.JobID)
+	if err != nil {
+		return s.getJobStatusResponse(job, status, prov, err)
+	}
+	if len(status.Outputs) == 0 {
+		return newInvalidJobResponse(fmt.Errorf("provider %q doesn't report the status of the outputs of job %q", job.ProviderName, job.ID))
+	}
+	failed := make(map[string]bool)
+	for _, output := range status.Outputs {
+		if output.Status == provider.StatusFailed {
+			failed[output.FileName] = true
+		}
+	}
+	resubmitted := db.Job{
+		SourceMedia:       job.SourceMedia,
+		SourceSegments:    job.SourceSegments,
+		AudioSelectors:    job.AudioSelectors,
+		StreamingParams:   job.StreamingParams,
+		NodeTags:          job.NodeTags,
+		OutputACL:         job.OutputACL,
+		Cluster:           job.Cluster,
+		MaxDuration:       job.MaxDuration,
+		EscalatePriority:  job.EscalatePriority,
+		ProviderOptions:   job.ProviderOptions,
+		Metadata:          job.Metadata,
+		ProviderTags:      job.ProviderTags,
+		ResubmittedFrom:   job.ID,
+		MaxRetries:        job.MaxRetries,
+		Test:              job.Test,
+		Name:              job.Name,
+		InputFormat:       job.InputFormat,
+		NotificationEmail: job.NotificationEmail,
+		WriteManifest:     job.WriteManifest,
+	}
+	for _, output := range job.Outputs {
+		if failed[output.FileName] {
+			resubmitted.Outputs = append(resubmitted.Outputs, output)
+		}
+	}
+	if len(resubmitted.Outputs) == 0 {
+		return newInvalidJobResponse(fmt.Errorf("job %q has no failed outputs", job.ID))
+	}
+	return s.submitJob(r.Context(), &resubmitted, prov, job.ProviderName, nil)
+}
+
+func (s *TranscodingService) resubmitTranscodeJobHandler(w http.ResponseWriter, r *http.Request) {
+	r, timings := s.withStepTimings(r)
+	resp := s.resubmitTranscodeJob(r)
+	timings.writeHeader(w)
+	s.writeJSONResponse(w, r, resp)
+}
+
+func supportsOutputACL(p provider.TranscodingProvider, acl string) bool {
+	for _, supported := range p.Capabilities().OutputACLs {
+		if supported == acl {
+			return true
+		}
+	}
+	return false
+}
+
+func supportsInputContainer(p provider.TranscodingProvider, container string) bool {
+	for _, supported := range p.Capabilities().InputContainers {
+		if supported == container {
+			return true
+		}
+	}
+	return false
+}
+
+func supportsOutputFormat(p provider.TranscodingProvider, format string) bool {
+	for _, supported := range p.Capabilities().OutputFormats {
+		if supported == format {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAudioLanguages returns whether any of the given outputs labels its
+// audio with a language.
+func hasAudioLanguages(outputs []NewTranscodeJobOutput) bool {
+	for _, output := range outputs {
+		if output.AudioLanguage != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *TranscodingService) genID() (string, error) {
@@ -104,7 +538,7 @@ func (s *TranscodingService) defaultFileName(source string, preset *db.PresetMap
 	_, source = path.Split(source)
 	source = source[:len(source)-len(sourceExtension)]
 	pattern := "%s_%s.%s"
-	if preset.OutputOpts.Extension == "m3u8" {
+	if preset.OutputOpts.OutputContainer() == "m3u8" {
 		pattern = "hls/" + pattern
 	}
 	return fmt.Sprintf(pattern, source, preset.Name, preset.OutputOpts.Extension)
@@ -120,10 +554,62 @@ func (s *TranscodingService) defaultFileName(source string, preset *db.PresetMap
 //       404: jobNotFound
 //       410: jobNotFoundInTheProvider
 //       500: genericError
+//       502: providerError
 func (s *TranscodingService) getTranscodeJob(r *http.Request) swagger.GizmoJSONResponse {
 	var params getTranscodeJobInput
 	params.loadParams(web.Vars(r))
-	return s.getJobStatusResponse(s.getTranscodeJobByID(params.JobID))
+	return s.getJobStatusResponse(s.getTranscodeJobByID(r.Context(), params.JobID))
+}
+
+// newTranscodeJobHandler serves newTranscodeJob, reporting the timing of the
+// steps of the submission in the Server-Timing header when requested.
+func (s *TranscodingService) newTranscodeJobHandler(w http.ResponseWriter, r *http.Request) {
+	r, timings := s.withStepTimings(r)
+	resp := s.newTranscodeJob(r)
+	timings.writeHeader(w)
+	s.writeJSONResponse(w, r, resp)
+}
+
+// getTranscodeJobHandler serves getTranscodeJob, including an ETag in
+// successful responses and replying with 304 (Not Modified) when the status
+// matches the one identified by the If-None-Match header.
+func (s *TranscodingService) getTranscodeJobHandler(w http.ResponseWriter, r *http.Request) {
+	resp := s.getTranscodeJob(r)
+	if statusResp, ok := resp.(*jobStatusResponse); ok {
+		etag, err := statusResp.etag()
+		if err == nil {
+			w.Header().Set("ETag", etag)
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	s.writeJSONResponse(w, r, resp)
+}
+
+// writeJSONResponse writes the given response in the same way JSONEndpoints
+// do, for use in handlers that need control over the response headers.
+func (s *TranscodingService) writeJSONResponse(w http.ResponseWriter, r *http.Request, resp swagger.GizmoJSONResponse) {
+	if unavailable, ok := resp.(*datastoreUnavailableResponse); ok && unavailable.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatUint(uint64(unavailable.retryAfter), 10))
+	}
+	endpoint := func(*http.Request) (int, interface{}, error) {
+		return resp.Result()
+	}
+	server.JSONToHTTP(s.JSONMiddleware(endpoint)).ServeHTTP(w, r)
+}
+
+// etagMatches checks whether the given ETag is listed in the value of an
+// If-None-Match header. Weak validators are compared as strong ones.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *TranscodingService) getJobStatusResponse(job *db.Job, status *provider.JobStatus, p provider.TranscodingProvider, err error) swagger.GizmoJSONResponse {
@@ -136,14 +622,14 @@ func (s *TranscodingService) getJobStatusResponse(job *db.Job, status *provider.
 			if _, ok := err.(provider.JobNotFoundError); ok {
 				return newJobNotFoundProviderResponse(providerError)
 			}
-			return swagger.NewErrorResponse(providerError)
+			return newProviderErrorResponse(providerError)
 		}
 		return swagger.NewErrorResponse(err)
 	}
 	return newJobStatusResponse(status)
 }
 
-func (s *TranscodingService) getTranscodeJobByID(jobID string) (*db.Job, *provider.JobStatus, provider.TranscodingProvider, error) {
+func (s *TranscodingService) getTranscodeJobByID(ctx context.Context, jobID string) (*db.Job, *provider.JobStatus, provider.TranscodingProvider, error) {
 	job, err := s.db.GetJob(jobID)
 	if err != nil {
 		if err == db.ErrJobNotFound {
@@ -151,20 +637,103 @@ func (s *TranscodingService) getTranscodeJobByID(jobID string) (*db.Job, *provid
 		}
 		return nil, nil, nil, fmt.Errorf("error retrieving job with id %q: %s", jobID, err)
 	}
+	providerObj, err := s.providerFor(job)
+	if err != nil {
+		return job, nil, nil, err
+	}
+	jobStatus, err := s.jobStatus(ctx, job, providerObj)
+	if err != nil {
+		return job, nil, providerObj, err
+	}
+	return job, jobStatus, providerObj, nil
+}
+
+// providerFor returns an instance of the provider of the given job.
+func (s *TranscodingService) providerFor(job *db.Job) (provider.TranscodingProvider, error) {
 	providerFactory, err := provider.GetProviderFactory(job.ProviderName)
 	if err != nil {
-		return job, nil, nil, fmt.Errorf("unknown provider %q for job id %q", job.ProviderName, jobID)
+		return nil, fmt.Errorf("unknown provider %q for job id %q", job.ProviderName, job.ID)
 	}
 	providerObj, err := providerFactory(s.config)
 	if err != nil {
-		return job, nil, nil, fmt.Errorf("error initializing provider %q on job id %q: %s %s", job.ProviderName, jobID, providerObj, err)
+		return nil, fmt.Errorf("error initializing provider %q on job id %q: %s %s", job.ProviderName, job.ID, providerObj, err)
+	}
+	return providerObj, nil
+}
+
+// jobStatus retrieves the status of the given job from the provider,
+// enforcing the maximum duration of the job, retrying it when it fails,
+// writing the manifest of its outputs and storing the status when it
+// changes. Held jobs are sent to the provider once the job they depend on
+// finishes.
+func (s *TranscodingService) jobStatus(ctx context.Context, job *db.Job, p provider.TranscodingProvider) (*provider.JobStatus, error) {
+	if isHeldJob(job) {
+		status, err := s.heldJobStatus(ctx, job, p)
+		if err != nil || status != nil {
+			return status, err
+		}
 	}
-	jobStatus, err := providerObj.JobStatus(job)
+	jobStatus, err := p.JobStatus(ctx, job)
 	if err != nil {
-		return job, nil, providerObj, err
+		return nil, err
 	}
 	jobStatus.ProviderName = job.ProviderName
-	return job, jobStatus, providerObj, nil
+	jobStatus.Test = job.Test
+	jobStatus.ComputeDurations()
+	s.escalateJobPriority(ctx, job, jobStatus, p)
+	err = s.checkJobTimeout(ctx, job, jobStatus, p)
+	if err != nil {
+		return nil, err
+	}
+	jobStatus, ok := s.retryFailedJob(ctx, job, jobStatus, p)
+	jobStatus.FailedAttempts = job.FailedAttempts
+	if !ok {
+		return jobStatus, nil
+	}
+	s.writeJobManifest(ctx, job, jobStatus, p)
+	jobStatus.Output.Manifest = job.Manifest
+	if status := string(jobStatus.Status); status != job.Status {
+		// concurrent requests may observe the job completing, only the
+		// one that claims it stores it and acts on it.
+		if isTerminalStatus(jobStatus.Status) && !s.claimJobTransition(job, status) {
+			return jobStatus, nil
+		}
+		job.Status = status
+		job.ProcessingTime = uint(jobStatus.ProcessingDuration / time.Second)
+		if jobStatus.SourceInfo.Duration > 0 {
+			job.SourceDuration = uint(jobStatus.SourceInfo.Duration / time.Second)
+		}
+		err = s.db.UpdateJob(job)
+		if err != nil {
+			return nil, fmt.Errorf("error storing the status of job %q: %s", job.ID, err)
+		}
+		s.notifyJob(job, jobStatus)
+		s.resolveDependents(ctx, job, jobStatus)
+	}
+	return jobStatus, nil
+}
+
+// claimJobTransition reports whether the caller should act on the given
+// change of the current attempt of the job, claiming it in the repository
+// so concurrent requests observing the same change don't. Failures to claim
+// it are logged, leaving the change to later requests.
+func (s *TranscodingService) claimJobTransition(job *db.Job, change string) bool {
+	claimed, err := s.db.ClaimJobTransition(job.ID, job.ProviderJobID+":"+change)
+	if err != nil {
+		s.logger.WithError(err).WithField("jobId", job.ID).Error("unable to claim the transition of job")
+		return false
+	}
+	return claimed
+}
+
+// releaseJobTransition releases a change of the job claimed with
+// claimJobTransition whose side effects failed, so later requests act on it
+// again. Failures are logged.
+func (s *TranscodingService) releaseJobTransition(job *db.Job, change string) {
+	err := s.db.ReleaseJobTransition(job.ID, job.ProviderJobID+":"+change)
+	if err != nil {
+		s.logger.WithError(err).WithField("jobId", job.ID).Error("unable to release the transition of job")
+	}
 }
 
 // swagger:route POST /jobs/{jobId}/cancel jobs cancelJob
@@ -176,27 +745,42 @@ func (s *TranscodingService) getTranscodeJobByID(jobID string) (*db.Job, *provid
 //       404: jobNotFound
 //       410: jobNotFoundInTheProvider
 //       500: genericError
+//       502: providerError
 func (s *TranscodingService) cancelTranscodeJob(r *http.Request) swagger.GizmoJSONResponse {
 	var params cancelTranscodeJobInput
 	params.loadParams(web.Vars(r))
-	job, _, prov, err := s.getTranscodeJobByID(params.JobID)
+	job, _, prov, err := s.getTranscodeJobByID(r.Context(), params.JobID)
 	if err != nil {
-		if err == db.ErrJobNotFound {
-			return newJobNotFoundResponse(err)
-		}
-		if _, ok := err.(provider.JobNotFoundError); ok {
-			return newJobNotFoundProviderResponse(err)
+		return cancelErrorResponse(prov, err)
+	}
+	if isHeldJob(job) {
+		status, err := s.cancelHeldJob(r.Context(), job)
+		if err != nil {
+			return swagger.NewErrorResponse(err)
 		}
-		return swagger.NewErrorResponse(err)
+		return newJobStatusResponse(status)
 	}
-	err = prov.CancelJob(job.ProviderJobID)
+	err = prov.CancelJob(r.Context(), job.ProviderJobID)
 	if err != nil {
-		return swagger.NewErrorResponse(err)
+		return cancelErrorResponse(prov, err)
 	}
-	status, err := prov.JobStatus(job)
+	status, err := prov.JobStatus(r.Context(), job)
 	if err != nil {
-		return swagger.NewErrorResponse(err)
+		return cancelErrorResponse(prov, err)
 	}
 	status.ProviderName = job.ProviderName
 	return newJobStatusResponse(status)
 }
+
+func cancelErrorResponse(p provider.TranscodingProvider, err error) swagger.GizmoJSONResponse {
+	if err == db.ErrJobNotFound {
+		return newJobNotFoundResponse(err)
+	}
+	if _, ok := err.(provider.JobNotFoundError); ok {
+		return newJobNotFoundProviderResponse(err)
+	}
+	if p != nil {
+		return newProviderErrorResponse(err)
+	}
+	return swagger.NewErrorResponse(err)
+}
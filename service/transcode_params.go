@@ -1,12 +1,15 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net/url"
+	"regexp"
+	"strings"
 
+	"github.com/NYTimes/video-transcoding-api/config"
 	"github.com/NYTimes/video-transcoding-api/db"
-	"github.com/NYTimes/video-transcoding-api/provider"
 )
 
 // NewTranscodeJobInputPayload makes up the parameters available for
@@ -15,58 +18,395 @@ type NewTranscodeJobInputPayload struct {
 	// source media for the transcoding job.
 	Source string `json:"source"`
 
+	// list of segments, with optional in and out timecodes, stitched
+	// together as the input of the job instead of the source media
+	Segments []db.SourceSegment `json:"segments,omitempty"`
+
+	// list of audio tracks of the source, picked by track number or
+	// language, that outputs may reference by name as their audio
+	AudioSelectors []db.AudioSelector `json:"audioSelectors,omitempty"`
+
 	// list of outputs in this job
-	Outputs []struct {
-		FileName string `json:"fileName"`
-		Preset   string `json:"preset"`
-	} `json:"outputs"`
+	Outputs []NewTranscodeJobOutput `json:"outputs"`
+
+	// name of a job profile, defined in the JOB_PROFILES setting, to use
+	// instead of the list of outputs. The job will have one output for
+	// each preset in the profile
+	Profile string `json:"profile,omitempty"`
 
 	// provider to use in this job
 	Provider string `json:"provider"`
 
 	// provider Adaptive Streaming parameters
 	StreamingParams db.StreamingParams `json:"streamingParams,omitempty"`
+
+	// list of node tags for placing the job on specific nodes of the
+	// provider (e.g. GPU nodes)
+	NodeTags []string `json:"nodeTags,omitempty"`
+
+	// access control applied to the output files: private or public-read.
+	// Defaults to private
+	OutputACL string `json:"outputACL,omitempty"`
+
+	// list of timecodes, in the format HH:MM:SS:FF, of the frames that
+	// should be captured as images
+	FrameCaptures []string `json:"frameCaptures,omitempty"`
+
+	// maximum duration of the job, in seconds, measured from the moment
+	// the provider starts running it. Defaults to the JOB_MAX_DURATION
+	// setting
+	MaxDuration uint `json:"maxDuration,omitempty"`
+
+	// whether the priority of the job should be raised if it stays queued
+	// for longer than the JOB_PRIORITY_ESCALATION_THRESHOLD setting. Only
+	// supported by providers able to update the priority of jobs
+	EscalatePriority bool `json:"escalatePriority,omitempty"`
+
+	// provider-specific settings not covered by the job. See the
+	// documentation of each provider for the supported options
+	ProviderOptions map[string]interface{} `json:"providerOptions,omitempty"`
+
+	// arbitrary metadata of the job, kept by the API
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// keys of the metadata attached as tags to the job in the provider
+	// (e.g. cost center). Only supported by providers able to tag jobs
+	ProviderTags []string `json:"providerTags,omitempty"`
+
+	// maximum number of times the job is submitted again when it fails in
+	// the provider for transient reasons, bounded by the JOB_MAX_RETRIES
+	// setting
+	MaxRetries uint `json:"maxRetries,omitempty"`
+
+	// whether this is a test job, removed after the TEST_JOB_RETENTION
+	// setting and routed to the TEST_JOB_PROVIDER, when set
+	Test bool `json:"test,omitempty"`
+
+	// human readable name of the job (e.g. the title of the asset), shown
+	// in the interface of the provider. Only supported by providers able
+	// to name jobs
+	Name string `json:"name,omitempty"`
+
+	// whether outputs whose presets can't be used in the job, because they
+	// don't exist or aren't mapped for the provider, should be skipped and
+	// reported in the response instead of failing the job. Defaults to
+	// false
+	BestEffort bool `json:"bestEffort,omitempty"`
+
+	// container the provider should read the source as, for sources whose
+	// format it can't detect (e.g. files without an extension). Must be
+	// one of the input containers supported by the provider. Defaults to
+	// auto-detection
+	InputFormat string `json:"inputFormat,omitempty"`
+
+	// email address notified when the job finishes, fails or is canceled,
+	// with links to its outputs. Only available when the SMTP settings
+	// are configured
+	NotificationEmail string `json:"notificationEmail,omitempty"`
+
+	// whether a JSON manifest listing the outputs of the job, with their
+	// properties, should be written to its destination once it finishes.
+	// Only supported by providers able to write manifests
+	WriteManifest bool `json:"writeManifest,omitempty"`
+
+	// id of a job that must finish before this job is sent to the
+	// provider. The job is held by the API until then, and fails if that
+	// job fails or is canceled
+	DependsOn string `json:"dependsOn,omitempty"`
 }
 
-// swagger:parameters newJob
+// NewTranscodeJobOutput is an output in the request for a new transcoding
+// job.
+type NewTranscodeJobOutput struct {
+	FileName string `json:"fileName"`
+	Preset   string `json:"preset"`
+
+	// name of the audio selector used as the audio of the output
+	AudioSelector string `json:"audioSelector,omitempty"`
+
+	// BCP 47 tag of the language of the audio of the output, used for
+	// labeling the rendition in manifests
+	AudioLanguage string `json:"audioLanguage,omitempty"`
+}
+
+// ValidationError is a problem found in a field of the request for a new
+// job.
+//
+// swagger:model
+type ValidationError struct {
+	// name of the invalid field, in the notation of the JSON payload
+	// (e.g. outputs[1].preset)
+	Field string `json:"field"`
+
+	// description of the problem
+	Message string `json:"message"`
+}
+
+// validationErrors accumulates the problems found in the request for a new
+// job, so they can be reported together.
+type validationErrors []ValidationError
+
+func (e *validationErrors) add(field string, err error) {
+	*e = append(*e, ValidationError{Field: field, Message: err.Error()})
+}
+
+func (e validationErrors) hasField(field string) bool {
+	for _, verr := range e {
+		if verr.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Error joins the messages of all the problems.
+func (e validationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, verr := range e {
+		messages[i] = verr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+var timecodeRegexp = regexp.MustCompile(`^[0-9]{2}:[0-5][0-9]:[0-5][0-9]:[0-9]{2}$`)
+
+// languageTagRegexp matches BCP 47 language tags made of a language, an
+// optional script, an optional region and any number of variants.
+var languageTagRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z]{4})?(-([a-zA-Z]{2}|[0-9]{3}))?(-([a-zA-Z0-9]{5,8}|[0-9][a-zA-Z0-9]{3}))*$`)
+
+// swagger:parameters newJob previewJob
 type newTranscodeJobInput struct {
 	// in: body
 	// required: true
 	Payload NewTranscodeJobInputPayload
 }
 
-// ProviderFactory loads and validates the parameters, and then returns the
-// provider factory.
-func (p *newTranscodeJobInput) ProviderFactory(body io.Reader) (provider.Factory, error) {
-	err := p.loadParams(body)
+func (p *newTranscodeJobInput) loadParams(body io.Reader) error {
+	return decodeJSON(body, &p.Payload)
+}
+
+// validate checks the parameters of the job that don't depend on the
+// provider, returning all the problems found.
+func (p *newTranscodeJobInput) validate() validationErrors {
+	var errs validationErrors
+	if p.Payload.Provider == "" {
+		errs.add("provider", errors.New("missing provider from request"))
+	}
+	if p.Payload.Source == "" && len(p.Payload.Segments) == 0 {
+		errs.add("source", errors.New("missing source media from request"))
+	}
+	if p.Payload.Source != "" && len(p.Payload.Segments) > 0 {
+		errs.add("segments", errors.New("source and segments are mutually exclusive"))
+	}
+	err := validateSegments(p.Payload.Segments)
+	if err != nil {
+		errs.add("segments", err)
+	}
+	if len(p.Payload.Outputs) == 0 && p.Payload.Profile == "" {
+		errs.add("outputs", errors.New("missing output list from request"))
+	}
+	if len(p.Payload.Outputs) > 0 && p.Payload.Profile != "" {
+		errs.add("profile", errors.New("outputs and profile are mutually exclusive"))
+	}
+	err = validateAudioSelectors(p.Payload.AudioSelectors, p.Payload.Outputs)
 	if err != nil {
-		return nil, err
+		errs.add("audioSelectors", err)
 	}
-	err = p.validate()
+	err = validateStreamingParams(p.Payload.StreamingParams)
 	if err != nil {
-		return nil, err
+		errs.add("streamingParams", err)
 	}
-	return provider.GetProviderFactory(p.Payload.Provider)
+	err = validateHLSLayout(p.Payload.StreamingParams, p.Payload.Outputs)
+	if err != nil {
+		errs.add("streamingParams.hlsLayout", err)
+	}
+	err = validateProviderTags(p.Payload.ProviderTags, p.Payload.Metadata)
+	if err != nil {
+		errs.add("providerTags", err)
+	}
+	switch p.Payload.OutputACL {
+	case "", db.OutputACLPrivate, db.OutputACLPublicRead:
+	default:
+		errs.add("outputACL", fmt.Errorf("invalid output ACL %q, must be one of private or public-read", p.Payload.OutputACL))
+	}
+	for i, timecode := range p.Payload.FrameCaptures {
+		if !timecodeRegexp.MatchString(timecode) {
+			errs.add(fmt.Sprintf("frameCaptures[%d]", i), fmt.Errorf("invalid frame capture timecode %q, must be in the format HH:MM:SS:FF", timecode))
+		}
+	}
+	return errs
 }
 
-func (p *newTranscodeJobInput) loadParams(body io.Reader) error {
-	return json.NewDecoder(body).Decode(&p.Payload)
+// validateSegments checks the timecodes of the given segments, ensuring that
+// consecutive segments of the same file don't overlap.
+func validateSegments(segments []db.SourceSegment) error {
+	for i, segment := range segments {
+		if segment.URI == "" {
+			return fmt.Errorf("missing uri in segment %d", i)
+		}
+		for _, timecode := range []string{segment.In, segment.Out} {
+			if timecode != "" && !timecodeRegexp.MatchString(timecode) {
+				return fmt.Errorf("invalid timecode %q in segment %d, must be in the format HH:MM:SS:FF", timecode, i)
+			}
+		}
+		// timecodes have a fixed width, so they sort lexicographically
+		if segment.In != "" && segment.Out != "" && segment.In >= segment.Out {
+			return fmt.Errorf("invalid segment %d: in point %s must come before out point %s", i, segment.In, segment.Out)
+		}
+		if i == 0 || segments[i-1].URI != segment.URI {
+			continue
+		}
+		previous := segments[i-1]
+		if previous.Out == "" || segment.In < previous.Out {
+			return fmt.Errorf("segment %d overlaps with the previous segment of the same file", i)
+		}
+	}
+	return nil
 }
 
-func (p *newTranscodeJobInput) validate() error {
-	if p.Payload.Provider == "" {
-		return errors.New("missing provider from request")
+// segmentNameTemplateRegexp matches the segment name templates of HLS jobs,
+// which must number the segments at the end of their names.
+var segmentNameTemplateRegexp = regexp.MustCompile(`^([A-Za-z0-9._-]|` + regexp.QuoteMeta(db.SegmentNameBitrate) + `)*` + regexp.QuoteMeta(db.SegmentNameIndex) + `$`)
+
+// validateStreamingParams checks the parameters specific to a streaming
+// protocol: the layout, base URL, segment names and variant order of HLS
+// jobs and the fragment type of the segments of CMAF jobs.
+func validateStreamingParams(params db.StreamingParams) error {
+	if params.HLSLayout != "" && params.Protocol != "hls" {
+		return errors.New("hls layout is only supported by the hls streaming protocol")
+	}
+	if params.BaseURL != "" {
+		if params.Protocol != "hls" {
+			return errors.New("base url is only supported by the hls streaming protocol")
+		}
+		baseURL, err := url.Parse(params.BaseURL)
+		if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" || baseURL.RawQuery != "" || baseURL.Fragment != "" {
+			return fmt.Errorf("invalid base url %q, must be an absolute http or https URL without query or fragment", params.BaseURL)
+		}
+	}
+	if params.SegmentNameTemplate != "" {
+		if params.Protocol != "hls" {
+			return errors.New("segment name template is only supported by the hls streaming protocol")
+		}
+		if !segmentNameTemplateRegexp.MatchString(params.SegmentNameTemplate) {
+			return fmt.Errorf("invalid segment name template %q, must end with %s and may only contain %s, letters, digits, dots, dashes and underscores", params.SegmentNameTemplate, db.SegmentNameIndex, db.SegmentNameBitrate)
+		}
+	}
+	if params.VariantOrder != "" {
+		if params.Protocol != "hls" {
+			return errors.New("variant order is only supported by the hls streaming protocol")
+		}
+		if params.VariantOrder != db.VariantOrderBandwidthAsc && params.VariantOrder != db.VariantOrderBandwidthDesc {
+			return fmt.Errorf("invalid variant order %q, must be one of %s or %s", params.VariantOrder, db.VariantOrderBandwidthAsc, db.VariantOrderBandwidthDesc)
+		}
+	}
+	if params.FragmentType == "" {
+		return nil
+	}
+	if params.Protocol != "cmaf" {
+		return errors.New("fragment type is only supported by the cmaf streaming protocol")
+	}
+	switch params.FragmentType {
+	case db.FragmentTypeSingleFile, db.FragmentTypeSegmented:
+		return nil
+	}
+	return fmt.Errorf("invalid fragment type %q, must be one of single-file or segmented", params.FragmentType)
+}
+
+// validateHLSLayout checks that the audio of the outputs of HLS jobs fits
+// their layout. Muxed variants can't offer alternate audio tracks, which belong in
+// the rendition group of the demuxed layout, where each rendition is labeled
+// with its language.
+func validateHLSLayout(params db.StreamingParams, outputs []NewTranscodeJobOutput) error {
+	if params.Protocol != "hls" {
+		return nil
+	}
+	switch params.HLSLayout {
+	case "", db.HLSLayoutMuxed:
+		var selector string
+		for _, output := range outputs {
+			if output.AudioSelector == "" {
+				continue
+			}
+			if selector != "" && output.AudioSelector != selector {
+				return fmt.Errorf("hls layout muxed can't combine the audio selectors %q and %q, alternate audio tracks require the demuxed layout", selector, output.AudioSelector)
+			}
+			selector = output.AudioSelector
+		}
+	case db.HLSLayoutDemuxed:
+		for _, output := range outputs {
+			if output.AudioSelector != "" && output.AudioLanguage == "" {
+				return fmt.Errorf("output with preset %q must have an audio language in the demuxed hls layout, to label its audio rendition", output.Preset)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid hls layout %q, must be one of muxed or demuxed", params.HLSLayout)
+	}
+	return nil
+}
+
+// validateAudioSelectors checks that each audio selector picks a track either
+// by number or by language, and that outputs only reference declared
+// selectors and label their audio with valid language tags.
+func validateAudioSelectors(selectors []db.AudioSelector, outputs []NewTranscodeJobOutput) error {
+	declared := make(map[string]bool, len(selectors))
+	for i, selector := range selectors {
+		if selector.Name == "" {
+			return fmt.Errorf("missing name in audio selector %d", i)
+		}
+		if declared[selector.Name] {
+			return fmt.Errorf("duplicate audio selector %q", selector.Name)
+		}
+		if (selector.Track > 0) == (selector.Language != "") {
+			return fmt.Errorf("audio selector %q must pick the audio track either by track or by language", selector.Name)
+		}
+		declared[selector.Name] = true
 	}
-	if p.Payload.Source == "" {
-		return errors.New("missing source media from request")
+	for _, output := range outputs {
+		if output.AudioSelector != "" && !declared[output.AudioSelector] {
+			return fmt.Errorf("output with preset %q references undeclared audio selector %q", output.Preset, output.AudioSelector)
+		}
+		if output.AudioLanguage != "" && !languageTagRegexp.MatchString(output.AudioLanguage) {
+			return fmt.Errorf("output with preset %q has invalid audio language %q, must be a BCP 47 language tag", output.Preset, output.AudioLanguage)
+		}
 	}
-	if len(p.Payload.Outputs) == 0 {
-		return errors.New("missing output list from request")
+	return nil
+}
+
+// validateProviderTags ensures that each tag propagated to the provider is
+// defined in the metadata of the job.
+func validateProviderTags(tags []string, metadata map[string]string) error {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if _, ok := metadata[tag]; !ok {
+			return fmt.Errorf("provider tag %q is not defined in the metadata", tag)
+		}
+		if seen[tag] {
+			return fmt.Errorf("duplicate provider tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+// expandProfile replaces the profile in the payload with the list of
+// outputs it stands for.
+func (p *newTranscodeJobInput) expandProfile(profiles config.JobProfiles) error {
+	if p.Payload.Profile == "" {
+		return nil
+	}
+	presets, ok := profiles[p.Payload.Profile]
+	if !ok {
+		return fmt.Errorf("job profile %q not found", p.Payload.Profile)
+	}
+	p.Payload.Outputs = make([]NewTranscodeJobOutput, len(presets))
+	for i, preset := range presets {
+		p.Payload.Outputs[i] = NewTranscodeJobOutput{Preset: preset}
 	}
 	return nil
 }
 
-// swagger:parameters getJob
+// swagger:parameters getJob getJobEvents
 type getTranscodeJobInput struct {
 	// in: path
 	// required: true
@@ -81,3 +421,28 @@ func (p *getTranscodeJobInput) loadParams(paramsMap map[string]string) {
 type cancelTranscodeJobInput struct {
 	getTranscodeJobInput
 }
+
+// swagger:parameters resubmitJob
+type resubmitTranscodeJobInput struct {
+	getTranscodeJobInput
+}
+
+// swagger:parameters getJobLogs
+type getTranscodeJobLogsInput struct {
+	getTranscodeJobInput
+}
+
+// swagger:parameters getJobSpec
+type getTranscodeJobSpecInput struct {
+	getTranscodeJobInput
+}
+
+// swagger:parameters getJobCost
+type getTranscodeJobCostInput struct {
+	getTranscodeJobInput
+}
+
+// swagger:parameters getJobETA
+type getTranscodeJobETAInput struct {
+	getTranscodeJobInput
+}
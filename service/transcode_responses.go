@@ -1,12 +1,18 @@
 package service
 
 import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/NYTimes/video-transcoding-api/provider"
 	"github.com/NYTimes/video-transcoding-api/swagger"
 )
 
+var errDatastoreUnavailable = errors.New("the datastore is unavailable, please try again later")
+
 // PartialJob is the simple response given to an API
 // call that creates a new transcoding job
 // swagger:model
@@ -15,6 +21,25 @@ type PartialJob struct {
 	//
 	// unique: true
 	JobID string `json:"jobId"`
+
+	// list of outputs skipped in best-effort jobs, because their presets
+	// couldn't be used in the job
+	SkippedOutputs []SkippedOutput `json:"skippedOutputs,omitempty"`
+}
+
+// SkippedOutput is an output of a best-effort job that was left out of the
+// job.
+//
+// swagger:model
+type SkippedOutput struct {
+	// name of the preset of the output
+	Preset string `json:"preset"`
+
+	// file name of the output, as given in the request
+	FileName string `json:"fileName,omitempty"`
+
+	// reason why the output was skipped
+	Reason string `json:"reason"`
 }
 
 // JSON-encoded version of the Job, includes only the id of the job, that can
@@ -28,10 +53,10 @@ type jobResponse struct {
 	baseResponse
 }
 
-func newJobResponse(jobID string) *jobResponse {
+func newJobResponse(jobID string, skipped []SkippedOutput) *jobResponse {
 	return &jobResponse{
 		baseResponse: baseResponse{
-			payload: &PartialJob{JobID: jobID},
+			payload: &PartialJob{JobID: jobID, SkippedOutputs: skipped},
 			status:  http.StatusOK,
 		},
 	}
@@ -57,20 +82,52 @@ func newJobStatusResponse(jobStatus *provider.JobStatus) *jobStatusResponse {
 	}
 }
 
+// etag returns a strong validator for the job status, derived from its JSON
+// representation, so it changes whenever the status, progress or outputs of
+// the job change.
+func (r *jobStatusResponse) etag() (string, error) {
+	data, err := json.Marshal(r.payload)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%x"`, sha1.Sum(data)), nil
+}
+
 // error returned when the given job data is not valid.
 //
 // swagger:response invalidJob
 type invalidJobResponse struct {
 	// in: body
-	Error *swagger.ErrorResponse
+	Error *JobValidationError
+}
+
+// JobValidationError is the error returned when the given job data is not
+// valid.
+//
+// swagger:model
+type JobValidationError struct {
+	// the error message. When creating jobs, it's the message of the first
+	// problem in the list of errors
+	Message string `json:"error"`
+
+	// list of all the problems found in the fields of the request for a
+	// new job
+	Errors []ValidationError `json:"errors,omitempty"`
 }
 
 func newInvalidJobResponse(err error) *invalidJobResponse {
-	return &invalidJobResponse{Error: swagger.NewErrorResponse(err).WithStatus(http.StatusBadRequest)}
+	jobErr := JobValidationError{Message: err.Error()}
+	if errs, ok := err.(validationErrors); ok && len(errs) > 0 {
+		jobErr.Message = errs[0].Message
+		jobErr.Errors = errs
+	}
+	return &invalidJobResponse{Error: &jobErr}
 }
 
+// Result returns the error as the payload of the response, so the
+// JSONMiddleware keeps the list of problems.
 func (r *invalidJobResponse) Result() (int, interface{}, error) {
-	return r.Error.Result()
+	return http.StatusBadRequest, r.Error, nil
 }
 
 // error returned the given job id could not be found on the API.
@@ -105,3 +162,41 @@ func newJobNotFoundProviderResponse(err error) *jobNotFoundProviderResponse {
 func (r *jobNotFoundProviderResponse) Result() (int, interface{}, error) {
 	return r.Error.Result()
 }
+
+// error returned when the underlying provider fails to handle the request.
+//
+// swagger:response providerError
+type providerErrorResponse struct {
+	// in: body
+	Error *swagger.ErrorResponse
+}
+
+func newProviderErrorResponse(err error) *providerErrorResponse {
+	return &providerErrorResponse{Error: swagger.NewErrorResponse(err).WithStatus(http.StatusBadGateway)}
+}
+
+func (r *providerErrorResponse) Result() (int, interface{}, error) {
+	return r.Error.Result()
+}
+
+// error returned when the datastore can't be reached. The request can be
+// retried after the interval in the Retry-After header.
+//
+// swagger:response datastoreUnavailable
+type datastoreUnavailableResponse struct {
+	// in: body
+	Error *swagger.ErrorResponse
+
+	retryAfter uint
+}
+
+func newDatastoreUnavailableResponse(retryAfter uint) *datastoreUnavailableResponse {
+	return &datastoreUnavailableResponse{
+		Error:      swagger.NewErrorResponse(errDatastoreUnavailable).WithStatus(http.StatusServiceUnavailable),
+		retryAfter: retryAfter,
+	}
+}
+
+func (r *datastoreUnavailableResponse) Result() (int, interface{}, error) {
+	return r.Error.Result()
+}
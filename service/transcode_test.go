@@ -2,12 +2,17 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/NYTimes/gizmo/server"
 	"github.com/NYTimes/video-transcoding-api/config"
@@ -33,7 +38,6 @@ func TestTranscode(t *testing.T) {
 			"New job",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_1080p","fileName":"91274824924924-published-supervideo-1080p.mp4"}],
   "streamingParams": {"playlistFileName":"output_hls/master.m3u8","protocol":"hls","segmentDuration":3},
   "provider": "fake"
@@ -50,7 +54,6 @@ func TestTranscode(t *testing.T) {
 			"New job - default playlist file name & segment duration",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_1080p","fileName":"91274824924924-published-supervideo-1080p.mp4"}],
   "streamingParams": {"protocol":"hls"},
   "provider": "fake"
@@ -67,7 +70,6 @@ func TestTranscode(t *testing.T) {
 			"New job - default playlist file name, segment duration & regular file name",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"hls_1080p","fileName":""}],
   "streamingParams": {"protocol":"hls"},
   "provider": "fake"
@@ -84,7 +86,6 @@ func TestTranscode(t *testing.T) {
 			"New job - no playlist file name",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_1080p","fileName":"91274824924924-published-supervideo-1080p.mp4"}],
   "streamingParams": {},
   "provider": "fake"
@@ -101,7 +102,6 @@ func TestTranscode(t *testing.T) {
 			"New job - default output file name",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_1080p"}],
   "provider": "fake"
 }`,
@@ -117,7 +117,6 @@ func TestTranscode(t *testing.T) {
 			"New job with preset not found in provider",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_360p"}],
   "provider": "fake"
 }`,
@@ -133,14 +132,13 @@ func TestTranscode(t *testing.T) {
 			"New job with preset not found in the API",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_720p"}],
   "provider": "fake"
 }`,
 			false,
 
 			http.StatusBadRequest,
-			map[string]interface{}{"error": db.ErrPresetMapNotFound.Error()},
+			invalidJobBody(ValidationError{"outputs[0].preset", db.ErrPresetMapNotFound.Error()}),
 			nil,
 			"",
 			0,
@@ -149,207 +147,1900 @@ func TestTranscode(t *testing.T) {
 			"New job with database error",
 			`{
   "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
   "outputs": [{"preset":"mp4_1080p"}],
   "provider": "fake"
 }`,
 			true,
 
-			http.StatusInternalServerError,
-			map[string]interface{}{"error": "database error"},
-			nil,
-			"",
-			0,
-		},
+			http.StatusInternalServerError,
+			map[string]interface{}{"error": "database error"},
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with invalid provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "nonexistent-provider"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"provider", "provider not found"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with disabled provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "disabled"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"provider", `provider "disabled" is disabled`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job missing outputs",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputs", "missing output list from request"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with unknown field",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			map[string]interface{}{"error": `unknown field "destination" in request body`},
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with malformed JSON",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}]
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			map[string]interface{}{"error": "malformed JSON at position 96: invalid character '\"' after object key:value pair"},
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with output ACL",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video-1080p.mp4"}],
+  "outputACL": "private",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video-1080p.mp4"},
+			"",
+			0,
+		},
+		{
+			"New job with invalid output ACL",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "outputACL": "authenticated-read",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputACL", `invalid output ACL "authenticated-read", must be one of private or public-read`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with frame captures",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video-1080p.mp4"}],
+  "frameCaptures": ["00:00:01:00", "00:01:30:12"],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video-1080p.mp4"},
+			"",
+			0,
+		},
+		{
+			"New job with invalid frame capture timecode",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "frameCaptures": ["00:00:01:00", "1:30"],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"frameCaptures[1]", `invalid frame capture timecode "1:30", must be in the format HH:MM:SS:FF`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with output ACL not supported by the provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "outputACL": "public-read",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputACL", `provider "fake" doesn't support the output ACL "public-read"`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with profile",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "profile": "web",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video_mp4_1080p.mp4", "hls/video_hls_1080p.m3u8"},
+			"",
+			0,
+		},
+		{
+			"New job with unknown profile",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "profile": "mobile",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"profile", `job profile "mobile" not found`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with profile containing unknown preset",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "profile": "broken",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputs[1].preset", `preset "mp4_480p" of job profile "broken": presetmap not found`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with both outputs and profile",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "profile": "web",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"profile", "outputs and profile are mutually exclusive"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with segments not supported by the provider",
+			`{
+  "segments": [{"uri":"http://another.non.existent/video.mp4","in":"00:00:10:00","out":"00:01:00:00"}],
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"segments", `provider "fake" doesn't support stitching input segments`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with both source and segments",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "segments": [{"uri":"http://another.non.existent/video.mp4"}],
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"segments", "source and segments are mutually exclusive"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with overlapping segments",
+			`{
+  "segments": [
+    {"uri":"http://another.non.existent/video.mp4","in":"00:00:10:00","out":"00:01:00:00"},
+    {"uri":"http://another.non.existent/video.mp4","in":"00:00:50:00","out":"00:02:00:00"}
+  ],
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"segments", "segment 1 overlaps with the previous segment of the same file"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with segment out point before in point",
+			`{
+  "segments": [{"uri":"http://another.non.existent/video.mp4","in":"00:01:00:00","out":"00:00:10:00"}],
+  "outputs": [{"preset":"mp4_1080p"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"segments", "invalid segment 0: in point 00:01:00:00 must come before out point 00:00:10:00"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with audio selectors not supported by the provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "audioSelectors": [{"name":"spanish","language":"spa"}],
+  "outputs": [{"preset":"mp4_1080p","audioSelector":"spanish"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"audioSelectors", `provider "fake" doesn't support audio selectors`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with undeclared audio selector",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "audioSelectors": [{"name":"spanish","language":"spa"}],
+  "outputs": [{"preset":"mp4_1080p","audioSelector":"commentary"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"audioSelectors", `output with preset "mp4_1080p" references undeclared audio selector "commentary"`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with audio selector picking both track and language",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "audioSelectors": [{"name":"spanish","track":2,"language":"spa"}],
+  "outputs": [{"preset":"mp4_1080p","audioSelector":"spanish"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"audioSelectors", `audio selector "spanish" must pick the audio track either by track or by language`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with invalid audio language",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","audioLanguage":"english"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"audioSelectors", `output with preset "mp4_1080p" has invalid audio language "english", must be a BCP 47 language tag`}, ValidationError{"outputs", `provider "fake" doesn't support audio languages`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with audio languages not supported by the provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","audioLanguage":"pt-BR"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputs", `provider "fake" doesn't support audio languages`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with metadata",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video_1080p.mp4"}],
+  "metadata": {"cost-center": "video-1234"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video_1080p.mp4"},
+			"",
+			0,
+		},
+		{
+			"New job with provider tags in a provider without job tags",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "metadata": {"cost-center": "video-1234"},
+  "providerTags": ["cost-center"],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"providerTags", `provider "fake" doesn't support job tags`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with name in a provider without job names",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "name": "Big Buck Bunny",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"name", `provider "fake" doesn't support job names`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with input format",
+			`{
+  "source": "http://another.non.existent/video",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video_1080p.mp4"}],
+  "inputFormat": "mxf",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video_1080p.mp4"},
+			"",
+			0,
+		},
+		{
+			"New job with input format not supported by the provider",
+			`{
+  "source": "http://another.non.existent/video",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "inputFormat": "mkv",
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"inputFormat", `provider "fake" doesn't support the input format "mkv"`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with too many retries",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "maxRetries": 4,
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"maxRetries", "invalid maxRetries 4, must be at most 3"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with provider tags missing from the metadata",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"}],
+  "metadata": {"cost-center": "video-1234"},
+  "providerTags": ["owner"],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"providerTags", `provider tag "owner" is not defined in the metadata`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New CMAF job",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p","fileName":"video_1080p.mp4"}],
+  "streamingParams": {"protocol":"cmaf","fragmentType":"segmented"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video_1080p.mp4"},
+			"cmaf/index",
+			5,
+		},
+		{
+			"New job with CMAF preset without the cmaf protocol",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p"}],
+  "streamingParams": {"protocol":"hls"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputs[0].preset", `preset "cmaf_1080p" uses the cmaf container, which requires the cmaf streaming protocol`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with audio description",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p","fileName":"video_1080p.m3u8"},{"preset":"hls_audio_description","fileName":"audio_description.m3u8"}],
+  "streamingParams": {"protocol":"hls"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video_1080p.m3u8", "audio_description.m3u8"},
+			"hls/index.m3u8",
+			5,
+		},
+		{
+			"New job with audio description without adaptive streaming",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p"},{"preset":"hls_audio_description"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputs[1].preset", `preset "hls_audio_description" is an audio description, which is only supported in adaptive streaming jobs`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with fragment type without the cmaf protocol",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","fragmentType":"segmented"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", "fragment type is only supported by the cmaf streaming protocol"}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with partially mapped presets",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video_1080p.mp4"},{"preset":"mp4_360p","fileName":"video_360p.mp4"},{"preset":"mp4_720p"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"outputs[2].preset", db.ErrPresetMapNotFound.Error()}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New job with presets not mapped for the provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video_1080p.mp4"},{"preset":"mp4_360p","fileName":"video_360p.mp4"}],
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			map[string]interface{}{"error": provider.ErrPresetMapNotFound.Error()},
+			nil,
+			"",
+			0,
+		},
+		{
+			"New best-effort job with partially mapped presets",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_1080p","fileName":"video_1080p.mp4"},{"preset":"mp4_360p","fileName":"video_360p.mp4"},{"preset":"mp4_720p"},{"preset":"cmaf_1080p"}],
+  "bestEffort": true,
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{
+				"jobId": "fill me",
+				"skippedOutputs": []interface{}{
+					map[string]interface{}{"preset": "mp4_360p", "fileName": "video_360p.mp4", "reason": provider.ErrPresetMapNotFound.Error()},
+					map[string]interface{}{"preset": "mp4_720p", "reason": db.ErrPresetMapNotFound.Error()},
+					map[string]interface{}{"preset": "cmaf_1080p", "reason": `preset "cmaf_1080p" uses the cmaf container, which requires the cmaf streaming protocol`},
+				},
+			},
+			[]string{"video_1080p.mp4"},
+			"",
+			0,
+		},
+		{
+			"New best-effort job without usable presets",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"mp4_360p","fileName":"video_360p.mp4"},{"preset":"mp4_720p"}],
+  "bestEffort": true,
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			map[string]interface{}{"error": "none of the outputs of the job can be transcoded: " + provider.ErrPresetMapNotFound.Error()},
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with the muxed layout",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p","fileName":"video_1080p.m3u8"}],
+  "streamingParams": {"protocol":"hls","hlsLayout":"muxed"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusOK,
+			map[string]interface{}{"jobId": "fill me"},
+			[]string{"video_1080p.m3u8"},
+			"hls/index.m3u8",
+			5,
+		},
+		{
+			"New HLS job with the demuxed layout not supported by the provider",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p","fileName":"video_1080p.m3u8"}],
+  "streamingParams": {"protocol":"hls","hlsLayout":"demuxed"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.hlsLayout", `provider "fake" doesn't support the demuxed hls layout`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with invalid layout",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","hlsLayout":"interleaved"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.hlsLayout", `invalid hls layout "interleaved", must be one of muxed or demuxed`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New CMAF job with HLS layout",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p"}],
+  "streamingParams": {"protocol":"cmaf","hlsLayout":"demuxed"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", "hls layout is only supported by the hls streaming protocol"}, ValidationError{"streamingParams.hlsLayout", `provider "fake" doesn't support the demuxed hls layout`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with base URL in a provider without absolute URLs",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","baseURL":"https://cdn.example.com/videos"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.baseURL", `provider "fake" doesn't support absolute urls in hls manifests`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with relative base URL",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","baseURL":"/videos"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid base url "/videos", must be an absolute http or https URL without query or fragment`}, ValidationError{"streamingParams.baseURL", `provider "fake" doesn't support absolute urls in hls manifests`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with base URL with query",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","baseURL":"https://cdn.example.com/videos?token=123"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid base url "https://cdn.example.com/videos?token=123", must be an absolute http or https URL without query or fragment`}, ValidationError{"streamingParams.baseURL", `provider "fake" doesn't support absolute urls in hls manifests`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New CMAF job with base URL",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p"}],
+  "streamingParams": {"protocol":"cmaf","baseURL":"https://cdn.example.com/videos"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", "base url is only supported by the hls streaming protocol"}, ValidationError{"streamingParams.baseURL", `provider "fake" doesn't support absolute urls in hls manifests`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with segment name template in a provider without segment names",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","segmentNameTemplate":"seg_{bitrate}_{index}"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.segmentNameTemplate", `provider "fake" doesn't support segment name templates`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with segment name template without index",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","segmentNameTemplate":"seg_{bitrate}"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid segment name template "seg_{bitrate}", must end with {index} and may only contain {bitrate}, letters, digits, dots, dashes and underscores`}, ValidationError{"streamingParams.segmentNameTemplate", `provider "fake" doesn't support segment name templates`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with segment name template with unknown placeholder",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","segmentNameTemplate":"seg_{width}_{index}"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid segment name template "seg_{width}_{index}", must end with {index} and may only contain {bitrate}, letters, digits, dots, dashes and underscores`}, ValidationError{"streamingParams.segmentNameTemplate", `provider "fake" doesn't support segment name templates`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with segment name template with path",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","segmentNameTemplate":"../seg_{index}"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid segment name template "../seg_{index}", must end with {index} and may only contain {bitrate}, letters, digits, dots, dashes and underscores`}, ValidationError{"streamingParams.segmentNameTemplate", `provider "fake" doesn't support segment name templates`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New CMAF job with segment name template",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p"}],
+  "streamingParams": {"protocol":"cmaf","segmentNameTemplate":"seg_{index}"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", "segment name template is only supported by the hls streaming protocol"}, ValidationError{"streamingParams.segmentNameTemplate", `provider "fake" doesn't support segment name templates`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with variant order in a provider without variant ordering",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","variantOrder":"bandwidth-asc"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.variantOrder", `provider "fake" doesn't support ordering hls variants`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New HLS job with invalid variant order",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"hls_1080p"}],
+  "streamingParams": {"protocol":"hls","variantOrder":"resolution"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid variant order "resolution", must be one of bandwidth-asc or bandwidth-desc`}, ValidationError{"streamingParams.variantOrder", `provider "fake" doesn't support ordering hls variants`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New CMAF job with variant order",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p"}],
+  "streamingParams": {"protocol":"cmaf","variantOrder":"bandwidth-desc"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", "variant order is only supported by the hls streaming protocol"}, ValidationError{"streamingParams.variantOrder", `provider "fake" doesn't support ordering hls variants`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New muxed HLS job with alternate audio tracks",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "audioSelectors": [{"name":"english","language":"eng"},{"name":"spanish","language":"spa"}],
+  "outputs": [{"preset":"hls_1080p","audioSelector":"english"},{"preset":"hls_1080p","audioSelector":"spanish"}],
+  "streamingParams": {"protocol":"hls"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.hlsLayout", `hls layout muxed can't combine the audio selectors "english" and "spanish", alternate audio tracks require the demuxed layout`}, ValidationError{"audioSelectors", `provider "fake" doesn't support audio selectors`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New demuxed HLS job with unlabeled audio rendition",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "audioSelectors": [{"name":"english","language":"eng"},{"name":"spanish","language":"spa"}],
+  "outputs": [{"preset":"hls_1080p"},{"preset":"hls_1080p","audioSelector":"english","audioLanguage":"en"},{"preset":"hls_1080p","audioSelector":"spanish"}],
+  "streamingParams": {"protocol":"hls","hlsLayout":"demuxed"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams.hlsLayout", `output with preset "hls_1080p" must have an audio language in the demuxed hls layout, to label its audio rendition`}, ValidationError{"audioSelectors", `provider "fake" doesn't support audio selectors`}, ValidationError{"outputs", `provider "fake" doesn't support audio languages`}),
+			nil,
+			"",
+			0,
+		},
+		{
+			"New CMAF job with invalid fragment type",
+			`{
+  "source": "http://another.non.existent/video.mp4",
+  "outputs": [{"preset":"cmaf_1080p"}],
+  "streamingParams": {"protocol":"cmaf","fragmentType":"chunked"},
+  "provider": "fake"
+}`,
+			false,
+
+			http.StatusBadRequest,
+			invalidJobBody(ValidationError{"streamingParams", `invalid fragment type "chunked", must be one of single-file or segmented`}),
+			nil,
+			"",
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		fprovider.jobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(test.givenTriggerDBError)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "hls_1080p",
+			ProviderMapping: map[string]string{"fake": "19928"},
+			OutputOpts:      db.OutputOptions{Extension: "m3u8"},
+		})
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "cmaf_1080p",
+			ProviderMapping: map[string]string{"fake": "20028"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4", Container: "cmaf"},
+		})
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "hls_audio_description",
+			ProviderMapping: map[string]string{"fake": "20128"},
+			OutputOpts:      db.OutputOptions{Extension: "m3u8", AudioDescription: true},
+		})
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_360p",
+			ProviderMapping: map[string]string{"elementalconductor": "172712"},
+		})
+		service, err := NewTranscodingService(&config.Config{
+			DefaultSegmentDuration: 5,
+			JobMaxRetries:          3,
+			Server:                 &server.Config{},
+			JobProfiles: config.JobProfiles{
+				"web":    {"mp4_1080p", "hls_1080p"},
+				"broken": {"mp4_1080p", "mp4_480p"},
+			},
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(test.givenRequestBody))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: expected response code of %d. got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Errorf("%s: unable to JSON decode response body: %s", test.givenTestCase, err)
+		}
+		if got["jobId"] == "" {
+			t.Errorf("%s: missing jobId from the response: %#v", test.givenTestCase, got)
+		}
+		if _, ok := test.wantBody["jobId"]; ok {
+			test.wantBody["jobId"] = got["jobId"]
+		}
+		if !reflect.DeepEqual(got, test.wantBody) {
+			t.Logf("%s: raw response from the api:\n%s", test.givenTestCase, w.Body.Bytes())
+			t.Errorf("%s: expected response body of\n%#v;\ngot\n%#v", test.givenTestCase, test.wantBody, got)
+		}
+		if test.wantCode == http.StatusOK {
+			_, err = fakeDBObj.GetJob(got["jobId"].(string))
+			if err != nil {
+				t.Error(err)
+			}
+			profile := fprovider.jobs[0]
+			fileNames := make([]string, len(profile.Outputs))
+			for i, output := range profile.Outputs {
+				fileNames[i] = output.FileName
+			}
+			if !reflect.DeepEqual(fileNames, test.wantOutputFileNames) {
+				t.Errorf("%s: wrong file names for output files\nwant %#v\ngot  %#v", test.givenTestCase, test.wantOutputFileNames, fileNames)
+			}
+			playlistFile := fprovider.jobs[0].StreamingParams.PlaylistFileName
+			if playlistFile != test.wantPlaylistFileName {
+				t.Errorf("%s: wrong playlist filename\nwant %q\ngot  %q", test.givenTestCase, test.wantPlaylistFileName, playlistFile)
+			}
+			segmentDuration := fprovider.jobs[0].StreamingParams.SegmentDuration
+			if segmentDuration != test.wantSegmentDuration {
+				t.Errorf("%s: wrong segment duration\nwant %d\ngot  %d", test.givenTestCase, test.wantSegmentDuration, segmentDuration)
+			}
+		}
+	}
+}
+
+// invalidJobBody returns the body of invalidJob responses listing the given
+// problems.
+func invalidJobBody(errs ...ValidationError) map[string]interface{} {
+	list := make([]interface{}, len(errs))
+	for i, err := range errs {
+		list[i] = map[string]interface{}{"field": err.Field, "message": err.Message}
+	}
+	return map[string]interface{}{"error": errs[0].Message, "errors": list}
+}
+
+func TestTranscodePresetFallbacks(t *testing.T) {
+	tests := []struct {
+		givenTestCase      string
+		givenFallbacks     config.PresetFallbacks
+		givenBestEffort    bool
+		givenOutputPresets []string
+
+		wantCode      int
+		wantError     string
+		wantPresetIDs []string
+	}{
+		{
+			"fallback for preset without mapping",
+			config.PresetFallbacks{"fake": {"mp4_360p": "generic-360p"}},
+			false,
+			[]string{"mp4_1080p", "mp4_360p"},
+			http.StatusOK,
+			"",
+			[]string{"18828", "generic-360p"},
+		},
+		{
+			"fallback of another provider",
+			config.PresetFallbacks{"zencoder": {"mp4_360p": "generic-360p"}},
+			false,
+			[]string{"mp4_1080p", "mp4_360p"},
+			http.StatusBadRequest,
+			"preset not found in provider",
+			nil,
+		},
+		{
+			"strict mode",
+			nil,
+			false,
+			[]string{"mp4_1080p", "mp4_360p"},
+			http.StatusBadRequest,
+			"preset not found in provider",
+			nil,
+		},
+		{
+			"existing mapping takes precedence",
+			config.PresetFallbacks{"fake": {"mp4_1080p": "generic-1080p"}},
+			false,
+			[]string{"mp4_1080p"},
+			http.StatusOK,
+			"",
+			[]string{"18828"},
+		},
+		{
+			"best-effort job with fallback",
+			config.PresetFallbacks{"fake": {"mp4_360p": "generic-360p"}},
+			true,
+			[]string{"mp4_360p"},
+			http.StatusOK,
+			"",
+			[]string{"generic-360p"},
+		},
+	}
+	defer func() { fprovider.jobs = nil }()
+	for _, test := range tests {
+		fprovider.jobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_360p",
+			ProviderMapping: map[string]string{"elementalconductor": "172712"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		logger := logrus.New()
+		logger.Out = ioutil.Discard
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, PresetFallbacks: test.givenFallbacks}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		outputs := make([]string, len(test.givenOutputPresets))
+		for i, preset := range test.givenOutputPresets {
+			outputs[i] = fmt.Sprintf(`{"preset":%q}`, preset)
+		}
+		body := fmt.Sprintf(`{"source":"http://some.source/video.mov","provider":"fake","bestEffort":%v,"outputs":[%s]}`, test.givenBestEffort, strings.Join(outputs, ","))
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d: %s", test.givenTestCase, test.wantCode, w.Code, w.Body.String())
+			continue
+		}
+		if test.wantError != "" {
+			var got map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("%s: %s", test.givenTestCase, err)
+			}
+			if got["error"] != test.wantError {
+				t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, test.wantError, got["error"])
+			}
+			continue
+		}
+		if len(fprovider.jobs) != 1 {
+			t.Fatalf("%s: wrong number of jobs sent to the provider. Want 1. Got %d", test.givenTestCase, len(fprovider.jobs))
+		}
+		presetIDs := make([]string, len(fprovider.jobs[0].Outputs))
+		for i, output := range fprovider.jobs[0].Outputs {
+			presetIDs[i] = output.Preset.ProviderMapping["fake"]
+		}
+		if !reflect.DeepEqual(presetIDs, test.wantPresetIDs) {
+			t.Errorf("%s: wrong presets sent to the provider\nwant %#v\ngot  %#v", test.givenTestCase, test.wantPresetIDs, presetIDs)
+		}
+		presetMap, err := fakeDBObj.GetPresetMap("mp4_360p")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := presetMap.ProviderMapping["fake"]; ok {
+			t.Errorf("%s: fallback shouldn't be stored in the preset map: %#v", test.givenTestCase, presetMap)
+		}
+	}
+}
+
+// presetMapCounter counts the preset map lookups made in the wrapped
+// repository.
+type presetMapCounter struct {
+	db.Repository
+	lookups      int
+	batchLookups int
+}
+
+func (r *presetMapCounter) GetPresetMap(name string) (*db.PresetMap, error) {
+	r.lookups++
+	return r.Repository.GetPresetMap(name)
+}
+
+func (r *presetMapCounter) GetPresetMaps(names []string) (map[string]*db.PresetMap, error) {
+	r.batchLookups++
+	return r.Repository.GetPresetMaps(names)
+}
+
+func newJobRequestWithPresets(n int) (*presetMapCounter, string) {
+	repo := &presetMapCounter{Repository: dbtest.NewFakeRepository(false)}
+	outputs := make([]string, n)
+	for i := range outputs {
+		name := fmt.Sprintf("mp4_%04d", i)
+		repo.CreatePresetMap(&db.PresetMap{
+			Name:            name,
+			ProviderMapping: map[string]string{"fake": name},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		// reverse order, so the order of the outputs doesn't match the
+		// order of creation of the presets
+		outputs[n-i-1] = fmt.Sprintf(`{"preset":%q,"fileName":"%d.mp4"}`, name, n-i-1)
+	}
+	body := `{"source":"http://some.source/video.mov","provider":"fake","outputs":[` + strings.Join(outputs, ",") + `]}`
+	return repo, body
+}
+
+func TestTranscodeLargePresetList(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	const presets = 500
+	repo, body := newJobRequestWithPresets(presets)
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+	srvr.Register(service)
+	r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if repo.batchLookups != 1 || repo.lookups != 0 {
+		t.Errorf("wrong number of preset map lookups. Want 1 batch lookup and no single lookups. Got %d and %d", repo.batchLookups, repo.lookups)
+	}
+	outputs := fprovider.jobs[0].Outputs
+	if len(outputs) != presets {
+		t.Fatalf("wrong number of outputs. Want %d. Got %d", presets, len(outputs))
+	}
+	for i, output := range outputs {
+		wantPreset := fmt.Sprintf("mp4_%04d", presets-i-1)
+		if output.Preset.Name != wantPreset || output.FileName != fmt.Sprintf("%d.mp4", i) {
+			t.Errorf("wrong output at position %d. Want %q (%d.mp4). Got %q (%s)", i, wantPreset, i, output.Preset.Name, output.FileName)
+		}
+	}
+}
+
+func TestTranscodeMaxOutputs(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	tests := []struct {
+		givenTestCase  string
+		givenMaxOutput uint
+
+		wantCode  int
+		wantError string
+	}{
+		{"at the limit", 3, http.StatusOK, ""},
+		{"over the limit", 2, http.StatusBadRequest, "job has 3 outputs, exceeding the maximum of 2"},
+		{"without limit", 0, http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		fprovider.jobs = nil
+		repo, body := newJobRequestWithPresets(3)
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{
+			Server:        &server.Config{},
+			JobMaxOutputs: test.givenMaxOutput,
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = repo
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong response code. Want %d. Got %d: %s", test.givenTestCase, test.wantCode, w.Code, w.Body.String())
+			continue
+		}
+		if test.wantError == "" {
+			continue
+		}
+		var resp JobValidationError
+		err = json.Unmarshal(w.Body.Bytes(), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Message != test.wantError {
+			t.Errorf("%s: wrong error message. Want %q. Got %q", test.givenTestCase, test.wantError, resp.Message)
+		}
+		if len(fprovider.jobs) != 0 {
+			t.Errorf("%s: unexpected job sent to the provider", test.givenTestCase)
+		}
+	}
+}
+
+func TestTranscodeValidationErrors(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreatePresetMap(&db.PresetMap{
+		Name:            "mp4_1080p",
+		ProviderMapping: map[string]string{"fake": "18828"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4"},
+	})
+	fakeDBObj.CreatePresetMap(&db.PresetMap{
+		Name:            "cmaf_1080p",
+		ProviderMapping: map[string]string{"fake": "18829"},
+		OutputOpts:      db.OutputOptions{Extension: "mp4", Container: "cmaf"},
+	})
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, JobMaxRetries: 3}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+	body := `{
+  "provider": "fake",
+  "outputs": [{"preset":"mp4_1080p"},{"preset":"mp4_4k"},{"preset":"cmaf_1080p"}],
+  "outputACL": "authenticated-read",
+  "maxRetries": 5,
+  "name": "Evening news"
+}`
+	r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("wrong response code. Want %d. Got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var resp JobValidationError
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrors := []ValidationError{
+		{"source", "missing source media from request"},
+		{"outputACL", `invalid output ACL "authenticated-read", must be one of private or public-read`},
+		{"maxRetries", "invalid maxRetries 5, must be at most 3"},
+		{"name", `provider "fake" doesn't support job names`},
+		{"outputs[1].preset", db.ErrPresetMapNotFound.Error()},
+		{"outputs[2].preset", `preset "cmaf_1080p" uses the cmaf container, which requires the cmaf streaming protocol`},
+	}
+	if !reflect.DeepEqual(resp.Errors, wantErrors) {
+		t.Errorf("wrong validation errors\nwant %#v\ngot  %#v", wantErrors, resp.Errors)
+	}
+	if resp.Message != wantErrors[0].Message {
+		t.Errorf("wrong error message. Want %q. Got %q", wantErrors[0].Message, resp.Message)
+	}
+	if len(fprovider.jobs) != 0 {
+		t.Error("unexpected job sent to the provider")
+	}
+}
+
+func TestTranscodeTestJob(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	tests := []struct {
+		givenTestCase     string
+		givenRequestBody  string
+		givenTestProvider string
+
+		wantProvider string
+		wantTest     bool
+	}{
+		{
+			"test job routed to the test job provider",
+			`{"source":"http://some.source/video.mov","provider":"fake","test":true,"outputs":[{"preset":"mp4_1080p"}]}`,
+			"zencoder",
+			"zencoder",
+			true,
+		},
+		{
+			"test job without test job provider",
+			`{"source":"http://some.source/video.mov","provider":"fake","test":true,"outputs":[{"preset":"mp4_1080p"}]}`,
+			"",
+			"fake",
+			true,
+		},
+		{
+			"regular job with test job provider",
+			`{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}]}`,
+			"zencoder",
+			"fake",
+			false,
+		},
+	}
+	for _, test := range tests {
+		fprovider.jobs = nil
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828", "zencoder": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{
+			Server:          &server.Config{},
+			TestJobProvider: test.givenTestProvider,
+		}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(test.givenRequestBody))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: wrong response code. Want %d. Got %d: %s", test.givenTestCase, http.StatusOK, w.Code, w.Body.String())
+			continue
+		}
+		jobs, err := fakeDBObj.ListJobs(db.JobFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != 1 {
+			t.Fatalf("%s: wrong number of jobs. Want 1. Got %d", test.givenTestCase, len(jobs))
+		}
+		if jobs[0].ProviderName != test.wantProvider {
+			t.Errorf("%s: wrong provider. Want %q. Got %q", test.givenTestCase, test.wantProvider, jobs[0].ProviderName)
+		}
+		if jobs[0].Test != test.wantTest {
+			t.Errorf("%s: wrong test flag. Want %v. Got %v", test.givenTestCase, test.wantTest, jobs[0].Test)
+		}
+	}
+}
+
+func BenchmarkTranscodeLargePresetList(b *testing.B) {
+	defer func() { fprovider.jobs = nil }()
+	repo, body := newJobRequestWithPresets(500)
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		b.Fatal(err)
+	}
+	service.db = repo
+	srvr.Register(service)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fprovider.jobs = nil
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			b.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestGetTranscodeJob(t *testing.T) {
+	tests := []struct {
+		givenTestCase        string
+		givenURI             string
+		givenTriggerDBError  bool
+		givenProtocol        string
+		givenSegmentDuration uint
+
+		wantCode int
+		wantBody interface{}
+	}{
+		{
+			"Get job",
+			"/jobs/job-123",
+			false,
+			"hls",
+			5,
+			http.StatusOK,
+			map[string]interface{}{
+				"providerJobId": "provider-job-123",
+				"status":        "finished",
+				"providerName":  "fake",
+				"statusMessage": "The job is finished",
+				"progress":      10.3,
+				"providerStatus": map[string]interface{}{
+					"progress":   10.3,
+					"sourcefile": "http://some.source.file",
+				},
+				"output": map[string]interface{}{
+					"destination": "s3://mybucket/some/dir/job-123",
+				},
+				"sourceInfo": map[string]interface{}{
+					"width":      float64(4096),
+					"height":     float64(2160),
+					"duration":   183e9,
+					"videoCodec": "VP9",
+				},
+			},
+		},
+		{
+			"Get job with inexistent job id",
+			"/jobs/non_existent_job",
+			false,
+			"",
+			0,
+			http.StatusNotFound,
+			map[string]interface{}{"error": "job not found"},
+		},
+		{
+			"Get job that doesn't exist in the provider",
+			"/jobs/job-gone",
+			false,
+			"",
+			0,
+			http.StatusGone,
+			map[string]interface{}{"error": `Error with provider "fake" when trying to retrieve job id "job-gone": could not found job with id: provider-job-gone`},
+		},
+		{
+			"Get job with provider error",
+			"/jobs/job-error",
+			false,
+			"",
+			0,
+			http.StatusBadGateway,
+			map[string]interface{}{"error": `Error with provider "fake" when trying to retrieve job id "job-error": internal server error`},
+		},
+	}
+
+	for _, test := range tests {
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(test.givenTriggerDBError)
+		fakeDBObj.CreateJob(&db.Job{ID: "job-gone", ProviderName: "fake", ProviderJobID: "provider-job-gone"})
+		fakeDBObj.CreateJob(&db.Job{ID: "job-error", ProviderName: "fake", ProviderJobID: "provider-job-error"})
+		fakeDBObj.CreateJob(&db.Job{
+			ID:            "job-123",
+			ProviderName:  "fake",
+			ProviderJobID: "provider-job-123",
+			StreamingParams: db.StreamingParams{
+				SegmentDuration: test.givenSegmentDuration,
+				Protocol:        test.givenProtocol,
+			},
+		})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("GET", test.givenURI, nil)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: expected response code of %d; got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var got interface{}
+		err = json.NewDecoder(w.Body).Decode(&got)
+		if err != nil {
+			t.Errorf("%s: unable to JSON decode response body: %s", test.givenTestCase, err)
+		}
+		if !reflect.DeepEqual(got, test.wantBody) {
+			t.Errorf("%s: expected response body of\n%#v;\ngot\n%#v", test.givenTestCase, test.wantBody, got)
+		}
+	}
+}
+
+// unavailableRepository simulates an outage of the datastore in the given
+// operations.
+type unavailableRepository struct {
+	db.Repository
+	presetMaps bool
+	createJob  bool
+}
+
+func (r *unavailableRepository) GetPresetMaps(names []string) (map[string]*db.PresetMap, error) {
+	if r.presetMaps {
+		return nil, db.UnavailableError{Err: errors.New("connection refused")}
+	}
+	return r.Repository.GetPresetMaps(names)
+}
+
+func (r *unavailableRepository) CreateJob(job *db.Job) error {
+	if r.createJob {
+		return db.UnavailableError{Err: errors.New("connection refused")}
+	}
+	return r.Repository.CreateJob(job)
+}
+
+func TestTranscodeDatastoreUnavailable(t *testing.T) {
+	tests := []struct {
+		givenTestCase   string
+		givenRepository unavailableRepository
+		givenRetryAfter uint
+
+		wantRetryAfter   string
+		wantProviderJobs int
+		wantCanceledJobs []string
+	}{
+		{
+			"preset maps unavailable",
+			unavailableRepository{presetMaps: true},
+			5,
+			"5",
+			0,
+			nil,
+		},
+		{
+			"job record unavailable",
+			unavailableRepository{createJob: true},
+			5,
+			"5",
+			1,
+			[]string{"provider-preset-job-123"},
+		},
+		{
+			"no retry interval",
+			unavailableRepository{createJob: true},
+			0,
+			"",
+			1,
+			[]string{"provider-preset-job-123"},
+		},
+	}
+	defer func() {
+		fprovider.jobs = nil
+		fprovider.canceledJobs = nil
+	}()
+	for _, test := range tests {
+		fprovider.jobs = nil
+		fprovider.canceledJobs = nil
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreatePresetMap(&db.PresetMap{
+			Name:            "mp4_1080p",
+			ProviderMapping: map[string]string{"fake": "18828"},
+			OutputOpts:      db.OutputOptions{Extension: "mp4"},
+		})
+		repo := test.givenRepository
+		repo.Repository = fakeDBObj
+		logger := logrus.New()
+		logger.Out = ioutil.Discard
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}, DatastoreRetryAfter: test.givenRetryAfter}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = &repo
+		srvr.Register(service)
+		body := `{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}]}`
+		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusServiceUnavailable, w.Code)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != test.wantRetryAfter {
+			t.Errorf("%s: wrong Retry-After header. Want %q. Got %q", test.givenTestCase, test.wantRetryAfter, retryAfter)
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		wantError := "the datastore is unavailable, please try again later"
+		if got["error"] != wantError {
+			t.Errorf("%s: wrong error. Want %q. Got %q", test.givenTestCase, wantError, got["error"])
+		}
+		if len(fprovider.jobs) != test.wantProviderJobs {
+			t.Errorf("%s: wrong number of jobs sent to the provider. Want %d. Got %d", test.givenTestCase, test.wantProviderJobs, len(fprovider.jobs))
+		}
+		if !reflect.DeepEqual(fprovider.canceledJobs, test.wantCanceledJobs) {
+			t.Errorf("%s: wrong list of canceled jobs. Want %#v. Got %#v", test.givenTestCase, test.wantCanceledJobs, fprovider.canceledJobs)
+		}
+		jobs, err := fakeDBObj.ListJobs(db.JobFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) > 0 {
+			t.Errorf("%s: unexpected jobs stored: %#v", test.givenTestCase, jobs)
+		}
+	}
+}
+
+func TestCanceledRequestAbortsProviderCalls(t *testing.T) {
+	tests := []struct {
+		givenTestCase string
+		givenMethod   string
+		givenURI      string
+		givenBody     string
+
+		wantCode  int
+		wantError string
+	}{
 		{
-			"New job with invalid provider",
-			`{
-  "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
-  "outputs": [{"preset":"mp4_1080p"}],
-  "provider": "nonexistent-provider"
-}`,
-			false,
-
-			http.StatusBadRequest,
-			map[string]interface{}{"error": "provider not found"},
-			nil,
-			"",
-			0,
+			"new job",
+			"POST",
+			"/jobs",
+			`{"source":"http://some.source/video.mov","provider":"fake","outputs":[{"preset":"mp4_1080p"}]}`,
+			http.StatusInternalServerError,
+			`Error with provider "fake": context canceled`,
 		},
 		{
-			"New job missing outputs",
-			`{
-  "source": "http://another.non.existent/video.mp4",
-  "destination": "s3://some.bucket.s3.amazonaws.com/some_path",
-  "provider": "fake"
-}`,
-			false,
-
-			http.StatusBadRequest,
-			map[string]interface{}{"error": "missing output list from request"},
-			nil,
+			"job status",
+			"GET",
+			"/jobs/job-123",
 			"",
-			0,
+			http.StatusBadGateway,
+			`Error with provider "fake" when trying to retrieve job id "job-123": context canceled`,
 		},
 	}
-
 	for _, test := range tests {
 		fprovider.jobs = nil
 		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
-		fakeDBObj := dbtest.NewFakeRepository(test.givenTriggerDBError)
+		fakeDBObj := dbtest.NewFakeRepository(false)
 		fakeDBObj.CreatePresetMap(&db.PresetMap{
 			Name:            "mp4_1080p",
 			ProviderMapping: map[string]string{"fake": "18828"},
 			OutputOpts:      db.OutputOptions{Extension: "mp4"},
 		})
-		fakeDBObj.CreatePresetMap(&db.PresetMap{
-			Name:            "hls_1080p",
-			ProviderMapping: map[string]string{"fake": "19928"},
-			OutputOpts:      db.OutputOptions{Extension: "m3u8"},
-		})
-		fakeDBObj.CreatePresetMap(&db.PresetMap{
-			Name:            "mp4_360p",
-			ProviderMapping: map[string]string{"elementalconductor": "172712"},
-		})
-		service, err := NewTranscodingService(&config.Config{
-			DefaultSegmentDuration: 5,
-			Server:                 &server.Config{},
-		}, logrus.New())
+		fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123", Status: "started"})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
 		if err != nil {
 			t.Fatal(err)
 		}
 		service.db = fakeDBObj
 		srvr.Register(service)
-		r, _ := http.NewRequest("POST", "/jobs", strings.NewReader(test.givenRequestBody))
-		r.Header.Set("Content-Type", "application/json")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r, _ := http.NewRequest(test.givenMethod, test.givenURI, strings.NewReader(test.givenBody))
+		r = r.WithContext(ctx)
 		w := httptest.NewRecorder()
 		srvr.ServeHTTP(w, r)
 		if w.Code != test.wantCode {
-			t.Errorf("%s: expected response code of %d. got %d", test.givenTestCase, test.wantCode, w.Code)
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
 		}
 		var got map[string]interface{}
 		err = json.Unmarshal(w.Body.Bytes(), &got)
 		if err != nil {
-			t.Errorf("%s: unable to JSON decode response body: %s", test.givenTestCase, err)
+			t.Fatalf("%s: %s", test.givenTestCase, err)
 		}
-		if got["jobId"] == "" {
-			t.Errorf("%s: missing jobId from the response: %#v", test.givenTestCase, got)
+		if got["error"] != test.wantError {
+			t.Errorf("%s: wrong error\nwant %q\ngot  %q", test.givenTestCase, test.wantError, got["error"])
 		}
-		if _, ok := test.wantBody["jobId"]; ok {
-			test.wantBody["jobId"] = got["jobId"]
+		if len(fprovider.jobs) > 0 {
+			t.Errorf("%s: unexpected job sent to the provider: %#v", test.givenTestCase, fprovider.jobs)
 		}
-		if !reflect.DeepEqual(got, test.wantBody) {
-			t.Logf("%s: raw response from the api:\n%s", test.givenTestCase, w.Body.Bytes())
-			t.Errorf("%s: expected response body of\n%#v;\ngot\n%#v", test.givenTestCase, test.wantBody, got)
+		job, err := fakeDBObj.GetJob("job-123")
+		if err != nil {
+			t.Fatal(err)
 		}
-		if test.wantCode == http.StatusOK {
-			_, err = fakeDBObj.GetJob(got["jobId"].(string))
-			if err != nil {
-				t.Error(err)
-			}
-			profile := fprovider.jobs[0]
-			fileNames := make([]string, len(profile.Outputs))
-			for i, output := range profile.Outputs {
-				fileNames[i] = output.FileName
-			}
-			if !reflect.DeepEqual(fileNames, test.wantOutputFileNames) {
-				t.Errorf("%s: wrong file names for output files\nwant %#v\ngot  %#v", test.givenTestCase, test.wantOutputFileNames, fileNames)
-			}
-			playlistFile := fprovider.jobs[0].StreamingParams.PlaylistFileName
-			if playlistFile != test.wantPlaylistFileName {
-				t.Errorf("%s: wrong playlist filename\nwant %q\ngot  %q", test.givenTestCase, test.wantPlaylistFileName, playlistFile)
-			}
-			segmentDuration := fprovider.jobs[0].StreamingParams.SegmentDuration
-			if segmentDuration != test.wantSegmentDuration {
-				t.Errorf("%s: wrong segment duration\nwant %d\ngot  %d", test.givenTestCase, test.wantSegmentDuration, segmentDuration)
-			}
+		if job.Status != "started" {
+			t.Errorf("%s: job status shouldn't change. Got %q", test.givenTestCase, job.Status)
 		}
 	}
 }
 
-func TestGetTranscodeJob(t *testing.T) {
+func TestGetTranscodeJobOutputsStatus(t *testing.T) {
 	tests := []struct {
-		givenTestCase        string
-		givenURI             string
-		givenTriggerDBError  bool
-		givenProtocol        string
-		givenSegmentDuration uint
+		givenTestCase    string
+		givenMaxDuration uint
 
-		wantCode int
-		wantBody interface{}
+		wantStatus  string
+		wantOutputs []interface{}
 	}{
 		{
-			"Get job",
-			"/jobs/job-123",
-			false,
-			"hls",
-			5,
-			http.StatusOK,
-			map[string]interface{}{
-				"providerJobId": "provider-job-123",
-				"status":        "finished",
-				"providerName":  "fake",
-				"statusMessage": "The job is finished",
-				"progress":      10.3,
-				"providerStatus": map[string]interface{}{
-					"progress":   10.3,
-					"sourcefile": "http://some.source.file",
-				},
-				"output": map[string]interface{}{
-					"destination": "s3://mybucket/some/dir/job-123",
-				},
-				"sourceInfo": map[string]interface{}{
-					"width":      float64(4096),
-					"height":     float64(2160),
-					"duration":   183e9,
-					"videoCodec": "VP9",
-				},
+			"outputs with different progress",
+			0,
+			"started",
+			[]interface{}{
+				map[string]interface{}{"fileName": "video_360p.mp4", "preset": "mp4_360p", "status": "finished", "progress": float64(100)},
+				map[string]interface{}{"fileName": "video_720p.mp4", "preset": "mp4_720p", "status": "started", "progress": float64(55)},
+				map[string]interface{}{"fileName": "video_1080p.mp4", "preset": "mp4_1080p", "status": "started", "progress": float64(25)},
 			},
 		},
 		{
-			"Get job with inexistent job id",
-			"/jobs/non_existent_job",
-			false,
-			"",
-			0,
-			http.StatusNotFound,
-			map[string]interface{}{"error": "job not found"},
+			"timed out job keeps finished outputs",
+			60,
+			"failed",
+			[]interface{}{
+				map[string]interface{}{"fileName": "video_360p.mp4", "preset": "mp4_360p", "status": "finished", "progress": float64(100)},
+				map[string]interface{}{"fileName": "video_720p.mp4", "preset": "mp4_720p", "status": "failed", "progress": float64(55)},
+				map[string]interface{}{"fileName": "video_1080p.mp4", "preset": "mp4_1080p", "status": "failed", "progress": float64(25)},
+			},
 		},
 	}
-
+	defer func() { fprovider.canceledJobs = nil }()
 	for _, test := range tests {
+		fprovider.canceledJobs = nil
 		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
-		fakeDBObj := dbtest.NewFakeRepository(test.givenTriggerDBError)
+		fakeDBObj := dbtest.NewFakeRepository(false)
 		fakeDBObj.CreateJob(&db.Job{
-			ID:            "job-123",
+			ID:            "job-renditions",
 			ProviderName:  "fake",
-			ProviderJobID: "provider-job-123",
-			StreamingParams: db.StreamingParams{
-				SegmentDuration: test.givenSegmentDuration,
-				Protocol:        test.givenProtocol,
-			},
+			ProviderJobID: "provider-job-renditions",
+			MaxDuration:   test.givenMaxDuration,
 		})
 		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
 		if err != nil {
 			t.Fatal(err)
 		}
 		service.db = fakeDBObj
+		service.clock = func() time.Time { return fakeJobStartTime.Add(time.Hour) }
 		srvr.Register(service)
-		r, _ := http.NewRequest("GET", test.givenURI, nil)
-		r.Header.Set("Content-Type", "application/json")
+		r, _ := http.NewRequest("GET", "/jobs/job-renditions", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, http.StatusOK, w.Code)
+		}
+		var got map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["status"] != test.wantStatus {
+			t.Errorf("%s: wrong status. Want %q. Got %q", test.givenTestCase, test.wantStatus, got["status"])
+		}
+		if !reflect.DeepEqual(got["outputs"], test.wantOutputs) {
+			t.Errorf("%s: wrong outputs\nwant %#v\ngot  %#v", test.givenTestCase, test.wantOutputs, got["outputs"])
+		}
+	}
+}
+
+func TestGetTranscodeJobETag(t *testing.T) {
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	fakeDBObj := dbtest.NewFakeRepository(false)
+	fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = fakeDBObj
+	srvr.Register(service)
+
+	r, _ := http.NewRequest("GET", "/jobs/job-123", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong response code. Want %d. Got %d", http.StatusOK, w.Code)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("unexpected empty ETag in job status response")
+	}
+
+	var tests = []struct {
+		givenTestCase    string
+		givenIfNoneMatch string
+		wantCode         int
+	}{
+		{"matching ETag", etag, http.StatusNotModified},
+		{"matching weak ETag", "W/" + etag, http.StatusNotModified},
+		{"matching ETag in list", `"some-other-etag", ` + etag, http.StatusNotModified},
+		{"wildcard", "*", http.StatusNotModified},
+		{"stale ETag", `"some-other-etag"`, http.StatusOK},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/jobs/job-123", nil)
+		r.Header.Set("If-None-Match", test.givenIfNoneMatch)
 		w := httptest.NewRecorder()
 		srvr.ServeHTTP(w, r)
 		if w.Code != test.wantCode {
-			t.Errorf("%s: expected response code of %d; got %d", test.givenTestCase, test.wantCode, w.Code)
+			t.Errorf("%s: wrong response code. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
 		}
-		var got interface{}
-		err = json.NewDecoder(w.Body).Decode(&got)
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Errorf("%s: wrong ETag. Want %q. Got %q", test.givenTestCase, etag, got)
+		}
+		if test.wantCode == http.StatusNotModified && w.Body.Len() > 0 {
+			t.Errorf("%s: unexpected body in 304 response: %s", test.givenTestCase, w.Body.String())
+		}
+	}
+
+	r, _ = http.NewRequest("GET", "/jobs/non_existent_job", nil)
+	r.Header.Set("If-None-Match", "*")
+	w = httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong response code for missing job. Want %d. Got %d", http.StatusNotFound, w.Code)
+	}
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Errorf("unexpected ETag in error response: %q", got)
+	}
+}
+
+func TestJobStatusETagChanges(t *testing.T) {
+	status := provider.JobStatus{
+		ProviderJobID: "provider-job-123",
+		Status:        provider.StatusStarted,
+		Progress:      10.3,
+	}
+	base, err := newJobStatusResponse(&status).etag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, _ := newJobStatusResponse(&status).etag()
+	if same != base {
+		t.Errorf("ETag changed for the same status. Want %q. Got %q", base, same)
+	}
+	var tests = []struct {
+		givenTestCase string
+		changeStatus  func(*provider.JobStatus)
+	}{
+		{"status", func(s *provider.JobStatus) { s.Status = provider.StatusFinished }},
+		{"progress", func(s *provider.JobStatus) { s.Progress = 50 }},
+		{"outputs", func(s *provider.JobStatus) {
+			s.Output.Files = []provider.OutputFile{{Path: "s3://mybucket/video.mp4"}}
+		}},
+	}
+	for _, test := range tests {
+		changed := status
+		test.changeStatus(&changed)
+		etag, err := newJobStatusResponse(&changed).etag()
 		if err != nil {
-			t.Errorf("%s: unable to JSON decode response body: %s", test.givenTestCase, err)
+			t.Fatal(err)
 		}
-		if !reflect.DeepEqual(got, test.wantBody) {
-			t.Errorf("%s: expected response body of\n%#v;\ngot\n%#v", test.givenTestCase, test.wantBody, got)
+		if etag == base {
+			t.Errorf("%s: ETag didn't change", test.givenTestCase)
 		}
 	}
 }
@@ -460,3 +2151,173 @@ func TestCancelTranscodeJob(t *testing.T) {
 		}
 	}
 }
+
+func TestResubmitTranscodeJob(t *testing.T) {
+	var tests = []struct {
+		givenTestCase string
+		givenJobID    string
+
+		wantCode      int
+		wantError     string
+		wantFileNames []string
+	}{
+		{
+			"job with a failed output",
+			"job-partial",
+			http.StatusOK,
+			"",
+			[]string{"video_720p.mp4"},
+		},
+		{
+			"job without per-output status",
+			"job-123",
+			http.StatusBadRequest,
+			`provider "fake" doesn't report the status of the outputs of job "job-123"`,
+			nil,
+		},
+		{
+			"job without failed outputs",
+			"job-renditions",
+			http.StatusBadRequest,
+			`job "job-renditions" has no failed outputs`,
+			nil,
+		},
+		{
+			"non-existing job",
+			"some-id",
+			http.StatusNotFound,
+			db.ErrJobNotFound.Error(),
+			nil,
+		},
+	}
+	defer func() { fprovider.jobs = nil }()
+	for _, test := range tests {
+		fprovider.jobs = nil
+		outputs := make([]db.TranscodeOutput, 0, 3)
+		for _, name := range []string{"360p", "720p", "1080p"} {
+			outputs = append(outputs, db.TranscodeOutput{
+				FileName: "video_" + name + ".mp4",
+				Preset: db.PresetMap{
+					Name:            "mp4_" + name,
+					ProviderMapping: map[string]string{"fake": "preset-" + name},
+					OutputOpts:      db.OutputOptions{Extension: "mp4"},
+				},
+			})
+		}
+		fakeDBObj := dbtest.NewFakeRepository(false)
+		fakeDBObj.CreateJob(&db.Job{ID: "job-123", ProviderName: "fake", ProviderJobID: "provider-job-123", Outputs: outputs})
+		fakeDBObj.CreateJob(&db.Job{ID: "job-renditions", ProviderName: "fake", ProviderJobID: "provider-job-renditions", Outputs: outputs})
+		fakeDBObj.CreateJob(&db.Job{
+			ID:            "job-partial",
+			ProviderName:  "fake",
+			ProviderJobID: "provider-job-partial",
+			SourceMedia:   "http://some.nice/video.mov",
+			OutputACL:     "private",
+			Outputs:       outputs,
+		})
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		service.db = fakeDBObj
+		srvr.Register(service)
+		r, _ := http.NewRequest("POST", "/jobs/"+test.givenJobID+"/resubmit", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: wrong code returned. Want %d. Got %d", test.givenTestCase, test.wantCode, w.Code)
+		}
+		var body map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if test.wantError != "" {
+			if body["error"] != test.wantError {
+				t.Errorf("%s: wrong error returned. Want %q. Got %q", test.givenTestCase, test.wantError, body["error"])
+			}
+			if len(fprovider.jobs) > 0 {
+				t.Errorf("%s: unexpected job sent to the provider: %#v", test.givenTestCase, fprovider.jobs[0])
+			}
+			continue
+		}
+		if len(fprovider.jobs) != 1 {
+			t.Fatalf("%s: wrong number of jobs sent to the provider. Want 1. Got %d", test.givenTestCase, len(fprovider.jobs))
+		}
+		var fileNames []string
+		for _, output := range fprovider.jobs[0].Outputs {
+			fileNames = append(fileNames, output.FileName)
+		}
+		if !reflect.DeepEqual(fileNames, test.wantFileNames) {
+			t.Errorf("%s: wrong outputs resubmitted. Want %#v. Got %#v", test.givenTestCase, test.wantFileNames, fileNames)
+		}
+		job, err := fakeDBObj.GetJob(body["jobId"].(string))
+		if err != nil {
+			t.Fatalf("%s: %s", test.givenTestCase, err)
+		}
+		if job.ResubmittedFrom != test.givenJobID {
+			t.Errorf("%s: wrong original job. Want %q. Got %q", test.givenTestCase, test.givenJobID, job.ResubmittedFrom)
+		}
+		if job.SourceMedia != "http://some.nice/video.mov" || job.OutputACL != "private" {
+			t.Errorf("%s: settings of the original job not kept: %#v", test.givenTestCase, job)
+		}
+	}
+}
+
+func TestResubmitTranscodeJobRedis(t *testing.T) {
+	defer func() { fprovider.jobs = nil }()
+	fprovider.jobs = nil
+	repo := newRedisRepository(t)
+	outputs := make([]db.TranscodeOutput, 0, 3)
+	for _, name := range []string{"360p", "720p", "1080p"} {
+		outputs = append(outputs, db.TranscodeOutput{
+			FileName: "video_" + name + ".mp4",
+			Preset: db.PresetMap{
+				Name:            "mp4_" + name,
+				ProviderMapping: map[string]string{"fake": "preset-" + name},
+				OutputOpts:      db.OutputOptions{Extension: "mp4"},
+			},
+		})
+	}
+	err := repo.CreateJob(&db.Job{
+		ID:             "job-partial",
+		ProviderName:   "fake",
+		ProviderJobID:  "provider-job-partial",
+		SourceMedia:    "http://some.nice/video.mov",
+		Outputs:        outputs,
+		AudioSelectors: []db.AudioSelector{{Name: "english", Language: "eng"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+	service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.db = repo
+	srvr.Register(service)
+	r, _ := http.NewRequest("POST", "/jobs/job-partial/resubmit", nil)
+	w := httptest.NewRecorder()
+	srvr.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("wrong code returned. Want %d. Got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var body map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := repo.GetJob(body["jobId"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOutputs := outputs[1:2]
+	if !reflect.DeepEqual(job.Outputs, wantOutputs) {
+		t.Errorf("wrong outputs stored for the resubmitted job.\nWant %#v\nGot  %#v", wantOutputs, job.Outputs)
+	}
+	if job.ResubmittedFrom != "job-partial" || !reflect.DeepEqual(job.AudioSelectors, []db.AudioSelector{{Name: "english", Language: "eng"}}) {
+		t.Errorf("settings of the original job not kept: %#v", job)
+	}
+}
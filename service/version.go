@@ -0,0 +1,52 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/NYTimes/video-transcoding-api/swagger"
+	"github.com/NYTimes/video-transcoding-api/version"
+)
+
+// Version describes the build of the API that is running.
+//
+// swagger:model
+type Version struct {
+	// version of the API, from the latest release tag, or the output of git
+	// describe when there are no tags
+	Version string `json:"version"`
+
+	// git commit the API was built from
+	GitCommit string `json:"gitCommit"`
+
+	// date and time of the build, in UTC
+	BuildDate string `json:"buildDate"`
+}
+
+// response for the getVersion operation.
+//
+// swagger:response version
+type versionResponse struct {
+	// in: body
+	Payload *Version
+
+	baseResponse
+}
+
+// swagger:route GET /version version getVersion
+//
+// Describe the build of the API, for verifying deployments.
+//
+//     Responses:
+//       200: version
+func (s *TranscodingService) getVersion(r *http.Request) swagger.GizmoJSONResponse {
+	return &versionResponse{
+		baseResponse: baseResponse{
+			payload: &Version{
+				Version:   version.Version,
+				GitCommit: version.GitCommit,
+				BuildDate: version.BuildDate,
+			},
+			status: http.StatusOK,
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/NYTimes/gizmo/server"
+	"github.com/NYTimes/video-transcoding-api/config"
+	"github.com/NYTimes/video-transcoding-api/version"
+	"github.com/sirupsen/logrus"
+)
+
+func TestGetVersion(t *testing.T) {
+	defer func(v, commit, date string) {
+		version.Version, version.GitCommit, version.BuildDate = v, commit, date
+	}(version.Version, version.GitCommit, version.BuildDate)
+	var tests = []struct {
+		testCase       string
+		givenVersion   string
+		givenGitCommit string
+		givenBuildDate string
+
+		expectedBody map[string]interface{}
+	}{
+		{
+			"defaults",
+			"dev",
+			"unknown",
+			"unknown",
+			map[string]interface{}{"version": "dev", "gitCommit": "unknown", "buildDate": "unknown"},
+		},
+		{
+			"values injected at build time",
+			"1.4.2",
+			"69f9799",
+			"2018-04-19T12:00:00Z",
+			map[string]interface{}{"version": "1.4.2", "gitCommit": "69f9799", "buildDate": "2018-04-19T12:00:00Z"},
+		},
+	}
+	for _, test := range tests {
+		version.Version, version.GitCommit, version.BuildDate = test.givenVersion, test.givenGitCommit, test.givenBuildDate
+		srvr := server.NewSimpleServer(&server.Config{RouterType: "fast"})
+		service, err := NewTranscodingService(&config.Config{Server: &server.Config{}}, logrus.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		srvr.Register(service)
+		r, _ := http.NewRequest("GET", "/version", nil)
+		w := httptest.NewRecorder()
+		srvr.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: wrong status code. Want %d. Got %d", test.testCase, http.StatusOK, w.Code)
+		}
+		var body map[string]interface{}
+		err = json.NewDecoder(w.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(body, test.expectedBody) {
+			t.Errorf("%s: wrong body\nwant %#v\ngot  %#v", test.testCase, test.expectedBody, body)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// Package version identifies the build of the Transcoding API.
+//
+// Its variables are defined at build time by the Makefile, using the
+// -X flag of the linker. Builds that don't define them report the defaults.
+package version
+
+// Version, GitCommit and BuildDate identify the build of the Transcoding
+// API.
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)